@@ -234,6 +234,17 @@ func (f *file) String() string {
 	return fs.FormatFileInfo(f)
 }
 
+// ContentHash returns a 16-byte hash of the file's contents, computed
+// by the compiler, suitable for use as a cache validator such as an
+// HTTP entity tag. For a directory, ContentHash returns nil.
+func (f *file) ContentHash() []byte {
+	if f.IsDir() {
+		return nil
+	}
+	h := f.hash
+	return h[:]
+}
+
 // dotFile is a file for the root directory,
 // which is omitted from the files list in a FS.
 var dotFile = &file{name: "./"}
@@ -289,6 +300,12 @@ func (f FS) readDir(dir string) []file {
 // Open opens the named file for reading and returns it as an [fs.File].
 //
 // The returned file implements [io.Seeker] and [io.ReaderAt] when the file is not a directory.
+// The [fs.FileInfo] returned by its Stat method has a method
+//
+//	ContentHash() []byte
+//
+// which returns a hash of the file's contents, for use by file servers
+// such as [net/http.FileServerFS] as a cache validator.
 func (f FS) Open(name string) (fs.File, error) {
 	file := f.lookup(name)
 	if file == nil {
@@ -248,3 +248,37 @@ func TestOffset(t *testing.T) {
 		t.Fatalf("ReadAt: got %q, want %q", got, want)
 	}
 }
+
+func TestContentHash(t *testing.T) {
+	type contentHasher interface{ ContentHash() []byte }
+	hash := func(name string) []byte {
+		t.Helper()
+		f, err := global.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, ok := info.(contentHasher)
+		if !ok {
+			t.Fatalf("Stat(%v) has no ContentHash method", name)
+		}
+		return h.ContentHash()
+	}
+	hello, glass := hash("testdata/hello.txt"), hash("testdata/glass.txt")
+	if len(hello) != 16 || len(glass) != 16 {
+		t.Fatalf("got hashes %x, %x; want 16 bytes", hello, glass)
+	}
+	if string(hello) == string(glass) {
+		t.Errorf("files with different contents have the same hash %x", hello)
+	}
+	if again := hash("testdata/hello.txt"); string(again) != string(hello) {
+		t.Errorf("got hash %x, then %x", hello, again)
+	}
+	if dir := hash("testdata"); dir != nil {
+		t.Errorf("directory has hash %x, want nil", dir)
+	}
+}
@@ -557,16 +557,22 @@ var depsRules = `
 	NET, crypto/tls
 	< net/http/httptrace;
 
+	crypto/tls
+	< net/http/internal/quic;
+
 	compress/gzip,
 	golang.org/x/net/http/httpguts,
 	golang.org/x/net/http/httpproxy,
 	golang.org/x/net/http2/hpack,
 	net/http/internal,
 	net/http/internal/ascii,
+	net/http/internal/quic,
 	net/http/internal/testcert,
 	net/http/httptrace,
 	mime/multipart,
-	log
+	log,
+	log/slog,
+	regexp
 	< net/http;
 
 	# HTTP-aware packages
@@ -575,11 +581,17 @@ var depsRules = `
 	< expvar;
 
 	net/http, net/http/internal/ascii
-	< net/http/cookiejar, net/http/httputil;
+	< net/http/httpcache, net/http/httputil, net/http/sse, net/http/websocket;
+
+	encoding/json, net/http, net/http/internal/ascii
+	< net/http/cookiejar, net/http/oauth2;
 
 	net/http, flag
 	< net/http/httptest;
 
+	crypto/tls, net/http
+	< net/dns;
+
 	net/http, regexp
 	< net/http/cgi
 	< net/http/fcgi;
@@ -0,0 +1,472 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// writerWindowLog is the log of the window size used by Writer.
+	// RFC 9659 limits the window of the zstd HTTP content coding to 8M;
+	// a smaller window keeps the memory used by each Writer small,
+	// as many of them may be in use by an HTTP server.
+	writerWindowLog  = 17
+	writerWindowSize = 1 << writerWindowLog
+
+	// maxBlockSize is the maximum size of a block. RFC 3.1.1.2.4.
+	maxBlockSize = 128 << 10
+
+	writerHashLog = 14
+	minMatch      = 4
+)
+
+// Writer implements [io.WriteCloser] to write a zstd compressed stream.
+//
+// Writer favors speed and simplicity over compression ratio:
+// it finds matches using a single hash table, stores literals
+// uncompressed, and encodes sequences using the predefined FSE tables.
+// The stream consists of a single frame with a checksum.
+type Writer struct {
+	w   io.Writer
+	err error
+
+	wroteHeader bool
+
+	// hist holds up to writerWindowSize bytes of previously
+	// written data, followed by the pending block at hist[pending:].
+	// It grows as data is written, so short streams use little memory.
+	hist    []byte
+	pending int
+
+	// table maps the hash of 4 bytes to their position in hist, plus 1.
+	table [1 << writerHashLog]int32
+
+	checksum xxhash64
+
+	// Scratch space for encoding a block.
+	lits []byte
+	seqs []sequence
+	out  []byte
+}
+
+// A sequence is a run of literals followed by a match.
+type sequence struct {
+	litLen   uint32
+	matchLen uint32
+	offset   uint32
+}
+
+var errWriterClosed = errors.New("zstd: write to closed Writer")
+
+// NewWriter creates a new Writer that compresses data to w.
+// Writes may be buffered; the caller must call Close when done.
+func NewWriter(w io.Writer) *Writer {
+	zw := new(Writer)
+	zw.Reset(w)
+	return zw
+}
+
+// Reset discards the Writer's state and makes it equivalent to the
+// result of NewWriter, writing to w instead.
+// This permits reusing a Writer rather than allocating a new one.
+func (w *Writer) Reset(out io.Writer) {
+	w.w = out
+	w.err = nil
+	w.wroteHeader = false
+	w.hist = w.hist[:0]
+	w.pending = 0
+	clear(w.table[:])
+	w.checksum.reset()
+}
+
+// Write implements [io.Writer].
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := 0
+	for len(p) > 0 {
+		k := maxBlockSize - (len(w.hist) - w.pending)
+		if k > len(p) {
+			k = len(p)
+		}
+		w.hist = append(w.hist, p[:k]...)
+		p = p[k:]
+		n += k
+		if len(w.hist)-w.pending == maxBlockSize {
+			if err := w.writeBlock(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush writes any pending data to the underlying writer.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.hist) == w.pending {
+		return nil
+	}
+	return w.writeBlock(false)
+}
+
+// Close writes any pending data and the end of the stream
+// to the underlying writer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		if w.err == errWriterClosed {
+			return nil
+		}
+		return w.err
+	}
+	if err := w.writeBlock(true); err != nil {
+		return err
+	}
+	w.err = errWriterClosed
+	return nil
+}
+
+// writeBlock compresses the pending data as a single block
+// and writes it to the underlying writer.
+func (w *Writer) writeBlock(last bool) error {
+	out := w.out[:0]
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		// Magic number, then a frame header descriptor with
+		// Content_Checksum_Flag set and no frame content size,
+		// then the window descriptor. RFC 3.1.1.1.
+		out = append(out, 0x28, 0xb5, 0x2f, 0xfd, 1<<2, (writerWindowLog-10)<<3)
+	}
+	src := w.hist[w.pending:]
+	w.checksum.update(src)
+	out = w.appendBlock(out, src, last)
+	if last {
+		out = binary.LittleEndian.AppendUint32(out, uint32(w.checksum.digest()))
+	}
+	w.out = out
+	w.pending = len(w.hist)
+	w.slide()
+	if _, err := w.w.Write(out); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// slide discards data in hist which is no longer in the window.
+func (w *Writer) slide() {
+	shift := w.pending - writerWindowSize
+	if shift <= 0 {
+		return
+	}
+	n := copy(w.hist, w.hist[shift:])
+	w.hist = w.hist[:n]
+	w.pending -= shift
+	for i, v := range w.table {
+		if int(v) > shift {
+			w.table[i] = v - int32(shift)
+		} else {
+			w.table[i] = 0
+		}
+	}
+}
+
+// appendBlock appends a block containing src to out.
+// RFC 3.1.1.2.
+func (w *Writer) appendBlock(out, src []byte, last bool) []byte {
+	start := len(out)
+	out = append(out, 0, 0, 0) // Block_Header, filled in below
+	blockType := 0             // Raw_Block
+	if len(src) > minMatch {
+		out = w.appendCompressedBlock(out)
+		if len(out)-start-3 < len(src) {
+			blockType = 2 // Compressed_Block
+		} else {
+			out = out[:start+3]
+		}
+	}
+	if blockType == 0 {
+		out = append(out, src...)
+	}
+	hdr := uint32(len(out)-start-3)<<3 | uint32(blockType)<<1
+	if last {
+		hdr |= 1
+	}
+	out[start] = byte(hdr)
+	out[start+1] = byte(hdr >> 8)
+	out[start+2] = byte(hdr >> 16)
+	return out
+}
+
+// appendCompressedBlock appends the content of a compressed block
+// containing the pending data to out. RFC 3.1.1.3.
+func (w *Writer) appendCompressedBlock(out []byte) []byte {
+	w.findSequences()
+
+	// Literals_Section_Header for a Raw_Literals_Block. RFC 3.1.1.3.1.1.
+	switch n := len(w.lits); {
+	case n < 1<<5:
+		out = append(out, byte(n<<3))
+	case n < 1<<12:
+		out = append(out, byte(n<<4)|1<<2, byte(n>>4))
+	default:
+		out = append(out, byte(n<<4)|3<<2, byte(n>>4), byte(n>>12))
+	}
+	out = append(out, w.lits...)
+
+	return appendSequences(out, w.seqs)
+}
+
+func hash4(v uint32) uint32 {
+	return (v * 2654435761) >> (32 - writerHashLog)
+}
+
+// findSequences finds matches for the pending block,
+// setting w.seqs and w.lits.
+func (w *Writer) findSequences() {
+	hist := w.hist
+	end := len(hist)
+	w.seqs = w.seqs[:0]
+	w.lits = w.lits[:0]
+	lit := w.pending // start of the current run of literals
+	for i := w.pending; i+minMatch <= end; {
+		v := binary.LittleEndian.Uint32(hist[i:])
+		h := hash4(v)
+		cand := int(w.table[h]) - 1
+		w.table[h] = int32(i + 1)
+		if cand < 0 || i-cand > writerWindowSize || binary.LittleEndian.Uint32(hist[cand:]) != v {
+			// Skip ahead faster the longer we go without a match.
+			i += 1 + (i-lit)>>6
+			continue
+		}
+		m := minMatch
+		for i+m < end && hist[cand+m] == hist[i+m] {
+			m++
+		}
+		for i > lit && cand > 0 && hist[i-1] == hist[cand-1] {
+			i--
+			cand--
+			m++
+		}
+		w.lits = append(w.lits, hist[lit:i]...)
+		w.seqs = append(w.seqs, sequence{
+			litLen:   uint32(i - lit),
+			matchLen: uint32(m),
+			offset:   uint32(i - cand),
+		})
+		i += m
+		lit = i
+		// Index a position near the end of the match,
+		// which is likely to start the next match.
+		if j := i - 2; j+minMatch <= end {
+			w.table[hash4(binary.LittleEndian.Uint32(hist[j:]))] = int32(j + 1)
+		}
+	}
+	w.lits = append(w.lits, hist[lit:end]...)
+}
+
+// fseEncoder encodes symbols using an FSE table.
+type fseEncoder struct {
+	table     []fseEntry
+	tableBits uint8
+
+	// states[sym][next] is the state which decodes sym
+	// and then transitions to state next.
+	states [][]uint16
+}
+
+func newFSEEncoder(norm []int16, tableBits int) *fseEncoder {
+	e := &fseEncoder{
+		table:     make([]fseEntry, 1<<tableBits),
+		tableBits: uint8(tableBits),
+		states:    make([][]uint16, len(norm)),
+	}
+	var r Reader
+	if err := r.buildFSE(0, norm, e.table, tableBits); err != nil {
+		panic(err)
+	}
+	for sym := range e.states {
+		e.states[sym] = make([]uint16, len(e.table))
+	}
+	for s, ent := range e.table {
+		for next := int(ent.base); next < int(ent.base)+1<<ent.bits; next++ {
+			e.states[ent.sym][next] = uint16(s)
+		}
+	}
+	return e
+}
+
+// transition returns the state which decodes sym and then transitions
+// to next, along with the bits the decoder reads to make the transition.
+func (e *fseEncoder) transition(sym uint8, next uint16) (state uint16, v uint32, nbits uint8) {
+	state = e.states[sym][next]
+	ent := e.table[state]
+	return state, uint32(next - ent.base), ent.bits
+}
+
+// literalPredefinedDistribution is the predefined distribution table
+// for literal lengths. RFC 3.1.1.3.2.2.1.
+var literalPredefinedDistribution = []int16{
+	4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+	-1, -1, -1, -1,
+}
+
+// offsetPredefinedDistribution is the predefined distribution table
+// for offsets. RFC 3.1.1.3.2.2.3.
+var offsetPredefinedDistribution = []int16{
+	1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+}
+
+// matchPredefinedDistribution is the predefined distribution table
+// for match lengths. RFC 3.1.1.3.2.2.2.
+var matchPredefinedDistribution = []int16{
+	1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+	-1, -1, -1, -1, -1,
+}
+
+var (
+	predefinedEncodersOnce sync.Once
+	predefinedEncoders     [3]*fseEncoder
+)
+
+func getPredefinedEncoders() *[3]*fseEncoder {
+	predefinedEncodersOnce.Do(func() {
+		predefinedEncoders[seqLiteral] = newFSEEncoder(literalPredefinedDistribution, seqCodeInfo[seqLiteral].predefTableBits)
+		predefinedEncoders[seqOffset] = newFSEEncoder(offsetPredefinedDistribution, seqCodeInfo[seqOffset].predefTableBits)
+		predefinedEncoders[seqMatch] = newFSEEncoder(matchPredefinedDistribution, seqCodeInfo[seqMatch].predefTableBits)
+	})
+	return &predefinedEncoders
+}
+
+// seqCodes holds the codes and extra bits for a sequence.
+type seqCodes struct {
+	code  [3]uint8
+	extra [3]uint32
+	nbits [3]uint8
+}
+
+func makeSeqCodes(seq sequence) seqCodes {
+	var c seqCodes
+
+	if ll := seq.litLen; ll < literalLengthOffset {
+		c.code[seqLiteral] = uint8(ll)
+	} else {
+		i := len(literalLengthBase) - 1
+		for literalLengthBase[i]&0xffffff > ll {
+			i--
+		}
+		c.code[seqLiteral] = uint8(literalLengthOffset + i)
+		c.extra[seqLiteral] = ll - literalLengthBase[i]&0xffffff
+		c.nbits[seqLiteral] = uint8(literalLengthBase[i] >> 24)
+	}
+
+	if ml := seq.matchLen; ml-3 < matchLengthOffset {
+		c.code[seqMatch] = uint8(ml - 3)
+	} else {
+		i := len(matchLengthBase) - 1
+		for matchLengthBase[i]&0xffffff > ml {
+			i--
+		}
+		c.code[seqMatch] = uint8(matchLengthOffset + i)
+		c.extra[seqMatch] = ml - matchLengthBase[i]&0xffffff
+		c.nbits[seqMatch] = uint8(matchLengthBase[i] >> 24)
+	}
+
+	// We never use repeated offsets, so Offset_Value is offset+3.
+	// RFC 3.1.1.3.2.1.1.
+	ov := seq.offset + 3
+	code := uint8(bits.Len32(ov) - 1)
+	c.code[seqOffset] = code
+	c.extra[seqOffset] = ov - 1<<code
+	c.nbits[seqOffset] = code
+
+	return c
+}
+
+// appendSequences appends a Sequences_Section encoding seqs to out.
+// RFC 3.1.1.3.2.
+func appendSequences(out []byte, seqs []sequence) []byte {
+	switch n := len(seqs); {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7f00:
+		out = append(out, byte(n>>8+128), byte(n))
+	default:
+		out = append(out, 255, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if len(seqs) == 0 {
+		return out
+	}
+	// Symbol_Compression_Modes: Predefined_Mode for all codes.
+	out = append(out, 0)
+
+	// The decoder reads the bitstream backward, so we write
+	// everything in the reverse of the order it is read.
+	// See execSeqs for the decoding order.
+	enc := getPredefinedEncoders()
+	bw := bitWriter{out: out}
+	var state [3]uint16
+	for i := len(seqs) - 1; i >= 0; i-- {
+		c := makeSeqCodes(seqs[i])
+		if i == len(seqs)-1 {
+			for k := range state {
+				state[k] = enc[k].states[c.code[k]][0]
+			}
+		} else {
+			for _, k := range [...]seqCode{seqOffset, seqMatch, seqLiteral} {
+				s, v, n := enc[k].transition(c.code[k], state[k])
+				bw.add(v, n)
+				state[k] = s
+			}
+		}
+		bw.add(c.extra[seqLiteral], c.nbits[seqLiteral])
+		bw.add(c.extra[seqMatch], c.nbits[seqMatch])
+		bw.add(c.extra[seqOffset], c.nbits[seqOffset])
+	}
+	for _, k := range [...]seqCode{seqMatch, seqOffset, seqLiteral} {
+		bw.add(uint32(state[k]), enc[k].tableBits)
+	}
+	return bw.finish()
+}
+
+// bitWriter writes a bit stream which is read by a reverseBitReader.
+type bitWriter struct {
+	out  []byte
+	bits uint64
+	cnt  uint
+}
+
+// add writes the low n bits of v.
+func (bw *bitWriter) add(v uint32, n uint8) {
+	bw.bits |= uint64(v) << bw.cnt
+	bw.cnt += uint(n)
+	for bw.cnt >= 8 {
+		bw.out = append(bw.out, byte(bw.bits))
+		bw.bits >>= 8
+		bw.cnt -= 8
+	}
+}
+
+// finish writes the 1 bit which marks the end of the stream,
+// and returns the output.
+func (bw *bitWriter) finish() []byte {
+	bw.add(1, 1)
+	if bw.cnt > 0 {
+		bw.out = append(bw.out, byte(bw.bits))
+	}
+	return bw.out
+}
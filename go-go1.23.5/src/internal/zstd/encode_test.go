@@ -0,0 +1,154 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func compress(t testing.TB, data []byte, flushEvery int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for len(data) > 0 {
+		n := len(data)
+		if flushEvery > 0 && n > flushEvery {
+			n = flushEvery
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+		if flushEvery > 0 {
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 300<<10)
+	rnd.Read(random)
+	text := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 5000))
+	var mixed []byte
+	for len(mixed) < 1<<20 {
+		mixed = append(mixed, text[:rnd.Intn(200)]...)
+		mixed = append(mixed, random[:rnd.Intn(50)]...)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		flushEvery int
+	}{
+		{"empty", nil, 0},
+		{"byte", []byte{'x'}, 0},
+		{"hello", []byte("hello, world\n"), 0},
+		{"run", bytes.Repeat([]byte{'a'}, 100<<10), 0},
+		{"text", text, 0},
+		{"random", random, 0},
+		{"mixed", mixed, 0},
+		{"mixed-flush", mixed, 1000},
+		{"long-lits", append(random[:70000:70000], text...), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			compressed := compress(t, test.data, test.flushEvery)
+			t.Logf("compressed %d bytes to %d", len(test.data), len(compressed))
+			got, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, test.data) {
+				showDiffs(t, got, test.data)
+			}
+			if test.name == "text" && len(compressed) > len(test.data)/10 {
+				t.Errorf("repetitive text compressed to %d bytes, want at most %d", len(compressed), len(test.data)/10)
+			}
+		})
+	}
+}
+
+func TestWriterLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping expensive test in short mode")
+	}
+	data := bigData(t)
+	compressed := compress(t, data, 0)
+	t.Logf("compressed %d bytes to %d", len(data), len(compressed))
+	got, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		showDiffs(t, got, data)
+	}
+}
+
+// TestWriterZstd checks that the zstd program can decompress our output.
+func TestWriterZstd(t *testing.T) {
+	zstd := findZstd(t)
+	data, err := os.ReadFile("../../testdata/Isaac.Newton-Opticks.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(zstd, "-d")
+	cmd.Stdin = bytes.NewReader(compress(t, data, 0))
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("zstd -d failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		showDiffs(t, out.Bytes(), data)
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	data := []byte(strings.Repeat("hello, world\n", 1000))
+	var buf1, buf2 bytes.Buffer
+	w := NewWriter(&buf1)
+	w.Write(data)
+	w.Close()
+	w.Reset(&buf2)
+	w.Write(data)
+	w.Close()
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Errorf("output after Reset differs from the first output")
+	}
+	if _, err := w.Write(data); err == nil {
+		t.Errorf("Write after Close succeeded")
+	}
+}
+
+func TestWriterAllocs(t *testing.T) {
+	data := bytes.Repeat([]byte("hello, world\n"), 100)
+	zw := NewWriter(io.Discard)
+	allocs := testing.AllocsPerRun(10, func() {
+		zw.Reset(io.Discard)
+		zw.Write(data)
+		zw.Close()
+	})
+	if allocs > 0 {
+		t.Errorf("reused Writer: %v allocs per stream, want 0", allocs)
+	}
+	if size := unsafe.Sizeof(*zw) + uintptr(cap(zw.hist)); size > 128<<10 {
+		t.Errorf("Writer uses %d bytes after a short stream, want at most 128K", size)
+	}
+}
@@ -0,0 +1,475 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Connecting to services described by SVCB and HTTPS records,
+// with Happy Eyeballs Version 3 (draft-ietf-happy-happyeyeballs-v3).
+
+package net
+
+import (
+	"cmp"
+	"context"
+	"internal/itoa"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+const (
+	// resolutionDelay is the time for which to wait for AAAA and
+	// SVCB answers after the first answer with addresses.
+	resolutionDelay = 50 * time.Millisecond
+
+	// defaultAttemptDelay is the default time after which a
+	// connection attempt is made while the previous one is pending.
+	defaultAttemptDelay = 250 * time.Millisecond
+
+	// maxSVCBAliases is the number of alias mode records
+	// which are followed when looking up SVCB records.
+	maxSVCBAliases = 8
+)
+
+// svcbDefaultALPN holds the protocols supported by the endpoints of
+// services with the given schemes unless they have the no-default-alpn
+// parameter.
+var svcbDefaultALPN = map[string]string{
+	"https": "http/1.1",
+}
+
+// DialService connects to the service identified by scheme, such as
+// "https", at address on the named network, which must be "tcp", "tcp4"
+// or "tcp6". It is like [Dialer.DialContext], but uses the service's
+// SVCB records (RFC 9460), or HTTPS records for the "https" scheme, to
+// find its endpoints and the ports and protocols which they support.
+//
+// The alpn argument lists the application protocols supported by the
+// caller, such as "h2" and "http/1.1". Endpoints which support none
+// of them are not used. If alpn is nil, all endpoints are used. The
+// SVCB record of the endpoint connected to is returned, to allow the
+// caller to use its parameters, such as the ECH configuration to use
+// with TLS, or nil if the service has no usable records. If no usable
+// endpoint can be connected to, DialService connects to address
+// directly, unless an endpoint has an ECH configuration, since doing
+// so would reveal the name of the service.
+//
+// The endpoints and their addresses are looked up concurrently, and
+// connections are attempted as described by Happy Eyeballs Version 3:
+// attempts start once the AAAA and SVCB answers are received, or a
+// short time after A answers if they are not; endpoints are tried in
+// order of priority, and addresses alternate between IPv6 and IPv4,
+// starting with IPv6. An attempt starts after each preceding one
+// fails or after FallbackDelay, with a default of 250ms, whichever is
+// sooner. If FallbackDelay is negative, attempts are made one at a time.
+func (d *Dialer) DialService(ctx context.Context, network, address, scheme string, alpn []string) (Conn, *SVCB, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, nil, &OpError{Op: "dial", Net: network, Err: UnknownNetworkError(network)}
+	}
+	host, service, err := SplitHostPort(address)
+	if err != nil {
+		return nil, nil, &OpError{Op: "dial", Net: network, Err: err}
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		c, err := d.DialContext(ctx, network, address)
+		return c, nil, err
+	}
+	if addrs, _ := lookupStaticHost(host); len(addrs) > 0 {
+		c, err := d.DialContext(ctx, network, address)
+		return c, nil, err
+	}
+
+	deadline := d.deadline(ctx, time.Now())
+	if !deadline.IsZero() {
+		if d, ok := ctx.Deadline(); !ok || deadline.Before(d) {
+			subCtx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+			ctx = subCtx
+		}
+	}
+	r := d.resolver()
+	port, err := r.LookupPort(ctx, network, service)
+	if err != nil {
+		return nil, nil, &OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	sd := &svcDialer{
+		sys:     &sysDialer{Dialer: *d, network: network, address: address},
+		r:       r,
+		network: network,
+		scheme:  scheme,
+		alpn:    alpn,
+		host:    host,
+		port:    port,
+		targets: make(map[string]*svcTarget),
+		tried:   make(map[svcCandidate]bool),
+	}
+	return sd.dial(ctx)
+}
+
+// An svcEndpoint is an endpoint of a service.
+type svcEndpoint struct {
+	rec    *SVCB  // nil for the origin
+	name   string // target name
+	target *svcTarget
+	port   int
+}
+
+// An svcTarget holds the addresses of an endpoint's target name.
+type svcTarget struct {
+	ip6, ip4     []netip.Addr
+	done6, done4 bool
+	err          error // first lookup error
+}
+
+func (t *svcTarget) done() bool { return t.done6 && t.done4 }
+
+// An svcCandidate is a connection attempt which may be made.
+type svcCandidate struct {
+	ep   *svcEndpoint
+	addr netip.Addr
+}
+
+type svcAddrAnswer struct {
+	name  string
+	ipv6  bool
+	addrs []netip.Addr
+	err   error
+}
+
+type svcAttemptResult struct {
+	c   Conn
+	err error
+	ep  *svcEndpoint
+}
+
+// An svcDialer holds the state of a call to DialService.
+type svcDialer struct {
+	sys      *sysDialer
+	r        *Resolver
+	network  string
+	scheme   string
+	alpn     []string
+	host     string
+	port     int
+	origin   *svcEndpoint
+	svcDone  bool
+	services []*svcEndpoint // usable endpoints from SVCB records
+	targets  map[string]*svcTarget
+	tried    map[svcCandidate]bool
+}
+
+func (sd *svcDialer) dial(ctx context.Context) (Conn, *SVCB, error) {
+	returned := make(chan struct{})
+	defer close(returned)
+	lookupCtx, cancelLookups := context.WithCancel(ctx)
+	defer cancelLookups()
+	attemptCtx, cancelAttempts := context.WithCancel(ctx)
+	defer cancelAttempts()
+
+	svcc := make(chan []*svcEndpoint)
+	addrc := make(chan svcAddrAnswer)
+	results := make(chan svcAttemptResult)
+
+	lookupAddrs := func(name string) *svcTarget {
+		key := dnsCacheName(name)
+		if t := sd.targets[key]; t != nil {
+			return t
+		}
+		t := &svcTarget{}
+		sd.targets[key] = t
+		for _, ipv6 := range []bool{true, false} {
+			if ipv6 && sd.network == "tcp4" || !ipv6 && sd.network == "tcp6" {
+				if ipv6 {
+					t.done6 = true
+				} else {
+					t.done4 = true
+				}
+				continue
+			}
+			go func() {
+				network := "ip4"
+				if ipv6 {
+					network = "ip6"
+				}
+				addrs, err := sd.r.LookupNetIP(lookupCtx, network, name)
+				select {
+				case addrc <- svcAddrAnswer{name, ipv6, addrs, err}:
+				case <-returned:
+				}
+			}()
+		}
+		return t
+	}
+	sd.origin = &svcEndpoint{name: sd.host, target: lookupAddrs(sd.host), port: sd.port}
+	go func() {
+		eps := sd.lookupEndpoints(lookupCtx)
+		select {
+		case svcc <- eps:
+		case <-returned:
+		}
+	}()
+
+	var (
+		delayTimer   *time.Timer // resolution delay
+		delayed      bool        // resolution delay has passed
+		attemptTimer *time.Timer
+		wantAttempt  = true // an attempt may be made when a candidate is available
+		pending      int    // attempts in progress
+		firstErr     error
+	)
+	defer func() {
+		if delayTimer != nil {
+			delayTimer.Stop()
+		}
+		if attemptTimer != nil {
+			attemptTimer.Stop()
+		}
+	}()
+	timerC := func(t *time.Timer) <-chan time.Time {
+		if t == nil {
+			return nil
+		}
+		return t.C
+	}
+
+	for {
+		// Start an attempt if one is wanted, and the resolution delay
+		// has passed or the answers it waits for have been received.
+		if wantAttempt && (delayed || sd.svcDone && sd.origin.target.done6) {
+			if c, ok := sd.next(); ok {
+				sd.tried[c] = true
+				wantAttempt = false
+				pending++
+				go func() {
+					conn, err := sd.sys.dialSingle(attemptCtx, &TCPAddr{IP: c.addr.AsSlice(), Port: c.ep.port, Zone: c.addr.Zone()})
+					select {
+					case results <- svcAttemptResult{conn, err, c.ep}:
+					case <-returned:
+						if conn != nil {
+							conn.Close()
+						}
+					}
+				}()
+				if delay := sd.sys.FallbackDelay; delay >= 0 {
+					if delay == 0 {
+						delay = defaultAttemptDelay
+					}
+					if attemptTimer == nil {
+						attemptTimer = time.NewTimer(delay)
+					} else {
+						attemptTimer.Reset(delay)
+					}
+				}
+			}
+		}
+		if pending == 0 && sd.exhausted() {
+			if firstErr == nil {
+				firstErr = sd.origin.target.err
+			}
+			if firstErr == nil {
+				firstErr = errNoSuitableAddress
+			}
+			if _, ok := firstErr.(*OpError); !ok {
+				firstErr = &OpError{Op: "dial", Net: sd.network, Err: firstErr}
+			}
+			return nil, nil, firstErr
+		}
+
+		select {
+		case eps := <-svcc:
+			sd.svcDone = true
+			for _, ep := range eps {
+				ep.target = lookupAddrs(ep.name)
+			}
+			sd.services = eps
+		case a := <-addrc:
+			t := sd.targets[dnsCacheName(a.name)]
+			if a.ipv6 {
+				t.ip6, t.done6 = a.addrs, true
+			} else {
+				t.ip4, t.done4 = a.addrs, true
+			}
+			if a.err != nil && t.err == nil {
+				t.err = a.err
+			}
+			if t == sd.origin.target && len(a.addrs) > 0 && delayTimer == nil {
+				delayTimer = time.NewTimer(resolutionDelay)
+			}
+		case <-timerC(delayTimer):
+			delayed = true
+		case <-timerC(attemptTimer):
+			wantAttempt = true
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.c, res.ep.rec, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			wantAttempt = true
+		case <-ctx.Done():
+			return nil, nil, &OpError{Op: "dial", Net: sd.network, Err: mapErr(ctx.Err())}
+		}
+		if sd.svcDone && sd.origin.target.done() {
+			delayed = true
+		}
+	}
+}
+
+// next returns the next connection attempt to make, if any.
+// Endpoints are tried in order of priority. The origin is tried
+// after them, and only when their addresses are known and none has
+// an ECH configuration.
+func (sd *svcDialer) next() (svcCandidate, bool) {
+	for _, ep := range sd.services {
+		if c, ok := sd.nextFor(ep); ok {
+			return c, true
+		}
+	}
+	if !sd.useOrigin() {
+		return svcCandidate{}, false
+	}
+	return sd.nextFor(sd.origin)
+}
+
+// useOrigin reports whether the origin may be tried now.
+func (sd *svcDialer) useOrigin() bool {
+	for _, ep := range sd.services {
+		if ep.rec.ECHConfigList() != nil || !ep.target.done() {
+			return false
+		}
+	}
+	return true
+}
+
+// nextFor returns the next untried address of ep, alternating between
+// the address families starting with IPv6. If the addresses of the
+// target cannot be looked up, those hinted by the record are used.
+func (sd *svcDialer) nextFor(ep *svcEndpoint) (svcCandidate, bool) {
+	t := ep.target
+	ip6, ip4 := t.ip6, t.ip4
+	if ep.rec != nil && t.done() && len(ip6) == 0 && len(ip4) == 0 {
+		for _, ip := range ep.rec.IPHints() {
+			if ip.Is4() && sd.network != "tcp6" {
+				ip4 = append(ip4, ip)
+			} else if ip.Is6() && sd.network != "tcp4" {
+				ip6 = append(ip6, ip)
+			}
+		}
+	}
+	for i := 0; i < len(ip6) || i < len(ip4); i++ {
+		for _, addrs := range [][]netip.Addr{ip6, ip4} {
+			if i < len(addrs) {
+				c := svcCandidate{ep, addrs[i]}
+				if !sd.tried[c] {
+					return c, true
+				}
+			}
+		}
+	}
+	return svcCandidate{}, false
+}
+
+// exhausted reports whether there are no more attempts to make.
+func (sd *svcDialer) exhausted() bool {
+	if !sd.svcDone || !sd.origin.target.done() {
+		return false
+	}
+	for _, t := range sd.targets {
+		if !t.done() {
+			return false
+		}
+	}
+	_, ok := sd.next()
+	return !ok
+}
+
+// lookupEndpoints returns the usable endpoints of the service, in
+// order of priority, without their targets set. It returns none if
+// the service has no usable SVCB records or they cannot be looked up.
+func (sd *svcDialer) lookupEndpoints(ctx context.Context) []*svcEndpoint {
+	typ := DNSTypeSVCB
+	name := "_" + itoa.Itoa(sd.port) + "._" + sd.scheme + "." + sd.host
+	if sd.scheme == "https" {
+		typ = DNSTypeHTTPS
+		if sd.port == 443 {
+			name = sd.host
+		}
+	}
+	var eps []*svcEndpoint
+	for i := 0; i <= maxSVCBAliases; i++ {
+		resp, err := sd.r.LookupRecords(ctx, name, typ)
+		if err != nil {
+			return nil
+		}
+		var alias *SVCB
+		for _, rec := range resp.Answers {
+			s, ok := rec.Value.(*SVCB)
+			if rec.Type != typ || !ok {
+				continue
+			}
+			if s.Priority == 0 {
+				alias = s
+				break
+			}
+			if !sd.usable(s) {
+				continue
+			}
+			port, ok := s.Port()
+			if !ok {
+				port = uint16(sd.port)
+			}
+			target := s.Target
+			if target == "." {
+				target = rec.Name
+			}
+			eps = append(eps, &svcEndpoint{rec: s, name: target, port: int(port)})
+		}
+		if alias == nil {
+			break
+		}
+		// An alias mode record takes precedence over
+		// service mode records (RFC 9460 Section 2.4.2).
+		eps = nil
+		if alias.Target == "." {
+			break
+		}
+		name = alias.Target
+	}
+	slices.SortStableFunc(eps, func(a, b *svcEndpoint) int {
+		return cmp.Compare(a.rec.Priority, b.rec.Priority)
+	})
+	return eps
+}
+
+// usable reports whether the endpoint described by s may be used: all
+// its mandatory parameters are understood, and it supports a protocol
+// in sd.alpn.
+func (sd *svcDialer) usable(s *SVCB) bool {
+	if v, ok := s.Param(SVCParamMandatory); ok {
+		for ; len(v) >= 2; v = v[2:] {
+			if SVCParamKey(uint16(v[0])<<8|uint16(v[1])) > SVCParamIPv6Hint {
+				return false
+			}
+		}
+	}
+	if sd.alpn == nil {
+		return true
+	}
+	protos := s.ALPN()
+	if def, ok := svcbDefaultALPN[sd.scheme]; ok && !s.NoDefaultALPN() {
+		protos = append(protos, def)
+	}
+	for _, p := range protos {
+		if slices.Contains(sd.alpn, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package net
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// svcbData returns the data of an SVCB record.
+func svcbData(priority uint16, target string, params ...SVCParam) []byte {
+	b := []byte{byte(priority >> 8), byte(priority)}
+	for _, label := range strings.Split(strings.TrimSuffix(target, "."), ".") {
+		if label != "" {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	b = append(b, 0)
+	for _, p := range params {
+		b = append(b, byte(p.Key>>8), byte(p.Key), byte(len(p.Value)>>8), byte(len(p.Value)))
+		b = append(b, p.Value...)
+	}
+	return b
+}
+
+func svcbPortParam(port int) SVCParam {
+	return SVCParam{SVCParamPort, []byte{byte(port >> 8), byte(port)}}
+}
+
+// svcTestServer returns a DNS server answering queries from zone,
+// which maps "name TYPE" to the addresses, for A and AAAA queries,
+// or the data of the records.
+func svcTestServer(zone map[string][]any) *fakeDNSServer {
+	return &fakeDNSServer{rh: func(_, _ string, q dnsmessage.Message, _ time.Time) (dnsmessage.Message, error) {
+		r := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: q.ID, Response: true, RecursionAvailable: true},
+			Questions: q.Questions,
+		}
+		qq := q.Questions[0]
+		for _, v := range zone[qq.Name.String()+" "+DNSType(qq.Type).String()] {
+			var body dnsmessage.ResourceBody
+			switch v := v.(type) {
+			case netip.Addr:
+				if v.Is4() {
+					body = &dnsmessage.AResource{A: v.As4()}
+				} else {
+					body = &dnsmessage.AAAAResource{AAAA: v.As16()}
+				}
+			case []byte:
+				body = &dnsmessage.UnknownResource{Type: qq.Type, Data: v}
+			}
+			r.Answers = append(r.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: qq.Name, Type: qq.Type, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   body,
+			})
+		}
+		return r, nil
+	}}
+}
+
+func TestDialService(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+	if err := conf.writeAndUpdate([]string{"nameserver 127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ln := newLocalListener(t, "tcp4")
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	port := ln.Addr().(*TCPAddr).Port
+	portName := "_" + strconv.Itoa(port) + "._https."
+	loopback := netip.MustParseAddr("127.0.0.1")
+	alpnH2 := SVCParam{SVCParamALPN, []byte("\x02h2")}
+	alpnH3 := SVCParam{SVCParamALPN, []byte("\x02h3")}
+	noDefault := SVCParam{SVCParamNoDefaultALPN, nil}
+
+	zone := map[string][]any{
+		// A service on another port of the same host.
+		"www.example. HTTPS": {svcbData(1, ".", alpnH2, svcbPortParam(port))},
+		"www.example. A":     {loopback},
+		// An alias of it.
+		"alias.example. HTTPS": {
+			svcbData(0, "www.example."),
+			svcbData(1, ".", svcbPortParam(1)), // ignored
+		},
+		// A service whose only endpoint supports HTTP/3.
+		portName + "h3.example. HTTPS": {svcbData(1, ".", alpnH3, noDefault, svcbPortParam(1))},
+		"h3.example. A":                {loopback},
+		// A service with an endpoint without addresses,
+		// but with hints.
+		"hints.example. HTTPS": {svcbData(1, "missing.example.", svcbPortParam(port), SVCParam{SVCParamIPv4Hint, []byte{127, 0, 0, 1}})},
+	}
+	r := &Resolver{PreferGo: true, Dial: svcTestServer(zone).DialContext}
+	d := &Dialer{Resolver: r, Timeout: 5 * time.Second}
+
+	for _, tt := range []struct {
+		address  string
+		alpn     []string
+		priority uint16 // of the record returned; 0 for none
+	}{
+		{"www.example.:443", []string{"h2", "http/1.1"}, 1},
+		{"www.example.:443", nil, 1},
+		{"www.example.:443", []string{"http/1.1"}, 1},
+		{"alias.example.:443", []string{"h2"}, 1},
+		{"h3.example.:" + strconv.Itoa(port), []string{"h2", "http/1.1"}, 0},
+		{"hints.example.:443", []string{"http/1.1"}, 1},
+		{"127.0.0.1:" + strconv.Itoa(port), []string{"http/1.1"}, 0},
+	} {
+		c, rec, err := d.DialService(context.Background(), "tcp", tt.address, "https", tt.alpn)
+		if err != nil {
+			t.Errorf("DialService(%q, %q): %v", tt.address, tt.alpn, err)
+			continue
+		}
+		if got := c.RemoteAddr().String(); got != ln.Addr().String() {
+			t.Errorf("DialService(%q, %q) connected to %v, want %v", tt.address, tt.alpn, got, ln.Addr())
+		}
+		c.Close()
+		if rec == nil && tt.priority != 0 || rec != nil && rec.Priority != tt.priority {
+			t.Errorf("DialService(%q, %q) returned record %+v, want priority %d", tt.address, tt.alpn, rec, tt.priority)
+		}
+	}
+
+	if _, _, err := d.DialService(context.Background(), "udp", "www.example.:443", "https", nil); err == nil {
+		t.Errorf("DialService with network udp succeeded")
+	}
+}
+
+func TestDialServiceECH(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+	if err := conf.writeAndUpdate([]string{"nameserver 127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		attempts []string
+	)
+	origTestHookDialTCP := testHookDialTCP
+	defer func() { testHookDialTCP = origTestHookDialTCP }()
+	testHookDialTCP = func(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+		mu.Lock()
+		attempts = append(attempts, raddr.String())
+		mu.Unlock()
+		return nil, errors.New("connection refused")
+	}
+
+	zone := map[string][]any{
+		"ech.example. HTTPS": {svcbData(1, "svc.example.", svcbPortParam(8443), SVCParam{SVCParamECH, []byte{1, 2, 3}})},
+		"svc.example. AAAA":  {netip.MustParseAddr("2001:db8::1")},
+		"svc.example. A":     {netip.MustParseAddr("192.0.2.1")},
+		"ech.example. A":     {netip.MustParseAddr("192.0.2.2")},
+	}
+	d := &Dialer{Resolver: &Resolver{PreferGo: true, Dial: svcTestServer(zone).DialContext}}
+	if _, _, err := d.DialService(context.Background(), "tcp", "ech.example.:443", "https", nil); err == nil {
+		t.Fatal("DialService succeeded")
+	}
+	// The origin is not connected to, since it would reveal the name.
+	slices.Sort(attempts)
+	want := []string{"192.0.2.1:8443", "[2001:db8::1]:8443"}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempted connections to %v, want %v", attempts, want)
+	}
+}
+
+func TestSVCDialerNext(t *testing.T) {
+	addrs := func(ss ...string) []netip.Addr {
+		var as []netip.Addr
+		for _, s := range ss {
+			as = append(as, netip.MustParseAddr(s))
+		}
+		return as
+	}
+	a := &svcTarget{ip6: addrs("2001:db8::1", "2001:db8::2"), ip4: addrs("192.0.2.1"), done6: true, done4: true}
+	b := &svcTarget{ip4: addrs("192.0.2.2"), done6: true, done4: true}
+	origin := &svcTarget{ip6: addrs("2001:db8::3"), ip4: addrs("192.0.2.3"), done6: true}
+	sd := &svcDialer{
+		services: []*svcEndpoint{
+			{rec: &SVCB{Priority: 1}, target: a, port: 443},
+			{rec: &SVCB{Priority: 2}, target: b, port: 8443},
+		},
+		origin: &svcEndpoint{target: origin, port: 443},
+		tried:  make(map[svcCandidate]bool),
+	}
+	var got []string
+	for {
+		c, ok := sd.next()
+		if !ok {
+			if !origin.done4 {
+				// The origin is tried once its
+				// addresses are known.
+				origin.done4 = true
+				continue
+			}
+			break
+		}
+		sd.tried[c] = true
+		got = append(got, netip.AddrPortFrom(c.addr, uint16(c.ep.port)).String())
+	}
+	want := []string{
+		"[2001:db8::1]:443", "192.0.2.1:443", "[2001:db8::2]:443",
+		"192.0.2.2:8443",
+		"[2001:db8::3]:443", "192.0.2.3:443",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got attempts %v, want %v", got, want)
+	}
+}
+
+func TestSVCDialerUsable(t *testing.T) {
+	sd := &svcDialer{scheme: "https", alpn: []string{"h2", "http/1.1"}}
+	for _, tt := range []struct {
+		params []SVCParam
+		want   bool
+	}{
+		{nil, true},
+		{[]SVCParam{{SVCParamALPN, []byte("\x02h3")}}, true},
+		{[]SVCParam{{SVCParamALPN, []byte("\x02h3")}, {SVCParamNoDefaultALPN, nil}}, false},
+		{[]SVCParam{{SVCParamALPN, []byte("\x02h2")}, {SVCParamNoDefaultALPN, nil}}, true},
+		{[]SVCParam{{SVCParamMandatory, []byte{0, 3}}, {SVCParamPort, []byte{1, 187}}}, true},
+		{[]SVCParam{{SVCParamMandatory, []byte{0, 9}}}, false},
+	} {
+		if got := sd.usable(&SVCB{Priority: 1, Target: ".", Params: tt.params}); got != tt.want {
+			t.Errorf("usable(%+v) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dns provides encrypted transports for Go's built-in DNS
+// resolver, and a DNS server.
+//
+// The transports are a [TLSTransport] for DNS over TLS (RFC 7858), and
+// an [HTTPSTransport] for DNS over HTTPS (RFC 8484). To use one, set it
+// as the Transport of a [net.Resolver]:
+//
+//	r := &net.Resolver{
+//		Transport: &dns.HTTPSTransport{URL: "https://192.0.2.1/dns-query"},
+//	}
+//	addrs, err := r.LookupHost(ctx, "go.dev")
+//
+// A [Server] answers queries over UDP and TCP using a [Handler], such
+// as a [Zone] of records, which may be parsed from a zone file with
+// [ParseZone]. A [ServeMux] serves several zones. A server may also
+// answer the queries of a Resolver without using the network, which
+// is useful in tests:
+//
+//	zone, err := dns.ParseZone(strings.NewReader(`
+//	$TTL 300
+//	@    SOA  ns hostmaster 1 3600 600 86400 60
+//	www  A    192.0.2.1
+//	`), "example.")
+//	...
+//	srv := &dns.Server{Handler: zone}
+//	r := &net.Resolver{PreferGo: true, Dial: srv.DialContext}
+//	addrs, err := r.LookupHost(ctx, "www.example.")
+package dns
+
+import "time"
+
+// maxMessageSize is the largest DNS message which can be sent over
+// TCP or TLS, and the largest response read from an HTTPS server.
+const maxMessageSize = 65535
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancellation of network operations.
+var aLongTimeAgo = time.Unix(1, 0)
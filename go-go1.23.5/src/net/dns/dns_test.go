@@ -0,0 +1,240 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/dns"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var testAddr = [4]byte{192, 0, 2, 1}
+
+// answer returns the response to the query msg, which has an A record
+// for every name.
+func answer(t *testing.T, msg []byte) []byte {
+	var q dnsmessage.Message
+	if err := q.Unpack(msg); err != nil {
+		t.Errorf("unpacking query: %v", err)
+		return nil
+	}
+	r := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: q.ID, Response: true, RecursionAvailable: true},
+		Questions: q.Questions,
+	}
+	if q.Questions[0].Type == dnsmessage.TypeA {
+		r.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: testAddr},
+		}}
+	}
+	b, err := r.Pack()
+	if err != nil {
+		t.Errorf("packing response: %v", err)
+	}
+	return b
+}
+
+func checkLookup(t *testing.T, r *net.Resolver) {
+	t.Helper()
+	addrs, err := r.LookupHost(context.Background(), "www.example.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"192.0.2.1"}; !slices.Equal(addrs, want) {
+		t.Errorf("LookupHost = %v, want %v", addrs, want)
+	}
+}
+
+func TestHTTPSTransport(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if req.Method != "POST" || req.URL.Path != "/dns-query" || req.Header.Get("Content-Type") != "application/dns-message" {
+			t.Errorf("unexpected request %v %v with Content-Type %q", req.Method, req.URL, req.Header.Get("Content-Type"))
+		}
+		msg, _ := io.ReadAll(req.Body)
+		if len(msg) < 2 || msg[0] != 0 || msg[1] != 0 {
+			t.Errorf("query ID is not zero")
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(answer(t, msg))
+	}))
+	defer srv.Close()
+
+	r := &net.Resolver{Transport: &dns.HTTPSTransport{URL: srv.URL + "/dns-query", Client: srv.Client()}}
+	checkLookup(t, r)
+	if requests.Load() == 0 {
+		t.Errorf("no requests sent to the server")
+	}
+
+	// Errors from the server are reported.
+	r.Transport = &dns.HTTPSTransport{URL: srv.URL + "/dns-query", Client: &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": {"text/html"}},
+				Body:       http.NoBody,
+			}, nil
+		}),
+	}}
+	if _, err := r.LookupHost(context.Background(), "www.example."); err == nil {
+		t.Errorf("lookup with unexpected Content-Type succeeded")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newTLSServer starts a DNS over TLS server which responds to each
+// query with answer(query). It returns the server's address, the
+// roots which trust it, and a count of the connections accepted.
+func newTLSServer(t *testing.T, answer func([]byte) []byte) (string, *x509.CertPool, *atomic.Int32) {
+	// Borrow the certificate of an httptest server.
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(hs.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(hs.Certificate())
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: hs.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns := new(atomic.Int32)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer c.Close()
+				b := make([]byte, 2)
+				for {
+					if _, err := io.ReadFull(c, b[:2]); err != nil {
+						return
+					}
+					msg := make([]byte, int(b[0])<<8|int(b[1]))
+					if _, err := io.ReadFull(c, msg); err != nil {
+						return
+					}
+					resp := answer(msg)
+					c.Write(append([]byte{byte(len(resp) >> 8), byte(len(resp))}, resp...))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), roots, conns
+}
+
+func TestTLSTransport(t *testing.T) {
+	addr, roots, conns := newTLSServer(t, func(msg []byte) []byte { return answer(t, msg) })
+	tr := &dns.TLSTransport{Addr: addr, Config: &tls.Config{RootCAs: roots}}
+	defer tr.CloseIdleConnections()
+	r := &net.Resolver{Transport: tr}
+	checkLookup(t, r)
+	checkLookup(t, r)
+	// LookupHost sends A and AAAA queries concurrently,
+	// so up to two connections are made and then reused.
+	if n := conns.Load(); n == 0 || n > 2 {
+		t.Errorf("made %v connections, want 1 or 2", n)
+	}
+
+	// Servers which cannot be authenticated are not used.
+	r = &net.Resolver{Transport: &dns.TLSTransport{Addr: addr}}
+	if _, err := r.LookupHost(context.Background(), "www.example."); err == nil {
+		t.Errorf("lookup with untrusted server succeeded")
+	}
+}
+
+// closeCountingConn is a net.Conn which counts the times it is closed.
+type closeCountingConn struct {
+	net.Conn
+	closes *atomic.Int32
+}
+
+func (c closeCountingConn) Close() error {
+	c.closes.Add(1)
+	return c.Conn.Close()
+}
+
+func TestTLSTransportIdleConns(t *testing.T) {
+	const burst = 5
+	var queries atomic.Int32
+	release := make(chan struct{})
+	addr, roots, _ := newTLSServer(t, func(msg []byte) []byte {
+		// Hold the responses to the first queries until all have
+		// arrived, so that each is sent on its own connection.
+		if queries.Add(1) == burst {
+			close(release)
+		}
+		<-release
+		return answer(t, msg)
+	})
+	var dials, closes atomic.Int32
+	const idleTimeout = 50 * time.Millisecond
+	tr := &dns.TLSTransport{
+		Addr:         addr,
+		Config:       &tls.Config{RootCAs: roots},
+		IdleTimeout:  idleTimeout,
+		MaxIdleConns: 2,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			c, err := new(net.Dialer).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			dials.Add(1)
+			return closeCountingConn{c, &closes}, nil
+		},
+	}
+	defer tr.CloseIdleConnections()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName("www.example."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	query, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := func() {
+		if _, err := tr.Exchange(context.Background(), "", query); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range burst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exchange()
+		}()
+	}
+	wg.Wait()
+	if d, c := dials.Load(), closes.Load(); d != burst || c != burst-2 {
+		t.Errorf("after %v concurrent queries: %v dials and %v closes, want %v and %v", burst, d, c, burst, burst-2)
+	}
+
+	// Connections which have been idle for too long are closed.
+	time.Sleep(2 * idleTimeout)
+	exchange()
+	if d, c := dials.Load(), closes.Load(); d != burst+1 || c != burst {
+		t.Errorf("after idle timeout: %v dials and %v closes, want %v and %v", d, c, burst+1, burst)
+	}
+}
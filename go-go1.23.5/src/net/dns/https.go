@@ -0,0 +1,82 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const dnsMessageType = "application/dns-message"
+
+// An HTTPSTransport is a [net.DNSTransport] which sends queries using
+// DNS over HTTPS (RFC 8484), in POST requests to a single URL.
+type HTTPSTransport struct {
+	// URL is the URL to which queries are sent, such as
+	// "https://dns.example/dns-query". The server addresses given
+	// to Exchange are ignored.
+	URL string
+
+	// Client is the HTTP client with which queries are sent.
+	// If nil, http.DefaultClient is used.
+	//
+	// The client must not look up the host of URL using a Resolver
+	// with this transport. Since the default client uses the default
+	// Resolver, if this transport is set on [net.DefaultResolver] the
+	// host should be an IP address, or the client's transport should
+	// dial it using a different Resolver.
+	Client *http.Client
+}
+
+// Exchange implements [net.DNSTransport].
+//
+// The query is sent with an ID of zero, to improve the efficiency of
+// HTTP caches, and the ID of the response is replaced with the original.
+func (t *HTTPSTransport) Exchange(ctx context.Context, address string, msg []byte) ([]byte, error) {
+	if len(msg) < 2 {
+		return nil, errors.New("dns: query too short")
+	}
+	query := bytes.Clone(msg)
+	query[0], query[1] = 0, 0
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dnsMessageType)
+	req.Header.Set("Accept", dnsMessageType)
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dns: unexpected HTTP status %q from %v", res.Status, t.URL)
+	}
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt != dnsMessageType {
+		return nil, fmt.Errorf("dns: unexpected Content-Type %q from %v", res.Header.Get("Content-Type"), t.URL)
+	}
+	resp, err := io.ReadAll(io.LimitReader(res.Body, maxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) > maxMessageSize {
+		return nil, fmt.Errorf("dns: response from %v too large", t.URL)
+	}
+	if len(resp) < 2 {
+		return nil, fmt.Errorf("dns: response from %v too short", t.URL)
+	}
+	resp[0], resp[1] = msg[0], msg[1]
+	return resp, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DialContext returns an in-memory connection to s, on which queries
+// are answered without using the network. The network must be "udp"
+// or "tcp", optionally followed by "4" or "6", and address is ignored.
+// Queries written to a "udp" connection are answered as if they had
+// been received over UDP, and so their responses may be truncated.
+//
+// DialContext has the signature of the Dial field of [net.Resolver],
+// so that a server may answer the queries of a Resolver in tests:
+//
+//	srv := &dns.Server{Handler: zone}
+//	r := &net.Resolver{PreferGo: true, Dial: srv.DialContext}
+func (s *Server) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	c := &memConn{s: s, wake: make(chan struct{}, 1)}
+	switch network {
+	case "udp", "udp4", "udp6":
+		c.network = "udp"
+	case "tcp", "tcp4", "tcp6":
+		c.network = "tcp"
+	default:
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+	if !s.trackConn(c, true) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: ErrServerClosed}
+	}
+	if c.network == "udp" {
+		return &memPacketConn{c}, nil
+	}
+	return c, nil
+}
+
+// A memAddr is the address of either end of an in-memory connection.
+type memAddr string // network
+
+func (a memAddr) Network() string { return string(a) }
+func (a memAddr) String() string  { return "memory" }
+
+// A memConn is an in-memory connection to a server, made by DialContext.
+// Queries are answered as they are written.
+type memConn struct {
+	s       *Server
+	network string // "udp" or "tcp"
+	wake    chan struct{}
+
+	mu           sync.Mutex
+	in           []byte   // incomplete query written to a TCP connection
+	out          [][]byte // responses not yet read
+	closed       bool
+	readDeadline time.Time
+}
+
+// A memPacketConn is a memConn for UDP, on which each read returns
+// one response.
+type memPacketConn struct {
+	*memConn
+}
+
+func (c *memPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, err := c.Read(b)
+	return n, c.RemoteAddr(), err
+}
+
+func (c *memPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.Write(b)
+}
+
+func (c *memConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.out) > 0 {
+			n := copy(b, c.out[0])
+			if c.network == "tcp" && n < len(c.out[0]) {
+				c.out[0] = c.out[0][n:]
+			} else {
+				c.out = c.out[1:]
+			}
+			c.mu.Unlock()
+			return n, nil
+		}
+		closed, deadline := c.closed, c.readDeadline
+		c.mu.Unlock()
+		if closed {
+			return 0, net.ErrClosed
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+		select {
+		case <-c.wake:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (c *memConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return 0, net.ErrClosed
+	}
+	ctx := c.s.context()
+	var resps [][]byte
+	if c.network == "udp" {
+		if resp := c.s.respond(ctx, append([]byte(nil), b...), "udp", c.LocalAddr()); resp != nil {
+			resps = append(resps, resp)
+		}
+	} else {
+		c.mu.Lock()
+		c.in = append(c.in, b...)
+		var queries [][]byte
+		for len(c.in) >= 2 {
+			n := 2 + (int(c.in[0])<<8 | int(c.in[1]))
+			if len(c.in) < n {
+				break
+			}
+			queries = append(queries, c.in[2:n])
+			c.in = c.in[n:]
+		}
+		c.mu.Unlock()
+		for _, q := range queries {
+			if resp := c.s.respond(ctx, q, "tcp", c.LocalAddr()); resp != nil {
+				resps = append(resps, append([]byte{byte(len(resp) >> 8), byte(len(resp))}, resp...))
+			}
+		}
+	}
+	if len(resps) > 0 {
+		c.mu.Lock()
+		c.out = append(c.out, resps...)
+		c.mu.Unlock()
+		c.signal()
+	}
+	return len(b), nil
+}
+
+// signal wakes a pending Read.
+func (c *memConn) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *memConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.signal()
+	c.s.trackConn(c, false)
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr  { return memAddr(c.network) }
+func (c *memConn) RemoteAddr() net.Addr { return memAddr(c.network) }
+
+func (c *memConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+func (c *memConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
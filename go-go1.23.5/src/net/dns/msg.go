@@ -0,0 +1,261 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// An RCode is a DNS response code.
+type RCode uint16
+
+// DNS response codes.
+const (
+	RCodeSuccess        RCode = 0  // NOERROR
+	RCodeFormatError    RCode = 1  // FORMERR
+	RCodeServerFailure  RCode = 2  // SERVFAIL
+	RCodeNameError      RCode = 3  // NXDOMAIN
+	RCodeNotImplemented RCode = 4  // NOTIMP
+	RCodeRefused        RCode = 5  // REFUSED
+	RCodeBadVersion     RCode = 16 // BADVERS, for unsupported EDNS versions
+)
+
+var rcodeNames = map[RCode]string{
+	RCodeSuccess:        "NOERROR",
+	RCodeFormatError:    "FORMERR",
+	RCodeServerFailure:  "SERVFAIL",
+	RCodeNameError:      "NXDOMAIN",
+	RCodeNotImplemented: "NOTIMP",
+	RCodeRefused:        "REFUSED",
+	RCodeBadVersion:     "BADVERS",
+}
+
+// String returns the mnemonic of c, such as "NXDOMAIN".
+func (c RCode) String() string {
+	if s, ok := rcodeNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("RCODE%d", uint16(c))
+}
+
+// buildMessage returns the response with header h to the question q.
+// If edns is set, it has an OPT record advertising udpSize.
+func buildMessage(h dnsmessage.Header, q dnsmessage.Question, rcode RCode, edns bool, udpSize int, answers, authorities, additionals []net.DNSRecord) ([]byte, error) {
+	h.RCode = dnsmessage.RCode(rcode & 0xf)
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), h)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, rec := range answers {
+		if err := appendRecord(&b, rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.StartAuthorities(); err != nil {
+		return nil, err
+	}
+	for _, rec := range authorities {
+		if err := appendRecord(&b, rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	for _, rec := range additionals {
+		if err := appendRecord(&b, rec); err != nil {
+			return nil, err
+		}
+	}
+	if edns {
+		var rh dnsmessage.ResourceHeader
+		if err := rh.SetEDNS0(udpSize, dnsmessage.RCode(rcode), false); err != nil {
+			return nil, err
+		}
+		if err := b.OPTResource(rh, dnsmessage.OPTResource{}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// appendRecord adds rec to the current section of b.
+func appendRecord(b *dnsmessage.Builder, rec net.DNSRecord) error {
+	name, err := newName(rec.Name)
+	if err != nil {
+		return err
+	}
+	class := dnsmessage.Class(rec.Class)
+	if class == 0 {
+		class = dnsmessage.ClassINET
+	}
+	h := dnsmessage.ResourceHeader{
+		Name:  name,
+		Type:  dnsmessage.Type(rec.Type),
+		Class: class,
+		TTL:   uint32(min(max(rec.TTL/time.Second, 0), 1<<31-1)),
+	}
+	if rec.Data != nil {
+		return b.UnknownResource(h, dnsmessage.UnknownResource{Type: h.Type, Data: rec.Data})
+	}
+	switch v := rec.Value.(type) {
+	case netip.Addr:
+		switch {
+		case rec.Type == net.DNSTypeA && v.Is4():
+			return b.AResource(h, dnsmessage.AResource{A: v.As4()})
+		case rec.Type == net.DNSTypeAAAA && v.Is6():
+			return b.AAAAResource(h, dnsmessage.AAAAResource{AAAA: v.As16()})
+		}
+	case string:
+		target, err := newName(v)
+		if err != nil {
+			return err
+		}
+		switch rec.Type {
+		case net.DNSTypeNS:
+			return b.NSResource(h, dnsmessage.NSResource{NS: target})
+		case net.DNSTypeCNAME:
+			return b.CNAMEResource(h, dnsmessage.CNAMEResource{CNAME: target})
+		case net.DNSTypePTR:
+			return b.PTRResource(h, dnsmessage.PTRResource{PTR: target})
+		}
+	case *net.MX:
+		if rec.Type == net.DNSTypeMX {
+			host, err := newName(v.Host)
+			if err != nil {
+				return err
+			}
+			return b.MXResource(h, dnsmessage.MXResource{Pref: v.Pref, MX: host})
+		}
+	case *net.SRV:
+		if rec.Type == net.DNSTypeSRV {
+			target, err := newName(v.Target)
+			if err != nil {
+				return err
+			}
+			return b.SRVResource(h, dnsmessage.SRVResource{Priority: v.Priority, Weight: v.Weight, Port: v.Port, Target: target})
+		}
+	case []string:
+		if rec.Type == net.DNSTypeTXT {
+			return b.TXTResource(h, dnsmessage.TXTResource{TXT: v})
+		}
+	case *net.SOA:
+		if rec.Type == net.DNSTypeSOA {
+			ns, err := newName(v.NS)
+			if err != nil {
+				return err
+			}
+			mbox, err := newName(v.MBox)
+			if err != nil {
+				return err
+			}
+			return b.SOAResource(h, dnsmessage.SOAResource{
+				NS:      ns,
+				MBox:    mbox,
+				Serial:  v.Serial,
+				Refresh: v.Refresh,
+				Retry:   v.Retry,
+				Expire:  v.Expire,
+				MinTTL:  v.MinTTL,
+			})
+		}
+	case *net.CAA:
+		if rec.Type == net.DNSTypeCAA {
+			return b.UnknownResource(h, dnsmessage.UnknownResource{Type: h.Type, Data: caaData(v)})
+		}
+	case *net.TLSA:
+		if rec.Type == net.DNSTypeTLSA {
+			return b.UnknownResource(h, dnsmessage.UnknownResource{Type: h.Type, Data: tlsaData(v)})
+		}
+	case *net.SVCB:
+		if rec.Type == net.DNSTypeSVCB || rec.Type == net.DNSTypeHTTPS {
+			data, err := svcbData(v)
+			if err != nil {
+				return err
+			}
+			return b.UnknownResource(h, dnsmessage.UnknownResource{Type: h.Type, Data: data})
+		}
+	}
+	return fmt.Errorf("dns: %v record for %v has value of type %T", rec.Type, rec.Name, rec.Value)
+}
+
+// newName returns the name s, which is made rooted if it is not.
+func newName(s string) (dnsmessage.Name, error) {
+	if len(s) == 0 || s[len(s)-1] != '.' {
+		s += "."
+	}
+	n, err := dnsmessage.NewName(s)
+	if err != nil {
+		return n, fmt.Errorf("dns: invalid name %q", s)
+	}
+	return n, nil
+}
+
+func caaData(c *net.CAA) []byte {
+	b := []byte{c.Flags, byte(len(c.Tag))}
+	b = append(b, c.Tag...)
+	return append(b, c.Value...)
+}
+
+func tlsaData(t *net.TLSA) []byte {
+	b := []byte{t.Usage, t.Selector, t.MatchingType}
+	return append(b, t.Data...)
+}
+
+func svcbData(s *net.SVCB) ([]byte, error) {
+	b := []byte{byte(s.Priority >> 8), byte(s.Priority)}
+	b, err := appendWireName(b, s.Target)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range s.Params {
+		if i > 0 && p.Key <= s.Params[i-1].Key {
+			return nil, errors.New("dns: SVCB parameters are not in increasing order of key")
+		}
+		if len(p.Value) > 0xffff {
+			return nil, errors.New("dns: SVCB parameter too long")
+		}
+		b = append(b, byte(p.Key>>8), byte(p.Key), byte(len(p.Value)>>8), byte(len(p.Value)))
+		b = append(b, p.Value...)
+	}
+	return b, nil
+}
+
+// appendWireName appends the uncompressed wire format of name to b.
+func appendWireName(b []byte, name string) ([]byte, error) {
+	n, err := newName(name)
+	if err != nil {
+		return nil, err
+	}
+	s := n.String()
+	if s == "." {
+		return append(b, 0), nil
+	}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' {
+			if i == start || i-start > 63 {
+				return nil, fmt.Errorf("dns: invalid name %q", name)
+			}
+			b = append(b, byte(i-start))
+			b = append(b, s[start:i]...)
+			start = i + 1
+		}
+	}
+	return append(b, 0), nil
+}
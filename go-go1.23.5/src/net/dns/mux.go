@@ -0,0 +1,67 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"sync"
+)
+
+// A ServeMux is a [Handler] which passes each query to the handler
+// registered for the zone containing its name. If the name is in
+// several zones, the handler for the one with the longest name is
+// used. Queries for names in no zone are refused.
+type ServeMux struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler // by canonical zone name
+}
+
+// NewServeMux allocates and returns a new [ServeMux].
+func NewServeMux() *ServeMux {
+	return &ServeMux{handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler for the zone with the given name, such
+// as "example.com" or "." for the root zone. If a handler already
+// exists for the zone, Handle panics.
+func (mux *ServeMux) Handle(zone string, handler Handler) {
+	if handler == nil {
+		panic("dns: nil handler")
+	}
+	name := canonicalName(zone)
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if _, ok := mux.handlers[name]; ok {
+		panic("dns: multiple registrations for zone " + name)
+	}
+	if mux.handlers == nil {
+		mux.handlers = make(map[string]Handler)
+	}
+	mux.handlers[name] = handler
+}
+
+// HandleFunc registers the handler function for the zone with the
+// given name.
+func (mux *ServeMux) HandleFunc(zone string, handler func(context.Context, *Request) *Response) {
+	mux.Handle(zone, HandlerFunc(handler))
+}
+
+// ServeDNS implements [Handler].
+func (mux *ServeMux) ServeDNS(ctx context.Context, req *Request) *Response {
+	name := canonicalName(req.Question.Name)
+	mux.mu.RLock()
+	var h Handler
+	for {
+		if h = mux.handlers[name]; h != nil || name == "." {
+			break
+		}
+		name = parentName(name)
+	}
+	mux.mu.RUnlock()
+	if h == nil {
+		return &Response{RCode: RCodeRefused}
+	}
+	return h.ServeDNS(ctx, req)
+}
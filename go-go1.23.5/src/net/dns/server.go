@@ -0,0 +1,522 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// minUDPSize is the size of the largest response which may be
+	// sent over UDP to clients which do not use EDNS(0).
+	minUDPSize = 512
+
+	// defaultMaxUDPSize is the default size of the largest response
+	// sent over UDP, which avoids IP fragmentation on most paths.
+	defaultMaxUDPSize = 1232
+
+	defaultTCPIdleTimeout = 10 * time.Second
+
+	defaultMaxUDPQueries = 1024
+)
+
+// ErrServerClosed is returned by the [Server.Serve], [Server.ServePacket]
+// and [Server.ListenAndServe] methods after a call to [Server.Close].
+var ErrServerClosed = errors.New("dns: Server closed")
+
+// A Handler responds to DNS queries.
+//
+// ServeDNS returns the response to the query req, or nil if no response
+// should be sent. The context is canceled when the server is closed.
+// If ServeDNS panics, the server logs the panic and sends a server
+// failure response.
+// The server builds the response message, setting the ID, question and
+// EDNS(0) record, and truncating it if it is too large to be sent over
+// UDP. If the records of the response cannot be encoded, a server
+// failure response is sent instead.
+type Handler interface {
+	ServeDNS(ctx context.Context, req *Request) *Response
+}
+
+// The HandlerFunc type is an adapter to allow the use of ordinary
+// functions as DNS handlers.
+type HandlerFunc func(ctx context.Context, req *Request) *Response
+
+// ServeDNS calls f(ctx, req).
+func (f HandlerFunc) ServeDNS(ctx context.Context, req *Request) *Response {
+	return f(ctx, req)
+}
+
+// A Question is the question of a DNS query.
+type Question struct {
+	Name  string      // rooted, with the case in which it was sent
+	Type  net.DNSType // record type, or 255 for all types
+	Class uint16      // 1 for the Internet
+}
+
+// A Request is a DNS query received by a server.
+type Request struct {
+	ID               uint16
+	Question         Question
+	RecursionDesired bool
+
+	// EDNS reports whether the query has an EDNS(0) OPT record,
+	// and DNSSECOK whether it has the DNSSEC OK bit set.
+	EDNS     bool
+	DNSSECOK bool
+
+	// Network is "udp" or "tcp", and RemoteAddr the client's address.
+	Network    string
+	RemoteAddr net.Addr
+}
+
+// A Response is the response to a DNS query.
+type Response struct {
+	RCode              RCode
+	Authoritative      bool
+	RecursionAvailable bool
+
+	// Answers, Authorities and Additionals are the records in the
+	// answer, authority and additional sections of the response.
+	// Records with a Class of zero are in the Internet class.
+	// The Data of a record is used as its data in wire format if
+	// it is not nil. Otherwise its Value is encoded, and must have
+	// the type documented by [net.DNSRecord] for the record type.
+	Answers     []net.DNSRecord
+	Authorities []net.DNSRecord
+	Additionals []net.DNSRecord
+}
+
+// A Server is a DNS server, which answers queries over UDP and TCP,
+// and from in-memory connections made with [Server.DialContext].
+//
+// A Server must not be copied after first use.
+type Server struct {
+	// Addr is the address on which ListenAndServe listens, as
+	// "host:port". If empty, ":53" is used.
+	Addr string
+
+	// Handler responds to queries. If nil, all queries are refused.
+	Handler Handler
+
+	// MaxUDPSize is the size of the largest response sent over UDP
+	// to clients which use EDNS(0) and accept it. If zero, 1232 is
+	// used. Larger responses are truncated, and must be retried over
+	// TCP. Clients which do not use EDNS(0) are sent at most 512 bytes.
+	MaxUDPSize int
+
+	// MaxUDPQueries is the largest number of queries received by
+	// ServePacket which are answered concurrently. If zero, 1024 is
+	// used. Further queries are not read until one has been answered.
+	MaxUDPQueries int
+
+	// IdleTimeout is the time for which a TCP connection is kept open
+	// while waiting for a query, and the time allowed for writing
+	// each response to it. If zero, 10 seconds is used.
+	IdleTimeout time.Duration
+
+	// ErrorLog specifies an optional logger for errors responding
+	// to queries. If nil, logging is done via the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closed    bool
+	listeners map[io.Closer]struct{} // net.Listener and net.PacketConn
+	conns     map[net.Conn]struct{}
+}
+
+// ListenAndServe listens on s.Addr on both UDP and TCP, and then
+// serves queries received on either. If the port of s.Addr is 0,
+// a port is chosen which is available for both.
+//
+// ListenAndServe always returns a non-nil error. After Close, the
+// returned error is ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":53"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		l.Close()
+		return err
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	pc, err := net.ListenPacket("udp", net.JoinHostPort(host, port))
+	if err != nil {
+		l.Close()
+		return err
+	}
+	errc := make(chan error, 2)
+	go func() { errc <- s.Serve(l) }()
+	go func() { errc <- s.ServePacket(pc) }()
+	err = <-errc
+	// Stop serving on the other connection too.
+	l.Close()
+	pc.Close()
+	<-errc
+	if s.shuttingDown() {
+		return ErrServerClosed
+	}
+	return err
+}
+
+// Serve accepts TCP connections on l, and serves the queries received
+// on each in a new goroutine.
+//
+// Serve always returns a non-nil error and closes l. After Close,
+// the returned error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	if !s.trackListener(l, true) {
+		return ErrServerClosed
+	}
+	defer s.trackListener(l, false)
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		go s.serveConn(c, "tcp")
+	}
+}
+
+// ServePacket serves the queries received on pc, each in a new goroutine.
+// At most s.MaxUDPQueries are answered at once.
+//
+// ServePacket always returns a non-nil error and closes pc. After Close,
+// the returned error is ErrServerClosed.
+func (s *Server) ServePacket(pc net.PacketConn) error {
+	defer pc.Close()
+	if !s.trackListener(pc, true) {
+		return ErrServerClosed
+	}
+	defer s.trackListener(pc, false)
+	ctx := s.context()
+	sem := make(chan struct{}, s.maxUDPQueries())
+	buf := make([]byte, maxMessageSize)
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ErrServerClosed
+		}
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		msg := bytes.Clone(buf[:n])
+		go func() {
+			defer func() { <-sem }()
+			if resp := s.respond(ctx, msg, "udp", addr); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+// Close closes all listeners and connections of s, including those
+// made with DialContext.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	listeners, conns := s.listeners, s.conns
+	s.listeners, s.conns = nil, nil
+	s.mu.Unlock()
+
+	var err error
+	for l := range listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range conns {
+		c.Close()
+	}
+	return err
+}
+
+func (s *Server) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		if s.closed {
+			s.cancel()
+		}
+	}
+	return s.ctx
+}
+
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// trackListener adds or removes l from the listeners closed by Close.
+// It reports false if l is to be added and the server is closed.
+func (s *Server) trackListener(l io.Closer, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.listeners, l)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[io.Closer]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) trackConn(c net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.conns, c)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) idleTimeout() time.Duration {
+	if s.IdleTimeout != 0 {
+		return s.IdleTimeout
+	}
+	return defaultTCPIdleTimeout
+}
+
+func (s *Server) maxUDPQueries() int {
+	if s.MaxUDPQueries > 0 {
+		return s.MaxUDPQueries
+	}
+	return defaultMaxUDPQueries
+}
+
+func (s *Server) maxUDPSize() int {
+	if s.MaxUDPSize != 0 {
+		return max(s.MaxUDPSize, minUDPSize)
+	}
+	return defaultMaxUDPSize
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// serveConn serves the queries received on the TCP connection c,
+// which are preceded by their length (RFC 1035 Section 4.2.2), one
+// at a time.
+func (s *Server) serveConn(c net.Conn, network string) {
+	defer c.Close()
+	if !s.trackConn(c, true) {
+		return
+	}
+	defer s.trackConn(c, false)
+	ctx := s.context()
+	var l [2]byte
+	for {
+		c.SetReadDeadline(time.Now().Add(s.idleTimeout()))
+		if _, err := io.ReadFull(c, l[:]); err != nil {
+			return
+		}
+		msg := make([]byte, int(l[0])<<8|int(l[1]))
+		if _, err := io.ReadFull(c, msg); err != nil {
+			return
+		}
+		resp := s.respond(ctx, msg, network, c.RemoteAddr())
+		if resp == nil {
+			continue
+		}
+		resp = append([]byte{byte(len(resp) >> 8), byte(len(resp))}, resp...)
+		c.SetWriteDeadline(time.Now().Add(s.idleTimeout()))
+		if _, err := c.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+// respond returns the response to the query msg, received over network
+// from addr, or nil if none should be sent.
+func (s *Server) respond(ctx context.Context, msg []byte, network string, addr net.Addr) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil || h.Response {
+		return nil
+	}
+	rh := dnsmessage.Header{
+		ID:               h.ID,
+		Response:         true,
+		OpCode:           h.OpCode,
+		RecursionDesired: h.RecursionDesired,
+	}
+	qs, err := p.AllQuestions()
+	if err != nil || len(qs) != 1 {
+		return s.errorResponse(rh, nil, RCodeFormatError, false)
+	}
+	q := qs[0]
+	if h.OpCode != 0 {
+		return s.errorResponse(rh, &q, RCodeNotImplemented, false)
+	}
+	req := &Request{
+		ID: h.ID,
+		Question: Question{
+			Name:  q.Name.String(),
+			Type:  net.DNSType(q.Type),
+			Class: uint16(q.Class),
+		},
+		RecursionDesired: h.RecursionDesired,
+		Network:          network,
+		RemoteAddr:       addr,
+	}
+	limit := maxMessageSize
+	if network == "udp" {
+		limit = minUDPSize
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		return s.errorResponse(rh, &q, RCodeFormatError, false)
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return s.errorResponse(rh, &q, RCodeFormatError, false)
+	}
+	for {
+		ah, err := p.AdditionalHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil || ah.Type == dnsmessage.TypeOPT && req.EDNS {
+			return s.errorResponse(rh, &q, RCodeFormatError, req.EDNS)
+		}
+		if err := p.SkipAdditional(); err != nil {
+			return s.errorResponse(rh, &q, RCodeFormatError, req.EDNS)
+		}
+		if ah.Type != dnsmessage.TypeOPT {
+			continue
+		}
+		req.EDNS = true
+		req.DNSSECOK = ah.DNSSECAllowed()
+		if version := ah.TTL >> 16 & 0xff; version != 0 {
+			return s.errorResponse(rh, &q, RCodeBadVersion, true)
+		}
+		if network == "udp" {
+			limit = min(max(int(ah.Class), minUDPSize), s.maxUDPSize())
+		}
+	}
+
+	handler := s.Handler
+	if handler == nil {
+		return s.errorResponse(rh, &q, RCodeRefused, req.EDNS)
+	}
+	resp, ok := s.serveDNS(ctx, handler, req)
+	if !ok {
+		return s.errorResponse(rh, &q, RCodeServerFailure, req.EDNS)
+	}
+	if resp == nil {
+		return nil
+	}
+	if resp.RCode > 0xf && !req.EDNS || resp.RCode > 0xfff {
+		s.logf("dns: cannot send response code %v to %v query for %v", resp.RCode, req.Question.Type, req.Question.Name)
+		return s.errorResponse(rh, &q, RCodeServerFailure, req.EDNS)
+	}
+	rh.Authoritative = resp.Authoritative
+	rh.RecursionAvailable = resp.RecursionAvailable
+	b, err := buildMessage(rh, q, resp.RCode, req.EDNS, s.maxUDPSize(), resp.Answers, resp.Authorities, resp.Additionals)
+	if err == nil && len(b) > limit {
+		// Leave out the additional records, which are not needed
+		// to answer the query (RFC 2181 Section 9), and if that is
+		// not enough, all the records.
+		b, err = buildMessage(rh, q, resp.RCode, req.EDNS, s.maxUDPSize(), resp.Answers, resp.Authorities, nil)
+		if err == nil && len(b) > limit {
+			rh.Truncated = true
+			b, err = buildMessage(rh, q, resp.RCode, req.EDNS, s.maxUDPSize(), nil, nil, nil)
+		}
+	}
+	if err != nil {
+		s.logf("dns: responding to %v query for %v: %v", req.Question.Type, req.Question.Name, err)
+		return s.errorResponse(rh, &q, RCodeServerFailure, req.EDNS)
+	}
+	return b
+}
+
+// serveDNS calls handler.ServeDNS(ctx, req). It reports false if the
+// handler panicked, after logging the panic.
+func (s *Server) serveDNS(ctx context.Context, handler Handler, req *Request) (resp *Response, ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			s.logf("dns: panic serving %v query for %v from %v: %v\n%s", req.Question.Type, req.Question.Name, req.RemoteAddr, err, buf)
+		}
+	}()
+	return handler.ServeDNS(ctx, req), true
+}
+
+// errorResponse returns a response with header h, the question q if it
+// is not nil, and no records.
+func (s *Server) errorResponse(h dnsmessage.Header, q *dnsmessage.Question, rcode RCode, edns bool) []byte {
+	h.RCode = dnsmessage.RCode(rcode & 0xf)
+	b := dnsmessage.NewBuilder(nil, h)
+	if q != nil {
+		b.StartQuestions()
+		if err := b.Question(*q); err != nil {
+			return nil
+		}
+	}
+	if edns {
+		var rh dnsmessage.ResourceHeader
+		rh.SetEDNS0(s.maxUDPSize(), dnsmessage.RCode(rcode), false)
+		b.StartAdditionals()
+		if err := b.OPTResource(rh, dnsmessage.OPTResource{}); err != nil {
+			return nil
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return msg
+}
@@ -0,0 +1,345 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/dns"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// bigZone has a name with enough addresses that the response
+// to a query for them does not fit in a UDP message.
+func bigZone(t *testing.T) *dns.Zone {
+	var b strings.Builder
+	b.WriteString(testZone)
+	for i := range 100 {
+		fmt.Fprintf(&b, "big.example. A 198.51.100.%d\n", i)
+	}
+	return mustParseZone(t, b.String())
+}
+
+func TestServerDialContext(t *testing.T) {
+	srv := &dns.Server{Handler: bigZone(t)}
+	defer srv.Close()
+	var (
+		mu       sync.Mutex
+		networks []string
+	)
+	r := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		mu.Lock()
+		networks = append(networks, network)
+		mu.Unlock()
+		return srv.DialContext(ctx, network, address)
+	}}
+	ctx := context.Background()
+
+	addrs, err := r.LookupHost(ctx, "www.example.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(addrs)
+	if want := []string{"192.0.2.1", "2001:db8::1"}; !slices.Equal(addrs, want) {
+		t.Errorf("LookupHost = %v, want %v", addrs, want)
+	}
+	cname, err := r.LookupCNAME(ctx, "alias.example.")
+	if err != nil || cname != "www.example." {
+		t.Errorf("LookupCNAME = %q, %v; want www.example.", cname, err)
+	}
+	mx, err := r.LookupMX(ctx, "example.")
+	if err != nil || len(mx) != 1 || *mx[0] != (net.MX{Host: "mail.example.", Pref: 10}) {
+		t.Errorf("LookupMX = %v, %v; want mail.example. 10", mx, err)
+	}
+	_, err = r.LookupHost(ctx, "missing.example.")
+	if dnsErr := (*net.DNSError)(nil); !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("LookupHost of missing name: got error %v, want not found", err)
+	}
+
+	// The response to the A query for big.example. is truncated,
+	// and so the query is retried over TCP.
+	mu.Lock()
+	networks = nil
+	mu.Unlock()
+	ips, err := r.LookupNetIP(ctx, "ip4", "big.example.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 100 {
+		t.Errorf("LookupNetIP returned %d addresses, want 100", len(ips))
+	}
+	if want := []string{"udp", "tcp"}; !slices.Equal(networks, want) {
+		t.Errorf("dialed %q, want %q", networks, want)
+	}
+
+	srv.Close()
+	if _, err := srv.DialContext(ctx, "udp", "127.0.0.1:53"); err == nil {
+		t.Errorf("DialContext after Close succeeded")
+	}
+}
+
+// exchange sends a query for name and typ to the server at addr over
+// network, with an EDNS(0) record of the given version if it is not
+// negative.
+func exchange(t *testing.T, network, addr, name string, typ dnsmessage.Type, ednsVersion int) (dnsmessage.Message, int) {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET})
+	if ednsVersion >= 0 {
+		var rh dnsmessage.ResourceHeader
+		rh.SetEDNS0(4096, dnsmessage.RCodeSuccess, false)
+		rh.TTL |= uint32(ednsVersion) << 16
+		b.StartAdditionals()
+		b.OPTResource(rh, dnsmessage.OPTResource{})
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(10 * time.Second))
+	if network == "tcp" {
+		msg = append([]byte{byte(len(msg) >> 8), byte(len(msg))}, msg...)
+	}
+	if _, err := c.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 65536)
+	n, err := c.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = buf[:n]
+	if network == "tcp" {
+		for len(buf) < 2 || len(buf) < 2+(int(buf[0])<<8|int(buf[1])) {
+			n, err := c.Read(buf[len(buf):cap(buf)])
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf = buf[:len(buf)+n]
+		}
+		buf = buf[2:]
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != 42 || !resp.Response || !resp.RecursionDesired {
+		t.Errorf("response has header %+v", resp.Header)
+	}
+	return resp, len(buf)
+}
+
+func TestServerListeners(t *testing.T) {
+	srv := &dns.Server{Handler: bigZone(t)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 2)
+	go func() { errc <- srv.Serve(l) }()
+	go func() { errc <- srv.ServePacket(pc) }()
+	udpAddr, tcpAddr := pc.LocalAddr().String(), l.Addr().String()
+
+	resp, _ := exchange(t, "udp", udpAddr, "www.example.", dnsmessage.TypeA, 0)
+	if resp.RCode != dnsmessage.RCodeSuccess || !resp.Authoritative || len(resp.Answers) != 1 {
+		t.Errorf("A query for www.example.: got %v, authoritative %v, %d answers", resp.RCode, resp.Authoritative, len(resp.Answers))
+	} else if a, ok := resp.Answers[0].Body.(*dnsmessage.AResource); !ok || netip.AddrFrom4(a.A) != netip.MustParseAddr("192.0.2.1") {
+		t.Errorf("A query for www.example.: got answer %v", resp.Answers[0])
+	}
+	if n := len(resp.Additionals); n != 1 || resp.Additionals[0].Header.Type != dnsmessage.TypeOPT {
+		t.Errorf("response to query with EDNS(0) has no OPT record")
+	}
+
+	// Responses are truncated to 512 bytes for clients without
+	// EDNS(0), or the size they advertise, up to MaxUDPSize.
+	for _, tt := range []struct {
+		ednsVersion int
+		limit       int
+	}{{-1, 512}, {0, 1232}} {
+		resp, n := exchange(t, "udp", udpAddr, "big.example.", dnsmessage.TypeA, tt.ednsVersion)
+		if !resp.Truncated || n > tt.limit || len(resp.Answers) != 0 {
+			t.Errorf("EDNS version %d: got %d byte response with truncation %v and %d answers; want truncated to %d bytes", tt.ednsVersion, n, resp.Truncated, len(resp.Answers), tt.limit)
+		}
+	}
+	resp, _ = exchange(t, "tcp", tcpAddr, "big.example.", dnsmessage.TypeA, -1)
+	if resp.Truncated || len(resp.Answers) != 100 {
+		t.Errorf("over TCP: got truncation %v and %d answers, want 100", resp.Truncated, len(resp.Answers))
+	}
+
+	// Unknown versions of EDNS are reported with an extended RCode.
+	resp, _ = exchange(t, "tcp", tcpAddr, "www.example.", dnsmessage.TypeA, 1)
+	if len(resp.Additionals) != 1 || resp.Additionals[0].Header.ExtendedRCode(resp.RCode) != dnsmessage.RCode(dns.RCodeBadVersion) {
+		t.Errorf("EDNS version 1: got %v with %v", resp.RCode, resp.Additionals)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := <-errc; err != dns.ErrServerClosed {
+			t.Errorf("Serve returned %v, want ErrServerClosed", err)
+		}
+	}
+}
+
+// syncBuffer is a strings.Builder which is safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestServerHandlerPanic(t *testing.T) {
+	zone := mustParseZone(t, testZone)
+	var logBuf syncBuffer
+	srv := &dns.Server{
+		Handler: dns.HandlerFunc(func(ctx context.Context, req *dns.Request) *dns.Response {
+			if req.Question.Name == "panic.example." {
+				panic("handler panic")
+			}
+			return zone.ServeDNS(ctx, req)
+		}),
+		ErrorLog: log.New(&logBuf, "", 0),
+	}
+	defer srv.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	go srv.ServePacket(pc)
+
+	for _, addr := range []struct{ network, addr string }{
+		{"udp", pc.LocalAddr().String()},
+		{"tcp", l.Addr().String()},
+	} {
+		resp, _ := exchange(t, addr.network, addr.addr, "panic.example.", dnsmessage.TypeA, 0)
+		if resp.RCode != dnsmessage.RCodeServerFailure {
+			t.Errorf("%v: query for panic.example. got %v, want server failure", addr.network, resp.RCode)
+		}
+		resp, _ = exchange(t, addr.network, addr.addr, "www.example.", dnsmessage.TypeA, 0)
+		if resp.RCode != dnsmessage.RCodeSuccess || len(resp.Answers) != 1 {
+			t.Errorf("%v: query after panic got %v with %d answers, want 1 answer", addr.network, resp.RCode, len(resp.Answers))
+		}
+	}
+	if got := logBuf.String(); !strings.Contains(got, "panic serving") || !strings.Contains(got, "handler panic") {
+		t.Errorf("server log = %q, want panic logged", got)
+	}
+}
+
+func TestServerMaxUDPQueries(t *testing.T) {
+	zone := mustParseZone(t, testZone)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	answered := make(chan struct{}, 1)
+	srv := &dns.Server{
+		MaxUDPQueries: 1,
+		Handler: dns.HandlerFunc(func(ctx context.Context, req *dns.Request) *dns.Response {
+			if req.Question.Name == "block.example." {
+				close(started)
+				<-release
+				return &dns.Response{RCode: dns.RCodeServerFailure}
+			}
+			answered <- struct{}{}
+			return zone.ServeDNS(ctx, req)
+		}),
+	}
+	defer srv.Close()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.ServePacket(pc)
+	addr := pc.LocalAddr().String()
+
+	go func() {
+		defer close(release)
+		<-started
+		// While the first query is being answered,
+		// the second is not read.
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 43})
+		b.StartQuestions()
+		b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName("www.example."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+		msg, _ := b.Finish()
+		c, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		c.Write(msg)
+		select {
+		case <-answered:
+			t.Error("second query answered while the first was in progress")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}()
+	resp, _ := exchange(t, "udp", addr, "block.example.", dnsmessage.TypeA, 0)
+	if resp.RCode != dnsmessage.RCodeServerFailure {
+		t.Errorf("query for block.example. got %v, want server failure", resp.RCode)
+	}
+	select {
+	case <-answered:
+	case <-time.After(10 * time.Second):
+		t.Error("second query not answered after the first")
+	}
+}
+
+func TestServeMux(t *testing.T) {
+	mux := dns.NewServeMux()
+	mux.Handle("example.", mustParseZone(t, testZone))
+	mux.HandleFunc("test.example", func(ctx context.Context, req *dns.Request) *dns.Response {
+		return &dns.Response{RCode: dns.RCodeServerFailure}
+	})
+	for _, tt := range []struct {
+		name string
+		want dns.RCode
+	}{
+		{"www.example.", dns.RCodeSuccess},
+		{"missing.example.", dns.RCodeNameError},
+		{"WWW.Test.Example.", dns.RCodeServerFailure},
+		{"www.example.org.", dns.RCodeRefused},
+	} {
+		resp := mux.ServeDNS(context.Background(), &dns.Request{Question: dns.Question{Name: tt.name, Type: net.DNSTypeA, Class: 1}})
+		if resp.RCode != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, resp.RCode, tt.want)
+		}
+	}
+}
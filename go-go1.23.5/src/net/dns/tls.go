@@ -0,0 +1,237 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIdleTimeout  = 30 * time.Second
+	defaultMaxIdleConns = 2
+)
+
+// A TLSTransport is a [net.DNSTransport] which sends queries using
+// DNS over TLS (RFC 7858). Connections are kept open for reuse, and
+// carry one query at a time.
+//
+// A TLSTransport must not be copied after first use.
+type TLSTransport struct {
+	// Addr is the address of the server, as "host:port". If empty,
+	// queries are sent to port 853 of the server addresses given to
+	// Exchange, which are taken from resolv.conf.
+	Addr string
+
+	// Config is the TLS configuration to use. If nil, the zero
+	// configuration is used. If it has no ServerName, the host
+	// of the server address is used, so that the server must have
+	// a certificate for its IP address unless Addr names a host.
+	Config *tls.Config
+
+	// DialContext specifies the dial function for creating TCP
+	// connections. If nil, a zero net.Dialer is used. It should not
+	// use a Resolver with this transport to look up host names.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// IdleTimeout is the time for which an idle connection is kept
+	// open for reuse. If zero, 30 seconds is used. If negative,
+	// connections are not reused.
+	IdleTimeout time.Duration
+
+	// MaxIdleConns is the maximum number of idle connections kept
+	// open to each server. If zero, 2 is used.
+	MaxIdleConns int
+
+	mu   sync.Mutex
+	idle map[string][]idleConn // by server address, least recently used first
+}
+
+type idleConn struct {
+	c     *tls.Conn
+	since time.Time
+}
+
+// Exchange implements [net.DNSTransport].
+func (t *TLSTransport) Exchange(ctx context.Context, address string, msg []byte) ([]byte, error) {
+	if len(msg) > maxMessageSize {
+		return nil, errors.New("dns: query too large")
+	}
+	addr := t.Addr
+	if addr == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(host, "853")
+	}
+	for {
+		c, reused := t.getIdle(addr)
+		if c == nil {
+			var err error
+			if c, err = t.dial(ctx, addr); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := roundTrip(ctx, c, msg)
+		if err == nil {
+			t.putIdle(addr, c)
+			return resp, nil
+		}
+		c.Close()
+		// The server may have closed an idle connection,
+		// in which case the query is retried on a new one.
+		if !reused || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+// CloseIdleConnections closes the connections which are not in use.
+func (t *TLSTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.mu.Unlock()
+	for _, conns := range idle {
+		for _, ic := range conns {
+			ic.c.Close()
+		}
+	}
+}
+
+func (t *TLSTransport) idleTimeout() time.Duration {
+	if t.IdleTimeout != 0 {
+		return t.IdleTimeout
+	}
+	return defaultIdleTimeout
+}
+
+func (t *TLSTransport) maxIdleConns() int {
+	if t.MaxIdleConns > 0 {
+		return t.MaxIdleConns
+	}
+	return defaultMaxIdleConns
+}
+
+// getIdle returns the most recently used idle connection to addr,
+// closing any which have been idle for too long.
+func (t *TLSTransport) getIdle(addr string) (c *tls.Conn, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conns := t.pruneIdleLocked(addr, 0)
+	if len(conns) == 0 {
+		return nil, false
+	}
+	c = conns[len(conns)-1].c
+	conns[len(conns)-1] = idleConn{}
+	t.idle[addr] = conns[:len(conns)-1]
+	return c, true
+}
+
+func (t *TLSTransport) putIdle(addr string, c *tls.Conn) {
+	if t.idleTimeout() < 0 {
+		c.Close()
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conns := t.pruneIdleLocked(addr, 1)
+	if t.idle == nil {
+		t.idle = make(map[string][]idleConn)
+	}
+	t.idle[addr] = append(conns, idleConn{c, time.Now()})
+}
+
+// pruneIdleLocked closes the idle connections to addr which have been
+// idle for too long, and the least recently used ones beyond those
+// which leave room for n more, and returns those which remain.
+func (t *TLSTransport) pruneIdleLocked(addr string, n int) []idleConn {
+	conns := t.idle[addr]
+	i := 0
+	for ; i < len(conns); i++ {
+		if time.Since(conns[i].since) < t.idleTimeout() && len(conns)-i+n <= t.maxIdleConns() {
+			break
+		}
+		conns[i].c.Close()
+	}
+	if i == len(conns) {
+		delete(t.idle, addr)
+		return nil
+	}
+	return slices.Delete(conns, 0, i)
+}
+
+func (t *TLSTransport) dial(ctx context.Context, addr string) (*tls.Conn, error) {
+	dial := t.DialContext
+	if dial == nil {
+		dial = new(net.Dialer).DialContext
+	}
+	raw, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var config *tls.Config
+	if t.Config == nil {
+		config = new(tls.Config)
+	} else {
+		config = t.Config.Clone()
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			raw.Close()
+			return nil, err
+		}
+		config.ServerName = host
+	}
+	c := tls.Client(raw, config)
+	if err := c.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// roundTrip sends the query msg on c, and reads the response, using
+// the two-byte length prefix of DNS over TCP (RFC 1035 Section 4.2.2).
+func roundTrip(ctx context.Context, c net.Conn, msg []byte) (resp []byte, err error) {
+	if d, ok := ctx.Deadline(); ok {
+		c.SetDeadline(d)
+	} else {
+		c.SetDeadline(time.Time{})
+	}
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(aLongTimeAgo)
+	})
+	defer func() {
+		if !stop() && err == nil {
+			// The deadline was set, so the connection
+			// must not be reused.
+			resp, err = nil, ctx.Err()
+		}
+	}()
+
+	b := make([]byte, 2+len(msg))
+	b[0], b[1] = byte(len(msg)>>8), byte(len(msg))
+	copy(b[2:], msg)
+	if _, err := c.Write(b); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(c, b[:2]); err != nil {
+		return nil, err
+	}
+	resp = make([]byte, int(b[0])<<8|int(b[1]))
+	if _, err := io.ReadFull(c, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
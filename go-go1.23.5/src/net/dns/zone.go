@@ -0,0 +1,255 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// maxCNAMEs is the number of CNAME records which are followed
+// within a zone when answering a query.
+const maxCNAMEs = 8
+
+// typeANY is the query type for all records (RFC 1035 Section 3.2.3).
+const typeANY net.DNSType = 255
+
+// A Zone is a [Handler] which answers queries authoritatively from the
+// records of a DNS zone, following CNAME records within it, expanding
+// wildcard records (RFC 4592), and referring queries for names below
+// its delegations to their name servers. It refuses queries for names
+// outside the zone.
+//
+// A Zone is safe for concurrent use, and must not be modified.
+type Zone struct {
+	origin  string
+	records []net.DNSRecord
+	names   map[string][]net.DNSRecord // by canonical owner name
+	nodes   map[string]bool            // names with records or descendants
+}
+
+// NewZone returns a zone with the apex origin and the given records,
+// whose names must be at or below origin. Names are compared without
+// regard to case, and may be rooted or not.
+//
+// The SOA record of the zone, if any, is added to negative responses,
+// to allow them to be cached (RFC 2308). It must be at the apex.
+func NewZone(origin string, records []net.DNSRecord) (*Zone, error) {
+	z := &Zone{
+		origin:  canonicalName(origin),
+		records: records,
+		names:   make(map[string][]net.DNSRecord),
+		nodes:   make(map[string]bool),
+	}
+	z.nodes[z.origin] = true
+	for _, rec := range records {
+		name := canonicalName(rec.Name)
+		if !inZone(name, z.origin) {
+			return nil, fmt.Errorf("dns: record for %v is outside zone %v", rec.Name, z.origin)
+		}
+		if rec.Type == net.DNSTypeSOA && name != z.origin {
+			return nil, fmt.Errorf("dns: SOA record for %v is not at the apex of zone %v", rec.Name, z.origin)
+		}
+		for _, other := range z.names[name] {
+			if rec.Type == net.DNSTypeCNAME || other.Type == net.DNSTypeCNAME {
+				return nil, fmt.Errorf("dns: CNAME record for %v has other records", rec.Name)
+			}
+		}
+		z.names[name] = append(z.names[name], rec)
+		for n := name; n != z.origin; n = parentName(n) {
+			z.nodes[n] = true
+		}
+	}
+	return z, nil
+}
+
+// Origin returns the name of the apex of z.
+func (z *Zone) Origin() string { return z.origin }
+
+// Records returns the records of z. The returned slice must not be modified.
+func (z *Zone) Records() []net.DNSRecord { return z.records }
+
+// ServeDNS implements [Handler].
+func (z *Zone) ServeDNS(ctx context.Context, req *Request) *Response {
+	q := req.Question
+	name := canonicalName(q.Name)
+	if q.Class != 1 && q.Class != 255 || !inZone(name, z.origin) {
+		return &Response{RCode: RCodeRefused}
+	}
+	resp := &Response{Authoritative: true}
+	for range maxCNAMEs + 1 {
+		if cut := z.delegation(name); cut != "" {
+			// Only the CNAME records leading to the delegation,
+			// if any, are authoritative.
+			resp.Authoritative = len(resp.Answers) > 0
+			resp.Authorities = append(resp.Authorities, z.recordsOf(cut, net.DNSTypeNS)...)
+			resp.Additionals = z.addresses(resp.Authorities)
+			return resp
+		}
+		recs, ok := z.lookup(name)
+		if !ok {
+			resp.RCode = RCodeNameError
+			resp.Authorities = z.negativeSOA()
+			return resp
+		}
+		var cname *net.DNSRecord
+		n := len(resp.Answers)
+		for i, rec := range recs {
+			if rec.Type == q.Type || q.Type == typeANY {
+				resp.Answers = append(resp.Answers, rec)
+			} else if rec.Type == net.DNSTypeCNAME {
+				cname = &recs[i]
+			}
+		}
+		if len(resp.Answers) > n {
+			resp.Additionals = z.addresses(resp.Answers[n:])
+			return resp
+		}
+		target, ok := "", false
+		if cname != nil {
+			target, ok = cname.Value.(string)
+		}
+		if !ok {
+			resp.Authorities = z.negativeSOA()
+			return resp
+		}
+		resp.Answers = append(resp.Answers, *cname)
+		name = canonicalName(target)
+		if !inZone(name, z.origin) {
+			return resp
+		}
+	}
+	return resp
+}
+
+// lookup returns the records of name, which exists if ok is set.
+// If name does not exist but matches a wildcard, the records of
+// the wildcard are returned with their names set to name.
+func (z *Zone) lookup(name string) (recs []net.DNSRecord, ok bool) {
+	if z.nodes[name] {
+		return z.names[name], true
+	}
+	// The closest encloser is the longest existing ancestor of name
+	// (RFC 4592 Section 3.3.1).
+	encloser := parentName(name)
+	for !z.nodes[encloser] {
+		encloser = parentName(encloser)
+	}
+	wild := z.names["*."+encloser]
+	if wild == nil {
+		return nil, false
+	}
+	recs = make([]net.DNSRecord, len(wild))
+	for i, rec := range wild {
+		rec.Name = name
+		recs[i] = rec
+	}
+	return recs, true
+}
+
+// delegation returns the name of the highest zone cut at or above name,
+// not counting the apex, or "" if there is none.
+func (z *Zone) delegation(name string) string {
+	var cut string
+	for n := name; n != z.origin; n = parentName(n) {
+		if len(z.recordsOf(n, net.DNSTypeNS)) > 0 {
+			cut = n
+		}
+	}
+	return cut
+}
+
+// recordsOf returns the records of name with type typ.
+func (z *Zone) recordsOf(name string, typ net.DNSType) []net.DNSRecord {
+	var recs []net.DNSRecord
+	for _, rec := range z.names[name] {
+		if rec.Type == typ {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+// addresses returns the address records in z of the names referred to
+// by the NS, MX and SRV records in recs.
+func (z *Zone) addresses(recs []net.DNSRecord) []net.DNSRecord {
+	var addrs []net.DNSRecord
+	seen := make(map[string]bool)
+	for _, rec := range recs {
+		var target string
+		switch v := rec.Value.(type) {
+		case string:
+			if rec.Type == net.DNSTypeNS {
+				target = v
+			}
+		case *net.MX:
+			target = v.Host
+		case *net.SRV:
+			target = v.Target
+		}
+		target = canonicalName(target)
+		if target == "." || seen[target] {
+			continue
+		}
+		seen[target] = true
+		for _, a := range z.names[target] {
+			if a.Type == net.DNSTypeA || a.Type == net.DNSTypeAAAA {
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	return addrs
+}
+
+// negativeSOA returns the SOA record to include in a negative response,
+// with a TTL no greater than its minimum TTL (RFC 2308 Section 3).
+func (z *Zone) negativeSOA() []net.DNSRecord {
+	soa := z.recordsOf(z.origin, net.DNSTypeSOA)
+	if len(soa) == 0 {
+		return nil
+	}
+	rec := soa[0]
+	if v, ok := rec.Value.(*net.SOA); ok {
+		rec.TTL = min(rec.TTL, time.Duration(v.MinTTL)*time.Second)
+	}
+	return []net.DNSRecord{rec}
+}
+
+// canonicalName returns name in lower case, and rooted.
+func canonicalName(name string) string {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	for i := 0; i < len(name); i++ {
+		if 'A' <= name[i] && name[i] <= 'Z' {
+			b := []byte(name)
+			for j := i; j < len(b); j++ {
+				if 'A' <= b[j] && b[j] <= 'Z' {
+					b[j] += 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return name
+}
+
+// parentName returns the parent of the canonical name, which must not
+// be the root.
+func parentName(name string) string {
+	i := strings.IndexByte(name, '.')
+	if i == len(name)-1 {
+		return "."
+	}
+	return name[i+1:]
+}
+
+// inZone reports whether the canonical name is at or below origin.
+func inZone(name, origin string) bool {
+	return origin == "." || name == origin || strings.HasSuffix(name, "."+origin)
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/dns"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testZone = `
+; A test zone.
+$TTL 1h
+@	IN	SOA	ns hostmaster.example. (
+		2026101701 ; serial
+		2h 10m 1w
+		60 )
+	NS	ns
+	MX	10 mail
+ns	A	192.0.2.53
+www	300	IN	A	192.0.2.1
+	IN	300	AAAA	2001:db8::1
+alias	CNAME	www
+chain	CNAME	alias.example.
+txt	TXT	"v=spf1 -all" "quote\" \059 semicolon" plain
+_sip._tcp	SRV	10 60 5060 www
+*.wild	A	192.0.2.2
+host.wild	TXT	"exists"
+caa	CAA	0 issue "ca.example"
+_443._tcp.www	TLSA	3 1 1 ( abcd
+		ef01 )
+www	HTTPS	1 . alpn="h2,h3" port=8443 ipv4hint=192.0.2.1 ech=AQID
+svc	HTTPS	0 www
+gen	TYPE99	\# 3 abcdef
+$ORIGIN sub.example.
+@	NS	ns.sub.example.
+ns	A	192.0.2.54
+`
+
+func mustParseZone(t *testing.T, s string) *dns.Zone {
+	t.Helper()
+	z, err := dns.ParseZone(strings.NewReader(s), "example.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestParseZone(t *testing.T) {
+	z := mustParseZone(t, testZone)
+	if z.Origin() != "example." {
+		t.Errorf("Origin() = %q, want example.", z.Origin())
+	}
+	const h = time.Hour
+	ip := netip.MustParseAddr
+	rec := func(name string, typ net.DNSType, ttl time.Duration, v any) net.DNSRecord {
+		return net.DNSRecord{Name: name, Type: typ, Class: 1, TTL: ttl, Value: v}
+	}
+	want := []net.DNSRecord{
+		rec("example.", net.DNSTypeSOA, h, &net.SOA{NS: "ns.example.", MBox: "hostmaster.example.", Serial: 2026101701, Refresh: 7200, Retry: 600, Expire: 604800, MinTTL: 60}),
+		rec("example.", net.DNSTypeNS, h, "ns.example."),
+		rec("example.", net.DNSTypeMX, h, &net.MX{Host: "mail.example.", Pref: 10}),
+		rec("ns.example.", net.DNSTypeA, h, ip("192.0.2.53")),
+		rec("www.example.", net.DNSTypeA, 300*time.Second, ip("192.0.2.1")),
+		rec("www.example.", net.DNSTypeAAAA, 300*time.Second, ip("2001:db8::1")),
+		rec("alias.example.", net.DNSTypeCNAME, h, "www.example."),
+		rec("chain.example.", net.DNSTypeCNAME, h, "alias.example."),
+		rec("txt.example.", net.DNSTypeTXT, h, []string{"v=spf1 -all", `quote" ; semicolon`, "plain"}),
+		rec("_sip._tcp.example.", net.DNSTypeSRV, h, &net.SRV{Target: "www.example.", Port: 5060, Priority: 10, Weight: 60}),
+		rec("*.wild.example.", net.DNSTypeA, h, ip("192.0.2.2")),
+		rec("host.wild.example.", net.DNSTypeTXT, h, []string{"exists"}),
+		rec("caa.example.", net.DNSTypeCAA, h, &net.CAA{Flags: 0, Tag: "issue", Value: "ca.example"}),
+		rec("_443._tcp.www.example.", net.DNSTypeTLSA, h, &net.TLSA{Usage: 3, Selector: 1, MatchingType: 1, Data: []byte{0xab, 0xcd, 0xef, 0x01}}),
+		rec("www.example.", net.DNSTypeHTTPS, h, &net.SVCB{Priority: 1, Target: ".", Params: []net.SVCParam{
+			{Key: net.SVCParamALPN, Value: []byte("\x02h2\x02h3")},
+			{Key: net.SVCParamPort, Value: []byte{0x20, 0xfb}},
+			{Key: net.SVCParamIPv4Hint, Value: []byte{192, 0, 2, 1}},
+			{Key: net.SVCParamECH, Value: []byte{1, 2, 3}},
+		}}),
+		rec("svc.example.", net.DNSTypeHTTPS, h, &net.SVCB{Priority: 0, Target: "www.example."}),
+		{Name: "gen.example.", Type: 99, Class: 1, TTL: h, Data: []byte{0xab, 0xcd, 0xef}},
+		rec("sub.example.", net.DNSTypeNS, h, "ns.sub.example."),
+		rec("ns.sub.example.", net.DNSTypeA, h, ip("192.0.2.54")),
+	}
+	got := z.Records()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("record %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseZoneErrors(t *testing.T) {
+	for _, tt := range []struct {
+		zone string
+		line int
+	}{
+		{"www A 192.0.2.1\n\tCH A 192.0.2.2", 2},
+		{"www A 2001:db8::1", 1},
+		{"www AAAA 192.0.2.1", 1},
+		{"www A 192.0.2.1 192.0.2.2", 1},
+		{"www MX mail", 1},
+		{"www TXT \"unterminated", 1},
+		{"@ SOA ns hostmaster ( 1 2 3 4\n5", 1},
+		{"@ SOA ns hostmaster 1 2 3 4 5 )", 1},
+		{"\tA 192.0.2.1", 1},
+		{"$INCLUDE other.zone", 1},
+		{"www 1x A 192.0.2.1", 1},
+		{"www TYPE99 abcd", 1},
+		{"www TYPE99 \\# 3 abcd", 1},
+		{"\n\nwww HTTPS 1 . alpn", 3},
+		{"www HTTPS 1 . port=1 port=2", 1},
+		{"www HTTPS 1 . unknown=1", 1},
+		{"www HTTPS 1 . ipv4hint=2001:db8::1", 1},
+		{"www TLSA 3 1 1 xyz", 1},
+		{"www.. A 192.0.2.1", 1},
+	} {
+		_, err := dns.ParseZone(strings.NewReader(tt.zone), "example.")
+		var perr *dns.ParseError
+		if !errors.As(err, &perr) || perr.Line != tt.line {
+			t.Errorf("ParseZone(%q): got error %v, want error on line %d", tt.zone, err, tt.line)
+		}
+	}
+
+	// Records must be in the zone.
+	if _, err := dns.ParseZone(strings.NewReader("www.example.org. A 192.0.2.1"), "example."); err == nil {
+		t.Errorf("ParseZone with record outside zone succeeded")
+	}
+	if _, err := dns.ParseZone(strings.NewReader("www CNAME alias\nwww A 192.0.2.1"), "example."); err == nil {
+		t.Errorf("ParseZone with CNAME and other records succeeded")
+	}
+}
+
+func TestZoneServeDNS(t *testing.T) {
+	z := mustParseZone(t, testZone)
+	names := func(recs []net.DNSRecord) []string {
+		var s []string
+		for _, rec := range recs {
+			s = append(s, rec.Name+" "+rec.Type.String())
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		name          string
+		typ           net.DNSType
+		rcode         dns.RCode
+		authoritative bool
+		answers       []string
+		authorities   []string
+		additionals   []string
+	}{
+		{"WWW.example.", net.DNSTypeA, dns.RCodeSuccess, true, []string{"www.example. A"}, nil, nil},
+		{"example.", net.DNSTypeMX, dns.RCodeSuccess, true, []string{"example. MX"}, nil, nil},
+		{"example.", net.DNSTypeNS, dns.RCodeSuccess, true, []string{"example. NS"}, nil, []string{"ns.example. A"}},
+		{"_sip._tcp.example.", net.DNSTypeSRV, dns.RCodeSuccess, true, []string{"_sip._tcp.example. SRV"}, nil, []string{"www.example. A", "www.example. AAAA"}},
+		{"chain.example.", net.DNSTypeAAAA, dns.RCodeSuccess, true, []string{"chain.example. CNAME", "alias.example. CNAME", "www.example. AAAA"}, nil, nil},
+		{"alias.example.", net.DNSTypeCNAME, dns.RCodeSuccess, true, []string{"alias.example. CNAME"}, nil, nil},
+		{"www.example.", net.DNSTypeTXT, dns.RCodeSuccess, true, nil, []string{"example. SOA"}, nil},
+		{"missing.example.", net.DNSTypeA, dns.RCodeNameError, true, nil, []string{"example. SOA"}, nil},
+		{"a.b.wild.example.", net.DNSTypeA, dns.RCodeSuccess, true, []string{"a.b.wild.example. A"}, nil, nil},
+		{"host.wild.example.", net.DNSTypeA, dns.RCodeSuccess, true, nil, []string{"example. SOA"}, nil},
+		{"wild.example.", net.DNSTypeA, dns.RCodeSuccess, true, nil, []string{"example. SOA"}, nil},
+		{"www.sub.example.", net.DNSTypeA, dns.RCodeSuccess, false, nil, []string{"sub.example. NS"}, []string{"ns.sub.example. A"}},
+		{"www.example.org.", net.DNSTypeA, dns.RCodeRefused, false, nil, nil, nil},
+	} {
+		resp := z.ServeDNS(context.Background(), &dns.Request{Question: dns.Question{Name: tt.name, Type: tt.typ, Class: 1}})
+		if resp.RCode != tt.rcode || resp.Authoritative != tt.authoritative {
+			t.Errorf("%v %v: got %v, authoritative %v; want %v, %v", tt.name, tt.typ, resp.RCode, resp.Authoritative, tt.rcode, tt.authoritative)
+		}
+		if got := names(resp.Answers); !reflect.DeepEqual(got, tt.answers) {
+			t.Errorf("%v %v: got answers %q, want %q", tt.name, tt.typ, got, tt.answers)
+		}
+		if got := names(resp.Authorities); !reflect.DeepEqual(got, tt.authorities) {
+			t.Errorf("%v %v: got authorities %q, want %q", tt.name, tt.typ, got, tt.authorities)
+		}
+		if got := names(resp.Additionals); !reflect.DeepEqual(got, tt.additionals) {
+			t.Errorf("%v %v: got additionals %q, want %q", tt.name, tt.typ, got, tt.additionals)
+		}
+		// Negative responses may be cached for the SOA minimum TTL.
+		for _, rec := range resp.Authorities {
+			if rec.Type == net.DNSTypeSOA && rec.TTL != time.Minute {
+				t.Errorf("%v %v: SOA record has TTL %v, want 1m", tt.name, tt.typ, rec.TTL)
+			}
+		}
+	}
+}
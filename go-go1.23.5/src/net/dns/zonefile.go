@@ -0,0 +1,692 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dns
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ParseError describes a problem with a zone file.
+type ParseError struct {
+	Line int // line on which the record or directive starts
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("dns: zone file line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// knownTypes are the record types whose data ParseZone parses from
+// their presentation format.
+var knownTypes = []net.DNSType{
+	net.DNSTypeA,
+	net.DNSTypeNS,
+	net.DNSTypeCNAME,
+	net.DNSTypeSOA,
+	net.DNSTypePTR,
+	net.DNSTypeMX,
+	net.DNSTypeTXT,
+	net.DNSTypeAAAA,
+	net.DNSTypeSRV,
+	net.DNSTypeTLSA,
+	net.DNSTypeSVCB,
+	net.DNSTypeHTTPS,
+	net.DNSTypeCAA,
+}
+
+// ParseZone parses a zone in the master file format of RFC 1035 Section 5
+// from r, and returns it as a Zone with the apex origin. Relative names
+// in the file are relative to origin, unless it has an $ORIGIN directive.
+// Records without a TTL have the one given by the last $TTL directive,
+// or if there is none, that of the previous record (RFC 2308 Section 4).
+// Only records of the Internet class are supported, and the $INCLUDE
+// directive is not.
+//
+// The data of records of the types with values described by
+// [net.DNSRecord] are parsed from their usual presentation format, and
+// returned as their values. The data of records of other types must be
+// given in the generic format of RFC 3597 Section 5, such as
+// "TYPE99 \# 2 abcd", and is returned as their Data.
+func ParseZone(r io.Reader, origin string) (*Zone, error) {
+	p := &zoneParser{
+		sc:     bufio.NewScanner(r),
+		origin: canonicalName(origin),
+	}
+	p.sc.Buffer(nil, 1<<20)
+	var records []net.DNSRecord
+	for {
+		line, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if line == nil {
+			break
+		}
+		rec, err := p.parseLine(line)
+		if err != nil {
+			return nil, &ParseError{Line: line.line, Err: err}
+		}
+		if rec != nil {
+			records = append(records, *rec)
+		}
+	}
+	return NewZone(origin, records)
+}
+
+type zoneParser struct {
+	sc         *bufio.Scanner
+	line       int
+	origin     string
+	owner      string        // owner of the previous record
+	ttl        time.Duration // TTL of the previous record
+	defaultTTL time.Duration // from $TTL
+	haveTTL    bool          // whether there was a $TTL directive
+}
+
+// A zoneLine is an entry of a zone file, which may span several lines
+// within parentheses.
+type zoneLine struct {
+	line   int
+	blank  bool // starts with white space, so has the previous owner
+	tokens []zoneToken
+}
+
+type zoneToken struct {
+	s      string
+	quoted bool
+}
+
+// next returns the next entry of the zone file, or nil at its end.
+func (p *zoneParser) next() (*zoneLine, error) {
+	var zl *zoneLine
+	depth := 0
+	for p.sc.Scan() {
+		p.line++
+		text := p.sc.Text()
+		if zl == nil {
+			zl = &zoneLine{line: p.line, blank: text != "" && (text[0] == ' ' || text[0] == '\t')}
+		}
+		var err error
+		if zl.tokens, depth, err = tokenize(text, zl.tokens, depth); err != nil {
+			return nil, &ParseError{Line: p.line, Err: err}
+		}
+		if depth > 0 {
+			continue
+		}
+		if len(zl.tokens) > 0 {
+			return zl, nil
+		}
+		zl = nil
+	}
+	if err := p.sc.Err(); err != nil {
+		return nil, err
+	}
+	if zl != nil && depth > 0 {
+		return nil, &ParseError{Line: zl.line, Err: errors.New("unbalanced parentheses")}
+	}
+	return nil, nil
+}
+
+// tokenize appends the tokens of a line to tokens, given the depth of
+// parentheses at its start, and returns the depth at its end.
+func tokenize(text string, tokens []zoneToken, depth int) ([]zoneToken, int, error) {
+	i := 0
+	for i < len(text) {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == ';':
+			return tokens, depth, nil
+		case c == '(':
+			depth++
+			i++
+			continue
+		case c == ')':
+			if depth == 0 {
+				return nil, 0, errors.New("unbalanced parentheses")
+			}
+			depth--
+			i++
+			continue
+		}
+		var tok zoneToken
+		var b []byte
+		tok.quoted = text[i] == '"'
+		inQuotes := false
+	token:
+		for ; i < len(text); i++ {
+			c := text[i]
+			switch {
+			case c == '"':
+				inQuotes = !inQuotes
+				continue
+			case c == '\\':
+				if i+1 == len(text) {
+					return nil, 0, errors.New("backslash at end of line")
+				}
+				// Keep escapes, which are decoded as appropriate
+				// for each field.
+				b = append(b, c, text[i+1])
+				i++
+				continue
+			case inQuotes:
+			case c == ' ' || c == '\t' || c == '\r' || c == ';' || c == '(' || c == ')':
+				break token
+			}
+			b = append(b, c)
+		}
+		if inQuotes {
+			return nil, 0, errors.New("unterminated quoted string")
+		}
+		tok.s = string(b)
+		tokens = append(tokens, tok)
+	}
+	return tokens, depth, nil
+}
+
+// parseLine parses an entry of a zone file, which is either a
+// directive or a record.
+func (p *zoneParser) parseLine(zl *zoneLine) (*net.DNSRecord, error) {
+	toks := zl.tokens
+	if !zl.blank && strings.HasPrefix(toks[0].s, "$") {
+		switch directive := toks[0].s; {
+		case strings.EqualFold(directive, "$ORIGIN") && len(toks) == 2:
+			origin, err := p.name(toks[1].s)
+			if err != nil {
+				return nil, err
+			}
+			p.origin = origin
+		case strings.EqualFold(directive, "$TTL") && len(toks) == 2:
+			ttl, err := parseTTL(toks[1].s)
+			if err != nil {
+				return nil, err
+			}
+			p.defaultTTL, p.haveTTL = ttl, true
+		default:
+			return nil, fmt.Errorf("unsupported directive %q", directive)
+		}
+		return nil, nil
+	}
+
+	rec := &net.DNSRecord{Class: 1, TTL: p.ttl}
+	if p.haveTTL {
+		rec.TTL = p.defaultTTL
+	}
+	if zl.blank {
+		if p.owner == "" {
+			return nil, errors.New("record has no owner")
+		}
+		rec.Name = p.owner
+	} else {
+		name, err := p.name(toks[0].s)
+		if err != nil {
+			return nil, err
+		}
+		rec.Name = name
+		toks = toks[1:]
+	}
+	p.owner = rec.Name
+
+	haveTTL, haveClass := false, false
+	for len(toks) > 0 && (!haveTTL || !haveClass) {
+		s := toks[0].s
+		if !haveTTL && s != "" && '0' <= s[0] && s[0] <= '9' {
+			ttl, err := parseTTL(s)
+			if err != nil {
+				return nil, err
+			}
+			rec.TTL, haveTTL = ttl, true
+		} else if !haveClass && strings.EqualFold(s, "IN") {
+			haveClass = true
+		} else {
+			break
+		}
+		toks = toks[1:]
+	}
+	if len(toks) == 0 {
+		return nil, errors.New("record has no type")
+	}
+	typ, ok := parseType(toks[0].s)
+	if !ok {
+		return nil, fmt.Errorf("unknown class or record type %q", toks[0].s)
+	}
+	rec.Type = typ
+	p.ttl = rec.TTL
+	toks = toks[1:]
+
+	if len(toks) > 0 && toks[0].s == `\#` && !toks[0].quoted {
+		data, err := parseGenericData(toks[1:])
+		if err != nil {
+			return nil, err
+		}
+		rec.Data = data
+		return rec, nil
+	}
+	if !slices.Contains(knownTypes, typ) {
+		return nil, fmt.Errorf("data of %v record must be in the generic format", typ)
+	}
+	v, err := p.parseValue(typ, toks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v record: %v", typ, err)
+	}
+	rec.Value = v
+	return rec, nil
+}
+
+// fieldCounts holds the number of fields in the data of records of
+// the types which have a fixed number.
+var fieldCounts = map[net.DNSType]int{
+	net.DNSTypeA:     1,
+	net.DNSTypeAAAA:  1,
+	net.DNSTypeNS:    1,
+	net.DNSTypeCNAME: 1,
+	net.DNSTypePTR:   1,
+	net.DNSTypeMX:    2,
+	net.DNSTypeSRV:   4,
+	net.DNSTypeSOA:   7,
+	net.DNSTypeCAA:   3,
+}
+
+// parseValue parses the data of a record of type typ.
+func (p *zoneParser) parseValue(typ net.DNSType, toks []zoneToken) (any, error) {
+	args := make([]string, len(toks))
+	for i, t := range toks {
+		args[i] = t.s
+	}
+	if want := fieldCounts[typ]; want != 0 && len(args) != want {
+		return nil, fmt.Errorf("got %d fields, want %d", len(args), want)
+	}
+	switch typ {
+	case net.DNSTypeA, net.DNSTypeAAAA:
+		ip, err := netip.ParseAddr(args[0])
+		if err != nil || ip.Zone() != "" || ip.Is4() != (typ == net.DNSTypeA) {
+			return nil, fmt.Errorf("invalid address %q", args[0])
+		}
+		return ip, nil
+	case net.DNSTypeNS, net.DNSTypeCNAME, net.DNSTypePTR:
+		return p.name(args[0])
+	case net.DNSTypeMX:
+		pref, err := parseUint(args[0], 16)
+		if err != nil {
+			return nil, err
+		}
+		host, err := p.name(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &net.MX{Host: host, Pref: uint16(pref)}, nil
+	case net.DNSTypeSRV:
+		var n [3]uint64
+		for i := range n {
+			var err error
+			if n[i], err = parseUint(args[i], 16); err != nil {
+				return nil, err
+			}
+		}
+		target, err := p.name(args[3])
+		if err != nil {
+			return nil, err
+		}
+		return &net.SRV{Priority: uint16(n[0]), Weight: uint16(n[1]), Port: uint16(n[2]), Target: target}, nil
+	case net.DNSTypeTXT:
+		if len(args) == 0 {
+			return nil, errors.New("no strings")
+		}
+		txt := make([]string, len(args))
+		for i, a := range args {
+			s, err := unescape(a)
+			if err != nil {
+				return nil, err
+			}
+			if len(s) > 255 {
+				return nil, errors.New("string longer than 255 bytes")
+			}
+			txt[i] = s
+		}
+		return txt, nil
+	case net.DNSTypeSOA:
+		ns, err := p.name(args[0])
+		if err != nil {
+			return nil, err
+		}
+		mbox, err := p.name(args[1])
+		if err != nil {
+			return nil, err
+		}
+		serial, err := parseUint(args[2], 32)
+		if err != nil {
+			return nil, err
+		}
+		var times [4]uint32
+		for i := range times {
+			d, err := parseTTL(args[3+i])
+			if err != nil {
+				return nil, err
+			}
+			times[i] = uint32(d / time.Second)
+		}
+		return &net.SOA{
+			NS:      ns,
+			MBox:    mbox,
+			Serial:  uint32(serial),
+			Refresh: times[0],
+			Retry:   times[1],
+			Expire:  times[2],
+			MinTTL:  times[3],
+		}, nil
+	case net.DNSTypeCAA:
+		flags, err := parseUint(args[0], 8)
+		if err != nil {
+			return nil, err
+		}
+		value, err := unescape(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if args[1] == "" || len(args[1]) > 255 {
+			return nil, fmt.Errorf("invalid tag %q", args[1])
+		}
+		return &net.CAA{Flags: uint8(flags), Tag: args[1], Value: value}, nil
+	case net.DNSTypeTLSA:
+		if len(args) < 4 {
+			return nil, fmt.Errorf("got %d fields, want at least 4", len(args))
+		}
+		var n [3]uint64
+		for i := range n {
+			var err error
+			if n[i], err = parseUint(args[i], 8); err != nil {
+				return nil, err
+			}
+		}
+		data, err := hex.DecodeString(strings.Join(args[3:], ""))
+		if err != nil {
+			return nil, err
+		}
+		return &net.TLSA{Usage: uint8(n[0]), Selector: uint8(n[1]), MatchingType: uint8(n[2]), Data: data}, nil
+	case net.DNSTypeSVCB, net.DNSTypeHTTPS:
+		return p.parseSVCB(args)
+	}
+	panic("unreachable")
+}
+
+// parseSVCB parses the data of an SVCB or HTTPS record, in the
+// presentation format of RFC 9460 Section 2.1.
+func (p *zoneParser) parseSVCB(args []string) (*net.SVCB, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("got %d fields, want at least 2", len(args))
+	}
+	priority, err := parseUint(args[0], 16)
+	if err != nil {
+		return nil, err
+	}
+	s := &net.SVCB{Priority: uint16(priority), Target: "."}
+	if args[1] != "." {
+		if s.Target, err = p.name(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range args[2:] {
+		k, v, hasValue := strings.Cut(arg, "=")
+		key, ok := parseSVCParamKey(k)
+		if !ok {
+			return nil, fmt.Errorf("unknown parameter %q", k)
+		}
+		// Only no-default-alpn, and parameters of keys without
+		// a name, may be given without values.
+		if key <= net.SVCParamIPv6Hint && hasValue == (key == net.SVCParamNoDefaultALPN) {
+			return nil, fmt.Errorf("invalid %v parameter %q", key, arg)
+		}
+		value, err := parseSVCParamValue(key, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v parameter: %v", key, err)
+		}
+		s.Params = append(s.Params, net.SVCParam{Key: key, Value: value})
+	}
+	slices.SortFunc(s.Params, func(a, b net.SVCParam) int { return cmp.Compare(a.Key, b.Key) })
+	for i := 1; i < len(s.Params); i++ {
+		if s.Params[i].Key == s.Params[i-1].Key {
+			return nil, fmt.Errorf("duplicate parameter %v", s.Params[i].Key)
+		}
+	}
+	return s, nil
+}
+
+var svcParamKeys = map[string]net.SVCParamKey{
+	"mandatory":       net.SVCParamMandatory,
+	"alpn":            net.SVCParamALPN,
+	"no-default-alpn": net.SVCParamNoDefaultALPN,
+	"port":            net.SVCParamPort,
+	"ipv4hint":        net.SVCParamIPv4Hint,
+	"ech":             net.SVCParamECH,
+	"ipv6hint":        net.SVCParamIPv6Hint,
+}
+
+// parseSVCParamKey parses the name of an SVCB parameter key, such as
+// "alpn" or "key65".
+func parseSVCParamKey(s string) (net.SVCParamKey, bool) {
+	if key, ok := svcParamKeys[s]; ok {
+		return key, true
+	}
+	n, ok := strings.CutPrefix(s, "key")
+	if !ok || n == "" || n[0] == '+' || n[0] == '-' {
+		return 0, false
+	}
+	k, err := strconv.ParseUint(n, 10, 16)
+	if err != nil || k == 65535 {
+		return 0, false
+	}
+	return net.SVCParamKey(k), true
+}
+
+// parseSVCParamValue returns the wire format of the value s of the
+// parameter key.
+func parseSVCParamValue(key net.SVCParamKey, s string) ([]byte, error) {
+	if key == net.SVCParamALPN {
+		// Commas within protocols are escaped as "\,",
+		// which must be decoded after splitting.
+		var b []byte
+		start := 0
+		for i := 0; i <= len(s); i++ {
+			if i < len(s) && s[i] == '\\' {
+				i++
+				continue
+			}
+			if i < len(s) && s[i] != ',' {
+				continue
+			}
+			proto, err := unescape(strings.ReplaceAll(s[start:i], `\,`, ","))
+			if err != nil {
+				return nil, err
+			}
+			if proto == "" || len(proto) > 255 {
+				return nil, fmt.Errorf("invalid protocol %q", proto)
+			}
+			b = append(b, byte(len(proto)))
+			b = append(b, proto...)
+			start = i + 1
+		}
+		return b, nil
+	}
+	s, err := unescape(s)
+	if err != nil {
+		return nil, err
+	}
+	switch key {
+	case net.SVCParamMandatory:
+		var b []byte
+		for _, k := range strings.Split(s, ",") {
+			key, ok := parseSVCParamKey(k)
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q", k)
+			}
+			b = append(b, byte(key>>8), byte(key))
+		}
+		return b, nil
+	case net.SVCParamNoDefaultALPN:
+		return nil, nil
+	case net.SVCParamPort:
+		port, err := parseUint(s, 16)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{byte(port >> 8), byte(port)}, nil
+	case net.SVCParamIPv4Hint, net.SVCParamIPv6Hint:
+		var b []byte
+		for _, a := range strings.Split(s, ",") {
+			ip, err := netip.ParseAddr(a)
+			if err != nil || ip.Zone() != "" || ip.Is4() != (key == net.SVCParamIPv4Hint) {
+				return nil, fmt.Errorf("invalid address %q", a)
+			}
+			b = append(b, ip.AsSlice()...)
+		}
+		return b, nil
+	case net.SVCParamECH:
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// parseGenericData parses record data in the format of RFC 3597
+// Section 5, following the "\#" token.
+func parseGenericData(toks []zoneToken) ([]byte, error) {
+	if len(toks) == 0 {
+		return nil, errors.New(`no length after \#`)
+	}
+	n, err := parseUint(toks[0].s, 16)
+	if err != nil {
+		return nil, err
+	}
+	var s strings.Builder
+	for _, t := range toks[1:] {
+		s.WriteString(t.s)
+	}
+	data, err := hex.DecodeString(s.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(n) {
+		return nil, fmt.Errorf("got %d bytes of data, want %d", len(data), n)
+	}
+	return data, nil
+}
+
+// name returns the rooted name for s, which is relative to the origin
+// unless it is rooted.
+func (p *zoneParser) name(s string) (string, error) {
+	switch {
+	case s == "@":
+		return p.origin, nil
+	case s == "" || strings.Contains(s, ".."):
+		return "", fmt.Errorf("invalid name %q", s)
+	case strings.HasSuffix(s, "."):
+	case p.origin == ".":
+		s += "."
+	default:
+		s += "." + p.origin
+	}
+	if _, err := newName(s); err != nil {
+		return "", fmt.Errorf("invalid name %q", s)
+	}
+	return s, nil
+}
+
+// parseType parses a record type, such as "AAAA" or "TYPE99".
+func parseType(s string) (net.DNSType, bool) {
+	for _, t := range knownTypes {
+		if strings.EqualFold(s, t.String()) {
+			return t, true
+		}
+	}
+	if len(s) > 4 && strings.EqualFold(s[:4], "TYPE") && '0' <= s[4] && s[4] <= '9' {
+		t, err := strconv.ParseUint(s[4:], 10, 16)
+		return net.DNSType(t), err == nil
+	}
+	return 0, false
+}
+
+// parseTTL parses a TTL, in seconds or with units of s, m, h, d and w,
+// such as "1h30m".
+func parseTTL(s string) (time.Duration, error) {
+	var ttl, n uint64
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if '0' <= c && c <= '9' {
+			n = n*10 + uint64(c-'0')
+			digits = true
+			if n > 1<<32 {
+				return 0, fmt.Errorf("invalid TTL %q", s)
+			}
+			continue
+		}
+		unit, ok := ttlUnits[c|0x20]
+		if !ok || !digits {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		ttl += n * unit
+		n, digits = 0, false
+	}
+	ttl += n
+	if s == "" || ttl > 1<<31-1 {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
+
+var ttlUnits = map[byte]uint64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+
+func parseUint(s string, bitSize int) (uint64, error) {
+	n, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// unescape decodes the escapes of the forms \X and \DDD in s.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b = append(b, s[i])
+			continue
+		}
+		if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+			n := int(s[i+1]-'0')*100 + int(s[i+2]-'0')*10 + int(s[i+3]-'0')
+			if n > 255 {
+				return "", fmt.Errorf("invalid escape in %q", s)
+			}
+			b = append(b, byte(n))
+			i += 3
+			continue
+		}
+		if i+1 == len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b = append(b, s[i+1])
+		i++
+	}
+	return string(b), nil
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
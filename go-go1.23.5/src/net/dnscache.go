@@ -0,0 +1,294 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// DNS response cache for the Go resolver.
+
+package net
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultDNSCacheEntries = 4096
+	defaultDNSCacheMaxTTL  = 24 * time.Hour
+	defaultDNSCacheNegTTL  = time.Hour
+)
+
+// dnsCacheNow returns the current time. It is replaced by tests.
+var dnsCacheNow = time.Now
+
+// A DNSCache caches the responses to queries made by Go's built-in DNS
+// resolver, so that repeated lookups of the same names need not be sent
+// to the network. It is used by a [Resolver] whose Cache field refers to
+// it, and may be shared by several Resolvers.
+//
+// A response to a query is cached for the smallest TTL of the records
+// in its answer section. A negative response, which reports that a name
+// does not exist or has no records of the requested type, is cached for
+// the smaller of the TTL and the MINIMUM field of the SOA record in its
+// authority section, or not at all if it has none (RFC 2308). Responses
+// with a TTL of zero, and failures such as timeouts and SERVFAIL
+// responses, are not cached.
+//
+// The cache only holds responses from DNS servers. Lookups answered by
+// the system resolver or the hosts file are not cached.
+//
+// The zero DNSCache is ready to use. A DNSCache must not be copied after
+// first use.
+type DNSCache struct {
+	// MaxEntries is the maximum number of responses to cache. When the
+	// cache is full, the least recently used response is evicted.
+	// If zero, a default of 4096 is used.
+	MaxEntries int
+
+	// MaxTTL is the longest time for which a response is cached,
+	// whatever the TTLs of its records. If zero, one day is used.
+	MaxTTL time.Duration
+
+	// MaxNegativeTTL is the longest time for which a negative response
+	// is cached. If zero, one hour is used. If negative, negative
+	// responses are not cached.
+	MaxNegativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[dnsCacheKey]*dnsCacheEntry
+	lru     dnsCacheEntry // sentinel; lru.next is the most recently used
+	stats   DNSCacheStats
+}
+
+// DNSCacheStats are statistics describing the use of a [DNSCache].
+type DNSCacheStats struct {
+	Entries      int    // responses currently cached, including expired ones not yet removed
+	Hits         uint64 // queries answered by a cached response
+	NegativeHits uint64 // queries answered by a cached negative response
+	Misses       uint64 // queries sent to the network
+	Evictions    uint64 // unexpired responses removed to make room for others
+}
+
+type dnsCacheKey struct {
+	name  string // lower-case, rooted
+	qtype dnsmessage.Type
+}
+
+type dnsCacheEntry struct {
+	key        dnsCacheKey
+	a          dnsAnswer // only the server is set if negative
+	negative   bool
+	stored     time.Time
+	expires    time.Time
+	prev, next *dnsCacheEntry
+}
+
+func (c *DNSCache) maxEntries() int {
+	if c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return defaultDNSCacheEntries
+}
+
+// Stats returns statistics describing the use of c.
+func (c *DNSCache) Stats() DNSCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// Flush removes all responses from c.
+func (c *DNSCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.lru.prev, c.lru.next = &c.lru, &c.lru
+}
+
+// FlushName removes the responses to queries for name from c.
+// The name is compared without regard to case, and treated as
+// rooted, so that "Example.com" and "example.com." are the same.
+// Names to which a query was extended using the search list in
+// resolv.conf must be flushed by their full names.
+func (c *DNSCache) FlushName(name string) {
+	key := dnsCacheName(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if k.name == key {
+			c.remove(e)
+		}
+	}
+}
+
+// dnsCacheName returns the cache key for the query name.
+func dnsCacheName(name string) string {
+	for i := 0; i < len(name); i++ {
+		if 'A' <= name[i] && name[i] <= 'Z' {
+			b := []byte(name)
+			lowerASCIIBytes(b)
+			name = string(b)
+			break
+		}
+	}
+	if len(name) == 0 || name[len(name)-1] != '.' {
+		name += "."
+	}
+	return name
+}
+
+// get returns the cached response to a query for name of type qtype,
+// and whether there is one. If the response is negative, only the
+// server of the returned answer is set.
+func (c *DNSCache) get(name string, qtype dnsmessage.Type) (a dnsAnswer, negative, ok bool) {
+	key := dnsCacheKey{dnsCacheName(name), qtype}
+	now := dnsCacheNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	if e != nil && !now.Before(e.expires) {
+		c.remove(e)
+		e = nil
+	}
+	if e == nil {
+		c.stats.Misses++
+		return dnsAnswer{}, false, false
+	}
+	c.moveToFront(e)
+	if e.negative {
+		c.stats.NegativeHits++
+		return e.a, true, true
+	}
+	c.stats.Hits++
+	a = e.a
+	a.age = now.Sub(e.stored)
+	return a, false, true
+}
+
+// put caches the response a to a query of type qtype.
+// If negative is set, the response is negative.
+func (c *DNSCache) put(qtype dnsmessage.Type, a dnsAnswer, negative bool) {
+	var ttl time.Duration
+	if negative {
+		ttl = negativeTTL(a.msg)
+		switch {
+		case c.MaxNegativeTTL < 0:
+			return
+		case c.MaxNegativeTTL > 0:
+			ttl = min(ttl, c.MaxNegativeTTL)
+		default:
+			ttl = min(ttl, defaultDNSCacheNegTTL)
+		}
+	} else {
+		ttl = answerTTL(a.msg)
+	}
+	maxTTL := c.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = defaultDNSCacheMaxTTL
+	}
+	ttl = min(ttl, maxTTL)
+	if ttl <= 0 {
+		return
+	}
+
+	now := dnsCacheNow()
+	e := &dnsCacheEntry{
+		key:      dnsCacheKey{dnsCacheName(a.name), qtype},
+		a:        a,
+		negative: negative,
+		stored:   now,
+		expires:  now.Add(ttl),
+	}
+	if negative {
+		e.a = dnsAnswer{server: a.server}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[dnsCacheKey]*dnsCacheEntry)
+		c.lru.prev, c.lru.next = &c.lru, &c.lru
+	}
+	if old := c.entries[e.key]; old != nil {
+		c.remove(old)
+	}
+	for len(c.entries) >= c.maxEntries() {
+		oldest := c.lru.prev
+		if now.Before(oldest.expires) {
+			c.stats.Evictions++
+		}
+		c.remove(oldest)
+	}
+	c.entries[e.key] = e
+	e.prev, e.next = &c.lru, c.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+func (c *DNSCache) remove(e *dnsCacheEntry) {
+	delete(c.entries, e.key)
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (c *DNSCache) moveToFront(e *dnsCacheEntry) {
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = &c.lru, c.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+// answerTTL returns the smallest TTL of the records in the answer
+// section of the message p, which is positioned at its start,
+// or zero if the section cannot be parsed.
+func answerTTL(p dnsmessage.Parser) time.Duration {
+	ttl := uint32(1<<31 - 1)
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			return time.Duration(ttl) * time.Second
+		}
+		if err != nil {
+			return 0
+		}
+		ttl = min(ttl, ttlValue(h.TTL))
+		if err := p.SkipAnswer(); err != nil {
+			return 0
+		}
+	}
+}
+
+// negativeTTL returns the time for which the negative response p,
+// which is positioned at the start of its answer section, may be
+// cached, or zero if it has no SOA record.
+func negativeTTL(p dnsmessage.Parser) time.Duration {
+	if err := p.SkipAllAnswers(); err != nil {
+		return 0
+	}
+	for {
+		h, err := p.AuthorityHeader()
+		if err != nil {
+			return 0
+		}
+		if h.Type != dnsmessage.TypeSOA {
+			if err := p.SkipAuthority(); err != nil {
+				return 0
+			}
+			continue
+		}
+		soa, err := p.SOAResource()
+		if err != nil {
+			return 0
+		}
+		return time.Duration(min(ttlValue(h.TTL), ttlValue(soa.MinTTL))) * time.Second
+	}
+}
+
+// ttlValue returns the value of a TTL field, which is zero if its
+// most significant bit is set (RFC 2181 Section 8).
+func ttlValue(ttl uint32) uint32 {
+	if ttl > 1<<31-1 {
+		return 0
+	}
+	return ttl
+}
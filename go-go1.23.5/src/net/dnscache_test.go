@@ -0,0 +1,233 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package net
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// setDNSCacheNow sets the time seen by DNS caches for the duration of t.
+func setDNSCacheNow(t *testing.T, now *time.Time) {
+	t.Cleanup(func() { dnsCacheNow = time.Now })
+	dnsCacheNow = func() time.Time { return *now }
+}
+
+// dnsCacheTestServer returns a fake DNS server which answers A queries
+// for names beginning with "nx" with NXDOMAIN, those beginning with
+// "fail" with SERVFAIL, and other names with an A record with TTL ttl.
+// Negative responses have an SOA record with the given MINIMUM, unless
+// it is zero. It counts the queries it receives in queries.
+func dnsCacheTestServer(ttl, soaMinTTL uint32, queries *atomic.Int32) *fakeDNSServer {
+	return &fakeDNSServer{rh: func(_, _ string, q dnsmessage.Message, _ time.Time) (dnsmessage.Message, error) {
+		queries.Add(1)
+		r := dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:                 q.ID,
+				Response:           true,
+				RecursionAvailable: true,
+			},
+			Questions: q.Questions,
+		}
+		name := q.Questions[0].Name.String()
+		switch {
+		case len(name) > 4 && name[:4] == "fail":
+			r.RCode = dnsmessage.RCodeServerFailure
+		case len(name) > 2 && name[:2] == "nx":
+			r.RCode = dnsmessage.RCodeNameError
+			if soaMinTTL != 0 {
+				r.Authorities = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{
+						Name:  mustNewName("example."),
+						Type:  dnsmessage.TypeSOA,
+						Class: dnsmessage.ClassINET,
+						TTL:   300,
+					},
+					Body: &dnsmessage.SOAResource{
+						NS:     mustNewName("ns.example."),
+						MBox:   mustNewName("hostmaster.example."),
+						MinTTL: soaMinTTL,
+					},
+				}}
+			}
+		default:
+			r.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{
+					Name:  q.Questions[0].Name,
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+					TTL:   ttl,
+				},
+				Body: &dnsmessage.AResource{A: TestAddr},
+			}}
+		}
+		return r, nil
+	}}
+}
+
+func TestDNSCache(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	setDNSCacheNow(t, &now)
+
+	var queries atomic.Int32
+	cache := &DNSCache{}
+	r := &Resolver{PreferGo: true, Dial: dnsCacheTestServer(60, 30, &queries).DialContext, Cache: cache}
+	conf := getSystemDNSConfig()
+	lookup := func(name string) ([4]byte, error) {
+		t.Helper()
+		p, _, err := r.tryOneName(context.Background(), conf, name, dnsmessage.TypeA)
+		if err != nil {
+			return [4]byte{}, err
+		}
+		a, err := p.AResource()
+		if err != nil {
+			t.Fatalf("%v: parsing cached answer: %v", name, err)
+		}
+		return a.A, nil
+	}
+	check := func(name string, wantQueries int32, wantErr error) {
+		t.Helper()
+		queries.Store(0)
+		a, err := lookup(name)
+		if wantErr == nil && (err != nil || a != TestAddr) {
+			t.Errorf("%v: got %v, %v; want %v", name, a, err, TestAddr)
+		}
+		if de, ok := err.(*DNSError); wantErr != nil && (!ok || de.Err != wantErr.Error()) {
+			t.Errorf("%v: got error %v, want %v", name, err, wantErr)
+		}
+		if got := queries.Load(); got != wantQueries {
+			t.Errorf("%v: sent %v queries, want %v", name, got, wantQueries)
+		}
+	}
+
+	check("www.example.", 1, nil)
+	check("www.example.", 0, nil)
+	check("WWW.Example.", 0, nil)
+	now = now.Add(59 * time.Second)
+	check("www.example.", 0, nil)
+	now = now.Add(time.Second)
+	check("www.example.", 1, nil)
+
+	check("nx.example.", 1, errNoSuchHost)
+	check("nx.example.", 0, errNoSuchHost)
+	now = now.Add(30 * time.Second)
+	check("nx.example.", 1, errNoSuchHost)
+
+	check("fail.example.", int32(conf.attempts*len(conf.servers)), errServerTemporarilyMisbehaving)
+	check("fail.example.", int32(conf.attempts*len(conf.servers)), errServerTemporarilyMisbehaving)
+
+	want := DNSCacheStats{Entries: 2, Hits: 3, NegativeHits: 1, Misses: 6}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	cache.FlushName("WWW.EXAMPLE")
+	check("www.example.", 1, nil)
+	check("nx.example.", 0, errNoSuchHost)
+	cache.Flush()
+	if got := cache.Stats().Entries; got != 0 {
+		t.Errorf("after Flush, Stats().Entries = %v, want 0", got)
+	}
+	check("nx.example.", 1, errNoSuchHost)
+}
+
+func TestDNSCacheTTLs(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	setDNSCacheNow(t, &now)
+
+	for _, test := range []struct {
+		name      string
+		ttl       uint32
+		soaMinTTL uint32
+		cache     *DNSCache
+		query     string
+		cachedFor time.Duration // 0 if not cached
+	}{
+		{"zero TTL", 0, 0, &DNSCache{}, "www.example.", 0},
+		{"large TTL", 1 << 30, 0, &DNSCache{}, "www.example.", 24 * time.Hour},
+		{"invalid TTL", 1 << 31, 0, &DNSCache{}, "www.example.", 0},
+		{"MaxTTL", 600, 0, &DNSCache{MaxTTL: time.Minute}, "www.example.", time.Minute},
+		{"negative without SOA", 0, 0, &DNSCache{}, "nx.example.", 0},
+		{"negative SOA TTL", 0, 1000, &DNSCache{}, "nx.example.", 300 * time.Second},
+		{"MaxNegativeTTL", 0, 1000, &DNSCache{MaxNegativeTTL: time.Minute}, "nx.example.", time.Minute},
+		{"negative caching disabled", 0, 30, &DNSCache{MaxNegativeTTL: -1}, "nx.example.", 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var queries atomic.Int32
+			r := &Resolver{PreferGo: true, Dial: dnsCacheTestServer(test.ttl, test.soaMinTTL, &queries).DialContext, Cache: test.cache}
+			conf := getSystemDNSConfig()
+			start := now
+			defer func() { now = start }()
+			lookup := func() int32 {
+				queries.Store(0)
+				r.tryOneName(context.Background(), conf, test.query, dnsmessage.TypeA)
+				return queries.Load()
+			}
+			lookup()
+			if test.cachedFor == 0 {
+				if lookup() == 0 {
+					t.Errorf("second lookup answered from cache, want query")
+				}
+				return
+			}
+			now = start.Add(test.cachedFor - time.Second)
+			if lookup() != 0 {
+				t.Errorf("lookup after %v sent a query, want cached", test.cachedFor-time.Second)
+			}
+			now = start.Add(test.cachedFor)
+			if lookup() == 0 {
+				t.Errorf("lookup after %v answered from cache, want query", test.cachedFor)
+			}
+		})
+	}
+}
+
+func TestDNSCacheEviction(t *testing.T) {
+	var queries atomic.Int32
+	cache := &DNSCache{MaxEntries: 2}
+	r := &Resolver{PreferGo: true, Dial: dnsCacheTestServer(60, 0, &queries).DialContext, Cache: cache}
+	conf := getSystemDNSConfig()
+	lookup := func(name string) int32 {
+		queries.Store(0)
+		if _, _, err := r.tryOneName(context.Background(), conf, name, dnsmessage.TypeA); err != nil {
+			t.Fatal(err)
+		}
+		return queries.Load()
+	}
+	lookup("a.example.")
+	lookup("b.example.")
+	lookup("a.example.") // a is now more recently used than b
+	lookup("c.example.") // evicts b
+	if lookup("a.example.") != 0 || lookup("c.example.") != 0 {
+		t.Errorf("recently used entries were evicted")
+	}
+	if lookup("b.example.") != 1 {
+		t.Errorf("least recently used entry was not evicted")
+	}
+	if got := cache.Stats(); got.Entries != 2 || got.Evictions != 2 {
+		t.Errorf("Stats() = %+v, want 2 entries and 2 evictions", got)
+	}
+}
+
+func TestDNSCacheLookupHost(t *testing.T) {
+	var queries atomic.Int32
+	cache := &DNSCache{}
+	r := &Resolver{PreferGo: true, Dial: dnsCacheTestServer(60, 30, &queries).DialContext, Cache: cache}
+	for i := 0; i < 3; i++ {
+		addrs, err := r.goLookupIP(context.Background(), "ip4", "www.example.", hostLookupDNS, nil)
+		if err != nil || len(addrs) != 1 {
+			t.Fatalf("got %v, %v; want one address", addrs, err)
+		}
+	}
+	if got := queries.Load(); got != 1 {
+		t.Errorf("sent %v queries for three lookups, want 1", got)
+	}
+}
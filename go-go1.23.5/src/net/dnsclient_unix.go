@@ -171,6 +171,9 @@ func (r *Resolver) exchange(ctx context.Context, server string, q dnsmessage.Que
 	if err != nil {
 		return dnsmessage.Parser{}, dnsmessage.Header{}, errCannotMarshalDNSMessage
 	}
+	if t := r.transport(); t != nil {
+		return exchangeTransport(ctx, t, server, id, q, udpReq, timeout)
+	}
 	var networks []string
 	if useTCP {
 		networks = []string{"tcp"}
@@ -217,6 +220,36 @@ func (r *Resolver) exchange(ctx context.Context, server string, q dnsmessage.Que
 	return dnsmessage.Parser{}, dnsmessage.Header{}, errNoAnswerFromDNSServer
 }
 
+// exchangeTransport sends the query req, with the given id and question,
+// to server using t.
+func exchangeTransport(ctx context.Context, t DNSTransport, server string, id uint16, q dnsmessage.Question, req []byte, timeout time.Duration) (dnsmessage.Parser, dnsmessage.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	b, err := t.Exchange(ctx, server, req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errTimeout
+		}
+		return dnsmessage.Parser{}, dnsmessage.Header{}, err
+	}
+	var p dnsmessage.Parser
+	h, err := p.Start(b)
+	if err != nil {
+		return dnsmessage.Parser{}, dnsmessage.Header{}, errCannotUnmarshalDNSMessage
+	}
+	rq, err := p.Question()
+	if err != nil {
+		return dnsmessage.Parser{}, dnsmessage.Header{}, errCannotUnmarshalDNSMessage
+	}
+	if !checkResponse(id, q, h, rq) {
+		return dnsmessage.Parser{}, dnsmessage.Header{}, errInvalidDNSResponse
+	}
+	if err := p.SkipQuestion(); err != dnsmessage.ErrSectionDone {
+		return dnsmessage.Parser{}, dnsmessage.Header{}, errInvalidDNSResponse
+	}
+	return p, h, nil
+}
+
 // checkHeader performs basic sanity checks on the header.
 func checkHeader(p *dnsmessage.Parser, h dnsmessage.Header) error {
 	rcode, hasAdd := extractExtendedRCode(*p, h)
@@ -291,16 +324,32 @@ func extractExtendedRCode(p dnsmessage.Parser, hdr dnsmessage.Header) (dnsmessag
 	}
 }
 
+// A dnsAnswer is a response to a query made by the Go resolver.
+type dnsAnswer struct {
+	name   string            // name queried, after any search list expansion
+	server string            // server which sent the response
+	hdr    dnsmessage.Header // header of the response
+	msg    dnsmessage.Parser // positioned at the start of the answer section
+	p      dnsmessage.Parser // positioned at the first answer of the type queried
+	age    time.Duration     // time for which the response has been cached
+}
+
 // Do a lookup for a single name, which must be rooted
 // (otherwise answer will not find the answers).
 func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype dnsmessage.Type) (dnsmessage.Parser, string, error) {
+	a, err := r.queryOneName(ctx, cfg, name, qtype)
+	return a.p, a.server, err
+}
+
+// queryOneName is like tryOneName, but returns the whole response.
+func (r *Resolver) queryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype dnsmessage.Type) (dnsAnswer, error) {
 	var lastErr error
 	serverOffset := cfg.serverOffset()
 	sLen := uint32(len(cfg.servers))
 
 	n, err := dnsmessage.NewName(name)
 	if err != nil {
-		return dnsmessage.Parser{}, "", &DNSError{Err: errCannotMarshalDNSMessage.Error(), Name: name}
+		return dnsAnswer{}, &DNSError{Err: errCannotMarshalDNSMessage.Error(), Name: name}
 	}
 	q := dnsmessage.Question{
 		Name:  n,
@@ -308,6 +357,16 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 		Class: dnsmessage.ClassINET,
 	}
 
+	cache := r.cache()
+	if cache != nil {
+		if a, negative, ok := cache.get(name, qtype); ok {
+			if negative {
+				return dnsAnswer{server: a.server}, newDNSError(errNoSuchHost, name, a.server)
+			}
+			return a, nil
+		}
+	}
+
 	for i := 0; i < cfg.attempts; i++ {
 		for j := uint32(0); j < sLen; j++ {
 			server := cfg.servers[(serverOffset+j)%sLen]
@@ -323,12 +382,17 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 				lastErr = dnsErr
 				continue
 			}
+			a := dnsAnswer{name: name, server: server, hdr: h, msg: p}
 
 			if err := checkHeader(&p, h); err != nil {
 				if err == errNoSuchHost {
+					if cache != nil {
+						cache.put(qtype, a, true)
+					}
 					// The name does not exist, so trying
 					// another server won't help.
-					return p, server, newDNSError(errNoSuchHost, name, server)
+					a.p = p
+					return a, newDNSError(errNoSuchHost, name, server)
 				}
 				lastErr = newDNSError(err, name, server)
 				continue
@@ -336,18 +400,26 @@ func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string,
 
 			if err := skipToAnswer(&p, qtype); err != nil {
 				if err == errNoSuchHost {
+					if cache != nil {
+						cache.put(qtype, a, true)
+					}
 					// The name does not exist, so trying
 					// another server won't help.
-					return p, server, newDNSError(errNoSuchHost, name, server)
+					a.p = p
+					return a, newDNSError(errNoSuchHost, name, server)
 				}
 				lastErr = newDNSError(err, name, server)
 				continue
 			}
 
-			return p, server, nil
+			a.p = p
+			if cache != nil {
+				cache.put(qtype, a, false)
+			}
+			return a, nil
 		}
 	}
-	return dnsmessage.Parser{}, "", lastErr
+	return dnsAnswer{}, lastErr
 }
 
 // A resolverConfig represents a DNS stub resolver configuration.
@@ -438,13 +510,19 @@ func (conf *resolverConfig) releaseSema() {
 }
 
 func (r *Resolver) lookup(ctx context.Context, name string, qtype dnsmessage.Type, conf *dnsConfig) (dnsmessage.Parser, string, error) {
+	a, err := r.lookupAnswer(ctx, name, qtype, conf)
+	return a.p, a.server, err
+}
+
+// lookupAnswer is like lookup, but returns the whole response.
+func (r *Resolver) lookupAnswer(ctx context.Context, name string, qtype dnsmessage.Type, conf *dnsConfig) (dnsAnswer, error) {
 	if !isDomainName(name) {
 		// We used to use "invalid domain name" as the error,
 		// but that is a detail of the specific lookup mechanism.
 		// Other lookups might allow broader name syntax
 		// (for example Multicast DNS allows UTF-8; see RFC 6762).
 		// For consistency with libc resolvers, report no such host.
-		return dnsmessage.Parser{}, "", newDNSError(errNoSuchHost, name, "")
+		return dnsAnswer{}, newDNSError(errNoSuchHost, name, "")
 	}
 
 	if conf == nil {
@@ -452,12 +530,11 @@ func (r *Resolver) lookup(ctx context.Context, name string, qtype dnsmessage.Typ
 	}
 
 	var (
-		p      dnsmessage.Parser
-		server string
-		err    error
+		a   dnsAnswer
+		err error
 	)
 	for _, fqdn := range conf.nameList(name) {
-		p, server, err = r.tryOneName(ctx, conf, fqdn, qtype)
+		a, err = r.queryOneName(ctx, conf, fqdn, qtype)
 		if err == nil {
 			break
 		}
@@ -468,7 +545,7 @@ func (r *Resolver) lookup(ctx context.Context, name string, qtype dnsmessage.Typ
 		}
 	}
 	if err == nil {
-		return p, server, nil
+		return a, nil
 	}
 	if err, ok := err.(*DNSError); ok {
 		// Show original name passed to lookup, not suffixed one.
@@ -476,7 +553,7 @@ func (r *Resolver) lookup(ctx context.Context, name string, qtype dnsmessage.Typ
 		// just one is misleading. See also golang.org/issue/6324.
 		err.Name = name
 	}
-	return dnsmessage.Parser{}, "", err
+	return dnsAnswer{}, err
 }
 
 // avoidDNS reports whether this is a hostname for which we should not
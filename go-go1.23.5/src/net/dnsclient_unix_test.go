@@ -2801,3 +2801,57 @@ func TestExtendedRCode(t *testing.T) {
 		t.Fatalf("r.tryOneName(): unexpected error: %v", err)
 	}
 }
+
+type dnsTransportFunc func(ctx context.Context, address string, msg []byte) ([]byte, error)
+
+func (f dnsTransportFunc) Exchange(ctx context.Context, address string, msg []byte) ([]byte, error) {
+	return f(ctx, address, msg)
+}
+
+func TestDNSTransport(t *testing.T) {
+	var q dnsmessage.Message
+	var changeID bool
+	r := &Resolver{Transport: dnsTransportFunc(func(ctx context.Context, address string, msg []byte) ([]byte, error) {
+		if err := q.Unpack(msg); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := fakeDNSServerSuccessful.rh("", address, q, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		if changeID {
+			resp.ID++
+		}
+		return resp.Pack()
+	})}
+	if !systemConf().mustUseGoResolver(r) {
+		t.Errorf("Resolver with Transport does not use Go's resolver")
+	}
+	conf := getSystemDNSConfig()
+
+	p, _, err := r.tryOneName(context.Background(), conf, "www.golang.org.", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, err := p.AResource(); err != nil || a.A != TestAddr {
+		t.Errorf("got %v, %v; want %v", a.A, err, TestAddr)
+	}
+
+	changeID = true
+	_, _, err = r.tryOneName(context.Background(), conf, "www.golang.org.", dnsmessage.TypeA)
+	if de, ok := err.(*DNSError); !ok || de.Err != errInvalidDNSResponse.Error() {
+		t.Errorf("response with wrong ID: got error %v, want %v", err, errInvalidDNSResponse)
+	}
+
+	// The transport's context is canceled after the timeout.
+	r.Transport = dnsTransportFunc(func(ctx context.Context, address string, msg []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	tconf := *conf
+	tconf.timeout = time.Millisecond
+	_, _, err = r.tryOneName(context.Background(), &tconf, "www.golang.org.", dnsmessage.TypeA)
+	if de, ok := err.(*DNSError); !ok || !de.IsTimeout {
+		t.Errorf("transport timeout: got error %v, want timeout", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"internal/itoa"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// A DNSType is the type of a DNS resource record, such as [DNSTypeA].
+type DNSType uint16
+
+// DNS resource record types.
+const (
+	DNSTypeA     DNSType = 1
+	DNSTypeNS    DNSType = 2
+	DNSTypeCNAME DNSType = 5
+	DNSTypeSOA   DNSType = 6
+	DNSTypePTR   DNSType = 12
+	DNSTypeMX    DNSType = 15
+	DNSTypeTXT   DNSType = 16
+	DNSTypeAAAA  DNSType = 28
+	DNSTypeSRV   DNSType = 33
+	DNSTypeTLSA  DNSType = 52
+	DNSTypeSVCB  DNSType = 64
+	DNSTypeHTTPS DNSType = 65
+	DNSTypeCAA   DNSType = 257
+)
+
+var dnsTypeNames = map[DNSType]string{
+	DNSTypeA:     "A",
+	DNSTypeNS:    "NS",
+	DNSTypeCNAME: "CNAME",
+	DNSTypeSOA:   "SOA",
+	DNSTypePTR:   "PTR",
+	DNSTypeMX:    "MX",
+	DNSTypeTXT:   "TXT",
+	DNSTypeAAAA:  "AAAA",
+	DNSTypeSRV:   "SRV",
+	DNSTypeTLSA:  "TLSA",
+	DNSTypeSVCB:  "SVCB",
+	DNSTypeHTTPS: "HTTPS",
+	DNSTypeCAA:   "CAA",
+}
+
+// String returns the mnemonic of t, such as "AAAA", or for types
+// without one, its generic form such as "TYPE99" (RFC 3597).
+func (t DNSType) String() string {
+	if s, ok := dnsTypeNames[t]; ok {
+		return s
+	}
+	return "TYPE" + itoa.Uitoa(uint(t))
+}
+
+// A DNSResponse is a response to a query made by [Resolver.LookupRecords].
+type DNSResponse struct {
+	// Name is the name which was queried, after any extension
+	// using the search list in resolv.conf. It is rooted.
+	Name string
+
+	// Server is the address of the DNS server which sent the response.
+	Server string
+
+	// AuthenticData reports whether the server indicated, with the
+	// AD bit, that it validated the records in the response using
+	// DNSSEC. Since the bit could have been set by anyone able to
+	// tamper with the response, it is only reported if resolv.conf
+	// has the trust-ad option, stating that its servers and the
+	// path to them are trusted.
+	AuthenticData bool
+
+	// Answers, Authorities and Additionals are the records in the
+	// answer, authority and additional sections of the response.
+	// The EDNS(0) OPT pseudo-record is not included.
+	Answers     []DNSRecord
+	Authorities []DNSRecord
+	Additionals []DNSRecord
+}
+
+// A DNSRecord is a DNS resource record.
+type DNSRecord struct {
+	Name  string  // owner name, rooted
+	Type  DNSType // record type
+	Class uint16  // record class; 1 for the Internet
+
+	// TTL is the time for which the record may be cached. If the
+	// response was taken from the Resolver's cache, it is reduced
+	// by the time for which it has been cached.
+	TTL time.Duration
+
+	// Value is the parsed data of the record. Its type depends on
+	// the record type:
+	//
+	//	A, AAAA          netip.Addr
+	//	NS, CNAME, PTR   string, a rooted name
+	//	MX               *MX
+	//	SRV              *SRV
+	//	TXT              []string
+	//	SOA              *SOA
+	//	CAA              *CAA
+	//	TLSA             *TLSA
+	//	SVCB, HTTPS      *SVCB
+	//
+	// It is nil for other types, and for records of the last
+	// three types whose data is malformed.
+	Value any
+
+	// Data is the record data in wire format, for records of types
+	// other than A, AAAA, NS, CNAME, PTR, MX, SRV, TXT and SOA.
+	// Names in the data of those types may be compressed, and so
+	// cannot be decoded without the rest of the response.
+	Data []byte
+}
+
+// LookupRecords returns the records of type typ for the name host.
+//
+// It always uses Go's built-in DNS resolver, whatever the value of
+// PreferGo, with the servers, search list and retry options set in
+// resolv.conf, and the Resolver's Cache if it has one. All records in
+// the response are returned, including any CNAME records in its answer
+// section which lead to those of type typ. If there are no records of
+// type typ, LookupRecords returns a [DNSError] with IsNotFound set.
+//
+// The returned names are not validated, and may contain characters
+// other than those permitted in host names.
+func (r *Resolver) LookupRecords(ctx context.Context, host string, typ DNSType) (*DNSResponse, error) {
+	conf := getSystemDNSConfig()
+	a, err := r.lookupAnswer(ctx, host, dnsmessage.Type(typ), conf)
+	if err != nil {
+		return nil, err
+	}
+	resp := &DNSResponse{
+		Name:          a.name,
+		Server:        a.server,
+		AuthenticData: a.hdr.AuthenticData && conf.trustAD,
+	}
+	p := a.msg
+	if resp.Answers, err = dnsRecords(&p, p.Answer, a.age); err == nil {
+		if resp.Authorities, err = dnsRecords(&p, p.Authority, a.age); err == nil {
+			resp.Additionals, err = dnsRecords(&p, p.Additional, a.age)
+		}
+	}
+	if err != nil {
+		return nil, &DNSError{
+			Err:    errCannotUnmarshalDNSMessage.Error(),
+			Name:   host,
+			Server: a.server,
+		}
+	}
+	return resp, nil
+}
+
+// dnsRecords parses the records of a section of the message p, using
+// next, which is p's method returning the next record in the section.
+// Their TTLs are reduced by age.
+func dnsRecords(p *dnsmessage.Parser, next func() (dnsmessage.Resource, error), age time.Duration) ([]DNSRecord, error) {
+	var recs []DNSRecord
+	for {
+		res, err := next()
+		if err == dnsmessage.ErrSectionDone {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if res.Header.Type == dnsmessage.TypeOPT {
+			continue
+		}
+		ttl := time.Duration(ttlValue(res.Header.TTL))*time.Second - age
+		recs = append(recs, DNSRecord{
+			Name:  res.Header.Name.String(),
+			Type:  DNSType(res.Header.Type),
+			Class: uint16(res.Header.Class),
+			TTL:   max(ttl, 0),
+		})
+		rec := &recs[len(recs)-1]
+		switch b := res.Body.(type) {
+		case *dnsmessage.AResource:
+			rec.Value = netip.AddrFrom4(b.A)
+		case *dnsmessage.AAAAResource:
+			rec.Value = netip.AddrFrom16(b.AAAA)
+		case *dnsmessage.NSResource:
+			rec.Value = b.NS.String()
+		case *dnsmessage.CNAMEResource:
+			rec.Value = b.CNAME.String()
+		case *dnsmessage.PTRResource:
+			rec.Value = b.PTR.String()
+		case *dnsmessage.MXResource:
+			rec.Value = &MX{Host: b.MX.String(), Pref: b.Pref}
+		case *dnsmessage.SRVResource:
+			rec.Value = &SRV{Target: b.Target.String(), Port: b.Port, Priority: b.Priority, Weight: b.Weight}
+		case *dnsmessage.TXTResource:
+			rec.Value = b.TXT
+		case *dnsmessage.SOAResource:
+			rec.Value = &SOA{
+				NS:      b.NS.String(),
+				MBox:    b.MBox.String(),
+				Serial:  b.Serial,
+				Refresh: b.Refresh,
+				Retry:   b.Retry,
+				Expire:  b.Expire,
+				MinTTL:  b.MinTTL,
+			}
+		case *dnsmessage.UnknownResource:
+			rec.Data = b.Data
+			var v any
+			var ok bool
+			switch rec.Type {
+			case DNSTypeCAA:
+				v, ok = parseCAA(b.Data)
+			case DNSTypeTLSA:
+				v, ok = parseTLSA(b.Data)
+			case DNSTypeSVCB, DNSTypeHTTPS:
+				v, ok = parseSVCB(b.Data)
+			}
+			if ok {
+				rec.Value = v
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package net
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// svcbTestData is the data of an SVCB record with priority 1, target
+// ".", and alpn "h2,h3", port 443, ipv4hint 192.0.2.1 and ech parameters.
+var svcbTestData = []byte{
+	0, 1, 0,
+	0, 1, 0, 6, 2, 'h', '2', 2, 'h', '3',
+	0, 3, 0, 2, 1, 187,
+	0, 4, 0, 4, 192, 0, 2, 1,
+	0, 5, 0, 3, 1, 2, 3,
+}
+
+func lookupRecordsTestServer(queries *atomic.Int32) *fakeDNSServer {
+	rr := func(name string, typ dnsmessage.Type, body dnsmessage.ResourceBody) dnsmessage.Resource {
+		return dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: mustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   body,
+		}
+	}
+	typeHTTPS := dnsmessage.Type(DNSTypeHTTPS)
+	typeCAA := dnsmessage.Type(DNSTypeCAA)
+	typeTLSA := dnsmessage.Type(DNSTypeTLSA)
+	return &fakeDNSServer{rh: func(_, _ string, q dnsmessage.Message, _ time.Time) (dnsmessage.Message, error) {
+		queries.Add(1)
+		r := dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:                 q.ID,
+				Response:           true,
+				RecursionAvailable: true,
+				AuthenticData:      true,
+			},
+			Questions: q.Questions,
+		}
+		switch q.Questions[0].Name.String() + " " + DNSType(q.Questions[0].Type).String() {
+		case "www.example. HTTPS":
+			r.Answers = []dnsmessage.Resource{
+				rr("www.example.", dnsmessage.TypeCNAME, &dnsmessage.CNAMEResource{CNAME: mustNewName("svc.example.")}),
+				rr("svc.example.", typeHTTPS, &dnsmessage.UnknownResource{Type: typeHTTPS, Data: svcbTestData}),
+			}
+			r.Authorities = []dnsmessage.Resource{
+				rr("example.", dnsmessage.TypeNS, &dnsmessage.NSResource{NS: mustNewName("ns.example.")}),
+			}
+			r.Additionals = []dnsmessage.Resource{
+				rr("ns.example.", dnsmessage.TypeA, &dnsmessage.AResource{A: TestAddr}),
+			}
+		case "example. CAA":
+			r.Answers = []dnsmessage.Resource{
+				rr("example.", typeCAA, &dnsmessage.UnknownResource{Type: typeCAA, Data: []byte("\x80\x05issueca.example")}),
+				rr("example.", typeCAA, &dnsmessage.UnknownResource{Type: typeCAA, Data: []byte("\x00")}),
+			}
+		case "_443._tcp.www.example. TLSA":
+			r.Answers = []dnsmessage.Resource{
+				rr("_443._tcp.www.example.", typeTLSA, &dnsmessage.UnknownResource{Type: typeTLSA, Data: []byte{3, 1, 1, 0xab, 0xcd}}),
+			}
+		case "example. SOA":
+			r.Answers = []dnsmessage.Resource{
+				rr("example.", dnsmessage.TypeSOA, &dnsmessage.SOAResource{
+					NS:     mustNewName("ns.example."),
+					MBox:   mustNewName("hostmaster.example."),
+					Serial: 2026,
+					MinTTL: 60,
+				}),
+			}
+		default:
+			r.RCode = dnsmessage.RCodeNameError
+		}
+		return r, nil
+	}}
+}
+
+func TestLookupRecords(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+	if err := conf.writeAndUpdate([]string{"nameserver 127.0.0.1", "search example", "options trust-ad"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var queries atomic.Int32
+	r := &Resolver{Dial: lookupRecordsTestServer(&queries).DialContext}
+	ctx := context.Background()
+
+	resp, err := r.LookupRecords(ctx, "www", DNSTypeHTTPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "www.example." || resp.Server != "127.0.0.1:53" || !resp.AuthenticData {
+		t.Errorf("got response for %v from %v, AuthenticData %v; want www.example. from 127.0.0.1:53, true", resp.Name, resp.Server, resp.AuthenticData)
+	}
+	want := []DNSRecord{{
+		Name: "www.example.", Type: DNSTypeCNAME, Class: 1, TTL: 300 * time.Second, Value: "svc.example.",
+	}, {
+		Name: "svc.example.", Type: DNSTypeHTTPS, Class: 1, TTL: 300 * time.Second, Data: svcbTestData,
+		Value: &SVCB{Priority: 1, Target: ".", Params: []SVCParam{
+			{SVCParamALPN, svcbTestData[7:13]},
+			{SVCParamPort, svcbTestData[17:19]},
+			{SVCParamIPv4Hint, svcbTestData[23:27]},
+			{SVCParamECH, svcbTestData[31:]},
+		}},
+	}}
+	if !reflect.DeepEqual(resp.Answers, want) {
+		t.Errorf("Answers:\ngot  %+v\nwant %+v", resp.Answers, want)
+	}
+	if len(resp.Authorities) != 1 || resp.Authorities[0].Value != "ns.example." {
+		t.Errorf("Authorities = %+v, want NS ns.example.", resp.Authorities)
+	}
+	if len(resp.Additionals) != 1 || resp.Additionals[0].Value != netip.AddrFrom4(TestAddr) {
+		t.Errorf("Additionals = %+v, want A %v", resp.Additionals, TestAddr)
+	}
+
+	resp, err = r.LookupRecords(ctx, "example.", DNSTypeCAA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Answers) != 2 {
+		t.Fatalf("got %d CAA records, want 2", len(resp.Answers))
+	}
+	if got, want := resp.Answers[0].Value, (&CAA{Flags: 128, Tag: "issue", Value: "ca.example"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CAA record: got %+v, want %+v", got, want)
+	}
+	if got := resp.Answers[1]; got.Value != nil || string(got.Data) != "\x00" {
+		t.Errorf("malformed CAA record: got %+v, want Data only", got)
+	}
+
+	resp, err = r.LookupRecords(ctx, "_443._tcp.www.example.", DNSTypeTLSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.Answers[0].Value, (&TLSA{Usage: 3, Selector: 1, MatchingType: 1, Data: []byte{0xab, 0xcd}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("TLSA record: got %+v, want %+v", got, want)
+	}
+
+	if err := conf.writeAndUpdate([]string{"nameserver 127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = r.LookupRecords(ctx, "example.", DNSTypeSOA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AuthenticData {
+		t.Errorf("AuthenticData set without trust-ad")
+	}
+	if got, want := resp.Answers[0].Value, (&SOA{NS: "ns.example.", MBox: "hostmaster.example.", Serial: 2026, MinTTL: 60}); !reflect.DeepEqual(got, want) {
+		t.Errorf("SOA record: got %+v, want %+v", got, want)
+	}
+
+	_, err = r.LookupRecords(ctx, "example.", DNSTypeTXT)
+	var dnsErr *DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("lookup of missing records: got error %v, want not found", err)
+	}
+}
+
+func TestLookupRecordsCache(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	setDNSCacheNow(t, &now)
+
+	var queries atomic.Int32
+	r := &Resolver{Dial: lookupRecordsTestServer(&queries).DialContext, Cache: &DNSCache{}}
+	for i, want := range []time.Duration{300 * time.Second, 200 * time.Second} {
+		resp, err := r.LookupRecords(context.Background(), "www.example.", DNSTypeHTTPS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range resp.Answers {
+			if rec.TTL != want {
+				t.Errorf("lookup %d: %v record has TTL %v, want %v", i, rec.Type, rec.TTL, want)
+			}
+		}
+		now = now.Add(100 * time.Second)
+	}
+	if got := queries.Load(); got != 1 {
+		t.Errorf("sent %v queries, want 1", got)
+	}
+}
+
+func TestParseSVCB(t *testing.T) {
+	s, ok := parseSVCB(svcbTestData)
+	if !ok {
+		t.Fatal("parseSVCB failed")
+	}
+	if got, want := s.ALPN(), []string{"h2", "h3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ALPN() = %q, want %q", got, want)
+	}
+	if port, ok := s.Port(); port != 443 || !ok {
+		t.Errorf("Port() = %v, %v; want 443, true", port, ok)
+	}
+	if got, want := s.IPHints(), []netip.Addr{netip.MustParseAddr("192.0.2.1")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IPHints() = %v, want %v", got, want)
+	}
+	if got := s.ECHConfigList(); string(got) != "\x01\x02\x03" {
+		t.Errorf("ECHConfigList() = %x, want 010203", got)
+	}
+	if s.NoDefaultALPN() {
+		t.Errorf("NoDefaultALPN() = true, want false")
+	}
+
+	s, ok = parseSVCB([]byte("\x00\x00\x03svc\x07example\x00"))
+	if !ok || s.Priority != 0 || s.Target != "svc.example." || s.Params != nil {
+		t.Errorf("alias mode record: got %+v, %v", s, ok)
+	}
+
+	for _, b := range []string{
+		"\x00",
+		"\x00\x01",
+		"\x00\x01\x03svc",
+		"\x00\x01\xc0\x0c",
+		"\x00\x01\x00\x00\x01\x00",
+		"\x00\x01\x00\x00\x01\x00\x05\x02h2",
+		"\x00\x01\x00\x00\x03\x00\x02\x01\xbb\x00\x01\x00\x03\x02h2",
+		"\x00\x01\x00\x00\x03\x00\x02\x01\xbb\x00\x03\x00\x02\x01\xbb",
+	} {
+		if s, ok := parseSVCB([]byte(b)); ok {
+			t.Errorf("parseSVCB(%q) = %+v, want failure", b, s)
+		}
+	}
+}
+
+func TestDNSTypeString(t *testing.T) {
+	for typ, want := range map[DNSType]string{DNSTypeAAAA: "AAAA", DNSTypeHTTPS: "HTTPS", 99: "TYPE99"} {
+		if got := typ.String(); got != want {
+			t.Errorf("DNSType(%d).String() = %q, want %q", typ, got, want)
+		}
+	}
+}
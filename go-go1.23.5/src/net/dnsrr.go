@@ -0,0 +1,242 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// DNS resource records returned by Resolver.LookupRecords,
+// other than those also returned by the type-specific lookups.
+
+package net
+
+import (
+	"internal/itoa"
+	"net/netip"
+)
+
+// An SOA represents a single DNS SOA record.
+type SOA struct {
+	NS      string // primary name server
+	MBox    string // mailbox of the person responsible for the zone
+	Serial  uint32
+	Refresh uint32 // seconds
+	Retry   uint32 // seconds
+	Expire  uint32 // seconds
+	MinTTL  uint32 // seconds for which negative responses may be cached
+}
+
+// A CAA represents a single DNS CAA record (RFC 8659).
+type CAA struct {
+	Flags uint8 // 128 if the property is critical
+	Tag   string
+	Value string
+}
+
+func parseCAA(b []byte) (*CAA, bool) {
+	if len(b) < 2 || len(b) < 2+int(b[1]) || b[1] == 0 {
+		return nil, false
+	}
+	n := 2 + int(b[1])
+	return &CAA{Flags: b[0], Tag: string(b[2:n]), Value: string(b[n:])}, true
+}
+
+// A TLSA represents a single DNS TLSA record (RFC 6698), which
+// associates a certificate or public key with a TLS service.
+type TLSA struct {
+	Usage        uint8 // certificate usage, such as 3 for DANE-EE
+	Selector     uint8 // 0 for the full certificate, 1 for its public key
+	MatchingType uint8 // 0 if Data is the selected content, or 1 or 2 for its SHA-256 or SHA-512 hash
+	Data         []byte
+}
+
+func parseTLSA(b []byte) (*TLSA, bool) {
+	if len(b) < 3 {
+		return nil, false
+	}
+	return &TLSA{Usage: b[0], Selector: b[1], MatchingType: b[2], Data: b[3:]}, true
+}
+
+// An SVCParamKey is the key of a service parameter of an [SVCB] record.
+type SVCParamKey uint16
+
+// Service parameter keys (RFC 9460 Section 14.3.2).
+const (
+	SVCParamMandatory     SVCParamKey = 0
+	SVCParamALPN          SVCParamKey = 1
+	SVCParamNoDefaultALPN SVCParamKey = 2
+	SVCParamPort          SVCParamKey = 3
+	SVCParamIPv4Hint      SVCParamKey = 4
+	SVCParamECH           SVCParamKey = 5
+	SVCParamIPv6Hint      SVCParamKey = 6
+)
+
+var svcParamKeyNames = [...]string{
+	SVCParamMandatory:     "mandatory",
+	SVCParamALPN:          "alpn",
+	SVCParamNoDefaultALPN: "no-default-alpn",
+	SVCParamPort:          "port",
+	SVCParamIPv4Hint:      "ipv4hint",
+	SVCParamECH:           "ech",
+	SVCParamIPv6Hint:      "ipv6hint",
+}
+
+// String returns the presentation format name of k, such as "alpn",
+// or for keys without one, its generic form such as "key65".
+func (k SVCParamKey) String() string {
+	if int(k) < len(svcParamKeyNames) {
+		return svcParamKeyNames[k]
+	}
+	return "key" + itoa.Uitoa(uint(k))
+}
+
+// An SVCParam is a service parameter of an [SVCB] record.
+type SVCParam struct {
+	Key   SVCParamKey
+	Value []byte // in wire format
+}
+
+// An SVCB represents a single DNS SVCB or HTTPS record (RFC 9460),
+// which describes an alternative endpoint for a service.
+type SVCB struct {
+	// Priority is the priority of the endpoint, where lower values
+	// are preferred. Zero means that the record is in alias mode,
+	// and Target is another name for the service.
+	Priority uint16
+
+	// Target is the rooted name of the endpoint. In service mode,
+	// "." means the owner name of the record; in alias mode, it
+	// means that the service is not available.
+	Target string
+
+	// Params holds the service parameters, in increasing order of key.
+	Params []SVCParam
+}
+
+func parseSVCB(b []byte) (*SVCB, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	s := &SVCB{Priority: uint16(b[0])<<8 | uint16(b[1])}
+	target, n, ok := parseWireName(b[2:])
+	if !ok {
+		return nil, false
+	}
+	s.Target = target
+	b = b[2+n:]
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, false
+		}
+		key := SVCParamKey(uint16(b[0])<<8 | uint16(b[1]))
+		n := int(b[2])<<8 | int(b[3])
+		if len(b) < 4+n {
+			return nil, false
+		}
+		// Keys must be in strictly increasing order.
+		if len(s.Params) > 0 && key <= s.Params[len(s.Params)-1].Key {
+			return nil, false
+		}
+		s.Params = append(s.Params, SVCParam{Key: key, Value: b[4 : 4+n]})
+		b = b[4+n:]
+	}
+	return s, true
+}
+
+// parseWireName parses the uncompressed domain name at the start of b.
+// It returns the rooted name and its length in b.
+func parseWireName(b []byte) (name string, n int, ok bool) {
+	var buf []byte
+	for {
+		if n >= len(b) {
+			return "", 0, false
+		}
+		l := int(b[n])
+		n++
+		if l == 0 {
+			break
+		}
+		// Compression pointers and extended label types
+		// are not permitted.
+		if l > 63 || n+l > len(b) {
+			return "", 0, false
+		}
+		buf = append(buf, b[n:n+l]...)
+		buf = append(buf, '.')
+		n += l
+	}
+	if len(buf) == 0 {
+		return ".", n, true
+	}
+	if len(buf) > 254 {
+		return "", 0, false
+	}
+	return string(buf), n, true
+}
+
+// Param returns the value of the parameter of s with the given key,
+// and whether s has it.
+func (s *SVCB) Param(key SVCParamKey) ([]byte, bool) {
+	for _, p := range s.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ALPN returns the protocol identifiers of the alpn parameter of s,
+// such as "h2" or "h3", or nil if it has none or it is malformed.
+func (s *SVCB) ALPN() []string {
+	v, _ := s.Param(SVCParamALPN)
+	var ids []string
+	for len(v) > 0 {
+		n := int(v[0])
+		if n == 0 || len(v) < 1+n {
+			return nil
+		}
+		ids = append(ids, string(v[1:1+n]))
+		v = v[1+n:]
+	}
+	return ids
+}
+
+// NoDefaultALPN reports whether s has the no-default-alpn parameter,
+// meaning that the endpoint does not support the default protocol
+// of the service, such as "http/1.1" for HTTPS records.
+func (s *SVCB) NoDefaultALPN() bool {
+	_, ok := s.Param(SVCParamNoDefaultALPN)
+	return ok
+}
+
+// Port returns the value of the port parameter of s, and whether
+// it has a valid one.
+func (s *SVCB) Port() (uint16, bool) {
+	v, ok := s.Param(SVCParamPort)
+	if !ok || len(v) != 2 {
+		return 0, false
+	}
+	return uint16(v[0])<<8 | uint16(v[1]), true
+}
+
+// IPHints returns the addresses of the ipv4hint and ipv6hint
+// parameters of s, in that order. Malformed hints are ignored.
+func (s *SVCB) IPHints() []netip.Addr {
+	var addrs []netip.Addr
+	if v, _ := s.Param(SVCParamIPv4Hint); len(v)%4 == 0 {
+		for ; len(v) > 0; v = v[4:] {
+			addrs = append(addrs, netip.AddrFrom4([4]byte(v)))
+		}
+	}
+	if v, _ := s.Param(SVCParamIPv6Hint); len(v)%16 == 0 {
+		for ; len(v) > 0; v = v[16:] {
+			addrs = append(addrs, netip.AddrFrom16([16]byte(v)))
+		}
+	}
+	return addrs
+}
+
+// ECHConfigList returns the value of the ech parameter of s, the
+// ECHConfigList with which to offer TLS Encrypted Client Hello to
+// the endpoint, or nil if it has none.
+func (s *SVCB) ECHConfigList() []byte {
+	v, _ := s.Param(SVCParamECH)
+	return v
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"log/slog"
+)
+
+// AccessLogger returns a function, suitable for use as [Server.RequestDone],
+// which logs each request to logger at [slog.LevelInfo].
+// If logger is nil, [slog.Default] is used.
+//
+// Each record has the message "http request" and the attributes
+// "method", "uri", "proto", "remote_addr", "status", "bytes",
+// and "duration". Requests whose connection was hijacked
+// also have the attribute "hijacked", and requests whose handler
+// panicked have the attribute "panicked".
+func AccessLogger(logger *slog.Logger) func(*Request, RequestStats) {
+	return func(r *Request, stats RequestStats) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("uri", r.RequestURI),
+			slog.String("proto", stats.Proto),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.Int("status", stats.StatusCode),
+			slog.Int64("bytes", stats.BytesWritten),
+			slog.Duration("duration", stats.Duration),
+		}
+		if stats.Hijacked {
+			attrs = append(attrs, slog.Bool("hijacked", true))
+		}
+		if stats.Panicked {
+			attrs = append(attrs, slog.Bool("panicked", true))
+		}
+		l.LogAttrs(r.Context(), slog.LevelInfo, "http request", attrs...)
+	}
+}
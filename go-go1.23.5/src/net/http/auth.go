@@ -0,0 +1,505 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP authentication challenges and responses. See RFC 9110 section 11.
+
+package http
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http/internal/ascii"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// A Challenge is an authentication challenge from a WWW-Authenticate
+// or Proxy-Authenticate header (RFC 9110 section 11.3).
+type Challenge struct {
+	// Scheme is the authentication scheme, such as "Basic" or "Digest".
+	// Schemes are case-insensitive.
+	Scheme string
+
+	// Token68 is the challenge's token68 value, for schemes
+	// which use one rather than parameters.
+	Token68 string
+
+	// Params are the challenge's parameters, with their names
+	// in lower case and quoted values unquoted.
+	Params map[string]string
+}
+
+var errMalformedChallenge = errors.New("http: malformed authentication challenge")
+
+// ParseChallenges parses the challenges in a WWW-Authenticate or
+// Proxy-Authenticate header value. A header value may contain several
+// challenges, separated by commas.
+func ParseChallenges(line string) ([]Challenge, error) {
+	var cs []Challenge
+	s := line
+	for {
+		s = skipListSeparators(s)
+		if s == "" {
+			return cs, nil
+		}
+		scheme, rest := cutToken(s)
+		if scheme == "" || (rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != ',') {
+			return nil, errMalformedChallenge
+		}
+		c := Challenge{Scheme: scheme}
+		s = trimLeftOWS(rest)
+		if tok, rest, ok := cutToken68(s); ok {
+			c.Token68 = tok
+			s = rest
+		} else {
+			for first := true; ; first = false {
+				t := s
+				if !first {
+					// Parameters are separated by commas. A token which
+					// is not followed by "=" begins the next challenge.
+					if t == "" || t[0] != ',' {
+						break
+					}
+					t = skipListSeparators(t)
+				}
+				name, value, rest, ok := cutAuthParam(t)
+				if !ok {
+					break
+				}
+				if c.Params == nil {
+					c.Params = make(map[string]string)
+				}
+				name, _ = ascii.ToLower(name)
+				c.Params[name] = value
+				s = trimLeftOWS(rest)
+			}
+		}
+		if s != "" && s[0] != ',' {
+			return nil, errMalformedChallenge
+		}
+		cs = append(cs, c)
+	}
+}
+
+func trimLeftOWS(s string) string {
+	return strings.TrimLeft(s, " \t")
+}
+
+// skipListSeparators removes leading whitespace and commas from s,
+// which skips empty list elements as RFC 9110 section 5.6.1 requires.
+func skipListSeparators(s string) string {
+	return strings.TrimLeft(s, " \t,")
+}
+
+// cutToken returns the token at the start of s, and the rest of s.
+func cutToken(s string) (tok, rest string) {
+	i := 0
+	for i < len(s) && httpguts.IsTokenRune(rune(s[i])) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// cutToken68 returns the token68 at the start of s, and the rest of s.
+// It reports false if s does not begin with a token68 which is the
+// whole of a challenge.
+func cutToken68(s string) (tok, rest string, ok bool) {
+	i := 0
+	for i < len(s) && isToken68Byte(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", s, false
+	}
+	for i < len(s) && s[i] == '=' {
+		i++
+	}
+	rest = trimLeftOWS(s[i:])
+	if rest != "" && rest[0] != ',' {
+		return "", s, false
+	}
+	return s[:i], rest, true
+}
+
+func isToken68Byte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		b == '-' || b == '.' || b == '_' || b == '~' || b == '+' || b == '/'
+}
+
+// cutAuthParam parses the auth-param at the start of s.
+func cutAuthParam(s string) (name, value, rest string, ok bool) {
+	name, rest = cutToken(s)
+	if name == "" {
+		return "", "", s, false
+	}
+	rest = trimLeftOWS(rest)
+	if rest == "" || rest[0] != '=' {
+		return "", "", s, false
+	}
+	rest = trimLeftOWS(rest[1:])
+	if rest != "" && rest[0] == '"' {
+		value, rest, ok = cutQuotedString(rest)
+		return name, value, rest, ok
+	}
+	value, rest = cutToken(rest)
+	return name, value, rest, value != ""
+}
+
+// cutQuotedString returns the unquoted value of the quoted-string
+// at the start of s, and the rest of s.
+func cutQuotedString(s string) (value, rest string, ok bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], true
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", s, false
+			}
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", s, false
+}
+
+// quoteString returns s as a quoted-string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// An Authenticator answers authentication challenges for a [Client].
+// See [Client.Auth] and [Client.ProxyAuth].
+//
+// An Authenticator must be safe for concurrent use by multiple goroutines.
+type Authenticator interface {
+	// Authenticate returns credentials with which to send req again,
+	// in answer to the challenges in a response to req. The credentials
+	// are used as the value of an Authorization or Proxy-Authorization
+	// header. Authenticate returns "" if it cannot answer any of the
+	// challenges.
+	//
+	// Authenticate must not modify req or read its Body. If it needs
+	// the request body, it may obtain a copy from req.GetBody.
+	Authenticate(req *Request, challenges []Challenge) (string, error)
+}
+
+// answerChallenges calls auth with the challenges in a response's
+// WWW-Authenticate or Proxy-Authenticate header values.
+// Malformed header values are ignored.
+func answerChallenges(auth Authenticator, req *Request, values []string) (string, error) {
+	var cs []Challenge
+	for _, v := range values {
+		c, err := ParseChallenges(v)
+		if err != nil {
+			continue
+		}
+		cs = append(cs, c...)
+	}
+	if len(cs) == 0 {
+		return "", nil
+	}
+	return auth.Authenticate(req, cs)
+}
+
+// BasicAuth is an [Authenticator] which answers challenges for the
+// Basic authentication scheme (RFC 7617) with a user name and password.
+//
+// Basic authentication sends the password in the clear,
+// so it should be used only with HTTPS.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements the [Authenticator] interface.
+func (a *BasicAuth) Authenticate(req *Request, challenges []Challenge) (string, error) {
+	for _, c := range challenges {
+		if ascii.EqualFold(c.Scheme, "Basic") {
+			return "Basic " + basicAuth(a.Username, a.Password), nil
+		}
+	}
+	return "", nil
+}
+
+// DigestAuth is an [Authenticator] which answers challenges for the
+// Digest authentication scheme (RFC 7616) with a user name and password.
+//
+// It supports the MD5, SHA-256 and SHA-512-256 algorithms and their
+// session variants, preferring the strongest algorithm offered.
+// It supports the "auth" and "auth-int" qualities of protection, and
+// chooses "auth-int", which also protects the request body, when it is
+// offered and the body is empty or can be obtained from [Request.GetBody].
+//
+// DigestAuth answers each challenge once, and does not reuse a
+// server's nonce for later requests, so each request which needs
+// authentication is sent twice.
+type DigestAuth struct {
+	Username string
+	Password string
+}
+
+// A digestAlgorithm is a Digest algorithm (RFC 7616 section 3.3).
+type digestAlgorithm struct {
+	newHash  func() hash.Hash
+	session  bool // the -sess variant
+	strength int
+}
+
+func parseDigestAlgorithm(name string) (digestAlgorithm, bool) {
+	var alg digestAlgorithm
+	if len(name) > len("-sess") && ascii.EqualFold(name[len(name)-len("-sess"):], "-sess") {
+		alg.session = true
+		name = name[:len(name)-len("-sess")]
+	}
+	switch {
+	case name == "" || ascii.EqualFold(name, "MD5"):
+		alg.newHash, alg.strength = md5.New, 1
+	case ascii.EqualFold(name, "SHA-256"):
+		alg.newHash, alg.strength = sha256.New, 2
+	case ascii.EqualFold(name, "SHA-512-256"):
+		alg.newHash, alg.strength = sha512.New512_256, 3
+	default:
+		return alg, false
+	}
+	return alg, true
+}
+
+// h returns the hex-encoded hash of the strings joined by colons.
+func (alg digestAlgorithm) h(s ...string) string {
+	h := alg.newHash()
+	io.WriteString(h, strings.Join(s, ":"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// digestCnonce returns a new client nonce. Tests replace it.
+var digestCnonce = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Authenticate implements the [Authenticator] interface.
+func (a *DigestAuth) Authenticate(req *Request, challenges []Challenge) (string, error) {
+	var c *Challenge
+	var alg digestAlgorithm
+	for i := range challenges {
+		ci := &challenges[i]
+		if !ascii.EqualFold(ci.Scheme, "Digest") || ci.Params["nonce"] == "" {
+			continue
+		}
+		if a, ok := parseDigestAlgorithm(ci.Params["algorithm"]); ok && (c == nil || a.strength > alg.strength) {
+			c, alg = ci, a
+		}
+	}
+	if c == nil {
+		return "", nil
+	}
+
+	qop := ""
+	if offered, ok := c.Params["qop"]; ok {
+		var auth, authInt bool
+		for _, q := range strings.Split(offered, ",") {
+			switch q = textproto.TrimString(q); {
+			case ascii.EqualFold(q, "auth"):
+				auth = true
+			case ascii.EqualFold(q, "auth-int"):
+				authInt = true
+			}
+		}
+		switch {
+		case authInt && (req.Body == nil || req.Body == NoBody || req.GetBody != nil):
+			qop = "auth-int"
+		case auth:
+			qop = "auth"
+		default:
+			return "", nil
+		}
+	}
+
+	realm, nonce := c.Params["realm"], c.Params["nonce"]
+	uri := req.URL.RequestURI()
+	cnonce := digestCnonce()
+	const nc = "00000001"
+
+	ha1 := alg.h(a.Username, realm, a.Password)
+	if alg.session {
+		ha1 = alg.h(ha1, nonce, cnonce)
+	}
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	ha2 := alg.h(method, uri)
+	if qop == "auth-int" {
+		bodyHash, err := alg.hashBody(req)
+		if err != nil {
+			return "", err
+		}
+		ha2 = alg.h(method, uri, bodyHash)
+	}
+	var response string
+	if qop == "" {
+		response = alg.h(ha1, nonce, ha2)
+	} else {
+		response = alg.h(ha1, nonce, nc, cnonce, qop, ha2)
+	}
+
+	var b strings.Builder
+	b.WriteString("Digest ")
+	userhash := ascii.EqualFold(c.Params["userhash"], "true")
+	switch {
+	case userhash:
+		b.WriteString("username=" + quoteString(alg.h(a.Username, realm)))
+	case needsExtValue(a.Username):
+		b.WriteString("username*=UTF-8''" + url.PathEscape(a.Username))
+	default:
+		b.WriteString("username=" + quoteString(a.Username))
+	}
+	b.WriteString(", realm=" + quoteString(realm))
+	b.WriteString(", uri=" + quoteString(uri))
+	if v, ok := c.Params["algorithm"]; ok {
+		b.WriteString(", algorithm=" + v)
+	}
+	b.WriteString(", nonce=" + quoteString(nonce))
+	if qop != "" {
+		b.WriteString(", nc=" + nc)
+		b.WriteString(", cnonce=" + quoteString(cnonce))
+		b.WriteString(", qop=" + qop)
+	}
+	b.WriteString(", response=" + quoteString(response))
+	if v, ok := c.Params["opaque"]; ok {
+		b.WriteString(", opaque=" + quoteString(v))
+	}
+	if userhash {
+		b.WriteString(", userhash=true")
+	}
+	return b.String(), nil
+}
+
+// hashBody returns the hex-encoded hash of req's body.
+func (alg digestAlgorithm) hashBody(req *Request) (string, error) {
+	h := alg.newHash()
+	if req.Body != nil && req.Body != NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// needsExtValue reports whether s cannot be sent as a quoted-string,
+// and must be sent with the username* parameter (RFC 7616 section 3.4.4).
+func needsExtValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '"' || c == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyAuthKey is the context key for a Client's ProxyAuth,
+// which a Transport uses to answer challenges to CONNECT requests.
+type proxyAuthKey struct{}
+
+// sendAuth sends req using c, answering authentication challenges in
+// the responses. It answers a 401 response's challenges only if trusted
+// is true. Like Client.send, it always closes req.Body.
+func (c *Client) sendAuth(ireq *Request, deadline time.Time, trusted bool) (resp *Response, didTimeout func() bool, err error) {
+	req := ireq
+	if c.ProxyAuth != nil {
+		req = ireq.WithContext(context.WithValue(ireq.Context(), proxyAuthKey{}, c.ProxyAuth))
+	}
+	// Sending a request adds the Jar's cookies to its header, so each
+	// answer starts from a copy of the original header and the
+	// credentials sent so far.
+	header := ireq.Header.Clone()
+	if header == nil {
+		header = make(Header)
+	}
+	var answered401, answered407 bool
+	for {
+		resp, didTimeout, err = c.sendRetry(req, deadline)
+		if err != nil {
+			return resp, didTimeout, err
+		}
+		var auth Authenticator
+		var challengeKey, credentialsKey string
+		switch {
+		case resp.StatusCode == StatusUnauthorized && trusted && !answered401:
+			auth, challengeKey, credentialsKey = c.Auth, "Www-Authenticate", "Authorization"
+			answered401 = true
+		case resp.StatusCode == StatusProxyAuthRequired && !answered407:
+			auth, challengeKey, credentialsKey = c.ProxyAuth, "Proxy-Authenticate", "Proxy-Authorization"
+			answered407 = true
+		}
+		if auth == nil {
+			return resp, nil, nil
+		}
+		if ireq.Body != nil && ireq.Body != NoBody && ireq.GetBody == nil {
+			return resp, nil, nil
+		}
+
+		next := new(Request)
+		*next = *req
+		next.Header = header.Clone()
+		credentials, err := answerChallenges(auth, next, resp.Header[challengeKey])
+		if err != nil {
+			resp.closeBody()
+			return nil, alwaysFalse, err
+		}
+		if credentials == "" {
+			return resp, nil, nil
+		}
+		header.Set(credentialsKey, credentials)
+		next.Header.Set(credentialsKey, credentials)
+		if ireq.Body != nil && ireq.Body != NoBody {
+			body, err := ireq.GetBody()
+			if err != nil {
+				resp.closeBody()
+				return nil, alwaysFalse, err
+			}
+			next.Body = body
+		}
+
+		// Read some of the body, as in Client.do, so a small
+		// response doesn't prevent the connection's reuse.
+		const maxBodySlurpSize = 2 << 10
+		if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
+			io.CopyN(io.Discard, resp.Body, maxBodySlurpSize)
+		}
+		resp.Body.Close()
+		req = next
+	}
+}
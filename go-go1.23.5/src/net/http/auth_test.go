@@ -0,0 +1,416 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	. "net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseChallenges(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want []Challenge
+	}{
+		{`Basic realm="example"`, []Challenge{
+			{Scheme: "Basic", Params: map[string]string{"realm": "example"}},
+		}},
+		{`Negotiate`, []Challenge{{Scheme: "Negotiate"}}},
+		{`Negotiate a87421000492aa874209af8bc028==`, []Challenge{
+			{Scheme: "Negotiate", Token68: "a87421000492aa874209af8bc028=="},
+		}},
+		{`Digest Realm="a \"b\" c", NONCE=xyz,qop="auth,auth-int" , algorithm=SHA-256`, []Challenge{
+			{Scheme: "Digest", Params: map[string]string{
+				"realm":     `a "b" c`,
+				"nonce":     "xyz",
+				"qop":       "auth,auth-int",
+				"algorithm": "SHA-256",
+			}},
+		}},
+		{`Newauth realm="apps", type=1, title="Login to \"apps\"", Basic realm="simple"`, []Challenge{
+			{Scheme: "Newauth", Params: map[string]string{"realm": "apps", "type": "1", "title": `Login to "apps"`}},
+			{Scheme: "Basic", Params: map[string]string{"realm": "simple"}},
+		}},
+		{`Bearer, , Basic abc=, Digest realm=x`, []Challenge{
+			{Scheme: "Bearer"},
+			{Scheme: "Basic", Token68: "abc="},
+			{Scheme: "Digest", Params: map[string]string{"realm": "x"}},
+		}},
+		{``, nil},
+		{`Basic realm="unterminated`, nil},
+		{`Basic a=b=c`, nil},
+		{`Basic abc def`, nil},
+		{`Basic "realm"`, nil},
+		{`"Basic"`, nil},
+	} {
+		got, err := ParseChallenges(test.line)
+		if test.want == nil && test.line != "" {
+			if err == nil {
+				t.Errorf("ParseChallenges(%q) = %+v, want error", test.line, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseChallenges(%q): %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseChallenges(%q):\ngot  %+v\nwant %+v", test.line, got, test.want)
+		}
+	}
+}
+
+// parseDigestCredentials returns the parameters of Digest credentials.
+func parseDigestCredentials(s string) map[string]string {
+	c, err := ParseChallenges(s)
+	if err != nil || len(c) != 1 || c[0].Scheme != "Digest" {
+		return nil
+	}
+	return c[0].Params
+}
+
+func TestDigestAuthRFC7616(t *testing.T) {
+	// The examples in RFC 7616 section 3.9.1.
+	SetDigestCnonceForTesting(t, "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ")
+	a := &DigestAuth{Username: "Mufasa", Password: "Circle of Life"}
+	req, _ := NewRequest("GET", "http://www.example.org/dir/index.html", nil)
+	challenges, err := ParseChallenges(`Digest realm="http-auth@example.org", qop="auth, auth-int", ` +
+		`algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+		`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS", ` +
+		`Digest realm="http-auth@example.org", qop="auth, auth-int", ` +
+		`algorithm=MD5, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+		`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Remove auth-int, which the RFC's examples don't use.
+	for _, c := range challenges {
+		c.Params["qop"] = "auth"
+	}
+	for _, test := range []struct {
+		challenges []Challenge
+		want       string
+	}{
+		{challenges, "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+		{challenges[1:], "8ca523f5e9506fed4657c9700eebdbec"},
+	} {
+		got, err := a.Authenticate(req, test.challenges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := parseDigestCredentials(got)
+		if params["response"] != test.want {
+			t.Errorf("got credentials %s\nwant response %q", got, test.want)
+		}
+		if params["username"] != "Mufasa" || params["uri"] != "/dir/index.html" ||
+			params["nc"] != "00000001" || params["qop"] != "auth" ||
+			params["opaque"] != "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS" {
+			t.Errorf("got credentials %s", got)
+		}
+	}
+
+	// Challenges for other schemes or unknown algorithms are ignored.
+	for _, line := range []string{
+		`Basic realm="x"`,
+		`Digest realm="x", nonce="n", algorithm=SHA-1`,
+		`Digest realm="x"`,
+	} {
+		challenges, _ := ParseChallenges(line)
+		if got, err := a.Authenticate(req, challenges); got != "" || err != nil {
+			t.Errorf("Authenticate(%q) = %q, %v; want no credentials", line, got, err)
+		}
+	}
+}
+
+func TestDigestAuthUsername(t *testing.T) {
+	req, _ := NewRequest("GET", "http://example.com/", nil)
+	for _, test := range []struct {
+		username, challenge, want string
+	}{
+		{"Jäsøn Doe", `Digest realm="r", nonce="n"`, `username*=UTF-8''J%C3%A4s%C3%B8n%20Doe`},
+		{`a"b`, `Digest realm="r", nonce="n"`, `username*=UTF-8''a%22b`},
+		{"user", `Digest realm="r", nonce="n", userhash=true`,
+			fmt.Sprintf(`username="%x"`, md5.Sum([]byte("user:r")))},
+	} {
+		challenges, _ := ParseChallenges(test.challenge)
+		got, err := (&DigestAuth{Username: test.username}).Authenticate(req, challenges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "Digest "+test.want+", ") {
+			t.Errorf("username %q: got credentials %s, want %s", test.username, got, test.want)
+		}
+	}
+}
+
+// digestServer returns a handler which requires Digest authentication
+// of user with password, using SHA-256 and the given qop, and which
+// responds with the request body.
+func digestServer(t *testing.T, user, password, qop string) Handler {
+	const realm, nonce = "test", "abc123"
+	h := func(s ...string) string {
+		var hf hash.Hash = sha256.New()
+		io.WriteString(hf, strings.Join(s, ":"))
+		return hex.EncodeToString(hf.Sum(nil))
+	}
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		p := parseDigestCredentials(r.Header.Get("Authorization"))
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.Header().Add("WWW-Authenticate", fmt.Sprintf(
+				`Digest realm=%q, nonce=%q, algorithm=SHA-256, qop=%q, opaque="o"`, realm, nonce, qop))
+			w.WriteHeader(StatusUnauthorized)
+			io.WriteString(w, "unauthorized")
+			return
+		}
+		ha2 := h(r.Method, p["uri"])
+		if p["qop"] == "auth-int" {
+			ha2 = h(r.Method, p["uri"], h(string(body)))
+		}
+		want := h(h(user, realm, password), nonce, p["nc"], p["cnonce"], p["qop"], ha2)
+		if p["username"] != user || p["uri"] != r.URL.RequestURI() || p["qop"] != qop ||
+			p["algorithm"] != "SHA-256" || p["opaque"] != "o" || p["response"] != want {
+			t.Errorf("server got bad credentials %s", r.Header.Get("Authorization"))
+			w.WriteHeader(StatusForbidden)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func TestClientDigestAuth(t *testing.T) { run(t, testClientDigestAuth) }
+func testClientDigestAuth(t *testing.T, mode testMode) {
+	for _, qop := range []string{"auth", "auth-int"} {
+		cst := newClientServerTest(t, mode, digestServer(t, "user", "secret", qop))
+		c := cst.c
+		c.Auth = &DigestAuth{Username: "user", Password: "secret"}
+
+		req, _ := NewRequest("POST", cst.ts.URL+"/path?q=1", strings.NewReader("request body"))
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != 200 || string(body) != "request body" {
+			t.Errorf("qop=%v: got %v %q, want 200 %q", qop, res.StatusCode, body, "request body")
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("qop=%v: Client.Do modified the request", qop)
+		}
+
+		// A request whose body cannot be obtained again is not resent.
+		req, _ = NewRequest("POST", cst.ts.URL, io.NopCloser(strings.NewReader("x")))
+		res, err = c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != StatusUnauthorized {
+			t.Errorf("qop=%v, body without GetBody: got status %v, want 401", qop, res.StatusCode)
+		}
+	}
+}
+
+func TestClientAuthWrongPassword(t *testing.T) { run(t, testClientAuthWrongPassword) }
+func testClientAuthWrongPassword(t *testing.T, mode testMode) {
+	var requests atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		requests.Add(1)
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+		w.WriteHeader(StatusUnauthorized)
+	}))
+	c := cst.c
+	c.Auth = &BasicAuth{Username: "user", Password: "wrong"}
+	res, err := c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusUnauthorized || requests.Load() != 2 {
+		t.Errorf("got status %v after %v requests, want 401 after 2", res.StatusCode, requests.Load())
+	}
+	if got, want := res.Request.Header.Get("Authorization"), "Basic dXNlcjp3cm9uZw=="; got != want {
+		t.Errorf("last request had Authorization %q, want %q", got, want)
+	}
+}
+
+func TestClientAuthJar(t *testing.T) { run(t, testClientAuthJar) }
+func testClientAuthJar(t *testing.T, mode testMode) {
+	var cookies []string
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(StatusUnauthorized)
+		}
+	}))
+	c := cst.c
+	c.Auth = &BasicAuth{Username: "user", Password: "secret"}
+	c.Jar, _ = cookiejar.New(nil)
+	u, _ := url.Parse(cst.ts.URL)
+	c.Jar.SetCookies(u, []*Cookie{{Name: "a", Value: "1"}})
+	res, err := c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("got status %v, want 200", res.StatusCode)
+	}
+	if want := []string{"a=1", "a=1"}; !reflect.DeepEqual(cookies, want) {
+		t.Errorf("server got cookies %q, want %q", cookies, want)
+	}
+}
+
+// proxyAuthHandler returns a handler for a proxy which requires Basic
+// authentication, and calls h once a request is authenticated.
+func proxyAuthHandler(t *testing.T, h Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if got, want := r.Header.Get("Proxy-Authorization"), "Basic dXNlcjpzZWNyZXQ="; got != want {
+			if got != "" {
+				t.Errorf("proxy got Proxy-Authorization %q, want %q", got, want)
+			}
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(StatusProxyAuthRequired)
+			io.WriteString(w, "proxy authentication required")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func TestClientProxyAuth(t *testing.T) {
+	proxy := httptest.NewServer(proxyAuthHandler(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		fmt.Fprintf(w, "proxied %s", r.URL)
+	})))
+	defer proxy.Close()
+	pu, _ := url.Parse(proxy.URL)
+	c := &Client{
+		Transport: &Transport{Proxy: ProxyURL(pu)},
+		ProxyAuth: &BasicAuth{Username: "user", Password: "secret"},
+	}
+	defer c.CloseIdleConnections()
+	res, err := c.Get("http://example.test/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "proxied http://example.test/x" {
+		t.Errorf("got %v %q", res.Status, body)
+	}
+}
+
+func TestTransportProxyConnectAuth(t *testing.T) {
+	backend := httptest.NewTLSServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer backend.Close()
+
+	var connects atomic.Int32
+	ln := newLocalListener(t)
+	defer ln.Close()
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		for {
+			req, err := ReadRequest(br)
+			if err != nil {
+				return
+			}
+			connects.Add(1)
+			if req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpzZWNyZXQ=" {
+				io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n"+
+					"Proxy-Authenticate: Basic realm=\"proxy\"\r\n"+
+					"Content-Length: 6\r\n\r\ndenied")
+				continue
+			}
+			target, err := net.Dial("tcp", req.URL.Host)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer target.Close()
+			io.WriteString(conn, "HTTP/1.1 200 OK\r\n\r\n")
+			go io.Copy(target, br)
+			io.Copy(conn, target)
+			return
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	tr := backend.Client().Transport.(*Transport).Clone()
+	tr.Proxy = func(*Request) (*url.URL, error) {
+		return url.Parse("http://" + ln.Addr().String())
+	}
+	defer tr.CloseIdleConnections()
+
+	// Without ProxyAuth, the proxy's challenge is an error.
+	if _, err := (&Client{Transport: tr}).Get(backend.URL); err == nil ||
+		!strings.Contains(err.Error(), "Proxy Authentication Required") {
+		t.Fatalf("without ProxyAuth: got error %v, want Proxy Authentication Required", err)
+	}
+
+	c := &Client{
+		Transport: tr,
+		ProxyAuth: &BasicAuth{Username: "user", Password: "secret"},
+	}
+	res, err := c.Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("got body %q, want %q", body, "hello")
+	}
+	// One CONNECT without credentials, then the CONNECT with and without
+	// credentials on the same connection.
+	if n := connects.Load(); n != 3 {
+		t.Errorf("proxy got %v CONNECT requests, want 3", n)
+	}
+
+	// The authenticated tunnel is not reused by a Client
+	// without ProxyAuth sharing the Transport.
+	if _, err := (&Client{Transport: tr}).Get(backend.URL); err == nil ||
+		!strings.Contains(err.Error(), "Proxy Authentication Required") {
+		t.Errorf("without ProxyAuth after authenticated tunnel: got error %v, want Proxy Authentication Required", err)
+	}
+	// It is reused by another Client with the same ProxyAuth.
+	connects.Store(0)
+	res, err = (&Client{Transport: tr, ProxyAuth: c.ProxyAuth}).Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if n := connects.Load(); n != 0 {
+		t.Errorf("proxy got %v CONNECT requests with the same ProxyAuth, want 0", n)
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Request binding: decoding request bodies into structs.
+
+package http
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Binder decodes request bodies into Go values, enforcing a size limit
+// and selecting a decoder by the request's Content-Type.
+//
+// The zero Binder is ready to use, and is the Binder used by [Bind].
+type Binder struct {
+	// MaxBytes is the maximum size of a request body.
+	// If zero, a default of 10 MB is used.
+	// If negative, the size is not limited.
+	MaxBytes int64
+
+	// MaxMemory is the maximum number of bytes of a multipart body's
+	// file parts which are stored in memory; the rest are stored in
+	// temporary files on disk. See [Request.ParseMultipartForm].
+	// If zero, a default of 32 MB is used.
+	MaxMemory int64
+
+	// AllowUnknownFields causes JSON objects with members that do not
+	// match any field of the destination to be accepted.
+	// By default, they are rejected.
+	AllowUnknownFields bool
+}
+
+// Bind decodes the body of r into v, using a zero [Binder].
+func Bind(w ResponseWriter, r *Request, v any) error {
+	var b Binder
+	return b.Bind(w, r, v)
+}
+
+// A BindError is an error decoding a request with [Binder.Bind].
+type BindError struct {
+	// StatusCode is the status with which the error should be
+	// reported to the client: 400 (Bad Request), 413 (Content Too
+	// Large) or 415 (Unsupported Media Type).
+	StatusCode int
+
+	// Field is the name of the field whose value could not be
+	// decoded, if known.
+	Field string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *BindError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("http: binding field %q: %v", e.Field, e.Err)
+	}
+	return "http: binding request: " + e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error { return e.Err }
+
+// Problem returns a [Problem] describing the error, for reporting
+// it to the client.
+func (e *BindError) Problem() *Problem {
+	p := &Problem{Status: e.StatusCode, Detail: e.Err.Error()}
+	if e.Field != "" {
+		p.Extensions = map[string]any{"field": e.Field}
+	}
+	return p
+}
+
+// Bind decodes the body of r into v, which must be a non-nil pointer.
+//
+// The decoder is chosen by the request's Content-Type:
+//
+//   - "application/json", and other media types with a "+json" suffix,
+//     are decoded with [encoding/json]. The body must contain a single
+//     JSON value. Unless AllowUnknownFields is set, members of objects
+//     which do not match a field of the destination are rejected.
+//   - "application/x-www-form-urlencoded" and "multipart/form-data"
+//     bodies are decoded into v, which must point to a struct, as
+//     described below.
+//
+// If r has no body, its URL query parameters are decoded into v as form
+// values, so that a handler may bind GET requests and POST requests
+// with the same struct.
+//
+// Form values are decoded into the exported fields of a struct, named
+// by the field's "form" tag, or else its "json" tag, or else the field's
+// name. A tag of "-" causes the field to be skipped. Fields of embedded
+// structs are decoded as if they were fields of the outer struct.
+// Fields may be of string, bool, integer, floating-point, time.Duration
+// or time.Time (in RFC 3339 format) type, or implement
+// [encoding.TextUnmarshaler], or be pointers to or slices of those types.
+// Fields of type *multipart.FileHeader or []*multipart.FileHeader
+// receive the files of a multipart body.
+//
+// Bind returns a [*BindError] for a request which cannot be decoded.
+// Its StatusCode is 413 (Content Too Large) if the body is larger than
+// MaxBytes, 415 (Unsupported Media Type) if its Content-Type is not one
+// of those above, and 400 (Bad Request) otherwise. A *BindError's
+// Problem method describes it as a [Problem], which may be written
+// with [WriteProblem].
+func (b *Binder) Bind(w ResponseWriter, r *Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("http: Bind of non-pointer %T", v)
+	}
+	if r.ContentLength == 0 {
+		return bindForm(rv.Elem(), r.URL.Query(), nil)
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return &BindError{StatusCode: StatusUnsupportedMediaType, Err: errors.New("missing Content-Type")}
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return &BindError{StatusCode: StatusUnsupportedMediaType, Err: err}
+	}
+	if max := b.maxBytes(); max >= 0 {
+		r.Body = MaxBytesReader(w, r.Body, max)
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return b.bindJSON(r.Body, v)
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return bodyError(err)
+		}
+		return bindForm(rv.Elem(), r.PostForm, nil)
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(b.maxMemory()); err != nil {
+			return bodyError(err)
+		}
+		return bindForm(rv.Elem(), r.MultipartForm.Value, r.MultipartForm.File)
+	}
+	return &BindError{StatusCode: StatusUnsupportedMediaType, Err: fmt.Errorf("unsupported Content-Type %q", mediaType)}
+}
+
+func (b *Binder) maxBytes() int64 {
+	if b.MaxBytes != 0 {
+		return b.MaxBytes
+	}
+	return 10 << 20
+}
+
+func (b *Binder) maxMemory() int64 {
+	if b.MaxMemory > 0 {
+		return b.MaxMemory
+	}
+	return defaultMaxMemory
+}
+
+// bodyError returns a BindError for an error reading or parsing a body.
+func bodyError(err error) *BindError {
+	var mbe *MaxBytesError
+	if errors.As(err, &mbe) {
+		return &BindError{StatusCode: StatusRequestEntityTooLarge, Err: err}
+	}
+	return &BindError{StatusCode: StatusBadRequest, Err: err}
+}
+
+func (b *Binder) bindJSON(body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	if !b.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		if err == io.EOF {
+			err = errors.New("empty JSON body")
+		}
+		be := bodyError(err)
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			be.Field = te.Field
+		}
+		return be
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after JSON value")
+		}
+		return bodyError(err)
+	}
+	return nil
+}
+
+var (
+	fileHeaderType      = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeaderSliceType = reflect.TypeFor[[]*multipart.FileHeader]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// bindForm decodes form values and files into the struct v.
+func bindForm(v reflect.Value, values url.Values, files map[string][]*multipart.FileHeader) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("http: Bind of form values into %v, not a struct", v.Type())
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := bindForm(v.Field(i), values, files); err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name := formFieldName(sf)
+		if name == "" {
+			continue
+		}
+		f := v.Field(i)
+		switch sf.Type {
+		case fileHeaderType:
+			if fhs := files[name]; len(fhs) > 0 {
+				f.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		case fileHeaderSliceType:
+			if fhs := files[name]; len(fhs) > 0 {
+				f.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+		if f.Kind() == reflect.Slice && !f.Addr().Type().Implements(textUnmarshalerType) {
+			s := reflect.MakeSlice(f.Type(), len(vs), len(vs))
+			for j, s1 := range vs {
+				if err := setFormValue(s.Index(j), s1); err != nil {
+					return formFieldError(name, err)
+				}
+			}
+			f.Set(s)
+			continue
+		}
+		if err := setFormValue(f, vs[0]); err != nil {
+			return formFieldError(name, err)
+		}
+	}
+	return nil
+}
+
+// formFieldName returns the form value name of a struct field,
+// or "" if the field is skipped.
+func formFieldName(sf reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		tag, ok := sf.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// setFormValue sets v from the form value s.
+func setFormValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setFormValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.CanAddr() {
+		if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return tu.UnmarshalText([]byte(s))
+		}
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return unsupportedFieldError{v.Type()}
+	}
+	return nil
+}
+
+// An unsupportedFieldError reports a field whose type Bind cannot
+// decode form values into. It is an error in the server, not the request.
+type unsupportedFieldError struct {
+	t reflect.Type
+}
+
+func (e unsupportedFieldError) Error() string {
+	return fmt.Sprintf("http: Bind of form value into unsupported type %v", e.t)
+}
+
+// formFieldError returns the error for a form value which cannot be
+// decoded into the named field.
+func formFieldError(name string, err error) error {
+	if _, ok := err.(unsupportedFieldError); ok {
+		return err
+	}
+	return &BindError{StatusCode: StatusBadRequest, Field: name, Err: err}
+}
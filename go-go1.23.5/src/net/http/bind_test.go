@@ -0,0 +1,195 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	. "net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindEmbedded struct {
+	Page int `form:"page"`
+}
+
+type bindTarget struct {
+	bindEmbedded
+	Name     string        `json:"name"`
+	Age      int           `json:"age,omitempty"`
+	Tags     []string      `form:"tag" json:"tags"`
+	Admin    *bool         `json:"admin"`
+	Timeout  time.Duration `json:"timeout"`
+	When     time.Time     `json:"when"`
+	Addr     netip.Addr    `json:"addr"`
+	Ratio    float64       `json:"ratio"`
+	Skipped  string        `form:"-" json:"-"`
+	internal string
+}
+
+func TestBind(t *testing.T) {
+	yes := true
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, test := range []struct {
+		name        string
+		method, url string
+		contentType string
+		body        string
+		want        bindTarget
+	}{{
+		name:        "JSON",
+		method:      "POST",
+		url:         "/",
+		contentType: "application/json; charset=utf-8",
+		body:        `{"name": "gopher", "age": 14, "tags": ["a", "b"], "admin": true, "when": "2026-01-02T03:04:05Z", "addr": "10.0.0.1", "Page": 3}`,
+		want: bindTarget{
+			bindEmbedded: bindEmbedded{Page: 3},
+			Name:         "gopher", Age: 14, Tags: []string{"a", "b"}, Admin: &yes, When: when,
+			Addr: netip.MustParseAddr("10.0.0.1"),
+		},
+	}, {
+		name:        "JSON suffix",
+		method:      "PUT",
+		url:         "/",
+		contentType: "application/merge-patch+json",
+		body:        `{"name": "gopher"}`,
+		want:        bindTarget{Name: "gopher"},
+	}, {
+		name:        "form",
+		method:      "POST",
+		url:         "/?name=ignored",
+		contentType: "application/x-www-form-urlencoded",
+		body:        "name=gopher&age=14&tag=a&tag=b&admin=true&timeout=1m30s&when=2026-01-02T03:04:05Z&addr=10.0.0.1&ratio=0.5&page=2&Skipped=x&internal=x",
+		want: bindTarget{
+			bindEmbedded: bindEmbedded{Page: 2},
+			Name:         "gopher", Age: 14, Tags: []string{"a", "b"}, Admin: &yes, Timeout: 90 * time.Second,
+			When: when, Addr: netip.MustParseAddr("10.0.0.1"), Ratio: 0.5,
+		},
+	}, {
+		name:   "query",
+		method: "GET",
+		url:    "/?name=gopher&tag=x&page=7",
+		want:   bindTarget{bindEmbedded: bindEmbedded{Page: 7}, Name: "gopher", Tags: []string{"x"}},
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader
+			if test.body != "" {
+				body = strings.NewReader(test.body)
+			}
+			req := httptest.NewRequest(test.method, test.url, body)
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			var got bindTarget
+			if err := Bind(httptest.NewRecorder(), req, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got  %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "gopher")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, _ := mw.CreateFormFile("files", name)
+		io.WriteString(fw, "contents of "+name)
+	}
+	mw.Close()
+	req := httptest.NewRequest("POST", "/?name=ignored", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var got struct {
+		Name  string                  `form:"name"`
+		First *multipart.FileHeader   `form:"files"`
+		Files []*multipart.FileHeader `form:"files"`
+	}
+	if err := Bind(httptest.NewRecorder(), req, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "gopher" || got.First == nil || got.First.Filename != "a.txt" || len(got.Files) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	f, err := got.Files[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if b, _ := io.ReadAll(f); string(b) != "contents of b.txt" {
+		t.Errorf("got file contents %q", b)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        string
+		binder      Binder
+		wantStatus  int
+		wantField   string
+	}{
+		{"no content type", "", `{}`, Binder{}, 415, ""},
+		{"unsupported", "text/plain", `hello`, Binder{}, 415, ""},
+		{"bad media type", "application/", `{}`, Binder{}, 415, ""},
+		{"syntax", "application/json", `{"name": `, Binder{}, 400, ""},
+		{"type", "application/json", `{"age": "old"}`, Binder{}, 400, "age"},
+		{"unknown field", "application/json", `{"color": "blue"}`, Binder{}, 400, ""},
+		{"trailing data", "application/json", `{} {}`, Binder{}, 400, ""},
+		{"too large", "application/json", `{"name": "` + strings.Repeat("x", 100) + `"}`, Binder{MaxBytes: 50}, 413, ""},
+		{"form too large", "application/x-www-form-urlencoded", "name=" + strings.Repeat("x", 100), Binder{MaxBytes: 50}, 413, ""},
+		{"form value", "application/x-www-form-urlencoded", "age=old", Binder{}, 400, "age"},
+		{"form slice value", "application/x-www-form-urlencoded", "admin=maybe", Binder{}, 400, "admin"},
+		{"bad multipart", "multipart/form-data; boundary=x", "--y", Binder{}, 400, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			var v bindTarget
+			err := test.binder.Bind(httptest.NewRecorder(), req, &v)
+			var be *BindError
+			if !errors.As(err, &be) {
+				t.Fatalf("got error %v, want *BindError", err)
+			}
+			if be.StatusCode != test.wantStatus || be.Field != test.wantField {
+				t.Errorf("got status %v, field %q (%v); want %v, %q", be.StatusCode, be.Field, err, test.wantStatus, test.wantField)
+			}
+			if p := ProblemFor(err); p.Status != test.wantStatus || p.Detail == "" {
+				t.Errorf("ProblemFor(%v) = %+v", err, p)
+			}
+		})
+	}
+
+	// Unknown fields may be allowed.
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"color": "blue", "name": "x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	var v bindTarget
+	if err := (&Binder{AllowUnknownFields: true}).Bind(httptest.NewRecorder(), req, &v); err != nil || v.Name != "x" {
+		t.Errorf("AllowUnknownFields: got %+v, %v", v, err)
+	}
+
+	// Errors in the destination are not errors in the request.
+	req = httptest.NewRequest("GET", "/?C=1", nil)
+	var bad struct{ C chan int }
+	if err := Bind(httptest.NewRecorder(), req, &bad); err == nil || errors.As(err, new(*BindError)) {
+		t.Errorf("binding into unsupported type: got error %v, want non-BindError", err)
+	}
+	if err := Bind(httptest.NewRecorder(), req, v); err == nil || errors.As(err, new(*BindError)) {
+		t.Errorf("binding into non-pointer: got error %v, want non-BindError", err)
+	}
+}
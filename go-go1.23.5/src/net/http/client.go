@@ -103,6 +103,40 @@ type Client struct {
 	// RoundTripper implementations should use the Request's Context
 	// for cancellation instead of implementing CancelRequest.
 	Timeout time.Duration
+
+	// Retry specifies the policy for retrying requests which fail
+	// or receive a response asking the client to try again later.
+	// If Retry is nil, requests are not retried.
+	Retry *RetryPolicy
+
+	// Auth answers authentication challenges from servers.
+	// If a server responds to a request with 401 Unauthorized and
+	// Auth returns credentials for the challenges in the response's
+	// WWW-Authenticate headers, the Client sends the request again
+	// with the credentials in its Authorization header, once.
+	// Challenges are answered only for the host of the initial
+	// request, and hosts to which it would forward sensitive headers
+	// when following redirects.
+	//
+	// A request with a body is sent again only if its GetBody
+	// function is defined.
+	//
+	// If Auth is nil, challenges from servers are not answered.
+	Auth Authenticator
+
+	// ProxyAuth answers authentication challenges from proxies, as
+	// Auth does for servers. It is used when a proxy responds with
+	// 407 Proxy Authentication Required, and its credentials are sent
+	// in the Proxy-Authorization header. When the Client's Transport
+	// is a [*Transport], ProxyAuth also answers challenges to the
+	// CONNECT requests it sends to establish tunnels through proxies.
+	// A tunnel is reused only by requests with the same ProxyAuth,
+	// which should be comparable, such as a pointer: tunnels
+	// established with an Authenticator which is not comparable
+	// are not reused.
+	//
+	// If ProxyAuth is nil, challenges from proxies are not answered.
+	ProxyAuth Authenticator
 }
 
 // DefaultClient is the default [Client] and is used by [Get], [Head], and [Post].
@@ -171,7 +205,24 @@ func refererForURL(lastReq, newReq *url.URL, explicitRef string) string {
 }
 
 // didTimeout is non-nil only if err != nil.
-func (c *Client) send(req *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
+// Challenges from servers are answered only if trusted is true.
+func (c *Client) send(req *Request, deadline time.Time, trusted bool) (resp *Response, didTimeout func() bool, err error) {
+	if (c.Auth != nil && trusted) || c.ProxyAuth != nil {
+		return c.sendAuth(req, deadline, trusted)
+	}
+	return c.sendRetry(req, deadline)
+}
+
+// sendRetry is like send, but does not answer authentication challenges.
+func (c *Client) sendRetry(req *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
+	if c.Retry != nil {
+		return c.Retry.send(c, req, deadline)
+	}
+	return c.sendOnce(req, deadline)
+}
+
+// sendOnce is like send, but makes a single attempt.
+func (c *Client) sendOnce(req *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
 	if c.Jar != nil {
 		for _, cookie := range c.Jar.Cookies(req.URL) {
 			req.AddCookie(cookie)
@@ -728,7 +779,7 @@ func (c *Client) do(req *Request) (retres *Response, reterr error) {
 		reqs = append(reqs, req)
 		var err error
 		var didTimeout func() bool
-		if resp, didTimeout, err = c.send(req, deadline); err != nil {
+		if resp, didTimeout, err = c.send(req, deadline, !stripSensitiveHeaders); err != nil {
 			// c.send() always closes req.Body
 			reqBodyClosed = true
 			if !deadline.IsZero() && didTimeout() {
@@ -1485,7 +1485,7 @@ func testClientCopyHeadersOnRedirect(t *testing.T, mode testMode) {
 			"User-Agent":      []string{ua},
 			"X-Foo":           []string{xfoo},
 			"Referer":         []string{ts2URL},
-			"Accept-Encoding": []string{"gzip"},
+			"Accept-Encoding": []string{"gzip, zstd"},
 			"Cookie":          []string{"foo=bar"},
 			"Authorization":   []string{"secretpassword"},
 		}
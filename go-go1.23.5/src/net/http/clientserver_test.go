@@ -7,6 +7,7 @@
 package http_test
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"context"
@@ -33,6 +34,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"golang.org/x/net/http2/hpack"
 )
 
 type testMode string
@@ -546,8 +549,8 @@ func testHandlerWritesTooMuch(t *testing.T, mode testMode) {
 func TestH12_AutoGzip(t *testing.T) {
 	h12Compare{
 		Handler: func(w ResponseWriter, r *Request) {
-			if ae := r.Header.Get("Accept-Encoding"); ae != "gzip" {
-				t.Errorf("%s Accept-Encoding = %q; want gzip", r.Proto, ae)
+			if ae := r.Header.Get("Accept-Encoding"); ae != "gzip, zstd" {
+				t.Errorf("%s Accept-Encoding = %q; want gzip, zstd", r.Proto, ae)
 			}
 			w.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(w)
@@ -1770,3 +1773,168 @@ func testEarlyHintsRequest(t *testing.T, mode testMode) {
 		t.Errorf("Read body %q; want Hello", body)
 	}
 }
+
+func TestUnencryptedHTTP2PriorKnowledge(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		fmt.Fprintf(w, "%v tls=%v", r.Proto, r.TLS != nil)
+	}))
+	ts.Config.Protocols = new(Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+
+	for _, test := range []struct {
+		http1, unencryptedHTTP2 bool
+		want                    string
+	}{
+		{http1: true, want: "HTTP/1.1 tls=false"},
+		{unencryptedHTTP2: true, want: "HTTP/2.0 tls=false"},
+		{http1: true, unencryptedHTTP2: true, want: "HTTP/1.1 tls=false"},
+	} {
+		tr := &Transport{Protocols: new(Protocols)}
+		tr.Protocols.SetHTTP1(test.http1)
+		tr.Protocols.SetUnencryptedHTTP2(test.unencryptedHTTP2)
+		c := &Client{Transport: tr}
+		for i := 0; i < 2; i++ {
+			res, err := c.Get(ts.URL)
+			if err != nil {
+				t.Fatalf("%v: Get: %v", tr.Protocols, err)
+			}
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(body); got != test.want {
+				t.Errorf("%v: got body %q, want %q", tr.Protocols, got, test.want)
+			}
+		}
+		tr.CloseIdleConnections()
+	}
+}
+
+func TestUnencryptedHTTP2DisabledByDefault(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	defer ts.Close()
+
+	tr := &Transport{Protocols: new(Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+	if res, err := c.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Fatalf("Get over unencrypted HTTP/2 to a server without it succeeded with %v", res.Proto)
+	}
+}
+
+func TestServerProtocolsWithoutHTTP1(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {}))
+	ts.Config.Protocols = new(Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+
+	c := ts.Client()
+	if res, err := c.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Fatalf("HTTP/1 Get to a server without HTTP/1 succeeded with %v", res.Proto)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	CondSkipHTTP2(t)
+	setParallel(t)
+	defer afterTest(t)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.Header.Get("Upgrade") != "" || r.Header.Get("Http2-Settings") != "" {
+			t.Errorf("handler saw upgrade headers: %v", r.Header)
+		}
+		fmt.Fprintf(w, "%v %v", r.Proto, r.URL.Path)
+	}))
+	ts.Config.Protocols = new(Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	io.WriteString(conn, "GET /upgraded HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\n"+
+		"HTTP2-Settings: \r\n"+
+		"\r\n")
+	br := bufio.NewReader(conn)
+	res, err := ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("upgrade response: %v %v", res.Status, res.Header)
+	}
+
+	// Client connection preface, followed by an empty SETTINGS frame.
+	io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n\x00\x00\x00\x04\x00\x00\x00\x00\x00")
+
+	// The response to the upgrading request is sent on stream 1.
+	var (
+		status string
+		body   []byte
+	)
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == ":status" {
+			status = f.Value
+		}
+	})
+	for {
+		var hdr [9]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			t.Fatal(err)
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		typ, flags := hdr[3], hdr[4]
+		streamID := (uint32(hdr[5])<<24 | uint32(hdr[6])<<16 | uint32(hdr[7])<<8 | uint32(hdr[8])) & (1<<31 - 1)
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatal(err)
+		}
+		if streamID != 1 {
+			continue
+		}
+		const (
+			frameData    = 0x0
+			frameHeaders = 0x1
+			flagEnd      = 0x1
+		)
+		switch typ {
+		case frameHeaders:
+			if _, err := dec.Write(payload); err != nil {
+				t.Fatal(err)
+			}
+		case frameData:
+			body = append(body, payload...)
+		}
+		if flags&flagEnd != 0 {
+			break
+		}
+	}
+	if status != "200" {
+		t.Errorf(":status = %q, want 200", status)
+	}
+	if got, want := string(body), "HTTP/2.0 /upgraded"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"compress/gzip"
+	"internal/zstd"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the smallest complete response body which
+// CompressHandler compresses. Smaller bodies are sent as is,
+// since compressing them would save little or nothing.
+const compressMinSize = 256
+
+// CompressHandler returns a [Handler] that runs h and compresses its
+// responses with gzip or zstd, as negotiated by the request's
+// Accept-Encoding header. When the client accepts both codings
+// with equal preference, zstd is used.
+//
+// The response is sent uncompressed when the client does not accept
+// either coding, when h sets a Content-Encoding header, when the
+// status code does not permit a body or is 206 (Partial Content),
+// when the response has a Cache-Control "no-transform" directive, or
+// when the complete body is shorter than a few hundred bytes.
+//
+// When it compresses a response, CompressHandler removes any
+// Content-Length header, changes a strong ETag into a weak one, and
+// sets Content-Type from the uncompressed body if h did not set it.
+// It adds "Accept-Encoding" to the Vary header of every response.
+//
+// Calling Flush on the [ResponseWriter] flushes the compressed stream,
+// so streaming handlers continue to work. Requests to upgrade the
+// connection to another protocol are passed to h unmodified.
+func CompressHandler(h Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.Header.Get("Upgrade") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header)
+		if encoding == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{rw: w, encoding: encoding}
+		h.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+// negotiateEncoding returns the content coding, "zstd" or "gzip",
+// to use for a response to a request with header h,
+// or "" if the response should not be compressed.
+func negotiateEncoding(h Header) string {
+	return NegotiateEncoding(h, compressCodings)
+}
+
+// compressCodings are the codings CompressHandler offers,
+// in order of preference.
+var compressCodings = []string{"zstd", "gzip"}
+
+var (
+	gzipWriterPool sync.Pool // *gzip.Writer
+	zstdWriterPool sync.Pool // *zstd.Writer
+)
+
+// A compressWriter is the ResponseWriter passed to the handler
+// wrapped by CompressHandler.
+//
+// It buffers the start of the body until it has enough to decide
+// whether to compress, then writes the header and either
+// compresses or passes through the rest of the body.
+type compressWriter struct {
+	rw       ResponseWriter
+	encoding string
+
+	status      int  // status set by WriteHeader
+	wroteHeader bool // handler called WriteHeader or Write
+	decided     bool // header has been written to rw
+	buf         []byte
+
+	zw compressor // nil if not compressing
+}
+
+// A compressor is a *gzip.Writer or *zstd.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func (cw *compressWriter) Header() Header {
+	return cw.rw.Header()
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 && code != StatusSwitchingProtocols {
+		cw.rw.WriteHeader(code)
+		return
+	}
+	if cw.wroteHeader {
+		// Let rw report the superfluous call.
+		cw.rw.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(StatusOK)
+	}
+	if !cw.decided {
+		if len(cw.buf)+len(p) < sniffLen {
+			cw.buf = append(cw.buf, p...)
+			return len(p), nil
+		}
+		// decide writes the buffer and does not retain it,
+		// so avoid copying p when nothing is buffered.
+		if len(cw.buf) == 0 {
+			cw.buf = p
+		} else {
+			cw.buf = append(cw.buf, p...)
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(p)
+	}
+	return cw.rw.Write(p)
+}
+
+// Flush writes any buffered data, compressed if need be,
+// and flushes the underlying ResponseWriter.
+func (cw *compressWriter) Flush() {
+	cw.FlushError()
+}
+
+// FlushError is like Flush, but returns any error which occurred.
+func (cw *compressWriter) FlushError() error {
+	if !cw.wroteHeader {
+		cw.WriteHeader(StatusOK)
+	}
+	if !cw.decided {
+		if err := cw.decide(true); err != nil {
+			return err
+		}
+	}
+	if cw.zw != nil {
+		if err := cw.zw.Flush(); err != nil {
+			return err
+		}
+	}
+	return NewResponseController(cw.rw).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter,
+// for use by [ResponseController].
+func (cw *compressWriter) Unwrap() ResponseWriter {
+	return cw.rw
+}
+
+// decide writes the response header to rw, choosing whether to compress
+// the body, and then writes any buffered body data.
+// more reports whether the handler may write more of the body.
+func (cw *compressWriter) decide(more bool) error {
+	cw.decided = true
+	h := cw.rw.Header()
+	compress := bodyAllowedForStatus(cw.status) &&
+		cw.status != StatusPartialContent &&
+		h.Get("Content-Encoding") == "" &&
+		!hasToken(h.Get("Cache-Control"), "no-transform") &&
+		(more || len(cw.buf) >= compressMinSize)
+	if cl := h.Get("Content-Length"); compress && cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n < compressMinSize {
+			compress = false
+		}
+	}
+	if compress {
+		if _, haveType := h["Content-Type"]; !haveType {
+			if len(cw.buf) > 0 {
+				h.Set("Content-Type", DetectContentType(cw.buf))
+			} else {
+				// Don't let rw sniff the compressed data.
+				h["Content-Type"] = nil
+			}
+		}
+		if etag := h.Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("Etag", "W/"+etag)
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.zw = newCompressor(cw.encoding, cw.rw)
+	}
+	cw.rw.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(buf)
+	} else {
+		_, err = cw.rw.Write(buf)
+	}
+	return err
+}
+
+// close finishes the response after the handler returns.
+func (cw *compressWriter) close() {
+	if !cw.wroteHeader {
+		return
+	}
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.zw == nil {
+		return
+	}
+	cw.zw.Close()
+	switch zw := cw.zw.(type) {
+	case *gzip.Writer:
+		zw.Reset(nil)
+		gzipWriterPool.Put(zw)
+	case *zstd.Writer:
+		zw.Reset(nil)
+		zstdWriterPool.Put(zw)
+	}
+	cw.zw = nil
+}
+
+// newCompressor returns a writer which compresses to w using encoding.
+func newCompressor(encoding string, w io.Writer) compressor {
+	if encoding == "zstd" {
+		if zw, ok := zstdWriterPool.Get().(*zstd.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		return zstd.NewWriter(w)
+	}
+	if zw, ok := gzipWriterPool.Get().(*gzip.Writer); ok {
+		zw.Reset(w)
+		return zw
+	}
+	return gzip.NewWriter(w)
+}
@@ -0,0 +1,277 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"internal/zstd"
+	"io"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var compressBody = strings.Repeat("Hello, compressed world! ", 100)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "zstd":
+		r = zstd.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decompressing %v: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestCompressHandlerNegotiation(t *testing.T) {
+	for _, test := range []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"zstd", "zstd"},
+		{"gzip, zstd", "zstd"},
+		{"zstd;q=0.5, gzip", "gzip"},
+		{"GZIP;Q=0.5, ZSTD;q=0.8", "zstd"},
+		{"gzip;q=0, zstd;q=0", ""},
+		{"*", "zstd"},
+		{"gzip;q=0.9, *;q=0.5", "gzip"},
+		{"*;q=0", ""},
+		{"br, deflate", ""},
+		{"gzip;q=bogus", ""},
+	} {
+		h := CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+			io.WriteString(w, compressBody)
+		}))
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != test.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", test.accept, got, test.want)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Vary = %q, want Accept-Encoding", test.accept, got)
+		}
+		if got := decompress(t, test.want, rec.Body.Bytes()); got != compressBody {
+			t.Errorf("Accept-Encoding %q: body = %q, want %q", test.accept, got, compressBody)
+		}
+	}
+}
+
+func TestCompressHandlerHeaders(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		handler  func(w ResponseWriter)
+		compress bool
+		header   Header
+	}{{
+		name: "sniff",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Length", "2500")
+			w.Header().Set("Etag", `"abc"`)
+			io.WriteString(w, "<html>"+compressBody)
+		},
+		compress: true,
+		header: Header{
+			"Content-Type":   {"text/html; charset=utf-8"},
+			"Etag":           {`W/"abc"`},
+			"Content-Length": nil,
+		},
+	}, {
+		name: "weak etag",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Etag", `W/"abc"`)
+			io.WriteString(w, compressBody)
+		},
+		compress: true,
+		header: Header{
+			"Content-Type": {"text/plain"},
+			"Etag":         {`W/"abc"`},
+		},
+	}, {
+		name: "small",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Etag", `"abc"`)
+			io.WriteString(w, "hello")
+		},
+		header: Header{"Etag": {`"abc"`}},
+	}, {
+		name: "small content length",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Length", "5")
+			w.Write([]byte("hello"))
+			w.(Flusher).Flush()
+		},
+		header: Header{"Content-Length": {"5"}},
+	}, {
+		name: "already encoded",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, compressBody)
+		},
+		header: Header{"Content-Encoding": {"br"}},
+	}, {
+		name: "no-transform",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Cache-Control", "public, no-transform")
+			io.WriteString(w, compressBody)
+		},
+	}, {
+		name: "partial content",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Range", "bytes 0-2499/5000")
+			w.WriteHeader(StatusPartialContent)
+			io.WriteString(w, compressBody)
+		},
+	}, {
+		name: "not modified",
+		handler: func(w ResponseWriter) {
+			w.WriteHeader(StatusNotModified)
+		},
+	}, {
+		name: "flush before write",
+		handler: func(w ResponseWriter) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(Flusher).Flush()
+			io.WriteString(w, "data: hello\n\n")
+		},
+		compress: true,
+		header:   Header{"Content-Type": {"text/event-stream"}},
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			h := CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+				test.handler(w)
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			res := rec.Result()
+			if got, want := res.Header.Get("Content-Encoding") == "gzip", test.compress; got != want {
+				t.Errorf("compressed = %v, want %v", got, want)
+			}
+			for k, want := range test.header {
+				if got := res.Header[k]; !slicesEqual(got, want) {
+					t.Errorf("%v = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func slicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompressHandlerStreaming(t *testing.T) {
+	run(t, testCompressHandlerStreaming, []testMode{http1Mode, http2Mode})
+}
+func testCompressHandlerStreaming(t *testing.T, mode testMode) {
+	proceed := make(chan struct{})
+	cst := newClientServerTest(t, mode, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, "first\n")
+		w.(Flusher).Flush()
+		<-proceed
+		io.WriteString(w, "second\n")
+	})))
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !res.Uncompressed {
+		t.Errorf("response was not compressed")
+	}
+	buf := make([]byte, len("first\n"))
+	if _, err := io.ReadFull(res.Body, buf); err != nil || string(buf) != "first\n" {
+		t.Fatalf("first read = %q, %v; want %q", buf, err, "first\n")
+	}
+	close(proceed)
+	rest, err := io.ReadAll(res.Body)
+	if err != nil || string(rest) != "second\n" {
+		t.Fatalf("rest of body = %q, %v; want %q", rest, err, "second\n")
+	}
+}
+
+// TestTransportDecompress tests that the Transport transparently decodes
+// responses it requested compression for, using CompressHandler to
+// produce them.
+func TestTransportDecompress(t *testing.T) {
+	run(t, testTransportDecompress, []testMode{http1Mode, https1Mode, http2Mode})
+}
+func testTransportDecompress(t *testing.T, mode testMode) {
+	cst := newClientServerTest(t, mode, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, compressBody)
+	})))
+
+	for _, test := range []struct {
+		accept   string
+		encoding string
+	}{
+		{"", "zstd"},
+		{"gzip", "gzip"},
+		{"zstd", "zstd"},
+	} {
+		req, _ := NewRequest("GET", cst.ts.URL, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+		res, err := cst.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.accept == "" {
+			// The Transport asked for compression itself, so it decodes.
+			if !res.Uncompressed || res.Header.Get("Content-Encoding") != "" || res.ContentLength != -1 {
+				t.Errorf("Accept-Encoding %q: Uncompressed = %v, Content-Encoding = %q, ContentLength = %v; want true, \"\", -1",
+					test.accept, res.Uncompressed, res.Header.Get("Content-Encoding"), res.ContentLength)
+			}
+		} else {
+			if res.Uncompressed || res.Header.Get("Content-Encoding") != test.encoding {
+				t.Errorf("Accept-Encoding %q: Uncompressed = %v, Content-Encoding = %q; want false, %q",
+					test.accept, res.Uncompressed, res.Header.Get("Content-Encoding"), test.encoding)
+			}
+			body = []byte(decompress(t, test.encoding, body))
+		}
+		if string(body) != compressBody {
+			t.Errorf("Accept-Encoding %q: body = %q, want %q", test.accept, body, compressBody)
+		}
+	}
+}
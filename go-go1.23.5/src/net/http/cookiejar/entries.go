@@ -0,0 +1,294 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An Entry is a cookie stored in a [Jar], with the attributes the jar
+// keeps for it (RFC 6265 section 5.3).
+type Entry struct {
+	Name   string
+	Value  string
+	Quoted bool // whether the value was quoted
+
+	// Domain is the canonical host name or domain the cookie is sent
+	// to, without a leading dot. If HostOnly is true, the cookie is
+	// only sent to Domain itself; otherwise it is also sent to its
+	// subdomains.
+	Domain   string
+	HostOnly bool
+
+	Path     string
+	SameSite http.SameSite
+	Secure   bool
+	HttpOnly bool
+
+	// Persistent is false for a session cookie, which has no
+	// expiry time. Expires is the expiry time of a persistent cookie,
+	// and is zero for a session cookie.
+	Persistent bool
+	Expires    time.Time
+
+	// Creation and LastAccess are the times the cookie was first
+	// stored, and last sent or updated.
+	Creation   time.Time
+	LastAccess time.Time
+}
+
+// Entries returns the cookies stored in the jar, in the order they
+// were first stored. Expired cookies are removed from the jar, and
+// are not returned.
+func (j *Jar) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entriesLocked(time.Now())
+}
+
+// entriesLocked is like Entries but takes the current time as a parameter.
+// j.mu must be held.
+func (j *Jar) entriesLocked(now time.Time) []Entry {
+	var all []entry
+	for key, submap := range j.entries {
+		for id, e := range submap {
+			if e.Persistent && !e.Expires.After(now) {
+				delete(submap, id)
+				continue
+			}
+			all = append(all, e)
+		}
+		if len(submap) == 0 {
+			delete(j.entries, key)
+		}
+	}
+	slices.SortFunc(all, func(a, b entry) int {
+		if r := a.Creation.Compare(b.Creation); r != 0 {
+			return r
+		}
+		return cmp.Compare(a.seqNum, b.seqNum)
+	})
+	entries := make([]Entry, len(all))
+	for i, e := range all {
+		entries[i] = e.export()
+	}
+	return entries
+}
+
+// export returns the exported representation of e.
+func (e *entry) export() Entry {
+	x := Entry{
+		Name:       e.Name,
+		Value:      e.Value,
+		Quoted:     e.Quoted,
+		Domain:     e.Domain,
+		HostOnly:   e.HostOnly,
+		Path:       e.Path,
+		Secure:     e.Secure,
+		HttpOnly:   e.HttpOnly,
+		Persistent: e.Persistent,
+		Creation:   e.Creation,
+		LastAccess: e.LastAccess,
+	}
+	if e.Persistent {
+		x.Expires = e.Expires
+	}
+	switch e.SameSite {
+	case "SameSite":
+		x.SameSite = http.SameSiteDefaultMode
+	case "SameSite=Strict":
+		x.SameSite = http.SameSiteStrictMode
+	case "SameSite=Lax":
+		x.SameSite = http.SameSiteLaxMode
+	}
+	return x
+}
+
+// AddEntries stores cookies in the jar, such as those returned by
+// [Jar.Entries] or read with [Format.Read], replacing any stored
+// cookies with the same name, domain and path.
+//
+// The entries are subject to the same rules as cookies set with
+// [Jar.SetCookies]: an entry without a valid domain is ignored,
+// a domain cookie for a public suffix is stored as a host cookie,
+// and an expired entry deletes the stored cookie it would replace.
+// An entry with a zero Creation or LastAccess time is given the
+// current time.
+func (j *Jar) AddEntries(entries []Entry) {
+	j.addEntries(entries, time.Now())
+}
+
+// addEntries is like AddEntries but takes the current time as a parameter.
+func (j *Jar) addEntries(entries []Entry, now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, x := range entries {
+		host, err := canonicalHost(strings.TrimPrefix(x.Domain, "."))
+		if err != nil || host == "" {
+			continue
+		}
+		domain := host
+		if x.HostOnly {
+			domain = ""
+		}
+		e := entry{
+			Name:       x.Name,
+			Value:      x.Value,
+			Quoted:     x.Quoted,
+			Path:       x.Path,
+			Secure:     x.Secure,
+			HttpOnly:   x.HttpOnly,
+			Persistent: x.Persistent,
+			Expires:    x.Expires,
+			Creation:   x.Creation,
+			LastAccess: x.LastAccess,
+		}
+		e.Domain, e.HostOnly, err = j.domainAndType(host, domain)
+		if err != nil {
+			continue
+		}
+		if e.Path == "" || e.Path[0] != '/' {
+			e.Path = "/"
+		}
+		switch x.SameSite {
+		case http.SameSiteDefaultMode:
+			e.SameSite = "SameSite"
+		case http.SameSiteStrictMode:
+			e.SameSite = "SameSite=Strict"
+		case http.SameSiteLaxMode:
+			e.SameSite = "SameSite=Lax"
+		}
+		if e.Creation.IsZero() {
+			e.Creation = now
+		}
+		if e.LastAccess.IsZero() {
+			e.LastAccess = now
+		}
+
+		key := jarKey(e.Domain, j.psList)
+		submap := j.entries[key]
+		id := e.id()
+		if !e.Persistent {
+			e.Expires = endOfTime
+		} else if !e.Expires.After(now) {
+			if _, ok := submap[id]; ok {
+				delete(submap, id)
+				if len(submap) == 0 {
+					delete(j.entries, key)
+				}
+				j.gen++
+			}
+			continue
+		}
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		if old, ok := submap[id]; ok {
+			e.seqNum = old.seqNum
+		} else {
+			e.seqNum = j.nextSeqNum
+			j.nextSeqNum++
+		}
+		submap[id] = e
+		j.gen++
+	}
+}
+
+// A Storage keeps the cookies of a [Jar] between uses.
+// See [Options.Storage].
+//
+// Implementations of Storage must be safe for concurrent use by
+// multiple goroutines.
+type Storage interface {
+	// Load returns the stored cookies.
+	Load() ([]Entry, error)
+
+	// Save replaces the stored cookies with entries.
+	Save(entries []Entry) error
+}
+
+// Save saves the jar's cookies to its Storage.
+// It does nothing if the jar has no Storage.
+func (j *Jar) Save() error {
+	if j.storage == nil {
+		return nil
+	}
+	return j.save(time.Now(), true)
+}
+
+// save saves the jar's cookies to its storage. Unless force is true,
+// it does nothing if they have not changed since they were last saved.
+func (j *Jar) save(now time.Time, force bool) error {
+	j.saveMu.Lock()
+	defer j.saveMu.Unlock()
+	j.mu.Lock()
+	gen := j.gen
+	if gen == j.savedGen && !force {
+		j.mu.Unlock()
+		return nil
+	}
+	entries := j.entriesLocked(now)
+	j.mu.Unlock()
+	if err := j.storage.Save(entries); err != nil {
+		return err
+	}
+	j.savedGen = gen
+	return nil
+}
+
+// NewFileStorage returns a [Storage] which keeps cookies in the named
+// file, encoded in the given format. Loading from a file that does
+// not exist returns no cookies. The file is replaced atomically when
+// it is saved, and is created with permissions 0600, as cookies are
+// often credentials.
+func NewFileStorage(name string, format Format) Storage {
+	return &fileStorage{name: name, format: format}
+}
+
+type fileStorage struct {
+	name   string
+	format Format
+}
+
+func (s *fileStorage) Load() ([]Entry, error) {
+	f, err := os.Open(s.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.format.Read(f)
+}
+
+func (s *fileStorage) Save(entries []Entry) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(s.name), filepath.Base(s.name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := s.format.Write(f, entries); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.name)
+}
@@ -0,0 +1,281 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cookieString returns the cookies sent by jar to u at time now,
+// in the form "name1=val1 name2=val2".
+func cookieString(jar *Jar, u string, now time.Time) string {
+	var s []string
+	for _, c := range jar.cookies(mustParseURL(u), now) {
+		s = append(s, c.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestEntries(t *testing.T) {
+	jar := newTestJar()
+	jar.setCookies(mustParseURL("https://www.host.test/dir/page"), []*http.Cookie{
+		{Name: "a", Value: "1"},
+		{Name: "b", Value: "2 3", Quoted: true, Domain: "host.test", Path: "/", Secure: true, HttpOnly: true,
+			SameSite: http.SameSiteStrictMode, MaxAge: 3600},
+		{Name: "expired", Value: "x", Expires: tNow.Add(time.Second)},
+	}, tNow)
+	later := tNow.Add(2 * time.Second)
+	jar.setCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{{Name: "c", Value: "4"}}, later)
+
+	jar.mu.Lock()
+	got := jar.entriesLocked(later)
+	jar.mu.Unlock()
+	want := []Entry{{
+		Name:       "a",
+		Value:      "1",
+		Domain:     "www.host.test",
+		HostOnly:   true,
+		Path:       "/dir",
+		Creation:   tNow,
+		LastAccess: tNow,
+	}, {
+		Name:       "b",
+		Value:      "2 3",
+		Quoted:     true,
+		Domain:     "host.test",
+		Path:       "/",
+		SameSite:   http.SameSiteStrictMode,
+		Secure:     true,
+		HttpOnly:   true,
+		Persistent: true,
+		Expires:    tNow.Add(time.Hour),
+		Creation:   tNow,
+		LastAccess: tNow,
+	}, {
+		Name:       "c",
+		Value:      "4",
+		Domain:     "www.host.test",
+		HostOnly:   true,
+		Path:       "/",
+		Creation:   later,
+		LastAccess: later,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Restoring the entries in a new jar restores its behavior.
+	jar2 := newTestJar()
+	jar2.addEntries(got, later)
+	for _, u := range []string{
+		"https://www.host.test/dir/x",
+		"http://www.host.test/dir/x",
+		"https://other.host.test/",
+	} {
+		if got, want := cookieString(jar2, u, later), cookieString(jar, u, later); got != want {
+			t.Errorf("cookies for %s: got %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestAddEntries(t *testing.T) {
+	jar := newTestJar()
+	jar.setCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{
+		{Name: "old", Value: "1"},
+	}, tNow)
+	jar.addEntries([]Entry{
+		// Deletes the existing cookie.
+		{Name: "old", Domain: "www.host.test", HostOnly: true, Path: "/", Persistent: true, Expires: tNow},
+		// A domain cookie for a public suffix is a host cookie.
+		{Name: "psl", Value: "1", Domain: "co.uk", Path: "/"},
+		// A domain cookie for an IP address is a host cookie.
+		{Name: "ip", Value: "1", Domain: "127.0.0.1"},
+		// Leading dots and upper case are canonicalized.
+		{Name: "dot", Value: "1", Domain: ".Example.TEST", Path: "/a"},
+		// Entries without a domain are ignored.
+		{Name: "empty", Value: "1"},
+	}, tNow)
+
+	for _, test := range []struct {
+		url, want string
+	}{
+		{"http://www.host.test/", ""},
+		{"http://co.uk/", "psl=1"},
+		{"http://www.co.uk/", ""},
+		{"http://127.0.0.1/", "ip=1"},
+		{"http://www.example.test/a/b", "dot=1"},
+		{"http://www.example.test/", ""},
+	} {
+		if got := cookieString(jar, test.url, tNow); got != test.want {
+			t.Errorf("cookies for %s: got %q, want %q", test.url, got, test.want)
+		}
+	}
+	if n := len(jar.entries); n != 3 {
+		t.Errorf("jar has %d keys, want 3", n)
+	}
+}
+
+// memStorage is a Storage that keeps entries in memory.
+type memStorage struct {
+	mu      sync.Mutex
+	entries []Entry
+	saves   int
+	err     error
+}
+
+func (s *memStorage) Load() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries, s.err
+}
+
+func (s *memStorage) Save(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = entries
+	s.saves++
+	return nil
+}
+
+func (s *memStorage) numSaves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+func TestStorage(t *testing.T) {
+	s := &memStorage{}
+	jar, err := New(&Options{PublicSuffixList: testPSL{}, Storage: s, SaveDelay: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParseURL("http://www.host.test/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}})
+	if s.saves != 0 {
+		t.Errorf("after SetCookies with negative SaveDelay: %v saves, want 0", s.saves)
+	}
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if s.saves != 1 || len(s.entries) != 1 || s.entries[0].Name != "a" {
+		t.Errorf("after Save: %v saves of %+v, want 1 save of cookie a", s.saves, s.entries)
+	}
+
+	jar2, err := New(&Options{PublicSuffixList: testPSL{}, Storage: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jar2.Cookies(u); len(got) != 1 || got[0].String() != "a=1" {
+		t.Errorf("cookies from loaded jar: got %v, want a=1", got)
+	}
+
+	s.err = errors.New("storage failure")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "2"}})
+	if err := jar.Save(); err != s.err {
+		t.Errorf("Save: got error %v, want %v", err, s.err)
+	}
+	s.err = nil
+	if err := jar.Save(); err != nil {
+		t.Errorf("Save: %v", err)
+	}
+	if len(s.entries) != 1 || s.entries[0].Value != "2" {
+		t.Errorf("after Save: stored %+v, want a=2", s.entries)
+	}
+
+	s.err = errors.New("load failure")
+	if _, err := New(&Options{Storage: s}); !errors.Is(err, s.err) {
+		t.Errorf("New with failing storage: got error %v, want %v", err, s.err)
+	}
+}
+
+func TestStorageSaveDelay(t *testing.T) {
+	s := &memStorage{}
+	jar, err := New(&Options{PublicSuffixList: testPSL{}, Storage: s, SaveDelay: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParseURL("http://www.host.test/")
+	for i := range 10 {
+		jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: strconv.Itoa(i)}})
+	}
+	jar.SetCookies(u, nil)
+	jar.SetCookies(mustParseURL("ftp://www.host.test/"), []*http.Cookie{{Name: "b", Value: "2"}})
+	if n := s.numSaves(); n != 0 {
+		t.Errorf("immediately after SetCookies: %v saves, want 0", n)
+	}
+	for s.numSaves() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The burst of changes is saved once.
+	time.Sleep(100 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saves != 1 || len(s.entries) != 1 || s.entries[0].Value != "9" {
+		t.Errorf("after SaveDelay: %v saves of %+v, want 1 save of a=9", s.saves, s.entries)
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatNetscape} {
+		t.Run(format.String(), func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "cookies")
+			s := NewFileStorage(name, format)
+			jar, err := New(&Options{Storage: s})
+			if err != nil {
+				t.Fatalf("New with missing file: %v", err)
+			}
+			u := mustParseURL("https://example.test/")
+			jar.SetCookies(u, []*http.Cookie{
+				{Name: "session", Value: "s"},
+				{Name: "persistent", Value: "p", MaxAge: 3600, Secure: true, HttpOnly: true},
+			})
+			if err := jar.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if runtime.GOOS != "windows" {
+				fi, err := os.Stat(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := fi.Mode().Perm(); perm != 0o600 {
+					t.Errorf("file permissions = %v, want 0600", perm)
+				}
+			}
+
+			jar2, err := New(&Options{Storage: s})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range jar2.Cookies(u) {
+				got = append(got, c.String())
+			}
+			if want := "session=s persistent=p"; strings.Join(got, " ") != want {
+				t.Errorf("cookies from loaded jar: got %q, want %q", got, want)
+			}
+			if got := jar2.Cookies(mustParseURL("http://example.test/")); len(got) != 1 {
+				t.Errorf("cookies for http: got %v, want only the insecure cookie", got)
+			}
+
+			matches, _ := filepath.Glob(name + ".*")
+			if len(matches) != 0 {
+				t.Errorf("temporary files left behind: %q", matches)
+			}
+		})
+	}
+}
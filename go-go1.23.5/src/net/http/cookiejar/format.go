@@ -0,0 +1,293 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Format is an encoding of a list of cookies, used to save the
+// contents of a [Jar] and to restore them.
+type Format int
+
+const (
+	// FormatJSON is a JSON object whose "cookies" member is an array
+	// with an object for each cookie. It preserves every field of
+	// [Entry].
+	//
+	// For example:
+	//
+	//	{
+	//	  "version": 1,
+	//	  "cookies": [
+	//	    {
+	//	      "name": "session",
+	//	      "value": "abc123",
+	//	      "domain": "example.com",
+	//	      "hostOnly": true,
+	//	      "path": "/",
+	//	      "sameSite": "lax",
+	//	      "secure": true,
+	//	      "httpOnly": true,
+	//	      "expires": "2027-01-01T00:00:00Z",
+	//	      "creation": "2026-01-01T00:00:00Z",
+	//	      "lastAccess": "2026-01-02T00:00:00Z"
+	//	    }
+	//	  ]
+	//	}
+	//
+	// A cookie without "expires" is a session cookie.
+	FormatJSON Format = iota
+
+	// FormatNetscape is the Netscape cookies.txt format, read and
+	// written by curl, wget and many browser extensions. Each line
+	// holds the tab-separated domain, subdomain flag, path, secure
+	// flag, expiry time in Unix seconds (zero for a session cookie),
+	// name and value of a cookie. An HttpOnly cookie's line starts
+	// with "#HttpOnly_", following curl. Other lines starting with
+	// "#", and blank lines, are ignored.
+	//
+	// The format does not record the SameSite attribute, or the times
+	// a cookie was created and last accessed, and it records expiry
+	// times only to the second.
+	FormatNetscape
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "FormatJSON"
+	case FormatNetscape:
+		return "FormatNetscape"
+	}
+	return "Format(" + strconv.Itoa(int(f)) + ")"
+}
+
+// Write writes entries to w in format f.
+func (f Format) Write(w io.Writer, entries []Entry) error {
+	switch f {
+	case FormatJSON:
+		return writeJSON(w, entries)
+	case FormatNetscape:
+		return writeNetscape(w, entries)
+	}
+	return fmt.Errorf("cookiejar: unknown format %v", f)
+}
+
+// Read reads entries written in format f from r.
+// The entries may be stored in a jar with [Jar.AddEntries].
+func (f Format) Read(r io.Reader) ([]Entry, error) {
+	switch f {
+	case FormatJSON:
+		return readJSON(r)
+	case FormatNetscape:
+		return readNetscape(r)
+	}
+	return nil, fmt.Errorf("cookiejar: unknown format %v", f)
+}
+
+// jsonVersion is the version of FormatJSON written by Write.
+const jsonVersion = 1
+
+type jsonFile struct {
+	Version int         `json:"version"`
+	Cookies []jsonEntry `json:"cookies"`
+}
+
+type jsonEntry struct {
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Quoted     bool       `json:"quoted,omitempty"`
+	Domain     string     `json:"domain"`
+	HostOnly   bool       `json:"hostOnly,omitempty"`
+	Path       string     `json:"path"`
+	SameSite   string     `json:"sameSite,omitempty"`
+	Secure     bool       `json:"secure,omitempty"`
+	HttpOnly   bool       `json:"httpOnly,omitempty"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	Creation   time.Time  `json:"creation"`
+	LastAccess time.Time  `json:"lastAccess"`
+}
+
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteDefaultMode: "default",
+	http.SameSiteLaxMode:     "lax",
+	http.SameSiteStrictMode:  "strict",
+	http.SameSiteNoneMode:    "none",
+}
+
+func writeJSON(w io.Writer, entries []Entry) error {
+	file := jsonFile{Version: jsonVersion, Cookies: make([]jsonEntry, len(entries))}
+	for i, e := range entries {
+		je := jsonEntry{
+			Name:       e.Name,
+			Value:      e.Value,
+			Quoted:     e.Quoted,
+			Domain:     e.Domain,
+			HostOnly:   e.HostOnly,
+			Path:       e.Path,
+			SameSite:   sameSiteNames[e.SameSite],
+			Secure:     e.Secure,
+			HttpOnly:   e.HttpOnly,
+			Creation:   e.Creation,
+			LastAccess: e.LastAccess,
+		}
+		if e.Persistent {
+			je.Expires = &e.Expires
+		}
+		file.Cookies[i] = je
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+func readJSON(r io.Reader) ([]Entry, error) {
+	var file jsonFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("cookiejar: reading JSON cookies: %w", err)
+	}
+	if file.Version != jsonVersion {
+		return nil, fmt.Errorf("cookiejar: unsupported JSON cookie file version %d", file.Version)
+	}
+	entries := make([]Entry, len(file.Cookies))
+	for i, je := range file.Cookies {
+		e := Entry{
+			Name:       je.Name,
+			Value:      je.Value,
+			Quoted:     je.Quoted,
+			Domain:     je.Domain,
+			HostOnly:   je.HostOnly,
+			Path:       je.Path,
+			Secure:     je.Secure,
+			HttpOnly:   je.HttpOnly,
+			Creation:   je.Creation,
+			LastAccess: je.LastAccess,
+		}
+		if je.SameSite != "" {
+			found := false
+			for mode, name := range sameSiteNames {
+				if je.SameSite == name {
+					e.SameSite, found = mode, true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("cookiejar: cookie %q has invalid sameSite %q", je.Name, je.SameSite)
+			}
+		}
+		if je.Expires != nil {
+			e.Persistent = true
+			e.Expires = *je.Expires
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+const (
+	netscapeHeader   = "# Netscape HTTP Cookie File"
+	httpOnlyPrefix   = "#HttpOnly_"
+	netscapeTrue     = "TRUE"
+	netscapeFalse    = "FALSE"
+	netscapeNumField = 7
+)
+
+func writeNetscape(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader + "\n\n")
+	for _, e := range entries {
+		if e.HttpOnly {
+			bw.WriteString(httpOnlyPrefix)
+		}
+		subdomains := netscapeTrue
+		if e.HostOnly {
+			subdomains = netscapeFalse
+		} else {
+			bw.WriteByte('.')
+		}
+		secure := netscapeFalse
+		if e.Secure {
+			secure = netscapeTrue
+		}
+		var expires int64
+		if e.Persistent {
+			expires = max(e.Expires.Unix(), 1)
+		}
+		value := e.Value
+		if e.Quoted {
+			value = `"` + value + `"`
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			e.Domain, subdomains, e.Path, secure, expires, e.Name, value)
+	}
+	return bw.Flush()
+}
+
+func readNetscape(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		var e Entry
+		if rest, ok := strings.CutPrefix(line, httpOnlyPrefix); ok {
+			line = rest
+			e.HttpOnly = true
+		} else if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != netscapeNumField {
+			return nil, fmt.Errorf("cookiejar: line %d: got %d fields, want %d", lineNum, len(fields), netscapeNumField)
+		}
+		domain, subdomains, path, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+		includeSubdomains, err := parseNetscapeBool(subdomains)
+		if err != nil {
+			return nil, fmt.Errorf("cookiejar: line %d: %v", lineNum, err)
+		}
+		e.HostOnly = !includeSubdomains
+		if e.Secure, err = parseNetscapeBool(secure); err != nil {
+			return nil, fmt.Errorf("cookiejar: line %d: %v", lineNum, err)
+		}
+		sec, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookiejar: line %d: invalid expiry time %q", lineNum, expires)
+		}
+		if sec != 0 {
+			e.Persistent = true
+			e.Expires = time.Unix(sec, 0).UTC()
+		}
+		e.Domain = strings.TrimPrefix(domain, ".")
+		e.Path = path
+		e.Name = name
+		if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+			e.Quoted = true
+		}
+		e.Value = value
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseNetscapeBool(s string) (bool, error) {
+	switch s {
+	case netscapeTrue:
+		return true, nil
+	case netscapeFalse:
+		return false, nil
+	}
+	return false, errors.New("invalid flag " + strconv.Quote(s))
+}
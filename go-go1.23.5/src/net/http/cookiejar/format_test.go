@@ -0,0 +1,128 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var formatTestEntries = []Entry{{
+	Name:       "session",
+	Value:      "abc",
+	Domain:     "www.example.com",
+	HostOnly:   true,
+	Path:       "/",
+	Creation:   tNow,
+	LastAccess: tNow.Add(time.Minute),
+}, {
+	Name:       "pref",
+	Value:      "a b",
+	Quoted:     true,
+	Domain:     "example.com",
+	Path:       "/app",
+	SameSite:   http.SameSiteLaxMode,
+	Secure:     true,
+	HttpOnly:   true,
+	Persistent: true,
+	Expires:    tNow.Add(24*time.Hour + 500*time.Millisecond),
+	Creation:   tNow.Add(time.Second),
+	LastAccess: tNow.Add(time.Second),
+}}
+
+func TestFormatJSON(t *testing.T) {
+	var buf strings.Builder
+	if err := FormatJSON.Write(&buf, formatTestEntries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FormatJSON.Read(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, formatTestEntries) {
+		t.Errorf("round trip:\ngot  %+v\nwant %+v\nencoding:\n%s", got, formatTestEntries, buf.String())
+	}
+	if !strings.Contains(buf.String(), `"sameSite": "lax"`) {
+		t.Errorf("encoding does not name SameSite mode:\n%s", buf.String())
+	}
+}
+
+func TestFormatNetscape(t *testing.T) {
+	var buf strings.Builder
+	if err := FormatNetscape.Write(&buf, formatTestEntries); err != nil {
+		t.Fatal(err)
+	}
+	const want = "# Netscape HTTP Cookie File\n" +
+		"\n" +
+		"www.example.com\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		"#HttpOnly_.example.com\tTRUE\t/app\tTRUE\t1357128000\tpref\t\"a b\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write:\ngot\n%s\nwant\n%s", got, want)
+	}
+
+	got, err := FormatNetscape.Read(strings.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The format does not record SameSite, creation and access times,
+	// or fractions of a second.
+	wantEntries := []Entry{formatTestEntries[0], formatTestEntries[1]}
+	for i := range wantEntries {
+		e := &wantEntries[i]
+		e.SameSite = 0
+		e.Creation = time.Time{}
+		e.LastAccess = time.Time{}
+		e.Expires = e.Expires.Truncate(time.Second)
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("Read:\ngot  %+v\nwant %+v", got, wantEntries)
+	}
+}
+
+func TestReadNetscape(t *testing.T) {
+	// As written by curl.
+	const file = "# Netscape HTTP Cookie File\r\n" +
+		"# https://curl.se/docs/http-cookies.html\r\n" +
+		"# This file was generated by libcurl! Edit at your own risk.\r\n" +
+		"\r\n" +
+		"#HttpOnly_example.org\tFALSE\t/\tFALSE\t0\tid\t42\r\n" +
+		".example.org\tTRUE\t/\tTRUE\t2000000000\ttheme\tdark\r\n"
+	got, err := FormatNetscape.Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Name: "id", Value: "42", Domain: "example.org", HostOnly: true, Path: "/", HttpOnly: true},
+		{Name: "theme", Value: "dark", Domain: "example.org", Path: "/", Secure: true,
+			Persistent: true, Expires: time.Unix(2000000000, 0).UTC()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFormatReadErrors(t *testing.T) {
+	for _, test := range []struct {
+		format  Format
+		in      string
+		wantErr string
+	}{
+		{FormatNetscape, "a\tTRUE\t/\tFALSE\t0\tname\n", "line 1: got 6 fields, want 7"},
+		{FormatNetscape, "# c\na\tyes\t/\tFALSE\t0\tn\tv\n", `line 2: invalid flag "yes"`},
+		{FormatNetscape, "a\tTRUE\t/\tFALSE\tsoon\tn\tv\n", `invalid expiry time "soon"`},
+		{FormatJSON, `{"version": 2, "cookies": []}`, "unsupported JSON cookie file version 2"},
+		{FormatJSON, `{"version": 1, "cookies": [{"name": "a", "sameSite": "loose"}]}`, `invalid sameSite "loose"`},
+		{FormatJSON, `[]`, "reading JSON cookies"},
+		{Format(7), "", "unknown format Format(7)"},
+	} {
+		_, err := test.format.Read(strings.NewReader(test.in))
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%v.Read(%q): got error %v, want %q", test.format, test.in, err, test.wantErr)
+		}
+	}
+}
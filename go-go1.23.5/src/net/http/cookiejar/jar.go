@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+// Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar,
+// whose contents may be saved and restored.
 package cookiejar
 
 import (
@@ -56,8 +57,25 @@ type Options struct {
 	// secure: it means that the HTTP server for foo.co.uk can set a cookie
 	// for bar.co.uk.
 	PublicSuffixList PublicSuffixList
+
+	// Storage, if non-nil, keeps the jar's cookies between uses.
+	// New loads the jar's cookies from Storage, and the jar saves
+	// them to Storage in the background after SetCookies changes
+	// them, and when Save is called. Programs should call Save
+	// before exiting, so that recent changes are not lost.
+	Storage Storage
+
+	// SaveDelay is how long the jar waits after SetCookies changes
+	// its cookies before saving them to Storage, so that a burst of
+	// changes is saved once. If SaveDelay is zero, a default of one
+	// second is used. If SaveDelay is negative, the cookies are saved
+	// only when Save is called.
+	SaveDelay time.Duration
 }
 
+// defaultSaveDelay is the default value of Options.SaveDelay.
+const defaultSaveDelay = 1 * time.Second
+
 // Jar implements the http.CookieJar interface from the net/http package.
 type Jar struct {
 	psList PublicSuffixList
@@ -72,16 +90,47 @@ type Jar struct {
 	// nextSeqNum is the next sequence number assigned to a new cookie
 	// created SetCookies.
 	nextSeqNum uint64
+
+	// gen is incremented whenever SetCookies or AddEntries
+	// changes entries.
+	gen uint64
+
+	// saveScheduled is whether a background save is pending.
+	saveScheduled bool
+
+	storage   Storage
+	saveDelay time.Duration
+
+	// saveMu serializes saves to storage, and locks savedGen,
+	// the value of gen when the entries were last saved.
+	saveMu   sync.Mutex
+	savedGen uint64
 }
 
 // New returns a new cookie jar. A nil [*Options] is equivalent to a zero
 // Options.
+//
+// If the options have a Storage, New loads the jar's cookies from it,
+// and returns an error if they cannot be loaded.
 func New(o *Options) (*Jar, error) {
 	jar := &Jar{
 		entries: make(map[string]map[string]entry),
 	}
 	if o != nil {
 		jar.psList = o.PublicSuffixList
+		jar.storage = o.Storage
+		jar.saveDelay = o.SaveDelay
+	}
+	if jar.saveDelay == 0 {
+		jar.saveDelay = defaultSaveDelay
+	}
+	if jar.storage != nil {
+		entries, err := jar.storage.Load()
+		if err != nil {
+			return nil, fmt.Errorf("cookiejar: loading cookies: %w", err)
+		}
+		jar.addEntries(entries, time.Now())
+		jar.savedGen = jar.gen
 	}
 	return jar, nil
 }
@@ -230,21 +279,46 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 // SetCookies implements the SetCookies method of the [http.CookieJar] interface.
 //
 // It does nothing if the URL's scheme is not HTTP or HTTPS.
+//
+// If the jar has a Storage, SetCookies arranges for the jar's cookies
+// to be saved to it in the background after changing them, as
+// described by [Options.SaveDelay]. An error saving them is discarded;
+// call [Jar.Save] to save them again and check for errors.
 func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
-	j.setCookies(u, cookies, time.Now())
+	if j.setCookies(u, cookies, time.Now()) && j.storage != nil && j.saveDelay > 0 {
+		j.scheduleSave()
+	}
+}
+
+// scheduleSave arranges for the jar's cookies to be saved after
+// j.saveDelay, unless a save is already pending.
+func (j *Jar) scheduleSave() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.saveScheduled {
+		return
+	}
+	j.saveScheduled = true
+	time.AfterFunc(j.saveDelay, func() {
+		j.mu.Lock()
+		j.saveScheduled = false
+		j.mu.Unlock()
+		j.save(time.Now(), false)
+	})
 }
 
 // setCookies is like SetCookies but takes the current time as parameter.
-func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
+// It reports whether the jar's entries were modified.
+func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) bool {
 	if len(cookies) == 0 {
-		return
+		return false
 	}
 	if u.Scheme != "http" && u.Scheme != "https" {
-		return
+		return false
 	}
 	host, err := canonicalHost(u.Host)
 	if err != nil {
-		return
+		return false
 	}
 	key := jarKey(host, j.psList)
 	defPath := defaultPath(u.Path)
@@ -293,7 +367,9 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 		} else {
 			j.entries[key] = submap
 		}
+		j.gen++
 	}
+	return modified
 }
 
 // canonicalHost strips port from host if present and returns the canonicalized
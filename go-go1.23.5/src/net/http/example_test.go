@@ -6,6 +6,7 @@ package http_test
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"io"
 	"log"
@@ -140,6 +141,25 @@ func ExampleServer_Shutdown() {
 	<-idleConnsClosed
 }
 
+func ExampleServer_Stats() {
+	srv := &http.Server{
+		Addr:                ":8080",
+		MaxConns:            1000,
+		MaxInFlightRequests: 100,
+		RateLimit: &http.RateLimit{
+			Rate:  10,
+			Burst: 20,
+		},
+	}
+
+	// Publish the server's admission statistics at /debug/vars.
+	expvar.Publish("http", expvar.Func(func() any {
+		return srv.Stats()
+	}))
+
+	log.Fatal(srv.ListenAndServe())
+}
+
 func ExampleListenAndServeTLS() {
 	http.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
 		io.WriteString(w, "Hello, TLS!\n")
@@ -28,7 +28,6 @@ var (
 	ExportErrRequestCanceled          = errRequestCanceled
 	ExportErrRequestCanceledConn      = errRequestCanceledConn
 	ExportErrServerClosedIdle         = errServerClosedIdle
-	ExportServeFile                   = serveFile
 	ExportScanETag                    = scanETag
 	ExportHttp2ConfigureServer        = http2ConfigureServer
 	Export_shouldCopyHeaderOnRedirect = shouldCopyHeaderOnRedirect
@@ -38,6 +37,10 @@ var (
 
 var MaxWriteWaitBeforeConnReuse = &maxWriteWaitBeforeConnReuse
 
+func ExportServeFile(w ResponseWriter, r *Request, fs FileSystem, name string, redirect bool) {
+	(&FileHandler{Root: fs}).serveFile(w, r, name, redirect)
+}
+
 func init() {
 	// We only want to pay for this cost during testing.
 	// When not under test, these values are always nil
@@ -94,6 +97,14 @@ func SetTestHookProxyConnectTimeout(t *testing.T, f func(context.Context, time.D
 	testHookProxyConnectTimeout = f
 }
 
+func SetDigestCnonceForTesting(t *testing.T, cnonce string) {
+	orig := digestCnonce
+	t.Cleanup(func() {
+		digestCnonce = orig
+	})
+	digestCnonce = func() string { return cnonce }
+}
+
 func NewTestTimeoutHandler(handler Handler, ctx context.Context) Handler {
 	return &timeoutHandler{
 		handler:     handler,
@@ -165,7 +176,7 @@ func (t *Transport) IdleConnStrsForTesting_h2() []string {
 func (t *Transport) IdleConnCountForTesting(scheme, addr string) int {
 	t.idleMu.Lock()
 	defer t.idleMu.Unlock()
-	key := connectMethodKey{"", scheme, addr, false}
+	key := connectMethodKey{"", scheme, addr, false, nil}
 	cacheKey := key.String()
 	for k, conns := range t.idleConn {
 		if k.String() == cacheKey {
@@ -195,7 +206,7 @@ func (t *Transport) QueueForIdleConnForTesting() {
 // persistConn for scheme, addr into the idle connection pool.
 func (t *Transport) PutIdleTestConn(scheme, addr string) bool {
 	c, _ := net.Pipe()
-	key := connectMethodKey{"", scheme, addr, false}
+	key := connectMethodKey{"", scheme, addr, false, nil}
 
 	if t.MaxConnsPerHost > 0 {
 		// Transport is tracking conns-per-host.
@@ -220,7 +231,7 @@ func (t *Transport) PutIdleTestConn(scheme, addr string) bool {
 // PutIdleTestConnH2 reports whether it was able to insert a fresh
 // HTTP/2 persistConn for scheme, addr into the idle connection pool.
 func (t *Transport) PutIdleTestConnH2(scheme, addr string, alt RoundTripper) bool {
-	key := connectMethodKey{"", scheme, addr, false}
+	key := connectMethodKey{"", scheme, addr, false, nil}
 
 	if t.MaxConnsPerHost > 0 {
 		// Transport is tracking conns-per-host.
@@ -277,6 +288,17 @@ func (s *Server) ExportAllConnsIdle() bool {
 	return true
 }
 
+// ExportRateLimitBuckets returns the number of rate limit buckets kept by s.
+func (s *Server) ExportRateLimitBuckets() int {
+	s.limits.mu.Lock()
+	defer s.limits.mu.Unlock()
+	return len(s.limits.buckets)
+}
+
+func ExportRateLimitKey(rl *RateLimit, r *Request) string {
+	return rl.key(r)
+}
+
 func (s *Server) ExportAllConnsByState() map[ConnState]int {
 	states := map[ConnState]int{}
 	s.mu.Lock()
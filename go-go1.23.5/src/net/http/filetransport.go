@@ -12,7 +12,7 @@ import (
 
 // fileTransport implements RoundTripper for the 'file' protocol.
 type fileTransport struct {
-	fh fileHandler
+	fh *FileHandler
 }
 
 // NewFileTransport returns a new [RoundTripper], serving the provided
@@ -29,7 +29,7 @@ type fileTransport struct {
 //	res, err := c.Get("file:///etc/passwd")
 //	...
 func NewFileTransport(fs FileSystem) RoundTripper {
-	return fileTransport{fileHandler{fs}}
+	return fileTransport{&FileHandler{Root: fs}}
 }
 
 // NewFileTransportFS returns a new [RoundTripper], serving the provided
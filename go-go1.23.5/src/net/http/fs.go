@@ -7,6 +7,8 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"internal/godebug"
@@ -19,9 +21,11 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -172,6 +176,29 @@ func dirList(w ResponseWriter, r *Request, f File) {
 	fmt.Fprintf(w, "</pre>\n")
 }
 
+// readDirEntries returns the entries of the directory f, sorted by name.
+func readDirEntries(f File) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	if d, ok := f.(fs.ReadDirFile); ok {
+		var err error
+		entries, err = d.ReadDir(-1)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		infos, err := f.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		entries = make([]fs.DirEntry, len(infos))
+		for i, info := range infos {
+			entries[i] = fs.FileInfoToDirEntry(info)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
 // GODEBUG=httpservecontentkeepheaders=1 restores the pre-1.23 behavior of not deleting
 // Cache-Control, Content-Encoding, Etag, or Last-Modified headers on ServeContent errors.
 var httpservecontentkeepheaders = godebug.New("httpservecontentkeepheaders")
@@ -671,7 +698,7 @@ func checkPreconditions(w ResponseWriter, r *Request, modtime time.Time) (done b
 }
 
 // name is '/'-separated, not filepath.Separator.
-func serveFile(w ResponseWriter, r *Request, fs FileSystem, name string, redirect bool) {
+func (h *FileHandler) serveFile(w ResponseWriter, r *Request, name string, redirect bool) {
 	const indexPage = "/index.html"
 
 	// redirect .../index.html to .../
@@ -682,7 +709,7 @@ func serveFile(w ResponseWriter, r *Request, fs FileSystem, name string, redirec
 		return
 	}
 
-	f, err := fs.Open(name)
+	f, err := h.Root.Open(name)
 	if err != nil {
 		msg, code := toHTTPError(err)
 		serveError(w, msg, code)
@@ -729,11 +756,12 @@ func serveFile(w ResponseWriter, r *Request, fs FileSystem, name string, redirec
 
 		// use contents of index.html for directory, if present
 		index := strings.TrimSuffix(name, "/") + indexPage
-		ff, err := fs.Open(index)
+		ff, err := h.Root.Open(index)
 		if err == nil {
 			defer ff.Close()
 			dd, err := ff.Stat()
 			if err == nil {
+				name = index
 				d = dd
 				f = ff
 			}
@@ -742,18 +770,136 @@ func serveFile(w ResponseWriter, r *Request, fs FileSystem, name string, redirec
 
 	// Still a directory? (we didn't find an index.html file)
 	if d.IsDir() {
-		if checkIfModifiedSince(r, d.ModTime()) == condFalse {
-			writeNotModified(w)
-			return
+		switch {
+		case h.DisableDirList:
+			serveError(w, "404 page not found", StatusNotFound)
+		case h.DirList != nil:
+			entries, err := readDirEntries(f)
+			if err != nil {
+				logf(r, "http: error reading directory: %v", err)
+				Error(w, "Error reading directory", StatusInternalServerError)
+				return
+			}
+			h.DirList(w, r, entries)
+		default:
+			if checkIfModifiedSince(r, d.ModTime()) == condFalse {
+				writeNotModified(w)
+				return
+			}
+			setLastModified(w, d.ModTime())
+			dirList(w, r, f)
 		}
-		setLastModified(w, d.ModTime())
-		dirList(w, r, f)
 		return
 	}
 
+	// The type of a precompressed variant is that of the original file.
+	ctypeName := d.Name()
+	if len(h.Precompressed) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if vf, vd, vname, coding := h.openPrecompressed(r, name); vf != nil {
+			defer vf.Close()
+			w.Header().Set("Content-Encoding", coding)
+			f, d, name = vf, vd, vname
+		}
+	}
+
+	if _, haveETag := w.Header()["Etag"]; !haveETag {
+		etag, err := h.etag(name, f, d)
+		if err != nil {
+			serveError(w, "seeker can't seek", StatusInternalServerError)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("Etag", etag)
+		}
+	}
+
 	// serveContent will check modification time
 	sizeFunc := func() (int64, error) { return d.Size(), nil }
-	serveContent(w, r, d.Name(), d.ModTime(), sizeFunc, f)
+	serveContent(w, r, ctypeName, d.ModTime(), sizeFunc, f)
+}
+
+// precompressedExt returns the file name extension of precompressed
+// variants of files with the given content coding, or "" if there is none.
+func precompressedExt(coding string) string {
+	switch canonicalCoding(coding) {
+	case "gzip":
+		return ".gz"
+	case "br":
+		return ".br"
+	case "zstd":
+		return ".zst"
+	}
+	return ""
+}
+
+// openPrecompressed opens the most acceptable precompressed variant of
+// the file name for the request r. It returns a nil File if the original
+// file should be served.
+func (h *FileHandler) openPrecompressed(r *Request, name string) (f File, d fs.FileInfo, vname, coding string) {
+	if mime.TypeByExtension(filepath.Ext(name)) == "" {
+		// The type could only be found by sniffing the original.
+		return nil, nil, "", ""
+	}
+	offers := make([]string, 0, len(h.Precompressed)+1)
+	for _, coding := range h.Precompressed {
+		if precompressedExt(coding) != "" {
+			offers = append(offers, coding)
+		}
+	}
+	for len(offers) > 0 {
+		coding := NegotiateEncoding(r.Header, append(offers, "identity"))
+		if coding == "" || coding == "identity" {
+			break
+		}
+		vname := name + precompressedExt(coding)
+		if f, err := h.Root.Open(vname); err == nil {
+			if d, err := f.Stat(); err == nil && !d.IsDir() {
+				return f, d, vname, coding
+			}
+			f.Close()
+		}
+		offers = slices.DeleteFunc(offers, func(c string) bool { return c == coding })
+	}
+	return nil, nil, "", ""
+}
+
+// A fileHash is the cached entity tag of a file hashed by a FileHandler.
+type fileHash struct {
+	size    int64
+	modtime time.Time
+	etag    string
+}
+
+// etag returns a strong entity tag for the file name, which is open as f
+// and has info d, or "" if none is known. f is left at its start.
+func (h *FileHandler) etag(name string, f File, d fs.FileInfo) (string, error) {
+	if ch, ok := d.(interface{ ContentHash() []byte }); ok {
+		if sum := ch.ContentHash(); len(sum) > 0 {
+			return `"` + base64.RawURLEncoding.EncodeToString(sum) + `"`, nil
+		}
+	}
+	if !h.HashContent {
+		return "", nil
+	}
+	if v, ok := h.hashes.Load(name); ok {
+		fh := v.(*fileHash)
+		if fh.size == d.Size() && fh.modtime.Equal(d.ModTime()) {
+			return fh.etag, nil
+		}
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		// Serve the file without an entity tag.
+		_, err = f.Seek(0, io.SeekStart)
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.hashes.Store(name, &fileHash{size: d.Size(), modtime: d.ModTime(), etag: etag})
+	return etag, nil
 }
 
 // toHTTPError returns a non-specific HTTP error message and status code
@@ -814,7 +960,8 @@ func ServeFile(w ResponseWriter, r *Request, name string) {
 		return
 	}
 	dir, file := filepath.Split(name)
-	serveFile(w, r, Dir(dir), file, false)
+	fh := &FileHandler{Root: Dir(dir)}
+	fh.serveFile(w, r, file, false)
 }
 
 // ServeFileFS replies to the request with the contents
@@ -847,7 +994,8 @@ func ServeFileFS(w ResponseWriter, r *Request, fsys fs.FS, name string) {
 		serveError(w, "invalid URL path", StatusBadRequest)
 		return
 	}
-	serveFile(w, r, FS(fsys), name, false)
+	fh := &FileHandler{Root: FS(fsys)}
+	fh.serveFile(w, r, name, false)
 }
 
 func containsDotDot(v string) bool {
@@ -864,10 +1012,6 @@ func containsDotDot(v string) bool {
 
 func isSlashRune(r rune) bool { return r == '/' || r == '\\' }
 
-type fileHandler struct {
-	root FileSystem
-}
-
 type ioFS struct {
 	fsys fs.FS
 }
@@ -949,41 +1093,98 @@ func FS(fsys fs.FS) FileSystem {
 
 // FileServer returns a handler that serves HTTP requests
 // with the contents of the file system rooted at root.
+// It is equivalent to &FileHandler{Root: root}.
 //
 // As a special case, the returned file server redirects any request
 // ending in "/index.html" to the same path, without the final
 // "index.html".
 //
+// If the [fs.FileInfo] of a file has a method
+//
+//	ContentHash() []byte
+//
+// as those of files in an [embed.FS] do, the file's responses have a
+// strong entity tag (ETag) derived from its result, so that clients can
+// make conditional requests for files without modification times.
+//
 // To use the operating system's file system implementation,
 // use [http.Dir]:
 //
 //	http.Handle("/", http.FileServer(http.Dir("/tmp")))
 //
 // To use an [fs.FS] implementation, use [http.FileServerFS] instead.
+// To serve precompressed files or control directory listings, use
+// a [FileHandler].
 func FileServer(root FileSystem) Handler {
-	return &fileHandler{root}
+	return &FileHandler{Root: root}
 }
 
 // FileServerFS returns a handler that serves HTTP requests
 // with the contents of the file system fsys.
 // The files provided by fsys must implement [io.Seeker].
+// It is equivalent to &FileHandler{Root: FS(fsys)}.
 //
 // As a special case, the returned file server redirects any request
 // ending in "/index.html" to the same path, without the final
 // "index.html".
 //
+// Like [FileServer], it derives entity tags for files which report
+// a hash of their contents, such as those in an [embed.FS].
+//
 //	http.Handle("/", http.FileServerFS(fsys))
 func FileServerFS(root fs.FS) Handler {
 	return FileServer(FS(root))
 }
 
-func (f *fileHandler) ServeHTTP(w ResponseWriter, r *Request) {
+// A FileHandler is a [Handler] that serves HTTP requests with the
+// contents of a file system, like the handler returned by [FileServer],
+// with additional options.
+//
+// A FileHandler must not be copied after first use.
+type FileHandler struct {
+	// Root is the file system from which files are served.
+	Root FileSystem
+
+	// Precompressed lists the content codings, in order of preference,
+	// of precompressed variants of files which may be served in place of
+	// the files themselves. The variant of a file "x" with the coding
+	// "zstd" is "x.zst", with "br" it is "x.br", and with "gzip" it is
+	// "x.gz". Other codings are ignored.
+	//
+	// When a request for x accepts the coding of a variant which exists,
+	// as determined by [NegotiateEncoding], the variant is served with
+	// a Content-Encoding header and the Content-Type of x. Variants are
+	// only served for files whose Content-Type is determined by their
+	// extension. If Precompressed is not empty, responses for files
+	// have a "Vary: Accept-Encoding" header.
+	Precompressed []string
+
+	// HashContent causes files which do not report a hash of their
+	// contents to be hashed, so that their responses have entity tags.
+	// A file is read to compute its hash the first time it is served,
+	// and again when its size or modification time changes.
+	HashContent bool
+
+	// DisableDirList causes requests for directories which have no
+	// index.html file to be answered with 404 (Not Found) instead of
+	// a listing of the directory.
+	DisableDirList bool
+
+	// DirList, if non-nil, replies to requests for directories which
+	// have no index.html file in place of the default HTML listing.
+	// It is passed the directory's entries, sorted by name.
+	DirList func(w ResponseWriter, r *Request, entries []fs.DirEntry)
+
+	hashes sync.Map // file name -> *fileHash
+}
+
+func (h *FileHandler) ServeHTTP(w ResponseWriter, r *Request) {
 	upath := r.URL.Path
 	if !strings.HasPrefix(upath, "/") {
 		upath = "/" + upath
 		r.URL.Path = upath
 	}
-	serveFile(w, r, f.root, path.Clean(upath), true)
+	h.serveFile(w, r, path.Clean(upath), true)
 }
 
 // httpRange specifies the byte range to be sent to the client.
@@ -8,6 +8,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"embed"
 	"errors"
 	"fmt"
 	"internal/testenv"
@@ -1802,3 +1803,152 @@ func testServeContentHeadersWithError(t *testing.T, keepHeaders bool) {
 		t.Errorf("got other-header = %q, want %q", g, e)
 	}
 }
+
+func TestFileHandlerPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.js":                 {Data: []byte("original")},
+		"a.js.gz":              {Data: []byte("gzip data")},
+		"a.js.br":              {Data: []byte("br data")},
+		"data.unknown":         {Data: []byte("original")},
+		"data.unknown.gz":      {Data: []byte("gzip data")},
+		"dir/index.html":       {Data: []byte("original")},
+		"dir/index.html.gz":    {Data: []byte("gzip data")},
+		"dir.css":              {Data: []byte("original")},
+		"dir.css.gz/file.html": {Data: []byte("not a variant")},
+	}
+	h := &FileHandler{Root: FS(fsys), Precompressed: []string{"zstd", "br", "gzip"}}
+	for _, test := range []struct {
+		path, acceptEncoding string
+		wantEncoding         string
+		wantType             string
+		wantBody             string
+	}{
+		{"/a.js", "gzip, br", "br", "text/javascript; charset=utf-8", "br data"},
+		{"/a.js", "gzip, br;q=0.5", "gzip", "text/javascript; charset=utf-8", "gzip data"},
+		{"/a.js", "x-gzip", "gzip", "text/javascript; charset=utf-8", "gzip data"},
+		{"/a.js", "zstd", "", "text/javascript; charset=utf-8", "original"},
+		{"/a.js", "zstd, gzip;q=0.1", "gzip", "text/javascript; charset=utf-8", "gzip data"},
+		{"/a.js", "", "", "text/javascript; charset=utf-8", "original"},
+		{"/a.js", "*;q=0", "", "text/javascript; charset=utf-8", "original"},
+		{"/data.unknown", "gzip", "", "text/plain; charset=utf-8", "original"},
+		{"/dir/", "gzip", "gzip", "text/html; charset=utf-8", "gzip data"},
+		{"/dir.css", "gzip", "", "text/css; charset=utf-8", "original"},
+		{"/a.js.gz", "gzip", "", "application/gzip", "gzip data"},
+	} {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		res := rec.Result()
+		if res.StatusCode != 200 {
+			t.Errorf("%v, Accept-Encoding %q: got status %v", test.path, test.acceptEncoding, res.Status)
+			continue
+		}
+		if got := res.Header.Get("Content-Encoding"); got != test.wantEncoding {
+			t.Errorf("%v, Accept-Encoding %q: got Content-Encoding %q, want %q", test.path, test.acceptEncoding, got, test.wantEncoding)
+		}
+		if got := res.Header.Get("Content-Type"); got != test.wantType {
+			t.Errorf("%v, Accept-Encoding %q: got Content-Type %q, want %q", test.path, test.acceptEncoding, got, test.wantType)
+		}
+		if got := res.Header.Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%v, Accept-Encoding %q: got Vary %q, want Accept-Encoding", test.path, test.acceptEncoding, got)
+		}
+		if got := rec.Body.String(); got != test.wantBody {
+			t.Errorf("%v, Accept-Encoding %q: got body %q, want %q", test.path, test.acceptEncoding, got, test.wantBody)
+		}
+	}
+}
+
+//go:embed testdata/file testdata/style.css
+var embedTestdata embed.FS
+
+func TestFileServerContentHashETag(t *testing.T) {
+	h := FileServerFS(embedTestdata)
+	get := func(path, ifNoneMatch string) *Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+	res := get("/testdata/file", "")
+	etag := res.Header.Get("Etag")
+	if res.StatusCode != 200 || len(etag) < 3 || etag[0] != '"' || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("got status %v, ETag %q; want 200 with strong ETag", res.Status, etag)
+	}
+	if other := get("/testdata/style.css", "").Header.Get("Etag"); other == "" || other == etag {
+		t.Errorf("got ETag %q for another file, with ETag %q", other, etag)
+	}
+	if res := get("/testdata/file", etag); res.StatusCode != StatusNotModified {
+		t.Errorf("with If-None-Match: got status %v, want 304", res.Status)
+	}
+	if res := get("/testdata/", ""); res.Header.Get("Etag") != "" {
+		t.Errorf("directory listing has ETag %q", res.Header.Get("Etag"))
+	}
+}
+
+func TestFileHandlerHashContent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt": {Data: []byte("contents"), ModTime: time.Unix(1e9, 0)},
+	}
+	get := func(h Handler) (etag, body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/file.txt", nil))
+		return rec.Header().Get("Etag"), rec.Body.String()
+	}
+	if etag, _ := get(FileServerFS(fsys)); etag != "" {
+		t.Errorf("FileServerFS: got ETag %q for file with no content hash", etag)
+	}
+	h := &FileHandler{Root: FS(fsys), HashContent: true}
+	etag1, body := get(h)
+	if etag1 == "" || body != "contents" {
+		t.Fatalf("got ETag %q, body %q; want ETag and complete body", etag1, body)
+	}
+	if etag, _ := get(h); etag != etag1 {
+		t.Errorf("got ETag %q, then %q", etag1, etag)
+	}
+	fsys["file.txt"] = &fstest.MapFile{Data: []byte("new contents"), ModTime: time.Unix(2e9, 0)}
+	if etag, body := get(h); etag == etag1 || body != "new contents" {
+		t.Errorf("after change: got ETag %q, body %q; want new ETag and body", etag, body)
+	}
+}
+
+func TestFileHandlerDirList(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/b":            {Data: []byte("b")},
+		"dir/a":            {Data: []byte("a")},
+		"dir/c/d":          {Data: []byte("d")},
+		"index/index.html": {Data: []byte("index")},
+	}
+	serve := func(h Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	h := &FileHandler{Root: FS(fsys), DisableDirList: true}
+	if rec := serve(h, "/dir/"); rec.Code != StatusNotFound {
+		t.Errorf("DisableDirList: got status %v, want 404", rec.Code)
+	}
+	if rec := serve(h, "/index/"); rec.Code != 200 || rec.Body.String() != "index" {
+		t.Errorf("DisableDirList with index.html: got %v %q, want index", rec.Code, rec.Body.String())
+	}
+
+	h = &FileHandler{Root: FS(fsys), DirList: func(w ResponseWriter, r *Request, entries []fs.DirEntry) {
+		for _, e := range entries {
+			fmt.Fprintf(w, "%v %v\n", e.Name(), e.IsDir())
+		}
+	}}
+	if rec := serve(h, "/dir/"); rec.Body.String() != "a false\nb false\nc true\n" {
+		t.Errorf("DirList: got body %q", rec.Body.String())
+	}
+	if rec := serve(h, "/dir"); rec.Code != StatusMovedPermanently {
+		t.Errorf("DirList, no trailing slash: got status %v, want 301", rec.Code)
+	}
+}
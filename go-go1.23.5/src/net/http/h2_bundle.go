@@ -883,7 +883,7 @@ func (c *http2dialCall) dial(ctx context.Context, addr string) {
 // This code decides which ones live or die.
 // The return value used is whether c was used.
 // c is never closed.
-func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c *tls.Conn) (used bool, err error) {
+func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c net.Conn) (used bool, err error) {
 	p.mu.Lock()
 	for _, cc := range p.conns[key] {
 		if cc.CanTakeNewRequest() {
@@ -919,8 +919,8 @@ type http2addConnCall struct {
 	err  error
 }
 
-func (c *http2addConnCall) run(t *http2Transport, key string, tc *tls.Conn) {
-	cc, err := t.NewClientConn(tc)
+func (c *http2addConnCall) run(t *http2Transport, key string, nc net.Conn) {
+	cc, err := t.NewClientConn(nc)
 
 	p := c.p
 	p.mu.Lock()
@@ -2898,7 +2898,7 @@ func (mh *http2MetaHeadersFrame) checkPseudos() error {
 	pf := mh.PseudoFields()
 	for i, hf := range pf {
 		switch hf.Name {
-		case ":method", ":path", ":scheme", ":authority":
+		case ":method", ":path", ":scheme", ":authority", ":protocol":
 			isRequest = true
 		case ":status":
 			isResponse = true
@@ -3453,6 +3453,10 @@ func (s http2Setting) Valid() error {
 		if s.Val < 16384 || s.Val > 1<<24-1 {
 			return http2ConnectionError(http2ErrCodeProtocol)
 		}
+	case http2SettingEnableConnectProtocol:
+		if s.Val != 1 && s.Val != 0 {
+			return http2ConnectionError(http2ErrCodeProtocol)
+		}
 	}
 	return nil
 }
@@ -3468,6 +3472,9 @@ const (
 	http2SettingInitialWindowSize    http2SettingID = 0x4
 	http2SettingMaxFrameSize         http2SettingID = 0x5
 	http2SettingMaxHeaderListSize    http2SettingID = 0x6
+
+	// RFC 8441, Section 3.
+	http2SettingEnableConnectProtocol http2SettingID = 0x8
 )
 
 var http2settingName = map[http2SettingID]string{
@@ -3477,6 +3484,8 @@ var http2settingName = map[http2SettingID]string{
 	http2SettingInitialWindowSize:    "INITIAL_WINDOW_SIZE",
 	http2SettingMaxFrameSize:         "MAX_FRAME_SIZE",
 	http2SettingMaxHeaderListSize:    "MAX_HEADER_LIST_SIZE",
+
+	http2SettingEnableConnectProtocol: "ENABLE_CONNECT_PROTOCOL",
 }
 
 func (s http2SettingID) String() string {
@@ -4166,7 +4175,7 @@ func http2ConfigureServer(s *Server, conf *http2Server) error {
 	if s.TLSNextProto == nil {
 		s.TLSNextProto = map[string]func(*Server, *tls.Conn, Handler){}
 	}
-	protoHandler := func(hs *Server, c *tls.Conn, h Handler) {
+	protoHandler := func(hs *Server, c net.Conn, h Handler) {
 		if http2testHookOnConn != nil {
 			http2testHookOnConn()
 		}
@@ -4182,13 +4191,37 @@ func http2ConfigureServer(s *Server, conf *http2Server) error {
 		if bc, ok := h.(baseContexter); ok {
 			ctx = bc.BaseContext()
 		}
-		conf.ServeConn(c, &http2ServeConnOpts{
+		opts := &http2ServeConnOpts{
 			Context:    ctx,
 			Handler:    h,
 			BaseConfig: hs,
-		})
+		}
+		// Connections upgraded from HTTP/1.1 with "Upgrade: h2c"
+		// carry the upgrading request and its HTTP2-Settings.
+		type h2cUpgrader interface {
+			H2CUpgrade() (*Request, []byte)
+		}
+		if u, ok := c.(h2cUpgrader); ok {
+			opts.UpgradeRequest, opts.Settings = u.H2CUpgrade()
+		}
+		conf.ServeConn(c, opts)
+	}
+	s.TLSNextProto[http2NextProtoTLS] = func(hs *Server, c *tls.Conn, h Handler) {
+		protoHandler(hs, c, h)
+	}
+	s.TLSNextProto[http2nextProtoUnencryptedHTTP2] = func(hs *Server, c *tls.Conn, h Handler) {
+		nc, err := http2unencryptedNetConnFromTLSConn(c)
+		if err != nil {
+			if lg := hs.ErrorLog; lg != nil {
+				lg.Print(err)
+			} else {
+				log.Print(err)
+			}
+			go c.Close()
+			return
+		}
+		protoHandler(hs, nc, h)
 	}
-	s.TLSNextProto[http2NextProtoTLS] = protoHandler
 	return nil
 }
 
@@ -4222,6 +4255,29 @@ type http2ServeConnOpts struct {
 	SawClientPreface bool
 }
 
+// nextProtoUnencryptedHTTP2 is used to indicate that a TLSNextProto handler
+// should handle unencrypted HTTP/2 connections.
+const http2nextProtoUnencryptedHTTP2 = "unencrypted_http2"
+
+// unencryptedNetConnFromTLSConn retrieves a net.Conn wrapped in a *tls.Conn.
+//
+// TLSNextProto functions accept a *tls.Conn.
+//
+// When passing an unencrypted HTTP/2 connection to a TLSNextProto function,
+// we pass a *tls.Conn with an underlying net.Conn containing the unencrypted connection.
+// To be extra careful about mistakes (accidentally dropping TLS encryption in a place
+// where we want it), the tls.Conn contains a net.Conn with an UnencryptedNetConn method
+// that returns the actual connection we want to use.
+func http2unencryptedNetConnFromTLSConn(tc *tls.Conn) (net.Conn, error) {
+	conner, ok := tc.NetConn().(interface {
+		UnencryptedNetConn() net.Conn
+	})
+	if !ok {
+		return nil, errors.New("http2: TLS conn unexpectedly found in unencrypted handoff")
+	}
+	return conner.UnencryptedNetConn(), nil
+}
+
 func (o *http2ServeConnOpts) context() context.Context {
 	if o != nil && o.Context != nil {
 		return o.Context
@@ -4512,6 +4568,7 @@ type http2stream struct {
 	cw        http2closeWaiter // closed wait stream transitions to closed state
 	ctx       context.Context
 	cancelCtx func()
+	start     time.Time // when the HEADERS frame opening the stream was processed
 
 	// owned by serverConn's serve loop:
 	bodyBytes        int64        // body bytes seen so far
@@ -4775,6 +4832,7 @@ func (sc *http2serverConn) serve() {
 			{http2SettingMaxHeaderListSize, sc.maxHeaderListSize()},
 			{http2SettingHeaderTableSize, sc.srv.maxDecoderHeaderTableSize()},
 			{http2SettingInitialWindowSize, uint32(sc.srv.initialStreamRecvWindowSize())},
+			{http2SettingEnableConnectProtocol, 1},
 		},
 	})
 	sc.unackedSettings++
@@ -5592,6 +5650,8 @@ func (sc *http2serverConn) processSetting(s http2Setting) error {
 		sc.maxFrameSize = int32(s.Val) // the maximum valid s.Val is < 2^31
 	case http2SettingMaxHeaderListSize:
 		sc.peerMaxHeaderListSize = s.Val
+	case http2SettingEnableConnectProtocol:
+		// Only meaningful when sent by a server.
 	default:
 		// Unknown setting: "An endpoint that receives a SETTINGS
 		// frame with any unknown or unsupported identifier MUST
@@ -5991,6 +6051,7 @@ func (sc *http2serverConn) newStream(id, pusherID uint32, state http2streamState
 		state:     state,
 		ctx:       ctx,
 		cancelCtx: cancelCtx,
+		start:     time.Now(),
 	}
 	st.cw.Init()
 	st.flow.conn = &sc.flow // link to conn-level counter
@@ -6022,10 +6083,16 @@ func (sc *http2serverConn) newWriterAndRequest(st *http2stream, f *http2MetaHead
 		scheme:    f.PseudoValue("scheme"),
 		authority: f.PseudoValue("authority"),
 		path:      f.PseudoValue("path"),
+		protocol:  f.PseudoValue("protocol"),
 	}
 
 	isConnect := rp.method == "CONNECT"
-	if isConnect {
+	if rp.protocol != "" {
+		// Extended CONNECT; RFC 8441, Section 4.
+		if !isConnect || rp.path == "" || rp.authority == "" || (rp.scheme != "https" && rp.scheme != "http") {
+			return nil, nil, sc.countError("bad_extended_connect", http2streamError(f.StreamID, http2ErrCodeProtocol))
+		}
+	} else if isConnect {
 		if rp.path != "" || rp.scheme != "" || rp.authority == "" {
 			return nil, nil, sc.countError("bad_connect", http2streamError(f.StreamID, http2ErrCodeProtocol))
 		}
@@ -6050,6 +6117,9 @@ func (sc *http2serverConn) newWriterAndRequest(st *http2stream, f *http2MetaHead
 	if rp.authority == "" {
 		rp.authority = rp.header.Get("Host")
 	}
+	if rp.protocol != "" {
+		rp.header.Set(":protocol", rp.protocol)
+	}
 
 	rw, req, err := sc.newWriterAndRequestNoBody(st, rp)
 	if err != nil {
@@ -6076,6 +6146,7 @@ func (sc *http2serverConn) newWriterAndRequest(st *http2stream, f *http2MetaHead
 type http2requestParam struct {
 	method                  string
 	scheme, authority, path string
+	protocol                string
 	header                  Header
 }
 
@@ -6117,7 +6188,7 @@ func (sc *http2serverConn) newWriterAndRequestNoBody(st *http2stream, rp http2re
 
 	var url_ *url.URL
 	var requestURI string
-	if rp.method == "CONNECT" {
+	if rp.method == "CONNECT" && rp.protocol == "" {
 		url_ = &url.URL{Host: rp.authority}
 		requestURI = rp.authority // mimic HTTP/1 server behavior
 	} else {
@@ -6242,6 +6313,13 @@ func (sc *http2serverConn) runHandler(rw *http2responseWriter, req *Request, han
 				buf = buf[:runtime.Stack(buf, false)]
 				sc.logf("http2: panic serving %v: %v\n%s", sc.conn.RemoteAddr(), e, buf)
 			}
+			rws := rw.rws
+			sc.hs.requestDone(req, RequestStats{
+				StatusCode:   rws.status,
+				BytesWritten: rws.wroteBytes,
+				Start:        rws.stream.start,
+				Panicked:     true,
+			})
 			return
 		}
 		rw.handlerDone()
@@ -6867,6 +6945,11 @@ func (w *http2responseWriter) handlerDone() {
 	rws := w.rws
 	rws.handlerDone = true
 	w.Flush()
+	rws.conn.hs.requestDone(rws.req, RequestStats{
+		StatusCode:   rws.status,
+		BytesWritten: rws.wroteBytes,
+		Start:        rws.stream.start,
+	})
 	w.rws = nil
 	http2responseWriterStatePool.Put(rws)
 }
@@ -7432,8 +7515,8 @@ func http2configureTransports(t1 *Transport) (*http2Transport, error) {
 	if !http2strSliceContains(t1.TLSClientConfig.NextProtos, "http/1.1") {
 		t1.TLSClientConfig.NextProtos = append(t1.TLSClientConfig.NextProtos, "http/1.1")
 	}
-	upgradeFn := func(authority string, c *tls.Conn) RoundTripper {
-		addr := http2authorityAddr("https", authority)
+	upgradeFn := func(scheme, authority string, c net.Conn) RoundTripper {
+		addr := http2authorityAddr(scheme, authority)
 		if used, err := connPool.addConnIfNeeded(addr, t2, c); err != nil {
 			go c.Close()
 			return http2erringRoundTripper{err}
@@ -7444,14 +7527,25 @@ func http2configureTransports(t1 *Transport) (*http2Transport, error) {
 			// was unknown)
 			go c.Close()
 		}
+		if scheme == "http" {
+			return (*http2unencryptedTransport)(t2)
+		}
 		return t2
 	}
-	if m := t1.TLSNextProto; len(m) == 0 {
-		t1.TLSNextProto = map[string]func(string, *tls.Conn) RoundTripper{
-			"h2": upgradeFn,
+	if t1.TLSNextProto == nil {
+		t1.TLSNextProto = make(map[string]func(string, *tls.Conn) RoundTripper)
+	}
+	t1.TLSNextProto[http2NextProtoTLS] = func(authority string, c *tls.Conn) RoundTripper {
+		return upgradeFn("https", authority, c)
+	}
+	// The "unencrypted_http2" TLSNextProto key is used to pass off non-TLS HTTP/2 conns.
+	t1.TLSNextProto[http2nextProtoUnencryptedHTTP2] = func(authority string, c *tls.Conn) RoundTripper {
+		nc, err := http2unencryptedNetConnFromTLSConn(c)
+		if err != nil {
+			go c.Close()
+			return http2erringRoundTripper{err}
 		}
-	} else {
-		m["h2"] = upgradeFn
+		return upgradeFn("http", authority, nc)
 	}
 	return t2, nil
 }
@@ -7486,31 +7580,33 @@ type http2ClientConn struct {
 	idleTimeout time.Duration // or 0 for never
 	idleTimer   http2timer
 
-	mu              sync.Mutex   // guards following
-	cond            *sync.Cond   // hold mu; broadcast on flow/closed changes
-	flow            http2outflow // our conn-level flow control quota (cs.outflow is per stream)
-	inflow          http2inflow  // peer's conn-level flow control
-	doNotReuse      bool         // whether conn is marked to not be reused for any future requests
-	closing         bool
-	closed          bool
-	seenSettings    bool                          // true if we've seen a settings frame, false otherwise
-	wantSettingsAck bool                          // we sent a SETTINGS frame and haven't heard back
-	goAway          *http2GoAwayFrame             // if non-nil, the GoAwayFrame we received
-	goAwayDebug     string                        // goAway frame's debug data, retained as a string
-	streams         map[uint32]*http2clientStream // client-initiated
-	streamsReserved int                           // incr by ReserveNewRequest; decr on RoundTrip
-	nextStreamID    uint32
-	pendingRequests int                       // requests blocked and waiting to be sent because len(streams) == maxConcurrentStreams
-	pings           map[[8]byte]chan struct{} // in flight ping data to notification channel
-	br              *bufio.Reader
-	lastActive      time.Time
-	lastIdle        time.Time // time last idle
+	mu               sync.Mutex   // guards following
+	cond             *sync.Cond   // hold mu; broadcast on flow/closed changes
+	flow             http2outflow // our conn-level flow control quota (cs.outflow is per stream)
+	inflow           http2inflow  // peer's conn-level flow control
+	doNotReuse       bool         // whether conn is marked to not be reused for any future requests
+	closing          bool
+	closed           bool
+	seenSettings     bool                          // true if we've seen a settings frame, false otherwise
+	seenSettingsChan chan struct{}                 // closed when seenSettings is set
+	wantSettingsAck  bool                          // we sent a SETTINGS frame and haven't heard back
+	goAway           *http2GoAwayFrame             // if non-nil, the GoAwayFrame we received
+	goAwayDebug      string                        // goAway frame's debug data, retained as a string
+	streams          map[uint32]*http2clientStream // client-initiated
+	streamsReserved  int                           // incr by ReserveNewRequest; decr on RoundTrip
+	nextStreamID     uint32
+	pendingRequests  int                       // requests blocked and waiting to be sent because len(streams) == maxConcurrentStreams
+	pings            map[[8]byte]chan struct{} // in flight ping data to notification channel
+	br               *bufio.Reader
+	lastActive       time.Time
+	lastIdle         time.Time // time last idle
 	// Settings from peer: (also guarded by wmu)
 	maxFrameSize           uint32
 	maxConcurrentStreams   uint32
 	peerMaxHeaderListSize  uint64
 	peerMaxHeaderTableSize uint32
 	initialWindowSize      uint32
+	extendedConnectAllowed bool // peer sent SETTINGS_ENABLE_CONNECT_PROTOCOL=1
 
 	// reqHeaderMu is a 1-element semaphore channel controlling access to sending new requests.
 	// Write to reqHeaderMu to lock it, read from it to unlock.
@@ -7691,12 +7787,22 @@ type http2RoundTripOpt struct {
 	// no cached connection is available, RoundTripOpt
 	// will return ErrNoCachedConn.
 	OnlyCachedConn bool
+
+	allowHTTP bool // allow http:// URLs
 }
 
 func (t *http2Transport) RoundTrip(req *Request) (*Response, error) {
 	return t.RoundTripOpt(req, http2RoundTripOpt{})
 }
 
+// unencryptedTransport is a Transport with a RoundTrip method that
+// always permits http:// URLs.
+type http2unencryptedTransport http2Transport
+
+func (t *http2unencryptedTransport) RoundTrip(req *Request) (*Response, error) {
+	return (*http2Transport)(t).RoundTripOpt(req, http2RoundTripOpt{allowHTTP: true})
+}
+
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
 // and returns a host:port. The port 443 is added if needed.
 func http2authorityAddr(scheme string, authority string) (addr string) {
@@ -7723,7 +7829,14 @@ func http2authorityAddr(scheme string, authority string) (addr string) {
 
 // RoundTripOpt is like RoundTrip, but takes options.
 func (t *http2Transport) RoundTripOpt(req *Request, opt http2RoundTripOpt) (*Response, error) {
-	if !(req.URL.Scheme == "https" || (req.URL.Scheme == "http" && t.AllowHTTP)) {
+	switch req.URL.Scheme {
+	case "https":
+		// Always okay.
+	case "http":
+		if !t.AllowHTTP && !opt.allowHTTP {
+			return nil, errors.New("http2: unencrypted HTTP/2 not enabled")
+		}
+	default:
 		return nil, errors.New("http2: unsupported scheme")
 	}
 
@@ -7925,6 +8038,7 @@ func (t *http2Transport) newClientConn(c net.Conn, singleUse bool) (*http2Client
 		peerMaxHeaderListSize: 0xffffffffffffffff,               // "infinite", per spec. Use 2^64-1 instead.
 		streams:               make(map[uint32]*http2clientStream),
 		singleUse:             singleUse,
+		seenSettingsChan:      make(chan struct{}),
 		wantSettingsAck:       true,
 		pings:                 make(map[[8]byte]chan struct{}),
 		reqHeaderMu:           make(chan struct{}, 1),
@@ -8438,8 +8552,8 @@ func (cc *http2ClientConn) roundTrip(req *Request, streamf func(*http2clientStre
 		req.Header.Get("Accept-Encoding") == "" &&
 		req.Header.Get("Range") == "" &&
 		!cs.isHead {
-		// Request gzip only, not deflate. Deflate is ambiguous and
-		// not as universally supported anyway.
+		// Request gzip and zstd only, not deflate. Deflate is ambiguous
+		// and not as universally supported anyway.
 		// See: https://zlib.net/zlib_faq.html#faq39
 		//
 		// Note that we don't request this for HEAD requests,
@@ -8567,6 +8681,26 @@ func (cs *http2clientStream) writeRequest(req *Request, streamf func(*http2clien
 		return err
 	}
 
+	if http2isExtendedConnect(req) {
+		// RFC 8441, Section 3: the client must not send an extended
+		// CONNECT request until the server has indicated support.
+		select {
+		case <-cc.seenSettingsChan:
+		case <-cc.readerDone:
+			return cc.readerErr
+		case <-cs.reqCancel:
+			return http2errRequestCanceled
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		cc.mu.Lock()
+		allowed := cc.extendedConnectAllowed
+		cc.mu.Unlock()
+		if !allowed {
+			return http2errExtendedConnectNotSupported
+		}
+	}
+
 	// Acquire the new-request lock by writing to reqHeaderMu.
 	// This lock guards the critical section covering allocating a new stream ID
 	// (requires mu) and creating the stream (requires wmu).
@@ -9085,8 +9219,19 @@ func (cs *http2clientStream) awaitFlowControl(maxBytes int) (taken int32, err er
 	}
 }
 
+// isExtendedConnect reports whether req is an extended CONNECT request
+// (RFC 8441), which carries the protocol in a ":protocol" header.
+func http2isExtendedConnect(req *Request) bool {
+	return req.Method == "CONNECT" && req.Header.Get(":protocol") != ""
+}
+
+var http2errExtendedConnectNotSupported = errors.New("http2: server does not support extended CONNECT")
+
 func http2validateHeaders(hdrs Header) string {
 	for k, vv := range hdrs {
+		if k == ":protocol" {
+			continue // checked by encodeHeaders
+		}
 		if !httpguts.ValidHeaderFieldName(k) {
 			return fmt.Sprintf("name %q", k)
 		}
@@ -9122,8 +9267,13 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 		return nil, errors.New("http2: invalid Host header")
 	}
 
+	isExtendedConnect := http2isExtendedConnect(req)
+	if req.Header[":protocol"] != nil && !isExtendedConnect {
+		return nil, errors.New("http2: :protocol header requires the CONNECT method")
+	}
+
 	var path string
-	if req.Method != "CONNECT" {
+	if req.Method != "CONNECT" || isExtendedConnect {
 		path = req.URL.RequestURI()
 		if !http2validPseudoPath(path) {
 			orig := path
@@ -9160,19 +9310,23 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 			m = MethodGet
 		}
 		f(":method", m)
-		if req.Method != "CONNECT" {
+		if req.Method != "CONNECT" || isExtendedConnect {
 			f(":path", path)
 			f(":scheme", req.URL.Scheme)
 		}
+		if isExtendedConnect {
+			f(":protocol", req.Header.Get(":protocol"))
+		}
 		if trailers != "" {
 			f("trailer", trailers)
 		}
 
 		var didUA bool
 		for k, vv := range req.Header {
-			if http2asciiEqualFold(k, "host") || http2asciiEqualFold(k, "content-length") {
+			if http2asciiEqualFold(k, "host") || http2asciiEqualFold(k, "content-length") || k == ":protocol" {
 				// Host is :authority, already sent.
 				// Content-Length is automatic, set below.
+				// :protocol is a pseudo-header, already sent.
 				continue
 			} else if http2asciiEqualFold(k, "connection") ||
 				http2asciiEqualFold(k, "proxy-connection") ||
@@ -9230,7 +9384,7 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 			f("content-length", strconv.FormatInt(contentLength, 10))
 		}
 		if addGzipHeader {
-			f("accept-encoding", "gzip")
+			f("accept-encoding", defaultAcceptEncoding())
 		}
 		if !didUA {
 			f("user-agent", http2defaultUserAgent)
@@ -9725,12 +9879,20 @@ func (rl *http2clientConnReadLoop) handleResponse(cs *http2clientStream, f *http
 	cs.bytesRemain = res.ContentLength
 	res.Body = http2transportResponseBody{cs}
 
-	if cs.requestedGzip && http2asciiEqualFold(res.Header.Get("Content-Encoding"), "gzip") {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = &http2gzipReader{body: res.Body}
-		res.Uncompressed = true
+	if cs.requestedGzip {
+		body := res.Body
+		switch ce := res.Header.Get("Content-Encoding"); {
+		case http2asciiEqualFold(ce, "gzip"):
+			res.Body = &http2gzipReader{body: body}
+		case http2asciiEqualFold(ce, "zstd"):
+			res.Body = &zstdReader{body: body}
+		}
+		if res.Body != body {
+			res.Header.Del("Content-Encoding")
+			res.Header.Del("Content-Length")
+			res.ContentLength = -1
+			res.Uncompressed = true
+		}
 	}
 	return res, nil
 }
@@ -10094,6 +10256,13 @@ func (rl *http2clientConnReadLoop) processSettingsNoWrite(f *http2SettingsFrame)
 		case http2SettingHeaderTableSize:
 			cc.henc.SetMaxDynamicTableSize(s.Val)
 			cc.peerMaxHeaderTableSize = s.Val
+		case http2SettingEnableConnectProtocol:
+			// RFC 8441, Section 3: a server may not withdraw
+			// support once it has been advertised.
+			if s.Val == 0 && cc.extendedConnectAllowed {
+				return http2ConnectionError(http2ErrCodeProtocol)
+			}
+			cc.extendedConnectAllowed = s.Val == 1
 		default:
 			cc.vlogf("Unhandled Setting: %v", s)
 		}
@@ -10112,6 +10281,7 @@ func (rl *http2clientConnReadLoop) processSettingsNoWrite(f *http2SettingsFrame)
 			cc.maxConcurrentStreams = http2defaultMaxConcurrentStreams
 		}
 		cc.seenSettings = true
+		close(cc.seenSettingsChan)
 	}
 
 	return nil
@@ -0,0 +1,722 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 (RFC 9114) over the QUIC implementation in net/http/internal/quic.
+//
+// This file contains the framing and connection-level code shared by the
+// client (h3_transport.go) and the server (h3_server.go).
+
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http/internal/quic"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// http3NextProto is the ALPN protocol identifier for HTTP/3.
+const http3NextProto = "h3"
+
+// HTTP/3 frame types (RFC 9114, Section 7.2).
+const (
+	http3FrameData        = 0x00
+	http3FrameHeaders     = 0x01
+	http3FrameCancelPush  = 0x03
+	http3FrameSettings    = 0x04
+	http3FramePushPromise = 0x05
+	http3FrameGoAway      = 0x07
+	http3FrameMaxPushID   = 0x0d
+)
+
+// http3FrameReservedHTTP2 reports whether t is an HTTP/2 frame type
+// with no HTTP/3 equivalent, which must not be sent (RFC 9114, Section 7.2.8).
+func http3FrameReservedHTTP2(t uint64) bool {
+	return t == 0x02 || t == 0x06 || t == 0x08 || t == 0x09
+}
+
+// HTTP/3 settings (RFC 9114, Section 7.2.4.1, and RFC 9204, Section 5).
+const (
+	http3SettingQPACKMaxTableCapacity = 0x01
+	http3SettingMaxFieldSectionSize   = 0x06
+	http3SettingQPACKBlockedStreams   = 0x07
+)
+
+// Unidirectional stream types (RFC 9114, Section 6.2, and RFC 9204, Section 4.2).
+const (
+	http3StreamControl      = 0x00
+	http3StreamPush         = 0x01
+	http3StreamQPACKEncoder = 0x02
+	http3StreamQPACKDecoder = 0x03
+)
+
+// An http3ErrCode is an HTTP/3 error code (RFC 9114, Section 8.1).
+type http3ErrCode uint64
+
+const (
+	http3ErrNoError              = http3ErrCode(0x100)
+	http3ErrGeneralProtocol      = http3ErrCode(0x101)
+	http3ErrInternal             = http3ErrCode(0x102)
+	http3ErrStreamCreation       = http3ErrCode(0x103)
+	http3ErrClosedCriticalStream = http3ErrCode(0x104)
+	http3ErrFrameUnexpected      = http3ErrCode(0x105)
+	http3ErrFrame                = http3ErrCode(0x106)
+	http3ErrExcessiveLoad        = http3ErrCode(0x107)
+	http3ErrID                   = http3ErrCode(0x108)
+	http3ErrSettings             = http3ErrCode(0x109)
+	http3ErrMissingSettings      = http3ErrCode(0x10a)
+	http3ErrRequestRejected      = http3ErrCode(0x10b)
+	http3ErrRequestCancelled     = http3ErrCode(0x10c)
+	http3ErrRequestIncomplete    = http3ErrCode(0x10d)
+	http3ErrMessage              = http3ErrCode(0x10e)
+	http3ErrConnect              = http3ErrCode(0x10f)
+	http3ErrVersionFallback      = http3ErrCode(0x110)
+
+	// QPACK errors (RFC 9204, Section 6).
+	http3ErrQPACKDecompressionFailed = http3ErrCode(0x200)
+)
+
+var http3ErrCodeName = map[http3ErrCode]string{
+	http3ErrNoError:                  "H3_NO_ERROR",
+	http3ErrGeneralProtocol:          "H3_GENERAL_PROTOCOL_ERROR",
+	http3ErrInternal:                 "H3_INTERNAL_ERROR",
+	http3ErrStreamCreation:           "H3_STREAM_CREATION_ERROR",
+	http3ErrClosedCriticalStream:     "H3_CLOSED_CRITICAL_STREAM",
+	http3ErrFrameUnexpected:          "H3_FRAME_UNEXPECTED",
+	http3ErrFrame:                    "H3_FRAME_ERROR",
+	http3ErrExcessiveLoad:            "H3_EXCESSIVE_LOAD",
+	http3ErrID:                       "H3_ID_ERROR",
+	http3ErrSettings:                 "H3_SETTINGS_ERROR",
+	http3ErrMissingSettings:          "H3_MISSING_SETTINGS",
+	http3ErrRequestRejected:          "H3_REQUEST_REJECTED",
+	http3ErrRequestCancelled:         "H3_REQUEST_CANCELLED",
+	http3ErrRequestIncomplete:        "H3_REQUEST_INCOMPLETE",
+	http3ErrMessage:                  "H3_MESSAGE_ERROR",
+	http3ErrConnect:                  "H3_CONNECT_ERROR",
+	http3ErrVersionFallback:          "H3_VERSION_FALLBACK",
+	http3ErrQPACKDecompressionFailed: "QPACK_DECOMPRESSION_FAILED",
+}
+
+func (e http3ErrCode) String() string {
+	if s, ok := http3ErrCodeName[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("H3 error 0x%x", uint64(e))
+}
+
+func (e http3ErrCode) Error() string {
+	return "http3: " + e.String()
+}
+
+// http3StreamError converts an error reading or writing a stream into
+// the error returned to the user.
+func http3StreamError(err error) error {
+	if code, ok := err.(quic.StreamErrorCode); ok {
+		return fmt.Errorf("http3: stream reset by peer: %v", http3ErrCode(code))
+	}
+	return err
+}
+
+// http3AppendVarint appends v as a QUIC variable-length integer.
+func http3AppendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// http3ReadVarint reads a QUIC variable-length integer.
+// It returns io.EOF only if no bytes were read, and
+// io.ErrUnexpectedEOF if the integer is truncated.
+func http3ReadVarint(r io.ByteReader) (uint64, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := 1 << (c >> 6)
+	v := uint64(c & 0x3f)
+	for i := 1; i < n; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// http3ConsumeVarint parses a QUIC variable-length integer from b.
+func http3ConsumeVarint(b []byte) (v uint64, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, nil, false
+	}
+	v = uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, b[n:], true
+}
+
+// http3AppendFrame appends a frame with the given type and payload.
+func http3AppendFrame(b []byte, ftype uint64, payload []byte) []byte {
+	b = http3AppendVarint(b, ftype)
+	b = http3AppendVarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+// http3AppendFrameHeader appends the header of a frame
+// with the given type and payload length.
+func http3AppendFrameHeader(b []byte, ftype uint64, length int) []byte {
+	b = http3AppendVarint(b, ftype)
+	return http3AppendVarint(b, uint64(length))
+}
+
+// http3ReadFrameHeader reads a frame type and length.
+// It returns io.EOF if the stream ends cleanly before the frame,
+// and http3ErrFrame if the stream ends inside the header.
+func http3ReadFrameHeader(br *bufio.Reader) (ftype, length uint64, err error) {
+	ftype, err = http3ReadVarint(br)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = http3ErrFrame
+		}
+		return 0, 0, err
+	}
+	length, err = http3ReadVarint(br)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = http3ErrFrame
+	}
+	return ftype, length, err
+}
+
+// http3ReadFramePayload reads a frame payload of the given length.
+// If the length exceeds max, it returns tooLarge.
+func http3ReadFramePayload(br *bufio.Reader, length uint64, max int64, tooLarge error) ([]byte, error) {
+	if length > uint64(max) {
+		return nil, tooLarge
+	}
+	p := make([]byte, length)
+	if _, err := io.ReadFull(br, p); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = http3ErrFrame
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// http3DiscardFrame skips the payload of a frame of unknown type.
+func http3DiscardFrame(br *bufio.Reader, length uint64) error {
+	for length > 0 {
+		n, err := br.Discard(int(min(length, 1<<20)))
+		length -= uint64(n)
+		if err != nil {
+			if err == io.EOF {
+				err = http3ErrFrame
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// http3MaxControlFrameSize limits the size of frames on the control stream.
+const http3MaxControlFrameSize = 16 << 10
+
+// http3Conn holds the state common to the client and server sides
+// of an HTTP/3 connection.
+type http3Conn struct {
+	qc *quic.Conn
+
+	// maxFieldSectionSize is the MAX_FIELD_SECTION_SIZE setting we send.
+	maxFieldSectionSize int64
+
+	// Set by the peer's control stream.
+	mu              sync.Mutex
+	peerMaxFieldSec int64 // peer's MAX_FIELD_SECTION_SIZE; -1 if unlimited
+	peerStreams     [4]bool
+	goawayID        int64 // stream or push ID from the peer's last GOAWAY
+	goaway          bool
+	onGoAway        func(id int64)
+
+	ctrl   *quic.Stream // our control stream
+	ctrlMu sync.Mutex   // serializes writes to ctrl
+}
+
+func (c *http3Conn) init(qc *quic.Conn, maxFieldSectionSize int64) {
+	c.qc = qc
+	c.maxFieldSectionSize = maxFieldSectionSize
+	c.peerMaxFieldSec = -1
+}
+
+// abort closes the connection with an HTTP/3 error code.
+func (c *http3Conn) abort(code http3ErrCode) {
+	c.qc.Abort(&quic.ApplicationError{Code: uint64(code)})
+}
+
+// http3CloseTimeout bounds how long close waits for the peer
+// to acknowledge data already written to streams.
+const http3CloseTimeout = 5 * time.Second
+
+// close closes the connection with H3_NO_ERROR once the peer has
+// received everything written to it, so that responses and requests
+// completed just before a graceful shutdown are not lost.
+func (c *http3Conn) close() {
+	c.qc.CloseGracefully(&quic.ApplicationError{Code: uint64(http3ErrNoError)}, http3CloseTimeout)
+}
+
+// abortErr closes the connection after a fatal error.
+// Errors which are HTTP/3 error codes are sent to the peer;
+// other errors abort the connection with H3_INTERNAL_ERROR.
+func (c *http3Conn) abortErr(err error) {
+	var code http3ErrCode
+	switch {
+	case errors.As(err, &code):
+	case err == errHTTP3QPACKDecompression:
+		code = http3ErrQPACKDecompressionFailed
+	default:
+		code = http3ErrInternal
+	}
+	c.abort(code)
+}
+
+// openControlStream creates our control stream and sends SETTINGS.
+func (c *http3Conn) openControlStream() error {
+	st, err := c.qc.NewSendOnlyStream(context.Background())
+	if err != nil {
+		return err
+	}
+	var settings []byte
+	settings = http3AppendVarint(settings, http3SettingMaxFieldSectionSize)
+	settings = http3AppendVarint(settings, uint64(c.maxFieldSectionSize))
+	// We use neither a dynamic table nor blocked streams, and leave
+	// QPACK_MAX_TABLE_CAPACITY and QPACK_BLOCKED_STREAMS at their default of 0.
+	b := http3AppendVarint(nil, http3StreamControl)
+	b = http3AppendFrame(b, http3FrameSettings, settings)
+	c.ctrl = st
+	_, err = st.Write(b)
+	return err
+}
+
+// writeGoAway sends a GOAWAY frame on the control stream.
+func (c *http3Conn) writeGoAway(id int64) error {
+	c.ctrlMu.Lock()
+	defer c.ctrlMu.Unlock()
+	if c.ctrl == nil {
+		return nil
+	}
+	_, err := c.ctrl.Write(http3AppendFrame(nil, http3FrameGoAway, http3AppendVarint(nil, uint64(id))))
+	return err
+}
+
+// handleUniStream reads a unidirectional stream created by the peer.
+func (c *http3Conn) handleUniStream(st *quic.Stream, isServer bool) {
+	br := bufio.NewReader(st)
+	stype, err := http3ReadVarint(br)
+	if err != nil {
+		// The stream was reset or closed before its type was sent.
+		st.CloseRead()
+		return
+	}
+	switch stype {
+	case http3StreamControl, http3StreamQPACKEncoder, http3StreamQPACKDecoder:
+		c.mu.Lock()
+		dup := c.peerStreams[stype]
+		c.peerStreams[stype] = true
+		c.mu.Unlock()
+		if dup {
+			// Only one of each of these streams is permitted
+			// (RFC 9114, Section 6.2.1; RFC 9204, Section 4.2).
+			c.abort(http3ErrStreamCreation)
+			return
+		}
+	case http3StreamPush:
+		if isServer {
+			// Only servers push.
+			c.abort(http3ErrStreamCreation)
+		} else {
+			// We never send MAX_PUSH_ID, so the server may not push.
+			c.abort(http3ErrID)
+		}
+		return
+	default:
+		// Unknown stream types are ignored (RFC 9114, Section 6.2).
+		st.StopSending(uint64(http3ErrStreamCreation))
+		return
+	}
+	if stype != http3StreamControl {
+		// With a dynamic table capacity of zero, the encoder stream carries
+		// at most a capacity of zero and the decoder stream nothing
+		// useful to us. The streams must remain open, however.
+		if _, err := io.Copy(io.Discard, br); err == nil {
+			c.abort(http3ErrClosedCriticalStream)
+		}
+		return
+	}
+	if err := c.readControlStream(br, isServer); err != nil {
+		c.abortErr(err)
+	}
+}
+
+// readControlStream reads frames from the peer's control stream.
+// It returns an error when the stream or connection ends.
+func (c *http3Conn) readControlStream(br *bufio.Reader, isServer bool) error {
+	first := true
+	for {
+		ftype, length, err := http3ReadFrameHeader(br)
+		if err == io.EOF {
+			return http3ErrClosedCriticalStream
+		}
+		if err != nil {
+			return err
+		}
+		if first != (ftype == http3FrameSettings) {
+			if first {
+				return http3ErrMissingSettings
+			}
+			return http3ErrFrameUnexpected
+		}
+		first = false
+		switch {
+		case ftype == http3FrameSettings:
+			p, err := http3ReadFramePayload(br, length, http3MaxControlFrameSize, http3ErrExcessiveLoad)
+			if err != nil {
+				return err
+			}
+			if err := c.handleSettings(p); err != nil {
+				return err
+			}
+		case ftype == http3FrameGoAway:
+			p, err := http3ReadFramePayload(br, length, 8, http3ErrFrame)
+			if err != nil {
+				return err
+			}
+			id, rest, ok := http3ConsumeVarint(p)
+			if !ok || len(rest) != 0 {
+				return http3ErrFrame
+			}
+			if err := c.handleGoAway(int64(id), isServer); err != nil {
+				return err
+			}
+		case ftype == http3FrameCancelPush, ftype == http3FrameMaxPushID:
+			if ftype == http3FrameMaxPushID && !isServer {
+				return http3ErrFrameUnexpected
+			}
+			// We never push and never permit the server to push,
+			// so these frames need no action.
+			if err := http3DiscardFrame(br, length); err != nil {
+				return err
+			}
+		case ftype == http3FrameData, ftype == http3FrameHeaders,
+			ftype == http3FramePushPromise, http3FrameReservedHTTP2(ftype):
+			return http3ErrFrameUnexpected
+		default:
+			if err := http3DiscardFrame(br, length); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *http3Conn) handleSettings(p []byte) error {
+	seen := make(map[uint64]bool)
+	for len(p) > 0 {
+		var id, v uint64
+		var ok bool
+		if id, p, ok = http3ConsumeVarint(p); !ok {
+			return http3ErrFrame
+		}
+		if v, p, ok = http3ConsumeVarint(p); !ok {
+			return http3ErrFrame
+		}
+		if seen[id] {
+			return http3ErrSettings
+		}
+		seen[id] = true
+		switch id {
+		case 0x02, 0x03, 0x04, 0x05:
+			// HTTP/2 settings with no HTTP/3 equivalent (RFC 9114, Section 7.2.4.1).
+			return http3ErrSettings
+		case http3SettingMaxFieldSectionSize:
+			c.mu.Lock()
+			c.peerMaxFieldSec = int64(min(v, 1<<62))
+			c.mu.Unlock()
+		}
+		// QPACK_MAX_TABLE_CAPACITY and QPACK_BLOCKED_STREAMS describe
+		// the peer's decoder. Our encoder doesn't use the dynamic table,
+		// so they don't affect us. Unknown settings are ignored.
+	}
+	return nil
+}
+
+func (c *http3Conn) handleGoAway(id int64, isServer bool) error {
+	c.mu.Lock()
+	if !isServer && id%4 != 0 {
+		// A server's GOAWAY carries a client-initiated bidirectional stream ID.
+		c.mu.Unlock()
+		return http3ErrID
+	}
+	if c.goaway && id > c.goawayID {
+		// The identifier in successive GOAWAY frames may not increase.
+		c.mu.Unlock()
+		return http3ErrID
+	}
+	c.goaway = true
+	c.goawayID = id
+	f := c.onGoAway
+	c.mu.Unlock()
+	if f != nil {
+		f(id)
+	}
+	return nil
+}
+
+// fieldSectionFits reports whether a field section of the given size
+// (computed as in RFC 9114, Section 4.2.2) fits within the peer's limit.
+func (c *http3Conn) fieldSectionFits(size int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peerMaxFieldSec < 0 || size <= c.peerMaxFieldSec
+}
+
+// http3Body is the body of a request or response received on an HTTP/3 stream.
+// It reads the DATA frames on the stream, and the trailers in an optional
+// HEADERS frame following them.
+type http3Body struct {
+	conn *http3Conn
+	st   *quic.Stream
+	br   *bufio.Reader
+
+	// trailer is where received trailers are stored.
+	trailer        *Header
+	maxTrailerSize int64
+
+	contentLength int64 // -1 if unknown
+	read          int64
+	remain        uint64 // bytes left in the current DATA frame
+	err           error  // sticky read error
+
+	closed atomic.Bool
+	once   sync.Once
+	onDone func(err error) // called once, when the body is finished or closed
+}
+
+var errHTTP3ContentLength = errors.New("http3: body length does not match Content-Length")
+
+func (b *http3Body) Read(p []byte) (int, error) {
+	if b.closed.Load() {
+		return 0, ErrBodyReadAfterClose
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	for b.remain == 0 {
+		ftype, length, err := http3ReadFrameHeader(b.br)
+		if err == io.EOF {
+			return 0, b.finish(b.checkLength(true))
+		}
+		if err != nil {
+			return 0, b.finish(err)
+		}
+		switch {
+		case ftype == http3FrameData:
+			b.remain = length
+		case ftype == http3FrameHeaders:
+			return 0, b.finish(b.readTrailers(length))
+		case ftype == http3FrameSettings, ftype == http3FrameGoAway,
+			ftype == http3FrameMaxPushID, ftype == http3FrameCancelPush,
+			ftype == http3FramePushPromise, http3FrameReservedHTTP2(ftype):
+			return 0, b.finish(http3ErrFrameUnexpected)
+		default:
+			if err := http3DiscardFrame(b.br, length); err != nil {
+				return 0, b.finish(err)
+			}
+		}
+	}
+	if uint64(len(p)) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err := b.br.Read(p)
+	b.remain -= uint64(n)
+	b.read += int64(n)
+	if err == io.EOF {
+		// The stream ended inside a DATA frame.
+		err = http3ErrFrame
+	}
+	if err == nil {
+		err = b.checkLength(false)
+	}
+	if err != nil {
+		return n, b.finish(err)
+	}
+	return n, nil
+}
+
+// checkLength verifies that the body length is consistent
+// with its declared Content-Length.
+func (b *http3Body) checkLength(atEOF bool) error {
+	if b.contentLength < 0 {
+		if atEOF {
+			return io.EOF
+		}
+		return nil
+	}
+	if b.read > b.contentLength || (atEOF && b.read != b.contentLength) {
+		return errHTTP3ContentLength
+	}
+	if atEOF {
+		return io.EOF
+	}
+	return nil
+}
+
+func (b *http3Body) readTrailers(length uint64) error {
+	if err := b.checkLength(true); err != io.EOF {
+		return err
+	}
+	p, err := http3ReadFramePayload(b.br, length, b.maxTrailerSize, errHTTP3HeaderTooLarge)
+	if err != nil {
+		return err
+	}
+	trailer := make(Header)
+	err = http3DecodeFieldSection(p, b.maxTrailerSize, func(name, value string) error {
+		if len(name) > 0 && name[0] == ':' {
+			// Pseudo-headers are not permitted in trailers.
+			return http3ErrMessage
+		}
+		if !isHTTP3FieldName(name) {
+			return http3ErrMessage
+		}
+		trailer.Add(CanonicalHeaderKey(name), value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// No frames may follow the trailers.
+	if _, err := b.br.Peek(1); err != io.EOF {
+		if err == nil {
+			err = http3ErrFrameUnexpected
+		}
+		return err
+	}
+	if *b.trailer == nil {
+		*b.trailer = make(Header)
+	}
+	for k, vv := range trailer {
+		(*b.trailer)[k] = vv
+	}
+	return io.EOF
+}
+
+// finish records the final result of reading the body.
+// Protocol errors are reported to the peer.
+func (b *http3Body) finish(err error) error {
+	var code http3ErrCode
+	switch {
+	case err == io.EOF:
+	case err == errHTTP3ContentLength, err == errHTTP3HeaderTooLarge, err == http3ErrMessage:
+		// Malformed message (RFC 9114, Section 4.1.2).
+		b.st.StopSending(uint64(http3ErrMessage))
+		b.st.Reset(uint64(http3ErrMessage))
+		if err == http3ErrMessage {
+			err = errors.New("http3: malformed trailers")
+		}
+	case errors.As(err, &code), err == errHTTP3QPACKDecompression:
+		b.conn.abortErr(err)
+	default:
+		err = http3StreamError(err)
+	}
+	b.err = err
+	b.done(err)
+	return err
+}
+
+func (b *http3Body) done(err error) {
+	b.once.Do(func() {
+		if b.onDone != nil {
+			b.onDone(err)
+		}
+	})
+}
+
+// Close aborts reading the body if it has not been read to completion.
+func (b *http3Body) Close() error {
+	if b.closed.Swap(true) {
+		return nil
+	}
+	b.done(errHTTP3BodyClosed)
+	return nil
+}
+
+var errHTTP3BodyClosed = errors.New("http3: body closed")
+
+// isHTTP3FieldName reports whether name is a valid lowercase field name.
+func isHTTP3FieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if 'A' <= c && c <= 'Z' {
+			return false
+		}
+		if !httpguts.IsTokenRune(rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// http3GzipReader wraps a response body so it can lazily
+// call gzip.NewReader on the first call to Read.
+type http3GzipReader struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	zerr error
+}
+
+func (gz *http3GzipReader) Read(p []byte) (int, error) {
+	if gz.zerr != nil {
+		return 0, gz.zerr
+	}
+	if gz.zr == nil {
+		gz.zr, gz.zerr = gzip.NewReader(gz.body)
+		if gz.zerr != nil {
+			return 0, gz.zerr
+		}
+	}
+	return gz.zr.Read(p)
+}
+
+func (gz *http3GzipReader) Close() error {
+	if err := gz.body.Close(); err != nil {
+		return err
+	}
+	gz.zerr = fs.ErrClosed
+	return nil
+}
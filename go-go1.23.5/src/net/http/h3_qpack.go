@@ -0,0 +1,312 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// This file implements the subset of QPACK (RFC 9204) used by HTTP/3:
+// field sections are encoded and decoded using only the static table.
+// We advertise a dynamic table capacity of zero, so a peer may not
+// use the dynamic table and its encoder and decoder streams carry
+// no instructions we need to act on.
+
+// http3StaticTable is the QPACK static table (RFC 9204, Appendix A).
+var http3StaticTable = [...]struct{ name, value string }{
+	{":authority", ""},
+	{":path", "/"},
+	{"age", "0"},
+	{"content-disposition", ""},
+	{"content-length", "0"},
+	{"cookie", ""},
+	{"date", ""},
+	{"etag", ""},
+	{"if-modified-since", ""},
+	{"if-none-match", ""},
+	{"last-modified", ""},
+	{"link", ""},
+	{"location", ""},
+	{"referer", ""},
+	{"set-cookie", ""},
+	{":method", "CONNECT"},
+	{":method", "DELETE"},
+	{":method", "GET"},
+	{":method", "HEAD"},
+	{":method", "OPTIONS"},
+	{":method", "POST"},
+	{":method", "PUT"},
+	{":scheme", "http"},
+	{":scheme", "https"},
+	{":status", "103"},
+	{":status", "200"},
+	{":status", "304"},
+	{":status", "404"},
+	{":status", "503"},
+	{"accept", "*/*"},
+	{"accept", "application/dns-message"},
+	{"accept-encoding", "gzip, deflate, br"},
+	{"accept-ranges", "bytes"},
+	{"access-control-allow-headers", "cache-control"},
+	{"access-control-allow-headers", "content-type"},
+	{"access-control-allow-origin", "*"},
+	{"cache-control", "max-age=0"},
+	{"cache-control", "max-age=2592000"},
+	{"cache-control", "max-age=604800"},
+	{"cache-control", "no-cache"},
+	{"cache-control", "no-store"},
+	{"cache-control", "public, max-age=31536000"},
+	{"content-encoding", "br"},
+	{"content-encoding", "gzip"},
+	{"content-type", "application/dns-message"},
+	{"content-type", "application/javascript"},
+	{"content-type", "application/json"},
+	{"content-type", "application/x-www-form-urlencoded"},
+	{"content-type", "image/gif"},
+	{"content-type", "image/jpeg"},
+	{"content-type", "image/png"},
+	{"content-type", "text/css"},
+	{"content-type", "text/html; charset=utf-8"},
+	{"content-type", "text/plain"},
+	{"content-type", "text/plain;charset=utf-8"},
+	{"range", "bytes=0-"},
+	{"strict-transport-security", "max-age=31536000"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains; preload"},
+	{"vary", "accept-encoding"},
+	{"vary", "origin"},
+	{"x-content-type-options", "nosniff"},
+	{"x-xss-protection", "1; mode=block"},
+	{":status", "100"},
+	{":status", "204"},
+	{":status", "206"},
+	{":status", "302"},
+	{":status", "400"},
+	{":status", "403"},
+	{":status", "421"},
+	{":status", "425"},
+	{":status", "500"},
+	{"accept-language", ""},
+	{"access-control-allow-credentials", "FALSE"},
+	{"access-control-allow-credentials", "TRUE"},
+	{"access-control-allow-headers", "*"},
+	{"access-control-allow-methods", "get"},
+	{"access-control-allow-methods", "get, post, options"},
+	{"access-control-allow-methods", "options"},
+	{"access-control-expose-headers", "content-length"},
+	{"access-control-request-headers", "content-type"},
+	{"access-control-request-method", "get"},
+	{"access-control-request-method", "post"},
+	{"alt-svc", "clear"},
+	{"authorization", ""},
+	{"content-security-policy", "script-src 'none'; object-src 'none'; base-uri 'none'"},
+	{"early-data", "1"},
+	{"expect-ct", ""},
+	{"forwarded", ""},
+	{"if-range", ""},
+	{"origin", ""},
+	{"purpose", "prefetch"},
+	{"server", ""},
+	{"timing-allow-origin", "*"},
+	{"upgrade-insecure-requests", "1"},
+	{"user-agent", ""},
+	{"x-forwarded-for", ""},
+	{"x-frame-options", "deny"},
+	{"x-frame-options", "sameorigin"},
+}
+
+// http3StaticIndex maps names and name/value pairs to static table indices.
+var http3StaticIndex = sync.OnceValues(func() (byName map[string]int, byField map[[2]string]int) {
+	byName = make(map[string]int)
+	byField = make(map[[2]string]int)
+	for i, f := range http3StaticTable {
+		if _, ok := byName[f.name]; !ok {
+			byName[f.name] = i
+		}
+		byField[[2]string{f.name, f.value}] = i
+	}
+	return byName, byField
+})
+
+var (
+	errHTTP3QPACKDecompression = errors.New("http3: QPACK decompression failed")
+	errHTTP3HeaderTooLarge     = errors.New("http3: header too large")
+)
+
+// http3AppendPrefixedInt appends v encoded as an integer with an
+// n-bit prefix (RFC 7541, Section 5.1), with the high bits of the
+// first byte set to first.
+func http3AppendPrefixedInt(b []byte, first byte, n uint, v uint64) []byte {
+	limit := uint64(1)<<n - 1
+	if v < limit {
+		return append(b, first|byte(v))
+	}
+	b = append(b, first|byte(limit))
+	v -= limit
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// http3ReadPrefixedInt reads an integer with an n-bit prefix from b.
+func http3ReadPrefixedInt(b []byte, n uint) (v uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, errHTTP3QPACKDecompression
+	}
+	limit := uint64(1)<<n - 1
+	v = uint64(b[0]) & limit
+	b = b[1:]
+	if v < limit {
+		return v, b, nil
+	}
+	for shift := uint(0); shift < 63; shift += 7 {
+		if len(b) == 0 {
+			return 0, nil, errHTTP3QPACKDecompression
+		}
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+	}
+	return 0, nil, errHTTP3QPACKDecompression
+}
+
+// http3AppendString appends a string literal whose length has an
+// n-bit prefix, with huffman flag hbit, Huffman encoding it if shorter.
+func http3AppendString(b []byte, first byte, n uint, hbit byte, s string) []byte {
+	if hl := hpack.HuffmanEncodeLength(s); hl < uint64(len(s)) {
+		b = http3AppendPrefixedInt(b, first|hbit, n, hl)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = http3AppendPrefixedInt(b, first, n, uint64(len(s)))
+	return append(b, s...)
+}
+
+func http3ReadString(b []byte, n uint, hbit byte) (s string, rest []byte, err error) {
+	if len(b) == 0 {
+		return "", nil, errHTTP3QPACKDecompression
+	}
+	huffman := b[0]&hbit != 0
+	length, b, err := http3ReadPrefixedInt(b, n)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(b)) < length {
+		return "", nil, errHTTP3QPACKDecompression
+	}
+	raw := b[:length]
+	b = b[length:]
+	if !huffman {
+		return string(raw), b, nil
+	}
+	s, err = hpack.HuffmanDecodeToString(raw)
+	if err != nil {
+		return "", nil, errHTTP3QPACKDecompression
+	}
+	return s, b, nil
+}
+
+// http3AppendFieldSectionPrefix appends the prefix of a field section
+// which does not reference the dynamic table.
+func http3AppendFieldSectionPrefix(b []byte) []byte {
+	// Required Insert Count = 0, Sign = 0, Delta Base = 0.
+	return append(b, 0, 0)
+}
+
+// http3AppendField appends a field line. The name must be lowercase.
+func http3AppendField(b []byte, name, value string) []byte {
+	byName, byField := http3StaticIndex()
+	if i, ok := byField[[2]string{name, value}]; ok {
+		// Indexed field line, static table (RFC 9204, Section 4.5.2).
+		return http3AppendPrefixedInt(b, 0xc0, 6, uint64(i))
+	}
+	if i, ok := byName[name]; ok {
+		// Literal field line with name reference,
+		// static table (RFC 9204, Section 4.5.4).
+		b = http3AppendPrefixedInt(b, 0x50, 4, uint64(i))
+		return http3AppendString(b, 0, 7, 0x80, value)
+	}
+	// Literal field line with literal name (RFC 9204, Section 4.5.6).
+	b = http3AppendString(b, 0x20, 3, 0x08, name)
+	return http3AppendString(b, 0, 7, 0x80, value)
+}
+
+// http3DecodeFieldSection decodes a field section, calling f for each field.
+// It returns errHTTP3HeaderTooLarge if the decoded size of the fields,
+// computed as in RFC 9114, Section 4.2.2, exceeds maxSize.
+func http3DecodeFieldSection(b []byte, maxSize int64, f func(name, value string) error) error {
+	ric, b, err := http3ReadPrefixedInt(b, 8)
+	if err != nil {
+		return err
+	}
+	if ric != 0 {
+		// We never allow the peer to use the dynamic table.
+		return errHTTP3QPACKDecompression
+	}
+	if _, b, err = http3ReadPrefixedInt(b, 7); err != nil {
+		return err
+	}
+	var size int64
+	for len(b) > 0 {
+		var name, value string
+		switch c := b[0]; {
+		case c&0x80 != 0:
+			// Indexed field line.
+			if c&0x40 == 0 {
+				return errHTTP3QPACKDecompression // dynamic table
+			}
+			var i uint64
+			if i, b, err = http3ReadPrefixedInt(b, 6); err != nil {
+				return err
+			}
+			if i >= uint64(len(http3StaticTable)) {
+				return errHTTP3QPACKDecompression
+			}
+			name, value = http3StaticTable[i].name, http3StaticTable[i].value
+		case c&0xc0 == 0x40:
+			// Literal field line with name reference.
+			if c&0x10 == 0 {
+				return errHTTP3QPACKDecompression // dynamic table
+			}
+			var i uint64
+			if i, b, err = http3ReadPrefixedInt(b, 4); err != nil {
+				return err
+			}
+			if i >= uint64(len(http3StaticTable)) {
+				return errHTTP3QPACKDecompression
+			}
+			name = http3StaticTable[i].name
+			if value, b, err = http3ReadString(b, 7, 0x80); err != nil {
+				return err
+			}
+		case c&0xe0 == 0x20:
+			// Literal field line with literal name.
+			if name, b, err = http3ReadString(b, 3, 0x08); err != nil {
+				return err
+			}
+			if value, b, err = http3ReadString(b, 7, 0x80); err != nil {
+				return err
+			}
+		default:
+			// Post-base indexed forms reference the dynamic table.
+			return errHTTP3QPACKDecompression
+		}
+		size += int64(len(name) + len(value) + 32)
+		if size > maxSize {
+			return errHTTP3HeaderTooLarge
+		}
+		if err := f(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
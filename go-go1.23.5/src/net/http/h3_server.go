@@ -0,0 +1,832 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 server implementation.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http/internal/ascii"
+	"net/http/internal/quic"
+	"net/textproto"
+	"net/url"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ServeQUIC accepts incoming HTTP/3 connections on the packet connection pc,
+// creating a new service goroutine for each. The service goroutines
+// read requests and then call srv.Handler to reply to them.
+//
+// Support for HTTP/3 is experimental.
+//
+// Files containing a certificate and matching private key for the
+// server must be provided if neither the [Server]'s
+// TLSConfig.Certificates, TLSConfig.GetCertificate nor
+// config.GetConfigForClient are populated. The TLSConfig's NextProtos
+// are replaced with "h3".
+//
+// The Server's BaseContext hook, if any, is passed a [net.Listener]
+// whose Addr method returns pc's local address, and whose Accept
+// method always fails.
+//
+// ServeQUIC takes ownership of pc, and closes it after the server is shut
+// down and all its HTTP/3 connections have closed. ServeQUIC always returns
+// a non-nil error. After [Server.Shutdown] or [Server.Close], the returned
+// error is [ErrServerClosed].
+func (srv *Server) ServeQUIC(pc net.PacketConn, certFile, keyFile string) error {
+	config := cloneTLSConfig(srv.TLSConfig)
+	config.NextProtos = []string{http3NextProto}
+	config.MinVersion = tls.VersionTLS13
+
+	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil || config.GetConfigForClient != nil
+	if !configHasCert || certFile != "" || keyFile != "" {
+		var err error
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			pc.Close()
+			return err
+		}
+	}
+
+	ep := quic.NewEndpoint(pc, &quic.Config{TLSConfig: config})
+	ctx, cancel := context.WithCancel(context.Background())
+	var l net.Listener = &http3Listener{ep: ep, cancel: cancel}
+	var wg sync.WaitGroup
+	defer func() {
+		// Closing the endpoint closes its connections,
+		// so wait for them to finish first.
+		go func() {
+			wg.Wait()
+			closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Second)
+			defer closeCancel()
+			ep.Close(closeCtx)
+		}()
+	}()
+
+	if !srv.trackListener(&l, true) {
+		return ErrServerClosed
+	}
+	defer srv.trackListener(&l, false)
+
+	baseCtx := context.Background()
+	if srv.BaseContext != nil {
+		baseCtx = srv.BaseContext(l)
+		if baseCtx == nil {
+			panic("BaseContext returned a nil context")
+		}
+	}
+	baseCtx = context.WithValue(baseCtx, ServerContextKey, srv)
+	baseCtx = context.WithValue(baseCtx, LocalAddrContextKey, pc.LocalAddr())
+	for {
+		qc, err := ep.Accept(ctx)
+		if err != nil {
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		sc := newHTTP3ServerConn(srv, qc, baseCtx)
+		if !srv.trackHTTP3Conn(sc, true) {
+			sc.abort(http3ErrNoError)
+			return ErrServerClosed
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer srv.trackHTTP3Conn(sc, false)
+			sc.serve()
+		}()
+	}
+}
+
+// ListenAndServeQUIC listens on the UDP network address srv.Addr and
+// then calls [Server.ServeQUIC] to handle HTTP/3 requests.
+//
+// Support for HTTP/3 is experimental.
+//
+// Filenames containing a certificate and matching private key for the
+// server must be provided if neither the [Server]'s TLSConfig.Certificates
+// nor TLSConfig.GetCertificate are populated.
+//
+// If srv.Addr is blank, ":https" is used.
+//
+// ListenAndServeQUIC always returns a non-nil error. After [Server.Shutdown] or
+// [Server.Close], the returned error is [ErrServerClosed].
+func (srv *Server) ListenAndServeQUIC(certFile, keyFile string) error {
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.ServeQUIC(pc, certFile, keyFile)
+}
+
+// trackHTTP3Conn adds or removes an HTTP/3 connection to the set of
+// tracked connections. When adding, it reports whether the server
+// is still up (not Shutdown or Closed).
+func (s *Server) trackHTTP3Conn(sc *http3ServerConn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.h3Conns, sc)
+		return true
+	}
+	if s.shuttingDown() {
+		return false
+	}
+	if s.h3Conns == nil {
+		s.h3Conns = make(map[*http3ServerConn]struct{})
+	}
+	s.h3Conns[sc] = struct{}{}
+	return true
+}
+
+// http3Listener is the net.Listener tracked by a Server for ServeQUIC,
+// so that closing the server's listeners stops accepting new connections.
+type http3Listener struct {
+	ep     *quic.Endpoint
+	cancel context.CancelFunc
+}
+
+func (l *http3Listener) Accept() (net.Conn, error) {
+	return nil, errors.New("http3: Accept not supported on QUIC listener")
+}
+
+func (l *http3Listener) Close() error {
+	l.cancel()
+	return nil
+}
+
+func (l *http3Listener) Addr() net.Addr { return l.ep.LocalAddr() }
+
+// http3ServerConn is a server connection to an HTTP/3 client.
+type http3ServerConn struct {
+	http3Conn
+	srv        *Server
+	ctx        context.Context
+	cancelCtx  context.CancelFunc
+	tlsState   *tls.ConnectionState
+	remoteAddr string
+
+	reqMu     sync.Mutex // guards the fields below
+	active    int        // requests being handled
+	nextID    int64      // stream ID after the highest accepted request stream
+	goingAway bool       // GOAWAY sent
+	goAwayID  int64      // first stream ID rejected after GOAWAY
+	idleTimer *time.Timer
+}
+
+func newHTTP3ServerConn(srv *Server, qc *quic.Conn, baseCtx context.Context) *http3ServerConn {
+	sc := &http3ServerConn{
+		srv:        srv,
+		remoteAddr: qc.RemoteAddr().String(),
+	}
+	sc.init(qc, int64(srv.maxHeaderBytes()))
+	state := qc.ConnectionState()
+	sc.tlsState = &state
+	sc.ctx, sc.cancelCtx = context.WithCancel(baseCtx)
+	return sc
+}
+
+func (sc *http3ServerConn) serve() {
+	defer sc.cancelCtx()
+	if err := sc.openControlStream(); err != nil {
+		sc.abort(http3ErrInternal)
+		return
+	}
+	sc.startIdleTimer()
+	for {
+		st, err := sc.qc.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		if st.IsReadOnly() {
+			go sc.handleUniStream(st, true)
+			continue
+		}
+		if !sc.startRequest(st.ID()) {
+			// Requests on streams after a GOAWAY are rejected
+			// and may be retried by the client (RFC 9114, Section 5.2).
+			st.StopSending(uint64(http3ErrRequestRejected))
+			st.Reset(uint64(http3ErrRequestRejected))
+			continue
+		}
+		go sc.serveRequest(st, time.Now())
+	}
+}
+
+// startRequest records the start of a request on the stream with the given ID.
+// It reports false if the request must be rejected.
+func (sc *http3ServerConn) startRequest(id int64) bool {
+	sc.reqMu.Lock()
+	defer sc.reqMu.Unlock()
+	if sc.goingAway && id >= sc.goAwayID {
+		return false
+	}
+	sc.active++
+	sc.nextID = max(sc.nextID, id+4)
+	if sc.idleTimer != nil {
+		sc.idleTimer.Stop()
+		sc.idleTimer = nil
+	}
+	return true
+}
+
+// finishRequest records the end of a request started with startRequest.
+func (sc *http3ServerConn) finishRequest() {
+	sc.reqMu.Lock()
+	sc.active--
+	idle := sc.active == 0
+	goingAway := sc.goingAway
+	sc.reqMu.Unlock()
+	switch {
+	case idle && goingAway:
+		sc.close()
+	case idle:
+		sc.startIdleTimer()
+	}
+}
+
+func (sc *http3ServerConn) startIdleTimer() {
+	d := sc.srv.idleTimeout()
+	if d <= 0 {
+		return
+	}
+	sc.reqMu.Lock()
+	defer sc.reqMu.Unlock()
+	if sc.active == 0 && sc.idleTimer == nil {
+		sc.idleTimer = time.AfterFunc(d, func() { sc.closeIfIdle() })
+	}
+}
+
+// closeIfIdle sends a GOAWAY to the client, if it has not already been sent,
+// and closes the connection if no requests are in flight.
+// It reports whether the connection was closed.
+func (sc *http3ServerConn) closeIfIdle() bool {
+	sc.reqMu.Lock()
+	if !sc.goingAway {
+		sc.goingAway = true
+		sc.goAwayID = sc.nextID
+		go sc.writeGoAway(sc.goAwayID)
+	}
+	idle := sc.active == 0
+	sc.reqMu.Unlock()
+	if idle {
+		sc.close()
+	}
+	return idle
+}
+
+// http3ResetStream aborts both sides of a request stream.
+func http3ResetStream(st *quic.Stream, code http3ErrCode) {
+	st.StopSending(uint64(code))
+	st.Reset(uint64(code))
+}
+
+// serveRequest reads a request from a stream and serves it.
+// The start time is the time the stream was accepted.
+func (sc *http3ServerConn) serveRequest(st *quic.Stream, start time.Time) {
+	defer sc.finishRequest()
+	st.SetReadContext(sc.ctx)
+	st.SetWriteContext(sc.ctx)
+	br := bufio.NewReader(st)
+	maxHeaderBytes := int64(sc.srv.maxHeaderBytes())
+
+	var p []byte
+	for p == nil {
+		ftype, length, err := http3ReadFrameHeader(br)
+		switch {
+		case err == io.EOF:
+			http3ResetStream(st, http3ErrRequestIncomplete)
+			return
+		case err != nil:
+			sc.requestStreamError(st, err)
+			return
+		case ftype == http3FrameHeaders:
+			p, err = http3ReadFramePayload(br, length, maxHeaderBytes, errHTTP3HeaderTooLarge)
+			if err == errHTTP3HeaderTooLarge {
+				sc.writeErrorResponse(st, StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+			if err != nil {
+				sc.requestStreamError(st, err)
+				return
+			}
+		case ftype == http3FrameData, ftype == http3FrameSettings, ftype == http3FrameGoAway,
+			ftype == http3FrameMaxPushID, ftype == http3FrameCancelPush,
+			ftype == http3FramePushPromise, http3FrameReservedHTTP2(ftype):
+			sc.abort(http3ErrFrameUnexpected)
+			return
+		default:
+			if err := http3DiscardFrame(br, length); err != nil {
+				sc.requestStreamError(st, err)
+				return
+			}
+		}
+	}
+
+	req, err := sc.newRequest(st, br, p, maxHeaderBytes)
+	switch {
+	case err == errHTTP3HeaderTooLarge:
+		sc.writeErrorResponse(st, StatusRequestHeaderFieldsTooLarge)
+		return
+	case err == errHTTP3QPACKDecompression:
+		sc.abortErr(err)
+		return
+	case err != nil:
+		// Malformed request (RFC 9114, Section 4.1.2).
+		http3ResetStream(st, http3ErrMessage)
+		return
+	}
+	ctx, cancel := context.WithCancel(sc.ctx)
+	defer cancel()
+	req.ctx = ctx
+
+	rw := &http3ResponseWriter{
+		sc:            sc,
+		st:            st,
+		req:           req,
+		handlerHeader: make(Header),
+		contentLength: -1,
+		cancelCtx:     cancel,
+	}
+	if body, ok := req.Body.(*http3Body); ok && req.expectsContinue() {
+		req.Body = &http3ExpectContinueReader{rw: rw, body: body}
+	}
+	ok := sc.runHandler(rw, req)
+	if ok {
+		rw.finish()
+	}
+	sc.srv.requestDone(req, RequestStats{
+		StatusCode:   rw.status,
+		BytesWritten: rw.written,
+		Start:        start,
+		Panicked:     !ok,
+	})
+	// RFC 9114, Section 4.1: a server may abort reading the request
+	// with H3_NO_ERROR once it has sent a complete response.
+	st.StopSending(uint64(http3ErrNoError))
+}
+
+// runHandler calls the server's handler.
+// It reports false if the handler panicked.
+func (sc *http3ServerConn) runHandler(rw *http3ResponseWriter, req *Request) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			if err != ErrAbortHandler {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				sc.srv.logf("http: panic serving %v: %v\n%s", sc.remoteAddr, err, buf)
+			}
+			http3ResetStream(rw.st, http3ErrInternal)
+			ok = false
+		}
+	}()
+	serverHandler{sc.srv}.ServeHTTP(rw, req)
+	return true
+}
+
+// requestStreamError handles an error reading a request header.
+func (sc *http3ServerConn) requestStreamError(st *quic.Stream, err error) {
+	var code http3ErrCode
+	if errors.As(err, &code) {
+		sc.abortErr(err)
+		return
+	}
+	// The stream was reset by the client, or the connection closed.
+	http3ResetStream(st, http3ErrRequestCancelled)
+}
+
+// writeErrorResponse sends a response with no body and closes the stream.
+func (sc *http3ServerConn) writeErrorResponse(st *quic.Stream, code int) {
+	b := http3AppendFieldSectionPrefix(nil)
+	b = http3AppendField(b, ":status", strconv.Itoa(code))
+	st.Write(http3AppendFrame(nil, http3FrameHeaders, b))
+	st.CloseWrite()
+	st.StopSending(uint64(http3ErrNoError))
+}
+
+var errHTTP3MalformedRequest = errors.New("http3: malformed request header")
+
+// newRequest creates a Request from a decoded HEADERS frame.
+func (sc *http3ServerConn) newRequest(st *quic.Stream, br *bufio.Reader, p []byte, maxHeaderBytes int64) (*Request, error) {
+	var method, scheme, authority, rawPath string
+	var seen [4]bool
+	header := make(Header)
+	regular := false
+	err := http3DecodeFieldSection(p, maxHeaderBytes, func(name, value string) error {
+		if strings.HasPrefix(name, ":") {
+			if regular {
+				return errHTTP3MalformedRequest
+			}
+			var i int
+			var dst *string
+			switch name {
+			case ":method":
+				i, dst = 0, &method
+			case ":scheme":
+				i, dst = 1, &scheme
+			case ":authority":
+				i, dst = 2, &authority
+			case ":path":
+				i, dst = 3, &rawPath
+			default:
+				return errHTTP3MalformedRequest
+			}
+			if seen[i] {
+				return errHTTP3MalformedRequest
+			}
+			seen[i] = true
+			*dst = value
+			return nil
+		}
+		regular = true
+		if !isHTTP3FieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return errHTTP3MalformedRequest
+		}
+		if slices.Contains(http3ConnectionHeaders, name) {
+			return errHTTP3MalformedRequest
+		}
+		if name == "te" && value != "trailers" {
+			return errHTTP3MalformedRequest
+		}
+		key := CanonicalHeaderKey(name)
+		header[key] = append(header[key], value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isConnect := method == "CONNECT"
+	switch {
+	case !seen[0] || !validMethod(method):
+		return nil, errHTTP3MalformedRequest
+	case isConnect && (seen[1] || seen[3] || authority == ""):
+		// Extended CONNECT is not supported.
+		return nil, errHTTP3MalformedRequest
+	case !isConnect && (scheme == "" || !validPseudoPath(rawPath)):
+		return nil, errHTTP3MalformedRequest
+	}
+
+	var u *url.URL
+	var requestURI string
+	if isConnect {
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		u, err = url.ParseRequestURI(rawPath)
+		if err != nil {
+			return nil, errHTTP3MalformedRequest
+		}
+		requestURI = rawPath
+	}
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	header.Del("Host")
+
+	// RFC 9114, Section 4.2.1: multiple cookie field lines
+	// are concatenated into a single field line.
+	if cookies := header["Cookie"]; len(cookies) > 1 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	var trailer Header
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = CanonicalHeaderKey(textproto.TrimString(key))
+			switch key {
+			case "Transfer-Encoding", "Trailer", "Content-Length":
+				// Bogus. (copy of http1 rules)
+				// Ignore.
+			default:
+				if trailer == nil {
+					trailer = make(Header)
+				}
+				trailer[key] = nil
+			}
+		}
+	}
+	delete(header, "Trailer")
+
+	contentLength := int64(-1)
+	if clens := header["Content-Length"]; len(clens) > 0 {
+		cl, err := strconv.ParseUint(clens[0], 10, 63)
+		if err != nil || len(clens) > 1 {
+			return nil, errHTTP3MalformedRequest
+		}
+		contentLength = int64(cl)
+	}
+
+	req := &Request{
+		Method:     method,
+		URL:        u,
+		RemoteAddr: sc.remoteAddr,
+		Header:     header,
+		RequestURI: requestURI,
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		ProtoMinor: 0,
+		TLS:        sc.tlsState,
+		Host:       authority,
+		Trailer:    trailer,
+	}
+	if contentLength == 0 || (contentLength < 0 && br.Buffered() == 0 && st.AtEOF()) {
+		req.ContentLength = 0
+		req.Body = NoBody
+		return req, nil
+	}
+	req.ContentLength = contentLength
+	req.Body = &http3Body{
+		conn:           &sc.http3Conn,
+		st:             st,
+		br:             br,
+		trailer:        &req.Trailer,
+		maxTrailerSize: maxHeaderBytes,
+		contentLength:  contentLength,
+	}
+	return req, nil
+}
+
+// http3ExpectContinueReader sends a 100 Continue response
+// on the first read of a request body.
+type http3ExpectContinueReader struct {
+	rw   *http3ResponseWriter
+	body *http3Body
+	sent bool
+}
+
+func (r *http3ExpectContinueReader) Read(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		if !r.rw.sentHeader {
+			r.rw.writeInformational(StatusContinue)
+		}
+	}
+	return r.body.Read(p)
+}
+
+func (r *http3ExpectContinueReader) Close() error {
+	return r.body.Close()
+}
+
+// http3ResponseBufferSize is the amount of response body buffered
+// before the response header is sent.
+const http3ResponseBufferSize = 4 << 10
+
+// http3ResponseWriter is the ResponseWriter for HTTP/3 requests.
+type http3ResponseWriter struct {
+	sc        *http3ServerConn
+	st        *quic.Stream
+	req       *Request
+	cancelCtx context.CancelFunc
+
+	handlerHeader Header // the handler's view of the header
+	trailers      []string
+	status        int  // status passed to WriteHeader
+	wroteHeader   bool // WriteHeader was called
+	sentHeader    bool // the HEADERS frame was sent
+	handlerDone   bool
+	contentLength int64 // from the Content-Length header, or -1
+	written       int64 // body bytes written by the handler
+	buf           []byte
+	err           error // sticky write error
+}
+
+var (
+	_ Flusher         = (*http3ResponseWriter)(nil)
+	_ io.StringWriter = (*http3ResponseWriter)(nil)
+)
+
+func (rw *http3ResponseWriter) Header() Header {
+	return rw.handlerHeader
+}
+
+func (rw *http3ResponseWriter) WriteHeader(code int) {
+	checkWriteHeaderCode(code)
+	if rw.wroteHeader {
+		caller := relevantCaller()
+		rw.sc.srv.logf("http: superfluous response.WriteHeader call from %s (%s:%d)", caller.Function, path.Base(caller.File), caller.Line)
+		return
+	}
+	if code >= 100 && code <= 199 {
+		rw.writeInformational(code)
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = code
+	if cl := rw.handlerHeader.Get("Content-Length"); cl != "" {
+		if v, err := strconv.ParseInt(cl, 10, 64); err == nil && v >= 0 {
+			rw.contentLength = v
+		} else {
+			rw.sc.srv.logf("http: invalid Content-Length of %q", cl)
+			rw.handlerHeader.Del("Content-Length")
+		}
+	}
+}
+
+// writeInformational sends a 1xx response.
+func (rw *http3ResponseWriter) writeInformational(code int) {
+	if rw.err != nil {
+		return
+	}
+	b := http3AppendFieldSectionPrefix(nil)
+	b = http3AppendField(b, ":status", strconv.Itoa(code))
+	b = appendHTTP3ResponseHeader(b, rw.handlerHeader)
+	if _, err := rw.st.Write(http3AppendFrame(nil, http3FrameHeaders, b)); err != nil {
+		rw.setErr(err)
+	}
+}
+
+func (rw *http3ResponseWriter) Write(p []byte) (int, error) {
+	return rw.write(p, "")
+}
+
+func (rw *http3ResponseWriter) WriteString(s string) (int, error) {
+	return rw.write(nil, s)
+}
+
+func (rw *http3ResponseWriter) write(p []byte, s string) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(StatusOK)
+	}
+	n := len(p) + len(s)
+	if !bodyAllowedForStatus(rw.status) {
+		return 0, ErrBodyNotAllowed
+	}
+	if rw.req.Method == "HEAD" {
+		// Eat writes.
+		return n, nil
+	}
+	rw.written += int64(n)
+	if rw.contentLength >= 0 && rw.written > rw.contentLength {
+		return 0, ErrContentLength
+	}
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	if p != nil {
+		rw.buf = append(rw.buf, p...)
+	} else {
+		rw.buf = append(rw.buf, s...)
+	}
+	if len(rw.buf) >= http3ResponseBufferSize {
+		if err := rw.FlushError(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (rw *http3ResponseWriter) Flush() {
+	rw.FlushError()
+}
+
+func (rw *http3ResponseWriter) FlushError() error {
+	if !rw.wroteHeader {
+		rw.WriteHeader(StatusOK)
+	}
+	if rw.err != nil {
+		return rw.err
+	}
+	var b []byte
+	if !rw.sentHeader {
+		b = rw.appendHeaders(b)
+	}
+	if len(rw.buf) > 0 {
+		b = http3AppendFrameHeader(b, http3FrameData, len(rw.buf))
+		b = append(b, rw.buf...)
+		rw.buf = rw.buf[:0]
+	}
+	if len(b) > 0 {
+		if _, err := rw.st.Write(b); err != nil {
+			rw.setErr(err)
+		}
+	}
+	return rw.err
+}
+
+func (rw *http3ResponseWriter) setErr(err error) {
+	rw.err = http3StreamError(err)
+	// The client is no longer interested in the response.
+	rw.cancelCtx()
+}
+
+// appendHeaders appends the HEADERS frame of the response.
+func (rw *http3ResponseWriter) appendHeaders(b []byte) []byte {
+	rw.sentHeader = true
+	h := rw.handlerHeader
+	if bodyAllowedForStatus(rw.status) {
+		if _, ok := h["Content-Type"]; !ok && len(rw.buf) > 0 {
+			h.Set("Content-Type", DetectContentType(rw.buf))
+		}
+		if _, ok := h["Content-Length"]; !ok && rw.handlerDone && (len(rw.buf) > 0 || rw.req.Method != "HEAD") {
+			h.Set("Content-Length", strconv.Itoa(len(rw.buf)))
+		}
+	}
+	if _, ok := h["Date"]; !ok {
+		h.Set("Date", time.Now().UTC().Format(TimeFormat))
+	}
+	for _, v := range h["Trailer"] {
+		foreachHeaderElement(v, func(key string) {
+			key = CanonicalHeaderKey(key)
+			if !httpguts.ValidTrailerHeader(key) {
+				return
+			}
+			if !slices.Contains(rw.trailers, key) {
+				rw.trailers = append(rw.trailers, key)
+			}
+		})
+	}
+	f := http3AppendFieldSectionPrefix(nil)
+	f = http3AppendField(f, ":status", strconv.Itoa(rw.status))
+	f = appendHTTP3ResponseHeader(f, h)
+	return http3AppendFrame(b, http3FrameHeaders, f)
+}
+
+// appendHTTP3ResponseHeader appends the fields in a response header.
+func appendHTTP3ResponseHeader(b []byte, h Header) []byte {
+	for k, vv := range h {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			continue
+		}
+		lk, ok := ascii.ToLower(k)
+		if !ok || !httpguts.ValidHeaderFieldName(k) || slices.Contains(http3ConnectionHeaders, lk) {
+			continue
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				continue
+			}
+			b = http3AppendField(b, lk, v)
+		}
+	}
+	return b
+}
+
+// finish completes the response after the handler returns.
+func (rw *http3ResponseWriter) finish() {
+	rw.handlerDone = true
+	if !rw.wroteHeader {
+		rw.WriteHeader(StatusOK)
+	}
+	if err := rw.FlushError(); err != nil {
+		return
+	}
+	if rw.contentLength >= 0 && rw.written < rw.contentLength && rw.req.Method != "HEAD" && bodyAllowedForStatus(rw.status) {
+		// The handler wrote less than it declared.
+		// Reset the stream to indicate the response is incomplete.
+		http3ResetStream(rw.st, http3ErrInternal)
+		return
+	}
+	var trailer Header
+	for _, k := range rw.trailers {
+		if vv, ok := rw.handlerHeader[k]; ok {
+			if trailer == nil {
+				trailer = make(Header)
+			}
+			trailer[k] = vv
+		}
+	}
+	for k, vv := range rw.handlerHeader {
+		if key, ok := strings.CutPrefix(k, TrailerPrefix); ok {
+			if trailer == nil {
+				trailer = make(Header)
+			}
+			trailer[CanonicalHeaderKey(key)] = vv
+		}
+	}
+	if len(trailer) > 0 {
+		f := http3AppendFieldSectionPrefix(nil)
+		f = appendHTTP3ResponseHeader(f, trailer)
+		if _, err := rw.st.Write(http3AppendFrame(nil, http3FrameHeaders, f)); err != nil {
+			rw.setErr(err)
+			return
+		}
+	}
+	rw.st.CloseWrite()
+}
@@ -0,0 +1,450 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests of HTTP/3 over loopback UDP.
+
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"net/http/internal/testcert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTP3Server starts a server serving HTTP/3 on a loopback UDP port,
+// and returns it and its base URL.
+// Each opt is called on the server before it starts serving.
+func newHTTP3Server(t *testing.T, h Handler, opts ...func(*Server)) (*Server, string) {
+	t.Helper()
+	cert, err := tls.X509KeyPair(testcert.LocalhostCert, testcert.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	srv := &Server{
+		Handler:   h,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		ErrorLog:  quietLog,
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ServeQUIC(pc, "", "") }()
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-errc; err != ErrServerClosed {
+			t.Errorf("ServeQUIC = %v, want ErrServerClosed", err)
+		}
+	})
+	return srv, "https://" + pc.LocalAddr().String()
+}
+
+func http3TestTLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(testcert.LocalhostCert)
+	return &tls.Config{RootCAs: pool}
+}
+
+func newHTTP3Transport(t *testing.T) *HTTP3Transport {
+	tr := &HTTP3Transport{TLSClientConfig: http3TestTLSConfig()}
+	t.Cleanup(tr.CloseIdleConnections)
+	return tr
+}
+
+func TestHTTP3Get(t *testing.T) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ProtoMajor != 3 || r.TLS == nil {
+			t.Errorf("server: Proto = %q, TLS = %v; want HTTP/3.0 with TLS", r.Proto, r.TLS)
+		}
+		if got, want := r.URL.RequestURI(), "/path?q=1"; got != want {
+			t.Errorf("server: RequestURI = %q, want %q", got, want)
+		}
+		if got := r.Header.Get("X-Foo"); got != "bar" {
+			t.Errorf("server: X-Foo = %q, want bar", got)
+		}
+		w.Header().Set("X-Reply", "yes")
+		io.WriteString(w, "<html>hello")
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	req, _ := NewRequest("GET", url+"/path?q=1", nil)
+	req.Header.Set("X-Foo", "bar")
+	for i := 0; i < 3; i++ {
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != 200 || res.Proto != "HTTP/3.0" || res.TLS == nil {
+			t.Errorf("response: %v %v, TLS = %v", res.Proto, res.Status, res.TLS)
+		}
+		if got, want := string(body), "<html>hello"; got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+		if got := res.Header.Get("X-Reply"); got != "yes" {
+			t.Errorf("X-Reply = %q, want yes", got)
+		}
+		if got, want := res.Header.Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+			t.Errorf("Content-Type = %q, want %q", got, want)
+		}
+		if res.ContentLength != int64(len(body)) {
+			t.Errorf("ContentLength = %v, want %v", res.ContentLength, len(body))
+		}
+	}
+}
+
+func TestHTTP3PostEcho(t *testing.T) {
+	for _, size := range []int{0, 100, 3 << 20} {
+		testHTTP3PostEcho(t, size)
+	}
+}
+
+func testHTTP3PostEcho(t *testing.T, size int) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ContentLength != int64(size) {
+			t.Errorf("server: ContentLength = %v, want %v", r.ContentLength, size)
+		}
+		io.Copy(w, r.Body)
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	want := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+	res, err := c.Post(url, "application/octet-stream", bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("size %v: echoed %v bytes, want %v identical bytes", size, len(got), len(want))
+	}
+}
+
+func TestHTTP3Trailers(t *testing.T) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, ok := r.Trailer["Client-Trailer"]; !ok {
+			t.Errorf("server: declared trailers = %v, want Client-Trailer", r.Trailer)
+		}
+		io.Copy(io.Discard, r.Body)
+		if got := r.Trailer.Get("Client-Trailer"); got != "c" {
+			t.Errorf("server: Client-Trailer = %q, want c", got)
+		}
+		w.Header().Set("Trailer", "Server-Trailer")
+		io.WriteString(w, "body")
+		w.Header().Set("Server-Trailer", "s")
+		w.Header().Set(TrailerPrefix+"Undeclared", "u")
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	req, _ := NewRequest("POST", url, strings.NewReader("request body"))
+	req.Trailer = Header{"Client-Trailer": {"c"}}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if _, ok := res.Trailer["Server-Trailer"]; !ok {
+		t.Errorf("declared trailers = %v, want Server-Trailer", res.Trailer)
+	}
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Trailer.Get("Server-Trailer"); got != "s" {
+		t.Errorf("Server-Trailer = %q, want s", got)
+	}
+	if got := res.Trailer.Get("Undeclared"); got != "u" {
+		t.Errorf("Undeclared = %q, want u", got)
+	}
+}
+
+func TestHTTP3NoBody(t *testing.T) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/204" {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Length", "5")
+		io.WriteString(w, "hello")
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	res, err := c.Head(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.Body != NoBody || res.ContentLength != 5 {
+		t.Errorf("HEAD: Body = %T, ContentLength = %v; want NoBody, 5", res.Body, res.ContentLength)
+	}
+	res, err = c.Get(url + "/204")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 204 || res.Body != NoBody {
+		t.Errorf("GET /204: %v, Body = %T; want 204, NoBody", res.Status, res.Body)
+	}
+}
+
+func TestHTTP3Gzip(t *testing.T) {
+	const msg = "hello, hello, hello, compressed world"
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip, zstd" {
+			t.Errorf("server: Accept-Encoding = %q, want gzip, zstd", got)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, msg)
+		gz.Close()
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	res, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != msg || !res.Uncompressed {
+		t.Errorf("body = %q, Uncompressed = %v; want %q, true", body, res.Uncompressed, msg)
+	}
+}
+
+func TestHTTP3AcceptEncodingGODEBUG(t *testing.T) {
+	t.Setenv("GODEBUG", "httpzstd=0")
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Header.Get("Accept-Encoding"))
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	res, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "gzip" {
+		t.Errorf("Accept-Encoding = %q, want gzip", body)
+	}
+}
+
+func TestHTTP3Zstd(t *testing.T) {
+	msg := strings.Repeat("hello, compressed world ", 100)
+	_, url := newHTTP3Server(t, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, msg)
+	})))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	res, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != msg || !res.Uncompressed {
+		t.Errorf("body = %q, Uncompressed = %v; want %q, true", body, res.Uncompressed, msg)
+	}
+}
+
+func TestHTTP3HandlerPanic(t *testing.T) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		panic(ErrAbortHandler)
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	res, err := c.Get(url)
+	if err == nil {
+		res.Body.Close()
+		t.Fatalf("Get succeeded, want error after handler panic")
+	}
+}
+
+func TestHTTP3RequestDone(t *testing.T) {
+	donec := make(chan RequestStats, 1)
+	var handlerStart time.Time
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/panic" {
+			panic(ErrAbortHandler)
+		}
+		handlerStart = time.Now()
+		w.WriteHeader(StatusTeapot)
+		io.WriteString(w, "Hello, ")
+		w.(Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, "world!")
+	}), func(srv *Server) {
+		srv.RequestDone = func(r *Request, stats RequestStats) {
+			donec <- stats
+		}
+	})
+	c := &Client{Transport: newHTTP3Transport(t)}
+	start := time.Now()
+	res, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	stats := <-donec
+	if stats.Proto != "HTTP/3.0" || stats.StatusCode != StatusTeapot || stats.BytesWritten != 13 || stats.Panicked {
+		t.Errorf("RequestDone got %+v, want Proto HTTP/3.0, StatusCode 418, BytesWritten 13, Panicked false", stats)
+	}
+	if stats.Start.Before(start) || stats.Start.After(handlerStart) {
+		t.Errorf("RequestDone got Start %v, want between %v and handler start %v", stats.Start, start, handlerStart)
+	}
+	if stats.Duration < 10*time.Millisecond {
+		t.Errorf("RequestDone got Duration %v, want >= 10ms", stats.Duration)
+	}
+
+	if res, err := c.Get(url + "/panic"); err == nil {
+		res.Body.Close()
+		t.Errorf("Get /panic succeeded, want error after handler panic")
+	}
+	if stats := <-donec; !stats.Panicked {
+		t.Errorf("RequestDone for panicking handler got %+v, want Panicked true", stats)
+	}
+}
+
+func TestHTTP3CancelRequest(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	gotReq := make(chan context.Context, 1)
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(200)
+		w.(Flusher).Flush()
+		gotReq <- r.Context()
+		<-unblock
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequestWithContext(ctx, "GET", url, nil)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-gotReq
+	cancel()
+	if _, err := io.ReadAll(res.Body); !errors.Is(err, context.Canceled) {
+		t.Errorf("reading body after cancel: %v, want context.Canceled", err)
+	}
+	res.Body.Close()
+}
+
+func TestHTTP3TransportProtocols(t *testing.T) {
+	_, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	protocols := &Protocols{}
+	protocols.SetHTTP3(true)
+	tr := &Transport{
+		TLSClientConfig: http3TestTLSConfig(),
+		Protocols:       protocols,
+	}
+	defer tr.CloseIdleConnections()
+	res, err := (&Client{Transport: tr}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.ProtoMajor != 3 || string(body) != "HTTP/3.0" {
+		t.Errorf("response %v, body %q; want HTTP/3.0", res.Proto, body)
+	}
+}
+
+func TestHTTP3TransportFallback(t *testing.T) {
+	// A TLS server with no HTTP/3 endpoint on the corresponding UDP port.
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	ts.StartTLS()
+	defer ts.Close()
+	protocols := &Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP3(true)
+	tr := ts.Client().Transport.(*Transport).Clone()
+	tr.Protocols = protocols
+	tr.TLSHandshakeTimeout = 200 * time.Millisecond
+	defer tr.CloseIdleConnections()
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		res, err := (&Client{Transport: tr}).Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "HTTP/1.1" {
+			t.Errorf("request %v: body %q, want HTTP/1.1", i, body)
+		}
+		if i == 1 && time.Since(start) >= tr.TLSHandshakeTimeout {
+			t.Errorf("second request took %v; want immediate fallback", time.Since(start))
+		}
+	}
+}
+
+func TestHTTP3Shutdown(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	srv, url := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		close(started)
+		<-unblock
+		io.WriteString(w, "done")
+	}))
+	c := &Client{Transport: newHTTP3Transport(t)}
+	type result struct {
+		body string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		res, err := c.Get(url)
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		resc <- result{string(body), err}
+	}()
+	<-started
+
+	shutdownc := make(chan error, 1)
+	go func() { shutdownc <- srv.Shutdown(context.Background()) }()
+	select {
+	case err := <-shutdownc:
+		t.Fatalf("Shutdown returned %v with a request in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	if r := <-resc; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request: body %q, err %v; want done", r.body, r.err)
+	}
+	if err := <-shutdownc; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
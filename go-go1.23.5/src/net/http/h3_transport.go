@@ -0,0 +1,921 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 client implementation.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"net/http/internal/ascii"
+	"net/http/internal/quic"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// HTTP3Transport is an implementation of [RoundTripper] that sends requests
+// using HTTP/3 over QUIC.
+//
+// Support for HTTP/3 is experimental. HTTP3Transport supports only
+// https URLs and does not use proxies. A [Transport] sends requests using
+// an HTTP3Transport configured from its own fields when its Protocols
+// include HTTP3.
+//
+// An HTTP3Transport maintains a pool of connections, and should be reused
+// instead of created as needed. It is safe for concurrent use by multiple
+// goroutines. The zero value is a usable HTTP3Transport.
+type HTTP3Transport struct {
+	// TLSClientConfig specifies the TLS configuration to use.
+	// If nil, the default configuration is used.
+	// The ALPN protocol list is always "h3", and
+	// the minimum TLS version is always TLS 1.3.
+	TLSClientConfig *tls.Config
+
+	// TLSHandshakeTimeout specifies the maximum amount of time
+	// to wait for a QUIC handshake. Zero means a default of 10 seconds.
+	TLSHandshakeTimeout time.Duration
+
+	// DisableCompression, if true, prevents the transport from
+	// requesting compression with an "Accept-Encoding: gzip, zstd"
+	// request header when the Request contains no existing
+	// Accept-Encoding value. See [Transport.DisableCompression].
+	DisableCompression bool
+
+	// MaxResponseHeaderBytes specifies a limit on how many
+	// response bytes are allowed in the server's response header.
+	// Zero means to use a default limit.
+	MaxResponseHeaderBytes int64
+
+	// IdleConnTimeout is the maximum amount of time an idle
+	// connection will remain idle before closing itself.
+	// Zero means no limit.
+	IdleConnTimeout time.Duration
+
+	mu       sync.Mutex
+	endpoint *quic.Endpoint
+	conns    map[string]*http3ClientConn // by canonical address
+	dials    map[string]*http3DialCall
+}
+
+// http3DefaultUserAgent is the User-Agent sent on HTTP/3 requests
+// which do not specify one.
+const http3DefaultUserAgent = "Go-http-client/3.0"
+
+// An http3DialError is returned by HTTP3Transport when a QUIC connection
+// to the server could not be established. No part of the request has been
+// sent, so a Transport may retry it using another protocol.
+type http3DialError struct {
+	err error
+}
+
+func (e http3DialError) Error() string { return "http3: " + e.err.Error() }
+func (e http3DialError) Unwrap() error { return e.err }
+
+// errHTTP3ConnUnusable is returned by http3ClientConn.roundTrip when the
+// connection closed before the request could be sent.
+var errHTTP3ConnUnusable = errors.New("http3: connection no longer usable")
+
+// http3DialCall is an in-flight dial.
+type http3DialCall struct {
+	done chan struct{} // closed when the dial completes
+	cc   *http3ClientConn
+	err  error
+}
+
+// RoundTrip implements the [RoundTripper] interface.
+func (t *HTTP3Transport) RoundTrip(req *Request) (*Response, error) {
+	resp, err := t.roundTrip(req)
+	if _, ok := err.(http3DialError); ok {
+		req.closeBody()
+	}
+	return resp, err
+}
+
+// roundTrip is RoundTrip, but it does not close the request body
+// when it returns an http3DialError.
+func (t *HTTP3Transport) roundTrip(req *Request) (*Response, error) {
+	switch {
+	case req.URL == nil:
+		req.closeBody()
+		return nil, errors.New("http: nil Request.URL")
+	case req.Header == nil:
+		req.closeBody()
+		return nil, errors.New("http: nil Request.Header")
+	case req.URL.Scheme != "https":
+		req.closeBody()
+		return nil, badStringError("unsupported protocol scheme", req.URL.Scheme)
+	case req.URL.Host == "":
+		req.closeBody()
+		return nil, errors.New("http: no Host in request URL")
+	case req.Method != "" && !validMethod(req.Method):
+		req.closeBody()
+		return nil, fmt.Errorf("net/http: invalid method %q", req.Method)
+	}
+	addr := canonicalAddr(req.URL)
+	for retry := false; ; retry = true {
+		cc, err := t.getConn(req.Context(), addr)
+		if err != nil {
+			if _, ok := err.(http3DialError); !ok {
+				req.closeBody()
+			}
+			return nil, err
+		}
+		resp, err := cc.roundTrip(req)
+		if err == errHTTP3ConnUnusable && !retry {
+			// Nothing was sent. Try again on a new connection.
+			continue
+		}
+		return resp, err
+	}
+}
+
+// getConn returns a connection to addr with a request reserved on it,
+// dialing a new connection if necessary.
+func (t *HTTP3Transport) getConn(ctx context.Context, addr string) (*http3ClientConn, error) {
+	for {
+		t.mu.Lock()
+		if cc := t.conns[addr]; cc != nil && cc.reserve() {
+			t.mu.Unlock()
+			return cc, nil
+		}
+		d := t.dials[addr]
+		if d == nil {
+			d = &http3DialCall{done: make(chan struct{})}
+			if t.dials == nil {
+				t.dials = make(map[string]*http3DialCall)
+			}
+			t.dials[addr] = d
+			go t.dial(d, addr)
+		}
+		t.mu.Unlock()
+		select {
+		case <-d.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if d.err != nil {
+			return nil, d.err
+		}
+		if d.cc.reserve() {
+			return d.cc, nil
+		}
+		// The new connection closed already. Try again.
+	}
+}
+
+// dial creates a connection to addr, completing the dial call d.
+// The dial is not tied to the context of any one request,
+// so that other requests may share the connection it creates.
+func (t *HTTP3Transport) dial(d *http3DialCall, addr string) {
+	d.cc, d.err = t.dialConn(addr)
+	if d.err != nil {
+		d.err = http3DialError{d.err}
+	}
+	t.mu.Lock()
+	delete(t.dials, addr)
+	if d.cc != nil {
+		if t.conns == nil {
+			t.conns = make(map[string]*http3ClientConn)
+		}
+		t.conns[addr] = d.cc
+	}
+	t.mu.Unlock()
+	close(d.done)
+}
+
+func (t *HTTP3Transport) dialConn(addr string) (*http3ClientConn, error) {
+	ep, err := t.getEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	cfg := cloneTLSConfig(t.TLSClientConfig)
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	cfg.NextProtos = []string{http3NextProto}
+	cfg.MinVersion = tls.VersionTLS13
+	timeout := t.TLSHandshakeTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	qc, err := ep.Dial(ctx, "udp", addr, &quic.Config{
+		TLSConfig:        cfg,
+		HandshakeTimeout: timeout,
+		// Keep the connection open while requests are in flight,
+		// even if a handler takes a long time to respond.
+		KeepAlivePeriod: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cc := &http3ClientConn{t: t, key: addr}
+	cc.init(qc, t.maxHeaderBytes())
+	cc.onGoAway = func(int64) {
+		// Requests already sent may still complete,
+		// but no new requests may use this connection.
+		cc.markUnusable()
+	}
+	if err := cc.openControlStream(); err != nil {
+		qc.Abort(nil)
+		return nil, err
+	}
+	go cc.acceptStreams()
+	go func() {
+		<-qc.Done()
+		cc.markUnusable()
+	}()
+	return cc, nil
+}
+
+func (t *HTTP3Transport) getEndpoint() (*quic.Endpoint, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.endpoint == nil {
+		ep, err := quic.Listen("udp", ":0", nil)
+		if err != nil {
+			return nil, err
+		}
+		t.endpoint = ep
+	}
+	return t.endpoint, nil
+}
+
+func (t *HTTP3Transport) maxHeaderBytes() int64 {
+	if t.MaxResponseHeaderBytes > 0 {
+		return t.MaxResponseHeaderBytes
+	}
+	return 10 << 20 // conservative default; same as Transport
+}
+
+func (t *HTTP3Transport) removeConn(cc *http3ClientConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[cc.key] == cc {
+		delete(t.conns, cc.key)
+	}
+}
+
+// CloseIdleConnections closes any connections which were previously
+// connected from previous requests but are now sitting idle.
+// It does not interrupt any connections currently in use.
+func (t *HTTP3Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	conns := make([]*http3ClientConn, 0, len(t.conns))
+	for _, cc := range t.conns {
+		conns = append(conns, cc)
+	}
+	t.mu.Unlock()
+	for _, cc := range conns {
+		cc.closeIfIdle()
+	}
+
+	// Release the UDP socket once there are no connections left.
+	t.mu.Lock()
+	var ep *quic.Endpoint
+	if len(t.conns) == 0 && len(t.dials) == 0 {
+		ep, t.endpoint = t.endpoint, nil
+	}
+	t.mu.Unlock()
+	if ep != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		ep.Close(ctx)
+	}
+}
+
+// http3ClientConn is a client connection to an HTTP/3 server.
+type http3ClientConn struct {
+	http3Conn
+	t   *HTTP3Transport
+	key string
+
+	reqMu     sync.Mutex // guards the fields below
+	active    int        // requests reserved or in flight
+	unusable  bool       // no new requests may be sent
+	idleTimer *time.Timer
+}
+
+// reserve reserves the connection for a new request.
+// It reports false if the connection cannot accept new requests.
+func (cc *http3ClientConn) reserve() bool {
+	cc.reqMu.Lock()
+	defer cc.reqMu.Unlock()
+	if cc.unusable {
+		return false
+	}
+	cc.active++
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+		cc.idleTimer = nil
+	}
+	return true
+}
+
+// release is called when a request reserved by reserve completes.
+func (cc *http3ClientConn) release() {
+	cc.reqMu.Lock()
+	defer cc.reqMu.Unlock()
+	cc.active--
+	if cc.active > 0 {
+		return
+	}
+	if cc.unusable {
+		// A GOAWAY was received and the last request has finished.
+		cc.close()
+		return
+	}
+	if d := cc.t.IdleConnTimeout; d > 0 {
+		cc.idleTimer = time.AfterFunc(d, func() { cc.closeIfIdle() })
+	}
+}
+
+func (cc *http3ClientConn) markUnusable() {
+	cc.reqMu.Lock()
+	cc.unusable = true
+	idle := cc.active == 0
+	cc.reqMu.Unlock()
+	cc.t.removeConn(cc)
+	if idle {
+		cc.close()
+	}
+}
+
+// closeIfIdle closes the connection if it has no requests in flight.
+func (cc *http3ClientConn) closeIfIdle() {
+	cc.reqMu.Lock()
+	if cc.active > 0 {
+		cc.reqMu.Unlock()
+		return
+	}
+	cc.unusable = true
+	cc.reqMu.Unlock()
+	cc.t.removeConn(cc)
+	cc.close()
+}
+
+// acceptStreams handles streams created by the server.
+func (cc *http3ClientConn) acceptStreams() {
+	for {
+		st, err := cc.qc.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		if !st.IsReadOnly() {
+			// Servers may not create bidirectional streams
+			// (RFC 9114, Section 6.1).
+			cc.abort(http3ErrStreamCreation)
+			return
+		}
+		go cc.handleUniStream(st, false)
+	}
+}
+
+// roundTrip sends a request on the connection, which must have
+// been reserved for it. The reservation is released when the
+// response body is closed or fully read, or on error.
+func (cc *http3ClientConn) roundTrip(req *Request) (_ *Response, err error) {
+	ctx := req.Context()
+	trace := httptrace.ContextClientTrace(ctx)
+	released := false
+	defer func() {
+		if err != nil && !released {
+			cc.release()
+		}
+	}()
+
+	requestedGzip := !cc.t.DisableCompression &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" &&
+		req.Method != "HEAD"
+	hdrs, err := cc.encodeRequestHeaders(req, requestedGzip)
+	if err != nil {
+		req.closeBody()
+		return nil, err
+	}
+
+	st, err := cc.qc.NewStream(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			req.closeBody()
+			return nil, err
+		}
+		return nil, errHTTP3ConnUnusable
+	}
+	st.SetReadContext(ctx)
+	st.SetWriteContext(ctx)
+	if trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: http3NetConn{cc.qc}, Reused: true})
+	}
+
+	// Once the stream is open, any failure resets it.
+	abortStream := func() {
+		st.StopSending(uint64(http3ErrRequestCancelled))
+		st.Reset(uint64(http3ErrRequestCancelled))
+	}
+	if _, err := st.Write(hdrs); err != nil {
+		abortStream()
+		req.closeBody()
+		return nil, http3StreamError(err)
+	}
+	if trace != nil && trace.WroteHeaders != nil {
+		trace.WroteHeaders()
+	}
+
+	// The request body is written concurrently with reading the response,
+	// since the server may respond before reading the whole request.
+	bodyErrc := make(chan error, 1)
+	if req.Body == nil || req.Body == NoBody {
+		st.CloseWrite()
+		bodyErrc <- nil
+		if trace != nil && trace.WroteRequest != nil {
+			trace.WroteRequest(httptrace.WroteRequestInfo{})
+		}
+	} else {
+		go func() {
+			err := cc.writeRequestBody(st, req)
+			if err != nil {
+				abortStream()
+			}
+			if trace != nil && trace.WroteRequest != nil {
+				trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+			}
+			bodyErrc <- err
+		}()
+	}
+
+	br := bufio.NewReader(st)
+	resp, err := cc.readResponse(st, br, req, trace)
+	if err != nil {
+		abortStream()
+		select {
+		case bodyErr := <-bodyErrc:
+			if bodyErr != nil {
+				// A failure sending the request body is more interesting
+				// than the resulting failure to read a response.
+				err = bodyErr
+			}
+		default:
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	if req.Method == "HEAD" || !bodyAllowedForStatus(resp.StatusCode) || resp.StatusCode == StatusNotModified {
+		// No response body. We have no further use for the stream.
+		st.StopSending(uint64(http3ErrNoError))
+		resp.Body = NoBody
+		released = true
+		cc.release()
+		return resp, nil
+	}
+
+	body := &http3Body{
+		conn:           &cc.http3Conn,
+		st:             st,
+		br:             br,
+		trailer:        &resp.Trailer,
+		maxTrailerSize: cc.t.maxHeaderBytes(),
+		contentLength:  resp.ContentLength,
+		onDone: func(err error) {
+			if err != io.EOF {
+				abortStream()
+			}
+			cc.release()
+		},
+	}
+	released = true
+	resp.Body = body
+	if requestedGzip {
+		switch ce := resp.Header.Get("Content-Encoding"); {
+		case ascii.EqualFold(ce, "gzip"):
+			resp.Body = &http3GzipReader{body: body}
+		case ascii.EqualFold(ce, "zstd"):
+			resp.Body = &zstdReader{body: body}
+		}
+		if resp.Body != body {
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+		}
+	}
+	return resp, nil
+}
+
+// writeRequestBody writes the request body and trailers, and closes
+// the send side of the stream.
+func (cc *http3ClientConn) writeRequestBody(st *quic.Stream, req *Request) (err error) {
+	defer req.closeBody()
+	const maxFrameSize = 16 << 10
+	buf := make([]byte, 16+maxFrameSize)
+	var sent int64
+	for {
+		n, rerr := req.Body.Read(buf[16:])
+		if n > 0 {
+			sent += int64(n)
+			hdr := http3AppendFrameHeader(buf[:0], http3FrameData, n)
+			// Place the frame header directly before the data.
+			frame := buf[16-len(hdr) : 16+n]
+			copy(frame, hdr)
+			if _, err := st.Write(frame); err != nil {
+				if code, ok := err.(quic.StreamErrorCode); ok && http3ErrCode(code) == http3ErrNoError {
+					// The server doesn't need the rest of the body
+					// (RFC 9114, Section 4.1).
+					return nil
+				}
+				return http3StreamError(err)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	if cl := req.ContentLength; cl > 0 && sent != cl {
+		return fmt.Errorf("http3: request body length %v does not match ContentLength %v", sent, cl)
+	}
+	if len(req.Trailer) > 0 {
+		b := http3AppendFieldSectionPrefix(nil)
+		for k, vv := range req.Trailer {
+			lk, _ := ascii.ToLower(k)
+			if !isHTTP3FieldName(lk) {
+				return fmt.Errorf("http3: invalid trailer field name %q", k)
+			}
+			for _, v := range vv {
+				if !httpguts.ValidHeaderFieldValue(v) {
+					return fmt.Errorf("http3: invalid trailer field value for %q", k)
+				}
+				b = http3AppendField(b, lk, v)
+			}
+		}
+		if _, err := st.Write(http3AppendFrame(nil, http3FrameHeaders, b)); err != nil {
+			return http3StreamError(err)
+		}
+	}
+	return st.CloseWrite()
+}
+
+// http3ConnectionHeaders are connection-specific header fields,
+// which may not appear in HTTP/3 messages (RFC 9114, Section 4.2).
+var http3ConnectionHeaders = []string{
+	"connection",
+	"keep-alive",
+	"proxy-connection",
+	"transfer-encoding",
+	"upgrade",
+}
+
+// encodeRequestHeaders returns a HEADERS frame containing the request header.
+func (cc *http3ClientConn) encodeRequestHeaders(req *Request, addGzipHeader bool) ([]byte, error) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host, err := httpguts.PunycodeHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	if !httpguts.ValidHostHeader(host) {
+		return nil, errors.New("http3: invalid Host header")
+	}
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	var path string
+	if method != "CONNECT" {
+		path = req.URL.RequestURI()
+		if !validPseudoPath(path) {
+			if req.URL.Opaque != "" {
+				return nil, fmt.Errorf("http3: invalid request :path %q from URL.Opaque = %q", path, req.URL.Opaque)
+			}
+			return nil, fmt.Errorf("http3: invalid request :path %q", path)
+		}
+	}
+	for k, vv := range req.Header {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return nil, fmt.Errorf("http3: invalid header field name %q", k)
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				// Don't include the value in the error, because it may be sensitive.
+				return nil, fmt.Errorf("http3: invalid header field value for %q", k)
+			}
+		}
+	}
+
+	var size int64
+	b := http3AppendFieldSectionPrefix(nil)
+	field := func(name, value string) {
+		size += int64(len(name) + len(value) + 32)
+		b = http3AppendField(b, name, value)
+	}
+	field(":method", method)
+	field(":authority", host)
+	if method != "CONNECT" {
+		field(":scheme", "https")
+		field(":path", path)
+	}
+	if len(req.Trailer) > 0 {
+		keys := make([]string, 0, len(req.Trailer))
+		for k := range req.Trailer {
+			k = CanonicalHeaderKey(k)
+			switch k {
+			case "Transfer-Encoding", "Trailer", "Content-Length":
+				return nil, fmt.Errorf("http3: invalid Trailer key %q", k)
+			}
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		field("trailer", strings.Join(keys, ","))
+	}
+	didUA := false
+	for k, vv := range req.Header {
+		lk, _ := ascii.ToLower(k)
+		switch {
+		case lk == "host", lk == "content-length", lk == "trailer":
+			// Host is sent as :authority, and we set
+			// Content-Length and Trailer ourselves.
+			continue
+		case slices.Contains(http3ConnectionHeaders, lk):
+			continue
+		case lk == "te":
+			// The only TE value permitted in HTTP/3 is "trailers".
+			if !slices.ContainsFunc(vv, func(v string) bool { return ascii.EqualFold(v, "trailers") }) {
+				continue
+			}
+			vv = []string{"trailers"}
+		case lk == "user-agent":
+			didUA = true
+			if len(vv) < 1 {
+				continue
+			}
+			vv = vv[:1]
+			if vv[0] == "" {
+				continue
+			}
+		}
+		for _, v := range vv {
+			field(lk, v)
+		}
+	}
+	if cl := req.outgoingLength(); cl > 0 || (cl == 0 && (method == "POST" || method == "PUT" || method == "PATCH")) {
+		field("content-length", strconv.FormatInt(cl, 10))
+	}
+	if addGzipHeader {
+		field("accept-encoding", defaultAcceptEncoding())
+	}
+	if !didUA {
+		field("user-agent", http3DefaultUserAgent)
+	}
+	if !cc.fieldSectionFits(size) {
+		return nil, errRequestHeaderListSize
+	}
+	return http3AppendFrame(nil, http3FrameHeaders, b), nil
+}
+
+// errRequestHeaderListSize is returned when a request header is larger
+// than the server's MAX_FIELD_SECTION_SIZE.
+var errRequestHeaderListSize = errors.New("http3: request header list larger than peer's advertised limit")
+
+// validPseudoPath reports whether v is a valid :path pseudo-header value.
+func validPseudoPath(v string) bool {
+	return (len(v) > 0 && v[0] == '/') || v == "*"
+}
+
+// readResponse reads the response header.
+func (cc *http3ClientConn) readResponse(st *quic.Stream, br *bufio.Reader, req *Request, trace *httptrace.ClientTrace) (*Response, error) {
+	maxSize := cc.t.maxHeaderBytes()
+	for {
+		ftype, length, err := http3ReadFrameHeader(br)
+		if err == io.EOF {
+			return nil, errors.New("http3: stream ended before response header")
+		}
+		if err != nil {
+			return nil, cc.responseError(err)
+		}
+		switch {
+		case ftype == http3FrameHeaders:
+		case ftype == http3FrameData, ftype == http3FrameSettings, ftype == http3FrameGoAway,
+			ftype == http3FrameMaxPushID, ftype == http3FrameCancelPush,
+			ftype == http3FramePushPromise, http3FrameReservedHTTP2(ftype):
+			return nil, cc.responseError(http3ErrFrameUnexpected)
+		default:
+			if err := http3DiscardFrame(br, length); err != nil {
+				return nil, cc.responseError(err)
+			}
+			continue
+		}
+		p, err := http3ReadFramePayload(br, length, maxSize, errHTTP3HeaderTooLarge)
+		if err != nil {
+			return nil, cc.responseError(err)
+		}
+		resp, err := cc.decodeResponseHeaders(p, req, maxSize)
+		if err != nil {
+			return nil, cc.responseError(err)
+		}
+		if resp.StatusCode >= 100 && resp.StatusCode <= 199 {
+			if trace != nil {
+				if resp.StatusCode == 100 && trace.Got100Continue != nil {
+					trace.Got100Continue()
+				}
+				if trace.Got1xxResponse != nil {
+					if err := trace.Got1xxResponse(resp.StatusCode, textproto.MIMEHeader(resp.Header)); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+		if trace != nil && trace.GotFirstResponseByte != nil {
+			trace.GotFirstResponseByte()
+		}
+		state := cc.qc.ConnectionState()
+		resp.TLS = &state
+		return resp, nil
+	}
+}
+
+// responseError reports a protocol error reading a response to the peer,
+// and returns the error to return to the user.
+func (cc *http3ClientConn) responseError(err error) error {
+	var code http3ErrCode
+	switch {
+	case err == errHTTP3HeaderTooLarge:
+		return err
+	case errors.As(err, &code), err == errHTTP3QPACKDecompression:
+		cc.abortErr(err)
+		return err
+	}
+	return http3StreamError(err)
+}
+
+var errHTTP3MalformedResponse = errors.New("http3: malformed response header")
+
+func (cc *http3ClientConn) decodeResponseHeaders(p []byte, req *Request, maxSize int64) (*Response, error) {
+	resp := &Response{
+		Proto:         "HTTP/3.0",
+		ProtoMajor:    3,
+		Header:        make(Header),
+		Request:       req,
+		ContentLength: -1,
+	}
+	status := ""
+	regular := false
+	err := http3DecodeFieldSection(p, maxSize, func(name, value string) error {
+		if strings.HasPrefix(name, ":") {
+			if name != ":status" || status != "" || regular {
+				return errHTTP3MalformedResponse
+			}
+			status = value
+			return nil
+		}
+		regular = true
+		if !isHTTP3FieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return errHTTP3MalformedResponse
+		}
+		if slices.Contains(http3ConnectionHeaders, name) {
+			return errHTTP3MalformedResponse
+		}
+		key := CanonicalHeaderKey(name)
+		if key == "Trailer" {
+			if resp.Trailer == nil {
+				resp.Trailer = make(Header)
+			}
+			foreachHeaderElement(value, func(v string) {
+				resp.Trailer[CanonicalHeaderKey(v)] = nil
+			})
+			return nil
+		}
+		resp.Header[key] = append(resp.Header[key], value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := strconv.Atoi(status)
+	if len(status) != 3 || err != nil || code < 100 || code == StatusSwitchingProtocols {
+		return nil, errHTTP3MalformedResponse
+	}
+	resp.StatusCode = code
+	resp.Status = status + " " + StatusText(code)
+	if clens := resp.Header["Content-Length"]; len(clens) > 0 {
+		cl, err := strconv.ParseUint(clens[0], 10, 63)
+		if err != nil || len(clens) > 1 {
+			return nil, errHTTP3MalformedResponse
+		}
+		resp.ContentLength = int64(cl)
+	}
+	return resp, nil
+}
+
+// http3NetConn is the net.Conn reported in httptrace.GotConnInfo.
+// It supports only the address methods.
+type http3NetConn struct {
+	qc *quic.Conn
+}
+
+func (c http3NetConn) Read([]byte) (int, error)         { return 0, errHTTP3NetConn }
+func (c http3NetConn) Write([]byte) (int, error)        { return 0, errHTTP3NetConn }
+func (c http3NetConn) Close() error                     { return errHTTP3NetConn }
+func (c http3NetConn) LocalAddr() net.Addr              { return c.qc.LocalAddr() }
+func (c http3NetConn) RemoteAddr() net.Addr             { return c.qc.RemoteAddr() }
+func (c http3NetConn) SetDeadline(time.Time) error      { return errHTTP3NetConn }
+func (c http3NetConn) SetReadDeadline(time.Time) error  { return errHTTP3NetConn }
+func (c http3NetConn) SetWriteDeadline(time.Time) error { return errHTTP3NetConn }
+
+var errHTTP3NetConn = errors.New("http3: operation not supported on a QUIC connection")
+
+// http3BrokenDuration is how long a Transport which can fall back to
+// HTTP/1 or HTTP/2 avoids HTTP/3 for an address after failing to connect.
+const http3BrokenDuration = 5 * time.Minute
+
+// errHTTP3Fallback is returned by Transport.roundTripHTTP3 when
+// the request should be sent using another protocol.
+var errHTTP3Fallback = errors.New("http3: fall back to TCP")
+
+// useHTTP3 reports whether t should attempt to send req using HTTP/3.
+func (t *Transport) useHTTP3(req *Request) bool {
+	if !t.protocols().HTTP3() || req.URL == nil || req.URL.Scheme != "https" || req.requiresHTTP1() || req.isExtendedConnect() {
+		return false
+	}
+	if t.Proxy != nil {
+		// HTTP/3 is not supported through proxies.
+		if u, err := t.Proxy(req); err != nil || u != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Transport) canFallBackFromHTTP3() bool {
+	p := t.protocols()
+	return p.HTTP1() || p.HTTP2()
+}
+
+// roundTripHTTP3 sends req using HTTP/3.
+// It returns errHTTP3Fallback if a connection to the server
+// could not be established and another protocol may be used.
+func (t *Transport) roundTripHTTP3(req *Request) (*Response, error) {
+	addr := canonicalAddr(req.URL)
+	fallback := t.canFallBackFromHTTP3()
+	t.h3mu.Lock()
+	if t.h3transport == nil {
+		t.h3transport = &HTTP3Transport{
+			TLSClientConfig:        t.TLSClientConfig,
+			TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
+			DisableCompression:     t.DisableCompression,
+			MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
+			IdleConnTimeout:        t.IdleConnTimeout,
+		}
+	}
+	t3 := t.h3transport
+	if retry, ok := t.h3broken[addr]; ok && fallback {
+		if time.Now().Before(retry) {
+			t.h3mu.Unlock()
+			return nil, errHTTP3Fallback
+		}
+		delete(t.h3broken, addr)
+	}
+	t.h3mu.Unlock()
+
+	resp, err := t3.roundTrip(req)
+	if _, ok := err.(http3DialError); ok {
+		if !fallback {
+			req.closeBody()
+			return nil, err
+		}
+		t.h3mu.Lock()
+		if t.h3broken == nil {
+			t.h3broken = make(map[string]time.Time)
+		}
+		t.h3broken[addr] = time.Now().Add(http3BrokenDuration)
+		t.h3mu.Unlock()
+		return nil, errHTTP3Fallback
+	}
+	return resp, err
+}
@@ -7,7 +7,10 @@
 package http
 
 import (
+	"crypto/tls"
 	"io"
+	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +32,143 @@ const maxInt64 = 1<<63 - 1
 // immediate cancellation of network operations.
 var aLongTimeAgo = time.Unix(1, 0)
 
+// Protocols is a set of HTTP protocols.
+// The zero value is an empty set of protocols.
+//
+// The supported protocols are:
+//
+//   - HTTP1 is the HTTP/1.0 and HTTP/1.1 protocols.
+//     HTTP1 is supported on both unsecured TCP and secured TLS connections.
+//
+//   - HTTP2 is the HTTP/2 protocol over a TLS connection.
+//
+//   - UnencryptedHTTP2 is the HTTP/2 protocol over an unsecured TCP connection,
+//     also known as h2c. A [Server] accepts both connections that begin with
+//     the HTTP/2 connection preface ("prior knowledge") and HTTP/1.1 requests
+//     carrying an "Upgrade: h2c" header. A [Transport] only uses prior
+//     knowledge, and only when HTTP1 is not also enabled.
+//
+//   - HTTP3 is the HTTP/3 protocol over QUIC. Support for HTTP/3 is
+//     experimental. A [Transport] with HTTP3 enabled sends https requests
+//     over QUIC, falling back to HTTP1 or HTTP2 (if enabled) when a QUIC
+//     connection cannot be established. A [Server] serves HTTP/3 only
+//     through [Server.ServeQUIC] and [Server.ListenAndServeQUIC].
+type Protocols struct {
+	bits uint8
+}
+
+const (
+	protoHTTP1 = 1 << iota
+	protoHTTP2
+	protoUnencryptedHTTP2
+	protoHTTP3
+)
+
+// HTTP1 reports whether p includes HTTP/1.
+func (p Protocols) HTTP1() bool { return p.bits&protoHTTP1 != 0 }
+
+// SetHTTP1 adds or removes HTTP/1 from p.
+func (p *Protocols) SetHTTP1(ok bool) { p.setBit(protoHTTP1, ok) }
+
+// HTTP2 reports whether p includes HTTP/2.
+func (p Protocols) HTTP2() bool { return p.bits&protoHTTP2 != 0 }
+
+// SetHTTP2 adds or removes HTTP/2 from p.
+func (p *Protocols) SetHTTP2(ok bool) { p.setBit(protoHTTP2, ok) }
+
+// UnencryptedHTTP2 reports whether p includes unencrypted HTTP/2.
+func (p Protocols) UnencryptedHTTP2() bool { return p.bits&protoUnencryptedHTTP2 != 0 }
+
+// SetUnencryptedHTTP2 adds or removes unencrypted HTTP/2 from p.
+func (p *Protocols) SetUnencryptedHTTP2(ok bool) { p.setBit(protoUnencryptedHTTP2, ok) }
+
+// HTTP3 reports whether p includes HTTP/3.
+func (p Protocols) HTTP3() bool { return p.bits&protoHTTP3 != 0 }
+
+// SetHTTP3 adds or removes HTTP/3 from p.
+func (p *Protocols) SetHTTP3(ok bool) { p.setBit(protoHTTP3, ok) }
+
+func (p *Protocols) setBit(bit uint8, ok bool) {
+	if ok {
+		p.bits |= bit
+	} else {
+		p.bits &^= bit
+	}
+}
+
+func (p Protocols) String() string {
+	var s []string
+	if p.HTTP1() {
+		s = append(s, "HTTP1")
+	}
+	if p.HTTP2() {
+		s = append(s, "HTTP2")
+	}
+	if p.UnencryptedHTTP2() {
+		s = append(s, "UnencryptedHTTP2")
+	}
+	if p.HTTP3() {
+		s = append(s, "HTTP3")
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+// nextProtoUnencryptedHTTP2 is the TLSNextProto key under which the HTTP/2
+// implementation registers its handler for unencrypted HTTP/2 connections.
+// It is never negotiated over TLS.
+const nextProtoUnencryptedHTTP2 = "unencrypted_http2"
+
+// unencryptedNetConnInTLSConn is used to pass an unencrypted net.Conn to
+// TLSNextProto functions, which only accept a *tls.Conn.
+// The *tls.Conn wrapping it must never be read from or written to;
+// the HTTP/2 implementation retrieves the net.Conn with UnencryptedNetConn.
+type unencryptedNetConnInTLSConn struct {
+	net.Conn // panic on all net.Conn methods
+	conn     net.Conn
+}
+
+func (c unencryptedNetConnInTLSConn) UnencryptedNetConn() net.Conn {
+	return c.conn
+}
+
+func unencryptedTLSConn(c net.Conn) *tls.Conn {
+	return tls.Client(unencryptedNetConnInTLSConn{conn: c}, nil)
+}
+
+// isUnencryptedTLSConn reports whether tc was created by unencryptedTLSConn.
+func isUnencryptedTLSConn(tc *tls.Conn) bool {
+	_, ok := tc.NetConn().(unencryptedNetConnInTLSConn)
+	return ok
+}
+
+// adjustNextProtos returns the ALPN protocol list to offer or accept,
+// with "h2" and "http/1.1" added or removed according to protos.
+func adjustNextProtos(nextProtos []string, protos Protocols) []string {
+	var have Protocols
+	nextProtos = slices.DeleteFunc(slices.Clone(nextProtos), func(s string) bool {
+		switch s {
+		case "http/1.1":
+			if !protos.HTTP1() {
+				return true
+			}
+			have.SetHTTP1(true)
+		case "h2":
+			if !protos.HTTP2() {
+				return true
+			}
+			have.SetHTTP2(true)
+		}
+		return false
+	})
+	if protos.HTTP2() && !have.HTTP2() {
+		nextProtos = append(nextProtos, "h2")
+	}
+	if protos.HTTP1() && !have.HTTP1() {
+		nextProtos = append(nextProtos, "http/1.1")
+	}
+	return nextProtos
+}
+
 // omitBundledHTTP2 is set by omithttp2.go when the nethttpomithttp2
 // build tag is set. That means h2_bundle.go isn't compiled in and we
 // shouldn't try to use it.
@@ -0,0 +1,730 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package httpcache implements a private HTTP cache, as specified by
+// RFC 9111, in the form of an [http.RoundTripper].
+//
+// A [Transport] stores responses to GET requests and uses them to answer
+// later requests for the same resource while they are fresh. When a
+// stored response becomes stale, the Transport revalidates it with the
+// origin server using a conditional request, which lets the server reply
+// with 304 (Not Modified) instead of a complete response.
+//
+// Each response passed through the Transport has a Cache-Status header
+// (RFC 9211) describing how the cache handled it, for example
+//
+//	Cache-Status: httpcache; hit
+//	Cache-Status: httpcache; fwd=stale; fwd-status=304
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/internal/ascii"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Transport is an [http.RoundTripper] which caches responses.
+//
+// Only responses to GET requests without a Range header are cached.
+// A successful response to a request with an unsafe method, such as POST,
+// invalidates the stored response for its target URL.
+//
+// A Transport is a private cache: it is intended for a single user
+// agent, and it stores responses marked "private" and responses
+// to requests with an Authorization header which are marked "public",
+// "must-revalidate", or "s-maxage".
+//
+// A Transport must not be copied after first use.
+type Transport struct {
+	// Transport is used to make requests to origin servers.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Storage holds the cached responses.
+	// If nil, the Transport does not cache responses.
+	Storage Storage
+
+	// MaxBodySize is the size of the largest response body
+	// which is stored. If zero, a default of 10MB is used.
+	MaxBodySize int64
+
+	now func() time.Time // for testing; if nil, time.Now
+
+	mu           sync.Mutex
+	revalidating map[string]bool // keys being revalidated in the background
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) maxBodySize() int64 {
+	if t.MaxBodySize > 0 {
+		return t.MaxBodySize
+	}
+	return 10 << 20
+}
+
+func (t *Transport) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// cacheStatus values, from RFC 9211.
+const (
+	statusHit         = "httpcache; hit"
+	statusStale       = "httpcache; hit; fwd=stale" // served stale while revalidating
+	statusMiss        = "httpcache; fwd=uri-miss"
+	statusVaryMiss    = "httpcache; fwd=vary-miss"
+	statusRequest     = "httpcache; fwd=request"
+	statusRevalidated = "httpcache; fwd=stale; fwd-status=304"
+	statusForwarded   = "httpcache; fwd=stale"
+	statusBypass      = "httpcache; fwd=bypass"
+	statusOnlyCached  = "httpcache; fwd=miss; detail=only-if-cached"
+)
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Storage == nil || req.Method != "GET" || req.Header.Get("Range") != "" {
+		resp, err := t.transport().RoundTrip(req)
+		if err == nil && t.Storage != nil && !isSafe(req.Method) && resp.StatusCode < 400 {
+			t.invalidate(req, resp)
+		}
+		return resp, err
+	}
+
+	key := cacheKey(req.URL)
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		return t.forward(req, key, statusBypass)
+	}
+	e, status := t.load(key, req)
+	if e == nil {
+		if reqCC.has("only-if-cached") {
+			return onlyIfCachedResponse(req), nil
+		}
+		return t.forward(req, key, status)
+	}
+
+	now := t.timeNow()
+	switch e.usable(req, reqCC, now) {
+	case useFresh, useStale:
+		return e.response(req, now, statusHit), nil
+	case useStaleRevalidate:
+		resp := e.response(req, now, statusStale)
+		t.revalidateInBackground(req, key, e)
+		return resp, nil
+	}
+	if reqCC.has("only-if-cached") {
+		return onlyIfCachedResponse(req), nil
+	}
+	if noCacheRequest(req, reqCC) {
+		status = statusRequest
+	}
+	return t.revalidate(req, key, e, status)
+}
+
+// onlyIfCachedResponse returns the response for a request with the
+// only-if-cached directive which cannot be satisfied from the cache.
+// See RFC 9111, Section 5.2.1.7.
+func onlyIfCachedResponse(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        "504 Gateway Timeout",
+		StatusCode:    http.StatusGatewayTimeout,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Cache-Status": {statusOnlyCached}},
+		Body:          http.NoBody,
+		ContentLength: 0,
+		Request:       req,
+	}
+	return resp
+}
+
+// forward sends req to the origin server, storing the response
+// if possible.
+func (t *Transport) forward(req *http.Request, key, status string) (*http.Response, error) {
+	reqTime := t.timeNow()
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.maybeStore(req, key, reqTime, resp, status)
+	return resp, nil
+}
+
+// revalidate sends a conditional request for e to the origin server.
+func (t *Transport) revalidate(req *http.Request, key string, e *entry, status string) (*http.Response, error) {
+	creq, conditional := e.conditionalRequest(req.Context(), req)
+	reqTime := t.timeNow()
+	resp, err := t.transport().RoundTrip(creq)
+	if err != nil {
+		return nil, err
+	}
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		e.update(resp, reqTime, t.timeNow())
+		t.Storage.Set(key, e.encode(key))
+		return e.response(req, t.timeNow(), statusRevalidated), nil
+	}
+	if status == "" {
+		status = statusForwarded
+	}
+	resp.Request = req
+	t.maybeStore(req, key, reqTime, resp, status)
+	return resp, nil
+}
+
+// revalidateInBackground revalidates e without blocking the caller,
+// as permitted by the stale-while-revalidate directive.
+// At most one background revalidation runs for each key.
+func (t *Transport) revalidateInBackground(req *http.Request, key string, e *entry) {
+	t.mu.Lock()
+	if t.revalidating[key] {
+		t.mu.Unlock()
+		return
+	}
+	if t.revalidating == nil {
+		t.revalidating = make(map[string]bool)
+	}
+	t.revalidating[key] = true
+	t.mu.Unlock()
+
+	breq := req.Clone(context.WithoutCancel(req.Context()))
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.revalidating, key)
+			t.mu.Unlock()
+		}()
+		resp, err := t.revalidate(breq, key, e, statusForwarded)
+		if err != nil {
+			return
+		}
+		// Read the body so that it is stored.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+}
+
+// invalidate removes the stored responses affected by a successful
+// response to a request with an unsafe method.
+// See RFC 9111, Section 4.4.
+func (t *Transport) invalidate(req *http.Request, resp *http.Response) {
+	t.Storage.Delete(cacheKey(req.URL))
+	for _, h := range []string{"Location", "Content-Location"} {
+		v := resp.Header.Get(h)
+		if v == "" {
+			continue
+		}
+		u, err := req.URL.Parse(v)
+		if err != nil || u.Scheme != req.URL.Scheme || !ascii.EqualFold(u.Host, req.URL.Host) {
+			continue
+		}
+		t.Storage.Delete(cacheKey(u))
+	}
+}
+
+// load returns the stored entry for req, or nil and a Cache-Status
+// value describing the miss.
+func (t *Transport) load(key string, req *http.Request) (*entry, string) {
+	b, ok := t.Storage.Get(key)
+	if !ok {
+		return nil, statusMiss
+	}
+	e, err := decodeEntry(key, b)
+	if err != nil {
+		t.Storage.Delete(key)
+		return nil, statusMiss
+	}
+	if !e.varyMatches(req) {
+		return nil, statusVaryMiss
+	}
+	return e, ""
+}
+
+// maybeStore adds a Cache-Status header to resp and arranges for it to
+// be stored once its body has been read, if it may be stored.
+func (t *Transport) maybeStore(req *http.Request, key string, reqTime time.Time, resp *http.Response, status string) {
+	if !storable(req, resp) || resp.ContentLength > t.maxBodySize() {
+		resp.Header.Add("Cache-Status", status)
+		return
+	}
+	e := &entry{
+		reqTime:    reqTime,
+		respTime:   t.timeNow(),
+		vary:       varyValues(req, resp.Header),
+		statusCode: resp.StatusCode,
+		header:     resp.Header.Clone(),
+	}
+	resp.Header.Add("Cache-Status", status+"; stored")
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		max:        t.maxBodySize(),
+		done: func(body []byte) {
+			e.body = body
+			t.Storage.Set(key, e.encode(key))
+		},
+	}
+}
+
+// A cachingBody copies a response body as it is read,
+// and calls done with the complete body when it has been read to EOF.
+type cachingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	max  int64
+	done func([]byte) // nil once called or body is too large
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.done != nil {
+		if int64(b.buf.Len()+n) > b.max {
+			b.done = nil
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return n, err
+}
+
+// cacheKey returns the storage key for the response to a GET of u.
+func cacheKey(u *url.URL) string {
+	k := *u
+	k.Fragment = ""
+	k.RawFragment = ""
+	k.Scheme = lower(k.Scheme)
+	k.Host = lower(k.Host)
+	return "GET " + k.String()
+}
+
+// lower returns the ASCII lowercase version of s.
+// Non-ASCII strings are returned unchanged.
+func lower(s string) string {
+	if l, ok := ascii.ToLower(s); ok {
+		return l
+	}
+	return s
+}
+
+func isSafe(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+// heuristicallyCacheable reports whether a response with the given
+// status code may be cached without explicit freshness information.
+// See RFC 9110, Section 15.1.
+func heuristicallyCacheable(code int) bool {
+	switch code {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+// storable reports whether resp, the response to req, may be stored.
+// See RFC 9111, Section 3.
+func storable(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode == http.StatusPartialContent ||
+		resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	reqCC := parseCacheControl(req.Header)
+	cc := parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || cc.has("no-store") {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" &&
+		!cc.has("public") && !cc.has("must-revalidate") && !cc.has("s-maxage") {
+		return false
+	}
+	for _, v := range resp.Header.Values("Vary") {
+		if strings.Contains(v, "*") {
+			return false
+		}
+	}
+	_, hasMaxAge := cc.seconds("max-age")
+	explicit := hasMaxAge || resp.Header.Get("Expires") != "" ||
+		cc.has("public") || cc.has("private")
+	if !explicit && !heuristicallyCacheable(resp.StatusCode) {
+		return false
+	}
+	// A response which is never fresh and cannot be revalidated is useless.
+	return freshnessLifetime(resp.Header, resp.StatusCode, time.Time{}) > 0 ||
+		resp.Header.Get("Etag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// noCacheRequest reports whether req forbids using a stored response
+// without validating it.
+func noCacheRequest(req *http.Request, reqCC cacheControl) bool {
+	if reqCC.has("no-cache") {
+		return true
+	}
+	// RFC 9111, Section 5.4.
+	_, hasCC := req.Header["Cache-Control"]
+	return !hasCC && hasToken(req.Header.Values("Pragma"), "no-cache")
+}
+
+func hasToken(values []string, token string) bool {
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			if ascii.EqualFold(textproto.TrimString(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varyValues returns the values of the request headers named by the
+// Vary header in h.
+func varyValues(req *http.Request, h http.Header) http.Header {
+	var vary http.Header
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = textproto.TrimString(name)
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(http.Header)
+			}
+			vary[textproto.CanonicalMIMEHeaderKey(name)] = []string{headerValue(req.Header, name)}
+		}
+	}
+	return vary
+}
+
+// headerValue returns the combined values of the header named name.
+func headerValue(h http.Header, name string) string {
+	return strings.Join(h.Values(name), ", ")
+}
+
+// A cacheControl holds the directives of a Cache-Control header.
+// Directive names are lower case. Directives without an argument
+// map to the empty string.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	var cc cacheControl
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = textproto.TrimString(d)
+			if d == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(d, "=")
+			name = lower(textproto.TrimString(name))
+			value = strings.Trim(textproto.TrimString(value), `"`)
+			if cc == nil {
+				cc = make(cacheControl)
+			}
+			if _, dup := cc[name]; !dup {
+				cc[name] = value
+			}
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns the value of a directive whose argument is
+// a number of seconds.
+// An invalid argument is treated as zero.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	return parseSeconds(v), true
+}
+
+// parseSeconds parses a non-negative number of seconds,
+// treating invalid values as zero and saturating large ones.
+func parseSeconds(v string) time.Duration {
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return maxDuration
+		}
+		return 0
+	}
+	if n > uint64(maxDuration/time.Second) {
+		return maxDuration
+	}
+	return time.Duration(n) * time.Second
+}
+
+const maxDuration = 1<<63 - 1
+
+// freshnessLifetime returns the freshness lifetime of a response with
+// header h. See RFC 9111, Section 4.2.1.
+// If date is zero, the response's Date header is used.
+func freshnessLifetime(h http.Header, statusCode int, date time.Time) time.Duration {
+	cc := parseCacheControl(h)
+	if d, ok := cc.seconds("max-age"); ok {
+		return d
+	}
+	if date.IsZero() {
+		date, _ = http.ParseTime(h.Get("Date"))
+	}
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil || date.IsZero() {
+			return 0 // already expired
+		}
+		return max(expires.Sub(date), 0)
+	}
+	if !heuristicallyCacheable(statusCode) && !cc.has("public") {
+		return 0
+	}
+	// Heuristic freshness: 10% of the time since the last modification.
+	// See RFC 9111, Section 4.2.2.
+	lm, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil || date.IsZero() || !lm.Before(date) {
+		return 0
+	}
+	return min(date.Sub(lm)/10, 24*time.Hour)
+}
+
+// An entry is a stored response.
+type entry struct {
+	reqTime  time.Time   // when the request was sent
+	respTime time.Time   // when the response was received
+	vary     http.Header // request header values selected by Vary
+
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+const entryMagic = "httpcache-entry-v1"
+
+// encode serializes e, storing key to detect collisions.
+//
+// The encoding is a line with the magic string, key, and request and
+// response times, then the Vary request headers as a MIME header block,
+// then the response in HTTP/1.1 wire format.
+func (e *entry) encode(key string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %d %d %s\r\n", entryMagic, e.reqTime.UnixNano(), e.respTime.UnixNano(), key)
+	e.vary.Write(&buf)
+	buf.WriteString("\r\n")
+	resp := &http.Response{
+		StatusCode:    e.statusCode,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+	resp.Write(&buf)
+	return buf.Bytes()
+}
+
+var errBadEntry = errors.New("httpcache: malformed stored entry")
+
+func decodeEntry(key string, b []byte) (*entry, error) {
+	br := bufio.NewReader(bytes.NewReader(b))
+	tr := textproto.NewReader(br)
+	line, err := tr.ReadLine()
+	if err != nil {
+		return nil, errBadEntry
+	}
+	f := strings.SplitN(line, " ", 4)
+	if len(f) != 4 || f[0] != entryMagic || f[3] != key {
+		return nil, errBadEntry
+	}
+	reqTime, err1 := strconv.ParseInt(f[1], 10, 64)
+	respTime, err2 := strconv.ParseInt(f[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, errBadEntry
+	}
+	vary, err := tr.ReadMIMEHeader()
+	if err != nil {
+		return nil, errBadEntry
+	}
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return nil, errBadEntry
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errBadEntry
+	}
+	e := &entry{
+		reqTime:    time.Unix(0, reqTime),
+		respTime:   time.Unix(0, respTime),
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       body,
+	}
+	if len(vary) > 0 {
+		e.vary = http.Header(vary)
+	}
+	return e, nil
+}
+
+// varyMatches reports whether e may be used for req,
+// according to its Vary header. See RFC 9111, Section 4.1.
+func (e *entry) varyMatches(req *http.Request) bool {
+	for name, v := range e.vary {
+		if headerValue(req.Header, name) != v[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// age returns the current age of e. See RFC 9111, Section 4.2.3.
+func (e *entry) age(now time.Time) time.Duration {
+	date, err := http.ParseTime(e.header.Get("Date"))
+	if err != nil {
+		date = e.respTime
+	}
+	apparentAge := max(e.respTime.Sub(date), 0)
+	ageValue := parseSeconds(e.header.Get("Age"))
+	responseDelay := e.respTime.Sub(e.reqTime)
+	correctedAgeValue := ageValue + responseDelay
+	correctedInitialAge := max(apparentAge, correctedAgeValue)
+	residentTime := now.Sub(e.respTime)
+	return correctedInitialAge + residentTime
+}
+
+func (e *entry) freshnessLifetime() time.Duration {
+	date, err := http.ParseTime(e.header.Get("Date"))
+	if err != nil {
+		date = e.respTime
+	}
+	return freshnessLifetime(e.header, e.statusCode, date)
+}
+
+// A use says how a stored response may be used to satisfy a request.
+type use int
+
+const (
+	useRevalidate      use = iota // must be validated with the origin
+	useFresh                      // fresh, may be used
+	useStale                      // stale, but the request accepts it
+	useStaleRevalidate            // stale, may be used while revalidating
+)
+
+// usable reports how e may be used to satisfy req.
+// See RFC 9111, Section 4.2 and 5.2.
+func (e *entry) usable(req *http.Request, reqCC cacheControl, now time.Time) use {
+	cc := parseCacheControl(e.header)
+	if noCacheRequest(req, reqCC) || cc.has("no-cache") {
+		return useRevalidate
+	}
+	age := e.age(now)
+	lifetime := e.freshnessLifetime()
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return useRevalidate
+	}
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+	if age < lifetime {
+		return useFresh
+	}
+	if cc.has("must-revalidate") || cc.has("proxy-revalidate") {
+		return useRevalidate
+	}
+	staleness := age - lifetime
+	if v, ok := reqCC["max-stale"]; ok && (v == "" || staleness <= parseSeconds(v)) {
+		return useStale
+	}
+	if swr, ok := cc.seconds("stale-while-revalidate"); ok && staleness <= swr {
+		return useStaleRevalidate
+	}
+	return useRevalidate
+}
+
+// response returns a response to req from e.
+func (e *entry) response(req *http.Request, now time.Time, status string) *http.Response {
+	h := e.header.Clone()
+	h.Set("Age", strconv.FormatInt(int64(e.age(now)/time.Second), 10))
+	h.Add("Cache-Status", status)
+	return &http.Response{
+		Status:        strconv.Itoa(e.statusCode) + " " + http.StatusText(e.statusCode),
+		StatusCode:    e.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// conditionalRequest returns a request to validate e, and reports
+// whether it added conditions to req. See RFC 9111, Section 4.3.1.
+// If req already has conditions of its own, it is returned unchanged.
+func (e *entry) conditionalRequest(ctx context.Context, req *http.Request) (*http.Request, bool) {
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since"} {
+		if req.Header.Get(h) != "" {
+			return req, false
+		}
+	}
+	etag := e.header.Get("Etag")
+	lastModified := e.header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return req, false
+	}
+	creq := req.Clone(ctx)
+	if etag != "" {
+		creq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		creq.Header.Set("If-Modified-Since", lastModified)
+	}
+	return creq, true
+}
+
+// update refreshes e from a 304 response. See RFC 9111, Section 4.3.4.
+func (e *entry) update(resp *http.Response, reqTime, respTime time.Time) {
+	for k, v := range resp.Header {
+		switch k {
+		case "Content-Length", "Content-Encoding", "Content-Range", "Transfer-Encoding",
+			"Connection", "Keep-Alive", "Trailer", "Upgrade", "Cache-Status":
+			continue
+		}
+		e.header[k] = v
+	}
+	e.reqTime = reqTime
+	e.respTime = respTime
+}
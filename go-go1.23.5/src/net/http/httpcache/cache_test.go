@@ -0,0 +1,371 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A cacheTest is a Transport in front of a test server,
+// with a fake clock shared by both.
+type cacheTest struct {
+	t     *testing.T
+	tr    *Transport
+	ts    *httptest.Server
+	calls atomic.Int32
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newCacheTest(t *testing.T, storage Storage, h func(w http.ResponseWriter, r *http.Request)) *cacheTest {
+	ct := &cacheTest{
+		t:   t,
+		now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	ct.ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct.calls.Add(1)
+		w.Header().Set("Date", ct.clock().Format(http.TimeFormat))
+		h(w, r)
+	}))
+	t.Cleanup(ct.ts.Close)
+	ct.tr = &Transport{
+		Transport: ct.ts.Client().Transport,
+		Storage:   storage,
+		now:       ct.clock,
+	}
+	return ct
+}
+
+func (ct *cacheTest) clock() time.Time {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.now
+}
+
+func (ct *cacheTest) advance(d time.Duration) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.now = ct.now.Add(d)
+}
+
+// get makes a request and reads the complete response.
+// Pairs of strings in header are added to the request.
+func (ct *cacheTest) get(method, path string, header ...string) (*http.Response, string) {
+	ct.t.Helper()
+	req, _ := http.NewRequest(method, ct.ts.URL+path, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	resp, err := ct.tr.RoundTrip(req)
+	if err != nil {
+		ct.t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		ct.t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+// want checks the response to a request, and the number
+// of requests the server has received so far.
+func (ct *cacheTest) want(resp *http.Response, body, wantBody, wantStatus string, wantCalls int32) {
+	ct.t.Helper()
+	if body != wantBody {
+		ct.t.Errorf("body = %q, want %q", body, wantBody)
+	}
+	if got := resp.Header.Get("Cache-Status"); got != wantStatus {
+		ct.t.Errorf("Cache-Status = %q, want %q", got, wantStatus)
+	}
+	if got := ct.calls.Load(); got != wantCalls {
+		ct.t.Errorf("server received %v requests, want %v", got, wantCalls)
+	}
+}
+
+func TestCacheFresh(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "hello")
+	})
+	resp, body := ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; fwd=uri-miss; stored", 1)
+
+	ct.advance(30 * time.Second)
+	resp, body = ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; hit", 1)
+	if age := resp.Header.Get("Age"); age != "30" {
+		t.Errorf("Age = %q, want 30", age)
+	}
+
+	// The request can demand a fresher response.
+	resp, body = ct.get("GET", "/", "Cache-Control", "max-age=10")
+	ct.want(resp, body, "hello", "httpcache; fwd=stale; stored", 2)
+
+	// Other URLs are not affected.
+	resp, body = ct.get("GET", "/other")
+	ct.want(resp, body, "hello", "httpcache; fwd=uri-miss; stored", 3)
+}
+
+func TestCacheRevalidateETag(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("Etag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.Header().Set("X-Revalidated", "yes")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "hello")
+	})
+	ct.get("GET", "/")
+	ct.advance(20 * time.Second)
+	resp, body := ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; fwd=stale; fwd-status=304", 2)
+	if resp.StatusCode != 200 || resp.Header.Get("X-Revalidated") != "yes" {
+		t.Errorf("got status %v, X-Revalidated %q; want 200 with headers from the 304", resp.StatusCode, resp.Header.Get("X-Revalidated"))
+	}
+
+	// The revalidated response is fresh again.
+	ct.advance(5 * time.Second)
+	resp, body = ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; hit", 2)
+
+	// A request with its own conditions receives the server's response.
+	ct.advance(20 * time.Second)
+	resp, _ = ct.get("GET", "/", "If-None-Match", `"v1"`)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional request: status %v, want 304", resp.StatusCode)
+	}
+}
+
+func TestCacheRevalidateLastModified(t *testing.T) {
+	var lastModified string
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "hello")
+	})
+	lastModified = ct.clock().Add(-10 * 24 * time.Hour).Format(http.TimeFormat)
+
+	// The heuristic freshness lifetime is 10% of 10 days.
+	ct.get("GET", "/")
+	ct.advance(23 * time.Hour)
+	resp, body := ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; hit", 1)
+	ct.advance(2 * time.Hour)
+	resp, body = ct.get("GET", "/")
+	ct.want(resp, body, "hello", "httpcache; fwd=stale; fwd-status=304", 2)
+}
+
+func TestCacheVary(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, "lang="+r.Header.Get("Accept-Language"))
+	})
+	resp, body := ct.get("GET", "/", "Accept-Language", "en")
+	ct.want(resp, body, "lang=en", "httpcache; fwd=uri-miss; stored", 1)
+	resp, body = ct.get("GET", "/", "Accept-Language", "en")
+	ct.want(resp, body, "lang=en", "httpcache; hit", 1)
+	resp, body = ct.get("GET", "/", "Accept-Language", "fr")
+	ct.want(resp, body, "lang=fr", "httpcache; fwd=vary-miss; stored", 2)
+	resp, body = ct.get("GET", "/", "Accept-Language", "fr")
+	ct.want(resp, body, "lang=fr", "httpcache; hit", 2)
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int32
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=60")
+		io.WriteString(w, "v"+string(rune('0'+version.Load())))
+	})
+	ct.get("GET", "/")
+	version.Store(1)
+	ct.advance(30 * time.Second)
+	resp, body := ct.get("GET", "/")
+	if body != "v0" || resp.Header.Get("Cache-Status") != "httpcache; hit; fwd=stale" {
+		t.Errorf("got %q, Cache-Status %q; want stale response served while revalidating",
+			body, resp.Header.Get("Cache-Status"))
+	}
+
+	// Wait for the background revalidation to store the new response.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, body = ct.get("GET", "/")
+		if body == "v1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("background revalidation did not update the cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := resp.Header.Get("Cache-Status"); got != "httpcache; hit" {
+		t.Errorf("Cache-Status = %q, want hit", got)
+	}
+
+	// Past the stale-while-revalidate window, the request waits.
+	ct.advance(100 * time.Second)
+	resp, body = ct.get("GET", "/")
+	ct.want(resp, body, "v1", "httpcache; fwd=stale; stored", 3)
+}
+
+func TestCacheDirectives(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/no-cache":
+			w.Header().Set("Cache-Control", "no-cache, max-age=60")
+		case "/must-revalidate":
+			w.Header().Set("Cache-Control", "max-age=10, must-revalidate")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		io.WriteString(w, r.URL.Path)
+	})
+
+	ct.get("GET", "/no-store")
+	resp, body := ct.get("GET", "/no-store")
+	ct.want(resp, body, "/no-store", "httpcache; fwd=uri-miss", 2)
+
+	ct.get("GET", "/no-cache")
+	resp, body = ct.get("GET", "/no-cache")
+	ct.want(resp, body, "/no-cache", "httpcache; fwd=stale; stored", 4)
+
+	ct.get("GET", "/")
+	resp, body = ct.get("GET", "/", "Cache-Control", "no-cache")
+	ct.want(resp, body, "/", "httpcache; fwd=request; stored", 6)
+	resp, body = ct.get("GET", "/", "Pragma", "no-cache")
+	ct.want(resp, body, "/", "httpcache; fwd=request; stored", 7)
+
+	ct.get("GET", "/must-revalidate")
+	ct.advance(20 * time.Second)
+	resp, body = ct.get("GET", "/must-revalidate", "Cache-Control", "max-stale")
+	ct.want(resp, body, "/must-revalidate", "httpcache; fwd=stale; stored", 9)
+	ct.advance(20 * time.Second)
+	resp, body = ct.get("GET", "/", "Cache-Control", "max-stale=60")
+	ct.want(resp, body, "/", "httpcache; hit", 9)
+
+	resp, _ = ct.get("GET", "/missing", "Cache-Control", "only-if-cached")
+	if resp.StatusCode != http.StatusGatewayTimeout || ct.calls.Load() != 9 {
+		t.Errorf("only-if-cached miss: status %v, want 504 without a request", resp.StatusCode)
+	}
+
+	resp, body = ct.get("GET", "/", "Authorization", "secret", "Cache-Control", "no-cache")
+	ct.want(resp, body, "/", "httpcache; fwd=request", 10)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.Method == "POST" {
+			w.Header().Set("Location", "/other")
+		}
+		io.WriteString(w, r.Method+" "+r.URL.Path)
+	})
+	ct.get("GET", "/")
+	ct.get("GET", "/other")
+	ct.get("POST", "/")
+	resp, body := ct.get("GET", "/")
+	ct.want(resp, body, "GET /", "httpcache; fwd=uri-miss; stored", 4)
+	resp, body = ct.get("GET", "/other")
+	ct.want(resp, body, "GET /other", "httpcache; fwd=uri-miss; stored", 5)
+}
+
+func TestCacheLargeBody(t *testing.T) {
+	ct := newCacheTest(t, NewMemoryStorage(0), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, strings.Repeat("x", 100))
+	})
+	ct.tr.MaxBodySize = 50
+	ct.get("GET", "/")
+	resp, _ := ct.get("GET", "/")
+	if got := resp.Header.Get("Cache-Status"); got != "httpcache; fwd=uri-miss" {
+		t.Errorf("Cache-Status = %q, want miss without storing", got)
+	}
+}
+
+func TestCacheDiskStorage(t *testing.T) {
+	dir := t.TempDir()
+	ct := newCacheTest(t, NewDiskStorage(dir), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept")
+		w.Header().Add("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "not found\n")
+	})
+	ct.get("GET", "/", "Accept", "text/plain")
+
+	// A new Transport sharing the directory uses the stored response.
+	ct.tr = &Transport{Storage: NewDiskStorage(dir), now: ct.clock}
+	resp, body := ct.get("GET", "/", "Accept", "text/plain")
+	ct.want(resp, body, "not found\n", "httpcache; hit", 1)
+	if resp.StatusCode != 404 || len(resp.Header["X-Multi"]) != 2 {
+		t.Errorf("stored response: status %v, X-Multi %q; want 404, [a b]", resp.StatusCode, resp.Header["X-Multi"])
+	}
+}
+
+func TestMemoryStorageEviction(t *testing.T) {
+	s := NewMemoryStorage(10)
+	s.Set("a", []byte("aaaa"))
+	s.Set("b", []byte("bbbb"))
+	s.Get("a") // make b the least recently used
+	s.Set("c", []byte("cccc"))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := s.Get(key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+	s.Set("d", []byte("too large to store"))
+	if _, ok := s.Get("d"); ok {
+		t.Errorf("value larger than the storage was stored")
+	}
+	s.Delete("a")
+	if _, ok := s.Get("a"); ok {
+		t.Errorf("Get after Delete found the value")
+	}
+}
+
+func TestMemoryStorageZero(t *testing.T) {
+	var s MemoryStorage
+	if _, ok := s.Get("a"); ok {
+		t.Errorf("Get on empty storage found a value")
+	}
+	s.Delete("a")
+	s.Set("a", []byte("aaaa"))
+	if v, ok := s.Get("a"); !ok || string(v) != "aaaa" {
+		t.Errorf("Get = %q, %v; want %q, true", v, ok, "aaaa")
+	}
+}
+
+func TestDiskStorage(t *testing.T) {
+	s := NewDiskStorage(t.TempDir() + "/cache")
+	if _, ok := s.Get("k"); ok {
+		t.Errorf("Get on empty storage found a value")
+	}
+	s.Set("k", []byte("v1"))
+	s.Set("k", []byte("v2"))
+	if v, ok := s.Get("k"); !ok || string(v) != "v2" {
+		t.Errorf("Get = %q, %v; want v2, true", v, ok)
+	}
+	s.Delete("k")
+	if _, ok := s.Get("k"); ok {
+		t.Errorf("Get after Delete found the value")
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Storage is the interface to the backend in which a [Transport]
+// keeps its cached responses.
+//
+// Storage is best effort: an implementation may drop entries at any time,
+// and need not report failures. A Get which finds nothing causes the
+// Transport to forward the request to the origin server.
+//
+// Implementations of Storage must be safe for concurrent use by
+// multiple goroutines.
+type Storage interface {
+	// Get returns the value stored under key,
+	// and reports whether it was found.
+	Get(key string) (value []byte, ok bool)
+
+	// Set stores value under key, replacing any existing value.
+	// The Storage may retain value.
+	Set(key string, value []byte)
+
+	// Delete removes any value stored under key.
+	Delete(key string)
+}
+
+// MemoryStorage is a [Storage] which keeps entries in memory.
+// When the total size of its values exceeds its limit,
+// it discards the least recently used entries.
+//
+// The zero value is an empty MemoryStorage with no limit.
+type MemoryStorage struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     list.List // of *memoryEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryStorage returns a new MemoryStorage which holds
+// at most maxBytes of values. If maxBytes is zero or negative,
+// the size of the storage is not limited.
+func NewMemoryStorage(maxBytes int64) *MemoryStorage {
+	return &MemoryStorage{maxBytes: maxBytes}
+}
+
+// Get implements [Storage].
+func (s *MemoryStorage) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, true
+}
+
+// Set implements [Storage].
+func (s *MemoryStorage) Set(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(key)
+	if s.maxBytes > 0 && int64(len(value)) > s.maxBytes {
+		return
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]*list.Element)
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key, value})
+	s.size += int64(len(value))
+	for s.maxBytes > 0 && s.size > s.maxBytes {
+		s.deleteLocked(s.lru.Back().Value.(*memoryEntry).key)
+	}
+}
+
+// Delete implements [Storage].
+func (s *MemoryStorage) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(key)
+}
+
+func (s *MemoryStorage) deleteLocked(key string) {
+	el, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.lru.Remove(el)
+	delete(s.entries, key)
+	s.size -= int64(len(el.Value.(*memoryEntry).value))
+}
+
+// DiskStorage is a [Storage] which keeps each entry in a file
+// in a directory. It does not limit the size of the directory.
+//
+// Several DiskStorages, in one or more processes,
+// may share a directory.
+type DiskStorage struct {
+	dir string
+}
+
+// NewDiskStorage returns a new DiskStorage which keeps its entries
+// in dir. The directory is created when the first entry is stored,
+// if it does not already exist.
+func NewDiskStorage(dir string) *DiskStorage {
+	return &DiskStorage{dir: dir}
+}
+
+// path returns the name of the file which holds the value for key.
+func (s *DiskStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements [Storage].
+func (s *DiskStorage) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set implements [Storage].
+// The value is written to a temporary file which is then renamed,
+// so that concurrent readers never see a partial value.
+func (s *DiskStorage) Set(key string, value []byte) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// Delete implements [Storage].
+func (s *DiskStorage) Delete(key string) {
+	os.Remove(s.path(key))
+}
@@ -0,0 +1,340 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Fault injection for Server.
+
+package httptest
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A FaultKind identifies a kind of failure injected by a [Server].
+type FaultKind int
+
+const (
+	// FaultDelayHeaders delays the response headers by the fault's
+	// Delay, or, if Delay is zero, until the request is canceled,
+	// simulating a server that exceeds the client's response header
+	// timeout. The handler is not called if the request is canceled
+	// while it is delayed.
+	FaultDelayHeaders FaultKind = iota + 1
+
+	// FaultSlowRead makes each read of the request body by the handler
+	// wait for the fault's Delay, and return at most the fault's Bytes
+	// bytes, or one byte if Bytes is zero.
+	FaultSlowRead
+
+	// FaultSlowWrite writes the response body in pieces of at most the
+	// fault's Bytes bytes, or one byte if Bytes is zero, flushing each
+	// piece and waiting for the fault's Delay before writing the next.
+	FaultSlowWrite
+
+	// FaultReset aborts the response once the fault's Bytes bytes of
+	// the body have been sent, or when the handler returns if it
+	// writes fewer. An HTTP/1 connection is reset (its socket is closed
+	// with SO_LINGER set to zero, so the client sees a TCP RST), and an
+	// HTTP/2 stream is reset with an RST_STREAM frame. If Bytes is
+	// negative, the response is aborted before its headers are sent.
+	FaultReset
+
+	// FaultTruncateChunked sends the response body with the chunked
+	// transfer encoding, and closes the connection after the fault's
+	// Bytes bytes, without sending the terminating chunk. HTTP/2 has
+	// no chunked encoding; on an HTTP/2 connection, FaultTruncateChunked
+	// is the same as FaultReset.
+	FaultTruncateChunked
+
+	// FaultGoAway closes the connection after the response. An HTTP/2
+	// server sends a GOAWAY frame and closes the connection once its
+	// other streams are done; an HTTP/1 server sends the response
+	// with a "Connection: close" header.
+	FaultGoAway
+)
+
+var faultKindNames = []string{
+	FaultDelayHeaders:    "FaultDelayHeaders",
+	FaultSlowRead:        "FaultSlowRead",
+	FaultSlowWrite:       "FaultSlowWrite",
+	FaultReset:           "FaultReset",
+	FaultTruncateChunked: "FaultTruncateChunked",
+	FaultGoAway:          "FaultGoAway",
+}
+
+func (k FaultKind) String() string {
+	if k > 0 && int(k) < len(faultKindNames) {
+		return faultKindNames[k]
+	}
+	return "FaultKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A Fault describes a failure for a [Server] to inject into its
+// responses. See [Server.InjectFault].
+type Fault struct {
+	// Kind is the kind of failure.
+	Kind FaultKind
+
+	// Match reports whether the fault applies to a request.
+	// If Match is nil, the fault applies to every request.
+	Match func(*http.Request) bool
+
+	// Times is the number of requests the fault is injected into,
+	// after which it is discarded. If Times is zero, the fault is
+	// injected into every request it applies to.
+	Times int
+
+	// Delay and Bytes are parameters of the fault, as described
+	// by its Kind.
+	Delay time.Duration
+	Bytes int
+}
+
+// An injectedFault is a Fault and the number of times
+// it remains to be injected.
+type injectedFault struct {
+	Fault
+	left int // or 0 for unlimited
+}
+
+// InjectFault arranges for the server to inject a fault into the
+// responses to future requests that the fault applies to.
+// Faults may be injected and cleared before or after the server
+// is started.
+//
+// When several faults apply to a request, only the first one
+// injected is used.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &injectedFault{Fault: f, left: f.Times})
+}
+
+// ClearFaults discards the faults injected with [Server.InjectFault].
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// takeFault returns the fault to inject into the response to r, if any.
+func (s *Server) takeFault(r *http.Request) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.faults) == 0 {
+		return Fault{}, false
+	}
+	for i, f := range s.faults {
+		if f.Match != nil && !f.Match(r) {
+			continue
+		}
+		if f.left > 0 {
+			f.left--
+			if f.left == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f.Fault, true
+	}
+	return Fault{}, false
+}
+
+// A faultHandler injects a Server's faults into the responses
+// of its handler.
+type faultHandler struct {
+	s *Server
+	h http.Handler // or nil for http.DefaultServeMux
+}
+
+func (fh faultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := fh.h
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	f, ok := fh.s.takeFault(r)
+	if !ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	ctx := r.Context()
+	switch f.Kind {
+	case FaultDelayHeaders:
+		var timer <-chan time.Time
+		if f.Delay > 0 {
+			t := time.NewTimer(f.Delay)
+			defer t.Stop()
+			timer = t.C
+		}
+		select {
+		case <-timer:
+		case <-ctx.Done():
+			return
+		}
+	case FaultSlowRead:
+		r.Body = &slowReader{ReadCloser: r.Body, ctx: ctx, f: f}
+	case FaultSlowWrite:
+		w = &slowWriter{ResponseWriter: w, ctx: ctx, f: f}
+	case FaultReset, FaultTruncateChunked:
+		aw := &abortWriter{ResponseWriter: w, r: r, f: f}
+		if f.Bytes < 0 {
+			aw.abort()
+		}
+		h.ServeHTTP(aw, r)
+		aw.abort()
+		return
+	case FaultGoAway:
+		w.Header().Set("Connection", "close")
+	}
+	h.ServeHTTP(w, r)
+}
+
+// pieceSize returns the number of bytes to read or write at once
+// for a slow fault.
+func (f *Fault) pieceSize() int {
+	return max(f.Bytes, 1)
+}
+
+// sleep waits for the fault's Delay, returning early
+// with an error if ctx is done.
+func (f *Fault) sleep(ctx context.Context) error {
+	if f.Delay <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(f.Delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// A slowReader is a request body for FaultSlowRead.
+type slowReader struct {
+	io.ReadCloser
+	ctx context.Context
+	f   Fault
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if err := r.f.sleep(r.ctx); err != nil {
+		return 0, err
+	}
+	if n := r.f.pieceSize(); len(p) > n {
+		p = p[:n]
+	}
+	return r.ReadCloser.Read(p)
+}
+
+// A slowWriter is a ResponseWriter for FaultSlowWrite.
+type slowWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	f       Fault
+	started bool
+}
+
+func (w *slowWriter) Write(p []byte) (n int, err error) {
+	rc := http.NewResponseController(w.ResponseWriter)
+	for len(p) > 0 {
+		if w.started {
+			if err := w.f.sleep(w.ctx); err != nil {
+				return n, err
+			}
+		}
+		w.started = true
+		piece := p[:min(len(p), w.f.pieceSize())]
+		m, err := w.ResponseWriter.Write(piece)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if err := rc.Flush(); err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+func (w *slowWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// An abortWriter is a ResponseWriter for FaultReset
+// and FaultTruncateChunked.
+type abortWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	f           Fault
+	wroteHeader bool
+	written     int
+}
+
+func (w *abortWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= 200 {
+		w.wroteHeader = true
+		if w.f.Kind == FaultTruncateChunked {
+			w.Header().Del("Content-Length")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *abortWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n := min(len(p), w.f.Bytes-w.written)
+	n, err := w.ResponseWriter.Write(p[:n])
+	w.written += n
+	if err == nil && w.written >= w.f.Bytes {
+		w.abort()
+	}
+	return n, err
+}
+
+func (w *abortWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// abort sends what has been written of the response and aborts it.
+// It does not return.
+func (w *abortWriter) abort() {
+	rc := http.NewResponseController(w.ResponseWriter)
+	if w.f.Bytes >= 0 {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		rc.Flush()
+	}
+	if w.r.ProtoMajor == 1 {
+		if c, buf, err := rc.Hijack(); err == nil {
+			buf.Flush()
+			if w.f.Kind == FaultReset {
+				setLingerZero(c)
+			}
+			c.Close()
+		}
+	}
+	// On HTTP/2, this resets the stream. On HTTP/1, the connection
+	// has been hijacked and closed, and this stops the handler.
+	panic(http.ErrAbortHandler)
+}
+
+// setLingerZero arranges for closing c to reset its TCP connection.
+func setLingerZero(c net.Conn) {
+	if tc, ok := c.(*tls.Conn); ok {
+		c = tc.NetConn()
+	}
+	if tc, ok := c.(*net.TCPConn); ok {
+		tc.SetLinger(0)
+	}
+}
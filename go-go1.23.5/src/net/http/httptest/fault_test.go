@@ -0,0 +1,343 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// faultServerModes are the ways a Server is started by the fault tests.
+var faultServerModes = []struct {
+	name  string
+	start func(*Server)
+}{
+	{"http1", (*Server).Start},
+	{"http2", func(s *Server) {
+		s.EnableHTTP2 = true
+		s.StartTLS()
+	}},
+}
+
+// runFaultTest runs f with a server for each mode,
+// started with the fault injected.
+func runFaultTest(t *testing.T, h http.Handler, fault Fault, f func(t *testing.T, ts *Server, proto int)) {
+	for i, mode := range faultServerModes {
+		t.Run(mode.name, func(t *testing.T) {
+			ts := NewUnstartedServer(h)
+			ts.InjectFault(fault)
+			mode.start(ts)
+			defer ts.Close()
+			f(t, ts, i+1)
+		})
+	}
+}
+
+func TestServerFaultDelayHeaders(t *testing.T) {
+	runFaultTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}), Fault{Kind: FaultDelayHeaders, Times: 1}, func(t *testing.T, ts *Server, proto int) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+		if _, err := ts.Client().Do(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("delayed request: got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatalf("request after fault: %v", err)
+		}
+		res.Body.Close()
+	})
+}
+
+func TestServerFaultSlowRead(t *testing.T) {
+	const delay = 5 * time.Millisecond
+	var reads atomic.Int32
+	runFaultTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 100)
+		for {
+			n, err := r.Body.Read(buf)
+			if n > 2 {
+				t.Errorf("read %v bytes, want at most 2", n)
+			}
+			reads.Add(1)
+			w.Write(buf[:n])
+			if err != nil {
+				return
+			}
+		}
+	}), Fault{Kind: FaultSlowRead, Delay: delay, Bytes: 2}, func(t *testing.T, ts *Server, proto int) {
+		reads.Store(0)
+		start := time.Now()
+		res, err := ts.Client().Post(ts.URL, "text/plain", strings.NewReader("abcdef"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil || string(body) != "abcdef" {
+			t.Errorf("got body %q, %v; want %q", body, err, "abcdef")
+		}
+		if n := reads.Load(); n < 3 {
+			t.Errorf("handler read body in %v reads, want at least 3", n)
+		}
+		if d := time.Since(start); d < 3*delay {
+			t.Errorf("request took %v, want at least %v", d, 3*delay)
+		}
+	})
+}
+
+func TestServerFaultSlowWrite(t *testing.T) {
+	const delay = 5 * time.Millisecond
+	runFaultTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "abcdef")
+	}), Fault{Kind: FaultSlowWrite, Delay: delay, Bytes: 2}, func(t *testing.T, ts *Server, proto int) {
+		start := time.Now()
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil || string(body) != "abcdef" {
+			t.Errorf("got body %q, %v; want %q", body, err, "abcdef")
+		}
+		if d := time.Since(start); d < 2*delay {
+			t.Errorf("request took %v, want at least %v", d, 2*delay)
+		}
+	})
+}
+
+func TestServerFaultReset(t *testing.T) {
+	runFaultTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write(make([]byte, 100))
+	}), Fault{Kind: FaultReset, Bytes: 10, Times: 1}, func(t *testing.T, ts *Server, proto int) {
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if len(body) > 10 {
+			t.Errorf("read %v bytes of body, want at most 10", len(body))
+		}
+		switch {
+		case err == nil:
+			t.Errorf("reading body: got nil error, want reset")
+		case proto == 1 && !isConnReset(err) && !errors.Is(err, io.ErrUnexpectedEOF):
+			t.Errorf("reading body: got %v, want connection reset", err)
+		case proto == 2 && !strings.Contains(err.Error(), "INTERNAL_ERROR"):
+			t.Errorf("reading body: got %v, want stream reset", err)
+		}
+
+		ts.InjectFault(Fault{Kind: FaultReset, Bytes: -1, Times: 1})
+		if res, err := ts.Client().Get(ts.URL); err == nil {
+			res.Body.Close()
+			t.Errorf("reset before headers: got status %v, want error", res.Status)
+		}
+	})
+}
+
+// isConnReset reports whether err is from a read
+// of a connection which was reset.
+func isConnReset(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "read"
+}
+
+func TestServerFaultTruncateChunked(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "12")
+		io.WriteString(w, "hello, world")
+	}))
+	ts.InjectFault(Fault{Kind: FaultTruncateChunked, Bytes: 5})
+	ts.Start()
+	defer ts.Close()
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if got, want := res.TransferEncoding, []string{"chunked"}; !slices.Equal(got, want) {
+		t.Errorf("TransferEncoding = %q, want %q", got, want)
+	}
+	if string(body) != "hello" || err != io.ErrUnexpectedEOF {
+		t.Errorf("got body %q, %v; want %q, %v", body, err, "hello", io.ErrUnexpectedEOF)
+	}
+}
+
+func TestServerFaultGoAway(t *testing.T) {
+	for _, mode := range faultServerModes {
+		t.Run(mode.name, func(t *testing.T) {
+			ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			var conns atomic.Int32
+			ts.Config.ConnState = func(c net.Conn, cs http.ConnState) {
+				if cs == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			ts.InjectFault(Fault{Kind: FaultGoAway, Times: 1})
+			mode.start(ts)
+			defer ts.Close()
+			for i := 0; i < 3; i++ {
+				res, err := ts.Client().Get(ts.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			// The second request is sent on a new connection,
+			// which the third reuses.
+			if n := conns.Load(); n != 2 {
+				t.Errorf("server accepted %v connections, want 2", n)
+			}
+		})
+	}
+}
+
+func TestServerFaultMatch(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	ts.InjectFault(Fault{
+		Kind:  FaultReset,
+		Match: func(r *http.Request) bool { return r.URL.Path == "/fail" },
+		Times: 2,
+	})
+	ts.Start()
+	defer ts.Close()
+	get := func(path string) error {
+		res, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+		return err
+	}
+	for i, test := range []struct {
+		path    string
+		wantErr bool
+	}{
+		{"/ok", false},
+		{"/fail", true},
+		{"/ok", false},
+		{"/fail", true},
+		{"/fail", false},
+	} {
+		if err := get(test.path); (err != nil) != test.wantErr {
+			t.Errorf("request %v for %v: got error %v, want error: %v", i, test.path, err, test.wantErr)
+		}
+	}
+
+	ts.InjectFault(Fault{Kind: FaultReset})
+	ts.ClearFaults()
+	if err := get("/fail"); err != nil {
+		t.Errorf("after ClearFaults: %v", err)
+	}
+}
+
+func TestServerFaultAfterStart(t *testing.T) {
+	ts := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+	get := func() error {
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+		return err
+	}
+	if err := get(); err != nil {
+		t.Fatalf("without faults: %v", err)
+	}
+	ts.InjectFault(Fault{Kind: FaultReset, Bytes: 1, Times: 1})
+	if err := get(); err == nil {
+		t.Errorf("with fault injected after Start: got nil error, want reset")
+	}
+	if err := get(); err != nil {
+		t.Errorf("after fault: %v", err)
+	}
+}
+
+func TestFaultTransport(t *testing.T) {
+	ts := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "from server")
+	}))
+	defer ts.Close()
+	errReset := errors.New("reset")
+	ft := &FaultTransport{
+		Transport: ts.Client().Transport,
+		Script: []RoundTripFault{
+			{Err: errReset},
+			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}, Body: "busy"},
+			{BodyBytes: 4, BodyErr: io.ErrUnexpectedEOF},
+			{Delay: time.Hour},
+			{},
+		},
+	}
+	c := &http.Client{Transport: ft}
+
+	if _, err := c.Get(ts.URL); !errors.Is(err, errReset) {
+		t.Errorf("step 0: got error %v, want %v", err, errReset)
+	}
+
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("step 1: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || res.Header.Get("Retry-After") != "1" || string(body) != "busy" {
+		t.Errorf("step 1: got %v %v %q, want scripted response", res.Status, res.Header, body)
+	}
+
+	res, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("step 2: %v", err)
+	}
+	body, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "from" || err != io.ErrUnexpectedEOF {
+		t.Errorf("step 2: got body %q, %v; want %q, %v", body, err, "from", io.ErrUnexpectedEOF)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("step 3: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	for i := 4; i < 6; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("step %v: %v", i, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "from server" {
+			t.Errorf("step %v: got body %q, want %q", i, body, "from server")
+		}
+	}
+	if got, want := ft.RoundTrips(), 6; got != want {
+		t.Errorf("RoundTrips() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A FaultTransport is an [http.RoundTripper] which replays a script
+// of failures, so that the retry and timeout logic of HTTP clients
+// can be tested deterministically, without a network.
+//
+// Each round trip takes the next step of the Script. Once the script
+// is exhausted, requests are sent with Transport unchanged.
+//
+// For example, a client whose first two requests fail, and whose
+// third receives a response whose body is cut short, can be tested
+// with
+//
+//	ft := &httptest.FaultTransport{
+//		Transport: ts.Client().Transport,
+//		Script: []httptest.RoundTripFault{
+//			{Err: syscall.ECONNRESET},
+//			{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}},
+//			{BodyBytes: 10, BodyErr: io.ErrUnexpectedEOF},
+//		},
+//	}
+//	client := &http.Client{Transport: ft}
+//
+// A FaultTransport must not be copied after first use.
+type FaultTransport struct {
+	// Transport sends requests that are not replaced by the script.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Script is the sequence of faults injected into successive
+	// round trips. The zero RoundTripFault injects no fault.
+	// Script must not be modified after first use.
+	Script []RoundTripFault
+
+	mu sync.Mutex
+	n  int // number of round trips started
+}
+
+// A RoundTripFault is a step of a [FaultTransport]'s script,
+// describing the fault injected into a single round trip.
+type RoundTripFault struct {
+	// Delay is how long the round trip waits before proceeding.
+	// If the request's context is done first, RoundTrip returns
+	// the context's error.
+	Delay time.Duration
+
+	// Err, if non-nil, is returned by RoundTrip in place of a
+	// response, and the request is not sent.
+	Err error
+
+	// StatusCode, if non-zero, is the status code of a response
+	// returned by RoundTrip in place of sending the request.
+	// The response has the given Header and Body.
+	StatusCode int
+	Header     http.Header
+	Body       string
+
+	// BodyErr, if non-nil, is returned by the response body's Read
+	// method after BodyBytes bytes of the body have been read, or at
+	// the end of the body if it is shorter.
+	// This applies to responses from Transport as well as to those
+	// given by StatusCode.
+	BodyBytes int64
+	BodyErr   error
+}
+
+// RoundTrip implements the [http.RoundTripper] interface.
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	var f RoundTripFault
+	if t.n < len(t.Script) {
+		f = t.Script[t.n]
+	}
+	t.n++
+	t.mu.Unlock()
+
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeRequestBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+	if f.Err != nil {
+		closeRequestBody(req)
+		return nil, f.Err
+	}
+	var res *http.Response
+	if f.StatusCode != 0 {
+		closeRequestBody(req)
+		res = &http.Response{
+			Status:        strconv.Itoa(f.StatusCode) + " " + http.StatusText(f.StatusCode),
+			StatusCode:    f.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        f.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(f.Body)),
+			ContentLength: int64(len(f.Body)),
+			Request:       req,
+		}
+		if res.Header == nil {
+			res.Header = make(http.Header)
+		}
+	} else {
+		rt := t.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		var err error
+		res, err = rt.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.BodyErr != nil {
+		res.Body = &faultBody{
+			r:   io.LimitReader(res.Body, f.BodyBytes),
+			c:   res.Body,
+			err: f.BodyErr,
+		}
+	}
+	return res, nil
+}
+
+// RoundTrips returns the number of round trips started with t.
+func (t *FaultTransport) RoundTrips() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.n
+}
+
+// CloseIdleConnections closes the idle connections of t's Transport,
+// if it has a CloseIdleConnections method.
+func (t *FaultTransport) CloseIdleConnections() {
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if ci, ok := rt.(closeIdleTransport); ok {
+		ci.CloseIdleConnections()
+	}
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// A faultBody is a response body which fails after a number of bytes.
+type faultBody struct {
+	r   io.Reader
+	c   io.Closer
+	err error
+}
+
+func (b *faultBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		err = b.err
+	}
+	return n, err
+}
+
+func (b *faultBody) Close() error {
+	return b.c.Close()
+}
@@ -38,7 +38,8 @@ type Server struct {
 	TLS *tls.Config
 
 	// Config may be changed after calling NewUnstartedServer and
-	// before Start or StartTLS.
+	// before Start or StartTLS. Start and StartTLS wrap its Handler
+	// in one which injects the faults added by InjectFault.
 	Config *http.Server
 
 	// certificate is a parsed version of the TLS config certificate, if present.
@@ -48,9 +49,10 @@ type Server struct {
 	// Close blocks until all requests are finished.
 	wg sync.WaitGroup
 
-	mu     sync.Mutex // guards closed and conns
+	mu     sync.Mutex // guards closed, conns and faults
 	closed bool
 	conns  map[net.Conn]http.ConnState // except terminal states
+	faults []*injectedFault
 
 	// client is configured for use with the server.
 	// Its transport is automatically closed when Close is called.
@@ -313,8 +315,9 @@ func (s *Server) goServe() {
 }
 
 // wrap installs the connection state-tracking hook to know which
-// connections are idle.
+// connections are idle, and the handler which injects faults.
 func (s *Server) wrap() {
+	s.Config.Handler = faultHandler{s: s, h: s.Config.Handler}
 	oldHook := s.Config.ConnState
 	s.Config.ConnState = func(c net.Conn, cs http.ConnState) {
 		s.mu.Lock()
@@ -161,6 +161,29 @@ type ClientTrace struct {
 	// request and any body. It may be called multiple times
 	// in the case of retried requests.
 	WroteRequest func(WroteRequestInfo)
+
+	// Retry is called when an http.Client retries a request
+	// according to its RetryPolicy, before waiting for the
+	// retry's delay. The hooks above are then called again
+	// for the new attempt.
+	Retry func(RetryInfo)
+}
+
+// RetryInfo contains information provided to the Retry hook.
+type RetryInfo struct {
+	// Attempt is the number of the upcoming attempt.
+	// The first retry is attempt 2.
+	Attempt int
+
+	// Delay is how long the client waits before the attempt.
+	Delay time.Duration
+
+	// StatusCode is the status code of the previous attempt's
+	// response, or zero if the previous attempt failed with Err.
+	StatusCode int
+
+	// Err is the error from the previous attempt, if any.
+	Err error
 }
 
 // WroteRequestInfo contains information provided to the WroteRequest
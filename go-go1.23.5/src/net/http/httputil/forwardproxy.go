@@ -0,0 +1,335 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Forward proxy
+
+package httputil
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/internal/ascii"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ForwardProxy is an HTTP Handler which acts as a forward proxy: it
+// sends requests on behalf of its clients to the servers they name.
+//
+// Requests whose target is in absolute form ("GET http://example.com/
+// HTTP/1.1") are forwarded as by a [ReverseProxy], using Transport.
+// CONNECT requests open a tunnel to the host and port they name,
+// through which the client may then send any data, usually a TLS
+// connection to the server. Tunnels are supported over HTTP/1, by
+// hijacking the client's connection, and over HTTP/2 (RFC 9113
+// section 8.5). Other requests are answered with 400 Bad Request.
+//
+// A ForwardProxy forwards requests to any server unless Allow is set.
+// A proxy reachable by untrusted clients should use Allow to restrict
+// the servers they may reach, and Authenticate to restrict its clients.
+type ForwardProxy struct {
+	// Transport is used to forward requests other than CONNECT requests.
+	// If nil, a Transport with the settings of http.DefaultTransport,
+	// except that it does not itself use a proxy, is used.
+	Transport http.RoundTripper
+
+	// DialContext is used to connect to the targets of CONNECT requests.
+	// If nil, a net.Dialer is used.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Authenticate, if non-nil, reports whether a request is from an
+	// authorized client, usually by checking its Proxy-Authorization
+	// header (see [ProxyBasicAuth]). Requests which are not authorized
+	// are answered with 407 Proxy Authentication Required.
+	//
+	// The Proxy-Authorization header is not forwarded.
+	Authenticate func(r *http.Request) bool
+
+	// AuthChallenge is the challenge in the Proxy-Authenticate header
+	// of 407 responses. If empty, `Basic realm="proxy"` is used.
+	AuthChallenge string
+
+	// Allow, if non-nil, is called when the proxy connects to a server
+	// for an authorized request, with the request and the address, in
+	// "ip:port" form, to which it is connecting. The host named by the
+	// request has already been resolved, so a host name cannot be used
+	// to reach an address which Allow would refuse. Allow is called for
+	// each address the proxy tries, from the ControlContext hook of a
+	// [net.Dialer]. If Allow returns an error, the proxy does not
+	// connect, and answers the request with 403 Forbidden.
+	//
+	// A connection made for a request other than a CONNECT request may
+	// be reused for later requests to the same host, without calling
+	// Allow again, so Allow should restrict the addresses which clients
+	// may reach, rather than which clients may reach them.
+	//
+	// The proxy cannot observe the addresses to which DialContext or
+	// Transport connect. If DialContext is set for a CONNECT request,
+	// or Transport for another request, Allow is instead called before
+	// connecting, with the address, in "host:port" form, named by the
+	// request.
+	Allow func(r *http.Request, addr string) error
+
+	// ErrorLog specifies an optional logger for errors that occur
+	// when proxying requests. If nil, logging is done via the log
+	// package's standard logger.
+	ErrorLog *log.Logger
+
+	rpOnce sync.Once
+	rp     *ReverseProxy
+}
+
+func (p *ForwardProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if p.Authenticate != nil && !p.Authenticate(req) {
+		challenge := p.AuthChallenge
+		if challenge == "" {
+			challenge = `Basic realm="proxy"`
+		}
+		rw.Header().Set("Proxy-Authenticate", challenge)
+		http.Error(rw, "Proxy Authentication Required", http.StatusProxyAuthRequired)
+		return
+	}
+
+	var addr string
+	if req.Method == "CONNECT" {
+		host, port, err := net.SplitHostPort(req.Host)
+		if err != nil || host == "" || port == "" {
+			http.Error(rw, "invalid CONNECT target", http.StatusBadRequest)
+			return
+		}
+		addr = req.Host
+	} else {
+		if req.URL.Host == "" || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+			http.Error(rw, "request target must be an absolute http or https URL", http.StatusBadRequest)
+			return
+		}
+		addr = canonicalAddr(req.URL.Scheme, req.URL.Host)
+	}
+	if p.Allow != nil {
+		customDial := p.Transport != nil
+		if req.Method == "CONNECT" {
+			customDial = p.DialContext != nil
+		}
+		if customDial {
+			if err := p.Allow(req, addr); err != nil {
+				http.Error(rw, "Forbidden", http.StatusForbidden)
+				return
+			}
+		} else {
+			req = req.WithContext(context.WithValue(req.Context(), allowRequestKey{}, req))
+		}
+	}
+
+	if req.Method == "CONNECT" {
+		p.serveConnect(rw, req, addr)
+		return
+	}
+	p.rpOnce.Do(func() {
+		transport := p.Transport
+		if transport == nil {
+			transport = &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:        30 * time.Second,
+					KeepAlive:      30 * time.Second,
+					ControlContext: p.control,
+				}).DialContext,
+				ForceAttemptHTTP2:     true,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+			}
+		}
+		p.rp = &ReverseProxy{
+			Rewrite: func(pr *ProxyRequest) {
+				// The outbound URL is already the target URL.
+				pr.Out.Header.Add("Via", viaReceivedProtocol(pr.In)+" go")
+			},
+			Transport:    transport,
+			ErrorLog:     p.ErrorLog,
+			ErrorHandler: p.errorHandler,
+		}
+	})
+	p.rp.ServeHTTP(rw, req)
+}
+
+// allowRequestKey is the context key for the request for which
+// a ForwardProxy is connecting to a server.
+type allowRequestKey struct{}
+
+// A notAllowedError is the error connecting to an address
+// which Allow refused.
+type notAllowedError struct {
+	err error
+}
+
+func (e *notAllowedError) Error() string { return "connection not allowed: " + e.err.Error() }
+func (e *notAllowedError) Unwrap() error { return e.err }
+
+// control is the ControlContext hook of the proxy's dialers.
+// It calls Allow with the resolved address to which the proxy
+// is connecting, if the request in ctx is to be checked.
+func (p *ForwardProxy) control(ctx context.Context, network, address string, c syscall.RawConn) error {
+	req, ok := ctx.Value(allowRequestKey{}).(*http.Request)
+	if !ok || p.Allow == nil {
+		return nil
+	}
+	if err := p.Allow(req, address); err != nil {
+		return &notAllowedError{err}
+	}
+	return nil
+}
+
+// errorHandler is the ErrorHandler of the proxy's ReverseProxy.
+func (p *ForwardProxy) errorHandler(rw http.ResponseWriter, req *http.Request, err error) {
+	var notAllowed *notAllowedError
+	if errors.As(err, &notAllowed) {
+		http.Error(rw, "Forbidden", http.StatusForbidden)
+		return
+	}
+	p.logf("httputil: forward proxy error: %v", err)
+	rw.WriteHeader(http.StatusBadGateway)
+}
+
+// canonicalAddr returns hostport with the default port for scheme
+// added if it has none.
+func canonicalAddr(scheme, hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	} else if port != "" {
+		return hostport
+	}
+	port = "80"
+	if scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// viaReceivedProtocol returns the received-protocol of a Via header
+// (RFC 9110 section 7.6.3) for req.
+func viaReceivedProtocol(req *http.Request) string {
+	if req.ProtoMajor >= 2 {
+		return strconv.Itoa(req.ProtoMajor)
+	}
+	return fmt.Sprintf("%d.%d", req.ProtoMajor, req.ProtoMinor)
+}
+
+func (p *ForwardProxy) serveConnect(rw http.ResponseWriter, req *http.Request, addr string) {
+	dial := p.DialContext
+	if dial == nil {
+		d := net.Dialer{ControlContext: p.control}
+		dial = d.DialContext
+	}
+	target, err := dial(req.Context(), "tcp", addr)
+	if err != nil {
+		var notAllowed *notAllowedError
+		if errors.As(err, &notAllowed) {
+			http.Error(rw, "Forbidden", http.StatusForbidden)
+			return
+		}
+		p.logf("httputil: forward proxy error connecting to %s: %v", addr, err)
+		http.Error(rw, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer target.Close()
+
+	rc := http.NewResponseController(rw)
+	if req.ProtoMajor == 1 {
+		conn, brw, err := rc.Hijack()
+		if err != nil {
+			p.logf("httputil: forward proxy can't hijack connection for CONNECT: %v", err)
+			http.Error(rw, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		// The server may have set deadlines on the connection,
+		// which must not apply to the tunnel.
+		conn.SetDeadline(time.Time{})
+		if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			return
+		}
+		// Send any data the client sent after its request.
+		if n := brw.Reader.Buffered(); n > 0 {
+			b, _ := brw.Reader.Peek(n)
+			if _, err := target.Write(b); err != nil {
+				return
+			}
+		}
+		tunnel(conn, conn, target)
+		return
+	}
+
+	// In HTTP/2, the tunnel is the request and response bodies.
+	rw.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	tunnel(req.Body, flushWriter{rw, rc}, target)
+}
+
+// tunnel copies data between a client and a target connection,
+// until either stops sending.
+func tunnel(clientR io.Reader, clientW io.Writer, target io.ReadWriter) {
+	errc := make(chan error, 2)
+	spc := switchProtocolCopier{user: struct {
+		io.Reader
+		io.Writer
+	}{clientR, clientW}, backend: target}
+	go spc.copyToBackend(errc)
+	go spc.copyFromBackend(errc)
+	<-errc
+}
+
+// flushWriter is an io.Writer which flushes each write to the client.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err == nil {
+		err = w.rc.Flush()
+	}
+	return n, err
+}
+
+func (p *ForwardProxy) logf(format string, args ...any) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// ProxyBasicAuth returns the username and password provided in the
+// request's Proxy-Authorization header, if the request uses HTTP Basic
+// authentication. See RFC 7617, Section 2.1.
+func ProxyBasicAuth(r *http.Request) (username, password string, ok bool) {
+	const prefix = "Basic "
+	auth := r.Header.Get("Proxy-Authorization")
+	if len(auth) < len(prefix) || !ascii.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	c, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok = strings.Cut(string(c), ":")
+	if !ok {
+		return "", "", false
+	}
+	return username, password, true
+}
@@ -0,0 +1,294 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newEchoListener returns a listener whose connections echo each line
+// they receive in upper case.
+func newEchoListener(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				br := bufio.NewReader(c)
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					io.WriteString(c, strings.ToUpper(line))
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+// checkEcho writes lines to w and checks that r echoes them.
+func checkEcho(t *testing.T, w io.Writer, r io.Reader) {
+	t.Helper()
+	br := bufio.NewReader(r)
+	for _, s := range []string{"foo", "bar"} {
+		fmt.Fprintf(w, "%s\n", s)
+		got, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.ToUpper(s) + "\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestForwardProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "" {
+			t.Errorf("backend got Proxy-Authorization header")
+		}
+		fmt.Fprintf(w, "%s %s via %s", r.Method, r.URL, r.Header.Get("Via"))
+	}))
+	defer backend.Close()
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("internal server got request for %v", r.URL)
+	}))
+	defer internal.Close()
+	_, internalPort, _ := net.SplitHostPort(internal.Listener.Addr().String())
+
+	fp := &ForwardProxy{
+		Authenticate: func(r *http.Request) bool {
+			user, pass, ok := ProxyBasicAuth(r)
+			return ok && user == "user" && pass == "secret"
+		},
+		Allow: func(r *http.Request, addr string) error {
+			ap, err := netip.ParseAddrPort(addr)
+			if err != nil {
+				t.Errorf("Allow called with %q, want resolved address", addr)
+			}
+			if strconv.Itoa(int(ap.Port())) == internalPort {
+				return errors.New("forbidden")
+			}
+			return nil
+		},
+	}
+	proxy := httptest.NewServer(fp)
+	defer proxy.Close()
+
+	get := func(proxyURL, target string) (*http.Response, string) {
+		t.Helper()
+		pu, _ := url.Parse(proxyURL)
+		c := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(pu)}}
+		defer c.CloseIdleConnections()
+		res, err := c.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		return res, string(body)
+	}
+
+	res, _ := get(proxy.URL, backend.URL)
+	if res.StatusCode != http.StatusProxyAuthRequired || res.Header.Get("Proxy-Authenticate") != `Basic realm="proxy"` {
+		t.Errorf("without credentials: got %v, Proxy-Authenticate %q", res.Status, res.Header.Get("Proxy-Authenticate"))
+	}
+
+	authURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+	res, body := get(authURL, backend.URL+"/path?q=1")
+	if want := "GET /path?q=1 via 1.1 go"; res.StatusCode != 200 || body != want {
+		t.Errorf("got %v %q, want 200 %q", res.Status, body, want)
+	}
+
+	// The host name is resolved before Allow is called.
+	if res, _ := get(authURL, "http://localhost:"+internalPort+"/"); res.StatusCode != http.StatusForbidden {
+		t.Errorf("request for forbidden address: got %v, want 403", res.Status)
+	}
+
+	// A request which is not in absolute form is not for the proxy.
+	res, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusProxyAuthRequired {
+		t.Errorf("origin-form request without credentials: got %v, want 407", res.Status)
+	}
+	fp.Authenticate = nil
+	res, err = http.Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("origin-form request: got %v, want 400", res.Status)
+	}
+}
+
+func TestForwardProxyConnect(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello over TLS")
+	}))
+	defer backend.Close()
+	internal := newEchoListener(t)
+	_, internalPort, _ := net.SplitHostPort(internal.Addr().String())
+	proxy := httptest.NewServer(&ForwardProxy{
+		Allow: func(r *http.Request, addr string) error {
+			if r.Method != "CONNECT" {
+				t.Errorf("got %v request, want CONNECT", r.Method)
+			}
+			ap, err := netip.ParseAddrPort(addr)
+			if err != nil {
+				t.Errorf("Allow called with %q, want resolved address", addr)
+			}
+			if strconv.Itoa(int(ap.Port())) == internalPort {
+				return errors.New("forbidden")
+			}
+			return nil
+		},
+	})
+	defer proxy.Close()
+
+	tr := backend.Client().Transport.(*http.Transport).Clone()
+	pu, _ := url.Parse(proxy.URL)
+	tr.Proxy = http.ProxyURL(pu)
+	defer tr.CloseIdleConnections()
+	c := &http.Client{Transport: tr}
+	res, err := c.Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "hello over TLS" {
+		t.Errorf("got body %q", body)
+	}
+
+	if _, err := c.Get("https://localhost:" + internalPort + "/"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("CONNECT to forbidden address: got error %v, want Forbidden", err)
+	}
+}
+
+func TestForwardProxyAllowCustomDial(t *testing.T) {
+	// With a DialContext, Allow is called with the address as named
+	// in the request, before connecting.
+	var dialed bool
+	proxy := httptest.NewServer(&ForwardProxy{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = true
+			return nil, errors.New("unexpected dial")
+		},
+		Allow: func(r *http.Request, addr string) error {
+			if addr != "forbidden.test:443" {
+				t.Errorf("Allow called with %q, want forbidden.test:443", addr)
+			}
+			return errors.New("forbidden")
+		},
+	})
+	defer proxy.Close()
+
+	pu, _ := url.Parse(proxy.URL)
+	tr := &http.Transport{Proxy: http.ProxyURL(pu)}
+	defer tr.CloseIdleConnections()
+	if _, err := (&http.Client{Transport: tr}).Get("https://forbidden.test/"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("CONNECT to forbidden host: got error %v, want Forbidden", err)
+	}
+	if dialed {
+		t.Errorf("DialContext called for forbidden host")
+	}
+}
+
+func TestForwardProxyConnectHTTP2(t *testing.T) {
+	ln := newEchoListener(t)
+	proxy := httptest.NewUnstartedServer(&ForwardProxy{})
+	proxy.EnableHTTP2 = true
+	proxy.StartTLS()
+	defer proxy.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ := http.NewRequest("CONNECT", proxy.URL, pr)
+	req.Host = ln.Addr().String()
+	res, err := proxy.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 || res.ProtoMajor != 2 {
+		t.Fatalf("got %v %v, want 200 over HTTP/2", res.Proto, res.Status)
+	}
+	checkEcho(t, pw, res.Body)
+}
+
+func TestForwardProxyConnectBufferedData(t *testing.T) {
+	ln := newEchoListener(t)
+	proxy := httptest.NewServer(&ForwardProxy{})
+	defer proxy.Close()
+
+	c, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// Send the first line of the tunneled data with the request.
+	fmt.Fprintf(c, "CONNECT %s HTTP/1.1\r\nHost: %[1]s\r\n\r\nfirst\n", ln.Addr())
+	br := bufio.NewReader(c)
+	res, err := http.ReadResponse(br, &http.Request{Method: "CONNECT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 {
+		t.Fatalf("got %v, want 200", res.Status)
+	}
+	if got, err := br.ReadString('\n'); got != "FIRST\n" || err != nil {
+		t.Fatalf("got %q, %v; want FIRST", got, err)
+	}
+	checkEcho(t, c, br)
+}
+
+func TestProxyBasicAuth(t *testing.T) {
+	for _, test := range []struct {
+		header, user, pass string
+		ok                 bool
+	}{
+		{"Basic dXNlcjpzZWNyZXQ=", "user", "secret", true},
+		{"basic dXNlcjpzZWNyZXQ=", "user", "secret", true},
+		{"Basic dXNlcg==", "", "", false},
+		{"Bearer xyz", "", "", false},
+		{"", "", "", false},
+	} {
+		r := &http.Request{Header: http.Header{}}
+		if test.header != "" {
+			r.Header.Set("Proxy-Authorization", test.header)
+		}
+		user, pass, ok := ProxyBasicAuth(r)
+		if user != test.user || pass != test.pass || ok != test.ok {
+			t.Errorf("ProxyBasicAuth(%q) = %q, %q, %v; want %q, %q, %v",
+				test.header, user, pass, ok, test.user, test.pass, test.ok)
+		}
+	}
+}
@@ -122,6 +122,7 @@ type ReverseProxy struct {
 	// does not match that of the downstream server.
 	//
 	// At most one of Rewrite or Director may be set.
+	// One of them must be set unless Upstreams is set.
 	Rewrite func(*ProxyRequest)
 
 	// Director is a function which modifies
@@ -152,8 +153,29 @@ type ReverseProxy struct {
 	// request if Request.Form is set after Director returns.
 	//
 	// At most one of Rewrite or Director may be set.
+	// One of them must be set unless Upstreams is set.
 	Director func(*http.Request)
 
+	// Upstreams optionally specifies a pool of upstream servers
+	// to which requests are balanced.
+	//
+	// If Upstreams is set, after Rewrite or Director (if any) is called
+	// the outbound request is routed to an upstream chosen from the pool,
+	// as by ProxyRequest.SetURL but leaving the outbound Host header
+	// unchanged. A Rewrite function which calls SetURL should pass it
+	// a URL with an empty scheme and host, so that only the path is
+	// changed. A Rewrite function which sets Out.Host to "" causes the
+	// upstream's host to be sent.
+	//
+	// If a connection to the chosen upstream cannot be established,
+	// idempotent requests are retried on another healthy upstream.
+	//
+	// If neither Rewrite nor Director is set, the outbound request is
+	// prepared as by a Rewrite function which calls SetXForwarded:
+	// client-provided forwarding headers are replaced and unparsable
+	// query parameters are removed.
+	Upstreams *UpstreamPool
+
 	// The transport used to perform proxy requests.
 	// If nil, http.DefaultTransport is used.
 	Transport http.RoundTripper
@@ -378,8 +400,12 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		outreq.Header = make(http.Header) // Issue 33142: historical behavior was to always allocate
 	}
 
-	if (p.Director != nil) == (p.Rewrite != nil) {
-		p.getErrorHandler()(rw, req, errors.New("ReverseProxy must have exactly one of Director or Rewrite set"))
+	if p.Director != nil && p.Rewrite != nil {
+		p.getErrorHandler()(rw, req, errors.New("ReverseProxy must not have both Director and Rewrite set"))
+		return
+	}
+	if p.Director == nil && p.Rewrite == nil && p.Upstreams == nil {
+		p.getErrorHandler()(rw, req, errors.New("ReverseProxy must have one of Director, Rewrite or Upstreams set"))
 		return
 	}
 
@@ -414,7 +440,7 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		outreq.Header.Set("Upgrade", reqUpType)
 	}
 
-	if p.Rewrite != nil {
+	if p.Rewrite != nil || p.Director == nil {
 		// Strip client-provided forwarding headers.
 		// The Rewrite func may use SetXForwarded to set new values
 		// for these or copy the previous values from the inbound request.
@@ -430,7 +456,12 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			In:  req,
 			Out: outreq,
 		}
-		p.Rewrite(pr)
+		if p.Rewrite != nil {
+			p.Rewrite(pr)
+		} else {
+			// Upstreams only.
+			pr.SetXForwarded()
+		}
 		outreq = pr.Out
 	} else {
 		if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
@@ -478,7 +509,17 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	}
 	outreq = outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), trace))
 
-	res, err := transport.RoundTrip(outreq)
+	var res *http.Response
+	var err error
+	if p.Upstreams != nil {
+		var done func()
+		res, done, err = p.Upstreams.roundTrip(transport, outreq)
+		if done != nil {
+			defer done()
+		}
+	} else {
+		res, err = transport.RoundTrip(outreq)
+	}
 	roundTripMutex.Lock()
 	roundTripDone = true
 	roundTripMutex.Unlock()
@@ -0,0 +1,345 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// SOCKS5 proxy server
+
+package httputil
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// SOCKS5Server is a SOCKS version 5 proxy server (RFC 1928), which may
+// be used as a proxy by an [http.Transport], or by other clients.
+//
+// It supports only the CONNECT command, which opens a tunnel to a
+// host and port. It accepts clients without authentication, unless
+// Authenticate is set, in which case it requires username/password
+// authentication (RFC 1929).
+//
+// A SOCKS5Server connects to any server unless Allow is set.
+type SOCKS5Server struct {
+	// DialContext is used to connect to the targets of requests.
+	// If nil, a net.Dialer is used.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Authenticate, if non-nil, reports whether a username and
+	// password are valid.
+	Authenticate func(username, password string) bool
+
+	// Allow, if non-nil, is called when the server connects to a
+	// target for a client, with the address of the client, its
+	// username, if it authenticated, and the address, in "ip:port"
+	// form, to which the server is connecting. The host named by the
+	// client has already been resolved, so a host name cannot be used
+	// to reach an address which Allow would refuse. Allow is called for
+	// each address the server tries, from the ControlContext hook of a
+	// [net.Dialer]. If Allow returns an error, the server does not
+	// connect, and refuses the request.
+	//
+	// The server cannot observe the addresses to which DialContext
+	// connects. If DialContext is set, Allow is instead called before
+	// connecting, with the address, in "host:port" form, named by the
+	// client.
+	Allow func(client net.Addr, username, addr string) error
+
+	// HandshakeTimeout, if non-zero, is the maximum time a client
+	// may take to authenticate and send its request.
+	HandshakeTimeout time.Duration
+
+	// ErrorLog specifies an optional logger for errors that occur
+	// when connecting to servers. If nil, logging is done via the
+	// log package's standard logger.
+	ErrorLog *log.Logger
+}
+
+// SOCKS protocol constants (RFC 1928 and RFC 1929).
+const (
+	socks5Version         = 5
+	socksAuthNone         = 0x00
+	socksAuthPassword     = 0x02
+	socksAuthNoAcceptable = 0xff
+	socksPasswordVersion  = 1
+
+	socksCmdConnect = 1
+
+	socksAddrIPv4   = 1
+	socksAddrDomain = 3
+	socksAddrIPv6   = 4
+
+	socksSucceeded           = 0
+	socksGeneralFailure      = 1
+	socksNotAllowed          = 2
+	socksHostUnreachable     = 4
+	socksCommandNotSupported = 7
+	socksAddrNotSupported    = 8
+)
+
+// Serve accepts connections on l, and serves each one in a new
+// goroutine with ServeConn. It returns when l.Accept fails.
+func (s *SOCKS5Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single client connection, and closes it.
+func (s *SOCKS5Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	if s.HandshakeTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.HandshakeTimeout))
+	}
+	br := bufio.NewReader(conn)
+	username, err := s.handshake(conn, br)
+	if err != nil {
+		return
+	}
+	addr, err := readSOCKSRequest(br)
+	if err != nil {
+		var rep socksReplyError
+		if errors.As(err, &rep) {
+			writeSOCKSReply(conn, byte(rep), nil)
+		}
+		return
+	}
+	dial := s.DialContext
+	if dial == nil {
+		d := net.Dialer{ControlContext: s.control(conn.RemoteAddr(), username)}
+		dial = d.DialContext
+	} else if s.Allow != nil {
+		if err := s.Allow(conn.RemoteAddr(), username, addr); err != nil {
+			writeSOCKSReply(conn, socksNotAllowed, nil)
+			return
+		}
+	}
+	ctx := context.Background()
+	if s.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.HandshakeTimeout)
+		defer cancel()
+	}
+	target, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		var notAllowed *notAllowedError
+		if errors.As(err, &notAllowed) {
+			writeSOCKSReply(conn, socksNotAllowed, nil)
+			return
+		}
+		s.logf("httputil: SOCKS5 server error connecting to %s: %v", addr, err)
+		writeSOCKSReply(conn, socksDialErrorReply(err), nil)
+		return
+	}
+	defer target.Close()
+	if err := writeSOCKSReply(conn, socksSucceeded, target.LocalAddr()); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Send any data the client sent after its request.
+	if n := br.Buffered(); n > 0 {
+		b, _ := br.Peek(n)
+		if _, err := target.Write(b); err != nil {
+			return
+		}
+	}
+	tunnel(conn, conn, target)
+}
+
+// control returns the ControlContext hook of the server's dialer
+// for a client, which calls Allow with each address to which the
+// server connects.
+func (s *SOCKS5Server) control(client net.Addr, username string) func(context.Context, string, string, syscall.RawConn) error {
+	if s.Allow == nil {
+		return nil
+	}
+	return func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		if err := s.Allow(client, username, address); err != nil {
+			return &notAllowedError{err}
+		}
+		return nil
+	}
+}
+
+// handshake negotiates an authentication method with the client,
+// and authenticates it. It returns the client's username, if any.
+func (s *SOCKS5Server) handshake(w io.Writer, br *bufio.Reader) (username string, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return "", err
+	}
+	if hdr[0] != socks5Version {
+		return "", errors.New("unsupported SOCKS version")
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return "", err
+	}
+	want := byte(socksAuthNone)
+	if s.Authenticate != nil {
+		want = socksAuthPassword
+	}
+	found := false
+	for _, m := range methods {
+		if m == want {
+			found = true
+		}
+	}
+	if !found {
+		w.Write([]byte{socks5Version, socksAuthNoAcceptable})
+		return "", errors.New("no acceptable SOCKS authentication method")
+	}
+	if _, err := w.Write([]byte{socks5Version, want}); err != nil {
+		return "", err
+	}
+	if want == socksAuthNone {
+		return "", nil
+	}
+
+	// Username/password authentication (RFC 1929).
+	ver, err := br.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if ver != socksPasswordVersion {
+		return "", errors.New("unsupported SOCKS username/password version")
+	}
+	username, err = readSOCKSString(br)
+	if err != nil {
+		return "", err
+	}
+	password, err := readSOCKSString(br)
+	if err != nil {
+		return "", err
+	}
+	if !s.Authenticate(username, password) {
+		w.Write([]byte{socksPasswordVersion, 1})
+		return "", errors.New("SOCKS authentication failed")
+	}
+	if _, err := w.Write([]byte{socksPasswordVersion, 0}); err != nil {
+		return "", err
+	}
+	return username, nil
+}
+
+// readSOCKSString reads a string preceded by its length in one byte.
+func readSOCKSString(br *bufio.Reader) (string, error) {
+	n, err := br.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// A socksReplyError is an error in a client's request, which is
+// reported to the client with the reply code it holds.
+type socksReplyError byte
+
+func (e socksReplyError) Error() string {
+	return "SOCKS request error " + strconv.Itoa(int(e))
+}
+
+// readSOCKSRequest reads a request, and returns the "host:port"
+// address to which the client asks to connect.
+func readSOCKSRequest(br *bufio.Reader) (addr string, err error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return "", err
+	}
+	if hdr[0] != socks5Version {
+		return "", errors.New("unsupported SOCKS version")
+	}
+	var host string
+	switch hdr[3] {
+	case socksAddrIPv4:
+		var ip [4]byte
+		if _, err := io.ReadFull(br, ip[:]); err != nil {
+			return "", err
+		}
+		host = netip.AddrFrom4(ip).String()
+	case socksAddrIPv6:
+		var ip [16]byte
+		if _, err := io.ReadFull(br, ip[:]); err != nil {
+			return "", err
+		}
+		host = netip.AddrFrom16(ip).String()
+	case socksAddrDomain:
+		if host, err = readSOCKSString(br); err != nil {
+			return "", err
+		}
+	default:
+		return "", socksReplyError(socksAddrNotSupported)
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return "", err
+	}
+	if hdr[1] != socksCmdConnect {
+		return "", socksReplyError(socksCommandNotSupported)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// writeSOCKSReply writes a reply with the given code and bound address.
+func writeSOCKSReply(w io.Writer, code byte, bound net.Addr) error {
+	b := []byte{socks5Version, code, 0}
+	var ip netip.Addr
+	var port uint16
+	if ta, ok := bound.(*net.TCPAddr); ok {
+		ap := ta.AddrPort()
+		ip, port = ap.Addr().Unmap(), ap.Port()
+	}
+	switch {
+	case ip.Is6():
+		b = append(b, socksAddrIPv6)
+		b = append(b, ip.AsSlice()...)
+	case ip.Is4():
+		b = append(b, socksAddrIPv4)
+		b = append(b, ip.AsSlice()...)
+	default:
+		b = append(b, socksAddrIPv4, 0, 0, 0, 0)
+	}
+	b = binary.BigEndian.AppendUint16(b, port)
+	_, err := w.Write(b)
+	return err
+}
+
+// socksDialErrorReply returns the reply code for an error connecting
+// to a server.
+func socksDialErrorReply(err error) byte {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return socksHostUnreachable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return socksHostUnreachable
+	}
+	return socksGeneralFailure
+}
+
+func (s *SOCKS5Server) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
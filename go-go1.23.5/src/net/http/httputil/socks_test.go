@@ -0,0 +1,155 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newSOCKS5Server starts s on a local listener, and returns its address.
+func newSOCKS5Server(t *testing.T, s *SOCKS5Server) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.Serve(ln)
+	return ln.Addr().String()
+}
+
+func TestSOCKS5Server(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer backend.Close()
+	internal := newEchoListener(t)
+	_, internalPort, _ := net.SplitHostPort(internal.Addr().String())
+
+	var allowed []string
+	addr := newSOCKS5Server(t, &SOCKS5Server{
+		Authenticate: func(username, password string) bool {
+			return username == "user" && password == "secret"
+		},
+		Allow: func(client net.Addr, username, addr string) error {
+			allowed = append(allowed, username+" "+addr)
+			if _, port, _ := net.SplitHostPort(addr); port == internalPort {
+				return errors.New("forbidden")
+			}
+			return nil
+		},
+	})
+
+	get := func(proxy, target string) (string, error) {
+		pu, _ := url.Parse(proxy)
+		tr := &http.Transport{Proxy: http.ProxyURL(pu)}
+		defer tr.CloseIdleConnections()
+		res, err := (&http.Client{Transport: tr}).Get(target)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		return string(b), err
+	}
+
+	if body, err := get("socks5://user:secret@"+addr, backend.URL); err != nil || body != "hello" {
+		t.Errorf("got %q, %v; want hello", body, err)
+	}
+	if want := "user " + backend.Listener.Addr().String(); len(allowed) != 1 || allowed[0] != want {
+		t.Errorf("Allow called with %q, want %q", allowed, want)
+	}
+	if _, err := get("socks5://user:wrong@"+addr, backend.URL); err == nil {
+		t.Errorf("with wrong password: got nil error")
+	}
+	if _, err := get("socks5://"+addr, backend.URL); err == nil {
+		t.Errorf("without credentials: got nil error")
+	}
+	// The host name is resolved before Allow is called.
+	allowed = nil
+	if _, err := get("socks5://user:secret@"+addr, "http://localhost:"+internalPort+"/"); err == nil ||
+		!strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("forbidden target: got error %v, want not allowed", err)
+	}
+	for _, a := range allowed {
+		_, hostport, _ := strings.Cut(a, " ")
+		if _, err := netip.ParseAddrPort(hostport); err != nil {
+			t.Errorf("Allow called with %q, want resolved address", hostport)
+		}
+	}
+}
+
+func TestSOCKS5ServerProtocol(t *testing.T) {
+	ln := newEchoListener(t)
+	addr := newSOCKS5Server(t, &SOCKS5Server{})
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	for _, test := range []struct {
+		name    string
+		request []byte
+		reply   byte
+	}{
+		{"IPv4", []byte{5, 1, 0, 1, 127, 0, 0, 1, byte(port >> 8), byte(port)}, 0},
+		{"domain", append(append([]byte{5, 1, 0, 3, 9}, "localhost"...), byte(port>>8), byte(port)), 0},
+		{"BIND", []byte{5, 2, 0, 1, 127, 0, 0, 1, 0, 80}, 7},
+		{"bad address type", []byte{5, 1, 0, 9}, 8},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.Write([]byte{5, 2, 0x02, 0x00})
+			var method [2]byte
+			if _, err := io.ReadFull(c, method[:]); err != nil || method != [2]byte{5, 0} {
+				t.Fatalf("method selection: got %v, %v; want [5 0]", method, err)
+			}
+			c.Write(test.request)
+			var reply [10]byte
+			if _, err := io.ReadFull(c, reply[:]); err != nil {
+				t.Fatal(err)
+			}
+			if reply[0] != 5 || reply[1] != test.reply {
+				t.Fatalf("got reply %v, want code %v", reply, test.reply)
+			}
+			if test.reply != 0 {
+				return
+			}
+			// Send a line before the reply could have been read by a real
+			// client, then use the tunnel.
+			c.Write([]byte("abc\n"))
+			b := make([]byte, 4)
+			if _, err := io.ReadFull(c, b); err != nil || !bytes.Equal(b, []byte("ABC\n")) {
+				t.Errorf("got %q, %v; want ABC", b, err)
+			}
+			checkEcho(t, c, c)
+		})
+	}
+}
+
+func TestSOCKS5ServerNoAcceptableMethod(t *testing.T) {
+	addr := newSOCKS5Server(t, &SOCKS5Server{
+		Authenticate: func(username, password string) bool { return true },
+	})
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Write([]byte{5, 1, 0x00})
+	var method [2]byte
+	if _, err := io.ReadFull(c, method[:]); err != nil || method != [2]byte{5, 0xff} {
+		t.Errorf("got %v, %v; want [5 255]", method, err)
+	}
+}
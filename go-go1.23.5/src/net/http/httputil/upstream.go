@@ -0,0 +1,519 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Upstream pools for ReverseProxy
+
+package httputil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrNoHealthyUpstream is passed to [ReverseProxy.ErrorHandler] when
+// every upstream in the proxy's [UpstreamPool] is unhealthy or has
+// already been tried for the request.
+var ErrNoHealthyUpstream = errors.New("httputil: no healthy upstream")
+
+// An Upstream is a backend server in an [UpstreamPool].
+type Upstream struct {
+	// URL is the upstream's base URL. Requests are routed to it
+	// as by [ProxyRequest.SetURL].
+	URL *url.URL
+
+	outstanding atomic.Int64
+
+	mu          sync.Mutex
+	fails       int       // consecutive failed requests
+	downUntil   time.Time // excluded after too many failed requests
+	checkFailed bool      // most recent active health check failed
+}
+
+// Outstanding returns the number of requests currently being proxied
+// to the upstream.
+func (u *Upstream) Outstanding() int {
+	return int(u.outstanding.Load())
+}
+
+// Healthy reports whether the upstream is eligible to receive requests:
+// its most recent active health check, if any, succeeded, and it is not
+// excluded because of failed requests.
+func (u *Upstream) Healthy() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.healthyLocked(time.Now())
+}
+
+func (u *Upstream) healthyLocked(now time.Time) bool {
+	return !u.checkFailed && !now.Before(u.downUntil)
+}
+
+// An UpstreamSelector chooses the upstream to which a request is sent.
+type UpstreamSelector interface {
+	// Select returns one of the candidate upstreams for req.
+	// The candidates are healthy and there is at least one.
+	// Select must be safe for concurrent use.
+	Select(req *http.Request, candidates []*Upstream) *Upstream
+}
+
+// RoundRobin returns an [UpstreamSelector] which cycles through
+// the candidate upstreams in turn.
+func RoundRobin() UpstreamSelector {
+	return new(roundRobin)
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (s *roundRobin) Select(req *http.Request, candidates []*Upstream) *Upstream {
+	n := s.next.Add(1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// LeastOutstanding returns an [UpstreamSelector] which chooses the
+// candidate with the fewest requests in flight, as reported by
+// [Upstream.Outstanding]. Ties are broken in round-robin order.
+func LeastOutstanding() UpstreamSelector {
+	return new(leastOutstanding)
+}
+
+type leastOutstanding struct {
+	next atomic.Uint64
+}
+
+func (s *leastOutstanding) Select(req *http.Request, candidates []*Upstream) *Upstream {
+	start := int((s.next.Add(1) - 1) % uint64(len(candidates)))
+	var best *Upstream
+	for i := range candidates {
+		u := candidates[(start+i)%len(candidates)]
+		if best == nil || u.Outstanding() < best.Outstanding() {
+			best = u
+		}
+	}
+	return best
+}
+
+// ConsistentHash returns an [UpstreamSelector] which sends all requests
+// with the same key to the same upstream, for as long as that upstream
+// is a candidate. When upstreams are added, removed, or become unhealthy,
+// only the keys which mapped to the changed upstreams move.
+//
+// The key function returns the key for a request. If key is nil,
+// the client's IP address is used.
+func ConsistentHash(key func(*http.Request) string) UpstreamSelector {
+	if key == nil {
+		key = clientIPKey
+	}
+	return &consistentHash{key: key}
+}
+
+type consistentHash struct {
+	key func(*http.Request) string
+}
+
+func clientIPKey(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// Select uses rendezvous hashing: each candidate is scored by hashing
+// it together with the key, and the highest score wins.
+func (s *consistentHash) Select(req *http.Request, candidates []*Upstream) *Upstream {
+	key := s.key(req)
+	var best *Upstream
+	var bestScore uint64
+	for _, u := range candidates {
+		score := fnv1a(fnv1a(fnv1a(fnv1aOffset, u.URL.String()), "\x00"), key)
+		if best == nil || score > bestScore {
+			best, bestScore = u, score
+		}
+	}
+	return best
+}
+
+const fnv1aOffset = 14695981039346656037
+
+// fnv1a adds s to the 64-bit FNV-1a hash h.
+func fnv1a(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// An UpstreamPool is a set of upstream servers to which a [ReverseProxy]
+// balances requests.
+//
+// Upstreams are checked for health passively, by observing the requests
+// proxied to them, and optionally actively, by [UpstreamPool.CheckHealth].
+// Requests are only sent to healthy upstreams.
+//
+// The zero value is an empty pool. The exported fields of an UpstreamPool
+// must not be modified after it is first used.
+type UpstreamPool struct {
+	// Selector chooses among healthy upstreams.
+	// If nil, RoundRobin is used.
+	Selector UpstreamSelector
+
+	// MaxFails is the number of consecutive failed requests after
+	// which an upstream is considered unhealthy for FailTimeout.
+	// A request fails if the connection to the upstream cannot be
+	// made, times out, or is reset or closed before a response is
+	// received. Requests canceled by the client are not counted.
+	// If zero, 1 is used. If negative, requests never make an
+	// upstream unhealthy.
+	MaxFails int
+
+	// FailTimeout is how long an upstream is considered unhealthy
+	// after MaxFails failed requests.
+	// If zero, 10 seconds is used.
+	FailTimeout time.Duration
+
+	// HealthCheckPath is the path requested by active health checks,
+	// relative to each upstream's URL.
+	// If empty, the upstream's URL itself is requested.
+	HealthCheckPath string
+
+	// HealthCheckInterval is the time between active health checks.
+	// If zero, 10 seconds is used.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout limits the duration of each health check request.
+	// If zero, 5 seconds is used.
+	HealthCheckTimeout time.Duration
+
+	// HealthCheckTransport is used to make health check requests.
+	// If nil, http.DefaultTransport is used.
+	HealthCheckTransport http.RoundTripper
+
+	initOnce sync.Once
+	selector UpstreamSelector
+
+	mu        sync.Mutex
+	upstreams []*Upstream
+}
+
+// NewUpstreamPool returns a pool containing an upstream for each target.
+func NewUpstreamPool(targets ...*url.URL) *UpstreamPool {
+	p := &UpstreamPool{}
+	for _, target := range targets {
+		p.Add(target)
+	}
+	return p
+}
+
+// Add adds an upstream for target to the pool and returns it.
+func (p *UpstreamPool) Add(target *url.URL) *Upstream {
+	u := &Upstream{URL: target}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upstreams = append(p.upstreams, u)
+	return u
+}
+
+// Remove removes u from the pool.
+// Requests already being proxied to u are not affected.
+func (p *UpstreamPool) Remove(u *Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upstreams = slices.DeleteFunc(p.upstreams, func(v *Upstream) bool { return v == u })
+}
+
+// Upstreams returns the upstreams in the pool.
+func (p *UpstreamPool) Upstreams() []*Upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.upstreams)
+}
+
+func (p *UpstreamPool) init() {
+	p.selector = p.Selector
+	if p.selector == nil {
+		p.selector = RoundRobin()
+	}
+}
+
+// pick chooses a healthy upstream for req which is not in tried,
+// and counts a request outstanding to it.
+func (p *UpstreamPool) pick(req *http.Request, tried []*Upstream) (*Upstream, error) {
+	p.initOnce.Do(p.init)
+	now := time.Now()
+	var candidates []*Upstream
+	for _, u := range p.Upstreams() {
+		if slices.Contains(tried, u) {
+			continue
+		}
+		u.mu.Lock()
+		healthy := u.healthyLocked(now)
+		u.mu.Unlock()
+		if healthy {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHealthyUpstream
+	}
+	u := p.selector.Select(req, candidates)
+	u.outstanding.Add(1)
+	return u, nil
+}
+
+func (p *UpstreamPool) maxFails() int {
+	if p.MaxFails == 0 {
+		return 1
+	}
+	return p.MaxFails
+}
+
+func (p *UpstreamPool) failTimeout() time.Duration {
+	if p.FailTimeout == 0 {
+		return 10 * time.Second
+	}
+	return p.FailTimeout
+}
+
+// observe records the outcome of a request proxied to u.
+func (p *UpstreamPool) observe(u *Upstream, failed bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !failed {
+		u.fails = 0
+		return
+	}
+	max := p.maxFails()
+	if max < 0 {
+		return
+	}
+	u.fails++
+	if u.fails >= max {
+		u.fails = 0
+		u.downUntil = time.Now().Add(p.failTimeout())
+	}
+}
+
+// roundTrip sends outreq to an upstream chosen from the pool.
+// The outbound request's URL is routed to the upstream, leaving its
+// Host unchanged. If the connection to the upstream cannot be made,
+// idempotent requests are retried on another upstream.
+//
+// On success, the chosen upstream still counts the request as
+// outstanding, and the caller must call done when it has finished
+// with the response.
+func (p *UpstreamPool) roundTrip(transport http.RoundTripper, outreq *http.Request) (res *http.Response, done func(), err error) {
+	var body *upstreamRetryBody
+	if outreq.Body != nil && isIdempotent(outreq) {
+		// The Transport closes the request body when RoundTrip fails.
+		// Hide that from it, so long as nothing has been read, so the
+		// body can be sent to another upstream.
+		body = &upstreamRetryBody{ReadCloser: outreq.Body}
+		defer body.finish()
+	}
+	var tried []*Upstream
+	for {
+		u, perr := p.pick(outreq, tried)
+		if perr != nil {
+			if err == nil {
+				err = perr
+			}
+			return nil, nil, err
+		}
+		tried = append(tried, u)
+		r := outreq.WithContext(outreq.Context())
+		r.URL = new(url.URL)
+		*r.URL = *outreq.URL
+		rewriteRequestURL(r, u.URL)
+		if body != nil {
+			r.Body = body
+		}
+		res, err = transport.RoundTrip(r)
+		if err == nil {
+			p.observe(u, false)
+		} else if outreq.Context().Err() == nil && isUpstreamFailure(err) {
+			// A request canceled by the client, or which failed for
+			// reasons of its own, says nothing about the upstream.
+			p.observe(u, true)
+		}
+		if err == nil {
+			return res, func() { u.outstanding.Add(-1) }, nil
+		}
+		u.outstanding.Add(-1)
+		if !isDialError(err) || !isIdempotent(outreq) || outreq.Context().Err() != nil {
+			return nil, nil, err
+		}
+		if outreq.Body != nil && (body == nil || body.read.Load()) {
+			return nil, nil, err
+		}
+	}
+}
+
+// isIdempotent reports whether req may be sent more than once,
+// as defined by RFC 9110, Section 9.2.2, or by the presence of
+// an Idempotency-Key header.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	// The Idempotency-Key, while non-standard, is widely used to
+	// mean a POST or other request is idempotent. See
+	// https://golang.org/issue/19943#issuecomment-421092421
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+// isDialError reports whether err indicates that no connection
+// to the upstream could be established.
+func isDialError(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// isUpstreamFailure reports whether err indicates that the upstream is
+// unreachable or unresponsive: the connection could not be made, timed
+// out, or was reset or closed before a response was received.
+func isUpstreamFailure(err error) bool {
+	if isDialError(err) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// upstreamRetryBody is a request body which defers closing the
+// underlying body until no more attempts will be made to send it,
+// and records whether it has been read.
+type upstreamRetryBody struct {
+	io.ReadCloser
+	read atomic.Bool
+
+	mu       sync.Mutex
+	closed   bool // Close has been called
+	finished bool // finish has been called
+}
+
+func (b *upstreamRetryBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.ReadCloser.Read(p)
+}
+
+func (b *upstreamRetryBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if !b.finished {
+		return nil
+	}
+	return b.ReadCloser.Close()
+}
+
+// finish is called when the body will not be sent again.
+// It closes the underlying body if Close has already been called.
+func (b *upstreamRetryBody) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finished = true
+	if b.closed {
+		b.ReadCloser.Close()
+	}
+}
+
+// CheckHealth actively checks the health of the upstreams in the pool
+// until ctx is done, and then returns ctx.Err().
+//
+// Every HealthCheckInterval, CheckHealth sends a GET request to each
+// upstream. An upstream is healthy if the request succeeds with a 2xx
+// or 3xx status, and unhealthy otherwise, until its next check.
+func (p *UpstreamPool) CheckHealth(ctx context.Context) error {
+	interval := p.HealthCheckInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var wg sync.WaitGroup
+		for _, u := range p.Upstreams() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				healthy := p.checkHealth(ctx, u)
+				if ctx.Err() != nil {
+					return
+				}
+				u.mu.Lock()
+				u.checkFailed = !healthy
+				if healthy {
+					u.fails = 0
+					u.downUntil = time.Time{}
+				}
+				u.mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// checkHealth sends a health check request to u.
+func (p *UpstreamPool) checkHealth(ctx context.Context, u *Upstream) bool {
+	timeout := p.HealthCheckTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	target := *u.URL
+	if p.HealthCheckPath != "" {
+		target.Path = singleJoiningSlash(target.Path, p.HealthCheckPath)
+		target.RawPath = ""
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target.String(), nil)
+	if err != nil {
+		return false
+	}
+	transport := p.HealthCheckTransport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	// Drain a little of the body so the connection may be reused.
+	io.CopyN(io.Discard, res.Body, 4<<10)
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 400
+}
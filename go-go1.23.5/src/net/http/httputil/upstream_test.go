@@ -0,0 +1,342 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newNamedBackend starts a backend server which responds with name.
+func newNamedBackend(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// deadURL returns the URL of a server which refuses connections.
+func deadURL(t *testing.T) *url.URL {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return mustParseURL("http://" + addr)
+}
+
+func proxyGet(t *testing.T, proxy http.Handler, method, path string, body io.Reader) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, body)
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestUpstreamPoolRoundRobin(t *testing.T) {
+	var urls []*url.URL
+	for _, name := range []string{"a", "b", "c"} {
+		urls = append(urls, mustParseURL(newNamedBackend(t, name).URL))
+	}
+	proxy := &ReverseProxy{Upstreams: NewUpstreamPool(urls...)}
+	var got []string
+	for range 6 {
+		_, body := proxyGet(t, proxy, "GET", "/", nil)
+		got = append(got, body)
+	}
+	if g, w := strings.Join(got, ""), "abcabc"; g != w {
+		t.Errorf("responses = %q, want %q", g, w)
+	}
+}
+
+func TestUpstreamPoolLeastOutstanding(t *testing.T) {
+	a := &Upstream{URL: mustParseURL("http://a.example")}
+	b := &Upstream{URL: mustParseURL("http://b.example")}
+	c := &Upstream{URL: mustParseURL("http://c.example")}
+	a.outstanding.Store(3)
+	b.outstanding.Store(1)
+	c.outstanding.Store(2)
+	s := LeastOutstanding()
+	for range 3 {
+		if got := s.Select(nil, []*Upstream{a, b, c}); got != b {
+			t.Fatalf("Select = %v, want %v", got.URL, b.URL)
+		}
+	}
+	a.outstanding.Store(1)
+	seen := map[*Upstream]bool{}
+	for range 4 {
+		seen[s.Select(nil, []*Upstream{a, b, c})] = true
+	}
+	if !seen[a] || !seen[b] || seen[c] {
+		t.Errorf("ties between a and b not shared: selected %v", seen)
+	}
+}
+
+func TestUpstreamPoolConsistentHash(t *testing.T) {
+	var ups []*Upstream
+	for i := range 5 {
+		ups = append(ups, &Upstream{URL: mustParseURL(fmt.Sprintf("http://%d.example", i))})
+	}
+	s := ConsistentHash(func(r *http.Request) string { return r.URL.Path })
+	req := func(key string) *http.Request {
+		return httptest.NewRequest("GET", "/"+key, nil)
+	}
+	moved := 0
+	for i := range 100 {
+		key := fmt.Sprint(i)
+		got := s.Select(req(key), ups)
+		if again := s.Select(req(key), ups); again != got {
+			t.Fatalf("key %q selected %v, then %v", key, got.URL, again.URL)
+		}
+		// Removing an upstream only moves the keys which selected it.
+		after := s.Select(req(key), ups[1:])
+		if got != ups[0] && after != got {
+			t.Errorf("key %q moved from %v to %v", key, got.URL, after.URL)
+		}
+		if got == ups[0] {
+			moved++
+		}
+	}
+	if moved == 0 || moved == 100 {
+		t.Errorf("%v of 100 keys selected the first of 5 upstreams", moved)
+	}
+}
+
+func TestUpstreamPoolRetryOnDialError(t *testing.T) {
+	good := newNamedBackend(t, "good")
+	pool := NewUpstreamPool(deadURL(t), mustParseURL(good.URL))
+	pool.MaxFails = -1 // keep trying the unreachable upstream first
+	proxy := &ReverseProxy{Upstreams: pool}
+	proxy.ErrorLog = log.New(io.Discard, "", 0)
+
+	for _, method := range []string{"GET", "PUT"} {
+		code, body := proxyGet(t, proxy, method, "/", strings.NewReader("body"))
+		if code != 200 || body != "good" {
+			t.Errorf("%v: got %v %q, want 200 %q", method, code, body, "good")
+		}
+	}
+	if n := pool.Upstreams()[1].Outstanding(); n != 0 {
+		t.Errorf("Outstanding = %v after requests finished, want 0", n)
+	}
+}
+
+// closeNotifyBody is a request body which
+// closes its channel when it is first closed.
+type closeNotifyBody struct {
+	io.Reader
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (b *closeNotifyBody) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func TestUpstreamPoolRetryClosesBody(t *testing.T) {
+	good := newNamedBackend(t, "good")
+	for _, test := range []struct {
+		name      string
+		upstreams []*url.URL
+		wantErr   bool
+	}{
+		{"retried", []*url.URL{deadURL(t), mustParseURL(good.URL)}, false},
+		{"all unreachable", []*url.URL{deadURL(t), deadURL(t)}, true},
+	} {
+		pool := NewUpstreamPool(test.upstreams...)
+		pool.MaxFails = -1
+		body := &closeNotifyBody{Reader: strings.NewReader("body"), closed: make(chan struct{})}
+		outreq, _ := http.NewRequest("PUT", "http://example.com/", body)
+		res, done, err := pool.roundTrip(http.DefaultTransport, outreq)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want error: %v", test.name, err, test.wantErr)
+		}
+		if err == nil {
+			res.Body.Close()
+			done()
+		}
+		// The transport closes the body when it has sent it,
+		// without waiting for the request to be finished.
+		select {
+		case <-body.closed:
+		case <-time.After(10 * time.Second):
+			t.Errorf("%v: request body was not closed", test.name)
+		}
+	}
+}
+
+func TestUpstreamPoolNoRetryNonIdempotent(t *testing.T) {
+	good := newNamedBackend(t, "good")
+	pool := NewUpstreamPool(deadURL(t), mustParseURL(good.URL))
+	var gotErr error
+	proxy := &ReverseProxy{
+		Upstreams: pool,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	code, _ := proxyGet(t, proxy, "POST", "/", strings.NewReader("body"))
+	if code != http.StatusBadGateway {
+		t.Errorf("POST to unreachable upstream: got status %v, want 502", code)
+	}
+	if !isDialError(gotErr) {
+		t.Errorf("ErrorHandler got %v, want dial error", gotErr)
+	}
+}
+
+func TestUpstreamPoolNoHealthyUpstream(t *testing.T) {
+	pool := NewUpstreamPool(deadURL(t))
+	var gotErr error
+	proxy := &ReverseProxy{
+		Upstreams: pool,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	proxyGet(t, proxy, "GET", "/", nil)
+	if !isDialError(gotErr) {
+		t.Errorf("first request: ErrorHandler got %v, want dial error", gotErr)
+	}
+	proxyGet(t, proxy, "GET", "/", nil)
+	if !errors.Is(gotErr, ErrNoHealthyUpstream) {
+		t.Errorf("second request: ErrorHandler got %v, want ErrNoHealthyUpstream", gotErr)
+	}
+}
+
+func TestUpstreamPoolClientCancelKeepsHealthy(t *testing.T) {
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+	pool := NewUpstreamPool(mustParseURL(backend.URL))
+	proxy := &ReverseProxy{
+		Upstreams:    pool,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	proxy.ServeHTTP(httptest.NewRecorder(), req)
+	if u := pool.Upstreams()[0]; !u.Healthy() {
+		t.Errorf("upstream unhealthy after the client canceled its request")
+	}
+}
+
+func TestUpstreamPoolConnectionClosedFails(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			c.Close()
+		}
+	}))
+	defer backend.Close()
+	pool := NewUpstreamPool(mustParseURL(backend.URL))
+	proxy := &ReverseProxy{
+		Upstreams:    pool,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {},
+	}
+	proxyGet(t, proxy, "GET", "/", nil)
+	if u := pool.Upstreams()[0]; u.Healthy() {
+		t.Errorf("upstream healthy after closing the connection without a response")
+	}
+}
+
+func TestUpstreamPoolWithRewrite(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v %v %v", r.URL.Path, r.Host, r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer backend.Close()
+	proxy := &ReverseProxy{
+		Upstreams: NewUpstreamPool(mustParseURL(backend.URL + "/base")),
+		Rewrite: func(r *ProxyRequest) {
+			r.SetXForwarded()
+			r.Out.URL.Path = "/rewritten" + r.Out.URL.Path
+		},
+	}
+	_, body := proxyGet(t, proxy, "GET", "http://front.example/x", nil)
+	if want := "/base/rewritten/x front.example front.example"; body != want {
+		t.Errorf("backend saw %q, want %q", body, want)
+	}
+}
+
+func TestUpstreamPoolForwardedHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q %q %q %q", r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Forwarded-Host"), r.Header["Forwarded"], r.URL.RawQuery)
+	}))
+	defer backend.Close()
+	proxy := &ReverseProxy{Upstreams: NewUpstreamPool(mustParseURL(backend.URL))}
+	req := httptest.NewRequest("GET", "http://front.example/?a=1&b=;", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Forwarded-Host", "spoofed.example")
+	req.Header.Set("Forwarded", "for=198.51.100.1")
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+	if want := `"192.0.2.1" "front.example" [] "a=1"`; rec.Body.String() != want {
+		t.Errorf("backend saw %v, want %v", rec.Body.String(), want)
+	}
+}
+
+func TestUpstreamPoolCheckHealth(t *testing.T) {
+	var healthy atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/base/healthz" {
+			t.Errorf("health check for %q, want /base/healthz", r.URL.Path)
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+	pool := NewUpstreamPool(mustParseURL(backend.URL + "/base"))
+	pool.HealthCheckPath = "/healthz"
+	pool.HealthCheckInterval = time.Millisecond
+	u := pool.Upstreams()[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	donec := make(chan error)
+	go func() { donec <- pool.CheckHealth(ctx) }()
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for u.Healthy() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Healthy() did not become %v", want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(false)
+	healthy.Store(true)
+	waitFor(true)
+	cancel()
+	if err := <-donec; err != context.Canceled {
+		t.Errorf("CheckHealth = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,657 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+type connSide int8
+
+const (
+	clientSide connSide = iota
+	serverSide
+)
+
+// A Conn is a QUIC connection.
+//
+// Multiple goroutines may invoke methods on a Conn simultaneously.
+type Conn struct {
+	side     connSide
+	endpoint *Endpoint
+	config   *Config
+	peerAddr net.Addr
+
+	recvc         chan []byte   // datagrams from the endpoint
+	wakec         chan struct{} // wakes the conn loop; buffered, capacity 1
+	donec         chan struct{} // closed when the conn loop exits
+	handshakeDone chan struct{} // closed when the handshake completes or fails
+
+	mu sync.Mutex // guards all fields below
+
+	tls *tls.QUICConn
+
+	localConnID   []byte // our connection ID
+	peerConnID    []byte // destination connection ID of packets we send
+	origDstConnID []byte // client's first Destination Connection ID
+	gotPeerConnID bool   // client: peerConnID was chosen by the server
+	peerParams    transportParameters
+	handshakeErr  error
+
+	spaces             [numberSpaceCount]spaceState
+	handshakeComplete  bool // the TLS handshake is complete
+	handshakeConfirmed bool // RFC 9001, Section 4.1.2
+	needHandshakeDone  bool // server: send a HANDSHAKE_DONE frame
+
+	created      time.Time
+	lastActivity time.Time // last time we received a packet, for the idle timer
+	lastSend     time.Time // last time we sent an ack-eliciting packet
+	idleTimeout  time.Duration
+
+	loss lossState
+
+	// Anti-amplification limit (RFC 9000, Section 8.1).
+	addrValidated bool
+	bytesRecv     int64
+	bytesSent     int64
+
+	// Connection-level flow control.
+	connOutMax    int64 // peer's MAX_DATA
+	connOutSent   int64 // stream data sent, counting each byte once
+	connInMax     int64 // our MAX_DATA
+	connInRecv    int64 // stream data received, counting each byte once
+	connInRead    int64 // stream data consumed by the application
+	needMaxData   bool
+	pathResponses [][8]byte
+
+	streams          map[int64]*Stream
+	nextLocal        [2]int64 // next stream index to open, by streamDir
+	peerMaxStreams   [2]int64 // peer's MAX_STREAMS, by streamDir
+	remoteOpened     [2]int64 // number of peer streams opened, by streamDir
+	remoteClosed     [2]int64 // number of peer streams closed, by streamDir
+	localMaxStreams  [2]int64 // our MAX_STREAMS, by streamDir
+	needMaxStreams   [2]bool
+	streamLimitc     chan struct{} // closed and replaced when peerMaxStreams changes
+	acceptq          []*Stream
+	acceptc          chan struct{} // signaled when acceptq grows
+	sendRoundRobinID int64         // stream ID to start sending from
+
+	closeState    closeState
+	closeErr      error  // error returned by operations on a closed Conn
+	closeApp      bool   // the CONNECTION_CLOSE we send is an application close
+	closeCode     uint64 // error code of the CONNECTION_CLOSE we send
+	closeReason   string
+	needClose     bool // send a CONNECTION_CLOSE frame
+	closeDeadline time.Time
+
+	// Set by CloseGracefully.
+	graceful         bool
+	gracefulErr      error
+	gracefulDeadline time.Time
+}
+
+type closeState int8
+
+const (
+	connOpen     closeState = iota
+	connClosing             // we sent CONNECTION_CLOSE (RFC 9000, Section 10.2.1)
+	connDraining            // the peer sent CONNECTION_CLOSE (RFC 9000, Section 10.2.2)
+	connDone
+)
+
+// spaceState is the state of one packet number space.
+type spaceState struct {
+	read, write packetKeys
+	discarded   bool
+
+	// Sending.
+	nextPnum     int64
+	sent         map[int64]*sentPacket
+	largestAcked int64
+	lossTime     time.Time
+	lastAckElic  time.Time // time the last ack-eliciting packet was sent
+	needProbe    bool
+	cryptoOut    sendBuffer
+
+	// Receiving.
+	seen            rangeset
+	largestRecvTime time.Time
+	ackPending      bool
+	cryptoIn        recvBuffer
+}
+
+func (c *Conn) String() string {
+	if c.side == clientSide {
+		return "quic client conn to " + c.peerAddr.String()
+	}
+	return "quic server conn from " + c.peerAddr.String()
+}
+
+func newConn(e *Endpoint, side connSide, peerAddr net.Addr, config *Config, localConnID, peerConnID, origDstConnID []byte) (*Conn, error) {
+	now := time.Now()
+	c := &Conn{
+		side:          side,
+		endpoint:      e,
+		config:        config,
+		peerAddr:      peerAddr,
+		recvc:         make(chan []byte, 64),
+		wakec:         make(chan struct{}, 1),
+		donec:         make(chan struct{}),
+		handshakeDone: make(chan struct{}),
+		localConnID:   localConnID,
+		peerConnID:    peerConnID,
+		origDstConnID: origDstConnID,
+		created:       now,
+		lastActivity:  now,
+		lastSend:      now,
+		peerParams:    defaultTransportParameters(),
+		idleTimeout:   config.maxIdleTimeout(),
+		streams:       make(map[int64]*Stream),
+		streamLimitc:  make(chan struct{}),
+		acceptc:       make(chan struct{}, 1),
+		addrValidated: side == clientSide,
+		connInMax:     config.maxConnReadBufferSize(),
+	}
+	c.localMaxStreams[bidiStream] = config.maxBidiRemoteStreams()
+	c.localMaxStreams[uniStream] = config.maxUniRemoteStreams()
+	c.loss.init()
+	for i := range c.spaces {
+		c.spaces[i].sent = make(map[int64]*sentPacket)
+		c.spaces[i].largestAcked = -1
+	}
+	clientKeys, serverKeys := initialKeys(origDstConnID)
+	if side == clientSide {
+		c.spaces[initialSpace].write, c.spaces[initialSpace].read = clientKeys, serverKeys
+	} else {
+		c.spaces[initialSpace].write, c.spaces[initialSpace].read = serverKeys, clientKeys
+	}
+
+	tlsConfig := config.TLSConfig.Clone()
+	if tlsConfig.MinVersion < tls.VersionTLS13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+	qconfig := &tls.QUICConfig{TLSConfig: tlsConfig}
+	if side == clientSide {
+		c.tls = tls.QUICClient(qconfig)
+	} else {
+		c.tls = tls.QUICServer(qconfig)
+	}
+	params := transportParameters{
+		maxIdleTimeout:                 c.idleTimeout,
+		maxUDPPayloadSize:              maxRecvDatagramSize - 9, // largest valid value below 2^16
+		initialMaxData:                 c.connInMax,
+		initialMaxStreamDataBidiLocal:  config.maxStreamReadBufferSize(),
+		initialMaxStreamDataBidiRemote: config.maxStreamReadBufferSize(),
+		initialMaxStreamDataUni:        config.maxStreamReadBufferSize(),
+		initialMaxStreamsBidi:          c.localMaxStreams[bidiStream],
+		initialMaxStreamsUni:           c.localMaxStreams[uniStream],
+		activeConnIDLimit:              2,
+		initialSrcConnID:               localConnID,
+	}
+	if side == serverSide {
+		params.originalDstConnID = origDstConnID
+	}
+	c.tls.SetTransportParameters(marshalTransportParameters(params))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.tls.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := c.handleTLSEventsLocked(now); err != nil {
+		c.tls.Close()
+		return nil, err
+	}
+	go c.loop()
+	return c, nil
+}
+
+func newRandomConnID() []byte {
+	id := make([]byte, connIDLen)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr { return c.endpoint.LocalAddr() }
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.peerAddr }
+
+// ConnectionState returns basic TLS details about the connection.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tls.ConnectionState()
+}
+
+// waitHandshake waits for the handshake to complete.
+func (c *Conn) waitHandshake(ctx context.Context) error {
+	select {
+	case <-c.handshakeDone:
+	case <-ctx.Done():
+		c.Abort(ctx.Err())
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handshakeErr
+}
+
+// Close closes the connection with no error code,
+// and waits for the peer to acknowledge the close or for
+// the closing period to end.
+func (c *Conn) Close() error {
+	c.Abort(nil)
+	<-c.donec
+	return nil
+}
+
+// Abort closes the connection and returns immediately.
+//
+// If err is an *ApplicationError, its code and reason are sent to the peer.
+// Otherwise, the peer receives an application error with code 0.
+// Any operation blocked on the connection returns err,
+// or an error indicating the connection was closed if err is nil.
+func (c *Conn) Abort(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	code, reason, err := c.applicationCloseLocked(err)
+	c.enterClosingLocked(time.Now(), true, code, reason, err)
+	c.wake()
+}
+
+// applicationCloseLocked returns the error code and reason to send
+// when the application closes the connection with err,
+// and the error to return from operations on the closed connection.
+func (c *Conn) applicationCloseLocked(err error) (code uint64, reason string, _ error) {
+	if ae, ok := err.(*ApplicationError); ok {
+		code, reason = ae.Code, ae.Reason
+	}
+	if err == nil {
+		err = errConnClosed
+	}
+	return code, reason, err
+}
+
+// CloseGracefully closes the connection like Abort, once the peer has
+// acknowledged all data written to streams or timeout has elapsed,
+// whichever comes first. It returns immediately.
+func (c *Conn) CloseGracefully(err error, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.graceful {
+		return
+	}
+	c.graceful = true
+	c.gracefulErr = err
+	c.gracefulDeadline = time.Now().Add(timeout)
+	c.wake()
+}
+
+// streamDataAckedLocked reports whether the peer has acknowledged
+// all data and FIN bits sent on streams.
+func (c *Conn) streamDataAckedLocked() bool {
+	for _, s := range c.streams {
+		if !s.hasSend() || s.outReset {
+			continue
+		}
+		if !s.out.allAcked() || (s.outFin && !s.outFinAcked) {
+			return false
+		}
+	}
+	return true
+}
+
+// Wait waits for the peer to close the connection,
+// returning the reason the connection closed.
+func (c *Conn) Wait(ctx context.Context) error {
+	select {
+	case <-c.donec:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeErr
+}
+
+// Done returns a channel closed when the connection has closed.
+func (c *Conn) Done() <-chan struct{} { return c.donec }
+
+// wake wakes the conn loop.
+func (c *Conn) wake() {
+	select {
+	case c.wakec <- struct{}{}:
+	default:
+	}
+}
+
+// deliver passes a datagram from the endpoint to the conn.
+func (c *Conn) deliver(b []byte) {
+	select {
+	case c.recvc <- b:
+	default:
+		// Drop the datagram; the peer will retransmit.
+	}
+}
+
+// loop is the connection's main goroutine.
+// It processes received datagrams and timer events and sends packets.
+func (c *Conn) loop() {
+	defer c.exit()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		now := time.Now()
+		c.mu.Lock()
+		c.handleTimersLocked(now)
+		dgrams := c.appendDatagramsLocked(now)
+		next := c.nextDeadlineLocked()
+		done := c.closeState == connDone
+		c.mu.Unlock()
+		for _, d := range dgrams {
+			c.endpoint.writeTo(d, c.peerAddr)
+		}
+		if done {
+			return
+		}
+		timer.Reset(time.Until(next))
+		select {
+		case b := <-c.recvc:
+			c.mu.Lock()
+			c.handleDatagramLocked(time.Now(), b)
+			for more := true; more; {
+				select {
+				case b := <-c.recvc:
+					c.handleDatagramLocked(time.Now(), b)
+				default:
+					more = false
+				}
+			}
+			c.mu.Unlock()
+		case <-c.wakec:
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Conn) exit() {
+	c.mu.Lock()
+	if c.closeErr == nil {
+		c.closeErr = errConnClosed
+	}
+	c.signalHandshakeDoneLocked(c.closeErr)
+	c.tls.Close()
+	c.mu.Unlock()
+	c.endpoint.removeConn(c)
+	close(c.donec)
+}
+
+// signalHandshakeDoneLocked records the handshake result and
+// unblocks waiters, once.
+func (c *Conn) signalHandshakeDoneLocked(err error) {
+	select {
+	case <-c.handshakeDone:
+		return
+	default:
+	}
+	c.handshakeErr = err
+	close(c.handshakeDone)
+	if err == nil && c.side == serverSide {
+		c.endpoint.queueAccept(c)
+	}
+}
+
+// enterClosingLocked starts closing the connection,
+// sending a CONNECTION_CLOSE frame with the given error.
+func (c *Conn) enterClosingLocked(now time.Time, app bool, code uint64, reason string, err error) {
+	if c.closeState != connOpen {
+		return
+	}
+	c.closeState = connClosing
+	c.closeErr = err
+	c.closeApp, c.closeCode, c.closeReason = app, code, reason
+	c.needClose = true
+	c.closeDeadline = now.Add(3 * c.loss.pto(c.peerParams.maxAckDelay))
+	c.signalHandshakeDoneLocked(err)
+	c.wakeAllStreamsLocked()
+}
+
+// abortLocked closes the connection after a local transport error.
+func (c *Conn) abortLocked(now time.Time, err error) {
+	var lte localTransportError
+	if !errors.As(err, &lte) {
+		if alert, ok := err.(tls.AlertError); ok {
+			lte = localTransportError{errCryptoBase + transportError(alert), err.Error()}
+		} else {
+			lte = localTransportError{errInternal, err.Error()}
+		}
+	}
+	c.enterClosingLocked(now, false, uint64(lte.code), lte.reason, lte)
+}
+
+// enterDrainingLocked handles a CONNECTION_CLOSE from the peer.
+func (c *Conn) enterDrainingLocked(now time.Time, err error) {
+	if c.closeState == connDraining || c.closeState == connDone {
+		return
+	}
+	if c.closeState == connOpen {
+		c.closeErr = err
+	}
+	c.closeState = connDraining
+	c.needClose = false
+	c.closeDeadline = now.Add(3 * c.loss.pto(c.peerParams.maxAckDelay))
+	c.signalHandshakeDoneLocked(err)
+	c.wakeAllStreamsLocked()
+}
+
+func (c *Conn) wakeAllStreamsLocked() {
+	for _, s := range c.streams {
+		s.signalIn()
+		s.signalOut()
+	}
+	signal(c.acceptc)
+	close(c.streamLimitc)
+	c.streamLimitc = make(chan struct{})
+}
+
+// handleTimersLocked handles any expired timers.
+func (c *Conn) handleTimersLocked(now time.Time) {
+	switch c.closeState {
+	case connClosing, connDraining:
+		if !now.Before(c.closeDeadline) {
+			c.closeState = connDone
+		}
+		return
+	case connDone:
+		return
+	}
+	if !c.handshakeComplete && now.Sub(c.created) >= c.config.handshakeTimeout() {
+		c.closeErr = errors.New("quic: handshake timeout")
+		c.signalHandshakeDoneLocked(c.closeErr)
+		c.closeState = connDone
+		c.wakeAllStreamsLocked()
+		return
+	}
+	if idle := c.idleDuration(); idle > 0 && now.Sub(c.lastActivity) >= idle {
+		c.closeErr = errIdleTimeout
+		c.signalHandshakeDoneLocked(c.closeErr)
+		c.closeState = connDone
+		c.wakeAllStreamsLocked()
+		return
+	}
+	if c.graceful && (c.streamDataAckedLocked() || !now.Before(c.gracefulDeadline)) {
+		code, reason, err := c.applicationCloseLocked(c.gracefulErr)
+		c.enterClosingLocked(now, true, code, reason, err)
+		return
+	}
+	if d := c.config.KeepAlivePeriod; d > 0 && c.handshakeConfirmed && now.Sub(c.lastSend) >= d {
+		c.spaces[appDataSpace].needProbe = true
+	}
+	c.handleLossTimersLocked(now)
+}
+
+// idleDuration returns the negotiated idle timeout (RFC 9000, Section 10.1).
+func (c *Conn) idleDuration() time.Duration {
+	idle := c.idleTimeout
+	if p := c.peerParams.maxIdleTimeout; p > 0 && (idle <= 0 || p < idle) {
+		idle = p
+	}
+	if idle <= 0 {
+		return 0
+	}
+	return max(idle, 3*c.loss.pto(c.peerParams.maxAckDelay))
+}
+
+// nextDeadlineLocked returns the next time a timer expires.
+func (c *Conn) nextDeadlineLocked() time.Time {
+	switch c.closeState {
+	case connClosing, connDraining:
+		return c.closeDeadline
+	case connDone:
+		return time.Now()
+	}
+	next := c.created.Add(c.config.handshakeTimeout())
+	if c.handshakeComplete {
+		next = c.lastActivity.Add(time.Hour)
+	}
+	if idle := c.idleDuration(); idle > 0 {
+		next = earliest(next, c.lastActivity.Add(idle))
+	}
+	if d := c.config.KeepAlivePeriod; d > 0 && c.handshakeConfirmed {
+		next = earliest(next, c.lastSend.Add(d))
+	}
+	if c.graceful {
+		next = earliest(next, c.gracefulDeadline)
+	}
+	return earliest(next, c.lossDeadlineLocked())
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.IsZero() || (!a.IsZero() && a.Before(b)) {
+		return a
+	}
+	return b
+}
+
+// tlsLevelSpace maps TLS encryption levels to packet number spaces.
+func tlsLevelSpace(level tls.QUICEncryptionLevel) (numberSpace, bool) {
+	switch level {
+	case tls.QUICEncryptionLevelInitial:
+		return initialSpace, true
+	case tls.QUICEncryptionLevelHandshake:
+		return handshakeSpace, true
+	case tls.QUICEncryptionLevelApplication:
+		return appDataSpace, true
+	}
+	return 0, false
+}
+
+func spaceTLSLevel(space numberSpace) tls.QUICEncryptionLevel {
+	switch space {
+	case initialSpace:
+		return tls.QUICEncryptionLevelInitial
+	case handshakeSpace:
+		return tls.QUICEncryptionLevelHandshake
+	}
+	return tls.QUICEncryptionLevelApplication
+}
+
+// handleTLSEventsLocked processes events produced by the TLS handshake.
+func (c *Conn) handleTLSEventsLocked(now time.Time) error {
+	for {
+		e := c.tls.NextEvent()
+		switch e.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret:
+			space, ok := tlsLevelSpace(e.Level)
+			if !ok {
+				continue // 0-RTT is not supported
+			}
+			keys, err := newPacketKeys(e.Suite, e.Data)
+			if err != nil {
+				return err
+			}
+			if e.Kind == tls.QUICSetReadSecret {
+				c.spaces[space].read = keys
+			} else {
+				c.spaces[space].write = keys
+			}
+		case tls.QUICWriteData:
+			space, ok := tlsLevelSpace(e.Level)
+			if !ok {
+				return localTransportError{errInternal, "unexpected TLS data level"}
+			}
+			c.spaces[space].cryptoOut.write(e.Data)
+		case tls.QUICTransportParameters:
+			if err := c.handlePeerTransportParametersLocked(e.Data); err != nil {
+				return err
+			}
+		case tls.QUICHandshakeDone:
+			c.handshakeComplete = true
+			if c.side == serverSide {
+				// RFC 9001, Section 4.1.2: the handshake is confirmed
+				// at the server when it completes.
+				c.handshakeConfirmed = true
+				c.needHandshakeDone = true
+				c.discardKeysLocked(handshakeSpace)
+			}
+			c.signalHandshakeDoneLocked(nil)
+		}
+	}
+}
+
+func (c *Conn) handlePeerTransportParametersLocked(b []byte) error {
+	p, err := unmarshalTransportParameters(b)
+	if err != nil {
+		return err
+	}
+	if c.side == clientSide {
+		if string(p.originalDstConnID) != string(c.origDstConnID) {
+			return localTransportError{errTransportParam, "original_destination_connection_id mismatch"}
+		}
+		if string(p.initialSrcConnID) != string(c.peerConnID) {
+			return localTransportError{errTransportParam, "initial_source_connection_id mismatch"}
+		}
+	} else {
+		if p.originalDstConnID != nil {
+			return localTransportError{errTransportParam, "client sent original_destination_connection_id"}
+		}
+		if string(p.initialSrcConnID) != string(c.peerConnID) {
+			return localTransportError{errTransportParam, "initial_source_connection_id mismatch"}
+		}
+	}
+	c.peerParams = p
+	c.connOutMax = p.initialMaxData
+	c.peerMaxStreams[bidiStream] = p.initialMaxStreamsBidi
+	c.peerMaxStreams[uniStream] = p.initialMaxStreamsUni
+	return nil
+}
+
+// discardKeysLocked drops the keys and state of a packet number space
+// (RFC 9001, Section 4.9).
+func (c *Conn) discardKeysLocked(space numberSpace) {
+	s := &c.spaces[space]
+	if s.discarded {
+		return
+	}
+	for _, p := range s.sent {
+		if p.inFlight {
+			c.loss.bytesInFlight -= p.size
+		}
+	}
+	*s = spaceState{discarded: true}
+	c.loss.ptoCount = 0
+}
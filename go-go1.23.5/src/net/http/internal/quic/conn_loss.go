@@ -0,0 +1,349 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// Loss detection and congestion control constants (RFC 9002).
+const (
+	packetThreshold  = 3
+	timeThresholdNum = 9 // time threshold is 9/8 of the RTT
+	timeThresholdDen = 8
+	granularity      = time.Millisecond
+	initialRTT       = 333 * time.Millisecond
+	initialWindow    = 10 * maxUDPPayloadSize
+	minimumWindow    = 2 * maxUDPPayloadSize
+	persistentMaxPTO = 6 // cap on PTO backoff
+)
+
+// A sentPacket records an ack-eliciting packet until it is
+// acknowledged or declared lost.
+type sentPacket struct {
+	pnum     int64
+	time     time.Time
+	size     int
+	inFlight bool
+	frames   []sentFrame
+}
+
+type sentFrameKind int8
+
+const (
+	sentCrypto sentFrameKind = iota
+	sentStream
+	sentResetStream
+	sentStopSending
+	sentMaxData
+	sentMaxStreamData
+	sentMaxStreams
+	sentHandshakeDone
+)
+
+// A sentFrame records a frame that must be retransmitted if lost.
+type sentFrame struct {
+	kind sentFrameKind
+	id   int64 // stream ID, or streamDir for sentMaxStreams
+	off  int64
+	n    int64
+	fin  bool
+}
+
+// lossState is the connection's RTT estimate and congestion controller.
+type lossState struct {
+	minRTT, smoothedRTT, rttVar, latestRTT time.Duration
+	hasRTTSample                           bool
+	ptoCount                               int
+
+	bytesInFlight int
+	cwnd          int
+	ssthresh      int
+	recoveryStart time.Time
+}
+
+func (l *lossState) init() {
+	l.smoothedRTT = initialRTT
+	l.rttVar = initialRTT / 2
+	l.cwnd = initialWindow
+	l.ssthresh = 1<<31 - 1
+}
+
+// updateRTT updates the RTT estimate (RFC 9002, Section 5).
+func (l *lossState) updateRTT(sample, ackDelay time.Duration) {
+	l.latestRTT = sample
+	if !l.hasRTTSample {
+		l.hasRTTSample = true
+		l.minRTT = sample
+		l.smoothedRTT = sample
+		l.rttVar = sample / 2
+		return
+	}
+	l.minRTT = min(l.minRTT, sample)
+	adjusted := sample
+	if sample >= l.minRTT+ackDelay {
+		adjusted = sample - ackDelay
+	}
+	diff := l.smoothedRTT - adjusted
+	if diff < 0 {
+		diff = -diff
+	}
+	l.rttVar = (3*l.rttVar + diff) / 4
+	l.smoothedRTT = (7*l.smoothedRTT + adjusted) / 8
+}
+
+// pto returns the probe timeout without backoff (RFC 9002, Section 6.2.1).
+func (l *lossState) pto(maxAckDelay time.Duration) time.Duration {
+	return l.smoothedRTT + max(4*l.rttVar, granularity) + maxAckDelay
+}
+
+// lossDelay returns the time threshold for declaring a packet lost.
+func (l *lossState) lossDelay() time.Duration {
+	d := max(l.latestRTT, l.smoothedRTT) * timeThresholdNum / timeThresholdDen
+	return max(d, granularity)
+}
+
+// canSend reports whether the congestion window permits sending
+// another full-size packet.
+func (l *lossState) canSend() bool {
+	return l.bytesInFlight+maxUDPPayloadSize <= l.cwnd
+}
+
+func (l *lossState) onAcked(p *sentPacket) {
+	if !p.inFlight {
+		return
+	}
+	l.bytesInFlight -= p.size
+	if !p.time.After(l.recoveryStart) {
+		return
+	}
+	if l.cwnd < l.ssthresh {
+		l.cwnd += p.size
+	} else {
+		l.cwnd += maxUDPPayloadSize * p.size / l.cwnd
+	}
+}
+
+func (l *lossState) onLost(now time.Time, p *sentPacket) {
+	if !p.inFlight {
+		return
+	}
+	l.bytesInFlight -= p.size
+	if !p.time.After(l.recoveryStart) {
+		return
+	}
+	// Enter recovery (RFC 9002, Section 7.3.2).
+	l.recoveryStart = now
+	l.ssthresh = max(l.cwnd/2, minimumWindow)
+	l.cwnd = l.ssthresh
+}
+
+// peerMaxAckDelay returns the max_ack_delay to use for a number space.
+func (c *Conn) peerMaxAckDelay(space numberSpace) time.Duration {
+	if space != appDataSpace {
+		return 0
+	}
+	return c.peerParams.maxAckDelay
+}
+
+// handleAckLocked processes an ACK frame (RFC 9002, Section A.7).
+func (c *Conn) handleAckLocked(now time.Time, space numberSpace, acked rangeset, delay uint64) error {
+	s := &c.spaces[space]
+	largest := acked.end() - 1
+	if largest >= s.nextPnum {
+		return localTransportError{errProtocolViolation, "acknowledgement for unsent packet"}
+	}
+	var largestNewlyAcked *sentPacket
+	for pnum, p := range s.sent {
+		if !acked.contains(pnum) {
+			continue
+		}
+		delete(s.sent, pnum)
+		if largestNewlyAcked == nil || pnum > largestNewlyAcked.pnum {
+			largestNewlyAcked = p
+		}
+		c.loss.onAcked(p)
+		c.onFramesAckedLocked(space, p.frames)
+	}
+	if largestNewlyAcked == nil {
+		return nil
+	}
+	s.largestAcked = max(s.largestAcked, largest)
+	if largestNewlyAcked.pnum == largest {
+		var ackDelay time.Duration
+		if space == appDataSpace {
+			exp := c.peerParams.ackDelayExponent
+			ackDelay = time.Duration(delay<<exp) * time.Microsecond
+			if c.handshakeConfirmed {
+				ackDelay = min(ackDelay, c.peerParams.maxAckDelay)
+			}
+		}
+		c.loss.updateRTT(now.Sub(largestNewlyAcked.time), ackDelay)
+	}
+	c.detectLostLocked(now, space)
+	c.loss.ptoCount = 0
+	return nil
+}
+
+// detectLostLocked declares packets lost using the packet and
+// time thresholds (RFC 9002, Section 6.1).
+func (c *Conn) detectLostLocked(now time.Time, space numberSpace) {
+	s := &c.spaces[space]
+	s.lossTime = time.Time{}
+	if s.largestAcked < 0 {
+		return
+	}
+	lossDelay := c.loss.lossDelay()
+	lostSendTime := now.Add(-lossDelay)
+	for pnum, p := range s.sent {
+		if pnum > s.largestAcked {
+			continue
+		}
+		if !p.time.After(lostSendTime) || s.largestAcked-pnum >= packetThreshold {
+			delete(s.sent, pnum)
+			c.loss.onLost(now, p)
+			c.onFramesLostLocked(space, p.frames)
+			continue
+		}
+		s.lossTime = earliest(s.lossTime, p.time.Add(lossDelay))
+	}
+}
+
+// lossDeadlineLocked returns the time of the next loss detection
+// or probe timeout event.
+func (c *Conn) lossDeadlineLocked() time.Time {
+	var t time.Time
+	for space := range c.spaces {
+		t = earliest(t, c.spaces[space].lossTime)
+	}
+	if !t.IsZero() {
+		return t
+	}
+	space, pto := c.ptoDeadlineLocked()
+	if space < 0 {
+		return time.Time{}
+	}
+	return pto
+}
+
+// ptoDeadlineLocked returns the space and time of the next probe timeout,
+// or -1 if no probe timer is armed (RFC 9002, Section 6.2.1).
+func (c *Conn) ptoDeadlineLocked() (numberSpace, time.Time) {
+	backoff := time.Duration(1) << min(c.loss.ptoCount, persistentMaxPTO)
+	var (
+		found numberSpace = -1
+		t     time.Time
+	)
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		s := &c.spaces[space]
+		if s.discarded || len(s.sent) == 0 {
+			continue
+		}
+		if space == appDataSpace && !c.handshakeConfirmed {
+			continue
+		}
+		d := s.lastAckElic.Add(c.loss.pto(c.peerMaxAckDelay(space)) * backoff)
+		if found < 0 || d.Before(t) {
+			found, t = space, d
+		}
+	}
+	if found < 0 && c.side == clientSide && !c.handshakeConfirmed {
+		// RFC 9002, Section 6.2.2.1: the client keeps a probe timer
+		// armed until the handshake is confirmed, so the server
+		// is never blocked by the anti-amplification limit.
+		space := initialSpace
+		if c.spaces[initialSpace].discarded {
+			space = handshakeSpace
+		}
+		return space, c.lastSend.Add(c.loss.pto(0) * backoff)
+	}
+	return found, t
+}
+
+// handleLossTimersLocked handles expired loss detection and probe timers.
+func (c *Conn) handleLossTimersLocked(now time.Time) {
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		if t := c.spaces[space].lossTime; !t.IsZero() && !now.Before(t) {
+			c.detectLostLocked(now, space)
+			return
+		}
+	}
+	space, t := c.ptoDeadlineLocked()
+	if space < 0 || now.Before(t) {
+		return
+	}
+	c.loss.ptoCount++
+	// Send a probe in the space whose timer expired.
+	// Resending outstanding data in the probe helps recover
+	// from tail loss faster than a bare PING.
+	s := &c.spaces[space]
+	s.needProbe = true
+	for pnum, p := range s.sent {
+		delete(s.sent, pnum)
+		if p.inFlight {
+			c.loss.bytesInFlight -= p.size
+		}
+		c.onFramesLostLocked(space, p.frames)
+	}
+	if c.side == clientSide && space == handshakeSpace && !c.spaces[handshakeSpace].write.isSet() {
+		c.spaces[initialSpace].needProbe = true
+	}
+	c.lastSend = now
+}
+
+func (c *Conn) onFramesAckedLocked(space numberSpace, frames []sentFrame) {
+	for _, f := range frames {
+		switch f.kind {
+		case sentCrypto:
+			c.spaces[space].cryptoOut.acked(f.off, f.n)
+		case sentStream:
+			if s := c.streams[f.id]; s != nil {
+				s.out.acked(f.off, f.n)
+				if f.fin {
+					s.outFinAcked = true
+				}
+				s.signalOut()
+				c.maybeRemoveStreamLocked(s)
+			}
+		case sentResetStream:
+			if s := c.streams[f.id]; s != nil {
+				s.outResetAcked = true
+				c.maybeRemoveStreamLocked(s)
+			}
+		}
+	}
+}
+
+func (c *Conn) onFramesLostLocked(space numberSpace, frames []sentFrame) {
+	for _, f := range frames {
+		switch f.kind {
+		case sentCrypto:
+			c.spaces[space].cryptoOut.lost(f.off, f.n)
+		case sentStream:
+			if s := c.streams[f.id]; s != nil && !s.outReset {
+				s.out.lost(f.off, f.n)
+				if f.fin && !s.outFinAcked {
+					s.outFinSent = false
+				}
+			}
+		case sentResetStream:
+			if s := c.streams[f.id]; s != nil {
+				s.needResetStream = true
+			}
+		case sentStopSending:
+			if s := c.streams[f.id]; s != nil && !s.recvFinished() {
+				s.needStopSending = true
+			}
+		case sentMaxData:
+			c.needMaxData = true
+		case sentMaxStreamData:
+			if s := c.streams[f.id]; s != nil && !s.inDone() {
+				s.needMaxStreamData = true
+			}
+		case sentMaxStreams:
+			c.needMaxStreams[f.id] = true
+		case sentHandshakeDone:
+			c.needHandshakeDone = true
+		}
+	}
+}
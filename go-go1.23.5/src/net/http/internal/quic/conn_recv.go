@@ -0,0 +1,340 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"time"
+)
+
+// maxCryptoBufferSize limits the amount of out-of-order CRYPTO data
+// buffered in one packet number space.
+const maxCryptoBufferSize = 1 << 16
+
+// handleDatagramLocked processes a datagram received from the peer.
+func (c *Conn) handleDatagramLocked(now time.Time, b []byte) {
+	if c.closeState == connDone || c.closeState == connDraining {
+		return
+	}
+	c.bytesRecv += int64(len(b))
+	for len(b) > 0 {
+		n := c.handlePacketLocked(now, b)
+		if n <= 0 {
+			break
+		}
+		b = b[n:]
+	}
+}
+
+// handlePacketLocked processes the packet at the start of b.
+// It returns the size of the packet, or -1 if the rest of the
+// datagram should be dropped.
+func (c *Conn) handlePacketLocked(now time.Time, b []byte) int {
+	var (
+		space     numberSpace
+		pkt       []byte
+		pnumOff   int
+		srcConnID []byte
+	)
+	switch ptype := getPacketType(b); ptype {
+	case packetTypeInitial, packetTypeHandshake:
+		h, ok := parseLongHeader(b)
+		if !ok || h.version != quicVersion1 {
+			return -1
+		}
+		if ptype == packetTypeInitial {
+			space = initialSpace
+		} else {
+			space = handshakeSpace
+		}
+		pkt, pnumOff = b[:h.size], h.pnumOff
+		if !bytes.Equal(h.dstConnID, c.localConnID) && !(c.side == serverSide && space == initialSpace) {
+			return h.size
+		}
+		if c.side == clientSide && !c.gotPeerConnID {
+			// RFC 9000, Section 7.2: the client switches to the
+			// connection ID chosen by the server.
+			srcConnID = h.srcConnID
+		}
+	case packetType1RTT:
+		if len(b) < 1+connIDLen {
+			return -1
+		}
+		space = appDataSpace
+		pkt, pnumOff = b, 1+connIDLen
+	case packetType0RTT:
+		h, ok := parseLongHeader(b)
+		if !ok {
+			return -1
+		}
+		return h.size // 0-RTT is not supported
+	default:
+		return -1
+	}
+
+	s := &c.spaces[space]
+	if s.discarded || !s.read.isSet() {
+		return len(pkt)
+	}
+	largest := int64(-1)
+	if len(s.seen) > 0 {
+		largest = s.seen.end() - 1
+	}
+	pnum, _, payload, err := s.read.unprotect(pkt, pnumOff, largest)
+	if err != nil {
+		// RFC 9000, Section 12.2: discard packets that fail to decrypt.
+		return len(pkt)
+	}
+	if pkt[0]&c.reservedBits(pkt[0]) != 0 {
+		c.abortLocked(now, localTransportError{errProtocolViolation, "reserved header bits are set"})
+		return -1
+	}
+	if s.seen.contains(pnum) {
+		return len(pkt) // duplicate
+	}
+	if srcConnID != nil {
+		c.peerConnID = bytes.Clone(srcConnID)
+		c.gotPeerConnID = true
+	}
+	c.lastActivity = now
+
+	if c.closeState == connClosing {
+		// RFC 9000, Section 10.2.1: respond to packets with another
+		// CONNECTION_CLOSE, but process nothing else except a close
+		// from the peer.
+		c.needClose = true
+		c.handleFramesLocked(now, space, payload, true)
+		return len(pkt)
+	}
+
+	ackEliciting, err := c.handleFramesLocked(now, space, payload, false)
+	if err != nil {
+		c.abortLocked(now, err)
+		return -1
+	}
+	s = &c.spaces[space] // the space may have been discarded
+	if !s.discarded {
+		s.seen.add(pnum, pnum+1)
+		if len(s.seen) > maxAckRanges {
+			s.seen = s.seen[len(s.seen)-maxAckRanges:]
+		}
+		if pnum == s.seen.end()-1 {
+			s.largestRecvTime = now
+		}
+		if ackEliciting {
+			s.ackPending = true
+		}
+	}
+	if c.side == serverSide && space == handshakeSpace {
+		// RFC 9001, Section 4.9.1: a server discards Initial keys
+		// when it first processes a Handshake packet. This also
+		// validates the client's address (RFC 9000, Section 8.1).
+		c.addrValidated = true
+		c.discardKeysLocked(initialSpace)
+	}
+	return len(pkt)
+}
+
+// reservedBits returns the mask of the reserved bits of a packet's
+// first byte, which must be zero after removing header protection.
+func (c *Conn) reservedBits(b byte) byte {
+	if b&headerFormLong != 0 {
+		return 0x0c
+	}
+	return 0x18
+}
+
+// handleFramesLocked processes the frames in a packet payload.
+// It reports whether the packet was ack-eliciting.
+// When closing is set, only CONNECTION_CLOSE frames are processed.
+func (c *Conn) handleFramesLocked(now time.Time, space numberSpace, b []byte, closing bool) (ackEliciting bool, err error) {
+	if len(b) == 0 {
+		return false, localTransportError{errProtocolViolation, "packet with no frames"}
+	}
+	for len(b) > 0 {
+		typ, n := consumeVarint(b)
+		if n < 0 {
+			return false, localTransportError{errFrameEncoding, "malformed frame type"}
+		}
+		if !frameAllowedInSpace(typ, space) {
+			return false, localTransportError{errProtocolViolation, "frame not allowed in packet type"}
+		}
+		switch typ {
+		case frameTypePadding, frameTypeAck, frameTypeAckECN,
+			frameTypeConnectionCloseTransport, frameTypeConnectionCloseApplication:
+		default:
+			ackEliciting = true
+		}
+		if closing && typ != frameTypeConnectionCloseTransport && typ != frameTypeConnectionCloseApplication {
+			if typ == frameTypePadding {
+				b = b[1:]
+				continue
+			}
+			// We can't skip over frames we don't parse,
+			// so stop at the first one.
+			return ackEliciting, nil
+		}
+		switch {
+		case typ == frameTypePadding:
+			n = 1
+		case typ == frameTypePing:
+			n = 1
+		case typ == frameTypeAck || typ == frameTypeAckECN:
+			var acked rangeset
+			var delay uint64
+			acked, delay, n = parseAckFrame(b)
+			if n > 0 {
+				err = c.handleAckLocked(now, space, acked, delay)
+			}
+		case typ == frameTypeCrypto:
+			var off int64
+			var data []byte
+			off, data, n = parseCryptoFrame(b)
+			if n > 0 {
+				err = c.handleCryptoLocked(now, space, off, data)
+			}
+		case typ >= frameTypeStreamBase && typ < frameTypeStreamBase+8:
+			var id, off int64
+			var fin bool
+			var data []byte
+			id, off, fin, data, n = parseStreamFrame(b)
+			if n > 0 {
+				err = c.handleStreamFrameLocked(id, off, data, fin)
+			}
+		case typ == frameTypeResetStream:
+			var id, finalSize int64
+			var code uint64
+			id, code, finalSize, n = parseResetStreamFrame(b)
+			if n > 0 {
+				err = c.handleResetStreamLocked(id, code, finalSize)
+			}
+		case typ == frameTypeStopSending:
+			var id int64
+			var code uint64
+			id, code, n = parseIDCodeFrame(b)
+			if n > 0 {
+				err = c.handleStopSendingLocked(id, code)
+			}
+		case typ == frameTypeMaxData:
+			var v uint64
+			v, n = parseVarintFrame(b)
+			if n > 0 && int64(v) > c.connOutMax {
+				c.connOutMax = int64(v)
+			}
+		case typ == frameTypeMaxStreamData:
+			var id int64
+			var v uint64
+			id, v, n = parseIDCodeFrame(b)
+			if n > 0 {
+				err = c.handleMaxStreamDataLocked(id, int64(v))
+			}
+		case typ == frameTypeMaxStreamsBidi || typ == frameTypeMaxStreamsUni:
+			var v uint64
+			v, n = parseVarintFrame(b)
+			if n > 0 {
+				dir := bidiStream
+				if typ == frameTypeMaxStreamsUni {
+					dir = uniStream
+				}
+				if v > 1<<60 {
+					err = localTransportError{errFrameEncoding, "MAX_STREAMS too large"}
+				} else if int64(v) > c.peerMaxStreams[dir] {
+					c.peerMaxStreams[dir] = int64(v)
+					close(c.streamLimitc)
+					c.streamLimitc = make(chan struct{})
+				}
+			}
+		case typ == frameTypeNewConnectionID:
+			// We never change the connection ID we send to,
+			// so additional connection IDs are unused.
+			_, _, _, n = parseNewConnectionIDFrame(b)
+		case typ == frameTypePathChallenge:
+			if len(b) < 9 {
+				n = -1
+				break
+			}
+			var data [8]byte
+			copy(data[:], b[1:9])
+			c.pathResponses = append(c.pathResponses, data)
+			n = 9
+		case typ == frameTypePathResponse:
+			n = 9
+			if len(b) < n {
+				n = -1
+			}
+		case typ == frameTypeConnectionCloseTransport || typ == frameTypeConnectionCloseApplication:
+			var app bool
+			var code uint64
+			var reason string
+			app, code, reason, n = parseConnectionCloseFrame(b)
+			if n > 0 {
+				var perr error
+				if app {
+					perr = &ApplicationError{Code: code, Reason: reason}
+				} else {
+					perr = peerTransportError{transportError(code), reason}
+				}
+				c.enterDrainingLocked(now, perr)
+				return ackEliciting, nil
+			}
+		case typ == frameTypeHandshakeDone:
+			n = 1
+			if c.side == serverSide {
+				err = localTransportError{errProtocolViolation, "client sent HANDSHAKE_DONE"}
+				break
+			}
+			if !c.handshakeConfirmed {
+				c.handshakeConfirmed = true
+				c.discardKeysLocked(handshakeSpace)
+			}
+		default:
+			n = skipFrame(typ, b)
+		}
+		if err != nil {
+			return false, err
+		}
+		if n <= 0 {
+			return false, localTransportError{errFrameEncoding, "malformed frame"}
+		}
+		b = b[n:]
+	}
+	return ackEliciting, nil
+}
+
+// frameAllowedInSpace reports whether a frame type may appear
+// in packets of a number space (RFC 9000, Section 12.4).
+func frameAllowedInSpace(typ uint64, space numberSpace) bool {
+	if space == appDataSpace {
+		return typ <= frameTypeHandshakeDone
+	}
+	switch typ {
+	case frameTypePadding, frameTypePing, frameTypeAck, frameTypeAckECN,
+		frameTypeCrypto, frameTypeConnectionCloseTransport:
+		return true
+	}
+	return false
+}
+
+// handleCryptoLocked handles a CRYPTO frame, passing any newly
+// contiguous data to TLS.
+func (c *Conn) handleCryptoLocked(now time.Time, space numberSpace, off int64, data []byte) error {
+	s := &c.spaces[space]
+	end := off + int64(len(data))
+	if end-s.cryptoIn.readOff > maxCryptoBufferSize {
+		return localTransportError{0x0d, "CRYPTO_BUFFER_EXCEEDED"}
+	}
+	s.cryptoIn.write(off, data)
+	if n := s.cryptoIn.readable(); n > 0 {
+		buf := make([]byte, n)
+		s.cryptoIn.read(buf)
+		if err := c.tls.HandleData(spaceTLSLevel(space), buf); err != nil {
+			return err
+		}
+		if err := c.handleTLSEventsLocked(now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"math"
+	"time"
+)
+
+// maxDatagramsPerWake limits the number of datagrams the conn loop sends
+// before checking for received datagrams and timers.
+const maxDatagramsPerWake = 16
+
+// appendDatagramsLocked returns the datagrams to send to the peer.
+func (c *Conn) appendDatagramsLocked(now time.Time) (dgrams [][]byte) {
+	switch c.closeState {
+	case connDraining, connDone:
+		return nil
+	case connClosing:
+		if !c.needClose {
+			return nil
+		}
+	}
+	for len(dgrams) < maxDatagramsPerWake {
+		if !c.addrValidated && 3*c.bytesRecv-c.bytesSent < maxUDPPayloadSize {
+			// Anti-amplification limit (RFC 9000, Section 8.1).
+			break
+		}
+		d := make([]byte, 0, maxUDPPayloadSize)
+		var (
+			pkts [numberSpaceCount]unsealedPacket
+			npkt int
+			pad  bool
+		)
+		for space := initialSpace; space < numberSpaceCount; space++ {
+			var ok bool
+			d, pkts[npkt], ok = c.appendPacketLocked(d, now, space)
+			if ok {
+				// RFC 9000, Section 14.1: pad datagrams containing
+				// ack-eliciting Initial packets (and all client Initial
+				// packets) to at least 1200 bytes.
+				pad = pad || (space == initialSpace && (c.side == clientSide || pkts[npkt].sent != nil))
+				npkt++
+			}
+		}
+		if npkt == 0 {
+			break
+		}
+		if pad && len(d) < minInitialDatagramSize {
+			// Pad the last packet in the datagram, before its tag.
+			last := &pkts[npkt-1]
+			d = d[:last.end]
+			for len(d) < minInitialDatagramSize-aeadTagSize {
+				d = append(d, frameTypePadding)
+			}
+			last.end = len(d)
+			d = append(d, make([]byte, aeadTagSize)...)
+		}
+		for _, p := range pkts[:npkt] {
+			c.sealPacketLocked(d, p)
+			if c.side == clientSide && p.space == handshakeSpace {
+				// RFC 9001, Section 4.9.1: a client discards Initial keys
+				// when it first sends a Handshake packet.
+				c.discardKeysLocked(initialSpace)
+			}
+		}
+		c.bytesSent += int64(len(d))
+		dgrams = append(dgrams, d)
+		if c.closeState == connClosing {
+			c.needClose = false
+			break
+		}
+	}
+	return dgrams
+}
+
+// An unsealedPacket is a packet in a datagram that has not yet been
+// encrypted. Room for its authentication tag follows its payload.
+type unsealedPacket struct {
+	space   numberSpace
+	pnum    int64
+	start   int // offset of the packet in the datagram
+	pnumOff int // offset of the packet number in the datagram
+	end     int // end of the packet payload, before the tag
+	sent    *sentPacket
+}
+
+// sealPacketLocked finishes the header of a packet and encrypts it in place.
+func (c *Conn) sealPacketLocked(d []byte, p unsealedPacket) {
+	pkt := d[p.start:p.end]
+	if p.space != appDataSpace {
+		finishLongHeader(pkt, p.pnumOff-p.start)
+	}
+	c.spaces[p.space].write.protect(pkt, p.pnumOff-p.start, packetNumberLength, p.pnum)
+	if p.sent != nil {
+		p.sent.size = p.end + aeadTagSize - p.start
+		c.loss.bytesInFlight += p.sent.size
+	}
+}
+
+// appendPacketLocked appends a packet in a number space to the datagram d,
+// if there is anything to send in that space. The packet is sealed later
+// by sealPacketLocked, after any padding is added.
+func (c *Conn) appendPacketLocked(d []byte, now time.Time, space numberSpace) ([]byte, unsealedPacket, bool) {
+	s := &c.spaces[space]
+	if s.discarded || !s.write.isSet() {
+		return d, unsealedPacket{}, false
+	}
+	start := len(d)
+	pnum := s.nextPnum
+	var pnumOff int
+	if space == appDataSpace {
+		d, pnumOff = append1RTTHeader(d, c.peerConnID, pnum)
+	} else {
+		d, pnumOff = appendLongHeader(d, packetTypeForSpace(space), c.peerConnID, c.localConnID, pnum)
+	}
+	payloadStart := len(d)
+	maxEnd := maxUDPPayloadSize - aeadTagSize
+	if maxEnd-payloadStart < 64 {
+		return d[:start], unsealedPacket{}, false
+	}
+
+	var (
+		frames       []sentFrame
+		ackEliciting bool
+		ackSent      bool
+	)
+	if c.closeState == connClosing {
+		code, reason := c.closeCode, c.closeReason
+		app := c.closeApp
+		if app && space != appDataSpace {
+			// RFC 9000, Section 10.2.3: application closes are sent
+			// as a transport APPLICATION_ERROR before 1-RTT.
+			app, code, reason = false, 0x0c, ""
+		}
+		if len(reason) > maxEnd-len(d)-32 {
+			reason = reason[:maxEnd-len(d)-32]
+		}
+		d = appendConnectionCloseFrame(d, app, code, reason)
+	} else {
+		if s.ackPending && len(s.seen) > 0 {
+			delay := uint64(now.Sub(s.largestRecvTime).Microseconds()) >> ackDelayExponent
+			d = appendAckFrame(d, s.seen, delay)
+			ackSent = true
+		}
+		if c.loss.canSend() || s.needProbe {
+			for s.cryptoOut.hasUnsent() {
+				avail := maxEnd - len(d) - cryptoFrameOverhead
+				if avail <= 0 {
+					break
+				}
+				off, data := s.cryptoOut.next(math.MaxInt64, avail)
+				if len(data) == 0 {
+					break
+				}
+				d = appendCryptoFrame(d, off, data)
+				s.cryptoOut.sent(off, int64(len(data)))
+				frames = append(frames, sentFrame{kind: sentCrypto, off: off, n: int64(len(data))})
+				ackEliciting = true
+			}
+			if space == appDataSpace {
+				var elicit bool
+				d, frames, elicit = c.appendAppFramesLocked(d, maxEnd, frames)
+				ackEliciting = ackEliciting || elicit
+			}
+			if s.needProbe && !ackEliciting {
+				d = append(d, frameTypePing)
+				ackEliciting = true
+			}
+		}
+	}
+	if len(d) == payloadStart {
+		return d[:start], unsealedPacket{}, false
+	}
+	p := unsealedPacket{
+		space:   space,
+		pnum:    pnum,
+		start:   start,
+		pnumOff: pnumOff,
+		end:     len(d),
+	}
+	d = append(d, make([]byte, aeadTagSize)...)
+
+	s.nextPnum++
+	if ackSent {
+		s.ackPending = false
+	}
+	if ackEliciting {
+		s.needProbe = false
+		p.sent = &sentPacket{
+			pnum:     pnum,
+			time:     now,
+			inFlight: true,
+			frames:   frames,
+		}
+		s.sent[pnum] = p.sent
+		s.lastAckElic = now
+		c.lastSend = now
+	}
+	return d, p, true
+}
+
+// appendAppFramesLocked appends frames sent only in 1-RTT packets:
+// connection and stream control frames and stream data.
+func (c *Conn) appendAppFramesLocked(d []byte, maxEnd int, frames []sentFrame) (_ []byte, _ []sentFrame, ackEliciting bool) {
+	// Control frames are small. Leave room for the largest of them.
+	const maxControlFrameSize = 1 + 3*8
+	fits := func() bool { return maxEnd-len(d) >= maxControlFrameSize }
+
+	if c.needHandshakeDone && fits() {
+		d = append(d, frameTypeHandshakeDone)
+		frames = append(frames, sentFrame{kind: sentHandshakeDone})
+		c.needHandshakeDone = false
+		ackEliciting = true
+	}
+	for len(c.pathResponses) > 0 && fits() {
+		d = append(d, frameTypePathResponse)
+		d = append(d, c.pathResponses[0][:]...)
+		c.pathResponses = c.pathResponses[1:]
+		ackEliciting = true
+	}
+	if c.needMaxData && fits() {
+		d = appendVarintFrame(d, frameTypeMaxData, uint64(c.connInMax))
+		frames = append(frames, sentFrame{kind: sentMaxData})
+		c.needMaxData = false
+		ackEliciting = true
+	}
+	for dir, typ := range [...]byte{frameTypeMaxStreamsBidi, frameTypeMaxStreamsUni} {
+		if c.needMaxStreams[dir] && fits() {
+			d = appendVarintFrame(d, typ, uint64(c.localMaxStreams[dir]))
+			frames = append(frames, sentFrame{kind: sentMaxStreams, id: int64(dir)})
+			c.needMaxStreams[dir] = false
+			ackEliciting = true
+		}
+	}
+	for _, s := range c.streams {
+		if s.needStopSending && fits() {
+			d = appendIDCodeFrame(d, frameTypeStopSending, s.id, s.inStopCode)
+			frames = append(frames, sentFrame{kind: sentStopSending, id: s.id})
+			s.needStopSending = false
+			ackEliciting = true
+		}
+		if s.needResetStream && fits() {
+			d = appendResetStreamFrame(d, s.id, s.outResetCode, s.out.maxSent)
+			frames = append(frames, sentFrame{kind: sentResetStream, id: s.id})
+			s.needResetStream = false
+			ackEliciting = true
+		}
+		if s.needMaxStreamData && fits() {
+			d = appendIDCodeFrame(d, frameTypeMaxStreamData, s.id, uint64(s.inMaxData))
+			frames = append(frames, sentFrame{kind: sentMaxStreamData, id: s.id})
+			s.needMaxStreamData = false
+			ackEliciting = true
+		}
+		if s.outReset {
+			continue
+		}
+		for s.out.hasUnsent() || (s.outFin && !s.outFinSent) {
+			avail := maxEnd - len(d) - streamFrameOverhead
+			if avail <= 0 {
+				break
+			}
+			limit := min(s.outMaxData, s.out.maxSent+c.connOutMax-c.connOutSent)
+			off, data := s.out.next(limit, avail)
+			end := off + int64(len(data))
+			fin := s.outFin && !s.outFinSent && end == s.out.end()
+			if len(data) == 0 && !fin {
+				break
+			}
+			d = appendStreamFrame(d, s.id, off, data, fin)
+			if end > s.out.maxSent {
+				c.connOutSent += end - s.out.maxSent
+			}
+			s.out.sent(off, int64(len(data)))
+			if fin {
+				s.outFinSent = true
+			}
+			frames = append(frames, sentFrame{kind: sentStream, id: s.id, off: off, n: int64(len(data)), fin: fin})
+			ackEliciting = true
+		}
+	}
+	return d, frames, ackEliciting
+}
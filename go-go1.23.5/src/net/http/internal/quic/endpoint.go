@@ -0,0 +1,233 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// An Endpoint handles QUIC traffic on a network address.
+// It can accept inbound connections or create outbound ones.
+//
+// Multiple goroutines may invoke methods on an Endpoint simultaneously.
+type Endpoint struct {
+	pc     net.PacketConn
+	config *Config // for accepting connections; nil for client-only endpoints
+
+	acceptc chan *Conn
+	closec  chan struct{}
+	readc   chan struct{} // closed when the read loop exits
+
+	mu     sync.Mutex
+	conns  map[string]*Conn // by local and original destination connection ID
+	all    map[*Conn]struct{}
+	closed bool
+}
+
+// Listen listens on a local network address.
+// If config is nil, the endpoint does not accept connections.
+func Listen(network, address string, config *Config) (*Endpoint, error) {
+	if config != nil && config.TLSConfig == nil {
+		return nil, errors.New("quic: Config.TLSConfig is nil")
+	}
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewEndpoint(pc, config), nil
+}
+
+// NewEndpoint returns an endpoint using the given packet connection.
+// The endpoint takes ownership of pc, and closes it when the endpoint is closed.
+// If config is nil, the endpoint does not accept connections.
+func NewEndpoint(pc net.PacketConn, config *Config) *Endpoint {
+	e := &Endpoint{
+		pc:      pc,
+		config:  config,
+		acceptc: make(chan *Conn, 64),
+		closec:  make(chan struct{}),
+		readc:   make(chan struct{}),
+		conns:   make(map[string]*Conn),
+		all:     make(map[*Conn]struct{}),
+	}
+	go e.readLoop()
+	return e
+}
+
+// LocalAddr returns the local network address.
+func (e *Endpoint) LocalAddr() net.Addr { return e.pc.LocalAddr() }
+
+// Close closes the endpoint, aborting all its connections.
+// It waits for connections to finish closing
+// until ctx is done, and then closes the underlying packet connection.
+func (e *Endpoint) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.closec)
+	}
+	conns := make([]*Conn, 0, len(e.all))
+	for c := range e.all {
+		conns = append(conns, c)
+	}
+	e.mu.Unlock()
+	for _, c := range conns {
+		c.Abort(nil)
+	}
+	var err error
+	for _, c := range conns {
+		select {
+		case <-c.donec:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+	}
+	e.pc.Close()
+	<-e.readc
+	return err
+}
+
+// Accept waits for and returns the next connection to the endpoint.
+// The handshake of a returned connection is complete.
+func (e *Endpoint) Accept(ctx context.Context) (*Conn, error) {
+	select {
+	case c := <-e.acceptc:
+		return c, nil
+	case <-e.closec:
+		return nil, errEndpointClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Dial creates and returns a connection to a network address.
+// It returns once the handshake is complete.
+func (e *Endpoint) Dial(ctx context.Context, network, address string, config *Config) (*Conn, error) {
+	if config == nil || config.TLSConfig == nil {
+		return nil, errors.New("quic: Config.TLSConfig is nil")
+	}
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil, errEndpointClosed
+	}
+	e.mu.Unlock()
+	localID := newRandomConnID()
+	peerID := newRandomConnID()
+	c, err := newConn(e, clientSide, addr, config, localID, peerID, peerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.addConn(c, localID); err != nil {
+		c.Abort(err)
+		return nil, err
+	}
+	c.wake()
+	if err := c.waitHandshake(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// addConn registers a conn under the given connection IDs.
+func (e *Endpoint) addConn(c *Conn, ids ...[]byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errEndpointClosed
+	}
+	for _, id := range ids {
+		e.conns[string(id)] = c
+	}
+	e.all[c] = struct{}{}
+	return nil
+}
+
+func (e *Endpoint) removeConn(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.conns, string(c.localConnID))
+	delete(e.conns, string(c.origDstConnID))
+	delete(e.all, c)
+}
+
+// queueAccept adds a server conn that completed its handshake to the
+// accept queue, or rejects it if the queue is full.
+func (e *Endpoint) queueAccept(c *Conn) {
+	select {
+	case e.acceptc <- c:
+	default:
+		c.enterClosingLocked(c.lastActivity, false, uint64(errConnectionRefused), "", errConnClosed)
+	}
+}
+
+func (e *Endpoint) writeTo(b []byte, addr net.Addr) {
+	e.pc.WriteTo(b, addr)
+}
+
+func (e *Endpoint) readLoop() {
+	defer close(e.readc)
+	buf := make([]byte, maxRecvDatagramSize)
+	for {
+		n, addr, err := e.pc.ReadFrom(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		e.handleDatagram(append([]byte(nil), buf[:n]...), addr)
+	}
+}
+
+func (e *Endpoint) handleDatagram(b []byte, addr net.Addr) {
+	id, ok := dstConnIDForDatagram(b)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	c := e.conns[string(id)]
+	closed := e.closed
+	e.mu.Unlock()
+	if c != nil {
+		c.deliver(b)
+		return
+	}
+	if closed || e.config == nil {
+		return
+	}
+	// A new connection starts with a client Initial packet
+	// in a datagram of at least 1200 bytes (RFC 9000, Section 14.1).
+	if getPacketType(b) != packetTypeInitial || len(b) < minInitialDatagramSize {
+		return
+	}
+	h, ok := parseLongHeader(b)
+	if !ok || h.version != quicVersion1 || len(h.dstConnID) < 8 {
+		return
+	}
+	origDstConnID := append([]byte(nil), h.dstConnID...)
+	peerID := append([]byte(nil), h.srcConnID...)
+	localID := newRandomConnID()
+	c, err := newConn(e, serverSide, addr, e.config, localID, peerID, origDstConnID)
+	if err != nil {
+		return
+	}
+	if err := e.addConn(c, localID, origDstConnID); err != nil {
+		c.Abort(err)
+		return
+	}
+	c.deliver(b)
+}
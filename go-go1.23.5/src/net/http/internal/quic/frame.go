@@ -0,0 +1,338 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// Frame types (RFC 9000, Section 19).
+const (
+	frameTypePadding                    = 0x00
+	frameTypePing                       = 0x01
+	frameTypeAck                        = 0x02
+	frameTypeAckECN                     = 0x03
+	frameTypeResetStream                = 0x04
+	frameTypeStopSending                = 0x05
+	frameTypeCrypto                     = 0x06
+	frameTypeNewToken                   = 0x07
+	frameTypeStreamBase                 = 0x08 // low three bits carry stream flags
+	frameTypeMaxData                    = 0x10
+	frameTypeMaxStreamData              = 0x11
+	frameTypeMaxStreamsBidi             = 0x12
+	frameTypeMaxStreamsUni              = 0x13
+	frameTypeDataBlocked                = 0x14
+	frameTypeStreamDataBlocked          = 0x15
+	frameTypeStreamsBlockedBidi         = 0x16
+	frameTypeStreamsBlockedUni          = 0x17
+	frameTypeNewConnectionID            = 0x18
+	frameTypeRetireConnectionID         = 0x19
+	frameTypePathChallenge              = 0x1a
+	frameTypePathResponse               = 0x1b
+	frameTypeConnectionCloseTransport   = 0x1c
+	frameTypeConnectionCloseApplication = 0x1d
+	frameTypeHandshakeDone              = 0x1e
+)
+
+// STREAM frame flags.
+const (
+	streamOffBit = 0x04
+	streamLenBit = 0x02
+	streamFinBit = 0x01
+)
+
+// ackDelayExponent is the ack_delay_exponent transport parameter
+// we use (the default value).
+const ackDelayExponent = 3
+
+// maxAckRanges limits the number of ACK ranges we send and track.
+const maxAckRanges = 32
+
+// parseAckFrame parses an ACK frame, including its type.
+// It returns the acknowledged packet numbers and the encoded ACK Delay.
+func parseAckFrame(b []byte) (acked rangeset, delay uint64, n int) {
+	typ, n := consumeVarint(b)
+	largest, nn := consumeVarintInt64(b[n:])
+	if nn < 0 {
+		return nil, 0, -1
+	}
+	n += nn
+	delay, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return nil, 0, -1
+	}
+	n += nn
+	count, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return nil, 0, -1
+	}
+	n += nn
+	first, nn := consumeVarintInt64(b[n:])
+	if nn < 0 || first > largest {
+		return nil, 0, -1
+	}
+	n += nn
+	end := largest + 1
+	start := largest - first
+	ranges := []i64range{{start, end}}
+	for i := uint64(0); i < count; i++ {
+		gap, nn := consumeVarintInt64(b[n:])
+		if nn < 0 {
+			return nil, 0, -1
+		}
+		n += nn
+		length, nn := consumeVarintInt64(b[n:])
+		if nn < 0 {
+			return nil, 0, -1
+		}
+		n += nn
+		end = start - gap - 1
+		start = end - length - 1
+		if start < 0 {
+			return nil, 0, -1
+		}
+		ranges = append(ranges, i64range{start, end})
+	}
+	if typ == frameTypeAckECN {
+		for i := 0; i < 3; i++ {
+			_, nn := consumeVarint(b[n:])
+			if nn < 0 {
+				return nil, 0, -1
+			}
+			n += nn
+		}
+	}
+	for i := len(ranges) - 1; i >= 0; i-- {
+		acked.add(ranges[i].start, ranges[i].end)
+	}
+	return acked, delay, n
+}
+
+// appendAckFrame appends an ACK frame acknowledging the packets in seen,
+// which must not be empty.
+func appendAckFrame(b []byte, seen rangeset, delay uint64) []byte {
+	b = append(b, frameTypeAck)
+	last := len(seen) - 1
+	b = appendVarint(b, uint64(seen[last].end-1))
+	b = appendVarint(b, delay)
+	count := min(last, maxAckRanges-1)
+	b = appendVarint(b, uint64(count))
+	b = appendVarint(b, uint64(seen[last].size()-1))
+	for i := last - 1; i >= last-count; i-- {
+		gap := seen[i+1].start - seen[i].end - 1
+		b = appendVarint(b, uint64(gap))
+		b = appendVarint(b, uint64(seen[i].size()-1))
+	}
+	return b
+}
+
+// parseCryptoFrame parses a CRYPTO frame, including its type.
+func parseCryptoFrame(b []byte) (off int64, data []byte, n int) {
+	_, n = consumeVarint(b)
+	off, nn := consumeVarintInt64(b[n:])
+	if nn < 0 {
+		return 0, nil, -1
+	}
+	n += nn
+	data, nn = consumeVarintBytes(b[n:])
+	if nn < 0 || off+int64(len(data)) > maxVarint {
+		return 0, nil, -1
+	}
+	return off, data, n + nn
+}
+
+// cryptoFrameOverhead is the maximum size of a CRYPTO frame header.
+const cryptoFrameOverhead = 1 + 8 + 2
+
+func appendCryptoFrame(b []byte, off int64, data []byte) []byte {
+	b = append(b, frameTypeCrypto)
+	b = appendVarint(b, uint64(off))
+	return appendVarintBytes(b, data)
+}
+
+// parseStreamFrame parses a STREAM frame, including its type.
+func parseStreamFrame(b []byte) (id, off int64, fin bool, data []byte, n int) {
+	typ, n := consumeVarint(b)
+	id, nn := consumeVarintInt64(b[n:])
+	if nn < 0 {
+		return 0, 0, false, nil, -1
+	}
+	n += nn
+	if typ&streamOffBit != 0 {
+		off, nn = consumeVarintInt64(b[n:])
+		if nn < 0 {
+			return 0, 0, false, nil, -1
+		}
+		n += nn
+	}
+	if typ&streamLenBit != 0 {
+		data, nn = consumeVarintBytes(b[n:])
+		if nn < 0 {
+			return 0, 0, false, nil, -1
+		}
+		n += nn
+	} else {
+		data = b[n:]
+		n = len(b)
+	}
+	if off+int64(len(data)) > maxVarint {
+		return 0, 0, false, nil, -1
+	}
+	return id, off, typ&streamFinBit != 0, data, n
+}
+
+// streamFrameOverhead is the maximum size of a STREAM frame header.
+const streamFrameOverhead = 1 + 8 + 8 + 2
+
+func appendStreamFrame(b []byte, id, off int64, data []byte, fin bool) []byte {
+	typ := byte(frameTypeStreamBase | streamOffBit | streamLenBit)
+	if fin {
+		typ |= streamFinBit
+	}
+	b = append(b, typ)
+	b = appendVarint(b, uint64(id))
+	b = appendVarint(b, uint64(off))
+	return appendVarintBytes(b, data)
+}
+
+// parseResetStreamFrame parses a RESET_STREAM frame, including its type.
+func parseResetStreamFrame(b []byte) (id int64, code uint64, finalSize int64, n int) {
+	_, n = consumeVarint(b)
+	var nn int
+	if id, nn = consumeVarintInt64(b[n:]); nn < 0 {
+		return 0, 0, 0, -1
+	}
+	n += nn
+	if code, nn = consumeVarint(b[n:]); nn < 0 {
+		return 0, 0, 0, -1
+	}
+	n += nn
+	if finalSize, nn = consumeVarintInt64(b[n:]); nn < 0 {
+		return 0, 0, 0, -1
+	}
+	return id, code, finalSize, n + nn
+}
+
+func appendResetStreamFrame(b []byte, id int64, code uint64, finalSize int64) []byte {
+	b = append(b, frameTypeResetStream)
+	b = appendVarint(b, uint64(id))
+	b = appendVarint(b, code)
+	return appendVarint(b, uint64(finalSize))
+}
+
+// parseIDCodeFrame parses a frame consisting of its type,
+// a stream ID, and a varint value: STOP_SENDING and MAX_STREAM_DATA.
+func parseIDCodeFrame(b []byte) (id int64, v uint64, n int) {
+	_, n = consumeVarint(b)
+	var nn int
+	if id, nn = consumeVarintInt64(b[n:]); nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	if v, nn = consumeVarint(b[n:]); nn < 0 {
+		return 0, 0, -1
+	}
+	return id, v, n + nn
+}
+
+func appendIDCodeFrame(b []byte, typ byte, id int64, v uint64) []byte {
+	b = append(b, typ)
+	b = appendVarint(b, uint64(id))
+	return appendVarint(b, v)
+}
+
+// parseVarintFrame parses a frame consisting of its type and a single
+// varint: MAX_DATA, MAX_STREAMS, DATA_BLOCKED, STREAMS_BLOCKED,
+// and RETIRE_CONNECTION_ID.
+func parseVarintFrame(b []byte) (v uint64, n int) {
+	_, n = consumeVarint(b)
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, -1
+	}
+	return v, n + nn
+}
+
+func appendVarintFrame(b []byte, typ byte, v uint64) []byte {
+	b = append(b, typ)
+	return appendVarint(b, v)
+}
+
+// parseNewConnectionIDFrame parses a NEW_CONNECTION_ID frame, including its type.
+func parseNewConnectionIDFrame(b []byte) (seq, retirePriorTo int64, cid []byte, n int) {
+	_, n = consumeVarint(b)
+	var nn int
+	if seq, nn = consumeVarintInt64(b[n:]); nn < 0 {
+		return 0, 0, nil, -1
+	}
+	n += nn
+	if retirePriorTo, nn = consumeVarintInt64(b[n:]); nn < 0 || retirePriorTo > seq {
+		return 0, 0, nil, -1
+	}
+	n += nn
+	if cid, nn = consumeUint8Bytes(b[n:]); nn < 0 || len(cid) < 1 || len(cid) > 20 {
+		return 0, 0, nil, -1
+	}
+	n += nn
+	const statelessResetTokenLen = 16
+	if len(b)-n < statelessResetTokenLen {
+		return 0, 0, nil, -1
+	}
+	return seq, retirePriorTo, cid, n + statelessResetTokenLen
+}
+
+// parseConnectionCloseFrame parses a CONNECTION_CLOSE frame, including its type.
+func parseConnectionCloseFrame(b []byte) (app bool, code uint64, reason string, n int) {
+	typ, n := consumeVarint(b)
+	app = typ == frameTypeConnectionCloseApplication
+	var nn int
+	if code, nn = consumeVarint(b[n:]); nn < 0 {
+		return false, 0, "", -1
+	}
+	n += nn
+	if !app {
+		// Frame type triggering the error.
+		if _, nn = consumeVarint(b[n:]); nn < 0 {
+			return false, 0, "", -1
+		}
+		n += nn
+	}
+	r, nn := consumeVarintBytes(b[n:])
+	if nn < 0 {
+		return false, 0, "", -1
+	}
+	return app, code, string(r), n + nn
+}
+
+func appendConnectionCloseFrame(b []byte, app bool, code uint64, reason string) []byte {
+	if app {
+		b = append(b, frameTypeConnectionCloseApplication)
+		b = appendVarint(b, code)
+	} else {
+		b = append(b, frameTypeConnectionCloseTransport)
+		b = appendVarint(b, code)
+		b = append(b, 0) // frame type
+	}
+	b = appendVarint(b, uint64(len(reason)))
+	return append(b, reason...)
+}
+
+// skipFrame returns the size of a frame carrying no information
+// this implementation uses, or -1 if it is malformed.
+func skipFrame(typ uint64, b []byte) int {
+	_, n := consumeVarint(b)
+	switch typ {
+	case frameTypeNewToken:
+		_, nn := consumeVarintBytes(b[n:])
+		if nn < 0 {
+			return -1
+		}
+		return n + nn
+	case frameTypeDataBlocked, frameTypeStreamsBlockedBidi, frameTypeStreamsBlockedUni,
+		frameTypeRetireConnectionID:
+		_, nn := parseVarintFrame(b)
+		return nn
+	case frameTypeStreamDataBlocked:
+		_, _, nn := parseIDCodeFrame(b)
+		return nn
+	}
+	return -1
+}
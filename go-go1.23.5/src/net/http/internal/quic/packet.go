@@ -0,0 +1,231 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "encoding/binary"
+
+const (
+	headerFormLong  = 0x80
+	fixedBit        = 0x40
+	longPacketTypes = 0x30
+)
+
+// A packetType is the type of a QUIC packet.
+type packetType byte
+
+const (
+	packetTypeInvalid packetType = iota
+	packetTypeInitial
+	packetType0RTT
+	packetTypeHandshake
+	packetTypeRetry
+	packetType1RTT
+	packetTypeVersionNegotiation
+)
+
+func (p packetType) String() string {
+	switch p {
+	case packetTypeInitial:
+		return "Initial"
+	case packetType0RTT:
+		return "0-RTT"
+	case packetTypeHandshake:
+		return "Handshake"
+	case packetTypeRetry:
+		return "Retry"
+	case packetType1RTT:
+		return "1-RTT"
+	case packetTypeVersionNegotiation:
+		return "VersionNegotiation"
+	}
+	return "Invalid"
+}
+
+// A numberSpace is a packet number space (RFC 9000, Section 12.3).
+type numberSpace int
+
+const (
+	initialSpace numberSpace = iota
+	handshakeSpace
+	appDataSpace
+	numberSpaceCount
+)
+
+func (s numberSpace) String() string {
+	switch s {
+	case initialSpace:
+		return "Initial"
+	case handshakeSpace:
+		return "Handshake"
+	case appDataSpace:
+		return "AppData"
+	}
+	return "BadSpace"
+}
+
+// packetTypeForSpace returns the type of packets sent in a number space.
+func packetTypeForSpace(s numberSpace) packetType {
+	switch s {
+	case initialSpace:
+		return packetTypeInitial
+	case handshakeSpace:
+		return packetTypeHandshake
+	}
+	return packetType1RTT
+}
+
+// getPacketType returns the type of the packet at the start of b.
+func getPacketType(b []byte) packetType {
+	if len(b) == 0 {
+		return packetTypeInvalid
+	}
+	if b[0]&headerFormLong == 0 {
+		if b[0]&fixedBit == 0 {
+			return packetTypeInvalid
+		}
+		return packetType1RTT
+	}
+	if len(b) < 5 {
+		return packetTypeInvalid
+	}
+	if binary.BigEndian.Uint32(b[1:5]) == 0 {
+		return packetTypeVersionNegotiation
+	}
+	switch (b[0] & longPacketTypes) >> 4 {
+	case 0:
+		return packetTypeInitial
+	case 1:
+		return packetType0RTT
+	case 2:
+		return packetTypeHandshake
+	}
+	return packetTypeRetry
+}
+
+// A longHeader is the parsed, still protected, header of a long header packet.
+type longHeader struct {
+	ptype   packetType
+	version uint32
+	dstConnID,
+	srcConnID []byte
+	token   []byte
+	pnumOff int // offset of the packet number in the packet
+	size    int // total size of the packet, including its header
+}
+
+// parseLongHeader parses the header of the long header packet at
+// the start of b. It returns false if the header is malformed.
+func parseLongHeader(b []byte) (h longHeader, ok bool) {
+	h.ptype = getPacketType(b)
+	if h.ptype == packetTypeInvalid || h.ptype == packetType1RTT {
+		return h, false
+	}
+	h.version = binary.BigEndian.Uint32(b[1:5])
+	off := 5
+	var n int
+	if h.dstConnID, n = consumeUint8Bytes(b[off:]); n < 0 || len(h.dstConnID) > 20 {
+		return h, false
+	}
+	off += n
+	if h.srcConnID, n = consumeUint8Bytes(b[off:]); n < 0 || len(h.srcConnID) > 20 {
+		return h, false
+	}
+	off += n
+	if h.version != quicVersion1 || h.ptype == packetTypeRetry || h.ptype == packetTypeVersionNegotiation {
+		// We can't parse the rest of the packet.
+		h.size = len(b)
+		return h, true
+	}
+	if h.ptype == packetTypeInitial {
+		if h.token, n = consumeVarintBytes(b[off:]); n < 0 {
+			return h, false
+		}
+		off += n
+	}
+	length, n := consumeVarint(b[off:])
+	if n < 0 || length > uint64(len(b)-off-n) {
+		return h, false
+	}
+	off += n
+	h.pnumOff = off
+	h.size = off + int(length)
+	return h, true
+}
+
+// dstConnIDForDatagram returns the destination connection ID of the
+// first packet in a datagram, given the length of connection IDs
+// used in short headers.
+func dstConnIDForDatagram(b []byte) ([]byte, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	if b[0]&headerFormLong == 0 {
+		if len(b) < 1+connIDLen {
+			return nil, false
+		}
+		return b[1 : 1+connIDLen], true
+	}
+	if len(b) < 6 {
+		return nil, false
+	}
+	id, n := consumeUint8Bytes(b[5:])
+	return id, n >= 0
+}
+
+// packetNumberLength is the length of encoded packet numbers we send.
+// Always using four bytes wastes a little space,
+// but ensures there is always enough ciphertext for the
+// header protection sample.
+const packetNumberLength = 4
+
+// appendLongHeader appends a long packet header to b.
+// The Length field is written as a placeholder to be filled in by
+// finishLongHeader. It returns the offset of the packet number.
+func appendLongHeader(b []byte, ptype packetType, dstConnID, srcConnID []byte, pnum int64) ([]byte, int) {
+	var typeBits byte
+	switch ptype {
+	case packetTypeInitial:
+		typeBits = 0x00
+	case packetTypeHandshake:
+		typeBits = 0x20
+	default:
+		panic("quic: bad long header packet type")
+	}
+	b = append(b, headerFormLong|fixedBit|typeBits|(packetNumberLength-1))
+	b = binary.BigEndian.AppendUint32(b, quicVersion1)
+	b = append(b, byte(len(dstConnID)))
+	b = append(b, dstConnID...)
+	b = append(b, byte(len(srcConnID)))
+	b = append(b, srcConnID...)
+	if ptype == packetTypeInitial {
+		b = append(b, 0) // no token
+	}
+	b = append(b, 0x40, 0) // 2-byte varint length, filled in later
+	pnumOff := len(b)
+	b = binary.BigEndian.AppendUint32(b, uint32(pnum))
+	return b, pnumOff
+}
+
+// finishLongHeader fills in the Length field of a long header, given the
+// size of the packet without its authentication tag.
+func finishLongHeader(pkt []byte, pnumOff int) {
+	length := len(pkt) - pnumOff + aeadTagSize
+	pkt[pnumOff-2] = 0x40 | byte(length>>8)
+	pkt[pnumOff-1] = byte(length)
+}
+
+// maxLongPacketLength is the largest value of the Length field
+// we can encode in finishLongHeader.
+const maxLongPacketLength = 1<<14 - 1
+
+// append1RTTHeader appends a short packet header to b.
+// It returns the offset of the packet number.
+func append1RTTHeader(b []byte, dstConnID []byte, pnum int64) ([]byte, int) {
+	b = append(b, fixedBit|(packetNumberLength-1))
+	b = append(b, dstConnID...)
+	pnumOff := len(b)
+	b = binary.BigEndian.AppendUint32(b, uint32(pnum))
+	return b, pnumOff
+}
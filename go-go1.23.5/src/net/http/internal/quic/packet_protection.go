@@ -0,0 +1,226 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// initialSalt is the salt used to derive Initial packet protection
+// keys (RFC 9001, Section 5.2).
+var initialSalt = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+
+// aeadTagSize is the size of the authentication tag of every
+// AEAD used by QUIC version 1.
+const aeadTagSize = 16
+
+// headerProtectionSampleSize is the size of the ciphertext sample used
+// for header protection (RFC 9001, Section 5.4.2).
+const headerProtectionSampleSize = 16
+
+// packetKeys are the keys protecting packets in one direction
+// at one encryption level.
+type packetKeys struct {
+	aead cipher.AEAD
+	iv   []byte
+	hp   headerProtection
+}
+
+func (k *packetKeys) isSet() bool { return k.aead != nil }
+
+type headerProtection interface {
+	// mask returns the header protection mask for a ciphertext sample.
+	mask(sample []byte) [5]byte
+}
+
+type aesHeaderProtection struct{ block cipher.Block }
+
+func (hp aesHeaderProtection) mask(sample []byte) (m [5]byte) {
+	var out [aes.BlockSize]byte
+	hp.block.Encrypt(out[:], sample)
+	copy(m[:], out[:])
+	return m
+}
+
+type chachaHeaderProtection struct{ key []byte }
+
+func (hp chachaHeaderProtection) mask(sample []byte) (m [5]byte) {
+	c, err := chacha20.NewUnauthenticatedCipher(hp.key, sample[4:16])
+	if err != nil {
+		panic(err)
+	}
+	c.SetCounter(binary.LittleEndian.Uint32(sample[:4]))
+	c.XORKeyStream(m[:], m[:])
+	return m
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1,
+// with an empty context.
+func hkdfExpandLabel(hash crypto.Hash, secret []byte, label string, length int) []byte {
+	const prefix = "tls13 "
+	info := make([]byte, 0, 2+1+len(prefix)+len(label)+1)
+	info = binary.BigEndian.AppendUint16(info, uint16(length))
+	info = append(info, byte(len(prefix)+len(label)))
+	info = append(info, prefix...)
+	info = append(info, label...)
+	info = append(info, 0)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(hash.New, secret, info).Read(out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// newPacketKeys derives the packet protection keys for a traffic
+// secret (RFC 9001, Section 5.1).
+func newPacketKeys(suite uint16, secret []byte) (packetKeys, error) {
+	var (
+		hash   crypto.Hash
+		keyLen int
+	)
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		hash, keyLen = crypto.SHA256, 16
+	case tls.TLS_AES_256_GCM_SHA384:
+		hash, keyLen = crypto.SHA384, 32
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		hash, keyLen = crypto.SHA256, chacha20poly1305.KeySize
+	default:
+		return packetKeys{}, fmt.Errorf("quic: unsupported cipher suite %#x", suite)
+	}
+	key := hkdfExpandLabel(hash, secret, "quic key", keyLen)
+	iv := hkdfExpandLabel(hash, secret, "quic iv", 12)
+	hpKey := hkdfExpandLabel(hash, secret, "quic hp", keyLen)
+	k := packetKeys{iv: iv}
+	if suite == tls.TLS_CHACHA20_POLY1305_SHA256 {
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return packetKeys{}, err
+		}
+		k.aead = aead
+		k.hp = chachaHeaderProtection{hpKey}
+		return k, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return packetKeys{}, err
+	}
+	k.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return packetKeys{}, err
+	}
+	hpBlock, err := aes.NewCipher(hpKey)
+	if err != nil {
+		return packetKeys{}, err
+	}
+	k.hp = aesHeaderProtection{hpBlock}
+	return k, nil
+}
+
+// initialKeys returns the client and server Initial packet protection keys
+// for the client's initial Destination Connection ID (RFC 9001, Section 5.2).
+func initialKeys(cid []byte) (client, server packetKeys) {
+	initialSecret := hkdf.Extract(sha256.New, cid, initialSalt)
+	clientSecret := hkdfExpandLabel(crypto.SHA256, initialSecret, "client in", sha256.Size)
+	serverSecret := hkdfExpandLabel(crypto.SHA256, initialSecret, "server in", sha256.Size)
+	var err error
+	if client, err = newPacketKeys(tls.TLS_AES_128_GCM_SHA256, clientSecret); err != nil {
+		panic(err)
+	}
+	if server, err = newPacketKeys(tls.TLS_AES_128_GCM_SHA256, serverSecret); err != nil {
+		panic(err)
+	}
+	return client, server
+}
+
+func (k *packetKeys) nonce(pnum int64) []byte {
+	nonce := make([]byte, len(k.iv))
+	copy(nonce, k.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pnum >> (8 * i))
+	}
+	return nonce
+}
+
+// protect encrypts the payload of the packet in pkt and applies header
+// protection. The payload starts at pnumOff+pnumLen and must be followed
+// in pkt's capacity by room for the authentication tag.
+// It returns the protected packet.
+func (k *packetKeys) protect(pkt []byte, pnumOff, pnumLen int, pnum int64) []byte {
+	hdr := pkt[:pnumOff+pnumLen]
+	payload := pkt[pnumOff+pnumLen:]
+	pkt = k.aead.Seal(hdr, k.nonce(pnum), payload, hdr)
+	sample := pkt[pnumOff+4:][:headerProtectionSampleSize]
+	mask := k.hp.mask(sample)
+	if pkt[0]&headerFormLong != 0 {
+		pkt[0] ^= mask[0] & 0x0f
+	} else {
+		pkt[0] ^= mask[0] & 0x1f
+	}
+	for i := 0; i < pnumLen; i++ {
+		pkt[pnumOff+i] ^= mask[1+i]
+	}
+	return pkt
+}
+
+var errDecrypt = errors.New("quic: packet decryption failed")
+
+// unprotect removes header protection from pkt and decrypts its payload,
+// in place. pnumOff is the offset of the packet number and largest is the
+// largest packet number received in the packet number space.
+// It returns the packet number, the decrypted payload, and the packet header.
+func (k *packetKeys) unprotect(pkt []byte, pnumOff int, largest int64) (pnum int64, hdr, payload []byte, err error) {
+	if len(pkt) < pnumOff+4+headerProtectionSampleSize {
+		return 0, nil, nil, errDecrypt
+	}
+	sample := pkt[pnumOff+4:][:headerProtectionSampleSize]
+	mask := k.hp.mask(sample)
+	if pkt[0]&headerFormLong != 0 {
+		pkt[0] ^= mask[0] & 0x0f
+	} else {
+		pkt[0] ^= mask[0] & 0x1f
+	}
+	pnumLen := int(pkt[0]&0x03) + 1
+	var truncated int64
+	for i := 0; i < pnumLen; i++ {
+		pkt[pnumOff+i] ^= mask[1+i]
+		truncated = truncated<<8 | int64(pkt[pnumOff+i])
+	}
+	pnum = decodePacketNumber(largest, truncated, pnumLen)
+	hdr = pkt[:pnumOff+pnumLen]
+	payload, err = k.aead.Open(pkt[len(hdr):len(hdr)], k.nonce(pnum), pkt[len(hdr):], hdr)
+	if err != nil {
+		return 0, nil, nil, errDecrypt
+	}
+	return pnum, hdr, payload, nil
+}
+
+// decodePacketNumber decodes a truncated packet number, given the largest
+// packet number received so far (RFC 9000, Appendix A.3).
+func decodePacketNumber(largest, truncated int64, pnumLen int) int64 {
+	expected := largest + 1
+	win := int64(1) << (8 * pnumLen)
+	hwin := win / 2
+	mask := win - 1
+	candidate := (expected &^ mask) | truncated
+	switch {
+	case candidate <= expected-hwin && candidate < (1<<62)-win:
+		return candidate + win
+	case candidate > expected+hwin && candidate >= win:
+		return candidate - win
+	}
+	return candidate
+}
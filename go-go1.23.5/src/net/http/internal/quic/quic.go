@@ -0,0 +1,210 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quic is an experimental implementation of the QUIC transport
+// protocol (RFC 9000), used by net/http for HTTP/3.
+//
+// The TLS handshake and key schedule are provided by [tls.QUICConn]
+// (RFC 9001). Loss detection and congestion control follow RFC 9002
+// in simplified form: a single NewReno congestion window, packet and
+// time thresholds for loss, and a probe timeout.
+//
+// Not implemented: 0-RTT, Retry, version negotiation, key updates,
+// connection migration, and issuing more than one connection ID.
+package quic
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// quicVersion1 is QUIC version 1 (RFC 9000).
+const quicVersion1 = 0x00000001
+
+const (
+	// connIDLen is the length of connection IDs chosen by this endpoint.
+	connIDLen = 8
+
+	// maxUDPPayloadSize is the size of the largest datagram we send.
+	// RFC 9000, Section 14 requires every path to support 1200 bytes,
+	// and we do not do path MTU discovery.
+	maxUDPPayloadSize = 1200
+
+	// minInitialDatagramSize is the size to which datagrams carrying
+	// ack-eliciting Initial packets are padded (RFC 9000, Section 14.1).
+	minInitialDatagramSize = 1200
+
+	// maxRecvDatagramSize is the size of the buffer used to read datagrams.
+	maxRecvDatagramSize = 1 << 16
+)
+
+// A Config structure is used to configure a QUIC endpoint or connection.
+// A Config must not be modified after being passed to a function in this package.
+// The zero value of each field means a default.
+type Config struct {
+	// TLSConfig is the endpoint's TLS configuration.
+	// It must be non-nil and include at least one certificate
+	// or else set GetCertificate for servers.
+	// The MinVersion is always raised to TLS 1.3.
+	TLSConfig *tls.Config
+
+	// MaxBidiRemoteStreams limits the number of simultaneous
+	// bidirectional streams a peer may open. Default: 100.
+	MaxBidiRemoteStreams int64
+
+	// MaxUniRemoteStreams limits the number of simultaneous
+	// unidirectional streams a peer may open. Default: 100.
+	MaxUniRemoteStreams int64
+
+	// MaxStreamReadBufferSize is the maximum amount of data sent by
+	// the peer that a stream will buffer before the application reads it.
+	// Default: 1 MiB.
+	MaxStreamReadBufferSize int64
+
+	// MaxStreamWriteBufferSize is the maximum amount of data a stream
+	// will buffer before Write blocks. Default: 1 MiB.
+	MaxStreamWriteBufferSize int64
+
+	// MaxConnReadBufferSize is the maximum amount of data sent by the
+	// peer that a connection will buffer for all streams together.
+	// Default: 1 MiB.
+	MaxConnReadBufferSize int64
+
+	// HandshakeTimeout is the maximum time allowed for the handshake.
+	// Default: 10 seconds.
+	HandshakeTimeout time.Duration
+
+	// MaxIdleTimeout is the maximum time a connection may be idle
+	// before it is closed. Default: 30 seconds.
+	MaxIdleTimeout time.Duration
+
+	// KeepAlivePeriod, if positive, is how often an idle connection
+	// sends a PING to keep itself open.
+	KeepAlivePeriod time.Duration
+}
+
+func configDefault[T ~int64](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+func (c *Config) maxBidiRemoteStreams() int64 { return configDefault(c.MaxBidiRemoteStreams, 100) }
+func (c *Config) maxUniRemoteStreams() int64  { return configDefault(c.MaxUniRemoteStreams, 100) }
+func (c *Config) maxStreamReadBufferSize() int64 {
+	return configDefault(c.MaxStreamReadBufferSize, 1<<20)
+}
+func (c *Config) maxStreamWriteBufferSize() int64 {
+	return configDefault(c.MaxStreamWriteBufferSize, 1<<20)
+}
+func (c *Config) maxConnReadBufferSize() int64 { return configDefault(c.MaxConnReadBufferSize, 1<<20) }
+func (c *Config) handshakeTimeout() time.Duration {
+	return configDefault(c.HandshakeTimeout, 10*time.Second)
+}
+func (c *Config) maxIdleTimeout() time.Duration {
+	return configDefault(c.MaxIdleTimeout, 30*time.Second)
+}
+
+// A transportError is a QUIC transport error code (RFC 9000, Section 20.1).
+type transportError uint64
+
+const (
+	errNo                = transportError(0x00)
+	errInternal          = transportError(0x01)
+	errConnectionRefused = transportError(0x02)
+	errFlowControl       = transportError(0x03)
+	errStreamLimit       = transportError(0x04)
+	errStreamState       = transportError(0x05)
+	errFinalSize         = transportError(0x06)
+	errFrameEncoding     = transportError(0x07)
+	errTransportParam    = transportError(0x08)
+	errProtocolViolation = transportError(0x0a)
+	errCryptoBase        = transportError(0x100) // 0x100-0x1ff: TLS alerts
+)
+
+func (e transportError) Error() string {
+	if e >= errCryptoBase && e <= errCryptoBase+0xff {
+		return fmt.Sprintf("quic: TLS alert %v", tls.AlertError(e-errCryptoBase))
+	}
+	return fmt.Sprintf("quic: transport error %#x", uint64(e))
+}
+
+// A localTransportError is an error detected by this endpoint,
+// with a reason sent to the peer in a CONNECTION_CLOSE frame.
+type localTransportError struct {
+	code   transportError
+	reason string
+}
+
+func (e localTransportError) Error() string {
+	if e.reason == "" {
+		return e.code.Error()
+	}
+	return e.code.Error() + ": " + e.reason
+}
+
+func (e localTransportError) Unwrap() error { return e.code }
+
+// A peerTransportError is a transport error reported by the peer
+// in a CONNECTION_CLOSE frame.
+type peerTransportError struct {
+	code   transportError
+	reason string
+}
+
+func (e peerTransportError) Error() string {
+	return fmt.Sprintf("quic: peer closed connection: %v (%q)", e.code, e.reason)
+}
+
+func (e peerTransportError) Unwrap() error { return e.code }
+
+// An ApplicationError is an application protocol error code
+// (RFC 9000, Section 20.2) carried in a CONNECTION_CLOSE frame.
+// Closing a connection with an ApplicationError sends it to the peer,
+// and a connection closed by the peer reports the peer's error.
+type ApplicationError struct {
+	Code   uint64
+	Reason string
+}
+
+func (e *ApplicationError) Error() string {
+	return fmt.Sprintf("quic: application error %#x: %v", e.Code, e.Reason)
+}
+
+// Is reports whether err is an ApplicationError with the same code.
+func (e *ApplicationError) Is(err error) bool {
+	e2, ok := err.(*ApplicationError)
+	return ok && e2.Code == e.Code
+}
+
+// A StreamErrorCode is an application protocol error code
+// (RFC 9000, Section 20.2) indicating why a stream is being closed.
+type StreamErrorCode uint64
+
+func (e StreamErrorCode) Error() string {
+	return fmt.Sprintf("quic: stream error code %#x", uint64(e))
+}
+
+// errIdleTimeout is returned by operations on a connection
+// closed because it was idle.
+var errIdleTimeout = idleTimeoutError{}
+
+type idleTimeoutError struct{}
+
+func (idleTimeoutError) Error() string   { return "quic: connection idle timeout" }
+func (idleTimeoutError) Timeout() bool   { return true }
+func (idleTimeoutError) Temporary() bool { return false }
+
+// errConnClosed is returned by operations on a connection
+// closed locally.
+var errConnClosed = errorString("quic: connection closed")
+
+// errEndpointClosed is returned by operations on a closed Endpoint.
+var errEndpointClosed = errorString("quic: endpoint closed")
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
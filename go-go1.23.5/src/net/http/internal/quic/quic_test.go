@@ -0,0 +1,382 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http/internal/testcert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestInitialSecrets(t *testing.T) {
+	// RFC 9001, Appendix A.1.
+	cid, _ := hex.DecodeString("8394c8f03e515708")
+	initialSecret := hkdf.Extract(sha256.New, cid, initialSalt)
+	for _, test := range []struct {
+		label       string
+		key, iv, hp string
+		secret      string
+	}{{
+		label:  "client in",
+		secret: "c00cf151ca5be075ed0ebfb5c80323c42d6b7db67881289af4008f1f6c357aea",
+		key:    "1f369613dd76d5467730efcbe3b1a22d",
+		iv:     "fa044b2f42a3fd3b46fb255c",
+		hp:     "9f50449e04a0e810283a1e9933adedd2",
+	}, {
+		label:  "server in",
+		secret: "3c199828fd139efd216c155ad844cc81fb82fa8d7446fa7d78be803acdda951b",
+		key:    "cf3a5331653c364c88f0f379b6067e37",
+		iv:     "0ac1493ca1905853b0bba03e",
+		hp:     "c206b8d9b9f0f37644430b490eeaa314",
+	}} {
+		secret := hkdfExpandLabel(crypto.SHA256, initialSecret, test.label, sha256.Size)
+		if got := hex.EncodeToString(secret); got != test.secret {
+			t.Errorf("%v secret = %v, want %v", test.label, got, test.secret)
+		}
+		for _, k := range []struct {
+			label string
+			n     int
+			want  string
+		}{
+			{"quic key", 16, test.key},
+			{"quic iv", 12, test.iv},
+			{"quic hp", 16, test.hp},
+		} {
+			if got := hex.EncodeToString(hkdfExpandLabel(crypto.SHA256, secret, k.label, k.n)); got != k.want {
+				t.Errorf("%v %v = %v, want %v", test.label, k.label, got, k.want)
+			}
+		}
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for _, v := range []uint64{0, 63, 64, 16383, 16384, 1<<30 - 1, 1 << 30, maxVarint} {
+		b := appendVarint(nil, v)
+		if len(b) != sizeVarint(v) {
+			t.Errorf("appendVarint(%v) has size %v, want %v", v, len(b), sizeVarint(v))
+		}
+		got, n := consumeVarint(b)
+		if got != v || n != len(b) {
+			t.Errorf("consumeVarint(appendVarint(%v)) = %v, %v", v, got, n)
+		}
+	}
+}
+
+func TestRangeset(t *testing.T) {
+	var s rangeset
+	s.add(10, 20)
+	s.add(30, 40)
+	s.add(20, 30)
+	if !s.isrange(10, 40) {
+		t.Fatalf("after adding adjacent ranges: %v, want [10,40)", s)
+	}
+	s.sub(15, 25)
+	if len(s) != 2 || s.contains(20) || !s.contains(14) || !s.contains(25) {
+		t.Fatalf("after sub(15, 25): %v", s)
+	}
+}
+
+func TestAckFrameRoundTrip(t *testing.T) {
+	var seen rangeset
+	seen.add(0, 3)
+	seen.add(5, 6)
+	seen.add(10, 20)
+	b := appendAckFrame(nil, seen, 7)
+	got, delay, n := parseAckFrame(b)
+	if n != len(b) || delay != 7 {
+		t.Fatalf("parseAckFrame: n=%v delay=%v, want %v, 7", n, delay, len(b))
+	}
+	if len(got) != len(seen) {
+		t.Fatalf("parseAckFrame = %v, want %v", got, seen)
+	}
+	for i := range got {
+		if got[i] != seen[i] {
+			t.Fatalf("parseAckFrame = %v, want %v", got, seen)
+		}
+	}
+}
+
+func TestTransportParametersRoundTrip(t *testing.T) {
+	p := transportParameters{
+		originalDstConnID:     []byte{1, 2, 3},
+		maxIdleTimeout:        5 * time.Second,
+		maxUDPPayloadSize:     1500,
+		initialMaxData:        1 << 20,
+		initialMaxStreamsBidi: 10,
+		activeConnIDLimit:     2,
+		initialSrcConnID:      []byte{4, 5},
+	}
+	got, err := unmarshalTransportParameters(marshalTransportParameters(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.originalDstConnID, p.originalDstConnID) ||
+		got.maxIdleTimeout != p.maxIdleTimeout ||
+		got.initialMaxData != p.initialMaxData ||
+		got.initialMaxStreamsBidi != p.initialMaxStreamsBidi ||
+		!bytes.Equal(got.initialSrcConnID, p.initialSrcConnID) {
+		t.Errorf("round trip: got %+v, want %+v", got, p)
+	}
+	dup := appendIntParam(nil, paramInitialMaxData, 1)
+	dup = appendIntParam(dup, paramInitialMaxData, 1)
+	if _, err := unmarshalTransportParameters(dup); err == nil {
+		t.Errorf("duplicate parameter: got no error")
+	}
+}
+
+func testConfigs(t *testing.T) (server, client *Config) {
+	cert, err := tls.X509KeyPair(testcert.LocalhostCert, testcert.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	server = &Config{TLSConfig: &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"test"},
+	}}
+	client = &Config{TLSConfig: &tls.Config{
+		RootCAs:    pool,
+		ServerName: "example.com",
+		NextProtos: []string{"test"},
+	}}
+	return server, client
+}
+
+// lossyPacketConn drops every n-th datagram it sends.
+type lossyPacketConn struct {
+	net.PacketConn
+	n     int64
+	count atomic.Int64
+}
+
+func (c *lossyPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if c.n > 0 && c.count.Add(1)%c.n == 0 {
+		return len(b), nil
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func newTestEndpoints(t *testing.T, dropEvery int64) (server, client *Endpoint, clientConfig *Config) {
+	serverConfig, clientConfig := testConfigs(t)
+	listen := func(config *Config) *Endpoint {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Skipf("cannot listen on UDP: %v", err)
+		}
+		e := NewEndpoint(&lossyPacketConn{PacketConn: pc, n: dropEvery}, config)
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			e.Close(ctx)
+		})
+		return e
+	}
+	return listen(serverConfig), listen(nil), clientConfig
+}
+
+func testStreamEcho(t *testing.T, dropEvery int64, size int) {
+	server, client, clientConfig := newTestEndpoints(t, dropEvery)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c, err := server.Accept(ctx)
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+			return
+		}
+		s, err := c.AcceptStream(ctx)
+		if err != nil {
+			t.Errorf("AcceptStream: %v", err)
+			return
+		}
+		s.SetReadContext(ctx)
+		s.SetWriteContext(ctx)
+		if _, err := io.Copy(s, s); err != nil {
+			t.Errorf("server copy: %v", err)
+		}
+		s.CloseWrite()
+	}()
+
+	c, err := client.Dial(ctx, "udp", server.LocalAddr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if got := c.ConnectionState().NegotiatedProtocol; got != "test" {
+		t.Errorf("NegotiatedProtocol = %q, want %q", got, "test")
+	}
+	s, err := c.NewStream(ctx)
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	s.SetReadContext(ctx)
+	s.SetWriteContext(ctx)
+	want := make([]byte, size)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+	go func() {
+		s.Write(want)
+		s.CloseWrite()
+	}()
+	got, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("echoed %v bytes, want %v identical bytes", len(got), len(want))
+	}
+	wg.Wait()
+	c.Close()
+}
+
+func TestStreamEcho(t *testing.T) {
+	testStreamEcho(t, 0, 100)
+}
+
+func TestStreamEchoLarge(t *testing.T) {
+	// Larger than the default flow control windows.
+	testStreamEcho(t, 0, 3<<20)
+}
+
+func TestStreamEchoWithLoss(t *testing.T) {
+	testStreamEcho(t, 7, 256<<10)
+}
+
+func TestConnCloseApplicationError(t *testing.T) {
+	server, client, clientConfig := newTestEndpoints(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	donec := make(chan error, 1)
+	go func() {
+		c, err := server.Accept(ctx)
+		if err != nil {
+			donec <- err
+			return
+		}
+		donec <- c.Wait(ctx)
+	}()
+	c, err := client.Dial(ctx, "udp", server.LocalAddr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	c.Abort(&ApplicationError{Code: 42, Reason: "bye"})
+	err = <-donec
+	var ae *ApplicationError
+	if !errors.As(err, &ae) || ae.Code != 42 || ae.Reason != "bye" {
+		t.Fatalf("server Wait = %v, want application error 42", err)
+	}
+}
+
+func TestConnCloseGracefully(t *testing.T) {
+	server, client, clientConfig := newTestEndpoints(t, 7)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	want := make([]byte, 256<<10)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+	go func() {
+		c, err := server.Accept(ctx)
+		if err != nil {
+			return
+		}
+		s, err := c.NewSendOnlyStream(ctx)
+		if err != nil {
+			c.Abort(nil)
+			return
+		}
+		s.SetWriteContext(ctx)
+		s.Write(want)
+		s.CloseWrite()
+		c.CloseGracefully(&ApplicationError{Code: 42}, 20*time.Second)
+	}()
+	c, err := client.Dial(ctx, "udp", server.LocalAddr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	s, err := c.AcceptStream(ctx)
+	if err != nil {
+		t.Fatalf("AcceptStream: %v", err)
+	}
+	s.SetReadContext(ctx)
+	got, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read %v bytes, want %v identical bytes", len(got), len(want))
+	}
+	err = c.Wait(ctx)
+	var ae *ApplicationError
+	if !errors.As(err, &ae) || ae.Code != 42 {
+		t.Fatalf("Wait = %v, want application error 42", err)
+	}
+}
+
+func TestStreamReset(t *testing.T) {
+	server, client, clientConfig := newTestEndpoints(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() {
+		c, err := server.Accept(ctx)
+		if err != nil {
+			return
+		}
+		s, err := c.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		s.Reset(7)
+	}()
+	c, err := client.Dial(ctx, "udp", server.LocalAddr().String(), clientConfig)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Abort(nil)
+	s, err := c.NewStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetReadContext(ctx)
+	s.Write([]byte("hello"))
+	_, err = io.ReadAll(s)
+	if code, ok := err.(StreamErrorCode); !ok || code != 7 {
+		t.Fatalf("Read after peer reset: %v, want StreamErrorCode(7)", err)
+	}
+}
+
+func TestDialHandshakeFailure(t *testing.T) {
+	server, client, clientConfig := newTestEndpoints(t, 0)
+	clientConfig.TLSConfig.ServerName = "wrong.example"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := client.Dial(ctx, "udp", server.LocalAddr().String(), clientConfig)
+	if err == nil {
+		t.Fatal("Dial with wrong server name succeeded")
+	}
+}
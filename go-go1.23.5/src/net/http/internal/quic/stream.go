@@ -0,0 +1,732 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+type streamDir int8
+
+const (
+	bidiStream streamDir = 0
+	uniStream  streamDir = 1
+)
+
+// Stream IDs encode the initiator in bit 0 and the direction
+// in bit 1 (RFC 9000, Section 2.1).
+func streamIDInitiator(id int64) connSide { return connSide(id & 1) }
+func streamIDDir(id int64) streamDir      { return streamDir((id >> 1) & 1) }
+func streamIDIndex(id int64) int64        { return id >> 2 }
+
+func newStreamID(side connSide, dir streamDir, index int64) int64 {
+	return index<<2 | int64(dir)<<1 | int64(side)
+}
+
+// A Stream is an ordered byte stream.
+//
+// Streams may be bidirectional or unidirectional.
+// Reading from a send-only stream or writing to a receive-only
+// stream returns an error.
+type Stream struct {
+	id   int64
+	conn *Conn
+
+	inc  chan struct{} // signaled when the receive side changes
+	outc chan struct{} // signaled when the send side changes
+
+	// The fields below are guarded by conn.mu.
+	readCtx, writeCtx context.Context
+
+	// Receive side.
+	in                recvBuffer
+	inMaxData         int64 // MAX_STREAM_DATA we sent
+	inHighest         int64 // highest offset received
+	inFinal           int64 // final size, or -1 if unknown
+	inResetCode       uint64
+	inReset           bool // the peer reset the stream
+	inClosed          bool // CloseRead was called
+	inStopCode        uint64
+	needStopSending   bool
+	needMaxStreamData bool
+
+	// Send side.
+	out             sendBuffer
+	outMaxData      int64 // peer's MAX_STREAM_DATA
+	outFin          bool  // CloseWrite was called
+	outFinSent      bool
+	outFinAcked     bool
+	outReset        bool
+	outResetCode    uint64
+	outResetAcked   bool
+	needResetStream bool
+	outStopCode     uint64 // peer's STOP_SENDING code, if outStopped
+	outStopped      bool
+
+	removed bool
+}
+
+func newStream(c *Conn, id int64) *Stream {
+	s := &Stream{
+		id:       id,
+		conn:     c,
+		inc:      make(chan struct{}, 1),
+		outc:     make(chan struct{}, 1),
+		inFinal:  -1,
+		readCtx:  context.Background(),
+		writeCtx: context.Background(),
+	}
+	local := streamIDInitiator(id) == c.side
+	switch {
+	case streamIDDir(id) == uniStream && local:
+		s.markRecvDone()
+	case streamIDDir(id) == uniStream && !local:
+		s.markSendDone()
+	}
+	if s.hasRecv() {
+		s.inMaxData = c.config.maxStreamReadBufferSize()
+	}
+	if s.hasSend() {
+		p := &c.peerParams
+		switch {
+		case streamIDDir(id) == uniStream:
+			s.outMaxData = p.initialMaxStreamDataUni
+		case local:
+			s.outMaxData = p.initialMaxStreamDataBidiRemote
+		default:
+			s.outMaxData = p.initialMaxStreamDataBidiLocal
+		}
+	}
+	return s
+}
+
+// markRecvDone and markSendDone set up a unidirectional stream's
+// missing half as already finished.
+func (s *Stream) markRecvDone() { s.inReset, s.inClosed = true, true }
+func (s *Stream) markSendDone() {
+	s.outFin, s.outFinSent, s.outFinAcked = true, true, true
+}
+
+func (s *Stream) hasRecv() bool {
+	return streamIDDir(s.id) == bidiStream || streamIDInitiator(s.id) != s.conn.side
+}
+
+func (s *Stream) hasSend() bool {
+	return streamIDDir(s.id) == bidiStream || streamIDInitiator(s.id) == s.conn.side
+}
+
+// ID returns the QUIC stream ID.
+func (s *Stream) ID() int64 { return s.id }
+
+// IsReadOnly reports whether the stream is read-only
+// (a unidirectional stream created by the peer).
+func (s *Stream) IsReadOnly() bool { return !s.hasSend() }
+
+// IsWriteOnly reports whether the stream is write-only
+// (a unidirectional stream created locally).
+func (s *Stream) IsWriteOnly() bool { return !s.hasRecv() }
+
+// SetReadContext sets the context used when reading from the stream.
+// Read returns when the context is done.
+func (s *Stream) SetReadContext(ctx context.Context) {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	s.readCtx = ctx
+}
+
+// SetWriteContext sets the context used when writing to the stream.
+// Write returns when the context is done.
+func (s *Stream) SetWriteContext(ctx context.Context) {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	s.writeCtx = ctx
+}
+
+func (s *Stream) signalIn()  { signal(s.inc) }
+func (s *Stream) signalOut() { signal(s.outc) }
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+var errStreamClosed = errors.New("quic: stream closed")
+
+// Read reads data from the stream.
+// It returns io.EOF after the peer has closed its side of the stream
+// and all data has been read, or a StreamErrorCode if the peer reset it.
+func (s *Stream) Read(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasRecv() {
+		return 0, errors.New("quic: read from write-only stream")
+	}
+	for {
+		if s.inClosed {
+			return 0, errStreamClosed
+		}
+		if s.inReset {
+			return 0, StreamErrorCode(s.inResetCode)
+		}
+		if n := s.in.readable(); n > 0 {
+			n = s.in.read(b)
+			c.onStreamReadLocked(s, n)
+			return n, nil
+		}
+		if s.inFinal >= 0 && s.in.readOff == s.inFinal {
+			c.maybeRemoveStreamLocked(s)
+			return 0, io.EOF
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+		if c.closeErr != nil {
+			return 0, c.closeErr
+		}
+		ctx := s.readCtx
+		c.mu.Unlock()
+		err := waitSignal(ctx, s.inc)
+		c.mu.Lock()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write writes data to the stream.
+// It blocks while the stream's send buffer is full.
+func (s *Stream) Write(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasSend() {
+		return 0, errors.New("quic: write to read-only stream")
+	}
+	n := 0
+	for {
+		switch {
+		case s.outStopped:
+			return n, StreamErrorCode(s.outStopCode)
+		case s.outReset || s.outFin:
+			return n, errStreamClosed
+		case c.closeErr != nil:
+			return n, c.closeErr
+		}
+		if avail := c.config.maxStreamWriteBufferSize() - s.out.buffered(); avail > 0 {
+			m := min(int64(len(b)-n), avail)
+			s.out.write(b[n : n+int(m)])
+			n += int(m)
+			c.wake()
+			if n == len(b) {
+				return n, nil
+			}
+		}
+		ctx := s.writeCtx
+		c.mu.Unlock()
+		err := waitSignal(ctx, s.outc)
+		c.mu.Lock()
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+func waitSignal(ctx context.Context, ch chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseWrite closes the send side of the stream.
+// The peer reads io.EOF after reading all data written to the stream.
+func (s *Stream) CloseWrite() error {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.hasSend() {
+		return nil
+	}
+	if !s.outFin && !s.outReset {
+		s.outFin = true
+		c.wake()
+	}
+	return nil
+}
+
+// CloseRead aborts the receive side of the stream.
+// If the peer has not finished sending, it is asked to stop
+// with STOP_SENDING and error code 0.
+func (s *Stream) CloseRead() {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.closeReadLocked(0)
+}
+
+// StopSending aborts the receive side of the stream, like CloseRead,
+// asking the peer to stop sending with the given error code.
+func (s *Stream) StopSending(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.closeReadLocked(code)
+}
+
+// AtEOF reports whether the peer has closed its side of the stream
+// and all data it sent has been read.
+func (s *Stream) AtEOF() bool {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return s.inFinal >= 0 && s.in.readOff == s.inFinal
+}
+
+func (s *Stream) closeReadLocked(code uint64) {
+	c := s.conn
+	if s.inClosed {
+		return
+	}
+	s.inClosed = true
+	if !s.inReset && !(s.inFinal >= 0 && s.inHighest == s.inFinal) {
+		s.needStopSending = true
+		s.inStopCode = code
+	}
+	// Data buffered but never read still counts as consumed for
+	// connection-level flow control.
+	c.creditConnLocked(s.in.discardTo(s.inHighest))
+	s.signalIn()
+	c.maybeRemoveStreamLocked(s)
+	c.wake()
+}
+
+// Reset aborts the send side of the stream with an error code.
+// Data not yet acknowledged by the peer is discarded.
+func (s *Stream) Reset(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.resetLocked(code)
+}
+
+func (s *Stream) resetLocked(code uint64) {
+	if !s.hasSend() || s.outReset || s.outFinAcked {
+		return
+	}
+	s.outReset = true
+	s.outResetCode = code
+	s.needResetStream = true
+	s.out.discardUnsent()
+	s.signalOut()
+	s.conn.wake()
+}
+
+// Close closes the stream: the send side finishes normally and
+// the receive side is aborted.
+func (s *Stream) Close() error {
+	s.CloseWrite()
+	s.CloseRead()
+	return nil
+}
+
+// inDone reports whether the application is done with the receive side
+// of the stream.
+func (s *Stream) inDone() bool {
+	return s.inReset || s.inClosed || (s.inFinal >= 0 && s.in.readOff == s.inFinal)
+}
+
+// recvFinished reports whether the peer is done sending on the stream.
+func (s *Stream) recvFinished() bool {
+	return s.inReset || (s.inFinal >= 0 && s.inHighest == s.inFinal && s.inDone())
+}
+
+// outDone reports whether the send side of the stream is finished.
+func (s *Stream) outDone() bool {
+	if s.outReset {
+		return s.outResetAcked
+	}
+	return s.outFinAcked && s.out.allAcked()
+}
+
+// AcceptStream waits for and returns the next stream created by the peer.
+func (c *Conn) AcceptStream(ctx context.Context) (*Stream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if len(c.acceptq) > 0 {
+			s := c.acceptq[0]
+			c.acceptq = c.acceptq[1:]
+			return s, nil
+		}
+		if c.closeErr != nil {
+			return nil, c.closeErr
+		}
+		c.mu.Unlock()
+		err := waitSignal(ctx, c.acceptc)
+		c.mu.Lock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// NewStream creates a bidirectional stream.
+// It blocks until the peer permits opening another stream.
+func (c *Conn) NewStream(ctx context.Context) (*Stream, error) {
+	return c.newLocalStream(ctx, bidiStream)
+}
+
+// NewSendOnlyStream creates a unidirectional, send-only stream.
+// It blocks until the peer permits opening another stream.
+func (c *Conn) NewSendOnlyStream(ctx context.Context) (*Stream, error) {
+	return c.newLocalStream(ctx, uniStream)
+}
+
+func (c *Conn) newLocalStream(ctx context.Context, dir streamDir) (*Stream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if c.closeErr != nil {
+			return nil, c.closeErr
+		}
+		if c.nextLocal[dir] < c.peerMaxStreams[dir] {
+			break
+		}
+		ch := c.streamLimitc
+		c.mu.Unlock()
+		var err error
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		c.mu.Lock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	id := newStreamID(c.side, dir, c.nextLocal[dir])
+	c.nextLocal[dir]++
+	s := newStream(c, id)
+	c.streams[id] = s
+	return s, nil
+}
+
+// streamForFrameLocked returns the stream a frame refers to,
+// creating peer-initiated streams as needed. It returns nil if the
+// stream has already been closed.
+func (c *Conn) streamForFrameLocked(id int64) (*Stream, error) {
+	if s := c.streams[id]; s != nil {
+		return s, nil
+	}
+	dir := streamIDDir(id)
+	index := streamIDIndex(id)
+	if streamIDInitiator(id) == c.side {
+		if index >= c.nextLocal[dir] {
+			return nil, localTransportError{errStreamState, "frame for unopened stream"}
+		}
+		return nil, nil
+	}
+	if index >= c.localMaxStreams[dir] {
+		return nil, localTransportError{errStreamLimit, "peer exceeded stream limit"}
+	}
+	if index < c.remoteOpened[dir] {
+		return nil, nil
+	}
+	// RFC 9000, Section 3.2: opening a stream implicitly opens
+	// all lower-numbered streams of the same type.
+	var s *Stream
+	for ; c.remoteOpened[dir] <= index; c.remoteOpened[dir]++ {
+		s = newStream(c, newStreamID(c.side^1, dir, c.remoteOpened[dir]))
+		c.streams[s.id] = s
+		c.acceptq = append(c.acceptq, s)
+	}
+	signal(c.acceptc)
+	return s, nil
+}
+
+func (c *Conn) handleStreamFrameLocked(id, off int64, data []byte, fin bool) error {
+	if streamIDInitiator(id) == c.side && streamIDDir(id) == uniStream {
+		return localTransportError{errStreamState, "STREAM frame for send-only stream"}
+	}
+	s, err := c.streamForFrameLocked(id)
+	if s == nil || err != nil {
+		return err
+	}
+	end := off + int64(len(data))
+	if s.inFinal >= 0 && (end > s.inFinal || (fin && end != s.inFinal)) {
+		return localTransportError{errFinalSize, "data beyond final size"}
+	}
+	if fin {
+		if end < s.inHighest {
+			return localTransportError{errFinalSize, "final size below data received"}
+		}
+		s.inFinal = end
+	}
+	if end > s.inMaxData {
+		return localTransportError{errFlowControl, "stream flow control exceeded"}
+	}
+	if end > s.inHighest {
+		c.connInRecv += end - s.inHighest
+		s.inHighest = end
+		if c.connInRecv > c.connInMax {
+			return localTransportError{errFlowControl, "connection flow control exceeded"}
+		}
+	}
+	if s.inReset || s.inClosed {
+		c.creditConnLocked(s.in.discardTo(s.inHighest))
+		c.maybeRemoveStreamLocked(s)
+		return nil
+	}
+	s.in.write(off, data)
+	s.signalIn()
+	return nil
+}
+
+func (c *Conn) handleResetStreamLocked(id int64, code uint64, finalSize int64) error {
+	if streamIDInitiator(id) == c.side && streamIDDir(id) == uniStream {
+		return localTransportError{errStreamState, "RESET_STREAM for send-only stream"}
+	}
+	s, err := c.streamForFrameLocked(id)
+	if s == nil || err != nil {
+		return err
+	}
+	if (s.inFinal >= 0 && finalSize != s.inFinal) || finalSize < s.inHighest {
+		return localTransportError{errFinalSize, "RESET_STREAM final size mismatch"}
+	}
+	if finalSize > s.inMaxData {
+		return localTransportError{errFlowControl, "stream flow control exceeded"}
+	}
+	c.connInRecv += finalSize - s.inHighest
+	s.inHighest = finalSize
+	s.inFinal = finalSize
+	if s.inReset {
+		return nil
+	}
+	s.inReset = true
+	s.inResetCode = code
+	s.needStopSending = false
+	c.creditConnLocked(s.in.discardTo(finalSize))
+	s.signalIn()
+	c.maybeRemoveStreamLocked(s)
+	return nil
+}
+
+func (c *Conn) handleStopSendingLocked(id int64, code uint64) error {
+	if streamIDInitiator(id) != c.side && streamIDDir(id) == uniStream {
+		return localTransportError{errStreamState, "STOP_SENDING for receive-only stream"}
+	}
+	s, err := c.streamForFrameLocked(id)
+	if s == nil || err != nil {
+		return err
+	}
+	if !s.outStopped {
+		s.outStopped = true
+		s.outStopCode = code
+	}
+	// RFC 9000, Section 3.5: respond with RESET_STREAM.
+	s.resetLocked(code)
+	return nil
+}
+
+func (c *Conn) handleMaxStreamDataLocked(id, v int64) error {
+	if streamIDInitiator(id) != c.side && streamIDDir(id) == uniStream {
+		return localTransportError{errStreamState, "MAX_STREAM_DATA for receive-only stream"}
+	}
+	s, err := c.streamForFrameLocked(id)
+	if s == nil || err != nil {
+		return err
+	}
+	if v > s.outMaxData {
+		s.outMaxData = v
+		c.wake()
+	}
+	return nil
+}
+
+// onStreamReadLocked updates flow control after the application
+// consumes n bytes from a stream.
+func (c *Conn) onStreamReadLocked(s *Stream, n int) {
+	if n == 0 {
+		return
+	}
+	c.creditConnLocked(int64(n))
+	if win := c.config.maxStreamReadBufferSize(); s.inFinal < 0 && s.inMaxData-s.in.readOff < win/2 {
+		s.inMaxData = s.in.readOff + win
+		s.needMaxStreamData = true
+		c.wake()
+	}
+}
+
+// creditConnLocked updates connection-level flow control after
+// n bytes of stream data are consumed or discarded.
+func (c *Conn) creditConnLocked(n int64) {
+	if n == 0 {
+		return
+	}
+	c.connInRead += n
+	if win := c.config.maxConnReadBufferSize(); c.connInMax-c.connInRead < win/2 {
+		c.connInMax = c.connInRead + win
+		c.needMaxData = true
+		c.wake()
+	}
+}
+
+// maybeRemoveStreamLocked forgets a stream once both of its sides are
+// finished, granting the peer credit for a new stream if it opened it.
+func (c *Conn) maybeRemoveStreamLocked(s *Stream) {
+	if s.removed || !s.recvFinished() || !s.outDone() || s.needResetStream {
+		return
+	}
+	s.removed = true
+	delete(c.streams, s.id)
+	if streamIDInitiator(s.id) != c.side {
+		dir := streamIDDir(s.id)
+		c.remoteClosed[dir]++
+		if win := c.config.maxBidiRemoteStreams(); dir == uniStream {
+			c.localMaxStreams[dir] = c.remoteClosed[dir] + c.config.maxUniRemoteStreams()
+		} else {
+			c.localMaxStreams[dir] = c.remoteClosed[dir] + win
+		}
+		c.needMaxStreams[dir] = true
+		c.wake()
+	}
+}
+
+// recvBuffer reassembles data received at arbitrary offsets.
+type recvBuffer struct {
+	readOff int64    // offset of the next byte to read
+	buf     []byte   // data starting at readOff
+	have    rangeset // received offsets at or after readOff
+}
+
+func (b *recvBuffer) write(off int64, data []byte) {
+	end := off + int64(len(data))
+	if end <= b.readOff {
+		return
+	}
+	if off < b.readOff {
+		data = data[b.readOff-off:]
+		off = b.readOff
+	}
+	if need := int(end - b.readOff); need > len(b.buf) {
+		b.buf = append(b.buf, make([]byte, need-len(b.buf))...)
+	}
+	copy(b.buf[off-b.readOff:], data)
+	b.have.add(off, end)
+}
+
+// readable returns the number of contiguous bytes available to read.
+func (b *recvBuffer) readable() int {
+	if len(b.have) == 0 || b.have[0].start > b.readOff {
+		return 0
+	}
+	return int(b.have[0].end - b.readOff)
+}
+
+func (b *recvBuffer) read(p []byte) int {
+	n := copy(p, b.buf[:b.readable()])
+	b.advance(int64(n))
+	return n
+}
+
+func (b *recvBuffer) advance(n int64) {
+	b.buf = b.buf[n:]
+	if len(b.buf) == 0 {
+		b.buf = nil
+	}
+	b.have.sub(b.readOff, b.readOff+n)
+	b.readOff += n
+}
+
+// discardTo drops all data before end, received or not,
+// returning the number of bytes dropped.
+func (b *recvBuffer) discardTo(end int64) int64 {
+	n := end - b.readOff
+	if n <= 0 {
+		return 0
+	}
+	*b = recvBuffer{readOff: end}
+	return n
+}
+
+// sendBuffer holds data written to a stream until the peer acknowledges it.
+type sendBuffer struct {
+	base    int64    // offset of buf[0]
+	buf     []byte   // data not yet acknowledged, starting at base
+	unsent  rangeset // offsets that need to be sent
+	ackedAt rangeset // acknowledged offsets at or after base
+	maxSent int64    // highest offset ever sent
+}
+
+func (b *sendBuffer) end() int64 { return b.base + int64(len(b.buf)) }
+
+func (b *sendBuffer) buffered() int64 { return int64(len(b.buf)) }
+
+func (b *sendBuffer) write(p []byte) {
+	start := b.end()
+	b.buf = append(b.buf, p...)
+	b.unsent.add(start, b.end())
+}
+
+// hasUnsent reports whether there is data to send.
+func (b *sendBuffer) hasUnsent() bool { return len(b.unsent) > 0 }
+
+// next returns the next data to send, beginning at off,
+// limited to offsets before limit and to at most n bytes.
+func (b *sendBuffer) next(limit int64, n int) (off int64, data []byte) {
+	if len(b.unsent) == 0 {
+		return b.end(), nil
+	}
+	r := b.unsent[0]
+	end := min(r.end, limit, r.start+int64(n))
+	if end <= r.start {
+		return r.start, nil
+	}
+	return r.start, b.buf[r.start-b.base : end-b.base]
+}
+
+func (b *sendBuffer) sent(off, n int64) {
+	b.unsent.sub(off, off+n)
+	b.maxSent = max(b.maxSent, off+n)
+}
+
+func (b *sendBuffer) lost(off, n int64) {
+	start := max(off, b.base)
+	if start >= off+n {
+		return
+	}
+	b.unsent.add(start, off+n)
+	for _, r := range b.ackedAt {
+		b.unsent.sub(r.start, r.end)
+	}
+}
+
+func (b *sendBuffer) acked(off, n int64) {
+	if off+n <= b.base {
+		return
+	}
+	b.unsent.sub(off, off+n)
+	b.ackedAt.add(max(off, b.base), off+n)
+	if len(b.ackedAt) > 0 && b.ackedAt[0].start == b.base {
+		drop := b.ackedAt[0].end - b.base
+		b.buf = b.buf[drop:]
+		b.base += drop
+		b.ackedAt = b.ackedAt[1:]
+	}
+}
+
+// allAcked reports whether all written data has been acknowledged.
+func (b *sendBuffer) allAcked() bool { return len(b.buf) == 0 }
+
+// discardUnsent stops sending any data not yet acknowledged.
+func (b *sendBuffer) discardUnsent() { b.unsent = nil }
@@ -0,0 +1,169 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// transportParameters are QUIC transport parameters (RFC 9000, Section 18).
+type transportParameters struct {
+	originalDstConnID              []byte
+	maxIdleTimeout                 time.Duration
+	maxUDPPayloadSize              int64
+	initialMaxData                 int64
+	initialMaxStreamDataBidiLocal  int64
+	initialMaxStreamDataBidiRemote int64
+	initialMaxStreamDataUni        int64
+	initialMaxStreamsBidi          int64
+	initialMaxStreamsUni           int64
+	ackDelayExponent               int8
+	maxAckDelay                    time.Duration
+	activeConnIDLimit              int64
+	initialSrcConnID               []byte
+	retrySrcConnID                 []byte
+}
+
+const (
+	paramOriginalDestinationConnectionID = 0x00
+	paramMaxIdleTimeout                  = 0x01
+	paramStatelessResetToken             = 0x02
+	paramMaxUDPPayloadSize               = 0x03
+	paramInitialMaxData                  = 0x04
+	paramInitialMaxStreamDataBidiLocal   = 0x05
+	paramInitialMaxStreamDataBidiRemote  = 0x06
+	paramInitialMaxStreamDataUni         = 0x07
+	paramInitialMaxStreamsBidi           = 0x08
+	paramInitialMaxStreamsUni            = 0x09
+	paramAckDelayExponent                = 0x0a
+	paramMaxAckDelay                     = 0x0b
+	paramDisableActiveMigration          = 0x0c
+	paramPreferredAddress                = 0x0d
+	paramActiveConnectionIDLimit         = 0x0e
+	paramInitialSourceConnectionID       = 0x0f
+	paramRetrySourceConnectionID         = 0x10
+)
+
+// defaultTransportParameters returns the default values of
+// the transport parameters, used for parameters a peer omits.
+func defaultTransportParameters() transportParameters {
+	return transportParameters{
+		maxUDPPayloadSize: 65527,
+		ackDelayExponent:  3,
+		maxAckDelay:       25 * time.Millisecond,
+		activeConnIDLimit: 2,
+	}
+}
+
+func appendIntParam(b []byte, id, v uint64) []byte {
+	b = appendVarint(b, id)
+	b = appendVarint(b, uint64(sizeVarint(v)))
+	return appendVarint(b, v)
+}
+
+func appendBytesParam(b []byte, id uint64, v []byte) []byte {
+	b = appendVarint(b, id)
+	return appendVarintBytes(b, v)
+}
+
+func marshalTransportParameters(p transportParameters) []byte {
+	var b []byte
+	if p.originalDstConnID != nil {
+		b = appendBytesParam(b, paramOriginalDestinationConnectionID, p.originalDstConnID)
+	}
+	if p.maxIdleTimeout > 0 {
+		b = appendIntParam(b, paramMaxIdleTimeout, uint64(p.maxIdleTimeout.Milliseconds()))
+	}
+	b = appendIntParam(b, paramMaxUDPPayloadSize, uint64(p.maxUDPPayloadSize))
+	b = appendIntParam(b, paramInitialMaxData, uint64(p.initialMaxData))
+	b = appendIntParam(b, paramInitialMaxStreamDataBidiLocal, uint64(p.initialMaxStreamDataBidiLocal))
+	b = appendIntParam(b, paramInitialMaxStreamDataBidiRemote, uint64(p.initialMaxStreamDataBidiRemote))
+	b = appendIntParam(b, paramInitialMaxStreamDataUni, uint64(p.initialMaxStreamDataUni))
+	b = appendIntParam(b, paramInitialMaxStreamsBidi, uint64(p.initialMaxStreamsBidi))
+	b = appendIntParam(b, paramInitialMaxStreamsUni, uint64(p.initialMaxStreamsUni))
+	b = appendIntParam(b, paramActiveConnectionIDLimit, uint64(p.activeConnIDLimit))
+	b = appendBytesParam(b, paramInitialSourceConnectionID, p.initialSrcConnID)
+	return b
+}
+
+func unmarshalTransportParameters(b []byte) (transportParameters, error) {
+	p := defaultTransportParameters()
+	seen := make(map[uint64]bool)
+	for len(b) > 0 {
+		id, n := consumeVarint(b)
+		if n < 0 {
+			return p, localTransportError{errTransportParam, "malformed transport parameters"}
+		}
+		b = b[n:]
+		val, n := consumeVarintBytes(b)
+		if n < 0 {
+			return p, localTransportError{errTransportParam, "malformed transport parameters"}
+		}
+		b = b[n:]
+		if seen[id] {
+			return p, localTransportError{errTransportParam, "duplicate transport parameter"}
+		}
+		seen[id] = true
+
+		intVal := func() (int64, error) {
+			v, n := consumeVarintInt64(val)
+			if n != len(val) {
+				return 0, localTransportError{errTransportParam, "malformed transport parameter"}
+			}
+			return v, nil
+		}
+		var err error
+		switch id {
+		case paramOriginalDestinationConnectionID:
+			p.originalDstConnID = val
+		case paramMaxIdleTimeout:
+			var v int64
+			v, err = intVal()
+			p.maxIdleTimeout = time.Duration(v) * time.Millisecond
+		case paramMaxUDPPayloadSize:
+			p.maxUDPPayloadSize, err = intVal()
+			if err == nil && p.maxUDPPayloadSize < 1200 {
+				err = localTransportError{errTransportParam, "invalid max_udp_payload_size"}
+			}
+		case paramInitialMaxData:
+			p.initialMaxData, err = intVal()
+		case paramInitialMaxStreamDataBidiLocal:
+			p.initialMaxStreamDataBidiLocal, err = intVal()
+		case paramInitialMaxStreamDataBidiRemote:
+			p.initialMaxStreamDataBidiRemote, err = intVal()
+		case paramInitialMaxStreamDataUni:
+			p.initialMaxStreamDataUni, err = intVal()
+		case paramInitialMaxStreamsBidi:
+			p.initialMaxStreamsBidi, err = intVal()
+		case paramInitialMaxStreamsUni:
+			p.initialMaxStreamsUni, err = intVal()
+		case paramAckDelayExponent:
+			var v int64
+			v, err = intVal()
+			if err == nil && v > 20 {
+				err = localTransportError{errTransportParam, "invalid ack_delay_exponent"}
+			}
+			p.ackDelayExponent = int8(v)
+		case paramMaxAckDelay:
+			var v int64
+			v, err = intVal()
+			if err == nil && v >= 1<<14 {
+				err = localTransportError{errTransportParam, "invalid max_ack_delay"}
+			}
+			p.maxAckDelay = time.Duration(v) * time.Millisecond
+		case paramActiveConnectionIDLimit:
+			p.activeConnIDLimit, err = intVal()
+		case paramInitialSourceConnectionID:
+			p.initialSrcConnID = val
+		case paramRetrySourceConnectionID:
+			p.retrySrcConnID = val
+		default:
+			// Ignore stateless_reset_token, disable_active_migration,
+			// preferred_address, and unknown parameters.
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
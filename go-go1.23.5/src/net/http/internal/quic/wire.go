@@ -0,0 +1,182 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// maxVarint is the largest value representable as a variable-length integer.
+const maxVarint = 1<<62 - 1
+
+// consumeVarint parses a variable-length integer (RFC 9000, Section 16)
+// at the start of b, returning the value and its encoded length.
+// It returns a negative length if b does not start with a complete integer.
+func consumeVarint(b []byte) (v uint64, n int) {
+	if len(b) < 1 {
+		return 0, -1
+	}
+	n = 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, -1
+	}
+	v = uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+// consumeVarintInt64 is consumeVarint returning an int64.
+func consumeVarintInt64(b []byte) (v int64, n int) {
+	u, n := consumeVarint(b)
+	return int64(u), n
+}
+
+// appendVarint appends v to b as a variable-length integer.
+// It panics if v is larger than maxVarint.
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case v <= maxVarint:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	panic("quic: varint too large")
+}
+
+// sizeVarint returns the encoded length of v.
+func sizeVarint(v uint64) int {
+	switch {
+	case v < 1<<6:
+		return 1
+	case v < 1<<14:
+		return 2
+	case v < 1<<30:
+		return 4
+	}
+	return 8
+}
+
+// consumeUint8Bytes parses a sequence of bytes prefixed with
+// an 8-bit length.
+func consumeUint8Bytes(b []byte) ([]byte, int) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, -1
+	}
+	return b[1 : 1+int(b[0])], 1 + int(b[0])
+}
+
+// consumeVarintBytes parses a sequence of bytes prefixed with
+// a variable-length integer length.
+func consumeVarintBytes(b []byte) ([]byte, int) {
+	size, n := consumeVarint(b)
+	if n < 0 || size > uint64(len(b)-n) {
+		return nil, -1
+	}
+	return b[n : n+int(size)], n + int(size)
+}
+
+// appendVarintBytes appends v prefixed with its length as a variable-length integer.
+func appendVarintBytes(b, v []byte) []byte {
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// An i64range is the half-open range [start, end).
+type i64range struct {
+	start, end int64
+}
+
+func (r i64range) size() int64 { return r.end - r.start }
+
+// A rangeset is a set of int64s, stored as an ordered list of
+// non-overlapping, non-adjacent ranges.
+type rangeset []i64range
+
+// add adds [start, end) to the set.
+func (s *rangeset) add(start, end int64) {
+	if start >= end {
+		return
+	}
+	rs := *s
+	// Find the first range ending at or after start.
+	i := 0
+	for i < len(rs) && rs[i].end < start {
+		i++
+	}
+	if i == len(rs) || rs[i].start > end {
+		// No overlap: insert a new range.
+		rs = append(rs, i64range{})
+		copy(rs[i+1:], rs[i:])
+		rs[i] = i64range{start, end}
+		*s = rs
+		return
+	}
+	// Merge with every range overlapping or adjacent to [start, end).
+	j := i
+	for j+1 < len(rs) && rs[j+1].start <= end {
+		j++
+	}
+	rs[i].start = min(rs[i].start, start)
+	rs[i].end = max(rs[j].end, end)
+	*s = append(rs[:i+1], rs[j+1:]...)
+}
+
+// sub removes [start, end) from the set.
+func (s *rangeset) sub(start, end int64) {
+	if start >= end {
+		return
+	}
+	var out rangeset
+	for _, r := range *s {
+		if r.end <= start || r.start >= end {
+			out = append(out, r)
+			continue
+		}
+		if r.start < start {
+			out = append(out, i64range{r.start, start})
+		}
+		if r.end > end {
+			out = append(out, i64range{end, r.end})
+		}
+	}
+	*s = out
+}
+
+// contains reports whether v is in the set.
+func (s rangeset) contains(v int64) bool {
+	for _, r := range s {
+		if v < r.start {
+			return false
+		}
+		if v < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// isrange reports whether the set contains exactly [start, end).
+func (s rangeset) isrange(start, end int64) bool {
+	return len(s) == 1 && s[0].start == start && s[0].end == end
+}
+
+// min returns the smallest value in the set, or 0 if it is empty.
+func (s rangeset) min() int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[0].start
+}
+
+// end returns one past the largest value in the set, or 0 if it is empty.
+func (s rangeset) end() int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].end
+}
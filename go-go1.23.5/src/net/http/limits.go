@@ -0,0 +1,280 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"container/list"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A RateLimit is a per-client token bucket rate limit,
+// used as [Server.RateLimit].
+//
+// Each client has a bucket holding up to Burst tokens, which refills
+// at Rate tokens per second. Each request takes a token from its
+// client's bucket; a request arriving when the bucket is empty is
+// rejected.
+type RateLimit struct {
+	// Rate is the number of requests per second allowed from each
+	// client over the long term. A RateLimit with a Rate of zero or
+	// less has no effect.
+	Rate float64
+
+	// Burst is the number of requests a client may make at once.
+	// If Burst is less than one, one is used.
+	Burst int
+
+	// Key, if non-nil, returns the key identifying the client
+	// which sent a request. Requests with the same key share a
+	// bucket. If Key is nil, the IP address of the request's
+	// RemoteAddr is used; IPv6 addresses in the same /64 prefix,
+	// which is usually assigned to a single host or site, share
+	// a bucket.
+	Key func(*Request) string
+
+	// MaxClients limits the number of clients whose buckets are
+	// kept. When a request arrives from a new client and there are
+	// already MaxClients buckets, the bucket of the least recently
+	// seen client is discarded. If zero, 100000 is used.
+	MaxClients int
+}
+
+func (rl *RateLimit) burst() float64 {
+	if rl.Burst < 1 {
+		return 1
+	}
+	return float64(rl.Burst)
+}
+
+func (rl *RateLimit) maxClients() int {
+	if rl.MaxClients > 0 {
+		return rl.MaxClients
+	}
+	return 100000
+}
+
+func (rl *RateLimit) key(r *Request) string {
+	if rl.Key != nil {
+		return rl.Key(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.Is6() && !ip.Is4In6() {
+		// A single host can easily use many addresses in its prefix.
+		p, _ := ip.WithZone("").Prefix(64)
+		return p.String()
+	}
+	return host
+}
+
+// ServerStats reports the state of a [Server]'s admission limits.
+// It is returned by [Server.Stats].
+//
+// Its fields are suitable for publishing with the expvar package:
+//
+//	expvar.Publish("http", expvar.Func(func() any { return srv.Stats() }))
+type ServerStats struct {
+	// OpenConns is the number of connections accepted by Serve
+	// which have not yet been closed or hijacked.
+	OpenConns int64
+
+	// InFlightRequests is the number of handlers currently running.
+	InFlightRequests int64
+
+	// RejectedConns is the number of connections refused
+	// because of Server.MaxConns.
+	RejectedConns int64
+
+	// RejectedRequests is the number of requests refused
+	// because of Server.MaxInFlightRequests.
+	RejectedRequests int64
+
+	// RateLimitedRequests is the number of requests refused
+	// because of Server.RateLimit.
+	RateLimitedRequests int64
+}
+
+// Stats returns the current state of the server's admission limits.
+// The counters are maintained whether or not limits are set.
+func (srv *Server) Stats() ServerStats {
+	l := &srv.limits
+	return ServerStats{
+		OpenConns:           l.conns.Load(),
+		InFlightRequests:    l.inFlight.Load(),
+		RejectedConns:       l.rejectedConns.Load(),
+		RejectedRequests:    l.rejectedRequests.Load(),
+		RateLimitedRequests: l.rateLimited.Load(),
+	}
+}
+
+// serverLimits is the state of a Server's admission limits.
+type serverLimits struct {
+	conns            atomic.Int64
+	inFlight         atomic.Int64
+	rejectedConns    atomic.Int64
+	rejectedRequests atomic.Int64
+	rateLimited      atomic.Int64
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element // of *tokenBucket, in lru
+	lru       list.List                // most recently used at the front
+	lastPrune time.Time
+}
+
+// overloadRetryAfter is the Retry-After value sent with
+// responses rejected because of MaxConns or MaxInFlightRequests.
+const overloadRetryAfter = "1"
+
+// rejectConnTimeout bounds the time spent sending a response
+// to a connection rejected because of MaxConns.
+var rejectConnTimeout = 5 * time.Second
+
+// bucketPruneInterval is how often idle token buckets are discarded.
+const bucketPruneInterval = time.Minute
+
+// acquireConn counts a newly accepted connection,
+// reporting false if MaxConns has been reached.
+func (srv *Server) acquireConn() bool {
+	l := &srv.limits
+	max := int64(srv.MaxConns)
+	for {
+		n := l.conns.Load()
+		if max > 0 && n >= max {
+			return false
+		}
+		if l.conns.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// releaseConn stops counting a connection which has been closed or hijacked.
+func (srv *Server) releaseConn() {
+	srv.limits.conns.Add(-1)
+}
+
+// rejectConn responds to and closes a connection refused because of MaxConns.
+func (srv *Server) rejectConn(rw net.Conn) {
+	srv.limits.rejectedConns.Add(1)
+	if hook := srv.ConnState; hook != nil {
+		hook(rw, StateRejected)
+	}
+	go func() {
+		defer rw.Close()
+		rw.SetDeadline(time.Now().Add(rejectConnTimeout))
+		_, err := io.WriteString(rw, "HTTP/1.1 503 Service Unavailable\r\n"+
+			"Retry-After: "+overloadRetryAfter+"\r\n"+
+			"Content-Type: text/plain; charset=utf-8\r\n"+
+			"Connection: close\r\n"+
+			"\r\n"+
+			"503 Service Unavailable: too many connections")
+		if err != nil {
+			return
+		}
+		// As in conn.closeWriteAndWait, give the client a chance
+		// to read the response before the socket is closed.
+		if tcp, ok := rw.(closeWriter); ok {
+			tcp.CloseWrite()
+			time.Sleep(rstAvoidanceDelay)
+		}
+	}()
+}
+
+// admitRequest applies the RateLimit and MaxInFlightRequests limits
+// to a request. If the request is admitted, it is counted as in
+// flight and admitRequest returns true. Otherwise, admitRequest
+// replies to the request and returns false.
+func (srv *Server) admitRequest(w ResponseWriter, r *Request) bool {
+	l := &srv.limits
+	if rl := srv.RateLimit; rl != nil && rl.Rate > 0 {
+		if wait := l.takeToken(rl, rl.key(r), time.Now()); wait > 0 {
+			l.rateLimited.Add(1)
+			secs := int64(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+			Error(w, "429 Too Many Requests", StatusTooManyRequests)
+			return false
+		}
+	}
+	n := l.inFlight.Add(1)
+	if max := srv.MaxInFlightRequests; max > 0 && n > int64(max) {
+		l.inFlight.Add(-1)
+		l.rejectedRequests.Add(1)
+		w.Header().Set("Retry-After", overloadRetryAfter)
+		Error(w, "503 Service Unavailable: too many requests", StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// A tokenBucket is the rate limit state of a single client.
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// fill adds the tokens accumulated since the last update.
+func (b *tokenBucket) fill(rl *RateLimit, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(rl.burst(), b.tokens+elapsed.Seconds()*rl.Rate)
+	}
+	b.last = now
+}
+
+// takeToken takes a token from the bucket for key. If the bucket is
+// empty, it returns the time until a token will be available.
+func (l *serverLimits) takeToken(rl *RateLimit, key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*list.Element)
+		l.lastPrune = now
+	}
+	if now.Sub(l.lastPrune) >= bucketPruneInterval {
+		// Discard full buckets: they are equivalent to new ones.
+		// The least recently used buckets are the most likely to be
+		// full, so stop at the first one which is not.
+		for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+			b := e.Value.(*tokenBucket)
+			b.fill(rl, now)
+			if b.tokens < rl.burst() {
+				break
+			}
+			l.removeBucket(e)
+		}
+		l.lastPrune = now
+	}
+	var b *tokenBucket
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+	} else {
+		for l.lru.Len() >= rl.maxClients() {
+			l.removeBucket(l.lru.Back())
+		}
+		b = &tokenBucket{key: key, tokens: rl.burst(), last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+	b.fill(rl, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rl.Rate * float64(time.Second))
+}
+
+func (l *serverLimits) removeBucket(e *list.Element) {
+	delete(l.buckets, e.Value.(*tokenBucket).key)
+	l.lru.Remove(e)
+}
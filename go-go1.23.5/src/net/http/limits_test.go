@@ -0,0 +1,255 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServerMaxConns(t *testing.T) { run(t, testServerMaxConns, []testMode{http1Mode}) }
+func testServerMaxConns(t *testing.T, mode testMode) {
+	var (
+		mu       sync.Mutex
+		rejected []net.Conn
+	)
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {}),
+		func(ts *httptest.Server) {
+			ts.Config.MaxConns = 1
+			ts.Config.ConnState = func(c net.Conn, state ConnState) {
+				if state == StateRejected {
+					mu.Lock()
+					rejected = append(rejected, c)
+					mu.Unlock()
+				}
+			}
+		})
+	srv := cst.ts.Config
+
+	c1, err := net.Dial("tcp", cst.ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	c2, err := net.Dial("tcp", cst.ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	res, err := ReadResponse(bufio.NewReader(c2), nil)
+	if err != nil {
+		t.Fatalf("reading response from connection over limit: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusServiceUnavailable {
+		t.Errorf("status = %v, want %v", res.StatusCode, StatusServiceUnavailable)
+	}
+	if got := res.Header.Get("Retry-After"); got == "" {
+		t.Errorf("missing Retry-After header")
+	}
+	mu.Lock()
+	if len(rejected) != 1 {
+		t.Errorf("ConnState hook saw %v rejected connections, want 1", len(rejected))
+	}
+	mu.Unlock()
+	if got := srv.Stats(); got.OpenConns != 1 || got.RejectedConns != 1 {
+		t.Errorf("Stats() = %+v, want OpenConns=1 RejectedConns=1", got)
+	}
+
+	// Once the first connection is closed, new connections are accepted.
+	c1.Close()
+	waitCondition(t, 10*time.Millisecond, func(d time.Duration) bool {
+		if n := srv.Stats().OpenConns; n != 0 {
+			if d > 0 {
+				t.Logf("%v open connections, waiting for 0", n)
+			}
+			return false
+		}
+		return true
+	})
+	res, err = cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusOK {
+		t.Errorf("after closing connection: status = %v, want %v", res.StatusCode, StatusOK)
+	}
+}
+
+func TestServerMaxInFlightRequests(t *testing.T) { run(t, testServerMaxInFlightRequests) }
+func testServerMaxInFlightRequests(t *testing.T, mode testMode) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/block" {
+			started <- struct{}{}
+			<-unblock
+		}
+	}), func(ts *httptest.Server) {
+		ts.Config.MaxInFlightRequests = 1
+	})
+	srv := cst.ts.Config
+
+	errc := make(chan error, 1)
+	go func() {
+		res, err := cst.c.Get(cst.ts.URL + "/block")
+		if err == nil {
+			res.Body.Close()
+		}
+		errc <- err
+	}()
+	<-started
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusServiceUnavailable {
+		t.Errorf("status = %v, want %v", res.StatusCode, StatusServiceUnavailable)
+	}
+	if got, want := res.Header.Get("Retry-After"), "1"; got != want {
+		t.Errorf("Retry-After = %q, want %q", got, want)
+	}
+	if got := srv.Stats(); got.InFlightRequests != 1 || got.RejectedRequests != 1 {
+		t.Errorf("Stats() = %+v, want InFlightRequests=1 RejectedRequests=1", got)
+	}
+
+	close(unblock)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	res, err = cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusOK {
+		t.Errorf("after first request completed: status = %v, want %v", res.StatusCode, StatusOK)
+	}
+	waitCondition(t, 10*time.Millisecond, func(d time.Duration) bool {
+		if n := srv.Stats().InFlightRequests; n != 0 {
+			if d > 0 {
+				t.Logf("%v requests in flight, waiting for 0", n)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+func TestServerRateLimit(t *testing.T) { run(t, testServerRateLimit) }
+func testServerRateLimit(t *testing.T, mode testMode) {
+	var called atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		called.Add(1)
+	}), func(ts *httptest.Server) {
+		ts.Config.RateLimit = &RateLimit{
+			Rate:  0.25,
+			Burst: 2,
+			Key: func(r *Request) string {
+				return r.Header.Get("Client")
+			},
+		}
+	})
+
+	get := func(client string) *Response {
+		t.Helper()
+		req, _ := NewRequest("GET", cst.ts.URL, nil)
+		req.Header.Set("Client", client)
+		res, err := cst.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+	for i := 0; i < 2; i++ {
+		if res := get("a"); res.StatusCode != StatusOK {
+			t.Fatalf("request %v within burst: status = %v, want %v", i, res.StatusCode, StatusOK)
+		}
+	}
+	res := get("a")
+	if res.StatusCode != StatusTooManyRequests {
+		t.Errorf("request over limit: status = %v, want %v", res.StatusCode, StatusTooManyRequests)
+	}
+	// The next token is available in 1/Rate seconds.
+	if got, want := res.Header.Get("Retry-After"), "4"; got != want {
+		t.Errorf("Retry-After = %q, want %q", got, want)
+	}
+	if res := get("b"); res.StatusCode != StatusOK {
+		t.Errorf("request from another client: status = %v, want %v", res.StatusCode, StatusOK)
+	}
+	if n := called.Load(); n != 3 {
+		t.Errorf("handler called %v times, want 3", n)
+	}
+	if got := cst.ts.Config.Stats().RateLimitedRequests; got != 1 {
+		t.Errorf("Stats().RateLimitedRequests = %v, want 1", got)
+	}
+}
+
+func TestServerRateLimitMaxClients(t *testing.T) {
+	run(t, testServerRateLimitMaxClients, []testMode{http1Mode})
+}
+func testServerRateLimitMaxClients(t *testing.T, mode testMode) {
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {}),
+		func(ts *httptest.Server) {
+			ts.Config.RateLimit = &RateLimit{
+				Rate:       0.25,
+				Burst:      1,
+				MaxClients: 3,
+				Key: func(r *Request) string {
+					return r.Header.Get("Client")
+				},
+			}
+		})
+	get := func(client string) int {
+		t.Helper()
+		req, _ := NewRequest("GET", cst.ts.URL, nil)
+		req.Header.Set("Client", client)
+		res, err := cst.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	for _, client := range []string{"a", "b", "c", "d", "e", "f"} {
+		get(client)
+		if n := cst.ts.Config.ExportRateLimitBuckets(); n > 3 {
+			t.Fatalf("after request from %q: %d buckets, want at most 3", client, n)
+		}
+	}
+	// The most recent clients are still limited.
+	if code := get("f"); code != StatusTooManyRequests {
+		t.Errorf("repeated request from recent client: status = %v, want %v", code, StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitDefaultKey(t *testing.T) {
+	rl := &RateLimit{Rate: 1}
+	for _, test := range []struct {
+		remoteAddr, want string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1"},
+		{"[2001:db8:1:2:3:4:5:6]:1234", "2001:db8:1:2::/64"},
+		{"[2001:db8:1:2:ffff::1]:80", "2001:db8:1:2::/64"},
+		{"[::ffff:192.0.2.1]:1234", "::ffff:192.0.2.1"},
+		{"unix", "unix"},
+	} {
+		r := &Request{RemoteAddr: test.remoteAddr}
+		if got := ExportRateLimitKey(rl, r); got != test.want {
+			t.Errorf("key for RemoteAddr %q = %q, want %q", test.remoteAddr, got, test.want)
+		}
+	}
+}
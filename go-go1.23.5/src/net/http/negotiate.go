@@ -0,0 +1,347 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Proactive content negotiation. See RFC 9110 Section 12.
+
+package http
+
+import (
+	"cmp"
+	"mime"
+	"net/http/internal/ascii"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// An AcceptRange is an element of an Accept, Accept-Charset,
+// Accept-Encoding or Accept-Language header field: a media range,
+// charset, content coding or language range, with its quality value.
+type AcceptRange struct {
+	// Value is the range, in lower case.
+	// For example, "text/html", "text/*", "gzip", "en-us" or "*".
+	Value string
+
+	// Params holds the parameters of a media range in an Accept
+	// field, such as "level" in "text/html;level=1", keyed by their
+	// names in lower case. The weight and any extension parameters
+	// following it are not included.
+	Params map[string]string
+
+	// Q is the quality value ("weight"), from 0 to 1.
+	// A range with Q zero is not acceptable.
+	Q float64
+}
+
+// ParseAccept parses the header field name in h, which should be one of
+// Accept, Accept-Charset, Accept-Encoding or Accept-Language, as
+// described in RFC 9110 Section 12.5. It returns the field's elements
+// in decreasing order of quality value; elements of equal quality are
+// in the order in which they appear. Malformed elements are omitted.
+func ParseAccept(h Header, name string) []AcceptRange {
+	name = CanonicalHeaderKey(name)
+	var ranges []AcceptRange
+	for _, v := range h[name] {
+		foreachHeaderElement(v, func(elem string) {
+			if r, ok := parseAcceptRange(elem, name == "Accept"); ok {
+				ranges = append(ranges, r)
+			}
+		})
+	}
+	slices.SortStableFunc(ranges, func(a, b AcceptRange) int {
+		return cmp.Compare(b.Q, a.Q)
+	})
+	return ranges
+}
+
+// parseAcceptRange parses one element of an Accept* field.
+// If mediaRange is set, the element is a media range with parameters.
+func parseAcceptRange(elem string, mediaRange bool) (AcceptRange, bool) {
+	r := AcceptRange{Q: 1}
+	value := elem
+	// The weight separates media type parameters from extension
+	// parameters, which are ignored.
+	for i := strings.IndexByte(elem, ';'); i >= 0; {
+		param := elem[i+1:]
+		next := strings.IndexByte(param, ';')
+		if next >= 0 {
+			param = param[:next]
+		}
+		name, v, _ := strings.Cut(param, "=")
+		if ascii.EqualFold(textproto.TrimString(name), "q") {
+			q, ok := parseQValue(textproto.TrimString(v))
+			if !ok {
+				return r, false
+			}
+			r.Q = q
+			value = elem[:i]
+			break
+		}
+		if next < 0 {
+			break
+		}
+		i += 1 + next
+	}
+
+	if mediaRange {
+		mt, params, err := mime.ParseMediaType(value)
+		if err != nil {
+			return r, false
+		}
+		typ, sub, ok := strings.Cut(mt, "/")
+		if !ok || typ == "*" && sub != "*" {
+			return r, false
+		}
+		r.Value = mt
+		if len(params) > 0 {
+			r.Params = params
+		}
+		return r, true
+	}
+
+	value = textproto.TrimString(value)
+	if !httpguts.ValidHeaderFieldName(value) {
+		return r, false
+	}
+	r.Value, _ = ascii.ToLower(value)
+	return r, true
+}
+
+// parseQValue parses a qvalue, which is a number from 0 to 1
+// with at most three digits after the decimal point.
+func parseQValue(s string) (float64, bool) {
+	if len(s) == 0 || len(s) > 5 || s[0] != '0' && s[0] != '1' {
+		return 0, false
+	}
+	if len(s) > 1 {
+		if s[1] != '.' {
+			return 0, false
+		}
+		for _, c := range []byte(s[2:]) {
+			if c < '0' || c > '9' || s[0] == '1' && c != '0' {
+				return 0, false
+			}
+		}
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	return q, err == nil
+}
+
+// negotiate returns the index of the most acceptable of n offers,
+// or -1 if none is acceptable.
+//
+// The quality of an offer is that of the range which matches it
+// most specifically. match reports how specifically range r matches
+// offer i, or -1 if it does not match it. Ties between offers of
+// equal quality go to the more specifically matched offer, and then
+// to the earlier offer.
+func negotiate(n int, ranges []AcceptRange, match func(i int, r *AcceptRange) int) int {
+	best, bestSpec, bestQ := -1, -1, 0.0
+	for i := 0; i < n; i++ {
+		spec, q := -1, 0.0
+		for j := range ranges {
+			// Ranges are in decreasing order of quality, so the first
+			// of several equally specific ranges has the highest.
+			if s := match(i, &ranges[j]); s > spec {
+				spec, q = s, ranges[j].Q
+			}
+		}
+		if q > bestQ || q == bestQ && q > 0 && spec > bestSpec {
+			best, bestSpec, bestQ = i, spec, q
+		}
+	}
+	return best
+}
+
+// NegotiateContentType returns the most acceptable of the offered media
+// types for a response to a request with header h, as indicated by its
+// Accept field (RFC 9110 Section 12.5.1).
+//
+// An offer may have parameters, as in "text/html; charset=utf-8".
+// A media range with parameters matches only offers which have the
+// same values for those parameters. The most specific media range
+// matching an offer determines its quality. Ties between equally
+// acceptable offers go to the more specifically matched offer, and
+// then to the earlier offer, so offers should be in the server's
+// order of preference.
+//
+// If h has no Accept field, or it has no valid elements, all media
+// types are acceptable and NegotiateContentType returns offers[0].
+// If no offer is acceptable, it returns "".
+func NegotiateContentType(h Header, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	ranges := ParseAccept(h, "Accept")
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+	type mediaType struct {
+		typ, sub string
+		params   map[string]string
+	}
+	types := make([]mediaType, len(offers))
+	for i, offer := range offers {
+		mt, params, err := mime.ParseMediaType(offer)
+		if err != nil {
+			continue
+		}
+		typ, sub, _ := strings.Cut(mt, "/")
+		types[i] = mediaType{typ, sub, params}
+	}
+	i := negotiate(len(offers), ranges, func(i int, r *AcceptRange) int {
+		t := &types[i]
+		if t.typ == "" {
+			return -1
+		}
+		if r.Value == "*/*" {
+			return 0
+		}
+		typ, sub, _ := strings.Cut(r.Value, "/")
+		if typ != t.typ {
+			return -1
+		}
+		if sub == "*" {
+			return 1
+		}
+		if sub != t.sub {
+			return -1
+		}
+		for k, v := range r.Params {
+			if !ascii.EqualFold(t.params[k], v) {
+				return -1
+			}
+		}
+		return 2 + len(r.Params)
+	})
+	if i < 0 {
+		return ""
+	}
+	return offers[i]
+}
+
+// NegotiateLanguage returns the most acceptable of the offered language
+// tags, such as "en-GB" or "fr", for a response to a request with header
+// h, as indicated by its Accept-Language field (RFC 9110 Section 12.5.4).
+//
+// A language range matches the tags which it is equal to or a prefix of,
+// ignoring case, so that "en" matches "en-GB" (RFC 4647 Section 3.3.1).
+// A range also matches the tags to which it may be truncated, so that
+// a client accepting "en-US" is offered "en" rather than nothing
+// (RFC 4647 Section 3.4). The range "*" matches any tag. The longest
+// range or tag matched determines the specificity of a match, and the
+// most specific range matching an offer determines its quality. Ties
+// between equally acceptable offers go to the more specifically matched
+// offer, and then to the earlier offer, so offers should be in the
+// server's order of preference.
+//
+// If h has no Accept-Language field, or it has no valid elements, all
+// languages are acceptable and NegotiateLanguage returns offers[0].
+// If no offer is acceptable, it returns "".
+func NegotiateLanguage(h Header, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	ranges := ParseAccept(h, "Accept-Language")
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+	tags := make([]string, len(offers))
+	for i, offer := range offers {
+		tags[i], _ = ascii.ToLower(offer)
+	}
+	i := negotiate(len(offers), ranges, func(i int, r *AcceptRange) int {
+		tag := tags[i]
+		switch {
+		case tag == "":
+			return -1
+		case r.Value == "*":
+			return 0
+		case languagePrefix(r.Value, tag):
+			return 2*len(r.Value) + 1
+		case languagePrefix(tag, r.Value):
+			// A truncation is less specific than a range of the
+			// same length matching directly.
+			return 2 * len(tag)
+		}
+		return -1
+	})
+	if i < 0 {
+		return ""
+	}
+	return offers[i]
+}
+
+// languagePrefix reports whether the lower-case language range or tag
+// prefix is equal to tag or a prefix of it ending at a subtag boundary.
+func languagePrefix(prefix, tag string) bool {
+	rest, ok := strings.CutPrefix(tag, prefix)
+	return ok && (rest == "" || rest[0] == '-')
+}
+
+// NegotiateEncoding returns the most acceptable of the offered content
+// codings, such as "zstd", "gzip" or "identity", for a response to a
+// request with header h, as indicated by its Accept-Encoding field
+// (RFC 9110 Section 12.5.3). Ties between equally acceptable codings
+// go to one named by the field over one matched by "*", and then to the
+// earlier offer, so offers should be in the server's order of
+// preference. The codings "x-gzip" and "x-compress" are treated as equivalent to
+// "gzip" and "compress".
+//
+// The identity coding, meaning no coding, is acceptable unless the
+// field excludes it with a weight of zero, either by name or with "*".
+// When the field does not name it, it is chosen only if no other offer
+// is acceptable. In particular, if h has no Accept-Encoding field, it
+// is the only acceptable coding: although RFC 9110 permits any coding
+// in that case, clients which omit the field often cannot decode them.
+// If no offer is acceptable, NegotiateEncoding returns "".
+func NegotiateEncoding(h Header, offers []string) string {
+	ranges := ParseAccept(h, "Accept-Encoding")
+	codings := make([]string, len(offers))
+	for i, offer := range offers {
+		codings[i] = canonicalCoding(offer)
+	}
+	match := func(i int, r *AcceptRange) int {
+		switch {
+		case r.Value == "*":
+			return 0
+		case canonicalCoding(r.Value) == codings[i]:
+			return 1
+		}
+		return -1
+	}
+	if i := negotiate(len(offers), ranges, match); i >= 0 {
+		return offers[i]
+	}
+	for i, coding := range codings {
+		if coding != "identity" {
+			continue
+		}
+		for j := range ranges {
+			if match(i, &ranges[j]) >= 0 {
+				// Identity was named or matched by "*", and
+				// was not acceptable.
+				return ""
+			}
+		}
+		return offers[i]
+	}
+	return ""
+}
+
+// canonicalCoding returns the lower-case name of a content coding,
+// with aliases replaced by the names they are equivalent to.
+func canonicalCoding(coding string) string {
+	coding, _ = ascii.ToLower(textproto.TrimString(coding))
+	switch coding {
+	case "x-gzip":
+		return "gzip"
+	case "x-compress":
+		return "compress"
+	}
+	return coding
+}
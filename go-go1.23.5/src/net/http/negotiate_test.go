@@ -0,0 +1,168 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	. "net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		field []string
+		want  []AcceptRange
+	}{{
+		name:  "Accept",
+		field: []string{`text/*;q=0.3, text/html;q=0.7, text/html;level=1`, `text/html;level=2;q=0.4, */*;q=0.5`},
+		want: []AcceptRange{
+			{Value: "text/html", Params: map[string]string{"level": "1"}, Q: 1},
+			{Value: "text/html", Q: 0.7},
+			{Value: "*/*", Q: 0.5},
+			{Value: "text/html", Params: map[string]string{"level": "2"}, Q: 0.4},
+			{Value: "text/*", Q: 0.3},
+		},
+	}, {
+		name:  "Accept",
+		field: []string{`Text/HTML; Q=0.5; ext=1, */html, text/, image/png;q=2, image/gif;q=0.1234, */*;q=1.000`},
+		want: []AcceptRange{
+			{Value: "*/*", Q: 1},
+			{Value: "text/html", Q: 0.5},
+		},
+	}, {
+		name:  "accept-language",
+		field: []string{`da, en-GB;q=0.8, en;q=0.7, *;q=0.`, `fr-`, `x y`},
+		want: []AcceptRange{
+			{Value: "da", Q: 1},
+			{Value: "fr-", Q: 1},
+			{Value: "en-gb", Q: 0.8},
+			{Value: "en", Q: 0.7},
+			{Value: "*", Q: 0},
+		},
+	}, {
+		name:  "Accept-Encoding",
+		field: []string{``},
+		want:  nil,
+	}} {
+		h := Header{}
+		for _, v := range test.field {
+			h.Add(test.name, v)
+		}
+		if got := ParseAccept(h, test.name); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseAccept(%q):\ngot  %v\nwant %v", test.field, got, test.want)
+		}
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	for _, test := range []struct {
+		accept string // "-" for none
+		offers []string
+		want   string
+	}{
+		{"-", []string{"application/json", "text/html"}, "application/json"},
+		{"", []string{"application/json", "text/html"}, "application/json"},
+		{"text/html", []string{"application/json", "text/html"}, "text/html"},
+		{"text/html", []string{"application/json"}, ""},
+		{"text/*", []string{"application/json", "text/plain; charset=utf-8"}, "text/plain; charset=utf-8"},
+		{"*/*", []string{"application/json", "text/html"}, "application/json"},
+		{"text/html;q=0.5, application/json;q=0.9", []string{"text/html", "application/json"}, "application/json"},
+		{"text/html, application/json", []string{"text/html", "application/json"}, "text/html"},
+		{"Application/JSON", []string{"application/json"}, "application/json"},
+		// The most specific range determines an offer's quality.
+		{"text/*;q=0.9, text/html;q=0.1", []string{"text/html", "text/plain"}, "text/plain"},
+		{"*/*, image/*;q=0", []string{"image/png", "text/plain"}, "text/plain"},
+		// Ties go to the more specifically matched offer.
+		{"*/*, application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"text/*, text/html", []string{"text/plain", "text/html"}, "text/html"},
+		{"text/html;level=1;q=0.2, text/html;q=0.8", []string{"text/html;level=1", "text/html;level=2"}, "text/html;level=2"},
+		{"text/plain;charset=UTF-8", []string{"text/plain;charset=iso-8859-1", "text/plain;charset=utf-8"}, "text/plain;charset=utf-8"},
+		{"application/json;q=0", []string{"application/json"}, ""},
+		{"text/html", nil, ""},
+		{"text/html", []string{"not a media type", "text/html"}, "text/html"},
+	} {
+		h := Header{}
+		if test.accept != "-" {
+			h.Set("Accept", test.accept)
+		}
+		if got := NegotiateContentType(h, test.offers); got != test.want {
+			t.Errorf("Accept: %v, offers %q: got %q, want %q", test.accept, test.offers, got, test.want)
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	for _, test := range []struct {
+		accept string // "-" for none
+		offers []string
+		want   string
+	}{
+		{"-", []string{"en", "fr"}, "en"},
+		{"fr", []string{"en", "fr"}, "fr"},
+		{"FR-ca", []string{"en", "fr-CA"}, "fr-CA"},
+		{"de", []string{"en", "fr"}, ""},
+		{"de, *;q=0.1", []string{"en", "fr"}, "en"},
+		// Ranges match tags they are prefixes of.
+		{"en", []string{"fr", "en-GB"}, "en-GB"},
+		{"en-g", []string{"en-GB"}, ""},
+		// Ranges are also truncated to match tags.
+		{"en-US, fr;q=0.5", []string{"fr", "en"}, "en"},
+		{"zh-Hant-TW", []string{"zh", "zh-Hant"}, "zh-Hant"},
+		// The most specific range determines quality.
+		{"en-US;q=0.1, en;q=0.9", []string{"en-US", "en-GB"}, "en-GB"},
+		{"en-US, en;q=0.2", []string{"en", "fr"}, "en"},
+		{"en-US, *;q=0.5", []string{"fr", "en"}, "en"},
+		{"*, fr;q=0", []string{"fr", "de"}, "de"},
+		{"da, en-GB;q=0.8, en;q=0.7", []string{"en", "en-GB", "da"}, "da"},
+		{"da, en-GB;q=0.8, en;q=0.7", []string{"en", "en-GB"}, "en-GB"},
+	} {
+		h := Header{}
+		if test.accept != "-" {
+			h.Set("Accept-Language", test.accept)
+		}
+		if got := NegotiateLanguage(h, test.offers); got != test.want {
+			t.Errorf("Accept-Language: %v, offers %q: got %q, want %q", test.accept, test.offers, got, test.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	all := []string{"zstd", "gzip", "identity"}
+	for _, test := range []struct {
+		accept string // "-" for none
+		offers []string
+		want   string
+	}{
+		{"-", all, "identity"},
+		{"-", []string{"gzip"}, ""},
+		{"", all, "identity"},
+		{"gzip", all, "gzip"},
+		{"gzip, zstd", all, "zstd"},
+		{"gzip, zstd;q=0.5", all, "gzip"},
+		{"x-gzip", all, "gzip"},
+		{"GZIP", []string{"x-gzip"}, "x-gzip"},
+		{"*", all, "zstd"},
+		{"*, zstd;q=0", all, "gzip"},
+		{"*, gzip", all, "gzip"},
+		{"br", all, "identity"},
+		{"br", []string{"gzip"}, ""},
+		{"gzip;q=0.001", all, "gzip"},
+		{"gzip;q=0.5, identity", all, "identity"},
+		{"identity;q=0", all, ""},
+		{"identity;q=0, gzip", all, "gzip"},
+		{"*;q=0", all, ""},
+		{"*;q=0, identity", all, "identity"},
+		{"gzip;q=0, *;q=0.1", all, "zstd"},
+	} {
+		h := Header{}
+		if test.accept != "-" {
+			h.Set("Accept-Encoding", test.accept)
+		}
+		if got := NegotiateEncoding(h, test.offers); got != test.want {
+			t.Errorf("Accept-Encoding: %v, offers %q: got %q, want %q", test.accept, test.offers, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNilToken = errors.New("oauth2: token source returned nil token")
+
+// An AuthStyle is a way for a client to authenticate itself to an
+// authorization server's token endpoint.
+type AuthStyle int
+
+const (
+	// AuthStyleHeader sends the client ID and secret in an
+	// Authorization header, using HTTP Basic authentication
+	// (RFC 6749 section 2.3.1). This is the default.
+	AuthStyleHeader AuthStyle = iota
+
+	// AuthStyleParams sends the client ID and secret in the
+	// client_id and client_secret request parameters.
+	AuthStyleParams
+)
+
+// An Endpoint is an authorization server's token endpoint, and the
+// credentials a client uses to authenticate itself to it.
+type Endpoint struct {
+	// TokenURL is the URL of the token endpoint.
+	TokenURL string
+
+	// ClientID and ClientSecret are the client's credentials.
+	// If ClientSecret is empty, the client is a public client,
+	// which sends only its ID, as the client_id parameter.
+	ClientID     string
+	ClientSecret string
+
+	// AuthStyle is how the credentials are sent.
+	AuthStyle AuthStyle
+
+	// Client is the HTTP client used to send token requests.
+	// If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// maxTokenResponseSize bounds the size of a token endpoint response.
+const maxTokenResponseSize = 1 << 20
+
+// Exchange sends a token request with the given parameters, which
+// must include grant_type, to the endpoint, and returns the token in
+// its response. If the server responds with an error, Exchange returns
+// an [*Error].
+func (e *Endpoint) Exchange(ctx context.Context, params url.Values) (*Token, error) {
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	useBasic := e.AuthStyle == AuthStyleHeader && e.ClientSecret != ""
+	if !useBasic {
+		if e.ClientID != "" {
+			form.Set("client_id", e.ClientID)
+		}
+		if e.ClientSecret != "" {
+			form.Set("client_secret", e.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		// RFC 6749 section 2.3.1 requires the credentials to be
+		// form-encoded before they are used as a user ID and password.
+		req.SetBasicAuth(url.QueryEscape(e.ClientID), url.QueryEscape(e.ClientSecret))
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: token request: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxTokenResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: reading token response: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		oerr := &Error{StatusCode: res.StatusCode, Body: body}
+		json.Unmarshal(body, oerr)
+		return nil, oerr
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("oauth2: parsing token response: %w", err)
+	}
+	if tr.ErrorCode != "" {
+		// Some servers report errors with a successful status.
+		return nil, &Error{StatusCode: res.StatusCode, Body: body, Code: tr.ErrorCode,
+			Description: tr.ErrorDescription, URI: tr.ErrorURI}
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("oauth2: token response has no access_token")
+	}
+	tok := &Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = timeNow().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// tokenResponse is a token endpoint's response (RFC 6749 section 5).
+type tokenResponse struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        expiresIn `json:"expires_in"`
+	ErrorCode        string    `json:"error"`
+	ErrorDescription string    `json:"error_description"`
+	ErrorURI         string    `json:"error_uri"`
+}
+
+// expiresIn is the lifetime of a token in seconds. Some servers send
+// it as a string rather than a number.
+type expiresIn int64
+
+func (e *expiresIn) UnmarshalJSON(b []byte) error {
+	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	if string(b) == "null" || len(b) == 0 {
+		return nil
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires_in %s", b)
+	}
+	*e = expiresIn(n)
+	return nil
+}
+
+// An Error is an error response from a token endpoint
+// (RFC 6749 section 5.2).
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Code, Description and URI are the error, error_description
+	// and error_uri members of the response, if it has them.
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	URI         string `json:"error_uri"`
+
+	// Body is the body of the response.
+	Body []byte `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oauth2: token request failed: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	s := "oauth2: token request failed: " + e.Code
+	if e.Description != "" {
+		s += ": " + e.Description
+	}
+	return s
+}
+
+// ClientCredentials is a [TokenSource] which obtains tokens using the
+// client credentials grant (RFC 6749 section 4.4), in which a client
+// authenticates as itself rather than on behalf of a user.
+//
+// Each call to Token requests a new token. A ClientCredentials
+// is usually wrapped in a [TokenCache], or used as the Source of a
+// [Transport], which caches its tokens.
+type ClientCredentials struct {
+	Endpoint
+
+	// Scopes are the scopes requested, if any.
+	Scopes []string
+
+	// Params are additional parameters for token requests, such as
+	// "audience" or "resource".
+	Params url.Values
+}
+
+// Token requests a token from the endpoint.
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	params := url.Values{}
+	for k, v := range c.Params {
+		params[k] = v
+	}
+	params.Set("grant_type", "client_credentials")
+	if len(c.Scopes) > 0 {
+		params.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	return c.Exchange(ctx, params)
+}
+
+// RefreshTokenSource returns a [TokenSource] which obtains tokens using
+// the refresh token grant (RFC 6749 section 6), starting with the given
+// refresh token.
+//
+// If the server issues a new refresh token with an access token, the
+// source uses it for subsequent requests. The tokens returned by the
+// source have the current refresh token in their RefreshToken field,
+// so that it may be saved.
+//
+// Each call to Token requests a new token. The source is usually
+// wrapped in a [TokenCache], or used as the Source of a [Transport],
+// which caches its tokens.
+func (e *Endpoint) RefreshTokenSource(refreshToken string) TokenSource {
+	return &refreshSource{e: e, refreshToken: refreshToken}
+}
+
+type refreshSource struct {
+	e *Endpoint
+
+	mu           sync.Mutex // serializes refreshes, so each sees the latest refresh token
+	refreshToken string
+}
+
+func (s *refreshSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshToken == "" {
+		return nil, errors.New("oauth2: no refresh token")
+	}
+	tok, err := s.e.Exchange(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = s.refreshToken
+	}
+	s.refreshToken = tok.RefreshToken
+	return tok, nil
+}
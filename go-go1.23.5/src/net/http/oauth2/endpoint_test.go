@@ -0,0 +1,167 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token endpoint which calls respond with each
+// request's form, and writes the status and body it returns.
+func newTokenServer(t *testing.T, respond func(r *http.Request) (int, string)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("token request method = %v, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("token request Content-Type = %q", ct)
+		}
+		r.ParseForm()
+		code, body := respond(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestClientCredentials(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	var got url.Values
+	var gotUser, gotPass string
+	ts := newTokenServer(t, func(r *http.Request) (int, string) {
+		got = r.PostForm
+		gotUser, gotPass, _ = r.BasicAuth()
+		return 200, `{"access_token": "at", "token_type": "bearer", "expires_in": 3600}`
+	})
+	cc := &ClientCredentials{
+		Endpoint: Endpoint{
+			TokenURL:     ts.URL,
+			ClientID:     "my client",
+			ClientSecret: "s3cr:t",
+		},
+		Scopes: []string{"read", "write"},
+		Params: url.Values{"audience": {"api"}},
+	}
+	tok, err := cc.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Token{AccessToken: "at", TokenType: "bearer", Expiry: now.Add(time.Hour)}
+	if *tok != want {
+		t.Errorf("got token %+v, want %+v", tok, want)
+	}
+	if got.Encode() != "audience=api&grant_type=client_credentials&scope=read+write" {
+		t.Errorf("got form %q", got.Encode())
+	}
+	if gotUser != "my+client" || gotPass != "s3cr%3At" {
+		t.Errorf("got basic auth %q:%q, want form-encoded credentials", gotUser, gotPass)
+	}
+
+	cc.AuthStyle = AuthStyleParams
+	if _, err := cc.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("client_id") != "my client" || got.Get("client_secret") != "s3cr:t" {
+		t.Errorf("AuthStyleParams: got form %q", got.Encode())
+	}
+}
+
+func TestExchangeResponses(t *testing.T) {
+	for _, test := range []struct {
+		code    int
+		body    string
+		want    *Token
+		wantErr string
+	}{
+		{200, `{"access_token": "a", "expires_in": "60"}`, &Token{AccessToken: "a"}, ""},
+		{200, `{"access_token": "a", "refresh_token": "r"}`, &Token{AccessToken: "a", RefreshToken: "r"}, ""},
+		{200, `{"token_type": "bearer"}`, nil, "no access_token"},
+		{200, `{"error": "slow_down"}`, nil, "token request failed: slow_down"},
+		{200, `not json`, nil, "parsing token response"},
+		{400, `{"error": "invalid_client", "error_description": "unknown client"}`, nil,
+			"token request failed: invalid_client: unknown client"},
+		{500, `oops`, nil, "token request failed: 500 Internal Server Error"},
+	} {
+		ts := newTokenServer(t, func(*http.Request) (int, string) { return test.code, test.body })
+		e := &Endpoint{TokenURL: ts.URL, ClientID: "id"}
+		tok, err := e.Exchange(context.Background(), url.Values{"grant_type": {"test"}})
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%v %s: got error %v, want %q", test.code, test.body, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v %s: %v", test.code, test.body, err)
+			continue
+		}
+		tok.Expiry = time.Time{}
+		if *tok != *test.want {
+			t.Errorf("%v %s: got %+v, want %+v", test.code, test.body, tok, test.want)
+		}
+	}
+
+	ts := newTokenServer(t, func(*http.Request) (int, string) {
+		return 401, `{"error": "invalid_client"}`
+	})
+	_, err := (&Endpoint{TokenURL: ts.URL}).Exchange(context.Background(), nil)
+	var oerr *Error
+	if !errors.As(err, &oerr) || oerr.StatusCode != 401 || oerr.Code != "invalid_client" {
+		t.Errorf("got error %#v, want *Error with status 401", err)
+	}
+}
+
+func TestRefreshTokenSource(t *testing.T) {
+	var seen []string
+	ts := newTokenServer(t, func(r *http.Request) (int, string) {
+		if g := r.PostForm.Get("grant_type"); g != "refresh_token" {
+			t.Errorf("grant_type = %q, want refresh_token", g)
+		}
+		rt := r.PostForm.Get("refresh_token")
+		seen = append(seen, rt)
+		switch rt {
+		case "r1":
+			// Rotate the refresh token.
+			return 200, `{"access_token": "a1", "refresh_token": "r2", "expires_in": 1}`
+		case "r2":
+			return 200, `{"access_token": "a2", "expires_in": 1}`
+		}
+		return 400, `{"error": "invalid_grant"}`
+	})
+	src := (&Endpoint{TokenURL: ts.URL, ClientID: "id", ClientSecret: "secret"}).RefreshTokenSource("r1")
+	for _, want := range []Token{
+		{AccessToken: "a1", RefreshToken: "r2"},
+		{AccessToken: "a2", RefreshToken: "r2"},
+		{AccessToken: "a2", RefreshToken: "r2"},
+	} {
+		tok, err := src.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != want.AccessToken || tok.RefreshToken != want.RefreshToken {
+			t.Errorf("got %+v, want %+v", tok, want)
+		}
+	}
+	if got, want := len(seen), 3; got != want || seen[0] != "r1" || seen[2] != "r2" {
+		t.Errorf("server saw refresh tokens %q", seen)
+	}
+
+	if _, err := (&Endpoint{TokenURL: ts.URL}).RefreshTokenSource("").Token(context.Background()); err == nil {
+		t.Error("empty refresh token: got nil error")
+	}
+}
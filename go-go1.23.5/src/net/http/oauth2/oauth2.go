@@ -0,0 +1,297 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package oauth2 implements HTTP clients authenticated with OAuth 2.0
+// bearer tokens, as specified in RFC 6749 and RFC 6750.
+//
+// A [TokenSource] provides tokens. The sources in this package obtain
+// them from an authorization server's token endpoint, using the
+// client credentials grant ([ClientCredentials]) or the refresh token
+// grant ([Endpoint.RefreshTokenSource]). A [TokenCache] reuses a
+// token until it expires, and a [Transport] adds tokens to requests:
+//
+//	cc := &oauth2.ClientCredentials{
+//		Endpoint: oauth2.Endpoint{
+//			TokenURL:     "https://auth.example.com/token",
+//			ClientID:     clientID,
+//			ClientSecret: clientSecret,
+//		},
+//		Scopes: []string{"read"},
+//	}
+//	client := &http.Client{Transport: &oauth2.Transport{Source: cc}}
+package oauth2
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/internal/ascii"
+	"sync"
+	"time"
+)
+
+// A Token is an OAuth 2.0 access token, with the information needed
+// to use and renew it.
+type Token struct {
+	// AccessToken is the token that authorizes requests.
+	AccessToken string `json:"access_token"`
+
+	// TokenType is the type of the token, usually "Bearer".
+	// An empty TokenType means "Bearer".
+	TokenType string `json:"token_type,omitempty"`
+
+	// RefreshToken, if non-empty, may be used to obtain a new access
+	// token when this one expires. See [Endpoint.RefreshTokenSource].
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Expiry is the time the access token expires.
+	// A zero Expiry means the token does not expire.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// expiryDelta is how long before its expiry time a token
+// is considered expired, to allow for clock skew and latency.
+const expiryDelta = 10 * time.Second
+
+// timeNow is time.Now, replaced in tests.
+var timeNow = time.Now
+
+// Valid reports whether t is non-nil, has an access token,
+// and will not expire within the next few seconds.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || timeNow().Add(expiryDelta).Before(t.Expiry)
+}
+
+// Type returns the token's type, as it appears in an Authorization
+// header: "Bearer" if t.TokenType is empty or a variant of "bearer",
+// and t.TokenType otherwise.
+func (t *Token) Type() string {
+	if t.TokenType == "" || ascii.EqualFold(t.TokenType, "bearer") {
+		return "Bearer"
+	}
+	return t.TokenType
+}
+
+// SetAuthHeader sets the Authorization header of r to authorize it
+// with t.
+func (t *Token) SetAuthHeader(r *http.Request) {
+	r.Header.Set("Authorization", t.Type()+" "+t.AccessToken)
+}
+
+// A TokenSource provides tokens.
+//
+// Implementations of TokenSource must be safe for concurrent use by
+// multiple goroutines.
+type TokenSource interface {
+	// Token returns a token, or an error if none can be obtained.
+	Token(ctx context.Context) (*Token, error)
+}
+
+// StaticTokenSource returns a [TokenSource] that always returns t.
+func StaticTokenSource(t *Token) TokenSource {
+	return staticSource{t}
+}
+
+type staticSource struct {
+	t *Token
+}
+
+func (s staticSource) Token(context.Context) (*Token, error) {
+	return s.t, nil
+}
+
+// A TokenCache is a [TokenSource] which returns the same token until
+// it expires, and then obtains a new one from another TokenSource.
+//
+// When several goroutines need a new token at once, the TokenCache
+// asks its source for only one token, which they all share.
+type TokenCache struct {
+	// FetchTimeout limits the time spent obtaining each new token
+	// from the source. If zero, one minute is used.
+	// It must not be changed after the TokenCache is first used.
+	FetchTimeout time.Duration
+
+	src TokenSource
+
+	mu    sync.Mutex
+	tok   *Token
+	fetch *tokenFetch // in progress, or nil
+}
+
+// A tokenFetch is a call to a TokenCache's source.
+type tokenFetch struct {
+	done chan struct{} // closed when tok and err are set
+	tok  *Token
+	err  error
+}
+
+// defaultFetchTimeout is the default TokenCache.FetchTimeout.
+const defaultFetchTimeout = time.Minute
+
+// NewTokenCache returns a [TokenCache] which obtains tokens from src.
+// If tok is non-nil, it is returned until it expires.
+func NewTokenCache(src TokenSource, tok *Token) *TokenCache {
+	return &TokenCache{src: src, tok: tok}
+}
+
+// Token returns the cached token if it is valid, and otherwise
+// obtains a new token from the cache's source.
+//
+// If ctx is done before a new token is obtained, Token returns the
+// context's error. The request for a new token continues, without
+// ctx's deadline or cancellation but limited by FetchTimeout,
+// for the benefit of other callers.
+func (c *TokenCache) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	if c.tok.Valid() {
+		tok := c.tok
+		c.mu.Unlock()
+		return tok, nil
+	}
+	f := c.fetch
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		c.fetch = f
+		go c.doFetch(context.WithoutCancel(ctx), f)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.tok, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *TokenCache) doFetch(ctx context.Context, f *tokenFetch) {
+	timeout := c.FetchTimeout
+	if timeout == 0 {
+		timeout = defaultFetchTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tok, err := c.src.Token(ctx)
+	if err == nil && tok == nil {
+		err = errNilToken
+	}
+	c.mu.Lock()
+	if err == nil {
+		c.tok = tok
+	}
+	c.fetch = nil
+	c.mu.Unlock()
+	f.tok, f.err = tok, err
+	close(f.done)
+}
+
+// Invalidate discards tok, which has been rejected by a server,
+// if it is the cached token, so that the next call to Token obtains
+// a new one.
+func (c *TokenCache) Invalidate(tok *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tok == tok {
+		c.tok = nil
+	}
+}
+
+// Transport is an [http.RoundTripper] which authorizes requests
+// with tokens from a [TokenSource].
+//
+// Unless its Source is a [*TokenCache], a Transport caches the tokens
+// from its Source, as a TokenCache does.
+//
+// If a server responds to a request with 401 Unauthorized, the
+// Transport discards its token, obtains a new one, and sends the
+// request again, once. It does so only if the request has no body,
+// or has a GetBody function to obtain a new copy of it. If the
+// Source returns the same token again, the 401 response is returned.
+type Transport struct {
+	// Source provides tokens for requests.
+	Source TokenSource
+
+	// Base is the RoundTripper used to send requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	cacheOnce sync.Once
+	cache     *TokenCache
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) tokenCache() *TokenCache {
+	t.cacheOnce.Do(func() {
+		if c, ok := t.Source.(*TokenCache); ok {
+			t.cache = c
+		} else {
+			t.cache = NewTokenCache(t.Source, nil)
+		}
+	})
+	return t.cache
+}
+
+// RoundTrip implements the [http.RoundTripper] interface.
+// It does not modify req; the request it sends is a copy of req
+// with an Authorization header.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cache := t.tokenCache()
+	tok, err := cache.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	res, err := t.base().RoundTrip(authorize(req, tok))
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+	cache.Invalidate(tok)
+	tok2, err := cache.Token(req.Context())
+	if err != nil || tok2.AccessToken == tok.AccessToken {
+		return res, nil
+	}
+	retry := authorize(req, tok2)
+	if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+		retry.Body = body
+	}
+	// Drain a little of the body so the connection may be reused.
+	io.CopyN(io.Discard, res.Body, 4<<10)
+	res.Body.Close()
+	return t.base().RoundTrip(retry)
+}
+
+// authorize returns a copy of req authorized with tok.
+func authorize(req *http.Request, tok *Token) *http.Request {
+	r := req.Clone(req.Context())
+	tok.SetAuthHeader(r)
+	return r
+}
+
+// CloseIdleConnections closes the idle connections of t's Base,
+// if it has a CloseIdleConnections method.
+func (t *Transport) CloseIdleConnections() {
+	type closeIdler interface{ CloseIdleConnections() }
+	if ci, ok := t.base().(closeIdler); ok {
+		ci.CloseIdleConnections()
+	}
+}
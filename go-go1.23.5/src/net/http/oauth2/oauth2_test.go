@@ -0,0 +1,323 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenValid(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	for _, test := range []struct {
+		tok  *Token
+		want bool
+	}{
+		{nil, false},
+		{&Token{}, false},
+		{&Token{AccessToken: "a"}, true},
+		{&Token{AccessToken: "a", Expiry: now.Add(time.Hour)}, true},
+		{&Token{AccessToken: "a", Expiry: now.Add(expiryDelta / 2)}, false},
+		{&Token{AccessToken: "a", Expiry: now.Add(-time.Hour)}, false},
+	} {
+		if got := test.tok.Valid(); got != test.want {
+			t.Errorf("%+v.Valid() = %v, want %v", test.tok, got, test.want)
+		}
+	}
+}
+
+func TestTokenType(t *testing.T) {
+	for _, test := range []struct{ typ, want string }{
+		{"", "Bearer"},
+		{"bearer", "Bearer"},
+		{"BEARER", "Bearer"},
+		{"MAC", "MAC"},
+	} {
+		r, _ := http.NewRequest("GET", "http://example.com/", nil)
+		(&Token{AccessToken: "x", TokenType: test.typ}).SetAuthHeader(r)
+		if got, want := r.Header.Get("Authorization"), test.want+" x"; got != want {
+			t.Errorf("TokenType %q: Authorization = %q, want %q", test.typ, got, want)
+		}
+	}
+}
+
+// countingSource is a TokenSource which returns a new token
+// each time it is called, after waiting for unblock if non-nil.
+type countingSource struct {
+	n       atomic.Int32
+	unblock chan struct{}
+	expiry  time.Duration
+}
+
+func (s *countingSource) Token(ctx context.Context) (*Token, error) {
+	if s.unblock != nil {
+		<-s.unblock
+	}
+	n := s.n.Add(1)
+	tok := &Token{AccessToken: fmt.Sprint("token", n)}
+	if s.expiry != 0 {
+		tok.Expiry = timeNow().Add(s.expiry)
+	}
+	return tok, nil
+}
+
+func TestTokenCacheConcurrent(t *testing.T) {
+	src := &countingSource{unblock: make(chan struct{})}
+	c := NewTokenCache(src, nil)
+
+	const n = 20
+	var wg sync.WaitGroup
+	toks := make([]*Token, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := c.Token(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			toks[i] = tok
+		}()
+	}
+	// Wait for the fetch to start, then let it finish.
+	for {
+		c.mu.Lock()
+		started := c.fetch != nil
+		c.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(src.unblock)
+	wg.Wait()
+	if got := src.n.Load(); got != 1 {
+		t.Errorf("source called %v times, want 1", got)
+	}
+	for i, tok := range toks {
+		if tok == nil || tok.AccessToken != "token1" {
+			t.Errorf("caller %v got %+v, want token1", i, tok)
+		}
+	}
+
+	// Invalidating a token that is no longer cached does nothing.
+	c.Invalidate(&Token{AccessToken: "token1"})
+	if tok, _ := c.Token(context.Background()); tok.AccessToken != "token1" {
+		t.Errorf("after invalidating another token: got %v, want token1", tok.AccessToken)
+	}
+	c.Invalidate(toks[0])
+	if tok, _ := c.Token(context.Background()); tok.AccessToken != "token2" {
+		t.Errorf("after Invalidate: got %v, want token2", tok.AccessToken)
+	}
+}
+
+func TestTokenCacheExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	src := &countingSource{expiry: time.Hour}
+	c := NewTokenCache(src, &Token{AccessToken: "initial", Expiry: now.Add(time.Hour)})
+	for _, test := range []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "initial"},
+		{time.Hour - 2*expiryDelta, "initial"},
+		{expiryDelta, "token1"},
+		{time.Hour / 2, "token1"},
+		{time.Hour, "token2"},
+	} {
+		now = now.Add(test.advance)
+		tok, err := c.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != test.want {
+			t.Errorf("at %v: got %v, want %v", now, tok.AccessToken, test.want)
+		}
+	}
+}
+
+func TestTokenCacheCanceled(t *testing.T) {
+	src := &countingSource{unblock: make(chan struct{})}
+	c := NewTokenCache(src, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Token(ctx); err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	// The fetch continues for later callers.
+	close(src.unblock)
+	if tok, err := c.Token(context.Background()); err != nil || tok.AccessToken != "token1" {
+		t.Errorf("got %v, %v; want token1", tok, err)
+	}
+}
+
+func TestTokenCacheFetchTimeout(t *testing.T) {
+	var hang atomic.Bool
+	hang.Store(true)
+	ts := newTokenServer(t, func(r *http.Request) (int, string) {
+		if hang.Load() {
+			<-r.Context().Done()
+		}
+		return 200, `{"access_token":"fresh","token_type":"Bearer"}`
+	})
+	cc := &ClientCredentials{Endpoint: Endpoint{TokenURL: ts.URL, ClientID: "id", ClientSecret: "secret"}}
+	c := NewTokenCache(cc, nil)
+	c.FetchTimeout = 50 * time.Millisecond
+	if _, err := c.Token(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Token from hanging server: got error %v, want context.DeadlineExceeded", err)
+	}
+	// The timed out fetch does not block later callers.
+	hang.Store(false)
+	if tok, err := c.Token(context.Background()); err != nil || tok.AccessToken != "fresh" {
+		t.Errorf("Token after timeout = %v, %v; want fresh", tok, err)
+	}
+}
+
+// A requestLog records the requests received by a server.
+type requestLog struct {
+	mu   sync.Mutex
+	reqs []string
+}
+
+func (l *requestLog) add(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reqs = append(l.reqs, s)
+}
+
+// take returns the recorded requests, and clears the log.
+func (l *requestLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	reqs := l.reqs
+	l.reqs = nil
+	return reqs
+}
+
+// newResourceServer returns a server which accepts only the access
+// token in valid, and logs the token and body of each request.
+func newResourceServer(t *testing.T, valid *atomic.Value) (*httptest.Server, *requestLog) {
+	log := new(requestLog)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		log.add(r.Header.Get("Authorization") + " " + string(body))
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(ts.Close)
+	return ts, log
+}
+
+func TestTransport(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("token1")
+	ts, log := newResourceServer(t, &valid)
+	src := &countingSource{}
+	c := &http.Client{Transport: &Transport{Source: src}}
+
+	get := func(body string) (int, error) {
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req, _ := http.NewRequest("POST", ts.URL, r)
+		res, err := c.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("RoundTrip modified the request")
+		}
+		return res.StatusCode, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if code, err := get("a"); code != 200 || err != nil {
+			t.Fatalf("request %v: %v, %v", i, code, err)
+		}
+	}
+	if n := src.n.Load(); n != 1 {
+		t.Errorf("source called %v times, want 1", n)
+	}
+
+	// After the token is revoked, the request is retried with a new token.
+	valid.Store("token2")
+	log.take()
+	if code, err := get("b"); code != 200 || err != nil {
+		t.Fatalf("request after revocation: %v, %v", code, err)
+	}
+	if got, want := strings.Join(log.take(), ","), "Bearer token1 b,Bearer token2 b"; got != want {
+		t.Errorf("server saw requests %q, want %q", got, want)
+	}
+
+	// A request is retried only once.
+	valid.Store("never")
+	if code, err := get(""); code != 401 || err != nil {
+		t.Fatalf("request with invalid tokens: %v, %v; want 401", code, err)
+	}
+	if n := len(log.take()); n != 2 {
+		t.Errorf("server saw %v requests, want 2", n)
+	}
+
+	// A request whose body cannot be rewound is not retried.
+	req, _ := http.NewRequest("POST", ts.URL, io.NopCloser(strings.NewReader("c")))
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if n := len(log.take()); res.StatusCode != 401 || n != 1 {
+		t.Errorf("request with unrewindable body: status %v after %v requests, want 401 after 1", res.StatusCode, n)
+	}
+}
+
+func TestTransportStaticToken(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("other")
+	ts, log := newResourceServer(t, &valid)
+	c := &http.Client{Transport: &Transport{Source: StaticTokenSource(&Token{AccessToken: "static"})}}
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	// The source returns the same token, so there is no retry.
+	if n := len(log.take()); res.StatusCode != 401 || n != 1 {
+		t.Errorf("got status %v after %v requests, want 401 after 1", res.StatusCode, n)
+	}
+}
+
+func TestTransportSourceError(t *testing.T) {
+	errNoToken := errors.New("no token")
+	src := sourceFunc(func(context.Context) (*Token, error) { return nil, errNoToken })
+	c := &http.Client{Transport: &Transport{Source: src}}
+	if _, err := c.Get("http://example.com/"); !errors.Is(err, errNoToken) {
+		t.Errorf("got error %v, want %v", err, errNoToken)
+	}
+}
+
+type sourceFunc func(context.Context) (*Token, error)
+
+func (f sourceFunc) Token(ctx context.Context) (*Token, error) { return f(ctx) }
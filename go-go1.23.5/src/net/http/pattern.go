@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 	"unicode"
 )
@@ -54,6 +55,12 @@ func (p *pattern) lastSegment() segment {
 //
 //	"{x}" => segment{s: "x", wild: true}
 //
+// If wild is true and c is non-nil, it matches a single path segment
+// that satisfies the constraint.
+// Example:
+//
+//	"{x:int}" => segment{s: "x", wild: true, c: <constraint for -?[0-9]+>}
+//
 // If both wild and multi are true, it matches all remaining path segments.
 // Example:
 //
@@ -61,7 +68,8 @@ func (p *pattern) lastSegment() segment {
 type segment struct {
 	s     string // literal or wildcard name or "/" for "/{$}".
 	wild  bool
-	multi bool // "..." wildcard
+	multi bool        // "..." wildcard
+	c     *constraint // constraint on a single wildcard, or nil
 }
 
 // parsePattern parses a string into a Pattern.
@@ -73,7 +81,8 @@ type segment struct {
 //   - METHOD is an HTTP method
 //   - HOST is a hostname
 //   - PATH consists of slash-separated segments, where each segment is either
-//     a literal or a wildcard of the form "{name}", "{name...}", or "{$}".
+//     a literal or a wildcard of the form "{name}", "{name:constraint}",
+//     "{name...}", or "{$}".
 //
 // METHOD, HOST and PATH are all optional; that is, the string can be "/".
 // If METHOD is present, it must be followed by at least one space or tab.
@@ -81,6 +90,7 @@ type segment struct {
 // The "{$}" and "{name...}" wildcard must occur at the end of PATH.
 // PATH may end with a '/'.
 // Wildcard names in a path must be distinct.
+// A constraint is the name of a registered constraint or a regular expression.
 func parsePattern(s string) (_ *pattern, err error) {
 	if len(s) == 0 {
 		return nil, errors.New("empty pattern")
@@ -156,7 +166,8 @@ func parsePattern(s string) (_ *pattern, err error) {
 				return nil, errors.New("bad wildcard segment (must end with '}')")
 			}
 			name := seg[1 : len(seg)-1]
-			if name == "$" {
+			name, cons, constrained := strings.Cut(name, ":")
+			if name == "$" && !constrained {
 				if len(rest) != 0 {
 					return nil, errors.New("{$} not at end")
 				}
@@ -177,7 +188,17 @@ func parsePattern(s string) (_ *pattern, err error) {
 				return nil, fmt.Errorf("duplicate wildcard name %q", name)
 			}
 			seenNames[name] = true
-			p.segments = append(p.segments, segment{s: name, wild: true, multi: multi})
+			var c *constraint
+			if constrained {
+				if multi {
+					return nil, errors.New("{...} wildcard cannot have a constraint")
+				}
+				c, err = lookupConstraint(cons)
+				if err != nil {
+					return nil, fmt.Errorf("wildcard %q: %w", name, err)
+				}
+			}
+			p.segments = append(p.segments, segment{s: name, wild: true, multi: multi, c: c})
 		}
 	}
 	return p, nil
@@ -326,17 +347,18 @@ func compareSegments(s1, s2 segment) relationship {
 		return moreSpecific
 	}
 	if s1.wild && s2.wild {
-		return equivalent
+		return compareConstraints(s1.c, s2.c)
 	}
 	if s1.wild {
-		if s2.s == "/" {
-			// A single wildcard doesn't match a trailing slash.
+		if s2.s == "/" || (s1.c != nil && !s1.c.match(s2.s)) {
+			// A single wildcard doesn't match a trailing slash,
+			// or a literal that doesn't satisfy its constraint.
 			return disjoint
 		}
 		return moreGeneral
 	}
 	if s2.wild {
-		if s1.s == "/" {
+		if s1.s == "/" || (s2.c != nil && !s2.c.match(s1.s)) {
 			return disjoint
 		}
 		return moreSpecific
@@ -438,7 +460,9 @@ func writeMatchingPath(b *strings.Builder, segs []segment) {
 
 func writeSegment(b *strings.Builder, s segment) {
 	b.WriteByte('/')
-	if !s.multi && s.s != "/" {
+	if s.c != nil && s.c.example != "" {
+		b.WriteString(s.c.example)
+	} else if !s.multi && s.s != "/" {
 		b.WriteString(s.s)
 	}
 }
@@ -449,8 +473,14 @@ func commonPath(p1, p2 *pattern) string {
 	var b strings.Builder
 	var segs1, segs2 []segment
 	for segs1, segs2 = p1.segments, p2.segments; len(segs1) > 0 && len(segs2) > 0; segs1, segs2 = segs1[1:], segs2[1:] {
-		if s1 := segs1[0]; s1.wild {
-			writeSegment(&b, segs2[0])
+		if s1, s2 := segs1[0], segs2[0]; s1.wild && s2.wild && s1.c != nil && s2.c != nil && s1.c != s2.c {
+			// Use a value that satisfies both constraints.
+			b.WriteByte('/')
+			b.WriteString(s1.c.compare(s2.c).both)
+		} else if s1.wild && s2.wild && s1.c != nil {
+			writeSegment(&b, s1)
+		} else if s1.wild {
+			writeSegment(&b, s2)
 		} else {
 			writeSegment(&b, s1)
 		}
@@ -495,6 +525,23 @@ func differencePath(p1, p2 *pattern) string {
 		}
 		if !s1.multi && s2.multi {
 			writeSegment(&b, s1)
+		} else if s1.wild && s2.wild && s1.c != nil && s1.c != s2.c {
+			// If some value satisfies s1's constraint but not s2's,
+			// p2 won't match; otherwise use one that satisfies both.
+			b.WriteByte('/')
+			var only1, both string
+			if s2.c == nil {
+				both = s1.c.example
+			} else {
+				r := s1.c.compare(s2.c)
+				only1, both = r.only1, r.both
+			}
+			if only1 != "" {
+				b.WriteString(only1)
+				writeMatchingPath(&b, segs1[1:])
+				return b.String()
+			}
+			b.WriteString(both)
 		} else if s1.wild && s2.wild {
 			// Both patterns will match whatever we put here; use
 			// the first wildcard name.
@@ -504,7 +551,12 @@ func differencePath(p1, p2 *pattern) string {
 			// Any segment other than s2.s will work.
 			// Prefer the wildcard name, but if it's the same as the literal,
 			// tweak the literal.
-			if s1.s != s2.s {
+			if s1.c != nil {
+				// The value must also satisfy the constraint.
+				lit, _ := compileConstraint(regexp.QuoteMeta(s2.s))
+				b.WriteByte('/')
+				b.WriteString(s1.c.compare(lit).only1)
+			} else if s1.s != s2.s {
 				writeSegment(&b, s1)
 			} else {
 				b.WriteByte('/')
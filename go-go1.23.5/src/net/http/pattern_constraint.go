@@ -0,0 +1,437 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Constrained wildcards for ServeMux patterns.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A constraint restricts the values matched by a "{name:constraint}" wildcard.
+// Every constraint is a regular expression, which lets the ServeMux
+// determine exactly how the sets of values matched by two constraints
+// are related when it checks patterns for conflicts.
+type constraint struct {
+	expr    string         // the regular expression
+	re      *regexp.Regexp // expr, anchored at both ends
+	prog    *syntax.Prog   // expr, for comparisons
+	example string         // a non-empty value matched by expr, for messages
+
+	mu   sync.Mutex
+	rels map[*constraint]*constraintRel // cache for compare
+}
+
+// A constraintRel describes how the values matched by
+// two constraints c1 and c2 are related.
+type constraintRel struct {
+	rel relationship
+	// Non-empty examples of values matched by both constraints,
+	// by c1 only, and by c2 only, or "" if there are none.
+	both, only1, only2 string
+}
+
+// namedConstraints maps constraint names to regular expressions.
+var namedConstraints = struct {
+	mu sync.RWMutex
+	m  map[string]string
+}{m: map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[0-9A-Za-z]+`,
+	"hex":   `[0-9A-Fa-f]+`,
+	"uuid":  `[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`,
+}}
+
+// constraints interns compiled constraints by expression.
+var constraints struct {
+	mu sync.Mutex
+	m  map[string]*constraint
+}
+
+// RegisterPatternConstraint makes a named constraint available to
+// [ServeMux] patterns: a wildcard written "{id:name}" matches only
+// path segments which expr, a regular expression in the syntax of
+// the regexp package, matches in their entirety.
+//
+// The constraints "int", "uint", "alpha", "alnum", "hex" and "uuid"
+// are predefined.
+//
+// A constraint must be registered before any pattern that uses it.
+// RegisterPatternConstraint panics if name is not a valid Go
+// identifier, if a constraint with that name is already registered,
+// or if expr is not a valid constraint.
+func RegisterPatternConstraint(name, expr string) {
+	if !isValidWildcardName(name) {
+		panic(fmt.Sprintf("http: invalid constraint name %q", name))
+	}
+	if _, err := compileConstraint(expr); err != nil {
+		panic(fmt.Sprintf("http: constraint %q: %v", name, err))
+	}
+	namedConstraints.mu.Lock()
+	defer namedConstraints.mu.Unlock()
+	if _, dup := namedConstraints.m[name]; dup {
+		panic(fmt.Sprintf("http: constraint %q already registered", name))
+	}
+	namedConstraints.m[name] = expr
+}
+
+// lookupConstraint returns the constraint written s in a pattern:
+// either the name of a registered constraint or a regular expression.
+func lookupConstraint(s string) (*constraint, error) {
+	if s == "" {
+		return nil, errors.New("empty constraint")
+	}
+	expr := s
+	if isValidWildcardName(s) {
+		namedConstraints.mu.RLock()
+		e, ok := namedConstraints.m[s]
+		namedConstraints.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown constraint %q", s)
+		}
+		expr = e
+	}
+	return compileConstraint(expr)
+}
+
+func compileConstraint(expr string) (*constraint, error) {
+	constraints.mu.Lock()
+	defer constraints.mu.Unlock()
+	if c := constraints.m[expr]; c != nil {
+		return c, nil
+	}
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if hasUnsupportedAssertion(re) {
+		return nil, fmt.Errorf("constraint %q: only ^, $, \\A and \\z assertions are supported", expr)
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	// expr parsed on its own, so it cannot escape the group.
+	anchored, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	c := &constraint{expr: expr, re: anchored, prog: prog}
+	r, _ := compareProgs(prog, nil)
+	c.example = r.only1
+	if constraints.m == nil {
+		constraints.m = make(map[string]*constraint)
+	}
+	constraints.m[expr] = c
+	return c, nil
+}
+
+// hasUnsupportedAssertion reports whether re contains a zero-width
+// assertion other than the beginning or end of the text.
+func hasUnsupportedAssertion(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	return slices.ContainsFunc(re.Sub, hasUnsupportedAssertion)
+}
+
+// match reports whether the constraint matches a path segment.
+func (c *constraint) match(seg string) bool {
+	return c.re.MatchString(seg)
+}
+
+// compareConstraints determines the relationship between the sets of
+// values matched by two wildcards' constraints. A nil constraint
+// matches every value.
+func compareConstraints(c1, c2 *constraint) relationship {
+	switch {
+	case c1 == c2:
+		return equivalent
+	case c1 == nil:
+		return moreGeneral
+	case c2 == nil:
+		return moreSpecific
+	}
+	return c1.compare(c2).rel
+}
+
+// compare returns the relationship of c to c2.
+func (c *constraint) compare(c2 *constraint) *constraintRel {
+	c.mu.Lock()
+	r := c.rels[c2]
+	c.mu.Unlock()
+	if r != nil {
+		return r
+	}
+	r, ok := compareProgs(c.prog, c2.prog)
+	if !ok {
+		// Too complex to compare; assume the worst.
+		r.rel = overlaps
+	}
+	c.mu.Lock()
+	if c.rels == nil {
+		c.rels = make(map[*constraint]*constraintRel)
+	}
+	c.rels[c2] = r
+	c.mu.Unlock()
+	return r
+}
+
+// Limits on the work done by compareProgs.
+const (
+	maxCompareStates = 1000
+	maxCompareSteps  = 1 << 20
+)
+
+// compareProgs determines how the sets of strings matched in their
+// entirety by p1 and p2 are related, by exploring the product of the
+// DFAs equivalent to the programs. A nil program matches nothing.
+// It reports false if the programs are too complex to compare.
+func compareProgs(p1, p2 *syntax.Prog) (*constraintRel, bool) {
+	reps := representativeRunes(p1, p2)
+	type state struct {
+		s1, s2 []uint32
+		prefix string
+	}
+	start := state{s1: startSet(p1), s2: startSet(p2)}
+	seen := map[string]bool{setKey(start.s1, start.s2): true}
+	queue := []state{start}
+	r := new(constraintRel)
+	var both, only1, only2 bool
+	addExample := func(example *string, s string) {
+		if *example == "" {
+			*example = s
+		}
+	}
+	steps := 0
+	for len(queue) > 0 {
+		st := queue[0]
+		queue = queue[1:]
+		atStart := st.prefix == ""
+		m1, m2 := accepts(p1, st.s1, atStart), accepts(p2, st.s2, atStart)
+		switch {
+		case m1 && m2:
+			both = true
+			addExample(&r.both, st.prefix)
+		case m1:
+			only1 = true
+			addExample(&r.only1, st.prefix)
+		case m2:
+			only2 = true
+			addExample(&r.only2, st.prefix)
+		}
+		if r.both != "" && r.only1 != "" && r.only2 != "" {
+			break
+		}
+		if p2 == nil && r.only1 != "" {
+			// Only looking for an example.
+			break
+		}
+		for _, c := range reps {
+			if steps++; steps > maxCompareSteps {
+				return r, false
+			}
+			next := state{
+				s1:     stepSet(p1, st.s1, c),
+				s2:     stepSet(p2, st.s2, c),
+				prefix: st.prefix + string(c),
+			}
+			if len(next.s1) == 0 && len(next.s2) == 0 {
+				continue
+			}
+			k := setKey(next.s1, next.s2)
+			if seen[k] {
+				continue
+			}
+			if len(seen) >= maxCompareStates {
+				return r, false
+			}
+			seen[k] = true
+			queue = append(queue, next)
+		}
+	}
+	switch {
+	case !both && (only1 || only2):
+		r.rel = disjoint
+	case !only1 && !only2:
+		r.rel = equivalent
+	case !only1:
+		r.rel = moreSpecific
+	case !only2:
+		r.rel = moreGeneral
+	default:
+		r.rel = overlaps
+	}
+	return r, true
+}
+
+// A program state set is a sorted list of the program counters of
+// instructions which consume a rune, match, or assert the end of the text.
+
+func startSet(p *syntax.Prog) []uint32 {
+	if p == nil {
+		return nil
+	}
+	return closure(p, []uint32{uint32(p.Start)}, true, false)
+}
+
+// closure returns the instructions reachable from pcs without consuming
+// input, given whether the input is at its start or end.
+func closure(p *syntax.Prog, pcs []uint32, atStart, atEnd bool) []uint32 {
+	var set []uint32
+	visited := make(map[uint32]bool)
+	stack := slices.Clone(pcs)
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[pc] {
+			continue
+		}
+		visited[pc] = true
+		inst := &p.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Out, inst.Arg)
+		case syntax.InstCapture, syntax.InstNop:
+			stack = append(stack, inst.Out)
+		case syntax.InstEmptyWidth:
+			op := syntax.EmptyOp(inst.Arg)
+			if (op&syntax.EmptyBeginText == 0 || atStart) && (op&syntax.EmptyEndText == 0 || atEnd) {
+				stack = append(stack, inst.Out)
+			} else if op&syntax.EmptyEndText != 0 && !atEnd {
+				// Resolved when the input ends.
+				set = append(set, pc)
+			}
+		case syntax.InstFail:
+		default:
+			set = append(set, pc)
+		}
+	}
+	slices.Sort(set)
+	return set
+}
+
+// accepts reports whether a program in state set matches at the end of the input.
+func accepts(p *syntax.Prog, set []uint32, atStart bool) bool {
+	if p == nil {
+		return false
+	}
+	for _, pc := range closure(p, set, atStart, true) {
+		if p.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// stepSet returns the state after consuming c in state set.
+func stepSet(p *syntax.Prog, set []uint32, c rune) []uint32 {
+	var next []uint32
+	for _, pc := range set {
+		inst := &p.Inst[pc]
+		switch inst.Op {
+		case syntax.InstRune, syntax.InstRune1:
+			if inst.MatchRunePos(c) >= 0 {
+				next = append(next, inst.Out)
+			}
+		case syntax.InstRuneAny:
+			next = append(next, inst.Out)
+		case syntax.InstRuneAnyNotNL:
+			if c != '\n' {
+				next = append(next, inst.Out)
+			}
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	return closure(p, next, false, false)
+}
+
+func setKey(s1, s2 []uint32) string {
+	var b strings.Builder
+	for _, pc := range s1 {
+		fmt.Fprintf(&b, "%d,", pc)
+	}
+	b.WriteByte('|')
+	for _, pc := range s2 {
+		fmt.Fprintf(&b, "%d,", pc)
+	}
+	return b.String()
+}
+
+// representativeRunes partitions the runes into ranges which every
+// instruction of the programs treats alike, and returns one rune from
+// each range, preferring printable ASCII runes for the sake of examples.
+func representativeRunes(progs ...*syntax.Prog) []rune {
+	bounds := []rune{0, '\n', '\n' + 1, surrogateMin, surrogateMax + 1, utf8.MaxRune + 1}
+	add := func(lo, hi rune) { bounds = append(bounds, lo, hi+1) }
+	for _, p := range progs {
+		if p == nil {
+			continue
+		}
+		for _, inst := range p.Inst {
+			if inst.Op != syntax.InstRune && inst.Op != syntax.InstRune1 {
+				continue
+			}
+			if len(inst.Rune) == 1 {
+				r := inst.Rune[0]
+				add(r, r)
+				if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+					for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+						add(f, f)
+					}
+				}
+				continue
+			}
+			for i := 0; i+1 < len(inst.Rune); i += 2 {
+				add(inst.Rune[i], inst.Rune[i+1])
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	var reps []rune
+	for i := 0; i+1 < len(bounds) && bounds[i] <= utf8.MaxRune; i++ {
+		lo, hi := bounds[i], bounds[i+1]-1
+		if lo == surrogateMin {
+			// Surrogates do not occur in strings.
+			continue
+		}
+		reps = append(reps, representative(lo, hi))
+	}
+	return reps
+}
+
+const (
+	surrogateMin = 0xD800
+	surrogateMax = 0xDFFF
+)
+
+func representative(lo, hi rune) rune {
+	for _, c := range "azAZ09-_.~" {
+		if lo <= c && c <= hi {
+			return c
+		}
+	}
+	if lo <= '~' && hi >= '!' {
+		return max(lo, '!')
+	}
+	if lo <= 0xA1 && hi >= 0xA1 {
+		return 0xA1
+	}
+	return lo
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompareConstraints(t *testing.T) {
+	for _, test := range []struct {
+		c1, c2 string
+		want   relationship
+	}{
+		{"int", "int", equivalent},
+		{"[0-9]+", "uint", equivalent},
+		{"[0-9]+", `\d+`, equivalent},
+		{"int", "uint", moreGeneral},
+		{"uint", "int", moreSpecific},
+		{"hex", "uint", moreGeneral},
+		{"alpha", "uint", disjoint},
+		{"alpha", "hex", overlaps},
+		{"[a-z]+", "(?i)[A-Z]+", moreSpecific},
+		{"^abc$", "(?:abc)", equivalent},
+		{`\Aa+\z`, "a+", equivalent},
+		{"a*", "a+", moreGeneral},
+		{"a|b", "b|c", overlaps},
+		{"x{2,3}", "x{3,4}", overlaps},
+		{"x{2}", "x{3}", disjoint},
+		{"uuid", "hex", disjoint},
+		{"[0-9A-Fa-f-]+", "uuid", moreGeneral},
+		{"[a-f-]+", "uuid", overlaps},
+		{".+", "alpha", moreGeneral},
+	} {
+		c1, err := lookupConstraint(test.c1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c2, err := lookupConstraint(test.c2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := compareConstraints(c1, c2); got != test.want {
+			t.Errorf("%q vs %q: got %s, want %s", test.c1, test.c2, got, test.want)
+		}
+	}
+}
+
+func TestCompileConstraintErrors(t *testing.T) {
+	for _, test := range []struct {
+		s       string
+		wantErr string
+	}{
+		{"", "empty constraint"},
+		{"nosuch", `unknown constraint "nosuch"`},
+		{"[a-", "missing closing ]"},
+		{`a\b`, "assertions are supported"},
+		{"(?m)^a", "assertions are supported"},
+	} {
+		_, err := lookupConstraint(test.s)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%q: got %v, want error containing %q", test.s, err, test.wantErr)
+		}
+	}
+}
+
+func TestRegisterPatternConstraint(t *testing.T) {
+	RegisterPatternConstraint("testSlug", "[a-z0-9-]+")
+	p, err := parsePattern("/posts/{s:testSlug}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := p.segments[1].c; c == nil || !c.match("a-1") || c.match("A") {
+		t.Errorf("got constraint %v, want testSlug", c)
+	}
+
+	for _, test := range []struct {
+		name, expr string
+		wantErr    string
+	}{
+		{"testSlug", "[a-z]+", "already registered"},
+		{"int", "[0-9]+", "already registered"},
+		{"has space", "a", "invalid constraint name"},
+		{"testBad", "(", "missing closing )"},
+	} {
+		func() {
+			defer func() {
+				if err := recover(); !strings.Contains(fmt.Sprint(err), test.wantErr) {
+					t.Errorf("RegisterPatternConstraint(%q, %q): panic %v, want %q",
+						test.name, test.expr, err, test.wantErr)
+				}
+			}()
+			RegisterPatternConstraint(test.name, test.expr)
+		}()
+	}
+}
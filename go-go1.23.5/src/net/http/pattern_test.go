@@ -25,6 +25,16 @@ func TestParsePattern(t *testing.T) {
 		return s
 	}
 
+	constrained := func(name, cons string) segment {
+		s := wild(name)
+		c, err := lookupConstraint(cons)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.c = c
+		return s
+	}
+
 	for _, test := range []struct {
 		in   string
 		want pattern
@@ -115,6 +125,15 @@ func TestParsePattern(t *testing.T) {
 			"DELETE    \texample.com/a/{foo12}/{$}",
 			pattern{method: "DELETE", host: "example.com", segments: []segment{lit("a"), wild("foo12"), lit("/")}},
 		},
+		// Constrained wildcards.
+		{
+			"/items/{id:int}/{slug:[a-z-]+}",
+			pattern{segments: []segment{lit("items"), constrained("id", "int"), constrained("slug", "[a-z-]+")}},
+		},
+		{
+			"/{year:[0-9]{4}}/",
+			pattern{segments: []segment{constrained("year", "[0-9]{4}"), multi("")}},
+		},
 	} {
 		got := mustParsePattern(t, test.in)
 		if !got.equal(&test.want) {
@@ -149,6 +168,12 @@ func TestParsePatternError(t *testing.T) {
 		{"{a}/b", "at offset 0: host contains '{' (missing initial '/'?)"},
 		{"/a/{x}/b/{x...}", "at offset 9: duplicate wildcard name"},
 		{"GET //", "at offset 4: non-CONNECT pattern with unclean path"},
+		{"/{x:}", "at offset 1: wildcard \"x\": empty constraint"},
+		{"/{x:integer}", "at offset 1: wildcard \"x\": unknown constraint \"integer\""},
+		{"/{x:[a-z}", "at offset 1: wildcard \"x\": error parsing regexp"},
+		{"/{x:\\bx}", "at offset 1: wildcard \"x\": constraint"},
+		{"/{x...:int}", "at offset 1: {...} wildcard cannot have a constraint"},
+		{"/{:int}", "at offset 1: empty wildcard"},
 	} {
 		_, err := parsePattern(test.in)
 		if err == nil || !strings.Contains(err.Error(), test.contains) {
@@ -332,6 +357,23 @@ func TestComparePaths(t *testing.T) {
 		{"/{z}/{$}", "/a/{x...}", overlaps},
 		{"/{z}/{$}", "/{z}/{x...}", moreSpecific},
 		{"/a/{z}/{$}", "/{z}/a/", overlaps},
+
+		// Constrained wildcards.
+		{"/{x:int}", "/{y}", moreSpecific},
+		{"/{x:int}", "/{y:int}", equivalent},
+		{"/{x:int}", "/{y:-?[0-9]+}", equivalent},
+		{"/{x:uint}", "/{y:int}", moreSpecific},
+		{"/{x:int}", "/{y:alpha}", disjoint},
+		{"/{x:hex}", "/{y:int}", overlaps},
+		{"/{x:int}", "/42", moreGeneral},
+		{"/{x:int}", "/new", disjoint},
+		{"/{x:int}", "/{$}", disjoint},
+		{"/{x:int}", "/", moreSpecific},
+		{"/{x:int}", "/{m...}", moreSpecific},
+		{"/{x:int}/a", "/{y}/{z}", moreSpecific},
+		{"/{x:int}/{z}", "/{y}/a", overlaps},
+		{"/{x:(?i)abc}", "/{y:ABC|aBc}", moreGeneral},
+		{"/{x:^a*$}", "/{y:a+}", moreGeneral},
 	} {
 		pat1 := mustParsePattern(t, test.p1)
 		pat2 := mustParsePattern(t, test.p2)
@@ -381,6 +423,12 @@ func TestConflictsWith(t *testing.T) {
 		{"GET /", "GET /foo", false},
 		{"GET /", "/foo", true},
 		{"GET /foo", "HEAD /", true},
+		{"/{x:int}", "/{y}", false},
+		{"/{x:int}", "/{y:int}", true},
+		{"/{x:int}", "/{y:alpha}", false},
+		{"/{x:int}", "/{y:hex}", true},
+		{"/{x:int}", "/new", false},
+		{"/{x:int}/{z}", "/{y}/a", true},
 	} {
 		pat1 := mustParsePattern(t, test.p1)
 		pat2 := mustParsePattern(t, test.p2)
@@ -429,6 +477,7 @@ func TestDescribeConflict(t *testing.T) {
 		{"/a", "GET /{x}", "matches more methods than GET /{x}, but has a more specific path pattern"},
 		{"GET /a", "HEAD /", "matches more methods than HEAD /, but has a more specific path pattern"},
 		{"POST /", "/a", "matches fewer methods than /a, but has a more general path pattern"},
+		{"/{x:int}", "/{y:hex}", `both match some paths, like "/0"`},
 	} {
 		got := describeConflict(mustParsePattern(t, test.p1), mustParsePattern(t, test.p2))
 		if !strings.Contains(got, test.want) {
@@ -453,6 +502,8 @@ func TestCommonPath(t *testing.T) {
 		{"/a/{x}/b/", "/{x}/c/{y...}", "/a/c/b/"},
 		{"/a/{x}/b/{$}", "/{x}/c/{y...}", "/a/c/b/"},
 		{"/a/{z}/{x...}", "/{z}/b/{y...}", "/a/b/"},
+		{"/{x:int}", "/{y:hex}", "/0"},
+		{"/{x:int}/{z}", "/{y}/a", "/0/a"},
 	} {
 		pat1 := mustParsePattern(t, test.p1)
 		pat2 := mustParsePattern(t, test.p2)
@@ -496,6 +547,12 @@ func TestDifferencePath(t *testing.T) {
 		{"/{x}/c/{y...}", "/a/{c}/b/", "/x/c/"},
 		{"/a/{x}/b/{$}", "/{x}/c/{y...}", "/a/x/b/"},
 		{"/{x}/c/{y...}", "/a/{x}/b/{$}", "/x/c/"},
+		{"/{x:int}", "/{y:hex}", "/-0"},
+		{"/{y:hex}", "/{x:int}", "/A"},
+		{"/{x:int}/{z}", "/{y}/a", "/0/z"},
+		{"/{y}/a", "/{x:int}/{z}", "/y/a"},
+		{"/{x:[ab]}/{z}", "/a/{y}", "/b/z"},
+		{"/{x:[ab]}/{z}", "/{y:[ac]}/a", "/b/z"},
 	} {
 		pat1 := mustParsePattern(t, test.p1)
 		pat2 := mustParsePattern(t, test.p2)
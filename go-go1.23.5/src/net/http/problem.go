@@ -0,0 +1,153 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Problem details for HTTP APIs. See RFC 9457.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// A Problem is a problem details object (RFC 9457), which describes
+// an error in a machine-readable form in the body of a response.
+//
+// A Problem is also an error, so that handlers and the functions they
+// call may return one.
+type Problem struct {
+	// Type is a URI reference which identifies the problem type.
+	// An empty Type is equivalent to "about:blank", which means the
+	// problem has no meaning beyond that of its status code.
+	Type string
+
+	// Title is a short, human-readable summary of the problem type.
+	// If Title and Type are empty, the status text for Status is used.
+	Title string
+
+	// Status is the HTTP status code of the response.
+	// If zero, 500 (Internal Server Error) is used.
+	Status int
+
+	// Detail is a human-readable explanation specific to this
+	// occurrence of the problem.
+	Detail string
+
+	// Instance is a URI reference which identifies this occurrence
+	// of the problem.
+	Instance string
+
+	// Extensions are additional members of the problem details object.
+	// Extensions whose names are those of the members above are ignored.
+	Extensions map[string]any
+}
+
+func (p *Problem) status() int {
+	if p.Status == 0 {
+		return StatusInternalServerError
+	}
+	return p.Status
+}
+
+func (p *Problem) Error() string {
+	s := p.Title
+	if s == "" {
+		s = StatusText(p.status())
+	}
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	return s
+}
+
+// MarshalJSON implements the [encoding/json.Marshaler] interface.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	set := func(name, v string) {
+		if v != "" {
+			m[name] = v
+		} else {
+			delete(m, name)
+		}
+	}
+	title := p.Title
+	if title == "" && p.Type == "" {
+		title = StatusText(p.status())
+	}
+	set("type", p.Type)
+	set("title", title)
+	set("detail", p.Detail)
+	set("instance", p.Instance)
+	m["status"] = p.status()
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements the [encoding/json.Unmarshaler] interface.
+// Members other than the standard ones are stored in p.Extensions.
+// Standard members of the wrong type are ignored, as RFC 9457 requires.
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	*p = Problem{}
+	str := func(name string) string {
+		s, _ := m[name].(string)
+		delete(m, name)
+		return s
+	}
+	p.Type = str("type")
+	p.Title = str("title")
+	p.Detail = str("detail")
+	p.Instance = str("instance")
+	if f, ok := m["status"].(float64); ok && f == float64(int(f)) {
+		p.Status = int(f)
+	}
+	delete(m, "status")
+	if len(m) > 0 {
+		p.Extensions = m
+	}
+	return nil
+}
+
+// ProblemFor returns a [Problem] describing err, for reporting it to a
+// client. If err is or wraps a *Problem, ProblemFor returns it. If err
+// wraps an error with a Problem method returning a *Problem, such as a
+// [*BindError] or [*MaxBytesError], ProblemFor returns its result.
+// Otherwise, it returns a Problem with status 500 (Internal Server
+// Error), which does not describe err, whose text may contain details
+// which should not be disclosed to clients.
+func ProblemFor(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	var pe interface{ Problem() *Problem }
+	if errors.As(err, &pe) {
+		return pe.Problem()
+	}
+	return &Problem{Status: StatusInternalServerError}
+}
+
+// WriteProblem replies to the request with p, in a response with
+// p's status code and the media type "application/problem+json".
+//
+// Like [Error], WriteProblem does not otherwise end the request;
+// the caller should ensure no further writes are done to w.
+func WriteProblem(w ResponseWriter, p *Problem) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		// An extension could not be marshaled.
+		b, _ = json.Marshal(&Problem{Type: p.Type, Title: p.Title, Status: p.Status, Detail: p.Detail, Instance: p.Instance})
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.status())
+	w.Write(append(b, '\n'))
+}
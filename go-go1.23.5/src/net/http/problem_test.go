@@ -0,0 +1,101 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblemJSON(t *testing.T) {
+	for _, test := range []struct {
+		p    *Problem
+		want string
+	}{
+		{&Problem{Status: 404}, `{"status":404,"title":"Not Found"}`},
+		{&Problem{}, `{"status":500,"title":"Internal Server Error"}`},
+		{&Problem{
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Status:     403,
+			Detail:     "Your current balance is 30, but that costs 50.",
+			Instance:   "/account/12345/msgs/abc",
+			Extensions: map[string]any{"balance": 30, "status": "ignored"},
+		}, `{"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc",` +
+			`"status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`},
+	} {
+		b, err := json.Marshal(test.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.want {
+			t.Errorf("Marshal(%+v):\ngot  %s\nwant %s", test.p, b, test.want)
+		}
+	}
+
+	var p Problem
+	if err := json.Unmarshal([]byte(`{"type": "about:blank", "status": 429, "title": 7, "retry": "later"}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{Type: "about:blank", Status: 429, Extensions: map[string]any{"retry": "later"}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Unmarshal: got %+v, want %+v", p, want)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Length", "1000")
+	WriteProblem(rec, &Problem{Status: 422, Detail: "bad thing"})
+	if rec.Code != 422 {
+		t.Errorf("got status %v, want 422", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("got Content-Length %q, want none", cl)
+	}
+	if got, want := rec.Body.String(), `{"detail":"bad thing","status":422,"title":"Unprocessable Entity"}`+"\n"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+
+	// A problem whose extensions cannot be marshaled is written without them.
+	rec = httptest.NewRecorder()
+	WriteProblem(rec, &Problem{Status: 400, Extensions: map[string]any{"bad": func() {}}})
+	if got, want := rec.Body.String(), `{"status":400,"title":"Bad Request"}`+"\n"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+}
+
+func TestProblemFor(t *testing.T) {
+	p := &Problem{Status: 409}
+	for _, test := range []struct {
+		err        error
+		wantStatus int
+	}{
+		{p, 409},
+		{fmt.Errorf("wrapped: %w", p), 409},
+		{&MaxBytesError{Limit: 10}, 413},
+		{&BindError{StatusCode: 415, Err: errors.New("x")}, 415},
+		{errors.New("secret internal failure"), 500},
+	} {
+		got := ProblemFor(test.err)
+		if got.Status != test.wantStatus {
+			t.Errorf("ProblemFor(%v).Status = %v, want %v", test.err, got.Status, test.wantStatus)
+		}
+		if test.wantStatus == 500 && got.Detail != "" {
+			t.Errorf("ProblemFor(%v) discloses %q", test.err, got.Detail)
+		}
+	}
+	if got := ProblemFor(p); got != p {
+		t.Errorf("ProblemFor(p) = %p, want %p", got, p)
+	}
+}
@@ -539,6 +539,16 @@ func (r *Request) isH2Upgrade() bool {
 	return r.Method == "PRI" && len(r.Header) == 0 && r.URL.Path == "*" && r.Proto == "HTTP/2.0"
 }
 
+// isH2CUpgrade reports whether r asks to upgrade the connection
+// to unencrypted HTTP/2 (RFC 7540, Section 3.2).
+func (r *Request) isH2CUpgrade() bool {
+	return r.ProtoAtLeast(1, 1) &&
+		httpguts.HeaderValuesContainsToken(r.Header["Upgrade"], "h2c") &&
+		httpguts.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade") &&
+		httpguts.HeaderValuesContainsToken(r.Header["Connection"], "HTTP2-Settings") &&
+		len(r.Header["Http2-Settings"]) == 1
+}
+
 // Return value if nonempty, def otherwise.
 func valueOrDefault(value, def string) string {
 	if value != "" {
@@ -1209,6 +1219,12 @@ func (e *MaxBytesError) Error() string {
 	return "http: request body too large"
 }
 
+// Problem returns a [Problem] describing the error, with status
+// 413 (Content Too Large).
+func (e *MaxBytesError) Problem() *Problem {
+	return &Problem{Status: StatusRequestEntityTooLarge, Detail: e.Error()}
+}
+
 type maxBytesReader struct {
 	w   ResponseWriter
 	r   io.ReadCloser // underlying reader
@@ -1482,6 +1498,21 @@ func (r *Request) PathValue(name string) string {
 	return r.otherValues[name]
 }
 
+// PathValueInt returns the value for the named path wildcard, as returned
+// by [Request.PathValue], interpreted as a decimal integer.
+// A wildcard with the "int" constraint, such as "{id:int}", matches
+// only values of that form, but they may be out of range.
+// The error, if any, is of type [*strconv.NumError].
+func (r *Request) PathValueInt(name string) (int64, error) {
+	return strconv.ParseInt(r.PathValue(name), 10, 64)
+}
+
+// PathValueUint is like [Request.PathValueInt], but for unsigned integers,
+// as matched by the "uint" constraint.
+func (r *Request) PathValueUint(name string) (uint64, error) {
+	return strconv.ParseUint(r.PathValue(name), 10, 64)
+}
+
 // SetPathValue sets name to value, so that subsequent calls to r.PathValue(name)
 // return value.
 func (r *Request) SetPathValue(name, value string) {
@@ -1589,3 +1620,10 @@ func (r *Request) requiresHTTP1() bool {
 	return hasToken(r.Header.Get("Connection"), "upgrade") &&
 		ascii.EqualFold(r.Header.Get("Upgrade"), "websocket")
 }
+
+// isExtendedConnect reports whether this is an HTTP/2 extended CONNECT
+// request (RFC 8441), which names the protocol to be tunneled in the
+// ":protocol" pseudo-header. Such requests can only be sent over HTTP/2.
+func (r *Request) isExtendedConnect() bool {
+	return r.Method == "CONNECT" && r.Header.has(":protocol")
+}
@@ -1616,6 +1616,37 @@ func TestSetPathValue(t *testing.T) {
 	res.Body.Close()
 }
 
+func TestPathValueConstraints(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/items/{id:int}", func(w ResponseWriter, r *Request) {
+		id, err := r.PathValueInt("id")
+		fmt.Fprintf(w, "int %d %v", id, err)
+	})
+	mux.HandleFunc("/items/{slug:[a-z-]+}", func(w ResponseWriter, r *Request) {
+		fmt.Fprintf(w, "slug %s", r.PathValue("slug"))
+	})
+	mux.HandleFunc("/items/{other}", func(w ResponseWriter, r *Request) {
+		_, err := r.PathValueUint("other")
+		fmt.Fprintf(w, "other %v", err != nil)
+	})
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/items/42", "int 42 <nil>"},
+		{"/items/-7", "int -7 <nil>"},
+		{"/items/99999999999999999999", `int 9223372036854775807 strconv.ParseInt: parsing "99999999999999999999": value out of range`},
+		{"/items/hello-world", "slug hello-world"},
+		{"/items/Hello", "other true"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
 func TestStatus(t *testing.T) {
 	// The main purpose of this test is to check 405 responses and the Allow header.
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
@@ -0,0 +1,218 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"io"
+	"math/rand"
+	"net/http/httptrace"
+	"strconv"
+	"time"
+)
+
+// A RetryPolicy controls how a [Client] retries requests.
+//
+// A request is retried only if it can be sent again safely:
+// its method must be idempotent (GET, HEAD, OPTIONS, TRACE, PUT, or
+// DELETE), or it must have an Idempotency-Key or X-Idempotency-Key
+// header, and it must have no body or a body which can be recreated
+// with [Request.GetBody].
+//
+// By default, a request is retried when the [RoundTripper] returns an
+// error other than cancellation of the request's context or expiry of
+// [Client.Timeout], and when the response status is 429 (Too Many
+// Requests) or 503 (Service Unavailable). The body of a response
+// which is retried is discarded.
+//
+// Before each retry, the Client waits for the delay given by the
+// response's Retry-After header, if any, or else for a randomized,
+// exponentially increasing backoff delay. It does not retry if the
+// delay would exceed MaxBackoff or the Client's Timeout.
+//
+// Retries of each request in a chain of redirects are counted
+// separately. Each retry is reported to the [httptrace.ClientTrace.Retry]
+// hook of the request's context.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt.
+	// If zero, a default of 3 is used.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry when the response
+	// has no Retry-After header. The delay doubles with each subsequent
+	// retry, up to MaxBackoff. Each delay is chosen randomly between
+	// half and all of its nominal value.
+	// If zero, a default of 100ms is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum delay before a retry.
+	// If zero, a default of 10s is used.
+	MaxBackoff time.Duration
+
+	// ShouldRetry optionally replaces the default choice of which
+	// responses and errors to retry. It is called with the response
+	// or error from each attempt, exactly one of which is non-nil,
+	// and reports whether to retry the request.
+	// ShouldRetry is not called for requests which cannot be retried safely.
+	ShouldRetry func(resp *Response, err error) bool
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return 3
+}
+
+func (p *RetryPolicy) minBackoff() time.Duration {
+	if p.MinBackoff > 0 {
+		return p.MinBackoff
+	}
+	return 100 * time.Millisecond
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return 10 * time.Second
+}
+
+func (p *RetryPolicy) shouldRetry(resp *Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == StatusTooManyRequests || resp.StatusCode == StatusServiceUnavailable
+}
+
+// backoff returns the delay before the retry which follows
+// attempt number n, counting from 1.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d, limit := p.minBackoff(), p.maxBackoff()
+	for ; n > 1 && d < limit; n-- {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter returns the delay requested by resp's Retry-After header.
+func retryAfter(resp *Response, now time.Time) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 || secs > int64(maxRetryAfter/time.Second) {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// maxRetryAfter bounds the delay-seconds in a Retry-After header
+// to avoid overflowing a time.Duration.
+const maxRetryAfter = 100 * 365 * 24 * time.Hour
+
+// isRetryable reports whether req may be sent more than once: its
+// method is idempotent as defined by RFC 9110, Section 9.2.2, or it
+// has an Idempotency-Key header, and its body can be sent again.
+func isRetryable(req *Request) bool {
+	if req.Body != nil && req.Body != NoBody && req.GetBody == nil {
+		return false
+	}
+	switch valueOrDefault(req.Method, "GET") {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.has("Idempotency-Key") || req.Header.has("X-Idempotency-Key")
+}
+
+// send sends req using c, retrying according to the policy.
+// Like Client.send, it always closes req.Body.
+func (p *RetryPolicy) send(c *Client, ireq *Request, deadline time.Time) (resp *Response, didTimeout func() bool, err error) {
+	ctx := ireq.Context()
+	trace := httptrace.ContextClientTrace(ctx)
+	// Each attempt adds the Jar's cookies to its request's header,
+	// so retries start from a copy of the original header.
+	header := ireq.Header.Clone()
+	req := ireq
+	for attempt := 1; ; attempt++ {
+		resp, didTimeout, err = c.sendOnce(req, deadline)
+		if attempt >= p.maxAttempts() || !isRetryable(ireq) {
+			return resp, didTimeout, err
+		}
+		if err != nil && (didTimeout() || ctx.Err() != nil) {
+			return resp, didTimeout, err
+		}
+		if !p.shouldRetry(resp, err) {
+			return resp, didTimeout, err
+		}
+
+		now := time.Now()
+		delay, ok := time.Duration(0), false
+		if resp != nil {
+			delay, ok = retryAfter(resp, now)
+		}
+		if !ok {
+			delay = p.backoff(attempt)
+		}
+		if delay > p.maxBackoff() || (!deadline.IsZero() && now.Add(delay).After(deadline)) {
+			return resp, didTimeout, err
+		}
+
+		req = new(Request)
+		*req = *ireq
+		req.Header = header.Clone()
+		if ireq.Body != nil && ireq.Body != NoBody {
+			body, gerr := ireq.GetBody()
+			if gerr != nil {
+				return resp, didTimeout, err
+			}
+			req.Body = body
+		}
+
+		info := httptrace.RetryInfo{
+			Attempt: attempt + 1,
+			Delay:   delay,
+			Err:     err,
+		}
+		if resp != nil {
+			info.StatusCode = resp.StatusCode
+			// Read some of the body, as in Client.do, so a small
+			// response doesn't prevent the connection's reuse.
+			const maxBodySlurpSize = 2 << 10
+			if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
+				io.CopyN(io.Discard, resp.Body, maxBodySlurpSize)
+			}
+			resp.Body.Close()
+		}
+		if trace != nil && trace.Retry != nil {
+			trace.Retry(info)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			req.closeBody()
+			return nil, alwaysFalse, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	. "net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetry(t *testing.T) { run(t, testClientRetry) }
+func testClientRetry(t *testing.T, mode testMode) {
+	var calls atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "request body" {
+			t.Errorf("server got body %q, want %q", body, "request body")
+		}
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(StatusServiceUnavailable)
+			io.WriteString(w, "try again")
+		case 2:
+			w.WriteHeader(StatusTooManyRequests)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	c := cst.c
+	c.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+
+	var retries []httptrace.RetryInfo
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		Retry: func(info httptrace.RetryInfo) {
+			retries = append(retries, info)
+		},
+	})
+	req, _ := NewRequestWithContext(ctx, "PUT", cst.ts.URL, strings.NewReader("request body"))
+	req.Header.Set("Idempotency-Key", "abc")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(body) != "ok" {
+		t.Errorf("got %v %q, want 200 %q", res.StatusCode, body, "ok")
+	}
+	if len(retries) != 2 {
+		t.Fatalf("Retry hook called %v times, want 2", len(retries))
+	}
+	if r := retries[0]; r.Attempt != 2 || r.StatusCode != 503 || r.Delay != 0 || r.Err != nil {
+		t.Errorf("first retry = %+v, want Attempt 2, StatusCode 503, Delay 0", r)
+	}
+	if r := retries[1]; r.Attempt != 3 || r.StatusCode != 429 || r.Delay < time.Millisecond || r.Delay > 2*time.Millisecond {
+		t.Errorf("second retry = %+v, want Attempt 3, StatusCode 429, Delay in [1ms, 2ms]", r)
+	}
+}
+
+func TestClientRetryMaxAttempts(t *testing.T) { run(t, testClientRetryMaxAttempts) }
+func testClientRetryMaxAttempts(t *testing.T, mode testMode) {
+	var calls atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		calls.Add(1)
+		w.WriteHeader(StatusServiceUnavailable)
+	}))
+	c := cst.c
+	c.Retry = &RetryPolicy{MaxAttempts: 4, MinBackoff: time.Microsecond}
+	res, err := c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 503 || calls.Load() != 4 {
+		t.Errorf("got status %v after %v calls, want 503 after 4", res.StatusCode, calls.Load())
+	}
+}
+
+func TestClientRetryNotReplayable(t *testing.T) { run(t, testClientRetryNotReplayable) }
+func testClientRetryNotReplayable(t *testing.T, mode testMode) {
+	var calls atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		calls.Add(1)
+		w.WriteHeader(StatusServiceUnavailable)
+	}))
+	c := cst.c
+	c.Retry = &RetryPolicy{MinBackoff: time.Microsecond}
+	for _, test := range []struct {
+		name string
+		req  func() *Request
+	}{{
+		name: "POST",
+		req: func() *Request {
+			req, _ := NewRequest("POST", cst.ts.URL, strings.NewReader("body"))
+			return req
+		},
+	}, {
+		name: "no GetBody",
+		req: func() *Request {
+			req, _ := NewRequest("PUT", cst.ts.URL, io.NopCloser(strings.NewReader("body")))
+			req.Header.Set("Idempotency-Key", "abc")
+			return req
+		},
+	}} {
+		calls.Store(0)
+		res, err := c.Do(test.req())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		res.Body.Close()
+		if n := calls.Load(); n != 1 {
+			t.Errorf("%v: request sent %v times, want 1", test.name, n)
+		}
+	}
+}
+
+func TestClientRetryJar(t *testing.T) { run(t, testClientRetryJar) }
+func testClientRetryJar(t *testing.T, mode testMode) {
+	var (
+		calls   atomic.Int32
+		cookies []string
+	)
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(StatusServiceUnavailable)
+		}
+	}))
+	c := cst.c
+	c.Jar, _ = cookiejar.New(nil)
+	u, _ := url.Parse(cst.ts.URL)
+	c.Jar.SetCookies(u, []*Cookie{{Name: "a", Value: "1"}})
+	c.Retry = &RetryPolicy{MinBackoff: time.Microsecond}
+
+	// PUT is idempotent, and so is retried without an Idempotency-Key.
+	req, _ := NewRequest("PUT", cst.ts.URL, strings.NewReader("body"))
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if want := []string{"a=1", "a=1", "a=1"}; !slices.Equal(cookies, want) {
+		t.Errorf("server got cookies %q, want %q", cookies, want)
+	}
+}
+
+func TestClientRetryAfterTooLong(t *testing.T) { run(t, testClientRetryAfterTooLong) }
+func testClientRetryAfterTooLong(t *testing.T, mode testMode) {
+	var calls atomic.Int32
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(TimeFormat))
+		w.WriteHeader(StatusTooManyRequests)
+	}))
+	c := cst.c
+	c.Retry = &RetryPolicy{}
+	res, err := c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 429 || calls.Load() != 1 {
+		t.Errorf("got status %v after %v calls, want 429 after 1", res.StatusCode, calls.Load())
+	}
+}
+
+type retryTestTransport func(*Request) (*Response, error)
+
+func (f retryTestTransport) RoundTrip(req *Request) (*Response, error) { return f(req) }
+
+func TestClientRetryTransportError(t *testing.T) {
+	errTransient := errors.New("transient")
+	var calls int
+	c := &Client{
+		Retry: &RetryPolicy{MinBackoff: time.Microsecond},
+		Transport: retryTestTransport(func(req *Request) (*Response, error) {
+			calls++
+			if calls < 3 {
+				return nil, errTransient
+			}
+			return &Response{StatusCode: 200, Body: NoBody, Request: req}, nil
+		}),
+	}
+	var errs []error
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		Retry: func(info httptrace.RetryInfo) {
+			errs = append(errs, info.Err)
+		},
+	})
+	req, _ := NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if calls != 3 || len(errs) != 2 || errs[0] != errTransient || errs[1] != errTransient {
+		t.Errorf("got %v calls, retry errors %v; want 3 calls, 2 transient errors", calls, errs)
+	}
+
+	// ShouldRetry can refuse to retry an error.
+	calls = 0
+	c.Retry.ShouldRetry = func(res *Response, err error) bool {
+		return err != errTransient
+	}
+	if _, err := c.Get("http://example.com/"); !errors.Is(err, errTransient) {
+		t.Errorf("Get = %v, want %v", err, errTransient)
+	}
+	if calls != 1 {
+		t.Errorf("got %v calls, want 1", calls)
+	}
+}
+
+func TestClientRetryCanceledDuringDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	c := ts.Client()
+	c.Retry = &RetryPolicy{MaxBackoff: time.Minute}
+	req, _ := NewRequestWithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Retry: func(httptrace.RetryInfo) { cancel() },
+	}), "GET", ts.URL, nil)
+	start := time.Now()
+	_, err := c.Do(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 4*time.Second {
+		t.Errorf("Do returned after %v, want prompt return on cancellation", d)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Mounting, introspection and reverse routing for ServeMux.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// A route is a pattern registered with a ServeMux, and its handler.
+type route struct {
+	pat     *pattern
+	handler Handler
+}
+
+// A mountPoint records that a ServeMux is mounted in parent.
+type mountPoint struct {
+	parent     *ServeMux
+	host       string // host of the prefix, or ""
+	path       string // path of the prefix, without a trailing slash
+	middleware []func(Handler) Handler
+}
+
+// Mount registers each pattern of sub with mux, with prefix prepended
+// to its path, and arranges for patterns later registered with sub to
+// be registered with mux as well. Requests matching a mounted pattern
+// are served by sub's handler for it, wrapped in middleware: the first
+// middleware is the outermost, and sees the request first.
+//
+// The prefix has the form "[HOST]/[PATH]", using the syntax of a
+// pattern without a method. Its path may contain "{name}" wildcards,
+// but not "{$}" or "{name...}", and a trailing slash is ignored.
+// A prefix of "/" mounts sub's patterns unchanged, which makes Mount
+// a way to apply middleware to a group of patterns. If the prefix has
+// a host, sub's patterns must not.
+//
+// For example, after
+//
+//	api := http.NewServeMux()
+//	api.HandleFunc("GET /items/{id}", getItem)
+//	mux.Mount("/api/v1", api, requireAuth)
+//
+// mux has the pattern "GET /api/v1/items/{id}", whose handler is
+// requireAuth(http.HandlerFunc(getItem)). A mounted pattern is
+// matched like any other, and the request's [Request.Pattern] and
+// [Request.PathValue] reflect the full pattern, including the prefix.
+//
+// The middleware is called when each pattern is mounted, without any
+// lock held, so it may itself register or mount patterns. It may be
+// called again for the same pattern if patterns are registered or
+// mounted concurrently.
+//
+// Mount panics if the prefix is invalid, if sub is mux or has mux
+// mounted in it, or if a mounted pattern conflicts with one already
+// registered with mux.
+func (mux *ServeMux) Mount(prefix string, sub *ServeMux, middleware ...func(Handler) Handler) {
+	if err := mux.mountErr(prefix, sub, middleware); err != nil {
+		panic(err)
+	}
+}
+
+func (mux *ServeMux) mountErr(prefix string, sub *ServeMux, middleware []func(Handler) Handler) error {
+	if use121 {
+		return errors.New("http: ServeMux.Mount is not supported with GODEBUG httpmuxgo121=1")
+	}
+	if sub == nil {
+		return errors.New("http: nil ServeMux")
+	}
+	host, path, err := parseMountPrefix(prefix)
+	if err != nil {
+		return fmt.Errorf("parsing mount prefix %q: %w", prefix, err)
+	}
+	m := &mountPoint{
+		parent:     mux,
+		host:       host,
+		path:       path,
+		middleware: slices.Clone(middleware),
+	}
+	// Mount nothing unless every pattern of sub can be mounted.
+	plan := func() ([]registration, error) {
+		if sub.isAncestorOf(mux) {
+			return nil, fmt.Errorf("http: mounting at %q would create a cycle", prefix)
+		}
+		var regs []registration
+		for _, r := range sub.routes {
+			joined, err := m.join(r.pat)
+			if err != nil {
+				return nil, err
+			}
+			regs, err = registrations(regs, registration{mux: mux, pat: joined, handler: r.handler, from: -1, mount: m})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return regs, nil
+	}
+	return register(plan, func() {
+		sub.mu.Lock()
+		sub.mounts = append(sub.mounts, m)
+		sub.mu.Unlock()
+	})
+}
+
+// parseMountPrefix validates a Mount prefix,
+// and returns its host and its path without a trailing slash.
+func parseMountPrefix(prefix string) (host, path string, err error) {
+	p, err := parsePattern(prefix)
+	if err != nil {
+		return "", "", err
+	}
+	if p.method != "" {
+		return "", "", errors.New("prefix has a method")
+	}
+	path = prefix[len(p.host):]
+	if cleanPath(path) != path {
+		return "", "", errors.New("unclean path")
+	}
+	segs := p.segments
+	if last := segs[len(segs)-1]; last.multi && last.s == "" {
+		// Trailing slash.
+		segs = segs[:len(segs)-1]
+		path = path[:len(path)-1]
+	}
+	for _, seg := range segs {
+		if seg.multi || (!seg.wild && seg.s == "/") {
+			return "", "", errors.New("prefix contains {$} or {...} wildcard")
+		}
+	}
+	return p.host, path, nil
+}
+
+// isAncestorOf reports whether mux is m,
+// or m is mounted, directly or indirectly, in mux.
+// registerMu must be held.
+func (mux *ServeMux) isAncestorOf(m *ServeMux) bool {
+	if mux == m {
+		return true
+	}
+	for _, mp := range m.mounts {
+		if mux.isAncestorOf(mp.parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// join returns the pattern with which a pattern of the mounted mux
+// is registered with the parent.
+func (m *mountPoint) join(pat *pattern) (*pattern, error) {
+	if pat.host != "" && m.host != "" {
+		return nil, fmt.Errorf("http: pattern %q (registered at %s) has a host, but is mounted at %q",
+			pat, pat.loc, m.host+m.path)
+	}
+	s := m.host + pat.host + m.path + pat.path()
+	if pat.method != "" {
+		s = pat.method + " " + s
+	}
+	joined, err := parsePattern(s)
+	if err != nil {
+		return nil, fmt.Errorf("mounting %q (registered at %s) at %q: %w", pat, pat.loc, m.host+m.path, err)
+	}
+	joined.loc = pat.loc
+	return joined, nil
+}
+
+// wrap returns h wrapped in m's middleware.
+func (m *mountPoint) wrap(h Handler) Handler {
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		h = m.middleware[i](h)
+	}
+	return h
+}
+
+// Routes returns an iterator over the patterns registered with mux
+// and their handlers, in the order they were registered. It includes
+// the patterns mounted with [ServeMux.Mount], with their prefixes;
+// their handlers are wrapped in the mount's middleware.
+//
+// The patterns may be passed to [ServeMux.BuildURL].
+func (mux *ServeMux) Routes() iter.Seq2[string, Handler] {
+	return func(yield func(string, Handler) bool) {
+		if use121 {
+			for _, e := range mux.mux121.entries() {
+				if !yield(e.pattern, e.h) {
+					return
+				}
+			}
+			return
+		}
+		// Routes are only appended, so a snapshot of the slice
+		// is unaffected by later registrations.
+		mux.mu.RLock()
+		routes := mux.routes[:len(mux.routes):len(mux.routes)]
+		mux.mu.RUnlock()
+		for _, r := range routes {
+			if !yield(r.pat.str, r.handler) {
+				return
+			}
+		}
+	}
+}
+
+// entries returns the registered patterns, sorted.
+func (mux *serveMux121) entries() []muxEntry {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	es := make([]muxEntry, 0, len(mux.m))
+	for _, e := range mux.m {
+		es = append(es, e)
+	}
+	slices.SortFunc(es, func(a, b muxEntry) int { return strings.Compare(a.pattern, b.pattern) })
+	return es
+}
+
+// BuildURL returns a URL whose path matches the registered pattern,
+// with each wildcard in the pattern replaced by its value in values.
+// The pattern must be identical to the string it was registered with,
+// or, for a mounted pattern, to the string reported by [ServeMux.Routes].
+//
+// Values are escaped as needed. A "{name}" wildcard matches a single
+// path segment, so its value must not be empty; a "{name...}" wildcard
+// matches the rest of the path, so slashes in its value separate
+// segments. The values must be exactly those of the pattern's wildcards.
+//
+// The URL's Host is the pattern's host, if any; it has no Scheme.
+//
+// For example, for the pattern "GET /users/{id}/files/{path...}" and the
+// values {"id": "a b", "path": "x/y"}, the URL is "/users/a%20b/files/x/y".
+func (mux *ServeMux) BuildURL(pattern string, values map[string]string) (*url.URL, error) {
+	if use121 {
+		return nil, errors.New("http: ServeMux.BuildURL is not supported with GODEBUG httpmuxgo121=1")
+	}
+	mux.mu.RLock()
+	var r route
+	i, ok := mux.byString[pattern]
+	if ok {
+		r = mux.routes[i]
+	}
+	mux.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("http: pattern %q is not registered", pattern)
+	}
+	pat := r.pat
+	path, err := pat.buildPath(values)
+	if err != nil {
+		return nil, fmt.Errorf("http: building URL for %q: %w", pattern, err)
+	}
+	u := &url.URL{Host: pat.host}
+	u.Path, _ = url.PathUnescape(path)
+	if u.EscapedPath() != path {
+		u.RawPath = path
+	}
+	return u, nil
+}
+
+// buildPath returns an escaped path which matches p,
+// with the given values for its wildcards.
+func (p *pattern) buildPath(values map[string]string) (string, error) {
+	var b strings.Builder
+	used := 0
+	for _, seg := range p.segments {
+		b.WriteByte('/')
+		switch {
+		case !seg.wild && seg.s == "/":
+			// {$}: the slash just written is the trailing slash.
+		case !seg.wild:
+			b.WriteString(url.PathEscape(seg.s))
+		case seg.s == "":
+			// Anonymous "..." wildcard from a trailing slash.
+		default:
+			v, ok := values[seg.s]
+			if !ok || (v == "" && !seg.multi) {
+				return "", fmt.Errorf("missing value for wildcard %q", seg.s)
+			}
+			if seg.c != nil && !seg.c.match(v) {
+				return "", fmt.Errorf("value %q for wildcard %q does not satisfy its constraint", v, seg.s)
+			}
+			used++
+			if !seg.multi {
+				b.WriteString(url.PathEscape(v))
+				break
+			}
+			for i, part := range strings.Split(v, "/") {
+				if i > 0 {
+					b.WriteByte('/')
+				}
+				b.WriteString(url.PathEscape(part))
+			}
+		}
+	}
+	if used != len(values) {
+		for name := range values {
+			if !p.hasWildcard(name) {
+				return "", fmt.Errorf("no wildcard %q", name)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// hasWildcard reports whether p has a wildcard with the given name.
+func (p *pattern) hasWildcard(name string) bool {
+	for _, seg := range p.segments {
+		if seg.wild && seg.s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the path of p, as written.
+func (p *pattern) path() string {
+	s := p.str
+	if p.method != "" {
+		s = strings.TrimLeft(s[len(p.method):], " \t")
+	}
+	return s[len(p.host):]
+}
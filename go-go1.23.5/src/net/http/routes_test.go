@@ -0,0 +1,295 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"fmt"
+	"io"
+	. "net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func tracing(name string) func(Handler) Handler {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			w.Header().Add("Trace", name)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestServeMuxMount(t *testing.T) {
+	describe := func(w ResponseWriter, r *Request) {
+		fmt.Fprintf(w, "%s ver=%s id=%s", r.Pattern, r.PathValue("ver"), r.PathValue("id"))
+	}
+	sub := NewServeMux()
+	sub.HandleFunc("GET /items/{id}", describe)
+	parent := NewServeMux()
+	parent.HandleFunc("/other/", describe)
+	parent.Mount("/api/{ver}/", sub, tracing("a"), tracing("b"))
+	// Patterns registered after mounting are mounted too.
+	sub.HandleFunc("POST /items", describe)
+	root := NewServeMux()
+	root.Mount("/x", parent, tracing("root"))
+
+	for _, test := range []struct {
+		mux        *ServeMux
+		method     string
+		path       string
+		wantCode   int
+		wantBody   string
+		wantTrace  []string
+		wantHeader string // Allow or Location
+	}{
+		{parent, "GET", "/api/v1/items/7", 200, "GET /api/{ver}/items/{id} ver=v1 id=7", []string{"a", "b"}, ""},
+		{parent, "POST", "/api/v2/items", 200, "POST /api/{ver}/items ver=v2 id=", []string{"a", "b"}, ""},
+		{parent, "GET", "/api/v1/items", 405, "", nil, "POST"},
+		{parent, "GET", "/other/z", 200, "/other/ ver= id=", nil, ""},
+		{sub, "GET", "/items/7", 200, "GET /items/{id} ver= id=7", nil, ""},
+		{root, "GET", "/x/api/v1/items/7", 200, "GET /x/api/{ver}/items/{id} ver=v1 id=7", []string{"root", "a", "b"}, ""},
+		{root, "GET", "/x/other", 301, "", nil, "/x/other/"},
+		{root, "GET", "/x/other/", 200, "/x/other/ ver= id=", []string{"root"}, ""},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		w := httptest.NewRecorder()
+		test.mux.ServeHTTP(w, r)
+		name := test.method + " " + test.path
+		if w.Code != test.wantCode {
+			t.Errorf("%s: code = %v, want %v", name, w.Code, test.wantCode)
+			continue
+		}
+		if test.wantBody != "" {
+			if got := w.Body.String(); got != test.wantBody {
+				t.Errorf("%s: body = %q, want %q", name, got, test.wantBody)
+			}
+		}
+		if got := w.Header()["Trace"]; !slices.Equal(got, test.wantTrace) {
+			t.Errorf("%s: middleware = %q, want %q", name, got, test.wantTrace)
+		}
+		if test.wantHeader != "" {
+			got := w.Header().Get("Allow") + w.Header().Get("Location")
+			if got != test.wantHeader {
+				t.Errorf("%s: Allow or Location = %q, want %q", name, got, test.wantHeader)
+			}
+		}
+	}
+}
+
+func TestServeMuxMountHost(t *testing.T) {
+	sub := NewServeMux()
+	sub.HandleFunc("/{$}", func(w ResponseWriter, r *Request) { io.WriteString(w, r.Pattern) })
+	mux := NewServeMux()
+	mux.Mount("example.com/", sub)
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if got, want := w.Body.String(), "example.com/{$}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestServeMuxMountErrors(t *testing.T) {
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	for _, test := range []struct {
+		name    string
+		prefix  string
+		setup   func(mux, sub *ServeMux)
+		wantErr string
+	}{
+		{"method", "GET /a", nil, "prefix has a method"},
+		{"dollar", "/a/{$}", nil, "{$} or {...}"},
+		{"multi", "/a/{x...}", nil, "{$} or {...}"},
+		{"unclean", "/a/../b", nil, "unclean path"},
+		{"bad", "/{x", nil, "bad wildcard"},
+		{"cycle", "/a", func(mux, sub *ServeMux) { sub.Mount("/b", mux) }, "cycle"},
+		{"hosts", "example.com/a", func(mux, sub *ServeMux) { sub.Handle("example.org/", h) }, "has a host"},
+		{"duplicate wildcard", "/{x}", func(mux, sub *ServeMux) { sub.Handle("/{x}", h) }, "duplicate wildcard"},
+		{"conflict", "/a", func(mux, sub *ServeMux) {
+			mux.Handle("/a/b", h)
+			sub.Handle("/b", h)
+		}, "conflicts with"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mux := NewServeMux()
+			sub := NewServeMux()
+			if test.setup != nil {
+				test.setup(mux, sub)
+			}
+			defer func() {
+				err := recover()
+				if err == nil {
+					t.Fatal("Mount did not panic")
+				}
+				if s := fmt.Sprint(err); !strings.Contains(s, test.wantErr) {
+					t.Errorf("panic %q, want it to contain %q", s, test.wantErr)
+				}
+			}()
+			mux.Mount(test.prefix, sub)
+		})
+	}
+	t.Run("self", func(t *testing.T) {
+		defer func() {
+			if err := recover(); !strings.Contains(fmt.Sprint(err), "cycle") {
+				t.Errorf("panic %v, want cycle error", err)
+			}
+		}()
+		mux := NewServeMux()
+		mux.Mount("/a", mux)
+	})
+}
+
+func TestServeMuxMountConflictRegistersNothing(t *testing.T) {
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	patterns := func(mux *ServeMux) []string {
+		var s []string
+		for pat := range mux.Routes() {
+			s = append(s, pat)
+		}
+		return s
+	}
+	mustPanic := func(f func()) {
+		t.Helper()
+		defer func() {
+			if err := recover(); !strings.Contains(fmt.Sprint(err), "conflicts with") {
+				t.Errorf("panic %v, want conflict", err)
+			}
+		}()
+		f()
+	}
+
+	// A pattern which conflicts in a parent is not
+	// registered with the mounted mux, or any parent.
+	root := NewServeMux()
+	root.Handle("/x/a/b", h)
+	parent := NewServeMux()
+	sub := NewServeMux()
+	parent.Mount("/a", sub)
+	root.Mount("/x", parent)
+	mustPanic(func() { sub.Handle("/b", h) })
+	if got := patterns(sub); len(got) != 0 {
+		t.Errorf("sub has patterns %q after conflict, want none", got)
+	}
+	if got := patterns(parent); len(got) != 0 {
+		t.Errorf("parent has patterns %q after conflict, want none", got)
+	}
+
+	// A mux whose patterns conflict is not mounted at all.
+	mux := NewServeMux()
+	mux.Handle("/a/b", h)
+	sub = NewServeMux()
+	sub.Handle("/a", h)
+	sub.Handle("/b", h)
+	mustPanic(func() { mux.Mount("/a", sub) })
+	sub.Handle("/c", h)
+	if got, want := patterns(mux), []string{"/a/b"}; !slices.Equal(got, want) {
+		t.Errorf("mux has patterns %q after failed Mount, want %q", got, want)
+	}
+}
+
+func TestServeMuxMountMiddlewareRegisters(t *testing.T) {
+	// Middleware may itself register and mount patterns.
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	other := NewServeMux()
+	n := 0
+	mw := func(next Handler) Handler {
+		n++
+		other.Handle(fmt.Sprintf("/wrapped/%d", n), h)
+		other.Mount(fmt.Sprintf("/sub/%d", n), NewServeMux())
+		return next
+	}
+	sub := NewServeMux()
+	sub.Handle("/a", h)
+	mux := NewServeMux()
+	mux.Mount("/x", sub, mw)
+	sub.Handle("/b", h)
+
+	var got []string
+	for pat := range mux.Routes() {
+		got = append(got, pat)
+	}
+	if want := []string{"/x/a", "/x/b"}; !slices.Equal(got, want) {
+		t.Errorf("mux has patterns %q, want %q", got, want)
+	}
+	if n != 2 {
+		t.Errorf("middleware called %d times, want 2", n)
+	}
+}
+
+func TestServeMuxRoutes(t *testing.T) {
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	sub := NewServeMux()
+	sub.Handle("GET /b/{id}", h)
+	mux := NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("POST example.com/a", h)
+	mux.Mount("/sub", sub)
+	sub.Handle("/c/", h)
+
+	var got []string
+	for pattern, handler := range mux.Routes() {
+		if handler == nil {
+			t.Errorf("%q: nil handler", pattern)
+		}
+		got = append(got, pattern)
+	}
+	want := []string{"/", "POST example.com/a", "GET /sub/b/{id}", "/sub/c/"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestServeMuxBuildURL(t *testing.T) {
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	mux := NewServeMux()
+	for _, test := range []struct {
+		pattern string
+		values  map[string]string
+		want    string // URL, or error substring prefixed with "error: "
+	}{
+		{"/", nil, "/"},
+		{"GET /a/b", nil, "/a/b"},
+		{"/users/{id}", map[string]string{"id": "42"}, "/users/42"},
+		{"/escape/{id}", map[string]string{"id": "a b/c"}, "/escape/a%20b%2Fc"},
+		{"/files/{path...}", map[string]string{"path": "x/y z"}, "/files/x/y%20z"},
+		{"/rest/{path...}", map[string]string{"path": ""}, "/rest/"},
+		{"/tree/", nil, "/tree/"},
+		{"/exact/{$}", nil, "/exact/"},
+		{"/{$}", nil, "/"},
+		{"/%7B/{x}", map[string]string{"x": "}"}, "/%7B/%7D"},
+		{"example.com/h/{x}", map[string]string{"x": "y"}, "//example.com/h/y"},
+		{"/missing/{x}", nil, "error: missing value for wildcard \"x\""},
+		{"/empty/{x}", map[string]string{"x": ""}, "error: missing value"},
+		{"/extra/{x}", map[string]string{"x": "1", "y": "2"}, "error: no wildcard \"y\""},
+		{"/typed/{id:int}", map[string]string{"id": "-3"}, "/typed/-3"},
+		{"/bad/{id:int}", map[string]string{"id": "x"}, "error: does not satisfy its constraint"},
+	} {
+		mux.Handle(test.pattern, h)
+		u, err := mux.BuildURL(test.pattern, test.values)
+		if want, ok := strings.CutPrefix(test.want, "error: "); ok {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%q %v: got (%v, %v), want error containing %q", test.pattern, test.values, u, err, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q %v: %v", test.pattern, test.values, err)
+			continue
+		}
+		if got := u.String(); got != test.want {
+			t.Errorf("%q %v: got %q, want %q", test.pattern, test.values, got, test.want)
+		}
+		// The URL must match the pattern it was built from.
+		r := httptest.NewRequest("GET", "http://example.com"+u.RequestURI(), nil)
+		if _, got := mux.Handler(r); got != test.pattern {
+			t.Errorf("%q %v: URL %q matches %q", test.pattern, test.values, u, got)
+		}
+	}
+	if _, err := mux.BuildURL("/unregistered", nil); err == nil {
+		t.Error("BuildURL of unregistered pattern: got nil error")
+	}
+}
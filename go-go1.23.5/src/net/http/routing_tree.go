@@ -19,6 +19,8 @@
 package http
 
 import (
+	"cmp"
+	"slices"
 	"strings"
 )
 
@@ -37,6 +39,17 @@ type routingNode struct {
 	children   mapping[string, *routingNode]
 	multiChild *routingNode // child with multi wildcard
 	emptyChild *routingNode // optimization: child with key ""
+	// Children with constrained single wildcards, ordered so that
+	// each precedes those whose constraints are more general.
+	constrained []constrainedChild
+}
+
+// A constrainedChild is the child of a routingNode
+// for a single wildcard with a constraint.
+type constrainedChild struct {
+	c    *constraint
+	node *routingNode
+	rank int // number of other children with more general constraints
 }
 
 // addPattern adds a pattern and its associated Handler to the tree
@@ -66,6 +79,8 @@ func (n *routingNode) addSegments(segs []segment, p *pattern, h Handler) {
 		c := &routingNode{}
 		n.multiChild = c
 		c.set(p, h)
+	} else if seg.wild && seg.c != nil {
+		n.addConstrainedChild(seg.c).addSegments(segs[1:], p, h)
 	} else if seg.wild {
 		n.addChild("").addSegments(segs[1:], p, h)
 	} else {
@@ -100,6 +115,33 @@ func (n *routingNode) addChild(key string) *routingNode {
 	return c
 }
 
+// addConstrainedChild adds a child node for a wildcard with
+// constraint c if one does not exist, and returns the child.
+func (n *routingNode) addConstrainedChild(c *constraint) *routingNode {
+	for _, cc := range n.constrained {
+		if cc.c == c {
+			return cc.node
+		}
+	}
+	child := constrainedChild{c: c, node: &routingNode{}}
+	for i := range n.constrained {
+		switch compareConstraints(c, n.constrained[i].c) {
+		case moreSpecific:
+			child.rank++
+		case moreGeneral:
+			n.constrained[i].rank++
+		}
+	}
+	n.constrained = append(n.constrained, child)
+	// A constraint is a strict subset of fewer constraints than
+	// any of its strict subsets, so sorting by rank puts more
+	// specific constraints first.
+	slices.SortStableFunc(n.constrained, func(a, b constrainedChild) int {
+		return cmp.Compare(b.rank, a.rank)
+	})
+	return child.node
+}
+
 // findChild returns the child of n with the given key, or nil
 // if there is no child with that key.
 func (n *routingNode) findChild(key string) *routingNode {
@@ -175,12 +217,22 @@ func (n *routingNode) matchPath(path string, matches []string) (*routingNode, []
 		return n, m
 	}
 	// If matching a literal fails, try again with patterns that have a single
-	// wildcard (represented by an empty string in the child mapping).
+	// wildcard: first those with constraints that the segment satisfies, most
+	// specific first, then those without (represented by an empty string in
+	// the child mapping).
 	// Again, by construction, patterns with a single wildcard must be more specific than
 	// those with a multi wildcard.
 	// We skip this step if the segment is a trailing slash, because single wildcards
 	// don't match trailing slashes.
 	if seg != "/" {
+		for _, cc := range n.constrained {
+			if !cc.c.match(seg) {
+				continue
+			}
+			if n, m := cc.node.matchPath(rest, append(matches, seg)); n != nil {
+				return n, m
+			}
+		}
 		if n, m := n.emptyChild.matchPath(rest, append(matches, seg)); n != nil {
 			return n, m
 		}
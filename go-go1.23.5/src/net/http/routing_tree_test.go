@@ -211,6 +211,27 @@ func TestRoutingNodeMatch(t *testing.T) {
 		{"GET", "", "/a/b/c", pat2, []string{"c"}},
 		{"GET", "", "/a/b/c/d", pat3, []string{"c/d"}},
 	})
+
+	// Constrained wildcards are tried after literals, most specific first,
+	// and before unconstrained wildcards.
+	test(buildTree(
+		"/c/new",
+		"/c/{name}",
+		"/c/{id:[0-9]+}",
+		"/c/{small:[0-9]}",
+		"/c/{slug:[a-z-]+}",
+		"/c/{id:[0-9]+}/x",
+		"/c/{hex:[0-9a-f]+}/x",
+	), []testCase{
+		{"GET", "", "/c/new", "/c/new", nil},
+		{"GET", "", "/c/7", "/c/{small:[0-9]}", []string{"7"}},
+		{"GET", "", "/c/42", "/c/{id:[0-9]+}", []string{"42"}},
+		{"GET", "", "/c/a-b", "/c/{slug:[a-z-]+}", []string{"a-b"}},
+		{"GET", "", "/c/A", "/c/{name}", []string{"A"}},
+		{"GET", "", "/c/42/x", "/c/{id:[0-9]+}/x", []string{"42"}},
+		{"GET", "", "/c/ff/x", "/c/{hex:[0-9a-f]+}/x", []string{"ff"}},
+		{"GET", "", "/c/zz/x", "", nil},
+	})
 }
 
 func TestMatchingMethods(t *testing.T) {
@@ -19,6 +19,7 @@ import (
 	"internal/testenv"
 	"io"
 	"log"
+	"log/slog"
 	"math/rand"
 	"mime/multipart"
 	"net"
@@ -4525,6 +4526,115 @@ func TestServerConnStateNew(t *testing.T) {
 	}
 }
 
+func TestServerRequestDone(t *testing.T) {
+	run(t, testServerRequestDone, []testMode{http1Mode, https1Mode, http2Mode})
+}
+func testServerRequestDone(t *testing.T, mode testMode) {
+	donec := make(chan RequestStats, 1)
+	var reqProto string
+	var handlerStart time.Time
+	start := time.Now()
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/panic" {
+			panic(ErrAbortHandler)
+		}
+		reqProto = r.Proto
+		handlerStart = time.Now()
+		if r.URL.Path == "/hijack" {
+			c, _, _ := w.(Hijacker).Hijack()
+			c.Write([]byte("HTTP/1.0 200 OK\r\nConnection: close\r\n\r\nHello."))
+			c.Close()
+			return
+		}
+		w.WriteHeader(StatusTeapot)
+		io.WriteString(w, "Hello, ")
+		w.(Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, "world!")
+	}), func(ts *httptest.Server) {
+		ts.Config.RequestDone = func(r *Request, stats RequestStats) {
+			donec <- stats
+		}
+	})
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	stats := <-donec
+	if stats.Proto != reqProto || stats.StatusCode != StatusTeapot || stats.BytesWritten != 13 || stats.Hijacked {
+		t.Errorf("RequestDone got %+v, want Proto %q, StatusCode 418, BytesWritten 13, Hijacked false", stats, reqProto)
+	}
+	if stats.Start.Before(start) || stats.Duration < 10*time.Millisecond {
+		t.Errorf("RequestDone got Start %v, Duration %v; want Start after %v, Duration >= 10ms", stats.Start, stats.Duration, start)
+	}
+	// Start is when the server began reading the request,
+	// which is before the handler runs for every protocol.
+	if stats.Start.After(handlerStart) {
+		t.Errorf("RequestDone got Start %v, want at or before handler start %v", stats.Start, handlerStart)
+	}
+	if want := time.Since(stats.Start); stats.Duration > want {
+		t.Errorf("RequestDone got Duration %v, want at most %v since Start", stats.Duration, want)
+	}
+
+	// POST, so that the client does not retry the request.
+	if res, err := cst.c.Post(cst.ts.URL+"/panic", "text/plain", nil); err == nil {
+		res.Body.Close()
+		t.Errorf("Post /panic succeeded, want error after handler panic")
+	}
+	if stats := <-donec; !stats.Panicked {
+		t.Errorf("RequestDone for panicking handler got %+v, want Panicked true", stats)
+	}
+
+	if mode == http2Mode {
+		return
+	}
+	res, err = cst.c.Get(cst.ts.URL + "/hijack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if stats := <-donec; !stats.Hijacked || stats.StatusCode != 0 {
+		t.Errorf("RequestDone for hijacked connection got %+v, want Hijacked true, StatusCode 0", stats)
+	}
+}
+
+func TestServerRequestDoneAccessLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logRequest := AccessLogger(logger)
+	donec := make(chan struct{})
+	srv := &Server{
+		RequestDone: func(r *Request, stats RequestStats) {
+			logRequest(r, stats)
+			close(donec)
+		},
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			NotFound(w, r)
+		}),
+	}
+	srv.Serve(&oneConnListener{
+		conn: &rwTestConn{
+			Reader: strings.NewReader("GET /missing?q=1 HTTP/1.1\r\nHost: foo\r\n\r\n"),
+			Writer: io.Discard,
+		},
+	})
+	<-donec
+	want := `level=INFO msg="http request" method=GET uri="/missing?q=1" proto=HTTP/1.1 remote_addr=remote-addr status=404 bytes=19` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("access log:\n got: %q\nwant: %q", got, want)
+	}
+}
+
 type closeWriteTestConn struct {
 	rwTestConn
 	didCloseWrite bool
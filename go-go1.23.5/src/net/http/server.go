@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"internal/godebug"
@@ -454,6 +455,8 @@ type response struct {
 	contentLength int64 // explicitly-declared Content-Length; or -1
 	status        int   // status code passed to WriteHeader
 
+	start time.Time // when the server began reading the request
+
 	// close connection after this reply.  set on request and
 	// updated after response from handler if there's a
 	// "Connection: keep-alive" response header and a
@@ -1101,6 +1104,7 @@ func (c *conn) readRequest(ctx context.Context) (w *response, err error) {
 		handlerHeader: make(Header),
 		contentLength: -1,
 		closeNotifyCh: make(chan bool, 1),
+		start:         t0,
 
 		// We populate these ahead of time so we're not
 		// reading from req.Header after their Handler starts
@@ -1705,6 +1709,17 @@ func (w *response) write(lenData int, dataB []byte, dataS string) (n int, err er
 	}
 }
 
+// requestStats returns the summary of the response passed to
+// Server.RequestDone.
+func (w *response) requestStats(hijacked bool) RequestStats {
+	return RequestStats{
+		StatusCode:   w.status,
+		BytesWritten: w.written,
+		Start:        w.start,
+		Hijacked:     hijacked,
+	}
+}
+
 func (w *response) finishRequest() {
 	w.handlerDone.Store(true)
 
@@ -1859,7 +1874,7 @@ func (c *conn) closeWriteAndWait() {
 // so that those can't be overridden with alternate implementations.
 func validNextProto(proto string) bool {
 	switch proto {
-	case "", "http/1.1", "http/1.0":
+	case "", "http/1.1", "http/1.0", nextProtoUnencryptedHTTP2:
 		return false
 	}
 	return true
@@ -1877,6 +1892,7 @@ func (c *conn) setState(nc net.Conn, state ConnState, runHook bool) {
 		srv.trackConn(c, true)
 	case StateHijacked, StateClosed:
 		srv.trackConn(c, false)
+		srv.releaseConn()
 	}
 	if state > 0xff || state < 0 {
 		panic("internal error")
@@ -1959,6 +1975,11 @@ func (c *conn) serve(ctx context.Context) {
 			c.close()
 			c.setState(c.rwc, StateClosed, runHooks)
 		}
+		if inFlightResponse != nil {
+			stats := inFlightResponse.requestStats(c.hijacked())
+			stats.Panicked = true
+			c.server.requestDone(inFlightResponse.req, stats)
+		}
 	}()
 
 	if tlsConn, ok := c.rwc.(*tls.Conn); ok {
@@ -2013,6 +2034,16 @@ func (c *conn) serve(ctx context.Context) {
 	c.bufr = newBufioReader(c.r)
 	c.bufw = newBufioWriterSize(checkConnErrorWriter{c}, 4<<10)
 
+	protos := c.server.protocols()
+	if c.tlsState == nil && protos.UnencryptedHTTP2() {
+		if c.maybeServeUnencryptedHTTP2(ctx) {
+			return
+		}
+	}
+	if !protos.HTTP1() {
+		return
+	}
+
 	for {
 		w, err := c.readRequest(ctx)
 		if c.r.remain != c.server.initialReadLimitSize() {
@@ -2073,6 +2104,12 @@ func (c *conn) serve(ctx context.Context) {
 			return
 		}
 
+		if c.tlsState == nil && protos.UnencryptedHTTP2() && req.isH2CUpgrade() {
+			if c.serveH2CUpgrade(ctx, req) {
+				return
+			}
+		}
+
 		c.curReq.Store(w)
 
 		if requestBodyRemains(req.Body) {
@@ -2093,9 +2130,11 @@ func (c *conn) serve(ctx context.Context) {
 		inFlightResponse = nil
 		w.cancelCtx()
 		if c.hijacked() {
+			c.server.requestDone(w.req, w.requestStats(true))
 			return
 		}
 		w.finishRequest()
+		c.server.requestDone(w.req, w.requestStats(false))
 		c.rwc.SetWriteDeadline(time.Time{})
 		if !w.shouldReuseConnection() {
 			if w.requestBodyLimitHit || w.closedRequestBodyEarly() {
@@ -2132,6 +2171,107 @@ func (c *conn) serve(ctx context.Context) {
 	}
 }
 
+// unencryptedHTTP2Preface is the HTTP/2 client connection preface
+// (RFC 9113, Section 3.4).
+const unencryptedHTTP2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// maybeServeUnencryptedHTTP2 serves c as an unencrypted HTTP/2 connection
+// if the client starts the connection with the HTTP/2 preface.
+// It reports whether it took over the connection.
+func (c *conn) maybeServeUnencryptedHTTP2(ctx context.Context) bool {
+	fn, ok := c.server.TLSNextProto[nextProtoUnencryptedHTTP2]
+	if !ok {
+		return false
+	}
+	if d := c.server.readHeaderTimeout(); d > 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	}
+	// Compare the preface a byte at a time, so an HTTP/1 client
+	// sending a short request is never kept waiting for more data.
+	c.r.setReadLimit(c.server.initialReadLimitSize())
+	for n := 1; n <= len(unencryptedHTTP2Preface); n++ {
+		b, err := c.bufr.Peek(n)
+		if err != nil || b[n-1] != unencryptedHTTP2Preface[n-1] {
+			return false
+		}
+	}
+	c.rwc.SetReadDeadline(time.Time{})
+	c.serveUnencryptedHTTP2(ctx, fn, &unencryptedHTTP2Conn{Conn: c.rwc, br: c.bufr})
+	return true
+}
+
+// serveH2CUpgrade responds to an "Upgrade: h2c" request and serves
+// the rest of the connection as unencrypted HTTP/2, with req as the
+// first stream. It reports whether it took over the connection.
+//
+// Requests with a body are served over HTTP/1 instead, as RFC 7540
+// permits: the body would have to be buffered before switching protocols.
+func (c *conn) serveH2CUpgrade(ctx context.Context, req *Request) bool {
+	fn, ok := c.server.TLSNextProto[nextProtoUnencryptedHTTP2]
+	if !ok || req.Body != NoBody {
+		return false
+	}
+	settings, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(req.Header.get("Http2-Settings"), "="))
+	if err != nil {
+		return false
+	}
+	if _, err := io.WriteString(c.rwc, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n"); err != nil {
+		return true
+	}
+	c.rwc.SetReadDeadline(time.Time{})
+	c.rwc.SetWriteDeadline(time.Time{})
+	for _, k := range []string{"Connection", "Upgrade", "Http2-Settings"} {
+		req.Header.Del(k)
+	}
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2.0", 2, 0
+	c.serveUnencryptedHTTP2(ctx, fn, &unencryptedHTTP2Conn{
+		Conn:     c.rwc,
+		br:       c.bufr,
+		upgrade:  req,
+		settings: settings,
+	})
+	return true
+}
+
+func (c *conn) serveUnencryptedHTTP2(ctx context.Context, fn func(*Server, *tls.Conn, Handler), conn *unencryptedHTTP2Conn) {
+	tlsConn := unencryptedTLSConn(conn)
+	h := initALPNRequest{ctx, tlsConn, serverHandler{c.server}}
+	// As for HTTP/2 over TLS, skip the state hooks so
+	// closeIdleConns does not close the connection.
+	c.setState(c.rwc, StateActive, skipHooks)
+	fn(c.server, tlsConn, h)
+}
+
+// unencryptedHTTP2Conn is the net.Conn handed to the HTTP/2 server
+// for an unencrypted HTTP/2 connection.
+// Reads first return any data already buffered by the HTTP/1 reader.
+type unencryptedHTTP2Conn struct {
+	net.Conn
+	br *bufio.Reader // nil once drained
+
+	// upgrade and settings are set for connections upgraded
+	// with "Upgrade: h2c".
+	upgrade  *Request
+	settings []byte
+}
+
+func (c *unencryptedHTTP2Conn) Read(p []byte) (int, error) {
+	if c.br != nil {
+		if c.br.Buffered() > 0 {
+			return c.br.Read(p)
+		}
+		c.br = nil
+	}
+	return c.Conn.Read(p)
+}
+
+// H2CUpgrade returns the request that upgraded the connection to
+// HTTP/2 and the decoded contents of its HTTP2-Settings header.
+// It is called by the HTTP/2 server.
+func (c *unencryptedHTTP2Conn) H2CUpgrade() (*Request, []byte) {
+	return c.upgrade, c.settings
+}
+
 func (w *response) sendExpectationFailed() {
 	// TODO(bradfitz): let ServeHTTP handlers handle
 	// requests with non-standard expectation[s]? Seems
@@ -2434,6 +2574,23 @@ func RedirectHandler(url string, code int) Handler {
 // The match for a wildcard can be obtained by calling [Request.PathValue] with the wildcard's name.
 // A trailing slash in a path acts as an anonymous "..." wildcard.
 //
+// A single-segment wildcard may have a constraint, written {NAME:CONSTRAINT},
+// which restricts the segments it matches. The constraint is either the name
+// of a constraint, or a regular expression in the syntax of the regexp package
+// that must match the entire (unescaped) segment. For example, "/items/{id:int}"
+// matches "/items/42" but not "/items/new", and "/posts/{slug:[a-z-]+}" matches
+// "/posts/hello-world". The predefined constraints are
+//
+//	int    -?[0-9]+
+//	uint   [0-9]+
+//	alpha  [A-Za-z]+
+//	alnum  [0-9A-Za-z]+
+//	hex    [0-9A-Fa-f]+
+//	uuid   a UUID in the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+//
+// and others may be added with [RegisterPatternConstraint].
+// [Request.PathValueInt] and [Request.PathValueUint] convert matches to integers.
+//
 // The special wildcard {$} matches only the end of the URL.
 // For example, the pattern "/{$}" matches only the path "/",
 // whereas the pattern "/" matches every path.
@@ -2459,6 +2616,12 @@ func RedirectHandler(url string, code int) Handler {
 // The former matches paths beginning with "/images/thumbnails/"
 // and the latter will match any other path in the "/images/" subtree.
 //
+// A wildcard with a constraint is more specific than one without, and a
+// wildcard whose constraint matches a subset of the values matched by another's
+// is more specific than it. So "/items/{id:int}", "/items/{name}" and "/items/new"
+// can all be registered, and "/items/{id:int}" and "/items/{slug:[a-z-]+}" do not
+// conflict, because no segment satisfies both constraints.
+//
 // As another example, consider the patterns "GET /" and "/index.html":
 // both match a GET request for "/index.html", but the former pattern
 // matches all other GET and HEAD requests, while the latter matches any
@@ -2506,8 +2669,10 @@ type ServeMux struct {
 	mu       sync.RWMutex
 	tree     routingNode
 	index    routingIndex
-	patterns []*pattern  // TODO(jba): remove if possible
-	mux121   serveMux121 // used only when GODEBUG=httpmuxgo121=1
+	routes   []route        // in registration order
+	byString map[string]int // index into routes, by pattern string
+	mounts   []*mountPoint  // where this mux is mounted in other muxes
+	mux121   serveMux121    // used only when GODEBUG=httpmuxgo121=1
 }
 
 // NewServeMux allocates and returns a new [ServeMux].
@@ -2822,23 +2987,160 @@ func (mux *ServeMux) registerErr(patstr string, handler Handler) error {
 	} else {
 		pat.loc = fmt.Sprintf("%s:%d", file, line)
 	}
+	return mux.registerPattern(pat, handler)
+}
+
+// registerMu serializes changes to the patterns and mounts of all
+// ServeMuxes. A pattern, and the patterns derived from it for the
+// muxes it is mounted in, are all checked for conflicts before any
+// of them is registered.
+//
+// Since the tree, index, routes and mounts of a ServeMux change only
+// while registerMu is held, they may be read without the mux's lock
+// by a goroutine holding registerMu.
+//
+// Mount middleware is user code, which may itself register patterns,
+// so it is never called with registerMu held.
+var registerMu sync.Mutex
+
+// registerPattern registers a parsed pattern, and registers it
+// in turn with each mux that mux is mounted in.
+func (mux *ServeMux) registerPattern(pat *pattern, handler Handler) error {
+	return register(func() ([]registration, error) {
+		return registrations(nil, registration{mux: mux, pat: pat, handler: handler, from: -1})
+	}, nil)
+}
+
+// A registration is a pattern to be registered with a mux.
+type registration struct {
+	mux     *ServeMux
+	pat     *pattern
+	handler Handler
 
-	mux.mu.Lock()
-	defer mux.mu.Unlock()
-	// Check for conflict.
-	if err := mux.index.possiblyConflictingPatterns(pat, func(pat2 *pattern) error {
-		if pat.conflictsWith(pat2) {
-			d := describeConflict(pat, pat2)
-			return fmt.Errorf("pattern %q (registered at %s) conflicts with pattern %q (registered at %s):\n%s",
-				pat, pat.loc, pat2, pat2.loc, d)
+	// A registration derived from another, for a mux it is mounted
+	// in, has the index of that registration in from, and the mount
+	// point whose middleware wraps its handler in mount. A
+	// registration which is not derived has a from of -1, and the
+	// handler to wrap, if it has a mount.
+	from  int
+	mount *mountPoint
+}
+
+// register plans registrations by calling plan with registerMu held,
+// builds their handlers without holding it, and registers them.
+// If the plan has changed in the meantime, because of registrations
+// or mounts by other goroutines or by middleware, register starts
+// again. If commit is non-nil, it is called with registerMu held
+// once the registrations are made.
+func register(plan func() ([]registration, error), commit func()) error {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+	regs, err := plan()
+	for err == nil {
+		built := slices.Clone(regs)
+		registerMu.Unlock()
+		err = buildHandlers(built)
+		registerMu.Lock()
+		if err != nil {
+			break
+		}
+		regs, err = plan()
+		if err == nil && samePlan(regs, built) {
+			if err = registerAll(built); err == nil && commit != nil {
+				commit()
+			}
+			break
+		}
+	}
+	return err
+}
+
+// registrations appends to regs the registration r, and the
+// registrations derived from it for the muxes that r.mux is mounted
+// in, without their handlers. registerMu must be held.
+func registrations(regs []registration, r registration) ([]registration, error) {
+	i := len(regs)
+	regs = append(regs, r)
+	for _, m := range r.mux.mounts {
+		joined, err := m.join(r.pat)
+		if err != nil {
+			return nil, err
+		}
+		regs, err = registrations(regs, registration{mux: m.parent, pat: joined, from: i, mount: m})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return regs, nil
+}
+
+// buildHandlers sets the handler of each registration in regs,
+// calling the middleware of its mount.
+func buildHandlers(regs []registration) error {
+	for i := range regs {
+		r := &regs[i]
+		if r.from >= 0 {
+			r.handler = regs[r.from].handler
+		}
+		if r.mount != nil {
+			r.handler = r.mount.wrap(r.handler)
+			if r.handler == nil {
+				return fmt.Errorf("http: middleware returned nil handler for %q", r.pat)
+			}
 		}
-		return nil
-	}); err != nil {
-		return err
 	}
-	mux.tree.addPattern(pat, handler)
-	mux.index.addPattern(pat)
-	mux.patterns = append(mux.patterns, pat)
+	return nil
+}
+
+// samePlan reports whether two plans make the same registrations,
+// ignoring their handlers.
+func samePlan(a, b []registration) bool {
+	return slices.EqualFunc(a, b, func(r, r2 registration) bool {
+		return r.mux == r2.mux && r.pat.str == r2.pat.str && r.from == r2.from && r.mount == r2.mount
+	})
+}
+
+// registerAll checks each registration for a conflict with the
+// patterns of its mux and with the earlier registrations, and if
+// there are none, registers them all. registerMu must be held.
+func registerAll(regs []registration) error {
+	for i, r := range regs {
+		if err := r.mux.index.possiblyConflictingPatterns(r.pat, func(pat2 *pattern) error {
+			return checkConflict(r.pat, pat2)
+		}); err != nil {
+			return err
+		}
+		for _, r2 := range regs[:i] {
+			if r2.mux == r.mux {
+				if err := checkConflict(r.pat, r2.pat); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, r := range regs {
+		mux := r.mux
+		mux.mu.Lock()
+		mux.tree.addPattern(r.pat, r.handler)
+		mux.index.addPattern(r.pat)
+		if mux.byString == nil {
+			mux.byString = make(map[string]int)
+		}
+		mux.byString[r.pat.str] = len(mux.routes)
+		mux.routes = append(mux.routes, route{r.pat, r.handler})
+		mux.mu.Unlock()
+	}
+	return nil
+}
+
+// checkConflict returns an error describing the conflict
+// between pat and pat2, if any.
+func checkConflict(pat, pat2 *pattern) error {
+	if pat.conflictsWith(pat2) {
+		d := describeConflict(pat, pat2)
+		return fmt.Errorf("pattern %q (registered at %s) conflicts with pattern %q (registered at %s):\n%s",
+			pat, pat.loc, pat2, pat2.loc, d)
+	}
 	return nil
 }
 
@@ -2953,6 +3255,41 @@ type Server struct {
 	// ConnState type and associated constants for details.
 	ConnState func(net.Conn, ConnState)
 
+	// MaxConns, if positive, limits the number of connections
+	// accepted by Serve that may be open at once. Hijacked
+	// connections do not count against the limit. A connection
+	// accepted while the limit is reached is sent a 503 (Service
+	// Unavailable) response with a Retry-After header and closed;
+	// it is reported to the ConnState hook as StateRejected.
+	MaxConns int
+
+	// MaxInFlightRequests, if positive, limits the number of
+	// handlers which may run at once, across all connections and
+	// protocols. A request received while the limit is reached is
+	// sent a 503 (Service Unavailable) response with a Retry-After
+	// header, without calling the Handler.
+	MaxInFlightRequests int
+
+	// RateLimit, if non-nil, limits the rate of requests from each
+	// client. A request exceeding the limit is sent a 429 (Too Many
+	// Requests) response with a Retry-After header, without calling
+	// the Handler.
+	RateLimit *RateLimit
+
+	// RequestDone specifies an optional callback function that is
+	// called after each request has been served, with a summary of
+	// the response. It is called once the handler has returned and
+	// the response has been written, or once the handler has hijacked
+	// the connection. If the handler panics, RequestDone is called
+	// with RequestStats.Panicked set once the connection or stream
+	// has been aborted.
+	//
+	// RequestDone is called on the goroutine which ran the handler.
+	// An HTTP/1 connection does not read its next request until
+	// RequestDone returns.
+	// See [AccessLogger] for a RequestDone function which logs requests.
+	RequestDone func(*Request, RequestStats)
+
 	// ErrorLog specifies an optional logger for errors accepting
 	// connections, unexpected behavior from handlers, and
 	// underlying FileSystem errors.
@@ -2973,15 +3310,32 @@ type Server struct {
 	// value.
 	ConnContext func(ctx context.Context, c net.Conn) context.Context
 
+	// Protocols is the set of protocols accepted by the server.
+	//
+	// If Protocols includes UnencryptedHTTP2, the server will accept
+	// unencrypted HTTP/2 connections, either with prior knowledge or
+	// by upgrading an HTTP/1.1 request carrying an "Upgrade: h2c" header.
+	// The server can serve both HTTP/1 and unencrypted HTTP/2 on the same
+	// address and port. Unencrypted HTTP/2 requires the HTTP/2
+	// implementation bundled with this package.
+	//
+	// If Protocols is nil, the default is usually HTTP/1 and HTTP/2.
+	// If TLSNextProto is non-nil and does not contain an "h2" entry,
+	// the default is HTTP/1 only.
+	Protocols *Protocols
+
 	inShutdown atomic.Bool // true when server is in shutdown
 
 	disableKeepAlives atomic.Bool
 	nextProtoOnce     sync.Once // guards setupHTTP2_* init
 	nextProtoErr      error     // result of http2.ConfigureServer if used
 
+	limits serverLimits
+
 	mu         sync.Mutex
 	listeners  map[*net.Listener]struct{}
 	activeConn map[*conn]struct{}
+	h3Conns    map[*http3ServerConn]struct{}
 	onShutdown []func()
 
 	listenerGroup sync.WaitGroup
@@ -3014,6 +3368,10 @@ func (srv *Server) Close() error {
 		c.rwc.Close()
 		delete(srv.activeConn, c)
 	}
+	for sc := range srv.h3Conns {
+		sc.abort(http3ErrNoError)
+		delete(srv.h3Conns, sc)
+	}
 	return err
 }
 
@@ -3118,6 +3476,13 @@ func (s *Server) closeIdleConns() bool {
 		c.rwc.Close()
 		delete(s.activeConn, c)
 	}
+	for sc := range s.h3Conns {
+		if !sc.closeIfIdle() {
+			quiescent = false
+			continue
+		}
+		delete(s.h3Conns, sc)
+	}
 	return quiescent
 }
 
@@ -3131,6 +3496,47 @@ func (s *Server) closeListenersLocked() error {
 	return err
 }
 
+// RequestStats summarizes how a request was served.
+// It is passed to the optional [Server.RequestDone] hook.
+type RequestStats struct {
+	// Proto is the protocol the request was served over,
+	// such as "HTTP/1.1", "HTTP/2.0", or "HTTP/3.0".
+	Proto string
+
+	// StatusCode is the response status code.
+	// It is zero if the connection was hijacked
+	// before a response header was written.
+	StatusCode int
+
+	// BytesWritten is the number of bytes of response body
+	// written by the handler.
+	BytesWritten int64
+
+	// Start is the time at which the server started reading the request.
+	Start time.Time
+
+	// Duration is the time from Start until the response was written.
+	Duration time.Duration
+
+	// Hijacked reports whether the handler hijacked the connection.
+	Hijacked bool
+
+	// Panicked reports whether the handler panicked.
+	// The client sees the connection or stream aborted,
+	// rather than a complete response.
+	Panicked bool
+}
+
+// requestDone calls the RequestDone hook, if set.
+func (s *Server) requestDone(r *Request, stats RequestStats) {
+	if s.RequestDone == nil {
+		return
+	}
+	stats.Proto = r.Proto
+	stats.Duration = time.Since(stats.Start)
+	s.RequestDone(r, stats)
+}
+
 // A ConnState represents the state of a client connection to a server.
 // It's used by the optional [Server.ConnState] hook.
 type ConnState int
@@ -3169,6 +3575,11 @@ const (
 	// This is a terminal state. Hijacked connections do not
 	// transition to StateClosed.
 	StateClosed
+
+	// StateRejected represents a connection that was refused
+	// because the server's MaxConns limit was reached. It is
+	// reported in place of StateNew, and is a terminal state.
+	StateRejected
 )
 
 var stateName = map[ConnState]string{
@@ -3177,6 +3588,7 @@ var stateName = map[ConnState]string{
 	StateIdle:     "idle",
 	StateHijacked: "hijacked",
 	StateClosed:   "closed",
+	StateRejected: "rejected",
 }
 
 func (c ConnState) String() string {
@@ -3207,6 +3619,10 @@ func (sh serverHandler) ServeHTTP(rw ResponseWriter, req *Request) {
 		handler = globalOptionsHandler{}
 	}
 
+	if !sh.srv.admitRequest(rw, req) {
+		return
+	}
+	defer sh.srv.limits.inFlight.Add(-1)
 	handler.ServeHTTP(rw, req)
 }
 
@@ -3280,7 +3696,11 @@ func (srv *Server) shouldConfigureHTTP2ForServe() bool {
 	// passed this tls.Config to tls.NewListener. And if they did,
 	// it's too late anyway to fix it. It would only be potentially racy.
 	// See Issue 15908.
-	return slices.Contains(srv.TLSConfig.NextProtos, http2NextProtoTLS)
+	//
+	// Unencrypted HTTP/2 never uses the tls.Config, so it is
+	// always safe to configure it.
+	return slices.Contains(srv.TLSConfig.NextProtos, http2NextProtoTLS) ||
+		srv.protocols().UnencryptedHTTP2()
 }
 
 // ErrServerClosed is returned by the [Server.Serve], [ServeTLS], [ListenAndServe],
@@ -3347,6 +3767,10 @@ func (srv *Server) Serve(l net.Listener) error {
 			}
 			return err
 		}
+		if !srv.acquireConn() {
+			srv.rejectConn(rw)
+			continue
+		}
 		connCtx := ctx
 		if cc := srv.ConnContext; cc != nil {
 			connCtx = cc(connCtx, rw)
@@ -3383,9 +3807,7 @@ func (srv *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
 	}
 
 	config := cloneTLSConfig(srv.TLSConfig)
-	if !slices.Contains(config.NextProtos, "http/1.1") {
-		config.NextProtos = append(config.NextProtos, "http/1.1")
-	}
+	config.NextProtos = adjustNextProtos(config.NextProtos, srv.protocols())
 
 	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil || config.GetConfigForClient != nil
 	if !configHasCert || certFile != "" || keyFile != "" {
@@ -3594,7 +4016,11 @@ func (srv *Server) onceSetNextProtoDefaults() {
 	if omitBundledHTTP2 {
 		return
 	}
-	if http2server.Value() == "0" {
+	p := srv.protocols()
+	if !p.HTTP2() && !p.UnencryptedHTTP2() {
+		return
+	}
+	if http2server.Value() == "0" && srv.Protocols == nil {
 		http2server.IncNonDefault()
 		return
 	}
@@ -3606,6 +4032,32 @@ func (srv *Server) onceSetNextProtoDefaults() {
 	}
 }
 
+// protocols returns the set of protocols accepted by the server.
+func (srv *Server) protocols() Protocols {
+	if srv.Protocols != nil {
+		return *srv.Protocols // user-configured set
+	}
+
+	// The historic way of disabling HTTP/2 is to set TLSNextProto to
+	// a non-nil map with no "h2" entry.
+	_, hasH2 := srv.TLSNextProto["h2"]
+	http2Disabled := srv.TLSNextProto != nil && !hasH2
+
+	// If GODEBUG=http2server=0, then HTTP/2 is disabled unless
+	// the user has manually added an "h2" entry to TLSNextProto
+	// (probably by using x/net/http2 directly).
+	if http2server.Value() == "0" && !hasH2 {
+		http2Disabled = true
+	}
+
+	var p Protocols
+	p.SetHTTP1(true) // default always includes HTTP/1
+	if !http2Disabled {
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
 // TimeoutHandler returns a [Handler] that runs h with the given time limit.
 //
 // The new Handler calls h.ServeHTTP to handle each request, but if a
@@ -3806,14 +4258,14 @@ type initALPNRequest struct {
 func (h initALPNRequest) BaseContext() context.Context { return h.ctx }
 
 func (h initALPNRequest) ServeHTTP(rw ResponseWriter, req *Request) {
-	if req.TLS == nil {
+	if req.TLS == nil && !isUnencryptedTLSConn(h.c) {
 		req.TLS = &tls.ConnectionState{}
 		*req.TLS = h.c.ConnectionState()
 	}
 	if req.Body == nil {
 		req.Body = NoBody
 	}
-	if req.RemoteAddr == "" {
+	if req.RemoteAddr == "" && !isUnencryptedTLSConn(h.c) {
 		req.RemoteAddr = h.c.RemoteAddr().String()
 	}
 	h.h.ServeHTTP(rw, req)
@@ -0,0 +1,390 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEventSize is the default limit on the size of a line,
+// and of the data of an event, read by a [Reader].
+const DefaultMaxEventSize = 1 << 20
+
+// ErrEventTooLong is returned by [Reader.Next] and [Stream.Next] when
+// a line or the data of an event is longer than the maximum event size.
+var ErrEventTooLong = errors.New("sse: event too long")
+
+// A Reader parses server-sent events from an event stream.
+type Reader struct {
+	// MaxEventSize limits the size of each line of the stream, and of
+	// the data of each event. If zero, DefaultMaxEventSize is used.
+	MaxEventSize int
+
+	r       *bufio.Reader
+	err     error // sticky ErrEventTooLong
+	started bool  // whether a byte order mark has been checked for
+	lastID  string
+	retry   time.Duration
+	data    strings.Builder
+	typ     string
+}
+
+// NewReader returns a Reader which reads events from r,
+// typically an [http.Response] Body.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event in the stream.
+// At the end of the stream, it returns [io.EOF];
+// an incomplete final event is discarded.
+// If a line or the data of an event is too long, Next returns
+// [ErrEventTooLong], and continues to do so in later calls.
+func (r *Reader) Next() (Event, error) {
+	if r.err != nil {
+		return Event{}, r.err
+	}
+	if !r.started {
+		r.started = true
+		if b, err := r.r.Peek(3); err == nil && string(b) == "\xEF\xBB\xBF" {
+			r.r.Discard(3)
+		}
+	}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == ErrEventTooLong {
+				r.err = err
+			}
+			r.data.Reset()
+			r.typ = ""
+			return Event{}, err
+		}
+		if line == "" {
+			if r.data.Len() == 0 {
+				r.typ = ""
+				continue
+			}
+			e := Event{
+				ID:   r.lastID,
+				Type: r.typ,
+				Data: strings.TrimSuffix(r.data.String(), "\n"),
+			}
+			if e.Type == "" {
+				e.Type = "message"
+			}
+			r.data.Reset()
+			r.typ = ""
+			return e, nil
+		}
+		if line[0] == ':' {
+			continue // comment
+		}
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+		switch field {
+		case "event":
+			r.typ = value
+		case "data":
+			if r.data.Len()+len(value) >= r.maxEventSize() {
+				r.data.Reset()
+				r.typ = ""
+				r.err = ErrEventTooLong
+				return Event{}, r.err
+			}
+			r.data.WriteString(value)
+			r.data.WriteByte('\n')
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 64); err == nil && ms <= uint64(maxRetry/time.Millisecond) {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (r *Reader) maxEventSize() int {
+	if r.MaxEventSize > 0 {
+		return r.MaxEventSize
+	}
+	return DefaultMaxEventSize
+}
+
+// readLine reads a line terminated by CRLF, LF, or CR,
+// returning it without the terminator.
+func (r *Reader) readLine() (string, error) {
+	max := r.maxEventSize()
+	var line []byte
+	for {
+		if _, err := r.r.Peek(1); err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		buf, _ := r.r.Peek(r.r.Buffered())
+		i := bytes.IndexAny(buf, "\r\n")
+		if i < 0 {
+			i = len(buf)
+		}
+		if len(line)+i > max {
+			return "", ErrEventTooLong
+		}
+		line = append(line, buf[:i]...)
+		if i == len(buf) {
+			r.r.Discard(i)
+			continue
+		}
+		r.r.Discard(i + 1)
+		if buf[i] == '\r' {
+			if next, err := r.r.Peek(1); err == nil && next[0] == '\n' {
+				r.r.Discard(1)
+			}
+		}
+		return string(line), nil
+	}
+}
+
+// LastEventID returns the most recent event ID sent in the stream.
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// Retry returns the most recent reconnection time sent in the stream,
+// or zero if none has been sent.
+func (r *Reader) Retry() time.Duration {
+	return r.retry
+}
+
+// maxRetry bounds the reconnection time to avoid overflow.
+const maxRetry = 24 * time.Hour
+
+// DefaultRetry is the time a [Stream] waits before reconnecting
+// when the server has not sent a reconnection time.
+const DefaultRetry = 3 * time.Second
+
+// ErrClosed is returned by [Stream.Next] after [Stream.Close] is called.
+var ErrClosed = errors.New("sse: stream closed")
+
+// errNoGetBody is returned by [Stream.Next] when the Stream must
+// reconnect but its request has a body and no GetBody function.
+var errNoGetBody = errors.New("sse: cannot reconnect: request body is not rewindable")
+
+// A Stream reads server-sent events from a URL. When the connection is
+// lost, the Stream reconnects after the reconnection time requested by
+// the server, sending the Last-Event-ID header so that the server can
+// resume the stream where it left off.
+//
+// The Stream stops reconnecting when the request's context is done,
+// when Close is called, or when the server responds with a status other
+// than 200 (OK) or a Content-Type other than "text/event-stream".
+// A server may use 204 (No Content) to tell the client to stop.
+// It also stops if an event is too long.
+type Stream struct {
+	// MaxEventSize limits the size of each line of the stream, and
+	// of the data of each event, as [Reader.MaxEventSize] does.
+	// It must not be changed after the first call to Next.
+	MaxEventSize int
+
+	client *http.Client
+	req    *http.Request
+
+	mu     sync.Mutex
+	body   io.ReadCloser // current response body, or nil
+	r      *Reader
+	lastID string
+	retry  time.Duration
+	sent   bool // the request has been sent at least once
+	closed bool
+	done   chan struct{} // closed by Close
+}
+
+// NewStream returns a Stream which reads the events sent in response
+// to req, sent with client. If client is nil, [http.DefaultClient] is used.
+// If req has a body, it must also have a GetBody function, so that
+// the request can be sent again when reconnecting; otherwise Next
+// returns an error instead of reconnecting.
+//
+// NewStream does not send the request; the first call to Next does.
+func NewStream(client *http.Client, req *http.Request) *Stream {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Stream{
+		client: client,
+		req:    req,
+		retry:  DefaultRetry,
+		done:   make(chan struct{}),
+	}
+}
+
+// Next returns the next event, connecting or reconnecting as needed.
+func (s *Stream) Next() (Event, error) {
+	ctx := s.req.Context()
+	for {
+		r, err := s.connect()
+		if err == nil {
+			var e Event
+			e, err = r.Next()
+			s.mu.Lock()
+			if ms := r.Retry(); ms > 0 {
+				s.retry = ms
+			}
+			if err == nil {
+				s.lastID = e.ID
+				s.mu.Unlock()
+				return e, nil
+			}
+			// The connection may have sent an ID without completing
+			// an event; keep it for the Last-Event-ID header.
+			s.lastID = r.LastEventID()
+			s.closeBodyLocked()
+			s.mu.Unlock()
+		}
+		var permanent *responseError
+		if errors.As(err, &permanent) || err == errNoGetBody || err == ErrEventTooLong {
+			return Event{}, err
+		}
+		if s.isClosed() {
+			return Event{}, ErrClosed
+		}
+		if ctx.Err() != nil {
+			return Event{}, ctx.Err()
+		}
+		t := time.NewTimer(s.retryDelay())
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return Event{}, ctx.Err()
+		case <-s.done:
+			t.Stop()
+			return Event{}, ErrClosed
+		}
+	}
+}
+
+// LastEventID returns the ID of the most recent event.
+func (s *Stream) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+// Close closes the connection and stops the Stream from reconnecting.
+// It may be called concurrently with Next.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+		s.closeBodyLocked()
+	}
+	return nil
+}
+
+func (s *Stream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Stream) retryDelay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.retry
+}
+
+func (s *Stream) closeBodyLocked() {
+	if s.body != nil {
+		s.body.Close()
+		s.body, s.r = nil, nil
+	}
+}
+
+// A responseError reports a response which ends the stream.
+type responseError struct {
+	resp *http.Response
+}
+
+func (e *responseError) Error() string {
+	if e.resp.StatusCode != http.StatusOK {
+		return "sse: server responded with status " + e.resp.Status
+	}
+	return fmt.Sprintf("sse: server responded with Content-Type %q", e.resp.Header.Get("Content-Type"))
+}
+
+// connect returns the Reader for the current connection,
+// making a new connection if there is none.
+func (s *Stream) connect() (*Reader, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if s.r != nil {
+		r := s.r
+		s.mu.Unlock()
+		return r, nil
+	}
+	hasBody := s.req.Body != nil && s.req.Body != http.NoBody
+	if hasBody && s.req.GetBody == nil && s.sent {
+		s.mu.Unlock()
+		return nil, errNoGetBody
+	}
+	s.sent = true
+	req := s.req.Clone(s.req.Context())
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+	s.mu.Unlock()
+
+	if hasBody && s.req.GetBody != nil {
+		body, err := s.req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != ContentType {
+		resp.Body.Close()
+		return nil, &responseError{resp}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		resp.Body.Close()
+		return nil, ErrClosed
+	}
+	s.body = resp.Body
+	s.r = NewReader(resp.Body)
+	s.r.MaxEventSize = s.MaxEventSize
+	s.r.lastID = s.lastID // the last event ID persists across connections
+	return s.r, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sse implements server-sent events, as specified by the
+// HTML Living Standard's "text/event-stream" format.
+//
+// On the server side, a [Writer] sends events from an [http.Handler].
+// On the client side, a [Reader] parses events from a response body,
+// and a [Stream] reads events from a URL, reconnecting when the
+// connection is lost.
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the media type of an event stream.
+const ContentType = "text/event-stream"
+
+// An Event is a server-sent event.
+type Event struct {
+	// ID is the event's identifier.
+	// When writing, an empty ID is omitted.
+	// When reading, ID is the most recent ID sent in the stream,
+	// which a client sends in the Last-Event-ID header when
+	// it reconnects.
+	ID string
+
+	// Type is the event's type.
+	// When writing, an empty Type is omitted, and the client
+	// treats the event as having the type "message".
+	// When reading, an event without a type has the type "message".
+	Type string
+
+	// Data is the event's payload.
+	// It may contain line breaks.
+	// When writing, an event with an empty Data is still dispatched
+	// to the client unless it has only an ID or a Retry, which the
+	// client records without dispatching an event.
+	Data string
+
+	// Retry, if positive, asks the client to wait this long before
+	// reconnecting if the connection is lost. It is only written;
+	// see [Reader.Retry] for reading.
+	Retry time.Duration
+}
+
+var errInvalidField = errors.New("sse: event ID or type contains a line break")
+
+// A Writer writes server-sent events to an [http.ResponseWriter].
+//
+// A Writer's methods may be called concurrently, but, as with the
+// ResponseWriter, not after the handler has returned.
+type Writer struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context // the request's context
+
+	mu        sync.Mutex
+	err       error // sticky write error
+	lastWrite time.Time
+	heartbeat *time.Ticker
+	stop      chan struct{} // closed to stop the heartbeat goroutine
+}
+
+// NewWriter returns a Writer which sends events on w, the
+// ResponseWriter for the request r.
+//
+// NewWriter sets the Content-Type header to "text/event-stream"
+// and the Cache-Control header to "no-cache". The response header
+// is sent with the first event, comment, or Flush.
+func NewWriter(w http.ResponseWriter, r *http.Request) *Writer {
+	h := w.Header()
+	h.Set("Content-Type", ContentType)
+	h.Set("Cache-Control", "no-cache")
+	return &Writer{
+		w:   w,
+		rc:  http.NewResponseController(w),
+		ctx: r.Context(),
+	}
+}
+
+// Send writes an event and flushes it to the client.
+func (w *Writer) Send(e Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.writeLocked(e); err != nil {
+		return err
+	}
+	return w.flushLocked()
+}
+
+// WriteEvent writes an event without flushing it.
+// The event is sent to the client when the underlying ResponseWriter's
+// buffer fills, or at the next call to Flush or Send.
+func (w *Writer) WriteEvent(e Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(e)
+}
+
+// Comment writes a comment, which the client ignores, and flushes it.
+// Line breaks in text start new comment lines.
+func (w *Writer) Comment(text string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var b strings.Builder
+	appendLines(&b, ":", text)
+	b.WriteString("\n")
+	if err := w.writeStringLocked(b.String()); err != nil {
+		return err
+	}
+	return w.flushLocked()
+}
+
+// Flush sends any buffered events to the client.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushLocked()
+}
+
+// Heartbeat arranges for an empty comment to be sent whenever no data
+// has been written for the given interval, which keeps intermediaries
+// from closing an idle connection. An interval of zero or less stops
+// the heartbeat.
+//
+// The heartbeat stops when the request's context is done, which
+// happens no later than when the handler returns, or when
+// [Writer.Close] is called.
+func (w *Writer) Heartbeat(interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopHeartbeatLocked()
+	if interval <= 0 {
+		return
+	}
+	w.lastWrite = time.Now()
+	t := time.NewTicker(interval)
+	stop := make(chan struct{})
+	w.heartbeat, w.stop = t, stop
+	go func() {
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-w.ctx.Done():
+				return
+			case now := <-t.C:
+				w.mu.Lock()
+				// The ResponseWriter must not be used once the
+				// request is done.
+				if w.stop == stop && w.ctx.Err() == nil && now.Sub(w.lastWrite) >= interval {
+					if w.writeStringLocked(":\n\n") == nil {
+						w.flushLocked()
+					}
+				}
+				w.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Close stops any heartbeat. It does not close the connection,
+// which ends when the handler returns. The heartbeat also stops
+// when the request's context is done, so calling Close is optional.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopHeartbeatLocked()
+	return nil
+}
+
+func (w *Writer) stopHeartbeatLocked() {
+	if w.stop != nil {
+		w.heartbeat.Stop()
+		close(w.stop)
+		w.heartbeat, w.stop = nil, nil
+	}
+}
+
+func (w *Writer) writeLocked(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Type, "\r\n") {
+		return errInvalidField
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(e.ID)
+		b.WriteString("\n")
+	}
+	if e.Type != "" {
+		b.WriteString("event: ")
+		b.WriteString(e.Type)
+		b.WriteString("\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(e.Retry.Milliseconds(), 10))
+		b.WriteString("\n")
+	}
+	// A client dispatches only events with a data field. An event with
+	// only an ID or Retry just updates the client's state.
+	if e.Data != "" || e.Type != "" || (e.ID == "" && e.Retry <= 0) {
+		appendLines(&b, "data: ", e.Data)
+	}
+	b.WriteString("\n")
+	return w.writeStringLocked(b.String())
+}
+
+// appendLines writes each line of text to b, preceded by prefix.
+func appendLines(b *strings.Builder, prefix, text string) {
+	for {
+		i := strings.IndexAny(text, "\r\n")
+		if i < 0 {
+			break
+		}
+		b.WriteString(prefix)
+		b.WriteString(text[:i])
+		b.WriteString("\n")
+		if strings.HasPrefix(text[i:], "\r\n") {
+			i++
+		}
+		text = text[i+1:]
+	}
+	b.WriteString(prefix)
+	b.WriteString(text)
+	b.WriteString("\n")
+}
+
+func (w *Writer) writeStringLocked(s string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.lastWrite = time.Now()
+	_, w.err = w.w.Write([]byte(s))
+	return w.err
+}
+
+func (w *Writer) flushLocked() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.lastWrite = time.Now()
+	w.err = w.rc.Flush()
+	return w.err
+}
@@ -0,0 +1,417 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewWriter(rec, httptest.NewRequest("GET", "/", nil))
+	if err := w.Send(Event{ID: "1", Type: "greeting", Data: "hello\nworld\r\n!", Retry: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteEvent(Event{Data: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteEvent(Event{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Comment("note\nmore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Send(Event{ID: "a\nb"}); err == nil {
+		t.Errorf("Send with a line break in the ID succeeded")
+	}
+	if !rec.Flushed {
+		t.Errorf("response was not flushed")
+	}
+	want := "id: 1\nevent: greeting\nretry: 2000\ndata: hello\ndata: world\ndata: !\n\n" +
+		"data: x\n\n" +
+		"data: \n\n" +
+		":note\n:more\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body:\n%q\nwant:\n%q", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	events := []Event{
+		{Type: "done"},
+		{ID: "1"},
+		{ID: "2", Type: "update"},
+		{Type: "update", Data: "a\nb"},
+		{Retry: time.Second},
+		{},
+		{Data: "last"},
+	}
+	rec := httptest.NewRecorder()
+	w := NewWriter(rec, httptest.NewRequest("GET", "/", nil))
+	for _, e := range events {
+		if err := w.WriteEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := NewReader(rec.Body)
+	var got []Event
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	// The events with only an ID or Retry are not dispatched.
+	want := []Event{
+		{Type: "done"},
+		{ID: "2", Type: "update"},
+		{ID: "2", Type: "update", Data: "a\nb"},
+		{ID: "2", Type: "message"},
+		{ID: "2", Type: "message", Data: "last"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events:\n%+v\nwant:\n%+v", got, want)
+	}
+	if r.Retry() != time.Second {
+		t.Errorf("Retry() = %v, want 1s", r.Retry())
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		want  []Event
+		retry time.Duration
+	}{{
+		name:  "simple",
+		input: "data: hello\n\n",
+		want:  []Event{{Type: "message", Data: "hello"}},
+	}, {
+		name:  "fields",
+		input: "id: 7\nevent: update\ndata: a\ndata:b\ndata\n\n",
+		want:  []Event{{ID: "7", Type: "update", Data: "a\nb\n"}},
+	}, {
+		name:  "line endings",
+		input: "\xEF\xBB\xBFdata: 1\r\n\r\ndata: 2\r\rdata: 3\n\n",
+		want: []Event{
+			{Type: "message", Data: "1"},
+			{Type: "message", Data: "2"},
+			{Type: "message", Data: "3"},
+		},
+	}, {
+		name:  "id persists",
+		input: "id: 1\ndata: a\n\ndata: b\n\nid\ndata: c\n\n",
+		want: []Event{
+			{ID: "1", Type: "message", Data: "a"},
+			{ID: "1", Type: "message", Data: "b"},
+			{ID: "", Type: "message", Data: "c"},
+		},
+	}, {
+		name:  "ignored",
+		input: ": comment\nevent: x\n\nid: a\x00b\nunknown: field\ndata:  two spaces\n\n",
+		want:  []Event{{Type: "message", Data: " two spaces"}},
+	}, {
+		name:  "retry",
+		input: "retry: 1500\n\nretry: 1x\n\nretry: -1\n\n",
+		retry: 1500 * time.Millisecond,
+	}, {
+		name:  "incomplete",
+		input: "data: complete\n\ndata: incomplete\n",
+		want:  []Event{{Type: "message", Data: "complete"}},
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(test.input))
+			var got []Event
+			for {
+				e, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, e)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("events:\n%+v\nwant:\n%+v", got, test.want)
+			}
+			if r.Retry() != test.retry {
+				t.Errorf("Retry() = %v, want %v", r.Retry(), test.retry)
+			}
+		})
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	var lastIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "text/event-stream" {
+			t.Errorf("Accept = %q, want text/event-stream", got)
+		}
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		switch len(lastIDs) {
+		case 1:
+			sw := NewWriter(w, r)
+			sw.Send(Event{Data: "one", ID: "1", Retry: time.Millisecond})
+			sw.Send(Event{Data: "two", ID: "2"})
+		case 2:
+			sw := NewWriter(w, r)
+			sw.Send(Event{Data: "three"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	s := NewStream(ts.Client(), req)
+	defer s.Close()
+	var got []string
+	for {
+		e, err := s.Next()
+		if err != nil {
+			if !strings.Contains(err.Error(), "204") {
+				t.Errorf("Next: %v, want error reporting status 204", err)
+			}
+			break
+		}
+		got = append(got, e.ID+":"+e.Data)
+	}
+	if want := []string{"1:one", "2:two", "2:three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if want := []string{"", "2", "2"}; !reflect.DeepEqual(lastIDs, want) {
+		t.Errorf("Last-Event-ID headers = %q, want %q", lastIDs, want)
+	}
+}
+
+func TestStreamReconnectIDWithoutEvent(t *testing.T) {
+	var lastIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		switch len(lastIDs) {
+		case 1:
+			w.Header().Set("Content-Type", ContentType)
+			io.WriteString(w, "retry: 1\ndata: one\nid: 1\n\nid: 2\n")
+		case 2:
+			w.Header().Set("Content-Type", ContentType)
+			io.WriteString(w, "id: 3\ndata: cut off")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	s := NewStream(ts.Client(), req)
+	defer s.Close()
+	if e, err := s.Next(); err != nil || e.Data != "one" {
+		t.Fatalf("Next = %+v, %v; want first event", e, err)
+	}
+	if _, err := s.Next(); err == nil || !strings.Contains(err.Error(), "204") {
+		t.Errorf("Next: %v, want error reporting status 204", err)
+	}
+	if want := []string{"", "2", "3"}; !reflect.DeepEqual(lastIDs, want) {
+		t.Errorf("Last-Event-ID headers = %q, want %q", lastIDs, want)
+	}
+	if got := s.LastEventID(); got != "3" {
+		t.Errorf("LastEventID = %q, want 3", got)
+	}
+}
+
+func TestStreamReconnectWithoutGetBody(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		NewWriter(w, r).Send(Event{Data: "only", Retry: time.Millisecond})
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL, io.NopCloser(strings.NewReader("body")))
+	s := NewStream(ts.Client(), req)
+	defer s.Close()
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Next(); err == nil || !strings.Contains(err.Error(), "rewindable") {
+		t.Errorf("Next = %v, want error reporting a non-rewindable body", err)
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1", requests)
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := NewWriter(w, r)
+		sw.Send(Event{Data: "first"})
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	s := NewStream(ts.Client(), req)
+	if e, err := s.Next(); err != nil || e.Data != "first" {
+		t.Fatalf("Next = %+v, %v; want first event", e, err)
+	}
+	time.AfterFunc(10*time.Millisecond, func() { s.Close() })
+	if _, err := s.Next(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Next after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestStreamContextCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NewWriter(w, r).Send(Event{Data: "only", Retry: time.Hour})
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	s := NewStream(ts.Client(), req)
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := s.Next(); err != context.Canceled {
+		t.Errorf("Next = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamBadContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "data: no\n\n")
+	}))
+	defer ts.Close()
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	if _, err := NewStream(ts.Client(), req).Next(); err == nil || !strings.Contains(err.Error(), "text/plain") {
+		t.Errorf("Next = %v, want Content-Type error", err)
+	}
+}
+
+func TestWriterHeartbeat(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := NewWriter(w, r)
+		defer sw.Close()
+		sw.Heartbeat(5 * time.Millisecond)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || line != ":\n" {
+		t.Errorf("first line = %q, %v; want heartbeat comment", line, err)
+	}
+}
+
+// countingWriter is a ResponseWriter which counts the writes to it.
+type countingWriter struct {
+	http.ResponseWriter
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return len(p), nil
+}
+
+func (w *countingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
+
+func TestWriterHeartbeatStopsWithRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	cw := &countingWriter{ResponseWriter: httptest.NewRecorder()}
+	sw := NewWriter(cw, r)
+	sw.Heartbeat(time.Millisecond)
+	for cw.count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The handler returns without calling Close.
+	cancel()
+	time.Sleep(5 * time.Millisecond)
+	n := cw.count()
+	time.Sleep(20 * time.Millisecond)
+	if got := cw.count(); got != n {
+		t.Errorf("heartbeat wrote %d times after the request was done", got-n)
+	}
+}
+
+func TestReaderMaxEventSize(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		stream string
+	}{
+		{"line", "data: " + strings.Repeat("x", 100) + "\n\n"},
+		{"unterminated line", "data: " + strings.Repeat("x", 100)},
+		{"data", strings.Repeat("data: xxxxxxxxxx\n", 10) + "\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader("data: ok\n\n" + test.stream + "data: after\n\n"))
+			r.MaxEventSize = 64
+			if e, err := r.Next(); err != nil || e.Data != "ok" {
+				t.Fatalf("Next = %+v, %v; want first event", e, err)
+			}
+			for range 2 {
+				if _, err := r.Next(); err != ErrEventTooLong {
+					t.Errorf("Next = %v, want ErrEventTooLong", err)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamMaxEventSize(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", ContentType)
+		io.WriteString(w, "retry: 1\ndata: "+strings.Repeat("x", 1000))
+	}))
+	defer ts.Close()
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	s := NewStream(ts.Client(), req)
+	s.MaxEventSize = 100
+	defer s.Close()
+	if _, err := s.Next(); err != ErrEventTooLong {
+		t.Errorf("Next = %v, want ErrEventTooLong", err)
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1", requests)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"internal/godebug"
+	"internal/zstd"
 	"io"
 	"log"
 	"net"
@@ -26,6 +27,7 @@ import (
 	"net/textproto"
 	"net/url"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -174,6 +176,20 @@ type Transport struct {
 	// If both are set, DialTLSContext takes priority.
 	DialTLS func(network, addr string) (net.Conn, error)
 
+	// ServiceDialer optionally specifies a dialer with which to connect
+	// to the servers of non-proxied HTTPS requests using their HTTPS
+	// DNS records (RFC 9460), as described by [net.Dialer.DialService].
+	// The records may determine the addresses and ports connected to,
+	// and the protocols offered with ALPN. If the record of the server
+	// connected to has an ECH configuration, it is used to encrypt the
+	// TLS ClientHello, unless TLSClientConfig sets
+	// EncryptedClientHelloConfigList or does not permit TLS 1.3.
+	//
+	// If ServiceDialer is set, the Dial and DialContext hooks are not
+	// used for HTTPS requests. It is ignored if DialTLSContext or
+	// DialTLS is set.
+	ServiceDialer *net.Dialer
+
 	// TLSClientConfig specifies the TLS configuration to use with
 	// tls.Client.
 	// If nil, the default configuration is used.
@@ -192,13 +208,16 @@ type Transport struct {
 	DisableKeepAlives bool
 
 	// DisableCompression, if true, prevents the Transport from
-	// requesting compression with an "Accept-Encoding: gzip"
+	// requesting compression with an "Accept-Encoding: gzip, zstd"
 	// request header when the Request contains no existing
-	// Accept-Encoding value. If the Transport requests gzip on
-	// its own and gets a gzipped response, it's transparently
-	// decoded in the Response.Body. However, if the user
-	// explicitly requested gzip it is not automatically
+	// Accept-Encoding value. If the Transport requests compression
+	// on its own and gets a gzip or zstd compressed response, it's
+	// transparently decoded in the Response.Body. However, if the
+	// user explicitly requested compression it is not automatically
 	// uncompressed.
+	//
+	// Setting GODEBUG=httpzstd=0 restores the earlier behavior of
+	// requesting only gzip compression ("Accept-Encoding: gzip").
 	DisableCompression bool
 
 	// MaxIdleConns controls the maximum number of idle (keep-alive)
@@ -287,12 +306,29 @@ type Transport struct {
 	h2transport        h2Transport // non-nil if http2 wired up
 	tlsNextProtoWasNil bool        // whether TLSNextProto was nil when the Once fired
 
+	h3mu        sync.Mutex
+	h3transport *HTTP3Transport      // created on first use of HTTP/3
+	h3broken    map[string]time.Time // addresses with failed HTTP/3 dials, and when to retry them
+
 	// ForceAttemptHTTP2 controls whether HTTP/2 is enabled when a non-zero
 	// Dial, DialTLS, or DialContext func or TLSClientConfig is provided.
 	// By default, use of any those fields conservatively disables HTTP/2.
 	// To use a custom dialer or TLS config and still attempt HTTP/2
 	// upgrades, set this to true.
 	ForceAttemptHTTP2 bool
+
+	// Protocols is the set of protocols supported by the transport.
+	//
+	// If Protocols includes UnencryptedHTTP2 and does not include HTTP1,
+	// the transport will use unencrypted HTTP/2 for requests for http:// URLs,
+	// assuming the server supports it ("prior knowledge").
+	// Unencrypted HTTP/2 requires the HTTP/2 implementation bundled with
+	// this package.
+	//
+	// If Protocols is nil, the default is usually HTTP/1 only.
+	// If ForceAttemptHTTP2 is true, or if TLSNextProto contains an "h2" entry,
+	// the default is HTTP/1 and HTTP/2.
+	Protocols *Protocols
 }
 
 func (t *Transport) writeBufferSize() int {
@@ -319,6 +355,7 @@ func (t *Transport) Clone() *Transport {
 		Dial:                   t.Dial,
 		DialTLS:                t.DialTLS,
 		DialTLSContext:         t.DialTLSContext,
+		ServiceDialer:          t.ServiceDialer,
 		TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
 		DisableKeepAlives:      t.DisableKeepAlives,
 		DisableCompression:     t.DisableCompression,
@@ -338,6 +375,10 @@ func (t *Transport) Clone() *Transport {
 	if t.TLSClientConfig != nil {
 		t2.TLSClientConfig = t.TLSClientConfig.Clone()
 	}
+	if t.Protocols != nil {
+		t2.Protocols = &Protocols{}
+		*t2.Protocols = *t.Protocols
+	}
 	if !t.tlsNextProtoWasNil {
 		npm := map[string]func(authority string, c *tls.Conn) RoundTripper{}
 		for k, v := range t.TLSNextProto {
@@ -364,6 +405,20 @@ func (t *Transport) hasCustomTLSDialer() bool {
 
 var http2client = godebug.New("http2client")
 
+// GODEBUG=httpzstd=0 restores the earlier behavior of requesting only
+// gzip compression when the Transport adds an Accept-Encoding header.
+var httpzstd = godebug.New("httpzstd")
+
+// defaultAcceptEncoding returns the Accept-Encoding header value
+// a Transport sends when it requests compression on its own.
+func defaultAcceptEncoding() string {
+	if httpzstd.Value() == "0" {
+		httpzstd.IncNonDefault()
+		return "gzip"
+	}
+	return "gzip, zstd"
+}
+
 // onceSetNextProtoDefaults initializes TLSNextProto.
 // It must be called via t.nextProtoOnce.Do.
 func (t *Transport) onceSetNextProtoDefaults() {
@@ -393,13 +448,7 @@ func (t *Transport) onceSetNextProtoDefaults() {
 		// Transport.
 		return
 	}
-	if !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialContext != nil || t.hasCustomTLSDialer()) {
-		// Be conservative and don't automatically enable
-		// http2 if they've specified a custom TLS config or
-		// custom dialers. Let them opt-in themselves via
-		// http2.ConfigureTransport so we don't surprise them
-		// by modifying their tls.Config. Issue 14275.
-		// However, if ForceAttemptHTTP2 is true, it overrides the above checks.
+	if p := t.protocols(); !p.HTTP2() && !p.UnencryptedHTTP2() {
 		return
 	}
 	if omitBundledHTTP2 {
@@ -428,6 +477,35 @@ func (t *Transport) onceSetNextProtoDefaults() {
 	}
 }
 
+// protocols returns the set of protocols supported by the transport.
+// It must not be called before onceSetNextProtoDefaults,
+// which may populate TLSNextProto.
+func (t *Transport) protocols() Protocols {
+	if t.Protocols != nil {
+		return *t.Protocols // user-configured set
+	}
+	var p Protocols
+	p.SetHTTP1(true) // default always includes HTTP/1
+	switch {
+	case t.TLSNextProto != nil:
+		// Setting TLSNextProto to an empty map is a documented way
+		// to disable HTTP/2 on a Transport.
+		if t.TLSNextProto["h2"] != nil {
+			p.SetHTTP2(true)
+		}
+	case !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialContext != nil || t.hasCustomTLSDialer()):
+		// Be conservative and don't automatically enable
+		// http2 if they've specified a custom TLS config or
+		// custom dialers. Let them opt-in themselves via
+		// http2.ConfigureTransport so we don't surprise them
+		// by modifying their tls.Config. Issue 14275.
+		// However, if ForceAttemptHTTP2 is true, it overrides the above checks.
+	default:
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
 // ProxyFromEnvironment returns the URL of the proxy to use for a
 // given request, as indicated by the environment variables
 // HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or the lowercase versions
@@ -512,6 +590,10 @@ func (t *Transport) alternateRoundTripper(req *Request) RoundTripper {
 
 func validateHeaders(hdrs Header) string {
 	for k, vv := range hdrs {
+		if k == ":protocol" {
+			// The extended CONNECT pseudo-header; see Request.isExtendedConnect.
+			continue
+		}
 		if !httpguts.ValidHeaderFieldName(k) {
 			return fmt.Sprintf("field name %q", k)
 		}
@@ -559,6 +641,17 @@ func (t *Transport) roundTrip(req *Request) (_ *Response, err error) {
 	origReq := req
 	req = setupRewindBody(req)
 
+	if t.useHTTP3(req) {
+		resp, err := t.roundTripHTTP3(req)
+		if err == nil {
+			resp.Request = origReq
+			return resp, nil
+		}
+		if err != errHTTP3Fallback {
+			return nil, err
+		}
+	}
+
 	if altRT := t.alternateRoundTripper(req); altRT != nil {
 		if resp, err := altRT.RoundTrip(req); err != ErrSkipAltProtocol {
 			return resp, err
@@ -640,6 +733,11 @@ func (t *Transport) roundTrip(req *Request) (_ *Response, err error) {
 		if pconn.alt != nil {
 			// HTTP/2 path.
 			resp, err = pconn.alt.RoundTrip(req)
+		} else if req.isExtendedConnect() {
+			// The connection was never used; return it to the pool.
+			t.putOrCloseIdleConn(pconn)
+			req.closeBody()
+			return nil, errors.New("net/http: extended CONNECT request requires HTTP/2")
 		} else {
 			resp, err = pconn.roundTrip(treq)
 		}
@@ -855,6 +953,12 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	t.h3mu.Lock()
+	t3 := t.h3transport
+	t.h3mu.Unlock()
+	if t3 != nil {
+		t3.CloseIdleConnections()
+	}
 }
 
 // prepareTransportCancel sets up state to convert Transport.CancelRequest into context cancelation.
@@ -926,9 +1030,24 @@ func (t *Transport) connectMethodForRequest(treq *transportRequest) (cm connectM
 		cm.proxyURL, err = t.Proxy(treq.Request)
 	}
 	cm.onlyH1 = treq.requiresHTTP1()
+	if cm.proxyURL != nil && cm.targetScheme == "https" &&
+		(cm.proxyURL.Scheme == "http" || cm.proxyURL.Scheme == "https") {
+		cm.authenticator, _ = treq.ctx.Value(proxyAuthKey{}).(Authenticator)
+		cm.authenticatorID = authenticatorIdentity(cm.authenticator)
+	}
 	return cm, err
 }
 
+// drainProxyConnectResponse reads and closes the body of a response
+// to a CONNECT request, and reports whether it was read completely,
+// so that the connection may be used for another CONNECT request.
+func drainProxyConnectResponse(resp *Response) bool {
+	const maxBody = 64 << 10
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxBody+1))
+	resp.Body.Close()
+	return err == nil && n <= maxBody
+}
+
 // proxyAuth returns the Proxy-Authorization header to set
 // on requests, if applicable.
 func (cm *connectMethod) proxyAuth() string {
@@ -1628,12 +1747,17 @@ func (t *Transport) decConnsPerHost(key connectMethodKey) {
 // The remote endpoint's name may be overridden by TLSClientConfig.ServerName.
 func (pconn *persistConn) addTLS(ctx context.Context, name string, trace *httptrace.ClientTrace) error {
 	// Initiate TLS and check remote host name against certificate.
-	cfg := cloneTLSConfig(pconn.t.TLSClientConfig)
+	cfg := pconn.tlsConfig()
 	if cfg.ServerName == "" {
 		cfg.ServerName = name
 	}
-	if pconn.cacheKey.onlyH1 {
-		cfg.NextProtos = nil
+	if s := pconn.svcb; s != nil {
+		cfg.NextProtos = svcbNextProtos(cfg.NextProtos, s)
+		if cfg.EncryptedClientHelloConfigList == nil &&
+			(cfg.MinVersion == 0 || cfg.MinVersion >= tls.VersionTLS13) &&
+			(cfg.MaxVersion == 0 || cfg.MaxVersion >= tls.VersionTLS13) {
+			cfg.EncryptedClientHelloConfigList = s.ECHConfigList()
+		}
 	}
 	plainConn := pconn.conn
 	tlsConn := tls.Client(plainConn, cfg)
@@ -1675,6 +1799,36 @@ func (pconn *persistConn) addTLS(ctx context.Context, name string, trace *httptr
 	return nil
 }
 
+// tlsConfig returns the TLS configuration for the connection,
+// with the protocols to offer with ALPN.
+func (pconn *persistConn) tlsConfig() *tls.Config {
+	cfg := cloneTLSConfig(pconn.t.TLSClientConfig)
+	if pconn.cacheKey.onlyH1 {
+		cfg.NextProtos = nil
+	} else if pconn.t.Protocols != nil {
+		cfg.NextProtos = adjustNextProtos(cfg.NextProtos, *pconn.t.Protocols)
+	}
+	return cfg
+}
+
+// svcbNextProtos returns the protocols in protos which are supported
+// by the endpoint described by the HTTPS record s. Since the endpoint
+// was chosen because it supports one of them, it is not empty unless
+// protos is.
+func svcbNextProtos(protos []string, s *net.SVCB) []string {
+	supported := s.ALPN()
+	if !s.NoDefaultALPN() {
+		supported = append(supported, "http/1.1")
+	}
+	var ps []string
+	for _, p := range protos {
+		if slices.Contains(supported, p) {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
 type erringRoundTripper interface {
 	RoundTripErr() error
 }
@@ -1725,7 +1879,16 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (pconn *pers
 			pconn.tlsState = &cs
 		}
 	} else {
-		conn, err := t.dial(ctx, "tcp", cm.addr())
+		var conn net.Conn
+		if d := t.ServiceDialer; d != nil && cm.scheme() == "https" && cm.proxyURL == nil {
+			alpn := pconn.tlsConfig().NextProtos
+			if len(alpn) == 0 {
+				alpn = []string{"http/1.1"}
+			}
+			conn, pconn.svcb, err = d.DialService(ctx, "tcp", cm.addr(), "https", alpn)
+		} else {
+			conn, err = t.dial(ctx, "tcp", cm.addr())
+		}
 		if err != nil {
 			return nil, wrapErr(err)
 		}
@@ -1803,51 +1966,70 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (pconn *pers
 		connectCtx, cancel := testHookProxyConnectTimeout(ctx, 1*time.Minute)
 		defer cancel()
 
-		didReadResponse := make(chan struct{}) // closed after CONNECT write+read is done or fails
-		var (
-			resp *Response
-			err  error // write or read error
-		)
-		// Write the CONNECT request & read the response.
-		go func() {
-			defer close(didReadResponse)
-			err = connectReq.Write(conn)
-			if err != nil {
-				return
+		// Okay to use and discard buffered reader here, because
+		// TLS server will not speak until spoken to.
+		br := bufio.NewReader(conn)
+		for {
+			didReadResponse := make(chan struct{}) // closed after CONNECT write+read is done or fails
+			var (
+				resp *Response
+				err  error // write or read error
+			)
+			// Write the CONNECT request & read the response.
+			go func() {
+				defer close(didReadResponse)
+				err = connectReq.Write(conn)
+				if err != nil {
+					return
+				}
+				resp, err = ReadResponse(br, connectReq)
+			}()
+			select {
+			case <-connectCtx.Done():
+				conn.Close()
+				<-didReadResponse
+				return nil, connectCtx.Err()
+			case <-didReadResponse:
+				// resp or err now set
 			}
-			// Okay to use and discard buffered reader here, because
-			// TLS server will not speak until spoken to.
-			br := bufio.NewReader(conn)
-			resp, err = ReadResponse(br, connectReq)
-		}()
-		select {
-		case <-connectCtx.Done():
-			conn.Close()
-			<-didReadResponse
-			return nil, connectCtx.Err()
-		case <-didReadResponse:
-			// resp or err now set
-		}
-		if err != nil {
-			conn.Close()
-			return nil, err
-		}
-
-		if t.OnProxyConnectResponse != nil {
-			err = t.OnProxyConnectResponse(ctx, cm.proxyURL, connectReq, resp)
 			if err != nil {
 				conn.Close()
 				return nil, err
 			}
-		}
 
-		if resp.StatusCode != 200 {
-			_, text, ok := strings.Cut(resp.Status, " ")
-			conn.Close()
-			if !ok {
-				return nil, errors.New("unknown status code")
+			if t.OnProxyConnectResponse != nil {
+				err = t.OnProxyConnectResponse(ctx, cm.proxyURL, connectReq, resp)
+				if err != nil {
+					conn.Close()
+					return nil, err
+				}
 			}
-			return nil, errors.New(text)
+
+			if resp.StatusCode == StatusProxyAuthRequired && cm.authenticator != nil &&
+				connectReq.Header.Get("Proxy-Authorization") == "" {
+				// Answer the proxy's challenge on the same connection,
+				// if the proxy keeps it open.
+				credentials, err := answerChallenges(cm.authenticator, connectReq, resp.Header["Proxy-Authenticate"])
+				if err != nil {
+					conn.Close()
+					return nil, err
+				}
+				if credentials != "" && !resp.Close && drainProxyConnectResponse(resp) {
+					connectReq.Header = connectReq.Header.Clone()
+					connectReq.Header.Set("Proxy-Authorization", credentials)
+					continue
+				}
+			}
+
+			if resp.StatusCode != 200 {
+				_, text, ok := strings.Cut(resp.Status, " ")
+				conn.Close()
+				if !ok {
+					return nil, errors.New("unknown status code")
+				}
+				return nil, errors.New(text)
+			}
+			break
 		}
 	}
 
@@ -1868,6 +2050,27 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (pconn *pers
 		}
 	}
 
+	protos := t.protocols()
+	if pconn.tlsState == nil && cm.targetScheme == "http" && !pconn.isProxy &&
+		protos.UnencryptedHTTP2() && !protos.HTTP1() {
+		// Unencrypted HTTP/2 with prior knowledge.
+		next, ok := t.TLSNextProto[nextProtoUnencryptedHTTP2]
+		if !ok {
+			pconn.conn.Close()
+			return nil, errors.New("http: Transport does not support unencrypted HTTP/2")
+		}
+		alt := next(cm.targetAddr, unencryptedTLSConn(pconn.conn))
+		if e, ok := alt.(erringRoundTripper); ok {
+			// pconn.conn was closed by next (http2configureTransports.upgradeFn).
+			return nil, e.RoundTripErr()
+		}
+		return &persistConn{t: t, cacheKey: pconn.cacheKey, alt: alt}, nil
+	}
+	if !protos.HTTP1() {
+		pconn.conn.Close()
+		return nil, errors.New("http: Transport.Protocols does not permit HTTP/1 for this connection")
+	}
+
 	pconn.br = bufio.NewReaderSize(pconn, t.readBufferSize())
 	pconn.bw = bufio.NewWriterSize(persistConnWriter{pconn}, t.writeBufferSize())
 
@@ -1928,6 +2131,12 @@ type connectMethod struct {
 	// be reused for different targetAddr values.
 	targetAddr string
 	onlyH1     bool // whether to disable HTTP/2 and force HTTP/1
+
+	// authenticator answers challenges to the CONNECT request for a
+	// tunnel to targetAddr. Tunnels established with different
+	// authenticators are pooled separately, by authenticatorID.
+	authenticator   Authenticator
+	authenticatorID any
 }
 
 func (cm *connectMethod) key() connectMethodKey {
@@ -1940,13 +2149,25 @@ func (cm *connectMethod) key() connectMethodKey {
 		}
 	}
 	return connectMethodKey{
-		proxy:  proxyStr,
-		scheme: cm.targetScheme,
-		addr:   targetAddr,
-		onlyH1: cm.onlyH1,
+		proxy:     proxyStr,
+		scheme:    cm.targetScheme,
+		addr:      targetAddr,
+		onlyH1:    cm.onlyH1,
+		proxyAuth: cm.authenticatorID,
 	}
 }
 
+// authenticatorIdentity returns a comparable value identifying auth,
+// for use in a connectMethodKey. An authenticator which is not
+// comparable is given a new identity for each request, so tunnels
+// authenticated by it are never reused.
+func authenticatorIdentity(auth Authenticator) any {
+	if auth == nil || reflect.TypeOf(auth).Comparable() {
+		return auth
+	}
+	return new(byte)
+}
+
 // scheme returns the first hop scheme: http, https, or socks5
 func (cm *connectMethod) scheme() string {
 	if cm.proxyURL != nil {
@@ -1979,6 +2200,7 @@ func (cm *connectMethod) tlsHost() string {
 type connectMethodKey struct {
 	proxy, scheme, addr string
 	onlyH1              bool
+	proxyAuth           any // identity of the Authenticator for a CONNECT tunnel
 }
 
 func (k connectMethodKey) String() string {
@@ -2002,6 +2224,7 @@ type persistConn struct {
 	cacheKey  connectMethodKey
 	conn      net.Conn
 	tlsState  *tls.ConnectionState
+	svcb      *net.SVCB           // HTTPS record of the server dialed, if any
 	br        *bufio.Reader       // from conn
 	bw        *bufio.Writer       // to conn
 	nwrite    int64               // bytes written
@@ -2305,12 +2528,19 @@ func (pc *persistConn) readLoop() {
 		}
 
 		resp.Body = body
-		if rc.addedGzip && ascii.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-			resp.Body = &gzipReader{body: body}
-			resp.Header.Del("Content-Encoding")
-			resp.Header.Del("Content-Length")
-			resp.ContentLength = -1
-			resp.Uncompressed = true
+		if rc.addedGzip {
+			switch ce := resp.Header.Get("Content-Encoding"); {
+			case ascii.EqualFold(ce, "gzip"):
+				resp.Body = &gzipReader{body: body}
+			case ascii.EqualFold(ce, "zstd"):
+				resp.Body = &zstdReader{body: body}
+			}
+			if resp.Body != body {
+				resp.Header.Del("Content-Encoding")
+				resp.Header.Del("Content-Length")
+				resp.ContentLength = -1
+				resp.Uncompressed = true
+			}
 		}
 
 		select {
@@ -2679,15 +2909,15 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 
 	// Ask for a compressed version if the caller didn't set their
 	// own value for Accept-Encoding. We only attempt to
-	// uncompress the gzip stream if we were the layer that
-	// requested it.
+	// uncompress the gzip or zstd stream if we were the layer
+	// that requested it.
 	requestedGzip := false
 	if !pc.t.DisableCompression &&
 		req.Header.Get("Accept-Encoding") == "" &&
 		req.Header.Get("Range") == "" &&
 		req.Method != "HEAD" {
-		// Request gzip only, not deflate. Deflate is ambiguous and
-		// not as universally supported anyway.
+		// Request gzip and zstd only, not deflate. Deflate is ambiguous
+		// and not as universally supported anyway.
 		// See: https://zlib.net/zlib_faq.html#faq39
 		//
 		// Note that we don't request this for HEAD requests,
@@ -2699,7 +2929,7 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		// auto-decoding a portion of a gzipped document will just fail
 		// anyway. See https://golang.org/issue/8923
 		requestedGzip = true
-		req.extraHeaders().Set("Accept-Encoding", "gzip")
+		req.extraHeaders().Set("Accept-Encoding", defaultAcceptEncoding())
 	}
 
 	var continueCh chan struct{}
@@ -2981,6 +3211,31 @@ func (gz *gzipReader) Close() error {
 	return gz.body.Close()
 }
 
+// zstdReader wraps a response body so it can lazily
+// create a zstd decoder on the first call to Read.
+// It is used by the HTTP/1, HTTP/2, and HTTP/3 transports.
+type zstdReader struct {
+	_    incomparable
+	body io.ReadCloser // underlying Response.Body
+	zr   *zstd.Reader  // lazily-initialized zstd reader
+	zerr error         // sticky error
+}
+
+func (zr *zstdReader) Read(p []byte) (n int, err error) {
+	if zr.zerr != nil {
+		return 0, zr.zerr
+	}
+	if zr.zr == nil {
+		zr.zr = zstd.NewReader(zr.body)
+	}
+	return zr.zr.Read(p)
+}
+
+func (zr *zstdReader) Close() error {
+	zr.zerr = errReadOnClosedResBody
+	return zr.body.Close()
+}
+
 type tlsHandshakeTimeoutError struct{}
 
 func (tlsHandshakeTimeoutError) Timeout() bool   { return true }
@@ -44,6 +44,7 @@ import (
 	"testing/iotest"
 	"time"
 
+	"golang.org/x/net/dns/dnsmessage"
 	"golang.org/x/net/http/httpguts"
 )
 
@@ -1013,13 +1014,44 @@ func testTransportHeadChunkedResponse(t *testing.T, mode testMode) {
 	}
 }
 
+func TestTransportAcceptEncodingGODEBUG(t *testing.T) {
+	run(t, func(t *testing.T, mode testMode) {
+		t.Run("default", func(t *testing.T) {
+			testTransportAcceptEncodingGODEBUG(t, mode, "", "gzip, zstd")
+		})
+		t.Run("httpzstd=0", func(t *testing.T) {
+			testTransportAcceptEncodingGODEBUG(t, mode, "httpzstd=0", "gzip")
+		})
+	}, testNotParallel)
+}
+func testTransportAcceptEncodingGODEBUG(t *testing.T, mode testMode, godebug, want string) {
+	if godebug != "" {
+		t.Setenv("GODEBUG", godebug)
+	}
+	cst := newClientServerTest(t, mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Header.Get("Accept-Encoding"))
+	}))
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Accept-Encoding = %q, want %q", got, want)
+	}
+}
+
 var roundTripTests = []struct {
 	accept       string
 	expectAccept string
 	compressed   bool
 }{
 	// Requests with no accept-encoding header use transparent compression
-	{"", "gzip", false},
+	{"", "gzip, zstd", false},
 	// Requests with other accept-encoding should pass through unmodified
 	{"foo", "foo", false},
 	// Requests with accept-encoding == gzip should be passed through
@@ -1036,7 +1068,7 @@ func testRoundTripGzip(t *testing.T, mode testMode) {
 			t.Errorf("in handler, test %v: Accept-Encoding = %q, want %q",
 				req.FormValue("testnum"), accept, expect)
 		}
-		if accept == "gzip" {
+		if strings.HasPrefix(accept, "gzip") {
 			rw.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(rw)
 			gz.Write([]byte(responseBody))
@@ -1050,7 +1082,7 @@ func testRoundTripGzip(t *testing.T, mode testMode) {
 
 	for i, test := range roundTripTests {
 		// Test basic request (no accept-encoding)
-		req, _ := NewRequest("GET", fmt.Sprintf("%s/?testnum=%d&expect_accept=%s", ts.URL, i, test.expectAccept), nil)
+		req, _ := NewRequest("GET", fmt.Sprintf("%s/?testnum=%d&expect_accept=%s", ts.URL, i, url.QueryEscape(test.expectAccept)), nil)
 		if test.accept != "" {
 			req.Header.Set("Accept-Encoding", test.accept)
 		}
@@ -1103,7 +1135,7 @@ func testTransportGzip(t *testing.T, mode testMode) {
 			}
 			return
 		}
-		if g, e := req.Header.Get("Accept-Encoding"), "gzip"; g != e {
+		if g, e := req.Header.Get("Accept-Encoding"), "gzip, zstd"; g != e {
 			t.Errorf("Accept-Encoding = %q, want %q", g, e)
 		}
 		rw.Header().Set("Content-Encoding", "gzip")
@@ -3798,7 +3830,7 @@ func testRetryRequestsOnError(t *testing.T, mode testMode) {
 			req: func() *Request {
 				return newRequest("GET", "http://fake.golang", nil)
 			},
-			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip\r\n\r\n`,
+			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip, zstd\r\n\r\n`,
 		},
 		{
 			name: "IdempotentGetBodySomeWritten",
@@ -3810,7 +3842,7 @@ func testRetryRequestsOnError(t *testing.T, mode testMode) {
 			req: func() *Request {
 				return newRequest("GET", "http://fake.golang", strings.NewReader("foo\n"))
 			},
-			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip\r\n\r\nfoo\n`,
+			reqString: `GET / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip, zstd\r\n\r\nfoo\n`,
 		},
 		{
 			name: "NothingWrittenNoBody",
@@ -3821,7 +3853,7 @@ func testRetryRequestsOnError(t *testing.T, mode testMode) {
 			req: func() *Request {
 				return newRequest("DELETE", "http://fake.golang", nil)
 			},
-			reqString: `DELETE / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip\r\n\r\n`,
+			reqString: `DELETE / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nAccept-Encoding: gzip, zstd\r\n\r\n`,
 		},
 		{
 			name: "NothingWrittenGetBody",
@@ -3834,7 +3866,7 @@ func testRetryRequestsOnError(t *testing.T, mode testMode) {
 			req: func() *Request {
 				return newRequest("POST", "http://fake.golang", strings.NewReader("foo\n"))
 			},
-			reqString: `POST / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip\r\n\r\nfoo\n`,
+			reqString: `POST / HTTP/1.1\r\nHost: fake.golang\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 4\r\nAccept-Encoding: gzip, zstd\r\n\r\nfoo\n`,
 		},
 	}
 
@@ -4447,7 +4479,7 @@ func TestTransportFlushesBodyChunks(t *testing.T) {
 	defer res.Body.Close()
 
 	want := []string{
-		"POST / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: x\r\nTransfer-Encoding: chunked\r\nAccept-Encoding: gzip\r\n\r\n",
+		"POST / HTTP/1.1\r\nHost: localhost:8080\r\nUser-Agent: x\r\nTransfer-Encoding: chunked\r\nAccept-Encoding: gzip, zstd\r\n\r\n",
 		"5\r\nnum0\n\r\n",
 		"5\r\nnum1\n\r\n",
 		"5\r\nnum2\n\r\n",
@@ -5012,7 +5044,7 @@ func testTransportEventTrace(t *testing.T, mode testMode, noHooks bool) {
 		wantOnce(fmt.Sprintf("WroteHeaderField: Host: [dns-is-faked.golang:%s]", port))
 		wantOnce(fmt.Sprintf("WroteHeaderField: Content-Length: [%d]", len(body)))
 		wantOnce("WroteHeaderField: X-Foo-Multiple-Vals: [bar baz]")
-		wantOnce("WroteHeaderField: Accept-Encoding: [gzip]")
+		wantOnce("WroteHeaderField: Accept-Encoding: [gzip, zstd]")
 	}
 	wantOnce("WroteHeaders")
 	wantOnce("Wait100Continue")
@@ -6332,6 +6364,8 @@ func TestTransportClone(t *testing.T) {
 		},
 		ReadBufferSize:  1,
 		WriteBufferSize: 1,
+		Protocols:       &Protocols{},
+		ServiceDialer:   &net.Dialer{},
 	}
 	tr2 := tr.Clone()
 	rv := reflect.ValueOf(tr2).Elem()
@@ -7133,3 +7167,75 @@ func testValidateClientRequestTrailers(t *testing.T, mode testMode) {
 		})
 	}
 }
+
+type dnsTransportFunc func(ctx context.Context, address string, msg []byte) ([]byte, error)
+
+func (f dnsTransportFunc) Exchange(ctx context.Context, address string, msg []byte) ([]byte, error) {
+	return f(ctx, address, msg)
+}
+
+func TestTransportServiceDialer(t *testing.T) {
+	CondSkipHTTP2(t)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	port := ts.Listener.Addr().(*net.TCPAddr).Port
+
+	// example.com has an HTTPS record for an endpoint on the port
+	// of the test server, which supports only HTTP/2.
+	const typeHTTPS = dnsmessage.Type(65)
+	svcb := []byte{
+		0, 1, 0,
+		0, 1, 0, 3, 2, 'h', '2',
+		0, 2, 0, 0,
+		0, 3, 0, 2, byte(port >> 8), byte(port),
+	}
+	var queried atomic.Bool
+	resolver := &net.Resolver{Transport: dnsTransportFunc(func(ctx context.Context, address string, msg []byte) ([]byte, error) {
+		var q dnsmessage.Message
+		if err := q.Unpack(msg); err != nil {
+			return nil, err
+		}
+		r := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: q.ID, Response: true, RecursionAvailable: true},
+			Questions: q.Questions,
+		}
+		qq := q.Questions[0]
+		hdr := dnsmessage.ResourceHeader{Name: qq.Name, Type: qq.Type, Class: dnsmessage.ClassINET, TTL: 60}
+		if qq.Name.String() == "example.com." {
+			switch qq.Type {
+			case typeHTTPS:
+				queried.Store(true)
+				r.Answers = []dnsmessage.Resource{{Header: hdr, Body: &dnsmessage.UnknownResource{Type: typeHTTPS, Data: svcb}}}
+			case dnsmessage.TypeA:
+				r.Answers = []dnsmessage.Resource{{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}}}
+			}
+		}
+		return r.Pack()
+	})}
+
+	tr := &Transport{
+		ServiceDialer:     &net.Dialer{Resolver: resolver},
+		TLSClientConfig:   &tls.Config{RootCAs: ts.Client().Transport.(*Transport).TLSClientConfig.RootCAs},
+		ForceAttemptHTTP2: true,
+	}
+	defer tr.CloseIdleConnections()
+	res, err := (&Client{Transport: tr}).Get("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !queried.Load() {
+		t.Errorf("HTTPS record was not looked up")
+	}
+	if got, want := string(body), "HTTP/2.0"; got != want {
+		t.Errorf("server saw protocol %q, want %q", got, want)
+	}
+}
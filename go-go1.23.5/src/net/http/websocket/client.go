@@ -0,0 +1,223 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// A Dialer opens WebSocket connections.
+// The zero value is a valid Dialer which uses [http.DefaultClient].
+type Dialer struct {
+	// Client is the HTTP client used for the opening handshake.
+	// If nil, http.DefaultClient is used.
+	//
+	// The client's Timeout must be zero: it would otherwise
+	// apply to the whole lifetime of the connection.
+	Client *http.Client
+
+	// Subprotocols lists the subprotocols to request,
+	// in order of preference.
+	Subprotocols []string
+
+	// EnableCompression offers the permessage-deflate extension.
+	EnableCompression bool
+
+	// UseHTTP2, if true, opens the connection with an HTTP/2 extended
+	// CONNECT request (RFC 8441) rather than an HTTP/1.1 upgrade.
+	// The client must use HTTP/2 to connect to the server, and the
+	// server must support extended CONNECT, or Dial fails.
+	UseHTTP2 bool
+}
+
+// A HandshakeError is returned by [Dialer.Dial]
+// when the server rejects the opening handshake.
+type HandshakeError struct {
+	// StatusCode is the HTTP status code of the server's response.
+	StatusCode int
+	// Reason describes why the handshake failed.
+	Reason string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: handshake failed: " + e.Reason
+}
+
+// Dial opens a WebSocket connection to the given URL, which has the
+// scheme "ws" or "wss" (or equivalently "http" or "https").
+// The header, which may be nil, is added to the handshake request;
+// it may be used to set headers such as Origin or Cookie.
+//
+// The returned response is the server's handshake response. Its Body
+// carries the connection and must not be used. If the server rejects the
+// handshake, Dial returns the response, with its Body closed, along with
+// a [*HandshakeError].
+//
+// The context is used only for the opening handshake.
+func (d *Dialer) Dial(ctx context.Context, rawURL string, header http.Header) (_ *Conn, _ *http.Response, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, nil, errors.New("websocket: unsupported URL scheme " + u.Scheme)
+	}
+	u.Fragment = ""
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// The handshake's context governs the opening handshake only,
+	// but canceling a request's context also closes its response body.
+	// Use a separate context which is canceled only if ctx is
+	// done before the handshake completes.
+	reqCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+	defer func() {
+		if stop() && err != nil {
+			cancel()
+		}
+	}()
+
+	var netConn net.Conn
+	reqCtx = httptrace.WithClientTrace(reqCtx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			netConn = info.Conn
+		},
+	})
+
+	method := "GET"
+	if d.UseHTTP2 {
+		method = "CONNECT"
+	}
+	var pr *io.PipeReader
+	var pw *io.PipeWriter
+	var body io.Reader
+	if d.UseHTTP2 {
+		pr, pw = io.Pipe()
+		body = pr
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, u.String(), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vv := range header {
+		req.Header[k] = append(req.Header[k], vv...)
+	}
+	var key string
+	if d.UseHTTP2 {
+		req.Header.Set(":protocol", "websocket")
+		req.ContentLength = -1
+	} else {
+		var b [16]byte
+		rand.Read(b[:])
+		key = base64.StdEncoding.EncodeToString(b[:])
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", key)
+	}
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if d.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", deflateExtension)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if pw != nil {
+			pw.Close()
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, nil, err
+	}
+	fail := func(reason string) (*Conn, *http.Response, error) {
+		if pw != nil {
+			pw.Close()
+		}
+		resp.Body.Close()
+		return nil, resp, &HandshakeError{StatusCode: resp.StatusCode, Reason: reason}
+	}
+
+	if d.UseHTTP2 {
+		if resp.StatusCode != http.StatusOK {
+			return fail("unexpected status " + resp.Status)
+		}
+	} else {
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			return fail("unexpected status " + resp.Status)
+		}
+		if !headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+			!headerContainsToken(resp.Header, "Connection", "upgrade") {
+			return fail("missing Upgrade or Connection header")
+		}
+		if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+			return fail("invalid Sec-WebSocket-Accept")
+		}
+	}
+	subprotocol := resp.Header.Get("Sec-WebSocket-Protocol")
+	if subprotocol != "" && !contains(d.Subprotocols, subprotocol) {
+		return fail("server selected a subprotocol that was not requested")
+	}
+	deflate, ok := checkDeflateResponse(parseExtensions(resp.Header), d.EnableCompression)
+	if !ok {
+		return fail("unexpected Sec-WebSocket-Extensions " + strconv.Quote(resp.Header.Get("Sec-WebSocket-Extensions")))
+	}
+
+	cfg := connConfig{
+		subprotocol: subprotocol,
+		br:          bufio.NewReader(resp.Body),
+		deflate:     deflate,
+	}
+	if d.UseHTTP2 {
+		cfg.w = pw
+		cfg.close = func() error {
+			pw.Close()
+			return resp.Body.Close()
+		}
+	} else {
+		rwc, ok := resp.Body.(io.ReadWriteCloser)
+		if !ok {
+			return fail("response body is not writable")
+		}
+		cfg.w = rwc
+		cfg.close = rwc.Close
+		if netConn != nil {
+			cfg.deadlines = netConn
+		}
+	}
+	return newConn(cfg), resp, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// This file implements the permessage-deflate extension, RFC 7692.
+
+const deflateExtension = "permessage-deflate"
+
+// deflateParams are the negotiated permessage-deflate parameters.
+type deflateParams struct {
+	serverNoContextTakeover bool
+	clientNoContextTakeover bool
+}
+
+// An extension is one element of a Sec-WebSocket-Extensions header.
+type extension struct {
+	name    string
+	params  map[string]string // "" for a parameter without a value
+	invalid bool              // a parameter appears more than once
+}
+
+// parseExtensions parses the Sec-WebSocket-Extensions headers in h.
+func parseExtensions(h http.Header) []extension {
+	var exts []extension
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+		for _, s := range splitQuoted(v, ',') {
+			parts := splitQuoted(s, ';')
+			name := textproto.TrimString(parts[0])
+			if name == "" {
+				continue
+			}
+			e := extension{name: name, params: make(map[string]string)}
+			for _, p := range parts[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				k = textproto.TrimString(k)
+				v = textproto.TrimString(v)
+				if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+					v = unquote(v[1 : len(v)-1])
+				}
+				if _, dup := e.params[k]; dup {
+					e.invalid = true
+				}
+				e.params[k] = v
+			}
+			exts = append(exts, e)
+		}
+	}
+	return exts
+}
+
+// splitQuoted splits s at each sep which is not within a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && quoted:
+			i++
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote removes the escapes from the contents of a quoted-string.
+func unquote(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// validWindowBits reports whether v is a valid max_window_bits value.
+func validWindowBits(v string) bool {
+	return len(v) > 0 && len(v) <= 2 && v >= "8" && v <= "15" && (len(v) == 1 || v[0] == '1')
+}
+
+// acceptDeflate chooses the first acceptable permessage-deflate offer
+// from a client, returning the negotiated parameters and the
+// Sec-WebSocket-Extensions response value.
+func acceptDeflate(exts []extension) (*deflateParams, string) {
+offers:
+	for _, e := range exts {
+		if e.name != deflateExtension || e.invalid {
+			continue
+		}
+		p := new(deflateParams)
+		for k, v := range e.params {
+			switch k {
+			case "server_no_context_takeover":
+				if v != "" {
+					continue offers
+				}
+				p.serverNoContextTakeover = true
+			case "client_no_context_takeover":
+				if v != "" {
+					continue offers
+				}
+				p.clientNoContextTakeover = true
+			case "server_max_window_bits":
+				// compress/flate always uses a 32KB window.
+				if v != "15" {
+					continue offers
+				}
+			case "client_max_window_bits":
+				// Any window size can be decompressed.
+				if v != "" && !validWindowBits(v) {
+					continue offers
+				}
+			default:
+				continue offers
+			}
+		}
+		resp := deflateExtension
+		if p.serverNoContextTakeover {
+			resp += "; server_no_context_takeover"
+		}
+		if p.clientNoContextTakeover {
+			resp += "; client_no_context_takeover"
+		}
+		return p, resp
+	}
+	return nil, ""
+}
+
+// checkDeflateResponse validates a server's Sec-WebSocket-Extensions
+// response to a client's offer, returning the negotiated parameters,
+// or nil if no extension was accepted. It reports false if the
+// response is not valid.
+func checkDeflateResponse(exts []extension, offered bool) (*deflateParams, bool) {
+	if len(exts) == 0 {
+		return nil, true
+	}
+	if len(exts) > 1 || exts[0].name != deflateExtension || !offered || exts[0].invalid {
+		return nil, false
+	}
+	p := new(deflateParams)
+	for k, v := range exts[0].params {
+		switch {
+		case k == "server_no_context_takeover" && v == "":
+			p.serverNoContextTakeover = true
+		case k == "client_no_context_takeover" && v == "":
+			p.clientNoContextTakeover = true
+		case k == "server_max_window_bits" && validWindowBits(v):
+			// Any window size can be decompressed.
+		default:
+			return nil, false
+		}
+	}
+	return p, true
+}
+
+// flushMarker ends the output of each flush of a flate.Writer.
+// It is removed from compressed messages.
+const flushMarker = "\x00\x00\xff\xff"
+
+// deflateTail is appended to a compressed message before decompressing it:
+// the removed flush marker, followed by an empty final block.
+const deflateTail = flushMarker + "\x01\x00\x00\xff\xff"
+
+// maxWindow is the size of the DEFLATE sliding window.
+const maxWindow = 32 << 10
+
+// A compressor compresses outgoing messages.
+type compressor struct {
+	fw        *flate.Writer
+	buf       bytes.Buffer // compressed data not yet written
+	noContext bool         // reset between messages
+}
+
+func newCompressor(noContext bool) *compressor {
+	c := &compressor{noContext: noContext}
+	c.fw, _ = flate.NewWriter(&c.buf, flate.BestSpeed)
+	return c
+}
+
+// start prepares to compress a message.
+func (c *compressor) start() {
+	c.buf.Reset()
+	if c.noContext {
+		c.fw.Reset(&c.buf)
+	}
+}
+
+// finish returns the rest of the current message's compressed data.
+func (c *compressor) finish() ([]byte, error) {
+	if err := c.fw.Flush(); err != nil {
+		return nil, err
+	}
+	b := c.buf.Bytes()
+	if !bytes.HasSuffix(b, []byte(flushMarker)) {
+		return nil, errors.New("websocket: internal error: missing flush marker")
+	}
+	return b[:len(b)-len(flushMarker)], nil
+}
+
+// A decompressor decompresses incoming messages.
+type decompressor struct {
+	fr        io.ReadCloser
+	br        *bufio.Reader
+	dict      []byte // recent output, for context takeover
+	noContext bool   // reset between messages
+}
+
+func newDecompressor(noContext bool) *decompressor {
+	return &decompressor{
+		fr:        flate.NewReader(nil),
+		br:        bufio.NewReader(nil),
+		noContext: noContext,
+	}
+}
+
+// reset prepares to decompress a message with the given payload.
+func (d *decompressor) reset(payload io.Reader) {
+	d.br.Reset(io.MultiReader(payload, strings.NewReader(deflateTail)))
+	var dict []byte
+	if !d.noContext && len(d.dict) > 0 {
+		dict = d.dict[max(0, len(d.dict)-maxWindow):]
+	}
+	d.fr.(flate.Resetter).Reset(d.br, dict)
+}
+
+// record adds decompressed data to the dictionary for the next message.
+func (d *decompressor) record(p []byte) {
+	if d.noContext {
+		return
+	}
+	if len(d.dict)+len(p) > 2*maxWindow {
+		keep := max(0, maxWindow-len(p))
+		n := copy(d.dict, d.dict[max(0, len(d.dict)-keep):])
+		d.dict = d.dict[:n]
+		p = p[max(0, len(p)-maxWindow):]
+	}
+	d.dict = append(d.dict, p...)
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/internal/ascii"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acceptGUID is combined with the client's key to compute
+// the Sec-WebSocket-Accept header, RFC 6455 Section 4.2.2.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// An Upgrader upgrades HTTP requests to WebSocket connections.
+// The zero value is a valid Upgrader which accepts same-origin
+// requests without negotiating a subprotocol or compression.
+type Upgrader struct {
+	// Subprotocols lists the subprotocols supported by the server,
+	// in order of preference. The first one also requested by the
+	// client is selected. If none match, no subprotocol is selected.
+	Subprotocols []string
+
+	// CheckOrigin reports whether a request's Origin is acceptable.
+	// If nil, a request is accepted if it has no Origin header,
+	// or if the host of its Origin matches the request's Host.
+	// Requests which fail the check receive a 403 (Forbidden) response.
+	CheckOrigin func(r *http.Request) bool
+
+	// EnableCompression enables the permessage-deflate extension
+	// when the client offers it.
+	EnableCompression bool
+}
+
+// IsWebSocketUpgrade reports whether r requests a WebSocket connection,
+// either as an HTTP/1.1 upgrade or an HTTP/2 extended CONNECT request.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	if r.Method == "CONNECT" {
+		return r.ProtoMajor >= 2 && r.Header.Get(":protocol") == "websocket"
+	}
+	return r.Method == "GET" &&
+		headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade upgrades the request to a WebSocket connection.
+// Headers set in w.Header() before calling Upgrade are
+// included in the response.
+//
+// If the request is not a valid WebSocket request, Upgrade replies with
+// an HTTP error and returns an error; the handler should then return.
+//
+// For HTTP/1.1 requests, Upgrade takes over the connection using
+// [http.ResponseController.Hijack], and the Conn may be used after the
+// handler returns. For HTTP/2 requests (RFC 8441), the Conn uses the
+// request and response streams, and the handler must not return until
+// it has finished using the Conn.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method == "CONNECT" && r.ProtoMajor >= 2 {
+		if r.Header.Get(":protocol") != "websocket" {
+			return nil, u.error(w, http.StatusBadRequest, "unsupported :protocol")
+		}
+	} else {
+		if r.Method != "GET" {
+			return nil, u.error(w, http.StatusMethodNotAllowed, "method is not GET")
+		}
+		if !headerContainsToken(r.Header, "Connection", "upgrade") {
+			return nil, u.error(w, http.StatusBadRequest, "Connection header does not contain \"upgrade\"")
+		}
+		if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+			return nil, u.error(w, http.StatusBadRequest, "Upgrade header does not contain \"websocket\"")
+		}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.error(w, http.StatusUpgradeRequired, "unsupported Sec-WebSocket-Version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.error(w, http.StatusForbidden, "origin not allowed")
+	}
+
+	h := w.Header()
+	subprotocol := u.selectSubprotocol(r)
+	if subprotocol != "" {
+		h.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	var deflate *deflateParams
+	if u.EnableCompression {
+		var ext string
+		deflate, ext = acceptDeflate(parseExtensions(r.Header))
+		if deflate != nil {
+			h.Set("Sec-WebSocket-Extensions", ext)
+		}
+	}
+
+	if r.ProtoMajor >= 2 {
+		return upgradeHTTP2(w, r, subprotocol, deflate)
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, u.error(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	rc := http.NewResponseController(w)
+	netConn, brw, err := rc.Hijack()
+	if err != nil {
+		return nil, u.error(w, http.StatusInternalServerError, "connection cannot be hijacked: "+err.Error())
+	}
+	// Clear any deadlines set by the server's ReadTimeout or WriteTimeout.
+	netConn.SetDeadline(time.Time{})
+
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+	bw := brw.Writer
+	bw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(bw)
+	bw.WriteString("\r\n")
+	if err := bw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	br := brw.Reader
+	if br.Size() < 4096 {
+		br = bufio.NewReaderSize(br, 4096)
+	}
+	return newConn(connConfig{
+		isServer:    true,
+		subprotocol: subprotocol,
+		br:          br,
+		w:           netConn,
+		close:       netConn.Close,
+		deadlines:   netConn,
+		deflate:     deflate,
+	}), nil
+}
+
+// upgradeHTTP2 accepts an extended CONNECT request, RFC 8441 Section 5.
+func upgradeHTTP2(w http.ResponseWriter, r *http.Request, subprotocol string, deflate *deflateParams) (*Conn, error) {
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+	return newConn(connConfig{
+		isServer:    true,
+		subprotocol: subprotocol,
+		br:          bufio.NewReader(r.Body),
+		w:           w,
+		flush:       rc.Flush,
+		close:       r.Body.Close,
+		deadlines:   rc,
+		deflate:     deflate,
+	}), nil
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	var offered []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			offered = append(offered, textproto.TrimString(p))
+		}
+	}
+	for _, p := range u.Subprotocols {
+		for _, o := range offered {
+			if p == o {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func (u *Upgrader) error(w http.ResponseWriter, code int, reason string) error {
+	http.Error(w, http.StatusText(code), code)
+	return errors.New("websocket: handshake failed: " + reason)
+}
+
+// sameOrigin reports whether r has no Origin header,
+// or an Origin whose host matches r.Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return ascii.EqualFold(u.Host, r.Host)
+}
+
+// headerContainsToken reports whether the comma-separated
+// header h[name] contains token, ignoring case.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if ascii.EqualFold(textproto.TrimString(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,1007 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package websocket implements the WebSocket protocol defined in RFC 6455,
+// including the permessage-deflate extension defined in RFC 7692 and
+// WebSockets over HTTP/2 defined in RFC 8441.
+//
+// On the server side, an [Upgrader] turns an HTTP request into a [Conn]:
+//
+//	var upgrader websocket.Upgrader
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		c, err := upgrader.Upgrade(w, r)
+//		if err != nil {
+//			return // Upgrade has replied with an HTTP error
+//		}
+//		defer c.CloseNow()
+//		for {
+//			typ, msg, err := c.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			if err := c.WriteMessage(typ, msg); err != nil {
+//				return
+//			}
+//		}
+//	}
+//
+// On the client side, a [Dialer] opens a Conn using an [http.Client].
+//
+// A Conn supports one concurrent reader and one concurrent writer of
+// messages. Control methods such as [Conn.Ping] and [Conn.Close] may be
+// called concurrently with both. Pings from the peer are answered, and
+// pongs and close frames are processed, only while a goroutine is
+// reading from the Conn, so applications should read from every Conn
+// until an error is returned, even if they expect no messages.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// A MessageType is the type of a WebSocket data message.
+type MessageType int
+
+const (
+	// TextMessage is a message containing UTF-8 encoded text.
+	TextMessage MessageType = 1
+	// BinaryMessage is a message containing arbitrary binary data.
+	BinaryMessage MessageType = 2
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case TextMessage:
+		return "text"
+	case BinaryMessage:
+		return "binary"
+	}
+	return "MessageType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// A StatusCode is a close status code, as defined in RFC 6455, Section 7.4.
+type StatusCode int
+
+const (
+	StatusNormalClosure      StatusCode = 1000
+	StatusGoingAway          StatusCode = 1001
+	StatusProtocolError      StatusCode = 1002
+	StatusUnsupportedData    StatusCode = 1003
+	StatusNoStatusReceived   StatusCode = 1005 // never sent; reported when a close frame has no status
+	StatusAbnormalClosure    StatusCode = 1006 // never sent
+	StatusInvalidPayload     StatusCode = 1007
+	StatusPolicyViolation    StatusCode = 1008
+	StatusMessageTooBig      StatusCode = 1009
+	StatusMandatoryExtension StatusCode = 1010
+	StatusInternalError      StatusCode = 1011
+	StatusServiceRestart     StatusCode = 1012
+	StatusTryAgainLater      StatusCode = 1013
+	StatusBadGateway         StatusCode = 1014
+)
+
+// sendable reports whether code may be sent in a close frame.
+func (code StatusCode) sendable() bool {
+	switch {
+	case code >= 1000 && code <= 1003,
+		code >= 1007 && code <= 1014,
+		code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// A CloseError is returned by the read methods of a [Conn]
+// after the peer has sent a close frame.
+type CloseError struct {
+	Code   StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	s := "websocket: connection closed with status " + strconv.Itoa(int(e.Code))
+	if e.Reason != "" {
+		s += ": " + e.Reason
+	}
+	return s
+}
+
+var (
+	// ErrCloseSent is returned when writing to a Conn
+	// after a close frame has been sent.
+	ErrCloseSent = errors.New("websocket: close frame already sent")
+
+	// ErrReadLimit is returned when reading a message
+	// larger than the Conn's read limit.
+	ErrReadLimit = errors.New("websocket: message exceeds read limit")
+
+	errInvalidUTF8   = errors.New("websocket: invalid UTF-8 in text message")
+	errStaleReader   = errors.New("websocket: read from a message reader after the next message was requested")
+	errWriterClosed  = errors.New("websocket: write to a closed message writer")
+	errBadDeflate    = errors.New("websocket: invalid compressed message")
+	errBadCloseFrame = errors.New("websocket: invalid close frame")
+)
+
+// DefaultReadLimit is the default maximum size of a message read by a [Conn].
+const DefaultReadLimit = 32 << 20
+
+// closeTimeout is how long Close waits for the peer's close frame.
+const closeTimeout = 5 * time.Second
+
+// writeFrameSize is the largest frame written by a message writer.
+const writeFrameSize = 16 << 10
+
+// Frame opcodes, RFC 6455 Section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// maxControlPayload is the largest payload of a control frame.
+const maxControlPayload = 125
+
+// A Conn is a WebSocket connection.
+type Conn struct {
+	isServer    bool
+	subprotocol string
+	br          *bufio.Reader
+	w           io.Writer
+	flush       func() error // after each frame; may be nil
+	closeFn     func() error // closes the underlying transport
+
+	// setReadDeadline and setWriteDeadline set deadlines on the
+	// underlying transport, or are nil if that is not supported.
+	setReadDeadline  func(time.Time) error
+	setWriteDeadline func(time.Time) error
+
+	// msgMu is held by the open message writer, if any.
+	msgMu sync.Mutex
+	comp  *compressor // nil if compression is not in use; guarded by msgMu
+
+	wmu       sync.Mutex // serializes frame writes
+	wbuf      []byte
+	writeErr  error // sticky
+	closeSent bool
+
+	rmu            sync.Mutex // guards the following read state
+	readErr        error      // sticky
+	readLimit      int64
+	decomp         *decompressor // nil if compression is not in use
+	reader         *messageReader
+	msgType        MessageType
+	msgCompressed  bool
+	msgDone        bool // the whole message has been read
+	msgRead        int64
+	frameFin       bool
+	frameRemaining int64
+	frameMask      [4]byte
+	frameMaskPos   int
+	frameMasked    bool
+	utf8           utf8Validator
+
+	mu        sync.Mutex
+	pingSeq   uint64
+	pings     map[string]chan struct{}
+	closeRecv chan struct{} // closed when the peer's close frame is received
+
+	closeOnce sync.Once
+	closed    chan struct{} // closed when the transport is closed
+	closeErr  error
+}
+
+// connConfig describes the transport and negotiated parameters
+// of a new Conn.
+type connConfig struct {
+	isServer    bool
+	subprotocol string
+	br          *bufio.Reader
+	w           io.Writer
+	flush       func() error
+	close       func() error
+	deadlines   interface {
+		SetReadDeadline(time.Time) error
+		SetWriteDeadline(time.Time) error
+	}
+	deflate *deflateParams
+}
+
+func newConn(cfg connConfig) *Conn {
+	c := &Conn{
+		isServer:    cfg.isServer,
+		subprotocol: cfg.subprotocol,
+		br:          cfg.br,
+		w:           cfg.w,
+		flush:       cfg.flush,
+		closeFn:     cfg.close,
+		readLimit:   DefaultReadLimit,
+		pings:       make(map[string]chan struct{}),
+		closeRecv:   make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	if cfg.deadlines != nil {
+		c.setReadDeadline = cfg.deadlines.SetReadDeadline
+		c.setWriteDeadline = cfg.deadlines.SetWriteDeadline
+	}
+	if p := cfg.deflate; p != nil {
+		// The server compresses using the server_* parameters,
+		// and the client using the client_* parameters.
+		compressNoContext, decompressNoContext := p.clientNoContextTakeover, p.serverNoContextTakeover
+		if c.isServer {
+			compressNoContext, decompressNoContext = decompressNoContext, compressNoContext
+		}
+		c.comp = newCompressor(compressNoContext)
+		c.decomp = newDecompressor(decompressNoContext)
+	}
+	return c
+}
+
+// Subprotocol returns the negotiated subprotocol,
+// or "" if none was negotiated.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// CompressionEnabled reports whether the permessage-deflate
+// extension was negotiated for the connection.
+func (c *Conn) CompressionEnabled() bool {
+	return c.comp != nil
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from
+// the peer, after decompression. If a message exceeds the limit, the
+// Conn sends a close frame with [StatusMessageTooBig] and the read
+// returns [ErrReadLimit]. A limit of zero or less means no limit.
+// The default is [DefaultReadLimit].
+func (c *Conn) SetReadLimit(limit int64) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for reads from the underlying
+// connection. A zero value means reads will not time out. After a
+// read has timed out, the Conn is unusable.
+//
+// It returns an error wrapping [http.ErrNotSupported]
+// if the underlying connection does not support deadlines.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	if c.setReadDeadline == nil {
+		return errDeadlinesNotSupported
+	}
+	return c.setReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writes to the underlying
+// connection. A zero value means writes will not time out. After a
+// write has timed out, the Conn is unusable.
+//
+// It returns an error wrapping [http.ErrNotSupported]
+// if the underlying connection does not support deadlines.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	if c.setWriteDeadline == nil {
+		return errDeadlinesNotSupported
+	}
+	return c.setWriteDeadline(t)
+}
+
+var errDeadlinesNotSupported = fmt.Errorf("websocket: connection does not support deadlines: %w", http.ErrNotSupported)
+
+// CloseNow closes the underlying connection without a close handshake.
+func (c *Conn) CloseNow() error {
+	c.closeTransport()
+	return c.closeErr
+}
+
+func (c *Conn) closeTransport() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.closeErr = c.closeFn()
+	})
+}
+
+// Close performs the closing handshake: it sends a close frame with the
+// given status code and reason, waits for the peer to reply with its own
+// close frame, and closes the underlying connection. If the peer does not
+// reply within a few seconds, the connection is closed anyway.
+//
+// A code of zero sends a close frame with no status code.
+// The reason must be at most 123 bytes long.
+//
+// If another goroutine is reading from the Conn, it receives the
+// peer's close frame, and its read returns a [*CloseError].
+// Otherwise, Close reads and discards messages until the close frame
+// arrives.
+func (c *Conn) Close(code StatusCode, reason string) error {
+	var payload []byte
+	if code != 0 {
+		if !code.sendable() {
+			return errors.New("websocket: status code " + strconv.Itoa(int(code)) + " may not be sent")
+		}
+		if len(reason) > maxControlPayload-2 {
+			return errors.New("websocket: close reason too long")
+		}
+		payload = binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+	} else if reason != "" {
+		return errors.New("websocket: close reason requires a status code")
+	}
+	err := c.writeControl(opClose, payload)
+	if err == ErrCloseSent {
+		// The handshake is already underway.
+		<-c.closed
+		return nil
+	}
+	if err != nil {
+		c.closeTransport()
+		return err
+	}
+
+	t := time.AfterFunc(closeTimeout, c.closeTransport)
+	defer t.Stop()
+	if c.rmu.TryLock() {
+		for c.readErr == nil {
+			if _, err := c.nextReaderLocked(); err != nil {
+				break
+			}
+		}
+		c.rmu.Unlock()
+	} else {
+		select {
+		case <-c.closeRecv:
+		case <-c.closed:
+		}
+	}
+	c.closeTransport()
+	return nil
+}
+
+// Ping sends a ping frame and waits for the peer's pong, or until ctx is
+// done or the connection is closed. Pongs are only received while another
+// goroutine is reading from the Conn.
+func (c *Conn) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	c.pingSeq++
+	payload := binary.BigEndian.AppendUint64(nil, c.pingSeq)
+	done := make(chan struct{})
+	c.pings[string(payload)] = done
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pings, string(payload))
+		c.mu.Unlock()
+	}()
+
+	if err := c.writeControl(opPing, payload); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return net.ErrClosed
+	}
+}
+
+func (c *Conn) handlePong(payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if done, ok := c.pings[string(payload)]; ok {
+		close(done)
+		delete(c.pings, string(payload))
+	}
+}
+
+// WriteMessage writes a message of the given type.
+// It may be called concurrently with reads, but not with other writes.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if c.comp == nil {
+		if typ != TextMessage && typ != BinaryMessage {
+			return errors.New("websocket: invalid message type " + typ.String())
+		}
+		c.msgMu.Lock()
+		defer c.msgMu.Unlock()
+		return c.writeFrame(true, false, byte(typ), data)
+	}
+	w, err := c.NextWriter(typ)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// NextWriter returns a writer for the next message of the given type.
+// The message is sent in one or more frames as it is written, and is
+// complete when the writer is closed. Only one message writer may be
+// open at a time; NextWriter blocks until the previous writer is closed.
+func (c *Conn) NextWriter(typ MessageType) (io.WriteCloser, error) {
+	if typ != TextMessage && typ != BinaryMessage {
+		return nil, errors.New("websocket: invalid message type " + typ.String())
+	}
+	c.msgMu.Lock()
+	w := &messageWriter{c: c, opcode: byte(typ), first: true}
+	if c.comp != nil {
+		w.compressed = true
+		c.comp.start()
+	}
+	return w, nil
+}
+
+type messageWriter struct {
+	c          *Conn
+	opcode     byte
+	first      bool // no frame has been written yet
+	compressed bool
+	closed     bool
+	err        error
+	buf        []byte // uncompressed data not yet written
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.compressed {
+		comp := w.c.comp
+		if _, err := comp.fw.Write(p); err != nil {
+			w.err = err
+			return 0, err
+		}
+		// Hold back the last four bytes, which may be the end of
+		// the flush marker removed from the final frame.
+		for comp.buf.Len() > writeFrameSize+4 {
+			if err := w.writeFrame(false, comp.buf.Next(writeFrameSize)); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	n := len(p)
+	for len(w.buf)+len(p) > writeFrameSize {
+		if len(w.buf) == 0 {
+			// Write directly from p.
+			if err := w.writeFrame(false, p[:writeFrameSize]); err != nil {
+				return 0, err
+			}
+			p = p[writeFrameSize:]
+			continue
+		}
+		m := writeFrameSize - len(w.buf)
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		if err := w.writeFrame(false, w.buf); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[:0]
+	}
+	w.buf = append(w.buf, p...)
+	return n, nil
+}
+
+// Close writes the final frame of the message.
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return errWriterClosed
+	}
+	w.closed = true
+	defer w.c.msgMu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if w.compressed {
+		data, err := w.c.comp.finish()
+		if err != nil {
+			return err
+		}
+		return w.writeFrame(true, data)
+	}
+	return w.writeFrame(true, w.buf)
+}
+
+func (w *messageWriter) writeFrame(fin bool, data []byte) error {
+	op := byte(opContinuation)
+	if w.first {
+		op = w.opcode
+	}
+	w.err = w.c.writeFrame(fin, w.compressed && w.first, op, data)
+	w.first = false
+	return w.err
+}
+
+// writeControl writes a control frame.
+// After a close frame has been written, it returns ErrCloseSent.
+func (c *Conn) writeControl(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+	return c.writeFrameLocked(true, false, op, payload)
+}
+
+// writeFrame writes a data frame.
+func (c *Conn) writeFrame(fin, rsv1 bool, op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeFrameLocked(fin, rsv1, op, payload)
+}
+
+func (c *Conn) writeFrameLocked(fin, rsv1 bool, op byte, payload []byte) error {
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	default:
+	}
+
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	var b1 byte
+	if !c.isServer {
+		b1 = 0x80 // clients mask every frame
+	}
+	buf := append(c.wbuf[:0], b0)
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, b1|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, b1|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, b1|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if c.isServer {
+		buf = append(buf, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			c.writeErr = err
+			return err
+		}
+		buf = append(buf, key[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(key, 0, buf[start:])
+	}
+	if cap(buf) <= 2*writeFrameSize {
+		c.wbuf = buf
+	}
+
+	if _, err := c.w.Write(buf); err != nil {
+		c.writeErr = err
+		c.closeTransport()
+		return err
+	}
+	if c.flush != nil {
+		if err := c.flush(); err != nil {
+			c.writeErr = err
+			c.closeTransport()
+			return err
+		}
+	}
+	return nil
+}
+
+// maskBytes applies the masking key to b, starting at position pos
+// of the key, and returns the next position.
+func maskBytes(key [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[pos&3]
+		pos++
+	}
+	return pos & 3
+}
+
+// ReadMessage reads the next data message, returning its type and content.
+// After the peer has sent a close frame, it returns a [*CloseError].
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	typ, r, err := c.NextReader()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := io.ReadAll(r)
+	return typ, data, err
+}
+
+// NextReader returns the type of the next data message and a reader for
+// its content. Any unread part of the previous message is discarded.
+// After the peer has sent a close frame, NextReader returns a [*CloseError].
+func (c *Conn) NextReader() (MessageType, io.Reader, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	r, err := c.nextReaderLocked()
+	if err != nil {
+		return 0, nil, err
+	}
+	return c.msgType, r, nil
+}
+
+func (c *Conn) nextReaderLocked() (*messageReader, error) {
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	if c.reader != nil {
+		// Discard the rest of the previous message.
+		var buf [512]byte
+		for {
+			_, err := c.readMessageLocked(buf[:])
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		c.reader = nil
+	}
+
+	h, err := c.nextFrame()
+	if err != nil {
+		return nil, err
+	}
+	if h.opcode == opContinuation {
+		return nil, c.fail(StatusProtocolError, errors.New("websocket: continuation frame without a message"))
+	}
+	c.msgType = MessageType(h.opcode)
+	c.msgCompressed = h.rsv1
+	c.msgDone = false
+	c.msgRead = 0
+	c.utf8 = utf8Validator{}
+	c.startFrame(h)
+	if c.msgCompressed {
+		c.decomp.reset(payloadReader{c})
+	}
+	c.reader = &messageReader{c}
+	return c.reader, nil
+}
+
+type messageReader struct {
+	c *Conn
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	c := r.c
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	if c.reader != r {
+		return 0, errStaleReader
+	}
+	return c.readMessageLocked(p)
+}
+
+// readMessageLocked reads the (decompressed) content of the current message.
+func (c *Conn) readMessageLocked(p []byte) (int, error) {
+	if c.readErr != nil {
+		return 0, c.readErr
+	}
+	if c.msgDone {
+		return 0, io.EOF
+	}
+	var n int
+	var err error
+	if c.msgCompressed {
+		n, err = c.decomp.fr.Read(p)
+		if err != nil && err != io.EOF && c.readErr == nil {
+			// The payload itself was read without error,
+			// so decompression must have failed.
+			err = c.fail(StatusInvalidPayload, errBadDeflate)
+		}
+	} else {
+		n, err = c.readPayloadLocked(p)
+	}
+	if n > 0 {
+		c.msgRead += int64(n)
+		if c.readLimit > 0 && c.msgRead > c.readLimit {
+			return 0, c.fail(StatusMessageTooBig, ErrReadLimit)
+		}
+		if c.msgType == TextMessage && !c.utf8.write(p[:n]) {
+			return 0, c.fail(StatusInvalidPayload, errInvalidUTF8)
+		}
+		if c.msgCompressed {
+			c.decomp.record(p[:n])
+		}
+	}
+	if err == io.EOF {
+		if c.msgType == TextMessage && !c.utf8.done() {
+			return 0, c.fail(StatusInvalidPayload, errInvalidUTF8)
+		}
+		c.msgDone = true
+	}
+	return n, err
+}
+
+// payloadReader reads the raw payload of the current message.
+// It is used as the source of the decompressor, with c.rmu held.
+type payloadReader struct {
+	c *Conn
+}
+
+func (r payloadReader) Read(p []byte) (int, error) {
+	return r.c.readPayloadLocked(p)
+}
+
+// readPayloadLocked reads the raw payload of the current message,
+// returning io.EOF at its end.
+func (c *Conn) readPayloadLocked(p []byte) (int, error) {
+	for c.frameRemaining == 0 {
+		if c.frameFin {
+			return 0, io.EOF
+		}
+		h, err := c.nextFrame()
+		if err != nil {
+			return 0, err
+		}
+		if h.opcode != opContinuation {
+			return 0, c.fail(StatusProtocolError, errors.New("websocket: new message started before the previous one finished"))
+		}
+		if h.rsv1 {
+			return 0, c.fail(StatusProtocolError, errors.New("websocket: RSV1 set on a continuation frame"))
+		}
+		c.startFrame(h)
+	}
+	if int64(len(p)) > c.frameRemaining {
+		p = p[:c.frameRemaining]
+	}
+	n, err := c.br.Read(p)
+	if c.frameMasked {
+		c.frameMaskPos = maskBytes(c.frameMask, c.frameMaskPos, p[:n])
+	}
+	c.frameRemaining -= int64(n)
+	if err != nil {
+		return n, c.readFailed(err)
+	}
+	return n, nil
+}
+
+func (c *Conn) startFrame(h frameHeader) {
+	c.frameFin = h.fin
+	c.frameRemaining = h.length
+	c.frameMasked = h.masked
+	c.frameMask = h.mask
+	c.frameMaskPos = 0
+}
+
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	opcode byte
+	masked bool
+	mask   [4]byte
+	length int64
+}
+
+func (h frameHeader) isControl() bool {
+	return h.opcode&0x8 != 0
+}
+
+// nextFrame returns the header of the next data frame,
+// handling any control frames that come before it.
+func (c *Conn) nextFrame() (frameHeader, error) {
+	for {
+		h, err := c.readFrameHeader()
+		if err != nil {
+			return h, err
+		}
+		if !h.isControl() {
+			return h, nil
+		}
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return h, c.readFailed(err)
+		}
+		if h.masked {
+			maskBytes(h.mask, 0, payload)
+		}
+		switch h.opcode {
+		case opPing:
+			if err := c.writeControl(opPong, payload); err != nil && err != ErrCloseSent {
+				return h, c.readFailed(err)
+			}
+		case opPong:
+			c.handlePong(payload)
+		case opClose:
+			return h, c.handleClose(payload)
+		}
+	}
+}
+
+func (c *Conn) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, c.readFailed(err)
+	}
+	h.fin = b[0]&0x80 != 0
+	h.rsv1 = b[0]&0x40 != 0
+	rsv23 := b[0]&0x30 != 0
+	h.opcode = b[0] & 0xf
+	h.masked = b[1]&0x80 != 0
+	h.length = int64(b[1] & 0x7f)
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, c.readFailed(err)
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, c.readFailed(err)
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n>>63 != 0 {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: invalid frame length"))
+		}
+		h.length = int64(n)
+	}
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+			return h, c.readFailed(err)
+		}
+	}
+
+	switch {
+	case h.masked != c.isServer:
+		if c.isServer {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: unmasked frame from client"))
+		}
+		return h, c.fail(StatusProtocolError, errors.New("websocket: masked frame from server"))
+	case rsv23:
+		return h, c.fail(StatusProtocolError, errors.New("websocket: unexpected RSV bits set"))
+	case h.isControl():
+		if h.opcode != opClose && h.opcode != opPing && h.opcode != opPong {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: unknown opcode "+strconv.Itoa(int(h.opcode))))
+		}
+		if !h.fin || h.rsv1 || h.length > maxControlPayload {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: invalid control frame"))
+		}
+	default:
+		if h.opcode != opContinuation && h.opcode != opText && h.opcode != opBinary {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: unknown opcode "+strconv.Itoa(int(h.opcode))))
+		}
+		if h.rsv1 && c.decomp == nil {
+			return h, c.fail(StatusProtocolError, errors.New("websocket: RSV1 set without compression"))
+		}
+	}
+	return h, nil
+}
+
+// handleClose processes a close frame from the peer, replying with a
+// close frame if one hasn't been sent, and returns the resulting error.
+func (c *Conn) handleClose(payload []byte) error {
+	ce := &CloseError{Code: StatusNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(StatusProtocolError, errBadCloseFrame)
+	case len(payload) >= 2:
+		ce.Code = StatusCode(binary.BigEndian.Uint16(payload))
+		ce.Reason = string(payload[2:])
+		if !ce.Code.sendable() || !utf8.ValidString(ce.Reason) {
+			return c.fail(StatusProtocolError, errBadCloseFrame)
+		}
+	}
+	c.readErr = ce
+	close(c.closeRecv)
+
+	var reply []byte
+	if ce.Code != StatusNoStatusReceived {
+		reply = binary.BigEndian.AppendUint16(nil, uint16(ce.Code))
+	}
+	c.writeControl(opClose, reply)
+	c.closeTransport()
+	return ce
+}
+
+// readFailed records an error reading from the underlying connection.
+func (c *Conn) readFailed(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if c.readErr == nil {
+		c.readErr = err
+	}
+	c.closeTransport()
+	return c.readErr
+}
+
+// fail fails the connection after a protocol violation by the peer:
+// it sends a close frame with the given status and closes the
+// underlying connection.
+func (c *Conn) fail(code StatusCode, err error) error {
+	if c.readErr != nil {
+		return c.readErr
+	}
+	c.readErr = err
+	reason := err.Error()
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	c.writeControl(opClose, payload)
+	c.closeTransport()
+	return err
+}
+
+// utf8Validator incrementally validates UTF-8 text
+// that may be split in the middle of a character.
+type utf8Validator struct {
+	buf [utf8.UTFMax]byte // incomplete trailing character
+	n   int
+}
+
+// write reports whether p continues valid UTF-8 text.
+func (v *utf8Validator) write(p []byte) bool {
+	for v.n > 0 && len(p) > 0 {
+		v.buf[v.n] = p[0]
+		v.n++
+		p = p[1:]
+		if utf8.FullRune(v.buf[:v.n]) {
+			if r, size := utf8.DecodeRune(v.buf[:v.n]); r == utf8.RuneError && size <= 1 {
+				return false
+			}
+			v.n = 0
+		}
+	}
+	if v.n > 0 {
+		return true // p was consumed by the incomplete character
+	}
+	// Set aside an incomplete character at the end of p.
+	end := len(p)
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	if !utf8.Valid(p[:end]) {
+		return false
+	}
+	v.n = copy(v.buf[:], p[end:])
+	return true
+}
+
+// done reports whether the text ended with a complete character.
+func (v *utf8Validator) done() bool {
+	return v.n == 0
+}
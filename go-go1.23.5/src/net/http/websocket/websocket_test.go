@@ -0,0 +1,545 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// echoHandler echoes messages until the client closes the connection.
+func echoHandler(t *testing.T, u *Upgrader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r)
+		if err != nil {
+			t.Logf("Upgrade: %v", err)
+			return
+		}
+		defer c.CloseNow()
+		for {
+			typ, rd, err := c.NextReader()
+			if err != nil {
+				return
+			}
+			wr, err := c.NextWriter(typ)
+			if err != nil {
+				return
+			}
+			if _, err := io.Copy(wr, rd); err != nil {
+				return
+			}
+			if err := wr.Close(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+var testMessages = []struct {
+	typ  MessageType
+	data string
+}{
+	{TextMessage, "hello"},
+	{BinaryMessage, ""},
+	{BinaryMessage, "\x00\x01\x02\xff"},
+	{TextMessage, strings.Repeat("héllo wörld ", 10000)},
+	{BinaryMessage, strings.Repeat("\xfe", 70000)},
+	{TextMessage, "hello"},
+}
+
+func testEcho(t *testing.T, c *Conn) {
+	t.Helper()
+	for _, m := range testMessages {
+		if err := c.WriteMessage(m.typ, []byte(m.data)); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		typ, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if typ != m.typ || string(data) != m.data {
+			t.Fatalf("echoed %v message of %d bytes, want %v message of %d bytes", typ, len(data), m.typ, len(m.data))
+		}
+	}
+	if err := c.Close(StatusNormalClosure, ""); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		http2       bool
+		compression bool
+	}{
+		{"HTTP/1", false, false},
+		{"HTTP/1 compressed", false, true},
+		{"HTTP/2", true, false},
+		{"HTTP/2 compressed", true, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			u := &Upgrader{EnableCompression: true}
+			ts := httptest.NewUnstartedServer(echoHandler(t, u))
+			ts.EnableHTTP2 = test.http2
+			ts.StartTLS()
+			defer ts.Close()
+
+			d := &Dialer{
+				Client:            ts.Client(),
+				EnableCompression: test.compression,
+				UseHTTP2:          test.http2,
+			}
+			c, resp, err := d.Dial(context.Background(), ts.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.CloseNow()
+			if want := map[bool]int{false: 1, true: 2}[test.http2]; resp.ProtoMajor != want {
+				t.Errorf("response ProtoMajor = %d, want %d", resp.ProtoMajor, want)
+			}
+			if c.CompressionEnabled() != test.compression {
+				t.Errorf("CompressionEnabled() = %v, want %v", c.CompressionEnabled(), test.compression)
+			}
+			testEcho(t, c)
+		})
+	}
+}
+
+func TestHTTP2ExtendedConnectNotSupported(t *testing.T) {
+	ts := httptest.NewUnstartedServer(echoHandler(t, &Upgrader{}))
+	ts.StartTLS() // HTTP/1 only
+	defer ts.Close()
+
+	d := &Dialer{Client: ts.Client(), UseHTTP2: true}
+	if _, _, err := d.Dial(context.Background(), ts.URL, nil); err == nil {
+		t.Fatal("Dial using HTTP/2 to an HTTP/1 server succeeded")
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	u := &Upgrader{Subprotocols: []string{"v2", "v1"}}
+	ts := httptest.NewServer(echoHandler(t, u))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		offer []string
+		want  string
+	}{
+		{[]string{"v1", "v2"}, "v2"},
+		{[]string{"v1"}, "v1"},
+		{[]string{"v3"}, ""},
+		{nil, ""},
+	} {
+		d := &Dialer{Subprotocols: test.offer}
+		c, resp, err := d.Dial(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Subprotocol(); got != test.want {
+			t.Errorf("offer %q: Subprotocol() = %q, want %q", test.offer, got, test.want)
+		}
+		if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != test.want {
+			t.Errorf("offer %q: Sec-WebSocket-Protocol = %q, want %q", test.offer, got, test.want)
+		}
+		c.Close(StatusNormalClosure, "")
+	}
+}
+
+func TestHandshakeRejected(t *testing.T) {
+	ts := httptest.NewServer(echoHandler(t, &Upgrader{}))
+	defer ts.Close()
+
+	_, resp, err := new(Dialer).Dial(context.Background(), ts.URL, http.Header{"Origin": {"https://evil.example"}})
+	var he *HandshakeError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden {
+		t.Errorf("Dial with cross-origin request: %v, want HandshakeError with status 403", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Dial with cross-origin request returned response %v, want 403", resp)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Errorf("request with version 8: status %v, Sec-WebSocket-Version %q; want 426 and 13",
+			resp.Status, resp.Header.Get("Sec-WebSocket-Version"))
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455, Section 1.3.
+	if got, want := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("acceptKey = %q, want %q", got, want)
+	}
+}
+
+// newConnPair returns a client and server Conn
+// connected by a loopback TCP connection.
+func newConnPair(t *testing.T, deflate *deflateParams) (client, server *Conn) {
+	cc, sc := newNetConnPair(t)
+	client = newConn(connConfig{br: bufio.NewReader(cc), w: cc, close: cc.Close, deadlines: cc, deflate: deflate})
+	server = newConn(connConfig{isServer: true, br: bufio.NewReader(sc), w: sc, close: sc.Close, deadlines: sc, deflate: deflate})
+	t.Cleanup(func() {
+		client.CloseNow()
+		server.CloseNow()
+	})
+	return client, server
+}
+
+func newNetConnPair(t *testing.T) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := ln.Accept()
+	if err != nil {
+		cc.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+		sc.Close()
+	})
+	return cc, sc
+}
+
+func TestContextTakeover(t *testing.T) {
+	for _, p := range []deflateParams{
+		{},
+		{serverNoContextTakeover: true},
+		{clientNoContextTakeover: true},
+		{serverNoContextTakeover: true, clientNoContextTakeover: true},
+	} {
+		client, server := newConnPair(t, &p)
+		msg := []byte(strings.Repeat("a repetitive message ", 10))
+		for i := 0; i < 3; i++ {
+			for _, c := range []struct{ from, to *Conn }{{client, server}, {server, client}} {
+				if err := c.from.WriteMessage(TextMessage, msg); err != nil {
+					t.Fatal(err)
+				}
+				_, got, err := c.to.ReadMessage()
+				if err != nil {
+					t.Fatalf("%+v: %v", p, err)
+				}
+				if !bytes.Equal(got, msg) {
+					t.Fatalf("%+v: got %q, want %q", p, got, msg)
+				}
+			}
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	client, server := newConnPair(t, nil)
+	go func() {
+		// Read so that the ping is answered.
+		for {
+			if _, _, err := server.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	readErr := make(chan error, 1)
+	go func() {
+		// Read so that the pong is received.
+		_, _, err := client.ReadMessage()
+		readErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if err := client.Ping(ctx); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	}
+
+	if err := client.Close(StatusGoingAway, "done"); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	var ce *CloseError
+	if err := <-readErr; !errors.As(err, &ce) || ce.Code != StatusGoingAway {
+		t.Errorf("ReadMessage during Close = %v, want CloseError with status %d", err, StatusGoingAway)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	client, server := newConnPair(t, nil)
+	serverErr := make(chan error, 1)
+	go func() {
+		_, _, err := server.ReadMessage()
+		serverErr <- err
+	}()
+	if err := client.Close(StatusNormalClosure, "bye"); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	err := <-serverErr
+	if want := (&CloseError{StatusNormalClosure, "bye"}); !reflect.DeepEqual(err, want) {
+		t.Errorf("server ReadMessage = %v, want %v", err, want)
+	}
+	if err := client.WriteMessage(TextMessage, []byte("x")); err != ErrCloseSent {
+		t.Errorf("WriteMessage after Close = %v, want ErrCloseSent", err)
+	}
+	if err := server.WriteMessage(TextMessage, []byte("x")); err != ErrCloseSent {
+		t.Errorf("server WriteMessage after close = %v, want ErrCloseSent", err)
+	}
+
+	if err := client.Close(StatusCode(1005), ""); err == nil {
+		t.Errorf("Close with status 1005 succeeded")
+	}
+}
+
+// frame returns the encoding of a frame sent by a client.
+func frame(fin bool, op byte, payload string) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	b := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, 0x80|byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0x80|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	key := [4]byte{1, 2, 3, 4}
+	b = append(b, key[:]...)
+	start := len(b)
+	b = append(b, payload...)
+	maskBytes(key, 0, b[start:])
+	return b
+}
+
+func closePayload(code StatusCode, reason string) string {
+	return string(binary.BigEndian.AppendUint16(nil, uint16(code))) + reason
+}
+
+func TestServerReadFrames(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		frames [][]byte
+		limit  int64
+		want   []string   // messages read before the error
+		err    error      // error returned by the read, if not a protocol error
+		status StatusCode // status of the close frame sent by the server
+	}{{
+		name: "fragmented with interleaved ping",
+		frames: [][]byte{
+			frame(false, opText, "hel"),
+			frame(true, opPing, "p"),
+			frame(false, opContinuation, ""),
+			frame(true, opContinuation, "lo"),
+			frame(true, opBinary, "bin"),
+			frame(true, opClose, closePayload(StatusNormalClosure, "")),
+		},
+		want:   []string{"hello", "bin"},
+		err:    &CloseError{Code: StatusNormalClosure},
+		status: StatusNormalClosure,
+	}, {
+		name:   "empty close",
+		frames: [][]byte{frame(true, opClose, "")},
+		err:    &CloseError{Code: StatusNoStatusReceived},
+	}, {
+		name:   "unmasked",
+		frames: [][]byte{{0x81, 0x01, 'x'}},
+		status: StatusProtocolError,
+	}, {
+		name:   "unexpected continuation",
+		frames: [][]byte{frame(true, opContinuation, "x")},
+		status: StatusProtocolError,
+	}, {
+		name:   "interleaved data",
+		frames: [][]byte{frame(false, opText, "x"), frame(true, opText, "y")},
+		status: StatusProtocolError,
+	}, {
+		name:   "fragmented control",
+		frames: [][]byte{frame(false, opPing, "")},
+		status: StatusProtocolError,
+	}, {
+		name:   "long control",
+		frames: [][]byte{frame(true, opPing, strings.Repeat("x", 126))},
+		status: StatusProtocolError,
+	}, {
+		name:   "reserved opcode",
+		frames: [][]byte{frame(true, 0x3, "")},
+		status: StatusProtocolError,
+	}, {
+		name:   "RSV1 without compression",
+		frames: [][]byte{append([]byte{0xc1}, frame(true, 0, "x")[1:]...)},
+		status: StatusProtocolError,
+	}, {
+		name:   "invalid close code",
+		frames: [][]byte{frame(true, opClose, closePayload(1005, ""))},
+		status: StatusProtocolError,
+	}, {
+		name:   "invalid UTF-8",
+		frames: [][]byte{frame(false, opText, "\xe2\x82"), frame(true, opContinuation, "\x28")},
+		err:    errInvalidUTF8,
+		status: StatusInvalidPayload,
+	}, {
+		name:   "truncated UTF-8",
+		frames: [][]byte{frame(true, opText, "\xe2\x82")},
+		err:    errInvalidUTF8,
+		status: StatusInvalidPayload,
+	}, {
+		name:   "read limit",
+		frames: [][]byte{frame(true, opBinary, "1234"), frame(false, opBinary, "123"), frame(true, opContinuation, "45")},
+		limit:  4,
+		want:   []string{"1234"},
+		err:    ErrReadLimit,
+		status: StatusMessageTooBig,
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			cc, sc := newNetConnPair(t)
+			c := newConn(connConfig{isServer: true, br: bufio.NewReader(sc), w: sc, close: sc.Close})
+			if test.limit > 0 {
+				c.SetReadLimit(test.limit)
+			}
+			for _, f := range test.frames {
+				if _, err := cc.Write(f); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []string
+			var err error
+			for {
+				var data []byte
+				_, data, err = c.ReadMessage()
+				if err != nil {
+					break
+				}
+				got = append(got, string(data))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("messages = %q, want %q", got, test.want)
+			}
+			if test.err != nil {
+				if !reflect.DeepEqual(err, test.err) {
+					t.Errorf("error = %v, want %v", err, test.err)
+				}
+			} else if err == nil || !strings.HasPrefix(err.Error(), "websocket: ") {
+				t.Errorf("error = %v, want protocol error", err)
+			}
+
+			// Read the frames sent by the server, up to its close frame.
+			cc.SetReadDeadline(time.Now().Add(10 * time.Second))
+			br := bufio.NewReader(cc)
+			var status StatusCode
+			for {
+				var h [2]byte
+				if _, err := io.ReadFull(br, h[:]); err != nil {
+					break
+				}
+				payload := make([]byte, h[1]&0x7f)
+				io.ReadFull(br, payload)
+				if h[0]&0xf == opClose {
+					if len(payload) >= 2 {
+						status = StatusCode(binary.BigEndian.Uint16(payload))
+					}
+					break
+				}
+			}
+			if test.status != 0 && status != test.status {
+				t.Errorf("server sent close status %d, want %d", status, test.status)
+			}
+		})
+	}
+}
+
+func TestUTF8Validator(t *testing.T) {
+	for _, s := range []string{"", "hello", "héllo wörld", "日本語", "😀😀", "\xff", "a\xe2\x82", "\xed\xa0\x80", "\xf0\x9f\x98"} {
+		want := utf8.ValidString(s)
+		for i := 0; i <= len(s); i++ {
+			for j := i; j <= len(s); j++ {
+				var v utf8Validator
+				ok := v.write([]byte(s[:i])) && v.write([]byte(s[i:j])) && v.write([]byte(s[j:])) && v.done()
+				if ok != want {
+					t.Errorf("%q split at %d, %d: valid = %v, want %v", s, i, j, ok, want)
+				}
+			}
+		}
+	}
+}
+
+func TestNegotiateDeflate(t *testing.T) {
+	for _, test := range []struct {
+		offer  string
+		want   *deflateParams
+		header string
+	}{
+		{"", nil, ""},
+		{"permessage-deflate", &deflateParams{}, "permessage-deflate"},
+		{"x-webkit-deflate-frame, permessage-deflate; client_max_window_bits", &deflateParams{}, "permessage-deflate"},
+		{
+			"permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+			&deflateParams{serverNoContextTakeover: true, clientNoContextTakeover: true},
+			"permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+		},
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate", &deflateParams{}, "permessage-deflate"},
+		{`permessage-deflate; server_max_window_bits="15"`, &deflateParams{}, "permessage-deflate"},
+		{"permessage-deflate; server_max_window_bits=10", nil, ""},
+		{"permessage-deflate; client_max_window_bits=16", nil, ""},
+		{"permessage-deflate; unknown", nil, ""},
+		{"permessage-deflate; client_no_context_takeover; client_no_context_takeover", nil, ""},
+	} {
+		h := http.Header{}
+		if test.offer != "" {
+			h.Set("Sec-WebSocket-Extensions", test.offer)
+		}
+		p, header := acceptDeflate(parseExtensions(h))
+		if !reflect.DeepEqual(p, test.want) || header != test.header {
+			t.Errorf("acceptDeflate(%q) = %+v, %q; want %+v, %q", test.offer, p, header, test.want, test.header)
+		}
+		if p == nil {
+			continue
+		}
+		// The client accepts the server's response.
+		h.Set("Sec-WebSocket-Extensions", header)
+		if cp, ok := checkDeflateResponse(parseExtensions(h), true); !ok || !reflect.DeepEqual(cp, p) {
+			t.Errorf("checkDeflateResponse(%q) = %+v, %v; want %+v, true", header, cp, ok, p)
+		}
+	}
+
+	for _, resp := range []string{
+		"permessage-deflate; client_max_window_bits=10",
+		"permessage-deflate, permessage-deflate",
+		"x-other",
+	} {
+		h := http.Header{"Sec-Websocket-Extensions": {resp}}
+		if _, ok := checkDeflateResponse(parseExtensions(h), true); ok {
+			t.Errorf("checkDeflateResponse(%q) succeeded, want failure", resp)
+		}
+	}
+}
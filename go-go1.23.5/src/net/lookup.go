@@ -159,6 +159,18 @@ type Resolver struct {
 	// If nil, the default dialer is used.
 	Dial func(ctx context.Context, network, address string) (Conn, error)
 
+	// Cache optionally specifies a cache for the responses received by
+	// Go's built-in DNS resolver. If nil, every query is sent to a DNS
+	// server, although concurrent lookups of the same host are merged.
+	Cache *DNSCache
+
+	// Transport optionally specifies how Go's built-in DNS resolver
+	// exchanges messages with DNS servers, in place of the UDP and TCP
+	// connections made with Dial, such as to reach them using DNS over
+	// TLS or HTTPS. If Transport is non-nil, the built-in resolver is
+	// always used, as if PreferGo were set.
+	Transport DNSTransport
+
 	// lookupGroup merges LookupIPAddr calls together for lookups for the same
 	// host. The lookupGroup key is the LookupIPAddr.host argument.
 	// The return values are ([]IPAddr, error).
@@ -168,9 +180,39 @@ type Resolver struct {
 	// TODO(bradfitz): Timeout time.Duration?
 }
 
-func (r *Resolver) preferGo() bool     { return r != nil && r.PreferGo }
+// A DNSTransport exchanges DNS messages with DNS servers for Go's
+// built-in DNS resolver. It is used by a [Resolver] whose Transport
+// field refers to it. The net/dns package provides transports for DNS
+// over TLS and HTTPS.
+type DNSTransport interface {
+	// Exchange sends the DNS query msg to the server at address, which
+	// is an IP address and port taken from resolv.conf, and returns the
+	// response. Messages are in the format of RFC 1035 Section 4, without
+	// the length prefix used over TCP. Transports may send the query
+	// to another address instead, such as one derived from address or
+	// one they are configured with. The response must answer the query,
+	// with the same ID. Exchange must be safe for concurrent use, and
+	// must return when ctx is done.
+	Exchange(ctx context.Context, address string, msg []byte) ([]byte, error)
+}
+
+func (r *Resolver) preferGo() bool     { return r != nil && (r.PreferGo || r.Transport != nil) }
 func (r *Resolver) strictErrors() bool { return r != nil && r.StrictErrors }
 
+func (r *Resolver) transport() DNSTransport {
+	if r == nil {
+		return nil
+	}
+	return r.Transport
+}
+
+func (r *Resolver) cache() *DNSCache {
+	if r == nil {
+		return nil
+	}
+	return r.Cache
+}
+
 func (r *Resolver) getLookupGroup() *singleflight.Group {
 	if r == nil {
 		return &DefaultResolver.lookupGroup